@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/config"
+	"github.com/your-username/echo-api/internal/handler"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/service/servicetest"
+	"github.com/your-username/echo-api/internal/util"
+)
+
+// benchProducts is how many products a benchmarked stack holds.
+const benchProducts = 100
+
+// BenchmarkCRUD serves the product routes, from request to response, on
+// stacks that add one cost at a time:
+//
+//	go test -run '^$' -bench CRUD .
+//
+// "handler" is the ProductHandler alone over a fake service, so the presets,
+// whose whole router runs over the in-memory repository, show what each
+// middleware chain costs, and crudBackends show what the database costs on
+// top of the test preset. BenchmarkRepositories in internal/repository
+// compares the repositories without HTTP; loadtest/crud.js drives a
+// running server.
+func BenchmarkCRUD(b *testing.B) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+
+	type stack struct {
+		name  string
+		build func() http.Handler
+	}
+	stacks := []stack{{"handler", func() http.Handler {
+		e := echo.New()
+		e.Validator = util.NewCustomValidator()
+		handler.NewProductHandler(servicetest.NewFake[model.Product](nil)).Register(e.Group("/products"))
+		return e
+	}}}
+	for _, preset := range []string{"test", "development", "production"} {
+		stacks = append(stacks, stack{"preset=" + preset, func() http.Handler {
+			cfg := config.Default()
+			cfg.Middleware.Preset = preset
+			cfg.RateLimit.Limits = map[string]string{"default": "1000000000/s"} // limited, never refused
+			return newTestServerWith(b, cfg)
+		}})
+	}
+	backends := crudBackends(b)
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stacks = append(stacks, stack{name, func() http.Handler {
+			cfg := config.Default()
+			backends[name](cfg)
+			return newTestServerWith(b, cfg)
+		}})
+	}
+
+	for _, s := range stacks {
+		// The access log writes to the os.Stdout it was built with, and the
+		// development preset logs every request.
+		stdout := os.Stdout
+		os.Stdout = devNull
+		do := requester(s.build())
+		os.Stdout = stdout
+		ids := make([]string, benchProducts)
+		for i := range ids {
+			ids[i] = benchCreate(b, do, i)
+		}
+		b.Run(s.name+"/get", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchExpect(b, do(http.MethodGet, "/products/"+ids[i%len(ids)], "", ""), http.StatusOK)
+			}
+		})
+		b.Run(s.name+"/list", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchExpect(b, do(http.MethodGet, "/products/", "", ""), http.StatusOK)
+			}
+		})
+		b.Run(s.name+"/update", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n := i % len(ids)
+				body := fmt.Sprintf(`{"name": "Product %d", "price": %d}`, n, n+1)
+				benchExpect(b, do(http.MethodPut, "/products/"+ids[n], "", body), http.StatusOK)
+			}
+		})
+		// A churn creates a product and deletes it, so the number of
+		// products stays at benchProducts.
+		b.Run(s.name+"/churn", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				id := benchCreate(b, do, benchProducts+i)
+				benchExpect(b, do(http.MethodDelete, "/products/"+id, "", ""), http.StatusNoContent)
+			}
+		})
+	}
+}
+
+// benchCreate creates the nth product and returns its ID.
+func benchCreate(b *testing.B, do func(method, path, token, body string) *httptest.ResponseRecorder, n int) string {
+	body := fmt.Sprintf(`{"name": "Product %d", "price": %d}`, n, n)
+	w := do(http.MethodPost, "/products/", "", body)
+	benchExpect(b, w, http.StatusCreated)
+	var created struct{ ID string }
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.ID == "" {
+		b.Fatalf("no id in %s", w.Body)
+	}
+	return created.ID
+}
+
+func benchExpect(b *testing.B, w *httptest.ResponseRecorder, status int) {
+	if w.Code != status {
+		b.Fatalf("got %d, want %d\n%s", w.Code, status, w.Body)
+	}
+}
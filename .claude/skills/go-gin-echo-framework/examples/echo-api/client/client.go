@@ -0,0 +1,240 @@
+// Package client is a typed Go client of the API, for consumers and for
+// integration tests, so that neither hand-rolls HTTP calls:
+//
+//	c := client.New("http://localhost:8080", client.Options{Retries: 2})
+//	if _, err := c.Login(ctx, email, password); err != nil { ... }
+//	u, err := c.Users().Get(ctx, id)
+//	if errors.Is(err, client.ErrNotFound) { ... }
+//
+// Every resource is a Resource over its model, with the routes of
+// handler.CrudHandler; cmd/scaffold adds the accessor of a new resource.
+// Failed requests return an *Error, which unwraps to ErrNotFound,
+// ErrInvalid and so on by status.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalid      = errors.New("invalid")      // 400, 422
+	ErrUnauthorized = errors.New("unauthorized") // 401
+	ErrForbidden    = errors.New("forbidden")    // 403
+	ErrNotFound     = errors.New("not found")    // 404
+	ErrConflict     = errors.New("conflict")     // 409
+)
+
+// Error is a response with a status of 400 or above.
+type Error struct {
+	Method, Path string
+	Status       int
+	Message      string // the "error" of the response, or its status text
+	RequestID    string // X-Request-ID of the response, to find it in the server logs
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.Status, e.Message)
+}
+
+// Unwrap returns the sentinel error of the status, or nil.
+func (e *Error) Unwrap() error {
+	switch e.Status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrInvalid
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	}
+	return nil
+}
+
+// Options configures a Client. The zero value sends every request once,
+// with http.DefaultClient and without a token until Login.
+type Options struct {
+	HTTPClient *http.Client
+	// Token returns the bearer token of each request, e.g. from a token
+	// cache; "" sends none. It takes precedence over Login.
+	Token func(ctx context.Context) (string, error)
+	// Header is added to every request, e.g. X-Tenant-ID.
+	Header http.Header
+	// Retries is how often a request that may be repeated (GET, PUT and
+	// DELETE) is retried after a network error, 429, 502, 503 or 504.
+	Retries int
+	// Backoff is the base of the exponential, full-jitter wait between
+	// tries; a Retry-After of the response takes precedence.
+	Backoff time.Duration
+}
+
+// Client calls one instance of the API. It is safe for concurrent use.
+type Client struct {
+	base string
+	opts Options
+
+	mu    sync.Mutex
+	token string // from Login
+}
+
+// New returns a client of the instance at base, e.g. https://api.example.com.
+func New(base string, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 100 * time.Millisecond
+	}
+	return &Client{base: strings.TrimSuffix(base, "/"), opts: opts}
+}
+
+// Token is the response of a login.
+type Token struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"` // seconds
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // seconds
+}
+
+// Login logs in with a password; later requests carry the access token,
+// unless Options.Token is set.
+func (c *Client) Login(ctx context.Context, email, password string) (*Token, error) {
+	var token Token
+	if err := c.do(ctx, http.MethodPost, "/auth/login", map[string]string{"email": email, "password": password}, &token); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.token = token.AccessToken
+	c.mu.Unlock()
+	return &token, nil
+}
+
+// do sends a request with body, unless nil, as JSON and decodes the
+// response into out, unless nil.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	res, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request, retrying it as Options allow, and returns the
+// response of a status below 400; its body is the caller's to close.
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+	}
+	retries := 0
+	if method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete {
+		retries = c.opts.Retries
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := c.attempt(ctx, method, path, data)
+		if err == nil && res.StatusCode < 400 {
+			return res, nil
+		}
+		if attempt == retries || ctx.Err() != nil || (err == nil && !retryable(res.StatusCode)) {
+			if err != nil {
+				return nil, err
+			}
+			return nil, failure(method, path, res)
+		}
+		wait := time.Duration(rand.N(int64(c.opts.Backoff) << attempt))
+		if res != nil {
+			if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(s) * time.Second
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, data []byte) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.opts.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := c.bearer(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: token: %w", method, path, err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.opts.HTTPClient.Do(req)
+}
+
+func (c *Client) bearer(ctx context.Context) (string, error) {
+	if c.opts.Token != nil {
+		return c.opts.Token(ctx)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token, nil
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// failure reads the *Error of res and closes its body.
+func failure(method, path string, res *http.Response) *Error {
+	defer res.Body.Close()
+	e := &Error{Method: method, Path: path, Status: res.StatusCode, RequestID: res.Header.Get("X-Request-ID")}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&body) == nil && body.Error != "" {
+		e.Message = body.Error
+	} else {
+		e.Message = http.StatusText(res.StatusCode)
+	}
+	return e
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestErrorsMapToSentinels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		switch r.URL.Path {
+		case "/products/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Product not found"}`))
+		case "/products/":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "name is required"}`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+	products := New(srv.URL, Options{}).Products()
+
+	_, err := products.Get(context.Background(), "missing")
+	var e *Error
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &e) || e.Message != "Product not found" || e.RequestID != "req-1" {
+		t.Errorf("Get: err = %#v", err)
+	}
+	if _, err := products.Create(context.Background(), &Product{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Create: err = %v, want ErrInvalid", err)
+	}
+	err = products.Delete(context.Background(), "x")
+	if !errors.As(err, &e) || e.Status != http.StatusTeapot || e.Unwrap() != nil || e.Message != "I'm a teapot" {
+		t.Errorf("Delete: err = %#v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": "p1", "name": "Lamp"}`))
+	}))
+	defer srv.Close()
+	products := New(srv.URL, Options{Retries: 2, Backoff: time.Millisecond}).Products()
+
+	if p, err := products.Get(context.Background(), "p1"); err != nil || p.Name != "Lamp" || calls.Load() != 3 {
+		t.Fatalf("Get = %+v, %v after %d calls, want Lamp after 3", p, err, calls.Load())
+	}
+
+	// A create may have been applied: it is sent once
+	calls.Store(0)
+	if _, err := products.Create(context.Background(), &Product{Name: "Lamp"}); err == nil || calls.Load() != 1 {
+		t.Fatalf("Create: err = %v after %d calls, want 503 after 1", err, calls.Load())
+	}
+}
+
+func TestLoginSetsTheToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/auth/login":
+			w.Write([]byte(`{"access_token": "t1", "token_type": "Bearer", "expires_in": 900}`))
+		case r.Header.Get("Authorization") != "Bearer t1":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "missing or invalid token"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+	c := New(srv.URL, Options{})
+
+	if _, err := c.Products().List(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("List before Login: err = %v, want ErrUnauthorized", err)
+	}
+	if token, err := c.Login(context.Background(), "ada@example.com", "password"); err != nil || token.ExpiresIn != 900 {
+		t.Fatalf("Login = %+v, %v", token, err)
+	}
+	if _, err := c.Products().List(context.Background()); err != nil {
+		t.Fatalf("List after Login: %v", err)
+	}
+
+	// Options.Token takes precedence
+	c = New(srv.URL, Options{Token: func(context.Context) (string, error) { return "t1", nil }})
+	if _, err := c.Products().List(context.Background()); err != nil {
+		t.Fatalf("List with Options.Token: %v", err)
+	}
+}
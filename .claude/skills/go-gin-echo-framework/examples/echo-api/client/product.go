@@ -0,0 +1,13 @@
+package client
+
+import "github.com/your-username/echo-api/internal/model"
+
+// Product is model.Product, named here for consumers outside this module.
+type Product = model.Product
+
+type ProductsClient = Resource[Product]
+
+// Products returns the client of the product routes.
+func (c *Client) Products() *ProductsClient {
+	return NewResource[Product](c, "/products")
+}
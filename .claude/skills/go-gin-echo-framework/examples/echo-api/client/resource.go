@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/your-username/echo-api/internal/model"
+)
+
+// Resource calls the CRUD routes of one resource, mounted at its path.
+type Resource[T model.Entity] struct {
+	c    *Client
+	path string // e.g. /users
+}
+
+// NewResource returns the client of the resource of T mounted at path,
+// for resources without an accessor on Client.
+func NewResource[T model.Entity](c *Client, path string) *Resource[T] {
+	return &Resource[T]{c: c, path: path}
+}
+
+func (r *Resource[T]) item(id string) string {
+	return r.path + "/" + url.PathEscape(id)
+}
+
+// List returns every item.
+func (r *Resource[T]) List(ctx context.Context) ([]T, error) {
+	var items []T
+	err := r.c.do(ctx, http.MethodGet, r.path+"/", nil, &items)
+	return items, err
+}
+
+// Stream calls fn for every item as it is received, for lists too long to
+// hold at once. An error of fn stops the stream and is returned.
+func (r *Resource[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	path := r.path + "/stream"
+	res, err := r.c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	dec := json.NewDecoder(res.Body)
+	for {
+		var item T
+		if err := dec.Decode(&item); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return &Error{Method: http.MethodGet, Path: path, Status: res.StatusCode, Message: "stream ended early: " + err.Error()}
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// Get returns the item with the given ID.
+func (r *Resource[T]) Get(ctx context.Context, id string) (*T, error) {
+	var item T
+	if err := r.c.do(ctx, http.MethodGet, r.item(id), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Create creates item, returning it as stored.
+func (r *Resource[T]) Create(ctx context.Context, item *T) (*T, error) {
+	var created T
+	if err := r.c.do(ctx, http.MethodPost, r.path+"/", item, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Update replaces the item with the ID of item, returning it as stored.
+func (r *Resource[T]) Update(ctx context.Context, item *T) (*T, error) {
+	var updated T
+	if err := r.c.do(ctx, http.MethodPut, r.item((*item).GetID()), item, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Delete deletes the item with the given ID.
+func (r *Resource[T]) Delete(ctx context.Context, id string) error {
+	return r.c.do(ctx, http.MethodDelete, r.item(id), nil, nil)
+}
+
+// UndoDelete restores the item with the given ID, deleted within the trash
+// window.
+func (r *Resource[T]) UndoDelete(ctx context.Context, id string) (*T, error) {
+	var restored T
+	if err := r.c.do(ctx, http.MethodPost, r.item(id)+"/undo-delete", nil, &restored); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
@@ -0,0 +1,25 @@
+// Command openapi-gen writes the OpenAPI 3 document for this module from its
+// swag annotations. It is run by `go generate ./...`; see internal/openapi.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/your-username/echo-api/internal/openapi"
+)
+
+func main() {
+	root := flag.String("root", ".", "module root containing main.go and internal/")
+	out := flag.String("o", "openapi.json", "output file")
+	flag.Parse()
+
+	doc, err := openapi.Generate(*root)
+	if err != nil {
+		log.Fatalf("openapi-gen: %v", err)
+	}
+	if err := os.WriteFile(*out, doc, 0o644); err != nil {
+		log.Fatalf("openapi-gen: %v", err)
+	}
+}
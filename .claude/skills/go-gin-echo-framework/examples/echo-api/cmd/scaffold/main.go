@@ -0,0 +1,712 @@
+// Command scaffold generates a new CRUD resource on top of the generic
+// repository, service and handler stack: model, in-memory repository,
+// service, domain event types, handler, table-driven handler tests, its
+// accessor in package client, a SQL migration, the route wiring in
+// main.go, where newRepository switches it to the SQL or MongoDB backend
+// named by database.url, and the steps of TestResponseSnapshots covering
+// its routes. Run it from the module root, directly or via go:generate:
+//
+//	go run ./cmd/scaffold -name Order -field Customer:string:required -field Total:float64:gte=0
+//	//go:generate go run ./cmd/scaffold -name Order -field Customer:string:required -force
+//
+// A field is Name:type[:validation], where type is string, int, int64,
+// float64, bool or time.Time and validation is a validator tag such as
+// required,email. The framework (gin or echo) is detected from go.mod.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// routesMarker is the line in main.go above which resource wiring is inserted.
+const routesMarker = "// scaffold:routes"
+
+// snapshotsMarker is the line in snapshot_test.go above which the resource's
+// snapshot steps are inserted.
+const snapshotsMarker = "// scaffold:snapshots"
+
+type field struct {
+	Name     string // Go field name, e.g. CreatedBy
+	Type     string // Go type
+	Validate string // validator tag, possibly empty
+	JSON     string // json name, e.g. created_by
+}
+
+func (f field) SQLType() string {
+	return map[string]string{
+		"string":    "TEXT",
+		"int":       "INTEGER",
+		"int64":     "BIGINT",
+		"float64":   "DOUBLE PRECISION",
+		"bool":      "BOOLEAN",
+		"time.Time": "TIMESTAMPTZ",
+	}[f.Type]
+}
+
+// Sample returns a JSON literal that passes the field's validation.
+func (f field) Sample() string {
+	switch f.Type {
+	case "int", "int64", "float64":
+		return "1"
+	case "bool":
+		return "true"
+	case "time.Time":
+		return `"2024-01-01T00:00:00Z"`
+	}
+	for _, rule := range strings.Split(f.Validate, ",") {
+		switch name, arg, _ := strings.Cut(rule, "="); name {
+		case "email":
+			return `"user@example.com"`
+		case "url", "uri":
+			return `"https://example.com"`
+		case "uuid", "uuid4":
+			return `"4e8b5d5e-2f0a-4f4b-9b7e-1f3c8c2d9a10"`
+		case "oneof":
+			option, _, _ := strings.Cut(arg, " ")
+			return fmt.Sprintf("%q", option)
+		}
+	}
+	return `"example"`
+}
+
+func (f field) Required() bool {
+	for _, rule := range strings.Split(f.Validate, ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+type resource struct {
+	Module    string // Go module path
+	Framework string // gin or echo
+	Name      string // exported type name, e.g. LineItem
+	Var       string // unexported identifier, e.g. lineItem
+	Recv      string // method receiver, e.g. l
+	Singular  string // lower-case words, e.g. "line item"
+	Kebab     string // service name and ID prefix, e.g. line-item
+	Plural    string // identifier-safe plural, e.g. lineItems
+	Table     string // SQL table, e.g. line_items
+	Path      string // route prefix, e.g. /line-items
+	Fields    []field
+}
+
+// PluralName is the exported plural, e.g. LineItems.
+func (r resource) PluralName() string {
+	return strings.ToUpper(r.Plural[:1]) + r.Plural[1:]
+}
+
+// Tag is the struct tag key the framework validates: binding (Gin) or validate (Echo).
+func (r resource) Tag() string {
+	if r.Framework == "gin" {
+		return "binding"
+	}
+	return "validate"
+}
+
+// Schema is the CREATE TABLE statement repository.NewSQLRepository expects,
+// written as the resource's migration.
+func (r resource) Schema() string {
+	cols := []string{"id TEXT PRIMARY KEY"}
+	for _, f := range r.Fields {
+		cols = append(cols, f.JSON+" "+f.SQLType()+" NOT NULL")
+	}
+	cols = append(cols, "created_at TIMESTAMP NOT NULL", "updated_at TIMESTAMP NOT NULL", "tenant_id TEXT NOT NULL DEFAULT ''", "owner_id TEXT NOT NULL DEFAULT ''")
+	return "CREATE TABLE " + r.Table + " (\n\t" + strings.Join(cols, ",\n\t") + "\n);"
+}
+
+// SampleJSON is a request body that passes validation.
+func (r resource) SampleJSON() string {
+	parts := make([]string, len(r.Fields))
+	for i, f := range r.Fields {
+		parts[i] = fmt.Sprintf("%q: %s", f.JSON, f.Sample())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// FirstRequired returns a field whose absence fails validation, or nil.
+func (r resource) FirstRequired() *field {
+	for i := range r.Fields {
+		if r.Fields[i].Required() {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("scaffold: ")
+
+	var (
+		name      = flag.String("name", "", "resource type name in CamelCase, e.g. Order")
+		plural    = flag.String("plural", "", "plural of the name when adding s is wrong, e.g. People")
+		framework = flag.String("framework", "", "gin or echo; detected from go.mod when empty")
+		dir       = flag.String("dir", ".", "module root to generate into")
+		force     = flag.Bool("force", false, "overwrite existing files")
+		fields    []field
+	)
+	flag.Func("field", "field as Name:type[:validation], repeatable", func(v string) error {
+		f, err := parseField(v)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, f)
+		return nil
+	})
+	flag.Parse()
+
+	if !regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`).MatchString(*name) {
+		log.Fatal("-name must be a CamelCase Go identifier, e.g. Order")
+	}
+	if len(fields) == 0 {
+		log.Fatal("at least one -field is required")
+	}
+	seen := map[string]bool{"ID": true}
+	for _, f := range fields {
+		if seen[f.Name] {
+			log.Fatalf("duplicate field %s (ID is always generated)", f.Name)
+		}
+		seen[f.Name] = true
+	}
+
+	module, detected, err := readModule(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *framework == "" {
+		*framework = detected
+	}
+	if *framework != "gin" && *framework != "echo" {
+		log.Fatalf("unknown framework %q (want gin or echo)", *framework)
+	}
+
+	res := newResource(module, *framework, *name, *plural, fields)
+	if err := generate(*dir, res, *force); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Run `go generate ./...` to add %s to the OpenAPI spec, `GOLDEN_UPDATE=1 go test -run TestResponseSnapshots .` to record its response snapshots, then `go test ./...`.\n", res.Path)
+}
+
+func parseField(v string) (field, error) {
+	parts := strings.SplitN(v, ":", 3)
+	if len(parts) < 2 {
+		return field{}, fmt.Errorf("field %q: want Name:type[:validation]", v)
+	}
+	f := field{Name: parts[0], Type: parts[1]}
+	if len(parts) == 3 {
+		f.Validate = parts[2]
+	}
+	if !regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`).MatchString(f.Name) {
+		return field{}, fmt.Errorf("field %q: name must be an exported Go identifier", v)
+	}
+	if f.Name == "ID" || f.Name == "CreatedAt" || f.Name == "UpdatedAt" || f.Name == "TenantID" || f.Name == "OwnerID" {
+		return field{}, fmt.Errorf("field %q: every model has %s already", v, f.Name)
+	}
+	if f.SQLType() == "" {
+		return field{}, fmt.Errorf("field %q: type must be string, int, int64, float64, bool or time.Time", v)
+	}
+	if strings.ContainsAny(f.Validate, "`\"") {
+		return field{}, fmt.Errorf("field %q: validation must not contain quotes", v)
+	}
+	f.JSON = strings.Join(words(f.Name), "_")
+	return f, nil
+}
+
+func newResource(module, framework, name, plural string, fields []field) resource {
+	w := words(name)
+	if plural == "" {
+		w[len(w)-1] = pluralize(w[len(w)-1])
+	} else {
+		w = words(plural)
+	}
+	pluralVar := w[0]
+	for _, s := range w[1:] {
+		pluralVar += strings.ToUpper(s[:1]) + s[1:]
+	}
+	return resource{
+		Module:    module,
+		Framework: framework,
+		Name:      name,
+		Var:       strings.ToLower(name[:1]) + name[1:],
+		Recv:      strings.ToLower(name[:1]),
+		Singular:  strings.Join(words(name), " "),
+		Kebab:     strings.Join(words(name), "-"),
+		Plural:    pluralVar,
+		Table:     strings.Join(w, "_"),
+		Path:      "/" + strings.Join(w, "-"),
+		Fields:    fields,
+	}
+}
+
+// words splits a CamelCase identifier into lower-case words: HTTPRoute -> http, route.
+func words(s string) []string {
+	var out []string
+	runes := []rune(s)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		upper := unicode.IsUpper(runes[i])
+		if upper && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			out = append(out, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(out, strings.ToLower(string(runes[start:])))
+}
+
+func pluralize(w string) string {
+	switch {
+	case strings.HasSuffix(w, "y") && len(w) > 1 && !strings.ContainsRune("aeiou", rune(w[len(w)-2])):
+		return w[:len(w)-1] + "ies"
+	case strings.HasSuffix(w, "s"), strings.HasSuffix(w, "x"), strings.HasSuffix(w, "ch"), strings.HasSuffix(w, "sh"):
+		return w + "es"
+	default:
+		return w + "s"
+	}
+}
+
+// readModule returns the module path and the web framework it depends on.
+func readModule(dir string) (module, framework string, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", "", fmt.Errorf("run from the module root: %w", err)
+	}
+	m := regexp.MustCompile(`(?m)^module\s+(\S+)`).FindSubmatch(data)
+	if m == nil {
+		return "", "", errors.New("go.mod has no module directive")
+	}
+	switch {
+	case bytes.Contains(data, []byte("github.com/gin-gonic/gin ")):
+		framework = "gin"
+	case bytes.Contains(data, []byte("github.com/labstack/echo/v4 ")):
+		framework = "echo"
+	}
+	return string(m[1]), framework, nil
+}
+
+func generate(dir string, res resource, force bool) error {
+	snake := strings.Join(words(res.Name), "_")
+	files := []struct {
+		path string
+		tmpl *template.Template
+	}{
+		{"internal/model/" + snake + ".go", modelTmpl},
+		{"internal/repository/" + snake + "_repository.go", repositoryTmpl},
+		{"internal/service/" + snake + "_service.go", serviceTmpl},
+		{"internal/domain/" + snake + ".go", domainTmpl},
+		{"internal/handler/" + snake + "_handler.go", handlerTmpl},
+		{"internal/handler/" + snake + "_handler_test.go", handlerTestTmpl[res.Framework]},
+		{"client/" + snake + ".go", clientTmpl},
+	}
+
+	rendered := make([][]byte, len(files))
+	for i, f := range files {
+		path := filepath.Join(dir, f.path)
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("%s already exists (use -force to overwrite)", f.path)
+		}
+		src, err := render(f.tmpl, res)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		rendered[i] = src
+	}
+	for i, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.path), rendered[i], 0o644); err != nil {
+			return err
+		}
+		fmt.Println("wrote", f.path)
+	}
+	if err := writeMigration(dir, res, force); err != nil {
+		return err
+	}
+	if err := insertAbove(filepath.Join(dir, "main.go"), routesMarker, "handler.New"+res.Name+"Handler(", wiringTmpl, res); err != nil {
+		return err
+	}
+	return insertAbove(filepath.Join(dir, "snapshot_test.go"), snapshotsMarker, `route: "`+res.Path+`/"`, snapshotsTmpl, res)
+}
+
+// writeMigration adds internal/migrations/sql/NNNN_create_<table>.sql with
+// the next free number, or rewrites the existing one under -force.
+func writeMigration(dir string, res resource, force bool) error {
+	sqlDir := filepath.Join(dir, "internal", "migrations", "sql")
+	existing, err := filepath.Glob(filepath.Join(sqlDir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	suffix := "_create_" + res.Table + ".sql"
+	next := 1
+	for _, path := range existing {
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, suffix) {
+			if !force {
+				return fmt.Errorf("migration %s already exists (use -force to overwrite)", name)
+			}
+			return os.WriteFile(path, []byte(res.Schema()+"\n"), 0o644)
+		}
+		if n, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0]); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	rel := filepath.Join("internal", "migrations", "sql", fmt.Sprintf("%04d", next)+suffix)
+	if err := os.WriteFile(filepath.Join(dir, rel), []byte(res.Schema()+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Println("wrote", rel, "(applied with -migrate)")
+	return nil
+}
+
+func render(t *template.Template, res resource) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, res); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go: %w", err)
+	}
+	return src, nil
+}
+
+// insertAbove inserts tmpl, executed for res, above the marker line of the
+// Go file at path, once: a file already containing guard is left alone, so
+// re-running the generator keeps existing wiring.
+func insertAbove(path, marker, guard string, tmpl *template.Template, res resource) error {
+	name := filepath.Base(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte(guard)) {
+		fmt.Println(name, "already covers", res.Path)
+		return nil
+	}
+	var snippet bytes.Buffer
+	if err := tmpl.Execute(&snippet, res); err != nil {
+		return err
+	}
+	i := bytes.Index(data, []byte(marker))
+	if i < 0 {
+		fmt.Printf("%s has no %q line; add this:\n\n%s\n", name, marker, snippet.String())
+		return nil
+	}
+	lineStart := bytes.LastIndexByte(data[:i], '\n') + 1
+	out := append(append(append([]byte{}, data[:lineStart]...), snippet.Bytes()...), data[lineStart:]...)
+	if out, err = format.Source(out); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return err
+	}
+	fmt.Println("added", res.Path, "to", name)
+	return nil
+}
+
+var funcs = template.FuncMap{
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+}
+
+func parse(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(funcs).Parse(text))
+}
+
+var modelTmpl = parse("model", `package model
+
+import "time"
+
+type {{.Name}} struct {
+	ID string `+"`json:\"id\" bson:\"_id\" readonly:\"true\"`"+` // assigned by the service on create
+{{- range .Fields}}
+	{{.Name}} {{.Type}} `+"`json:\"{{.JSON}}\" bson:\"{{.JSON}}\"{{if .Validate}} {{$.Tag}}:\"{{.Validate}}\"{{end}}`"+`
+{{- end}}
+	CreatedAt time.Time `+"`json:\"created_at\" bson:\"created_at\" readonly:\"true\"`"+` // set by the service on create
+	UpdatedAt time.Time `+"`json:\"updated_at\" bson:\"updated_at\" readonly:\"true\"`"+` // set by the service on every write
+	TenantID string `+"`json:\"tenant_id,omitempty\" bson:\"tenant_id,omitempty\" readonly:\"true\"`"+` // set by the repository when tenancy is enabled
+	OwnerID string `+"`json:\"owner_id,omitempty\" bson:\"owner_id,omitempty\" readonly:\"true\"`"+` // the account that created it, set by the service on create
+}
+
+func ({{.Recv}} {{.Name}}) GetID() string { return {{.Recv}}.ID }
+
+func ({{.Recv}} *{{.Name}}) SetID(id string) { {{.Recv}}.ID = id }
+
+func ({{.Recv}} {{.Name}}) LastModified() time.Time { return {{.Recv}}.UpdatedAt }
+
+func ({{.Recv}} *{{.Name}}) Touch(t time.Time) { {{.Recv}}.UpdatedAt = t }
+
+func ({{.Recv}} {{.Name}}) Created() time.Time { return {{.Recv}}.CreatedAt }
+
+func ({{.Recv}} *{{.Name}}) SetCreated(t time.Time) { {{.Recv}}.CreatedAt = t }
+
+func ({{.Recv}} {{.Name}}) Tenant() string { return {{.Recv}}.TenantID }
+
+func ({{.Recv}} *{{.Name}}) SetTenant(id string) { {{.Recv}}.TenantID = id }
+
+func ({{.Recv}} {{.Name}}) Owner() string { return {{.Recv}}.OwnerID }
+
+func ({{.Recv}} *{{.Name}}) SetOwner(id string) { {{.Recv}}.OwnerID = id }
+`)
+
+var repositoryTmpl = parse("repository", `package repository
+
+import "{{.Module}}/internal/model"
+
+type {{.Name}}Repository = CrudRepository[model.{{.Name}}]
+
+// New{{.Name}}Repository returns an empty in-memory repository of its own.
+func New{{.Name}}Repository() {{.Name}}Repository {
+	return NewMemoryRepository[model.{{.Name}}]({{quote .Singular}})
+}
+`)
+
+var serviceTmpl = parse("service", `package service
+
+import (
+	"{{.Module}}/internal/audit"
+	"{{.Module}}/internal/clock"
+	"{{.Module}}/internal/domain"
+	"{{.Module}}/internal/events"
+	"{{.Module}}/internal/idgen"
+	"{{.Module}}/internal/model"
+	"{{.Module}}/internal/repository"
+	"{{.Module}}/internal/trash"
+)
+
+type {{.Name}}Service = CrudService[model.{{.Name}}]
+
+// New{{.Name}}Service adds no hooks; set BeforeCreate and BeforeUpdate to
+// normalise or validate {{.Singular}} records beyond their struct tags, and
+// Merge to keep the fields an update leaves out.
+func New{{.Name}}Service({{.Var}}Repo repository.{{.Name}}Repository, uow repository.UnitOfWork, bus *events.Bus, publisher domain.EventPublisher, rec *audit.Recorder, bin *trash.Bin, clk clock.Clock, ids idgen.Generator) {{.Name}}Service {
+	return NewCrudService[model.{{.Name}}]({{.Var}}Repo, uow, bus, publisher, rec, bin, clk, ids, {{quote .Kebab}}, Hooks[model.{{.Name}}]{})
+}
+`)
+
+var domainTmpl = parse("domain", `package domain
+
+import "{{.Module}}/internal/model"
+
+// The events of the {{.Singular}} resource.
+type (
+	{{.Name}}Created = Created[model.{{.Name}}]
+	{{.Name}}Updated = Updated[model.{{.Name}}]
+	{{.Name}}Deleted = Deleted[model.{{.Name}}]
+)
+`)
+
+var handlerTmpl = parse("handler", `package handler
+
+import (
+	"{{.Module}}/internal/model"
+	"{{.Module}}/internal/service"
+)
+
+type {{.Name}}Handler = CrudHandler[model.{{.Name}}, *model.{{.Name}}]
+
+// New{{.Name}}Handler serves the {{.Singular}} CRUD routes.
+//
+// @Resource {{.Path}} model.{{.Name}}
+// @Tags {{.Name}}
+// @Security none
+func New{{.Name}}Handler({{.Var}}Service service.{{.Name}}Service) *{{.Name}}Handler {
+	return NewCrudHandler[model.{{.Name}}]({{.Var}}Service, {{quote .Name}})
+}
+`)
+
+// handlerTestShared is the framework-independent part of the generated tests;
+// each framework template defines newTestRouter and serve.
+const handlerTestShared = `
+func Test{{.Name}}Handler(t *testing.T) {
+	valid := ` + "`{{.SampleJSON}}`" + `
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"list", http.MethodGet, "{{.Path}}/", "", http.StatusOK},
+		{"create", http.MethodPost, "{{.Path}}/", valid, http.StatusCreated},
+		{"create with malformed body", http.MethodPost, "{{.Path}}/", "{", http.StatusBadRequest},
+{{- with .FirstRequired}}
+		{"create without {{.JSON}}", http.MethodPost, "{{$.Path}}/", "{}", http.StatusBadRequest},
+{{- end}}
+		{"get missing", http.MethodGet, "{{.Path}}/missing", "", http.StatusNotFound},
+		{"update missing", http.MethodPut, "{{.Path}}/missing", valid, http.StatusNotFound},
+		{"delete missing", http.MethodDelete, "{{.Path}}/missing", "", http.StatusNotFound},
+	}
+
+	router := new{{.Name}}TestRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve{{.Name}}(router, tt.method, tt.path, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func Test{{.Name}}HandlerLifecycle(t *testing.T) {
+	router := new{{.Name}}TestRouter()
+
+	rec := serve{{.Name}}(router, http.MethodPost, "{{.Path}}/", ` + "`{{.SampleJSON}}`" + `)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d: %s", rec.Code, rec.Body)
+	}
+	var created model.{{.Name}}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.ID == "" {
+		t.Fatalf("create returned %s (%v)", rec.Body, err)
+	}
+	path := "{{.Path}}/" + created.ID
+
+	steps := []struct {
+		method string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPut, ` + "`{{.SampleJSON}}`" + `, http.StatusOK},
+		{http.MethodDelete, "", http.StatusNoContent},
+		{http.MethodGet, "", http.StatusNotFound},
+	}
+	for _, s := range steps {
+		if rec := serve{{.Name}}(router, s.method, path, s.body); rec.Code != s.want {
+			t.Fatalf("%s %s = %d, want %d: %s", s.method, path, rec.Code, s.want, rec.Body)
+		}
+	}
+}
+`
+
+var clientTmpl = parse("client", `package client
+
+import "{{.Module}}/internal/model"
+
+// {{.Name}} is model.{{.Name}}, named here for consumers outside this module.
+type {{.Name}} = model.{{.Name}}
+
+type {{.PluralName}}Client = Resource[{{.Name}}]
+
+// {{.PluralName}} returns the client of the {{.Singular}} routes.
+func (c *Client) {{.PluralName}}() *{{.PluralName}}Client {
+	return NewResource[{{.Name}}](c, {{quote .Path}})
+}
+`)
+
+var handlerTestTmpl = map[string]*template.Template{
+	"gin": parse("gin test", `package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"{{.Module}}/internal/model"
+	"{{.Module}}/internal/repository"
+	"{{.Module}}/internal/service"
+)
+
+func new{{.Name}}TestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	New{{.Name}}Handler(service.New{{.Name}}Service(repository.New{{.Name}}Repository(), repository.NewNoopUnitOfWork(), nil, nil, nil, nil, nil, nil)).Register(router.Group("{{.Path}}"))
+	return router
+}
+
+func serve{{.Name}}(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+`+handlerTestShared),
+	"echo": parse("echo test", `package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"{{.Module}}/internal/model"
+	"{{.Module}}/internal/repository"
+	"{{.Module}}/internal/service"
+	"{{.Module}}/internal/util"
+)
+
+func new{{.Name}}TestRouter() *echo.Echo {
+	e := echo.New()
+	e.Validator = util.NewCustomValidator()
+	New{{.Name}}Handler(service.New{{.Name}}Service(repository.New{{.Name}}Repository(), repository.NewNoopUnitOfWork(), nil, nil, nil, nil, nil, nil)).Register(e.Group("{{.Path}}"))
+	return e
+}
+
+func serve{{.Name}}(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+`+handlerTestShared),
+}
+
+// snapshotsTmpl covers every route of the resource: it creates an item, whose
+// ID the later steps address, then deletes and restores it.
+var snapshotsTmpl = parse("snapshots", `		// {{.Name}} routes (generated by cmd/scaffold)
+		{name: "{{slice .Path 1}}-create", method: http.MethodPost, route: "{{.Path}}/", body: `+"`{{.SampleJSON}}`"+`, keep: map[string]string{"id": "id"}},
+		{name: "{{slice .Path 1}}-list", method: http.MethodGet, route: "{{.Path}}/"},
+		{name: "{{slice .Path 1}}-get", method: http.MethodGet, route: "{{.Path}}/:id"},
+		{name: "{{slice .Path 1}}-stream", method: http.MethodGet, route: "{{.Path}}/stream"},
+		{name: "{{slice .Path 1}}-schema", method: http.MethodGet, route: "{{.Path}}/schema"},
+		{name: "{{slice .Path 1}}-update", method: http.MethodPut, route: "{{.Path}}/:id", body: `+"`{{.SampleJSON}}`"+`},
+		{name: "{{slice .Path 1}}-delete", method: http.MethodDelete, route: "{{.Path}}/:id"},
+		{name: "{{slice .Path 1}}-undo-delete", method: http.MethodPost, route: "{{.Path}}/:id/undo-delete"},
+
+`)
+
+var wiringTmpl = parse("wiring", `	// {{.Name}} routes (generated by cmd/scaffold)
+	{{.Var}}Repo := newRepository(db, {{quote .Table}}, {{quote .Singular}}, repository.New{{.Name}}Repository)
+	if cfg.Tenancy.Enabled {
+		{{.Var}}Repo, err = newTenantRepository(context.Background(), cfg.Tenancy, db, {{.Var}}Repo, {{quote .Table}}, {{quote .Singular}})
+		if err != nil {
+			log.Fatalf("tenancy: %v", err)
+		}
+	}
+	if cfg.Resilience.Enabled {
+		{{.Var}}Repo = repository.NewResilientRepository({{.Var}}Repo, breakers, "repository:{{.Table}}")
+	}
+	{{.Var}}Quota := repository.NewQuotaRepository({{.Var}}Repo, db.uow, db.locks, {{quote .Table}}, planQuota({{quote .Table}}), nil)
+	{{.Var}}Repo = {{.Var}}Quota
+	if cfg.Tenancy.Enabled && cfg.Tenancy.Audit {
+		{{.Var}}Repo = repository.NewTenantAuditRepository({{.Var}}Repo, {{quote .Singular}}, cfg.Tenancy.Isolation == tenant.IsolationSchema)
+	}
+	{{.Var}}Service := service.New{{.Name}}Service({{.Var}}Repo, db.uow, bus, publisher, auditor, bin, clk, ids)
+	handler.New{{.Name}}Handler({{.Var}}Service).Register({{if eq .Framework "gin"}}router{{else}}e{{end}}.Group({{quote .Path}}, groupMiddleware({{quote .Table}})...))
+	archived = append(archived, archive.Resource({{quote .Table}}, {{.Var}}Service))
+	published = append(published, domain.Events[model.{{.Name}}]({{quote .Singular}})...)
+	seeded = append(seeded, fixtures.Resource({{quote .Table}}, {{.Var}}Service))
+	metered = append(metered, service.Metered({{quote .Table}}, {{.Var}}Quota))
+	claimed = append(claimed, {{.Var}}Service.Transfer)
+
+`)
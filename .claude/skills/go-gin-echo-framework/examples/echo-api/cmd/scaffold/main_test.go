@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// TestGeneratedResourceBuilds scaffolds a resource into a copy of the module
+// and checks that, with the OpenAPI spec regenerated, the module builds,
+// vets and passes its tests, the generated handler tests among them.
+func TestGeneratedResourceBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and tests a copy of the module")
+	}
+	module, framework, err := readModule("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	copyModule(t, "../..", dir, path.Base(module))
+
+	var fields []field
+	for _, v := range []string{"Customer:string:required", "Total:float64:gte=0", "Paid:bool"} {
+		f, err := parseField(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, f)
+	}
+	if err := generate(dir, newResource(module, framework, "Order", "", fields), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The steps main prints, then the gates; -short keeps the copy of this
+	// test from scaffolding again
+	for _, step := range []struct {
+		env  []string
+		args []string
+	}{
+		{nil, []string{"generate", "./internal/openapi"}},
+		{[]string{"GOLDEN_UPDATE=1"}, []string{"test", "-run", "TestResponseSnapshots", "."}},
+		{nil, []string{"build", "./..."}},
+		{nil, []string{"vet", "./..."}},
+		{nil, []string{"test", "-short", "./..."}},
+	} {
+		cmd := exec.Command("go", step.args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), step.env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %v: %v\n%s", step.args, err, out)
+		}
+	}
+}
+
+// copyModule copies the module at src to dst, leaving out its binary, bin.
+func copyModule(t *testing.T, src, dst, bin string) {
+	t.Helper()
+	err := filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil || rel == bin {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
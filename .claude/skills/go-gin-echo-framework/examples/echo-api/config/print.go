@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/your-username/echo-api/internal/secret"
+)
+
+// Redacted flattens the configuration into dotted keys (e.g. "server.port")
+// with secrets replaced, suitable for logging or diagnostics endpoints.
+func (c *Config) Redacted() map[string]string {
+	out := map[string]string{}
+	flatten(reflect.ValueOf(*c), "", true, out)
+	return out
+}
+
+func flatten(v reflect.Value, prefix string, redact bool, out map[string]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		key := prefix + name
+		fv := v.Field(i)
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			flatten(fv, key+".", redact, out)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < fv.Len(); j++ {
+				flatten(fv.Index(j), fmt.Sprintf("%s.%d.", key, j), redact, out)
+			}
+		case fv.Kind() == reflect.Map && fv.Type().Elem().Kind() == reflect.Struct:
+			for _, mk := range fv.MapKeys() {
+				flatten(fv.MapIndex(mk), fmt.Sprintf("%s.%v.", key, mk), redact, out)
+			}
+		case fv.Kind() == reflect.Map:
+			for _, mk := range fv.MapKeys() {
+				out[fmt.Sprintf("%s.%v", key, mk)] = format(fv.MapIndex(mk), redact)
+			}
+		default:
+			out[key] = format(fv, redact)
+		}
+	}
+}
+
+// format prints a setting. Secrets print redacted unless redact is false,
+// which only diff uses, to notice a changed secret without printing it.
+func format(v reflect.Value, redact bool) string {
+	if s, ok := v.Interface().(secret.Secret); ok && !redact {
+		return s.Reveal()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/redact"
+	"github.com/your-username/echo-api/internal/secret"
+)
+
+func TestRedactedHidesEverySecret(t *testing.T) {
+	c := Default()
+	c.Database.URL = "postgres://app:hunter2@db/app"
+	c.Auth.JWTSecret = "hunter2-hunter2-hunter2-hunter2!"
+	c.Auth.Providers = map[string]auth.OIDCProvider{"google": {ClientSecret: "hunter2"}}
+	c.MQTT.Clients = map[string]secret.Secret{"sensor": "hunter2-hunter2!"}
+
+	lines := c.Redacted()
+	for k, v := range lines {
+		if strings.Contains(v, "hunter2") {
+			t.Errorf("%s = %s", k, v)
+		}
+	}
+	for _, k := range []string{"database.url", "auth.jwt_secret", "auth.providers.google.client_secret", "mqtt.clients.sensor"} {
+		if lines[k] != secret.Redacted {
+			t.Errorf("%s = %q, want %s", k, lines[k], secret.Redacted)
+		}
+	}
+}
+
+// TestDefaultsRedactIssuedCredentials checks that by default the recorder
+// and the payload log keep the credentials the service hands out, and the
+// signatures of blob URLs, out of the URLs and bodies they keep.
+func TestDefaultsRedactIssuedCredentials(t *testing.T) {
+	c := Default()
+	for name, fields := range map[string][]string{"recorder": c.Recorder.RedactFields, "payload_log": c.PayloadLog.Redact} {
+		r := redact.New(nil, fields)
+		if got := r.URL("/blobs/a.gif?expires=1700000000&signature=hunter2"); strings.Contains(got, "hunter2") {
+			t.Errorf("%s: url = %s", name, got)
+		}
+		body := `{"access_token":"hunter2","refresh_token":"hunter2","guest_token":"hunter2","mfa_token":"hunter2","code":"hunter2"}`
+		if got := r.Body("application/json", body); strings.Contains(got, "hunter2") {
+			t.Errorf("%s: body = %s", name, got)
+		}
+	}
+}
+
+func TestDiffNoticesChangedSecrets(t *testing.T) {
+	a, b := Default(), Default()
+	b.Auth.JWTSecret = a.Auth.JWTSecret + "-rotated"
+	if got := diff(a, b); !slices.Equal(got, []string{"auth.jwt_secret"}) {
+		t.Fatalf("diff = %v", got)
+	}
+}
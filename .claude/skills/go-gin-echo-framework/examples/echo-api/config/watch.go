@@ -0,0 +1,173 @@
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadable lists the setting prefixes that components pick up at runtime.
+// Changes to anything else are applied to Current() but only take effect after a restart.
+var reloadable = []string{"compatibility", "logging.level", "rate_limit.limits.", "transforms.", "envelope.", "feature_flags.", "flag_rules.rules.", "plans.", "auth.max_api_keys", "custom_fields."}
+
+// debounce coalesces the burst of events editors emit when saving a file.
+const debounce = 200 * time.Millisecond
+
+// Watcher reloads the configuration when its file changes and notifies
+// subscribers. An invalid file is logged and ignored, keeping the last good
+// configuration in place.
+type Watcher struct {
+	args []string
+
+	mu      sync.RWMutex
+	current *Config
+	subs    []func(old, new *Config)
+
+	fsw  *fsnotify.Watcher
+	done chan struct{}
+}
+
+// NewWatcher starts watching initial.File(). args must be the same
+// arguments initial was loaded with so flags keep their precedence on reload.
+// When no config file is in use the watcher never fires.
+func NewWatcher(initial *Config, args []string) (*Watcher, error) {
+	w := &Watcher{args: args, current: initial, done: make(chan struct{})}
+	if initial.File() == "" {
+		close(w.done)
+		return w, nil
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory: editors and ConfigMap mounts replace the file
+	// rather than writing to it, which would drop a watch on the file itself.
+	if err := fsw.Add(filepath.Dir(initial.File())); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	w.fsw = fsw
+	go w.loop(filepath.Clean(initial.File()))
+	return w, nil
+}
+
+// Current returns the latest valid configuration.
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe registers fn to be called after every successful reload.
+// Callbacks run sequentially on the watcher goroutine and must not block.
+func (w *Watcher) Subscribe(fn func(old, new *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Close stops watching. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	if w.fsw == nil {
+		return nil
+	}
+	err := w.fsw.Close()
+	<-w.done
+	return err
+}
+
+func (w *Watcher) loop(path string) {
+	defer close(w.done)
+
+	var timer *time.Timer
+	for {
+		select {
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) != path || ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(debounce, w.reload)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("WARNING: config watcher: %v", err)
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	next, err := Load(w.args)
+	if err != nil {
+		log.Printf("WARNING: config reload rejected, keeping previous configuration: %v", err)
+		return
+	}
+
+	w.mu.Lock()
+	old := w.current
+	w.current = next
+	subs := append([]func(old, new *Config){}, w.subs...)
+	w.mu.Unlock()
+
+	changed := diff(old, next)
+	if len(changed) == 0 {
+		return
+	}
+	var restart []string
+	for _, key := range changed {
+		if !isReloadable(key) {
+			restart = append(restart, key)
+		}
+	}
+	log.Printf("config reloaded, changed: %s", strings.Join(changed, ", "))
+	if len(restart) > 0 {
+		log.Printf("WARNING: config changes require a restart to take effect: %s", strings.Join(restart, ", "))
+	}
+
+	for _, fn := range subs {
+		fn(old, next)
+	}
+}
+
+// diff returns the sorted keys whose values differ between a and b.
+func diff(a, b *Config) []string {
+	before, after := map[string]string{}, map[string]string{}
+	flatten(reflect.ValueOf(*a), "", false, before)
+	flatten(reflect.ValueOf(*b), "", false, after)
+
+	var keys []string
+	for k, v := range after {
+		if before[k] != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isReloadable(key string) bool {
+	for _, prefix := range reloadable {
+		if key == prefix || strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,5 @@
+package main
+
+// Protobuf and gRPC stubs in internal/pb are generated from proto/ with buf
+// and the protoc-gen-go and protoc-gen-go-grpc plugins on PATH.
+//go:generate buf generate
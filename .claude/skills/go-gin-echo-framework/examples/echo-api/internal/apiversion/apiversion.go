@@ -0,0 +1,168 @@
+// Package apiversion versions the HTTP API by path: version N of a resource
+// is served under /api/vN by handlers of its own, over the same services as
+// every other version. Requests to the unversioned /api prefix are routed
+// to the version their Accept header asks for (application/json; version=2).
+//
+// A version being phased out says so on every response: Deprecation (RFC
+// 9745) from the date it is deprecated, Sunset (RFC 8594) with the date it
+// will be retired and a Link to the same resource in its successor. Past
+// its sunset it answers 410 Gone.
+package apiversion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+)
+
+// Prefix is the root of the versioned routes.
+const Prefix = "/api"
+
+// Version is a major version of the API, 1 for /api/v1.
+type Version int
+
+func (v Version) String() string { return "v" + strconv.Itoa(int(v)) }
+
+// Path is the root of the version's routes, e.g. /api/v1.
+func (v Version) Path() string { return Prefix + "/" + v.String() }
+
+// Parse parses a version as written in paths ("v2") or media types ("2").
+func Parse(s string) (Version, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid API version %q (want v1, v2, ...)", s)
+	}
+	return Version(n), nil
+}
+
+// FromPath returns the version of a path under /api/vN.
+func FromPath(path string) (Version, bool) {
+	rest, ok := strings.CutPrefix(path, Prefix+"/")
+	if !ok {
+		return 0, false
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	if !strings.HasPrefix(segment, "v") {
+		return 0, false
+	}
+	v, err := Parse(segment)
+	return v, err == nil
+}
+
+// FromAccept returns the version parameter of the first media range in an
+// Accept header that has one, e.g. 2 for "application/json; version=2".
+func FromAccept(accept string) (Version, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["version"] == "" {
+			continue
+		}
+		v, err := Parse(params["version"])
+		return v, err == nil
+	}
+	return 0, false
+}
+
+// Negotiate returns the version r asks for by its path, else by its Accept
+// header, else fallback.
+func Negotiate(r *http.Request, fallback Version) Version {
+	if v, ok := FromPath(r.URL.Path); ok {
+		return v
+	}
+	if v, ok := FromAccept(r.Header.Get("Accept")); ok {
+		return v
+	}
+	return fallback
+}
+
+// Rewrite routes requests under the unversioned /api prefix to the served
+// version they negotiate, fallback if they name none, by rewriting their
+// path to /api/vN before routing. A version that is not served answers 406
+// Not Acceptable.
+func Rewrite(served []Version, fallback Version) func(http.Handler) http.Handler {
+	names := make([]string, len(served))
+	for i, v := range served {
+		names[i] = v.String()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rest, ok := strings.CutPrefix(r.URL.Path, Prefix+"/")
+			if _, versioned := FromPath(r.URL.Path); !ok || versioned {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept")
+			v := Negotiate(r, fallback)
+			if !slices.Contains(served, v) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusNotAcceptable)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": fmt.Sprintf("API version %s is not served; ask for %s", v, strings.Join(names, " or ")),
+				})
+				return
+			}
+			// In place, like transform.Middleware, for routers that hold the request already
+			r.URL.Path = v.Path() + "/" + rest
+			r.URL.RawPath = ""
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Lifecycle is the deprecation schedule of a version; the zero value is a
+// current version.
+type Lifecycle struct {
+	Deprecated time.Time `yaml:"deprecated"` // from when clients are told to move on
+	Sunset     time.Time `yaml:"sunset"`     // when the version stops being served
+}
+
+// Validate reports a sunset that comes before the deprecation.
+func (l Lifecycle) Validate() error {
+	if !l.Sunset.IsZero() && l.Sunset.Before(l.Deprecated) {
+		return errors.New("sunset must not be before deprecated")
+	}
+	return nil
+}
+
+// Policy announces the lifecycle of one version on its responses.
+type Policy struct {
+	version   Version
+	successor Version
+	lifecycle Lifecycle
+	clock     clock.Clock
+}
+
+// New returns the policy of version v, which clients leave for successor
+// (v itself if it is the latest), telling the time by clk (nil is the
+// system clock).
+func New(v, successor Version, l Lifecycle, clk clock.Clock) *Policy {
+	return &Policy{version: v, successor: successor, lifecycle: l, clock: clock.OrSystem(clk)}
+}
+
+// Apply sets the lifecycle headers on w and returns the status and message
+// to reject r with once the version is retired, or 0 to let it through.
+func (p *Policy) Apply(w http.ResponseWriter, r *http.Request) (int, string) {
+	l, h := p.lifecycle, w.Header()
+	if !l.Deprecated.IsZero() {
+		h.Set("Deprecation", "@"+strconv.FormatInt(l.Deprecated.Unix(), 10))
+		if p.successor != p.version {
+			rest := strings.TrimPrefix(r.URL.Path, p.version.Path())
+			h.Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", p.successor.Path(), rest))
+		}
+	}
+	if !l.Sunset.IsZero() {
+		h.Set("Sunset", l.Sunset.UTC().Format(http.TimeFormat))
+		if !p.clock.Now().Before(l.Sunset) {
+			return http.StatusGone, fmt.Sprintf("API %s was retired on %s; use %s", p.version, l.Sunset.UTC().Format(time.DateOnly), p.successor)
+		}
+	}
+	return 0, ""
+}
@@ -0,0 +1,100 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name, path, accept string
+		want               Version
+	}{
+		{"path", "/api/v1/users", "", 1},
+		{"path wins over Accept", "/api/v1/users", "application/json; version=2", 1},
+		{"Accept", "/api/users", "application/json; version=1", 1},
+		{"Accept with v", "/api/users", "application/xml;q=0.5, application/json; version=v1", 1},
+		{"fallback", "/api/users", "application/json", 3},
+		{"not a version segment", "/api/videos", "", 3},
+		{"malformed", "/api/users", "application/json; version=one", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := Negotiate(r, 3); got != tt.want {
+				t.Fatalf("Negotiate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteRoutesUnversionedRequests(t *testing.T) {
+	var routed string
+	h := Rewrite([]Version{1, 2}, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routed = r.URL.Path
+	}))
+	tests := []struct {
+		path, accept string
+		want         string // path routed, or "" when rejected
+	}{
+		{"/api/users/42", "", "/api/v2/users/42"},
+		{"/api/users/42", "application/json; version=1", "/api/v1/users/42"},
+		{"/api/v1/users/42", "application/json; version=2", "/api/v1/users/42"},
+		{"/users/42", "application/json; version=1", "/users/42"},
+		{"/api/users/42", "application/json; version=3", ""},
+	}
+	for _, tt := range tests {
+		routed = ""
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if routed != tt.want {
+			t.Errorf("%s (%s) routed to %q, want %q", tt.path, tt.accept, routed, tt.want)
+		}
+		if tt.want == "" && w.Code != http.StatusNotAcceptable {
+			t.Errorf("%s (%s) answered %d, want 406", tt.path, tt.accept, w.Code)
+		}
+	}
+}
+
+func TestPolicyAnnouncesTheLifecycle(t *testing.T) {
+	deprecated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := deprecated.AddDate(0, 6, 0)
+	clk := clock.NewFake(deprecated.AddDate(0, 1, 0))
+	p := New(1, 2, Lifecycle{Deprecated: deprecated, Sunset: sunset}, clk)
+
+	w := httptest.NewRecorder()
+	if status, msg := p.Apply(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil)); status != 0 {
+		t.Fatalf("rejected with %d %s before the sunset", status, msg)
+	}
+	for name, want := range map[string]string{
+		"Deprecation": "@1767225600",
+		"Sunset":      "Wed, 01 Jul 2026 00:00:00 GMT",
+		"Link":        `</api/v2/users/42>; rel="successor-version"`,
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	clk.Advance(sunset.Sub(clk.Now()))
+	if status, _ := p.Apply(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil)); status != http.StatusGone {
+		t.Fatalf("answered %d at the sunset, want 410", status)
+	}
+}
+
+func TestCurrentVersionsSetNoHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(2, 2, Lifecycle{}, nil).Apply(w, httptest.NewRequest(http.MethodGet, "/api/v2/users", nil))
+	if len(w.Header()) != 0 {
+		t.Fatalf("headers %v on a current version", w.Header())
+	}
+}
@@ -0,0 +1,18 @@
+package apiversion
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware applies p to the routes of one version, answering for a
+// retired version before any handler runs.
+func Middleware(p *Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if status, msg := p.Apply(c.Response(), c.Request()); status != 0 {
+				return c.JSON(status, map[string]string{"error": msg})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package archive exports every resource collection to a portable archive
+// and imports one back, to move data between deployments and between
+// database backends. An archive is a zip of one NDJSON file per collection,
+// <name>.ndjson, and manifest.json describing them with the schema version
+// of their items. Items go through the services on both ends, so an import
+// runs their hooks and is audited and published like any other write.
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/schema"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// Format is the version of the archive layout written by Export. Import
+// reads archives of this format and older.
+const Format = 1
+
+// MediaType is the media type of an archive.
+const MediaType = "application/zip"
+
+const manifestFile = "manifest.json"
+
+// ErrInvalid is wrapped by Import errors about the archive itself: not a
+// zip, no or a malformed manifest, unknown collections, items that do not
+// decode, and schema versions newer than the server's.
+var ErrInvalid = errors.New("invalid archive")
+
+// Strategy decides what Import does with an item whose ID already exists.
+type Strategy string
+
+const (
+	Skip      Strategy = "skip"      // keep the existing item
+	Overwrite Strategy = "overwrite" // replace it with the imported one
+	Merge     Strategy = "merge"     // update the fields the imported item has, keeping the others
+)
+
+// Strategies lists the valid strategies, the default first.
+var Strategies = []Strategy{Skip, Overwrite, Merge}
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format      int          `json:"format"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Collections []Collection `json:"collections"`
+}
+
+// Collection is a manifest entry.
+type Collection struct {
+	Name    string `json:"name"`    // e.g. products
+	File    string `json:"file"`    // e.g. products.ndjson
+	Version int    `json:"version"` // schema version of the items
+	Items   int    `json:"items"`
+}
+
+// Result counts what Import did with the items of one collection.
+type Result struct {
+	Collection  string `json:"collection"`
+	Created     int    `json:"created"`
+	Skipped     int    `json:"skipped"`
+	Overwritten int    `json:"overwritten"`
+	Merged      int    `json:"merged"`
+}
+
+// Source is a collection Export reads and Import writes; see Resource.
+type Source struct {
+	Name    string // plural resource name, e.g. products
+	Version int    // schema version of its items (see package schema)
+	stream  func(ctx context.Context, fn func(item any) error) error
+	load    func(ctx context.Context, data []byte, version int, strategy Strategy, validate func(any) error, r *Result) error
+}
+
+// Resource is the collection of a CRUD resource, read and written through
+// svc, at the schema version of T.
+func Resource[T model.Entity, P model.EntityPtr[T]](name string, svc service.CrudService[T]) Source {
+	return Source{
+		Name:    name,
+		Version: schema.Version(new(T)),
+		stream: func(ctx context.Context, fn func(item any) error) error {
+			return svc.Stream(ctx, func(item T) error { return fn(item) })
+		},
+		load: func(ctx context.Context, data []byte, version int, strategy Strategy, validate func(any) error, r *Result) error {
+			var item T
+			data, err := schema.UpgradeJSON(data, version, &item)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+			if err := json.Unmarshal(data, &item); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+			id := P(&item).GetID()
+			if id == "" {
+				return fmt.Errorf("%w: item has no id", ErrInvalid)
+			}
+			existing, err := svc.GetByID(ctx, id)
+			switch {
+			case errors.Is(err, service.ErrNotFound):
+				if err := validate(&item); err != nil {
+					return fmt.Errorf("%w: %v", service.ErrInvalid, err)
+				}
+				_, err = svc.Create(ctx, &item)
+				r.Created++
+				return err
+			case err != nil:
+				return err
+			case strategy == Skip:
+				r.Skipped++
+				return nil
+			case strategy == Merge:
+				// The imported fields over the existing item, as in a JSON
+				// merge patch without deletions
+				if item, err = merge(existing, data); err != nil {
+					return err
+				}
+				r.Merged++
+			default:
+				r.Overwritten++
+			}
+			if err := validate(&item); err != nil {
+				return fmt.Errorf("%w: %v", service.ErrInvalid, err)
+			}
+			_, err = svc.Update(ctx, &item)
+			return err
+		},
+	}
+}
+
+// merge decodes data over a copy of existing.
+func merge[T any](existing *T, data []byte) (T, error) {
+	merged := *existing
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return merged, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return merged, nil
+}
+
+// Archive exports and imports the collections of its sources.
+type Archive struct {
+	uow      repository.UnitOfWork
+	validate func(item any) error
+	clock    clock.Clock
+	sources  []Source
+}
+
+// New builds the archive of sources, importing in uow transactions. Items
+// are checked with validate, the framework's request validation, before
+// they are written. The manifest is dated by clk (nil is the system clock).
+func New(uow repository.UnitOfWork, validate func(item any) error, clk clock.Clock, sources ...Source) *Archive {
+	return &Archive{uow: uow, validate: validate, clock: clock.OrSystem(clk), sources: sources}
+}
+
+// Export writes the archive of every collection to w, reading each item
+// as it is written, so memory use does not grow with the collections.
+func (a *Archive) Export(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	m := Manifest{Format: Format, CreatedAt: a.clock.Now().UTC()}
+	for _, s := range a.sources {
+		c := Collection{Name: s.Name, File: s.Name + ".ndjson", Version: s.Version}
+		f, err := zw.Create(c.File)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		err = s.stream(ctx, func(item any) error {
+			c.Items++
+			return enc.Encode(item)
+		})
+		if err != nil {
+			return fmt.Errorf("export %s: %w", s.Name, err)
+		}
+		m.Collections = append(m.Collections, c)
+	}
+	f, err := zw.Create(manifestFile)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Import writes the items of the archive in r, whose length is size, with
+// strategy for those whose ID exists, in one transaction: an error leaves
+// nothing imported (with the in-memory database, the items before it stay).
+// The archive may hold any subset of the collections, at schema versions up
+// to the server's; items of an older version are upgraded as they are read.
+func (a *Archive) Import(ctx context.Context, r io.ReaderAt, size int64, strategy Strategy) ([]Result, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	m, err := readManifest(zr)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]Source, len(m.Collections))
+	for i, c := range m.Collections {
+		j := slices.IndexFunc(a.sources, func(s Source) bool { return s.Name == c.Name })
+		if j < 0 {
+			return nil, fmt.Errorf("%w: unknown collection %q", ErrInvalid, c.Name)
+		}
+		if c.Version > a.sources[j].Version {
+			return nil, fmt.Errorf("%w: %s are at schema version %d, newer than this server's %d", ErrInvalid, c.Name, c.Version, a.sources[j].Version)
+		}
+		sources[i] = a.sources[j]
+	}
+
+	var results []Result
+	err = a.uow.Do(ctx, func(ctx context.Context) error {
+		for i, c := range m.Collections {
+			r := Result{Collection: c.Name}
+			if err := a.load(ctx, zr, c, sources[i], strategy, &r); err != nil {
+				return err
+			}
+			results = append(results, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// load imports the items of the collection c from zr.
+func (a *Archive) load(ctx context.Context, zr *zip.Reader, c Collection, s Source, strategy Strategy, r *Result) error {
+	f, err := zr.Open(c.File)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, c.Name, err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	n := 0
+	for {
+		var item json.RawMessage
+		if err := dec.Decode(&item); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %s item %d: %v", ErrInvalid, c.File, n+1, err)
+		}
+		n++
+		if err := s.load(ctx, item, c.Version, strategy, a.validate, r); err != nil {
+			return fmt.Errorf("%s item %d: %w", c.File, n, err)
+		}
+	}
+	if n != c.Items {
+		return fmt.Errorf("%w: %s has %d items, the manifest %d", ErrInvalid, c.File, n, c.Items)
+	}
+	return nil
+}
+
+func readManifest(zr *zip.Reader) (Manifest, error) {
+	var m Manifest
+	f, err := zr.Open(manifestFile)
+	if err != nil {
+		return m, fmt.Errorf("%w: no %s", ErrInvalid, manifestFile)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return m, fmt.Errorf("%w: %s: %v", ErrInvalid, manifestFile, err)
+	}
+	if m.Format < 1 || m.Format > Format {
+		return m, fmt.Errorf("%w: format %d is not supported, only 1 to %d", ErrInvalid, m.Format, Format)
+	}
+	return m, nil
+}
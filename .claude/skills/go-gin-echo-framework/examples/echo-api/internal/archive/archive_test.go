@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+func validate(item any) error {
+	if item.(*model.Product).Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func newMemory() (*Archive, service.CrudService[model.Product]) {
+	uow := repository.NewNoopUnitOfWork()
+	products := service.NewCrudService[model.Product](repository.NewMemoryRepository[model.Product]("product"), uow, nil, nil, nil, nil, nil, nil, "product", service.Hooks[model.Product]{})
+	return New(uow, validate, nil, Resource("products", products)), products
+}
+
+func newSQL(t *testing.T) (*Archive, service.CrudService[model.Product]) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	uow := repository.NewSQLUnitOfWork(db)
+	products := service.NewCrudService[model.Product](repository.NewSQLRepository[model.Product](db, dialect, "products", "product"), uow, nil, nil, nil, nil, nil, nil, "product", service.Hooks[model.Product]{})
+	return New(uow, validate, nil, Resource("products", products)), products
+}
+
+func export(t *testing.T, a *Archive) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := a.Export(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// archiveOf zips files, e.g. a manifest and products.ndjson.
+func archiveOf(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, _ := zw.Create(name)
+		io.WriteString(f, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExportImportAcrossBackends(t *testing.T) {
+	ctx := context.Background()
+	from, src := newMemory()
+	for _, u := range []model.Product{{ID: "a", Name: "Lamp", Price: 10}, {ID: "b", Name: "Desk"}} {
+		if _, err := src.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	to, dst := newSQL(t)
+	r := export(t, from)
+	results, err := to.Import(ctx, r, r.Size(), Skip)
+	if err != nil || len(results) != 1 || results[0] != (Result{Collection: "products", Created: 2}) {
+		t.Fatalf("Import = %+v, %v", results, err)
+	}
+	if u, err := dst.GetByID(ctx, "a"); err != nil || u.Name != "Lamp" || u.Price != 10 {
+		t.Errorf("imported a = %+v, %v", u, err)
+	}
+}
+
+func TestImportStrategies(t *testing.T) {
+	ctx := context.Background()
+	manifest := `{"format": 1, "collections": [{"name": "products", "file": "products.ndjson", "version": 1, "items": 2}]}`
+	items := `{"id": "a", "name": "Lamp Imported"}` + "\n" + `{"id": "b", "name": "Desk"}` + "\n"
+	for strategy, want := range map[Strategy]struct {
+		result Result
+		name   string
+		price  float64
+	}{
+		Skip:      {Result{Collection: "products", Created: 1, Skipped: 1}, "Lamp", 10},
+		Overwrite: {Result{Collection: "products", Created: 1, Overwritten: 1}, "Lamp Imported", 0},
+		Merge:     {Result{Collection: "products", Created: 1, Merged: 1}, "Lamp Imported", 10},
+	} {
+		t.Run(string(strategy), func(t *testing.T) {
+			a, products := newSQL(t)
+			if _, err := products.Create(ctx, &model.Product{ID: "a", Name: "Lamp", Price: 10}); err != nil {
+				t.Fatal(err)
+			}
+			r := archiveOf(t, map[string]string{"manifest.json": manifest, "products.ndjson": items})
+			results, err := a.Import(ctx, r, r.Size(), strategy)
+			if err != nil || len(results) != 1 || results[0] != want.result {
+				t.Fatalf("Import = %+v, %v; want %+v", results, err, want.result)
+			}
+			if u, err := products.GetByID(ctx, "a"); err != nil || u.Name != want.name || u.Price != want.price {
+				t.Errorf("a = %+v, %v", u, err)
+			}
+		})
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	ctx := context.Background()
+	manifest := func(version, items string) string {
+		return `{"format": 1, "collections": [{"name": "products", "file": "products.ndjson", "version": ` + version + `, "items": ` + items + `}]}`
+	}
+	for name, files := range map[string]map[string]string{
+		"no manifest":        {"products.ndjson": ""},
+		"newer format":       {"manifest.json": `{"format": 2}`},
+		"newer version":      {"manifest.json": manifest("2", "0"), "products.ndjson": ""},
+		"unknown collection": {"manifest.json": `{"format": 1, "collections": [{"name": "orders", "file": "orders.ndjson", "version": 1}]}`},
+		"missing file":       {"manifest.json": manifest("1", "0")},
+		"truncated":          {"manifest.json": manifest("1", "2"), "products.ndjson": `{"id": "a", "name": "Lamp"}`},
+		"malformed item":     {"manifest.json": manifest("1", "1"), "products.ndjson": `{"id": "a", "name": `},
+		"item without id":    {"manifest.json": manifest("1", "1"), "products.ndjson": `{"name": "Lamp"}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a, _ := newSQL(t)
+			r := archiveOf(t, files)
+			if _, err := a.Import(ctx, r, r.Size(), Skip); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Import: %v, want ErrInvalid", err)
+			}
+		})
+	}
+
+	t.Run("invalid item", func(t *testing.T) {
+		a, products := newSQL(t)
+		r := archiveOf(t, map[string]string{"manifest.json": manifest("1", "2"), "products.ndjson": `{"id": "a", "name": "Lamp"}` + "\n" + `{"id": "b"}`})
+		_, err := a.Import(ctx, r, r.Size(), Skip)
+		if !errors.Is(err, service.ErrInvalid) || !strings.Contains(err.Error(), "item 2") {
+			t.Fatalf("Import: %v, want ErrInvalid for item 2", err)
+		}
+		if _, err := products.GetByID(ctx, "a"); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("item 1 of a failed import: %v", err)
+		}
+	})
+}
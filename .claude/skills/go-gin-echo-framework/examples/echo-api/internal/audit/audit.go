@@ -0,0 +1,189 @@
+// Package audit keeps a trail of every write made through the service
+// layer: who made it (the authenticated caller), in which request, when,
+// and the item as it was before and after.
+//
+// A Recorder writes each entry to its sinks. The database sink records it
+// in the write's own transaction, so a write is never without its entry and
+// a rolled-back write leaves none; the file and stdout sinks, which cannot
+// take part in the transaction, are written once it commits.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/idgen"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/requestid"
+)
+
+// Sink names in Options.Sinks.
+const (
+	SinkDatabase = "database" // the audit_log table, or memory with the in-memory database
+	SinkFile     = "file"     // JSON lines appended to Options.File
+	SinkStdout   = "stdout"   // JSON lines on standard output, for log collectors
+)
+
+// Sinks lists the sink names.
+var Sinks = []string{SinkDatabase, SinkFile, SinkStdout}
+
+// Options select the sinks.
+type Options struct {
+	Sinks []string `yaml:"sinks"` // any of database, file and stdout; empty disables auditing
+	File  string   `yaml:"file"`  // path of the file sink
+}
+
+// Validate checks the sink names and that the file sink has a path.
+func (o Options) Validate() error {
+	var errs []error
+	for _, s := range o.Sinks {
+		if !slices.Contains(Sinks, s) {
+			errs = append(errs, fmt.Errorf("unknown sink %q (want database, file or stdout)", s))
+		}
+	}
+	if slices.Contains(o.Sinks, SinkFile) && o.File == "" {
+		errs = append(errs, errors.New("file is required by the file sink"))
+	}
+	return errors.Join(errs...)
+}
+
+// Entry is the record of one write.
+type Entry struct {
+	ID        string          `json:"id"`
+	At        time.Time       `json:"at"`
+	Actor     string          `json:"actor"`                // caller as in access logs, e.g. "user-1" or "user-1/key:3f2a"; empty when anonymous
+	RequestID string          `json:"request_id,omitempty"` // X-Request-ID of the request that made the write
+	Resource  string          `json:"resource"`             // singular resource name, e.g. "product"
+	Op        changes.Op      `json:"op"`
+	SubjectID string          `json:"subject_id"`       // ID of the item written
+	Before    json.RawMessage `json:"before,omitempty"` // item before an update or delete
+	After     json.RawMessage `json:"after,omitempty"`  // item after a create or update
+}
+
+// Sink stores entries.
+type Sink interface {
+	Write(ctx context.Context, e Entry) error
+}
+
+// TxSink is a Sink that writes in the repository transaction on the
+// context, failing the write it records if it cannot.
+type TxSink interface {
+	Sink
+	WritesInTransaction()
+}
+
+// Reader is a Sink that can be queried, for GET /admin/audit.
+type Reader interface {
+	Sink
+	List(ctx context.Context, q Query) ([]Entry, error)
+}
+
+// MaxLimit caps the entries of one query.
+const MaxLimit = 1000
+
+// Query selects entries, newest first. Every field that is set must match.
+type Query struct {
+	Resource  string
+	SubjectID string
+	Actor     string
+	Limit     int // at most MaxLimit; 0 is 100
+}
+
+func (q Query) matches(e Entry) bool {
+	return (q.Resource == "" || q.Resource == e.Resource) &&
+		(q.SubjectID == "" || q.SubjectID == e.SubjectID) &&
+		(q.Actor == "" || q.Actor == e.Actor)
+}
+
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return 100
+	}
+	return min(q.Limit, MaxLimit)
+}
+
+// ErrNoReader is returned by List when no sink can be queried.
+var ErrNoReader = errors.New("no audit sink can be queried; enable the database sink")
+
+// Recorder records entries to its sinks. A nil *Recorder records nothing.
+type Recorder struct {
+	sinks []Sink
+	clock clock.Clock
+	ids   idgen.Generator
+}
+
+// NewRecorder records to sinks, stamping entries by clk (nil is the system
+// clock) and identifying them by ids (nil is idgen.Default).
+func NewRecorder(clk clock.Clock, ids idgen.Generator, sinks ...Sink) *Recorder {
+	return &Recorder{sinks: sinks, clock: clock.OrSystem(clk), ids: idgen.OrDefault(ids)}
+}
+
+// Record records op on the item of resource identified by id, with its
+// before and after states (nil for none). The actor and request ID are
+// taken from ctx. An error from a TxSink fails the write; other sinks only
+// log theirs, after the write commits.
+func (r *Recorder) Record(ctx context.Context, resource string, op changes.Op, id string, before, after any) error {
+	if r == nil {
+		return nil
+	}
+	e := Entry{
+		ID:        r.ids.NewID(),
+		At:        r.clock.Now().UTC(),
+		RequestID: requestid.FromContext(ctx),
+		Resource:  resource,
+		Op:        op,
+		SubjectID: id,
+	}
+	if caller := auth.CallerFromContext(ctx); caller != nil {
+		e.Actor = caller.String()
+	}
+	var err error
+	if e.Before, err = snapshot(before); err != nil {
+		return fmt.Errorf("audit %s %s: %w", resource, id, err)
+	}
+	if e.After, err = snapshot(after); err != nil {
+		return fmt.Errorf("audit %s %s: %w", resource, id, err)
+	}
+
+	for _, s := range r.sinks {
+		if _, ok := s.(TxSink); ok {
+			if err := s.Write(ctx, e); err != nil {
+				return fmt.Errorf("failed to record %s %s in the audit log: %w", op, resource, err)
+			}
+			continue
+		}
+		repository.AfterCommit(ctx, func() {
+			if err := s.Write(context.WithoutCancel(ctx), e); err != nil {
+				log.Printf("WARNING: audit entry %s (%s %s %s): %v", e.ID, op, resource, id, err)
+			}
+		})
+	}
+	return nil
+}
+
+// List queries the first sink that is a Reader.
+func (r *Recorder) List(ctx context.Context, q Query) ([]Entry, error) {
+	if r != nil {
+		for _, s := range r.sinks {
+			if reader, ok := s.(Reader); ok {
+				return reader.List(ctx, q)
+			}
+		}
+	}
+	return nil, ErrNoReader
+}
+
+func snapshot(item any) (json.RawMessage, error) {
+	if item == nil {
+		return nil, nil
+	}
+	return json.Marshal(item)
+}
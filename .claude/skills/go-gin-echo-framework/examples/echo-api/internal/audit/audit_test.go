@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+type item struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestRecordWritesWithTheTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	table, stdout := NewSQL(db, dialect), &bytes.Buffer{}
+	rec := NewRecorder(clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)), nil, table, NewStream(stdout))
+	uow := repository.NewSQLUnitOfWork(db)
+
+	ctx = auth.WithCaller(ctx, &auth.Caller{Subject: "user-1"})
+	rollback := errors.New("rollback")
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if err := rec.Record(ctx, "item", changes.OpCreated, "a", nil, item{ID: "a"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		return rec.Record(ctx, "item", changes.OpUpdated, "a", item{ID: "a"}, item{ID: "a", Name: "A"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := rec.List(ctx, Query{Resource: "item", SubjectID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want only the committed update", len(entries))
+	}
+	e := entries[0]
+	if e.Op != changes.OpUpdated || e.Actor != "user-1" || string(e.Before) != `{"id":"a","name":""}` || string(e.After) != `{"id":"a","name":"A"}` {
+		t.Fatalf("entry %+v", e)
+	}
+	if lines := bytes.Count(stdout.Bytes(), []byte("\n")); lines != 1 {
+		t.Fatalf("stdout got %d lines, want the committed update only:\n%s", lines, stdout)
+	}
+}
+
+func TestListQueriesTheFirstReader(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewRecorder(nil, nil, NewStream(&bytes.Buffer{})).List(ctx, Query{}); !errors.Is(err, ErrNoReader) {
+		t.Fatalf("List without a reader: %v", err)
+	}
+
+	mem := NewMemory(2)
+	rec := NewRecorder(nil, nil, NewStream(&bytes.Buffer{}), mem)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := rec.Record(ctx, "item", changes.OpDeleted, id, item{ID: id}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := rec.List(ctx, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].SubjectID != "c" || entries[1].SubjectID != "b" {
+		t.Fatalf("entries %+v, want c and b, newest first", entries)
+	}
+}
@@ -0,0 +1,186 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+// Memory keeps the newest entries in process, for the in-memory database.
+type Memory struct {
+	mu       sync.Mutex
+	entries  []Entry
+	capacity int
+}
+
+// NewMemory keeps up to capacity entries, dropping the oldest first.
+func NewMemory(capacity int) *Memory {
+	return &Memory{capacity: capacity}
+}
+
+func (m *Memory) Write(_ context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) == m.capacity {
+		m.entries = slices.Delete(m.entries, 0, 1)
+	}
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func (m *Memory) List(_ context.Context, q Query) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []Entry{}
+	for i := len(m.entries) - 1; i >= 0 && len(out) < q.limit(); i-- {
+		if q.matches(m.entries[i]) {
+			out = append(out, m.entries[i])
+		}
+	}
+	return out, nil
+}
+
+// Stream writes each entry as one line of JSON to a writer, e.g. os.Stdout.
+type Stream struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewStream(w io.Writer) *Stream {
+	return &Stream{w: w}
+}
+
+func (s *Stream) Write(_ context.Context, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// File appends entries to a file as JSON lines and queries them by reading
+// the file through.
+type File struct {
+	*Stream
+	f *os.File
+}
+
+// OpenFile appends to the file at path, creating it if needed. Close it on
+// shutdown.
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	return &File{Stream: NewStream(f), f: f}, nil
+}
+
+func (f *File) Close() error { return f.f.Close() }
+
+func (f *File) List(_ context.Context, q Query) ([]Entry, error) {
+	var matched []Entry
+	scanner := bufio.NewScanner(io.NewSectionReader(f.f, 0, 1<<62))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("read audit file: %w", err)
+		}
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+	out := []Entry{}
+	for i := len(matched) - 1; i >= 0 && len(out) < q.limit(); i-- {
+		out = append(out, matched[i])
+	}
+	return out, nil
+}
+
+// SQL is the TxSink that records entries in the audit_log table.
+type SQL struct {
+	db      *sql.DB
+	dialect repository.Dialect
+	insert  string
+}
+
+// NewSQL records entries in the audit_log table of db, in the
+// repository.NewSQLUnitOfWork(db) transaction on the context if there is
+// one.
+func NewSQL(db *sql.DB, dialect repository.Dialect) *SQL {
+	p := dialect.Placeholder
+	return &SQL{
+		db:      db,
+		dialect: dialect,
+		insert: fmt.Sprintf("INSERT INTO audit_log (id, at, actor, request_id, resource, op, subject_id, before_state, after_state) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)",
+			p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9)),
+	}
+}
+
+func (s *SQL) Write(ctx context.Context, e Entry) error {
+	_, err := repository.SQLConn(ctx, s.db).ExecContext(ctx, s.insert,
+		e.ID, e.At, e.Actor, e.RequestID, e.Resource, string(e.Op), e.SubjectID, nullable(e.Before), nullable(e.After))
+	return err
+}
+
+// WritesInTransaction marks SQL as a TxSink.
+func (*SQL) WritesInTransaction() {}
+
+func (s *SQL) List(ctx context.Context, q Query) ([]Entry, error) {
+	var where []string
+	var args []any
+	for _, f := range []struct{ column, value string }{{"resource", q.Resource}, {"subject_id", q.SubjectID}, {"actor", q.Actor}} {
+		if f.value != "" {
+			args = append(args, f.value)
+			where = append(where, f.column+" = "+s.dialect.Placeholder(len(args)))
+		}
+	}
+	query := "SELECT id, at, actor, request_id, resource, op, subject_id, before_state, after_state FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, q.limit())
+	query += " ORDER BY at DESC, id DESC LIMIT " + s.dialect.Placeholder(len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the audit log: %w", err)
+	}
+	defer rows.Close()
+	out := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.At, &e.Actor, &e.RequestID, &e.Resource, &e.Op, &e.SubjectID, &before, &after); err != nil {
+			return nil, fmt.Errorf("failed to read the audit log: %w", err)
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		e.At = e.At.UTC()
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func nullable(raw json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(raw), Valid: raw != nil}
+}
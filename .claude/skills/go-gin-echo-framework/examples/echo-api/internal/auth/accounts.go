@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+// ErrAccountNotFound is returned for an account without a login.
+var ErrAccountNotFound = errors.New("account not found")
+
+// AccountInfo is an account as admins see it: its login, without the
+// password hash, and what it holds.
+type AccountInfo struct {
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	TenantID       string     `json:"tenant_id,omitempty"`
+	FailedAttempts int        `json:"failed_attempts"`        // consecutive, since the last login or lockout
+	LockedUntil    *time.Time `json:"locked_until,omitempty"` // set while locked out
+	Roles          []string   `json:"roles"`
+	APIKeys        int        `json:"api_keys"`
+}
+
+// AccountService lets admins manage the accounts that log in, whatever
+// their tenant.
+type AccountService interface {
+	List(ctx context.Context) ([]AccountInfo, error)
+	// Unlock lifts the lockout of an account after too many failed logins.
+	Unlock(ctx context.Context, id string) (*AccountInfo, error)
+	// Delete removes the logins of an account, password, passkeys and
+	// external identities alike, with its API keys and authenticator app,
+	// and refuses to refresh its sessions: it is locked out once the access
+	// tokens it holds expire.
+	Delete(ctx context.Context, id string) error
+}
+
+type accountService struct {
+	creds       repository.CredentialRepository
+	identities  repository.IdentityRepository
+	keys        repository.APIKeyRepository
+	passkeys    repository.PasskeyRepository
+	factors     repository.TOTPRepository
+	uow         repository.UnitOfWork
+	revocations Revocations
+	roles       func() Roles
+	refreshTTL  time.Duration
+	clock       clock.Clock
+}
+
+// NewAccountService manages the accounts of the stores NewAuthService,
+// NewOIDCService, NewAPIKeyService, NewPasskeyService and NewMFAService are
+// given, revoking sessions in the
+// same revocations. roles returns the grants reported with each account;
+// refreshTTL is that of the auth service, the longest a session lasts.
+func NewAccountService(creds repository.CredentialRepository, identities repository.IdentityRepository, keys repository.APIKeyRepository, passkeys repository.PasskeyRepository, factors repository.TOTPRepository, uow repository.UnitOfWork, revocations Revocations, roles func() Roles, refreshTTL time.Duration, clk clock.Clock) AccountService {
+	return &accountService{creds: creds, identities: identities, keys: keys, passkeys: passkeys, factors: factors, uow: uow, revocations: revocations, roles: roles, refreshTTL: refreshTTL, clock: clock.OrSystem(clk)}
+}
+
+func (s *accountService) List(ctx context.Context) ([]AccountInfo, error) {
+	creds, err := s.creds.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	keys, err := s.keys.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	held := map[string]int{}
+	for _, k := range keys {
+		held[k.Owner]++
+	}
+	roles := s.roles()
+	out := make([]AccountInfo, 0, len(creds))
+	for i := range creds {
+		info := s.info(&creds[i], roles)
+		info.APIKeys = held[info.ID]
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Email < out[j].Email })
+	return out, nil
+}
+
+func (s *accountService) Unlock(ctx context.Context, id string) (*AccountInfo, error) {
+	var info AccountInfo
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		cred, err := credentialOf(ctx, s.creds, id)
+		if err != nil {
+			return err
+		}
+		cred.FailedAttempts = 0
+		cred.LockedUntil = time.Time{}
+		if _, err := s.creds.Update(ctx, cred); err != nil {
+			return fmt.Errorf("failed to unlock account: %w", err)
+		}
+		info = s.info(cred, s.roles())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (s *accountService) Delete(ctx context.Context, id string) error {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		cred, err := credentialOf(ctx, s.creds, id)
+		if err != nil {
+			return err
+		}
+		if err := s.creds.Delete(ctx, cred.ID); err != nil {
+			return fmt.Errorf("failed to delete login: %w", err)
+		}
+		if err := deleteOwned(ctx, s.identities, func(i model.Identity) bool { return i.Subject == id }); err != nil {
+			return fmt.Errorf("failed to delete identities: %w", err)
+		}
+		if err := deleteOwned(ctx, s.keys, func(k model.APIKey) bool { return k.Owner == id }); err != nil {
+			return fmt.Errorf("failed to delete API keys: %w", err)
+		}
+		if err := deleteOwned(ctx, s.passkeys, func(p model.Passkey) bool { return p.Subject == id }); err != nil {
+			return fmt.Errorf("failed to delete passkeys: %w", err)
+		}
+		if err := s.factors.Delete(ctx, id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to delete authenticator app: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Checked by Refresh; every session of the account ends within refreshTTL
+	_, err = s.revocations.Revoke(ctx, "subject:"+id, s.clock.Now().Add(s.refreshTTL))
+	return err
+}
+
+// credentialOf returns the password login of account id in creds, or
+// ErrAccountNotFound.
+func credentialOf(ctx context.Context, creds repository.CredentialRepository, id string) (*model.Credential, error) {
+	var found *model.Credential
+	err := creds.Stream(ctx, func(c model.Credential) error {
+		if c.Subject == id {
+			found = &c
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up account: %w", err)
+	}
+	if found == nil {
+		return nil, ErrAccountNotFound
+	}
+	return found, nil
+}
+
+func (s *accountService) info(cred *model.Credential, roles Roles) AccountInfo {
+	info := AccountInfo{ID: cred.Subject, Email: cred.ID, TenantID: cred.TenantID, FailedAttempts: cred.FailedAttempts, Roles: roles.Of(cred.Subject)}
+	if s.clock.Now().Before(cred.LockedUntil) {
+		until := cred.LockedUntil
+		info.LockedUntil = &until
+	}
+	return info
+}
+
+// deleteOwned deletes the items of repo that owned selects.
+func deleteOwned[T model.Entity](ctx context.Context, repo repository.CrudRepository[T], owned func(T) bool) error {
+	var ids []string
+	err := repo.Stream(ctx, func(item T) error {
+		if owned(item) {
+			ids = append(ids, item.GetID())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := repo.Delete(ctx, id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// OwnershipTransfer hands what account from owns of one kind, e.g. the
+// items of a resource (see service.CrudService.Transfer), to account to,
+// in the transaction on ctx.
+type OwnershipTransfer func(ctx context.Context, from, to string) error
+
+// GuestService lets accounts take over the sessions of guests (see
+// AuthService.StartGuest), such as the one a visitor filled a cart in
+// before registering.
+type GuestService interface {
+	// Claim hands everything the guest of guestToken, its access token,
+	// owns to account subject in one transaction, and ends the guest's
+	// session. A guest is claimed once, in the tenant it started in; any
+	// other token returns ErrInvalidGuest.
+	Claim(ctx context.Context, subject, guestToken string) error
+}
+
+type guestService struct {
+	tokens      AuthService
+	uow         repository.UnitOfWork
+	revocations Revocations
+	refreshTTL  time.Duration
+	clock       clock.Clock
+	transfers   []OwnershipTransfer
+}
+
+// NewGuestService claims the guests of tokens, ending their sessions in
+// the same revocations; refreshTTL is that of tokens, the longest a session
+// lasts. Each of transfers moves one kind of what guests own.
+func NewGuestService(tokens AuthService, uow repository.UnitOfWork, revocations Revocations, refreshTTL time.Duration, clk clock.Clock, transfers ...OwnershipTransfer) GuestService {
+	return &guestService{tokens: tokens, uow: uow, revocations: revocations, refreshTTL: refreshTTL, clock: clock.OrSystem(clk), transfers: transfers}
+}
+
+func (s *guestService) Claim(ctx context.Context, subject, guestToken string) error {
+	guest, err := s.tokens.Verify(ctx, guestToken)
+	if errors.Is(err, ErrInvalidToken) {
+		return ErrInvalidGuest
+	}
+	if err != nil {
+		return err
+	}
+	if !guest.Guest || guest.Subject == subject || guest.Tenant != tenant.From(ctx) {
+		return ErrInvalidGuest
+	}
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		for _, transfer := range s.transfers {
+			if err := transfer(ctx, guest.Subject, subject); err != nil {
+				return err
+			}
+		}
+		// Its tokens stop working, so the guest creates nothing nobody
+		// claims. Revoking last, before the commit, keeps the guest's
+		// things with it when the revocation fails, and of concurrent
+		// claims of the same guest only the one that revokes moves them.
+		won, err := s.revocations.Revoke(ctx, "family:"+guest.Family, s.clock.Now().Add(s.refreshTTL))
+		if err != nil {
+			return err
+		}
+		if !won {
+			return ErrInvalidGuest
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInvalidGuest) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim guest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+func TestGuestSessionsAreClaimedOnce(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	revocations := NewMemoryRevocations(nil)
+	svc := NewAuthService(creds, uow, revocations, nil, testOptions)
+	var moved [][2]string
+	guests := NewGuestService(svc, uow, revocations, testOptions.RefreshTTL, nil, func(_ context.Context, from, to string) error {
+		moved = append(moved, [2]string{from, to})
+		return nil
+	})
+
+	started, err := svc.StartGuest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A refreshed guest is still one
+	refreshed, err := svc.Refresh(ctx, started.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, err := svc.Verify(ctx, refreshed.AccessToken)
+	if err != nil || !guest.Guest {
+		t.Fatalf("Verify = %+v, %v; want a guest", guest, err)
+	}
+
+	account := login(t, svc)
+	if err := guests.Claim(ctx, "user-1", account.AccessToken); !errors.Is(err, ErrInvalidGuest) {
+		t.Errorf("claiming an account's token: %v, want ErrInvalidGuest", err)
+	}
+	if err := guests.Claim(tenant.With(ctx, "acme"), "user-1", refreshed.AccessToken); !errors.Is(err, ErrInvalidGuest) {
+		t.Errorf("claiming in another tenant: %v, want ErrInvalidGuest", err)
+	}
+	if len(moved) != 0 {
+		t.Fatalf("refused claims moved %v", moved)
+	}
+
+	if err := guests.Claim(ctx, "user-1", refreshed.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[0] != [2]string{guest.Subject, "user-1"} {
+		t.Errorf("moved %v, want the guest's to user-1", moved)
+	}
+	// The guest's session ends with the claim
+	if _, err := svc.Verify(ctx, refreshed.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify after the claim: %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh after the claim: %v, want ErrInvalidToken", err)
+	}
+	if err := guests.Claim(ctx, "user-2", refreshed.AccessToken); !errors.Is(err, ErrInvalidGuest) {
+		t.Errorf("second claim: %v, want ErrInvalidGuest", err)
+	}
+}
+
+func TestFailedClaimKeepsTheGuestSession(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	revocations := NewMemoryRevocations(nil)
+	svc := NewAuthService(creds, uow, revocations, nil, testOptions)
+	broken := errors.New("store down")
+	guests := NewGuestService(svc, uow, revocations, testOptions.RefreshTTL, nil, func(context.Context, string, string) error {
+		return broken
+	})
+
+	started, err := svc.StartGuest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := guests.Claim(ctx, "user-1", started.AccessToken); !errors.Is(err, broken) {
+		t.Fatalf("Claim = %v, want the transfer's error", err)
+	}
+	if _, err := svc.Verify(ctx, started.AccessToken); err != nil {
+		t.Errorf("Verify after a failed claim: %v", err)
+	}
+}
+
+// racedRevocations is a Revocations whose Revoke fails with err or, without
+// one, finds the family revoked by a concurrent claim.
+type racedRevocations struct {
+	Revocations
+	err error
+}
+
+func (r racedRevocations) Revoke(context.Context, string, time.Time) (bool, error) {
+	return false, r.err
+}
+
+func TestClaimRollsBackUnlessItRevokesTheGuest(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	revocations := NewMemoryRevocations(nil)
+	svc := NewAuthService(creds, uow, revocations, nil, testOptions)
+	started, err := svc.StartGuest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The transfer writes in the claim's transaction
+	transfer := func(ctx context.Context, from, to string) error {
+		_, err := creds.Create(ctx, &model.Credential{ID: "moved", Subject: to})
+		return err
+	}
+
+	broken := errors.New("store down")
+	for _, tt := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"revocation fails", broken, broken},
+		{"concurrent claim revoked first", nil, ErrInvalidGuest},
+	} {
+		guests := NewGuestService(svc, uow, racedRevocations{revocations, tt.err}, testOptions.RefreshTTL, nil, transfer)
+		if err := guests.Claim(ctx, "user-1", started.AccessToken); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Claim = %v, want %v", tt.name, err, tt.want)
+		}
+		if _, err := creds.GetByID(ctx, "moved"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("%s: the transfer was kept: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/ldap"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+func newDirectory(t *testing.T) *ldap.Fake {
+	t.Helper()
+	dir, err := ldap.NewFake([]ldap.Entry{
+		{DN: "cn=svc,dc=example,dc=com"},
+		{DN: "uid=ada,ou=people,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"}, "uid": {"ada"}, "mail": {"Ada@Example.com"}, "cn": {"Ada Lovelace"},
+			"memberOf": {"CN=Admins, OU=Groups, DC=example, DC=com", "cn=staff,ou=groups,dc=example,dc=com"},
+		}},
+		{DN: "uid=alan,ou=people,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"}, "uid": {"alan"}, "cn": {"Alan Turing"},
+		}},
+	}, map[string]string{
+		"cn=svc,dc=example,dc=com":             "svc-secret",
+		"uid=ada,ou=people,dc=example,dc=com":  "lovelace",
+		"uid=alan,ou=people,dc=example,dc=com": "enigma",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dir.Close() })
+	return dir
+}
+
+func TestLDAPLogin(t *testing.T) {
+	ctx := context.Background()
+	dir := newDirectory(t)
+	db, dialect := migratedDB(t)
+	creds := repository.NewSQLRepository[model.Credential](db, dialect, "credentials", "credential")
+	identities := repository.NewSQLRepository[model.Identity](db, dialect, "identities", "identity")
+	uow := repository.NewSQLUnitOfWork(db)
+
+	// Ada registered before the directory took over logins
+	account, err := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions).Register(ctx, "ada@example.com", "correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions
+	opts.Provider = NewLDAPProvider(creds, identities, uow, nil, LDAPOptions{
+		URL:          dir.URL,
+		BindDN:       "cn=svc,dc=example,dc=com",
+		BindPassword: "svc-secret",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		Roles:        map[string][]string{"admin": {"cn=admins,ou=groups,dc=example,dc=com"}, "auditor": {"cn=auditors,ou=groups,dc=example,dc=com"}},
+	}.WithDefaults(), nil, nil)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, opts)
+
+	token, err := svc.Authenticate(ctx, "ada", "lovelace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.Verify(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != account.ID || !slices.Equal(claims.Roles, []string{"admin"}) {
+		t.Errorf("claims = %+v, want the registered account %s with the admin role", claims, account.ID)
+	}
+	refreshed, err := svc.Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, _ := svc.Verify(ctx, refreshed.AccessToken); claims == nil || !slices.Equal(claims.Roles, []string{"admin"}) {
+		t.Errorf("refreshed claims = %+v, want the roles of the login", claims)
+	}
+
+	// A user without an email gets an account of their own, linked by DN
+	token, err = svc.Authenticate(ctx, "alan", "enigma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, _ = svc.Verify(ctx, token.AccessToken)
+	identity, err := identities.GetByID(ctx, "ldap:uid=alan,ou=people,dc=example,dc=com")
+	if err != nil || identity.Subject != claims.Subject || claims.Subject == account.ID || len(claims.Roles) != 0 {
+		t.Errorf("identity = %+v, %v; claims = %+v", identity, err, claims)
+	}
+
+	for _, c := range []struct{ login, password string }{{"ada", "wrong"}, {"ada", ""}, {"grace", "hopper"}, {"*", "lovelace"}, {"ada)(uid=*", "lovelace"}} {
+		if _, err := svc.Authenticate(ctx, c.login, c.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%q, %q) = %v, want ErrInvalidCredentials", c.login, c.password, err)
+		}
+	}
+	if _, err := svc.Register(ctx, "grace@example.com", "correct horse", ""); !errors.Is(err, ErrRegistrationClosed) {
+		t.Errorf("Register = %v, want ErrRegistrationClosed", err)
+	}
+
+	dir.Close()
+	if _, err := svc.Authenticate(ctx, "ada", "lovelace"); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("Authenticate with the directory down = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestLDAPOptionsValidate(t *testing.T) {
+	valid := LDAPOptions{URL: "ldaps://ldap.example.com", BaseDN: "dc=example,dc=com"}.WithDefaults()
+	if err := valid.Validate(); err != nil {
+		t.Error(err)
+	}
+	for name, o := range map[string]LDAPOptions{
+		"http url":         {URL: "https://ldap.example.com", BaseDN: "dc=example,dc=com"},
+		"no base":          {URL: "ldap://ldap.example.com"},
+		"no bind password": {URL: "ldap://ldap.example.com", BaseDN: "dc=example,dc=com", BindDN: "cn=svc"},
+		"no login":         {URL: "ldap://ldap.example.com", BaseDN: "dc=example,dc=com", UserFilter: "(uid=ada)"},
+		"bad filter":       {URL: "ldap://ldap.example.com", BaseDN: "dc=example,dc=com", UserFilter: "(uid={login}"},
+	} {
+		if err := o.WithDefaults().Validate(); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
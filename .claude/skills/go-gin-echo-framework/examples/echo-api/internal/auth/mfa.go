@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+// Parameters of the one-time codes, the defaults of authenticator apps
+// (RFC 6238), which some of them ignore any others of.
+const (
+	totpPeriod = 30 // seconds
+	totpDigits = 6
+	totpSkew   = 1 // periods either side of now accepted, for clock drift
+)
+
+// mfaTokenTTL bounds how long a user may take to enter the code after the
+// password.
+const mfaTokenTTL = 5 * time.Minute
+
+var (
+	ErrInvalidCode    = errors.New("invalid or already used one-time code")
+	ErrMFAEnrolled    = errors.New("an authenticator app is already enrolled")
+	ErrMFANotEnrolled = errors.New("no authenticator app is enrolled")
+)
+
+// MFARequiredError is returned by Authenticate for the right password of
+// an account with an authenticator app: the login completes with
+// CompleteMFA, given Token and a code of the app.
+type MFARequiredError struct {
+	Token     string
+	ExpiresIn int // seconds
+}
+
+func (e *MFARequiredError) Error() string {
+	return "a one-time code of the authenticator app is required"
+}
+
+// TOTPEnrollment is the secret of an authenticator app being enrolled.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"` // base32, to type into apps that cannot scan URI
+	URI    string `json:"uri"`    // otpauth:// URI, usually shown as a QR code
+}
+
+// MFAService enrolls the authenticator apps whose codes complete the
+// password logins of their accounts (see AuthService.CompleteMFA), the
+// fallback of accounts away from their passkeys.
+type MFAService interface {
+	// EnrollTOTP creates the secret of subject's app, replacing one that is
+	// not confirmed yet, or returns ErrMFAEnrolled.
+	EnrollTOTP(ctx context.Context, subject string) (*TOTPEnrollment, error)
+	// ConfirmTOTP turns the app on with one of its codes; logins ask for a
+	// code from then on.
+	ConfirmTOTP(ctx context.Context, subject, code string) error
+	// DisableTOTP removes the app, proven with one of its codes.
+	DisableTOTP(ctx context.Context, subject, code string) error
+}
+
+type mfaService struct {
+	factors repository.TOTPRepository
+	creds   repository.CredentialRepository
+	uow     repository.UnitOfWork
+	issuer  string
+	clock   clock.Clock
+}
+
+// NewMFAService stores secrets in factors, which Options.Factors of the
+// auth service must be for logins to ask for codes. issuer names the
+// service in the apps, next to the account's email in creds.
+func NewMFAService(factors repository.TOTPRepository, creds repository.CredentialRepository, uow repository.UnitOfWork, issuer string, clk clock.Clock) MFAService {
+	return &mfaService{factors: factors, creds: creds, uow: uow, issuer: issuer, clock: clock.OrSystem(clk)}
+}
+
+func (s *mfaService) EnrollTOTP(ctx context.Context, subject string) (*TOTPEnrollment, error) {
+	key := make([]byte, 20) // the length of an HMAC-SHA1 key
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	label := subject
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if cred, err := credentialOf(ctx, s.creds, subject); err == nil {
+			label = cred.ID
+		} else if !errors.Is(err, ErrAccountNotFound) {
+			return err
+		}
+		factor := &model.TOTPFactor{ID: subject, Secret: secret, CreatedAt: s.clock.Now().UTC().Truncate(time.Second)}
+		existing, err := s.factors.GetByID(ctx, subject)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			_, err = s.factors.Create(ctx, factor)
+		case err != nil:
+		case existing.Confirmed:
+			return ErrMFAEnrolled
+		default:
+			_, err = s.factors.Update(ctx, factor)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store authenticator app: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{
+		"secret":    {secret},
+		"issuer":    {s.issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    "otpauth://totp/" + url.PathEscape(s.issuer+":"+label) + "?" + q.Encode(),
+	}, nil
+}
+
+func (s *mfaService) ConfirmTOTP(ctx context.Context, subject, code string) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		factor, err := s.factor(ctx, subject)
+		if err != nil {
+			return err
+		}
+		if factor.Confirmed {
+			return ErrMFAEnrolled
+		}
+		if !acceptCode(factor, code, s.clock.Now()) {
+			return ErrInvalidCode
+		}
+		factor.Confirmed = true
+		if _, err := s.factors.Update(ctx, factor); err != nil {
+			return fmt.Errorf("failed to confirm authenticator app: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *mfaService) DisableTOTP(ctx context.Context, subject, code string) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		factor, err := s.factor(ctx, subject)
+		if err != nil {
+			return err
+		}
+		if !acceptCode(factor, code, s.clock.Now()) {
+			return ErrInvalidCode
+		}
+		if err := s.factors.Delete(ctx, subject); err != nil {
+			return fmt.Errorf("failed to remove authenticator app: %w", err)
+		}
+		return nil
+	})
+}
+
+// factor returns the app of subject, or ErrMFANotEnrolled.
+func (s *mfaService) factor(ctx context.Context, subject string) (*model.TOTPFactor, error) {
+	factor, err := s.factors.GetByID(ctx, subject)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up authenticator app: %w", err)
+	}
+	return factor, nil
+}
+
+// acceptCode reports whether code is one of factor's app at now, and newer
+// than the last accepted, which it records in factor for the caller to
+// store: each code is good for one login.
+func acceptCode(factor *model.TOTPFactor, code string, now time.Time) bool {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(factor.Secret)
+	if err != nil || len(code) != totpDigits {
+		return false
+	}
+	step := now.Unix() / totpPeriod
+	for s := step - totpSkew; s <= step+totpSkew; s++ {
+		if s > factor.LastStep && subtle.ConstantTimeCompare([]byte(hotp(key, s)), []byte(code)) == 1 {
+			factor.LastStep = s
+			return true
+		}
+	}
+	return false
+}
+
+// TOTPCode returns the code an authenticator app shows for the base32
+// secret at t.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	return hotp(key, t.Unix()/totpPeriod), nil
+}
+
+// hotp returns the HMAC-based one-time code of counter (RFC 4226).
+func hotp(key []byte, counter int64) string {
+	mac := hmac.New(sha1.New, key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(counter)))
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
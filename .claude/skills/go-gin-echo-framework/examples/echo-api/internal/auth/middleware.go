@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Identify records the caller of requests that carry a Bearer access token
+// or an API key in the echo context (CallerKey) and the request context (see
+// CallerFromContext). Requests without credentials pass through anonymously;
+// invalid credentials are rejected, and requests are failed closed when a
+// revocation or key store cannot be reached.
+func Identify(tokens AuthService, keys APIKeyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			caller, err := authenticate(req.Context(), req.Header, tokens, keys)
+			if unauthorized(err) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			}
+			if caller != nil {
+				c.Set(CallerKey, caller)
+				c.SetRequest(req.WithContext(WithCaller(req.Context(), caller)))
+			}
+			return next(c)
+		}
+	}
+}
+
+// Require rejects anonymous requests on routes behind Identify, and the
+// sessions of guests (see Caller.Guest): those reach only the routes of the
+// items they own, which do not require a caller.
+func Require() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			caller := CallerFromContext(c.Request().Context())
+			if caller == nil {
+				c.Response().Header().Set("WWW-Authenticate", "Bearer")
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+			if caller.Guest {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "this route requires an account; register or log in"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireRole is the role-based access control of routes behind Identify:
+// it rejects requests unless the caller's account holds role in the grants
+// roles returns, or the identity provider of the caller's login granted it
+// (see Caller.Roles). roles is consulted on every request so grants can be
+// reloaded at runtime.
+func RequireRole(role string, roles func() Roles) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			caller := CallerFromContext(c.Request().Context())
+			if caller == nil {
+				c.Response().Header().Set("WWW-Authenticate", "Bearer")
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+			if !roles().Has(caller.Subject, role) && !slices.Contains(caller.Roles, role) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireAdmin is RequireRole(RoleAdmin, roles).
+func RequireAdmin(roles func() Roles) echo.MiddlewareFunc {
+	return RequireRole(RoleAdmin, roles)
+}
+
+// RequireLogin rejects callers authenticated by an API key on routes behind
+// Identify, for routes too sensitive for a long-lived credential that is
+// often kept in CI settings: the caller must have logged in for a bearer
+// token. Anonymous requests pass, for RequireRole to reject.
+func RequireLogin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if caller := CallerFromContext(c.Request().Context()); caller != nil && caller.Method != MethodJWT {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "this route requires a bearer token from a login, not an API key"})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+	"github.com/your-username/echo-api/internal/webauthn"
+)
+
+var (
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	ErrInvalidPasskey  = errors.New("invalid or expired passkey ceremony")
+)
+
+// PasskeyOptions configures the relying party passkeys are registered with
+// (auth.passkeys).
+type PasskeyOptions struct {
+	RPID    string        `yaml:"rp_id"`   // domain passkeys are scoped to, e.g. example.com; they work on its subdomains too
+	RPName  string        `yaml:"rp_name"` // shown by authenticators
+	Origins []string      `yaml:"origins"` // of the pages that run ceremonies, e.g. https://app.example.com
+	Timeout time.Duration `yaml:"timeout"` // how long a user may take at the authenticator
+}
+
+// Validate reports the first missing or malformed setting of options.
+func (o PasskeyOptions) Validate() error {
+	if o.RPID == "" || strings.ContainsAny(o.RPID, ":/") {
+		return errors.New("rp_id must be a domain name, without scheme or port")
+	}
+	if len(o.Origins) == 0 {
+		return errors.New("origins are required")
+	}
+	for _, origin := range o.Origins {
+		u, err := url.Parse(origin)
+		if err != nil || !absoluteURL(origin) || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("origin %q must be an http:// or https:// URL without a path", origin)
+		}
+		if host := u.Hostname(); host != o.RPID && !strings.HasSuffix(host, "."+o.RPID) {
+			return fmt.Errorf("origin %q is not on rp_id %s or a subdomain of it", origin, o.RPID)
+		}
+	}
+	if o.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// PasskeyRegistration is the start of adding a passkey: the options to
+// pass to navigator.credentials.create, and the session to return with
+// its result.
+type PasskeyRegistration struct {
+	Session   string                   `json:"session"`
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+// PasskeyLogin is the start of logging in with a passkey: the options to
+// pass to navigator.credentials.get, and the session to return with its
+// result.
+type PasskeyLogin struct {
+	Session   string                  `json:"session"`
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+// PasskeyInfo is a passkey as its account sees it, without the key.
+type PasskeyInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // unset until the first login
+}
+
+// PasskeyService registers WebAuthn credentials as passkeys of logged-in
+// accounts and logs in with them, without a password. Each ceremony takes
+// two requests: Begin returns options for the browser, whose answer goes
+// to Finish with the session Begin returned, good for one attempt.
+// Accounts keep their password, and authenticator app, for devices without
+// their passkeys.
+type PasskeyService interface {
+	// BeginRegistration starts adding a passkey to account subject.
+	BeginRegistration(ctx context.Context, subject string) (*PasskeyRegistration, error)
+	// FinishRegistration verifies the browser's registration and stores the
+	// passkey under name.
+	FinishRegistration(ctx context.Context, subject, session, name string, r *webauthn.Registration) (*PasskeyInfo, error)
+	// BeginLogin starts a login with a passkey of the account registered
+	// with email, or with any discoverable passkey if email is empty. An
+	// unknown email gets options that no passkey satisfies, so emails
+	// cannot be probed.
+	BeginLogin(ctx context.Context, email string) (*PasskeyLogin, error)
+	// FinishLogin verifies the browser's assertion and starts a session of
+	// the passkey's account in the tenant on ctx, the one it was registered
+	// in. Failures are ErrInvalidPasskey.
+	FinishLogin(ctx context.Context, session string, a *webauthn.Assertion) (*Token, error)
+	// List returns the passkeys of subject, oldest first.
+	List(ctx context.Context, subject string) ([]PasskeyInfo, error)
+	// Delete removes passkey id of subject, or returns ErrPasskeyNotFound.
+	Delete(ctx context.Context, subject, id string) error
+}
+
+// ceremonyClaims is the JWT payload of the session of a ceremony.
+type ceremonyClaims struct {
+	jwt.RegisteredClaims
+	Use       string `json:"use"`              // "passkey-registration" or "passkey-login"
+	Challenge string `json:"chl"`              // base64url
+	Tenant    string `json:"tenant,omitempty"` // of the request that began it
+}
+
+type passkeyService struct {
+	tokens      AuthService
+	creds       repository.CredentialRepository
+	passkeys    repository.PasskeyRepository
+	uow         repository.UnitOfWork
+	revocations Revocations
+	secret      []byte
+	rp          webauthn.RelyingParty
+	timeout     time.Duration
+	clock       clock.Clock
+}
+
+// NewPasskeyService stores passkeys in passkeys and issues sessions with
+// tokens, naming accounts by their email in creds. Ceremony sessions are
+// signed with secret (empty uses a random per-process key) and used up in
+// revocations.
+func NewPasskeyService(tokens AuthService, creds repository.CredentialRepository, passkeys repository.PasskeyRepository, uow repository.UnitOfWork, revocations Revocations, secret []byte, opts PasskeyOptions, clk clock.Clock) PasskeyService {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Sprintf("auth: failed to generate passkey session key: %v", err))
+		}
+	}
+	return &passkeyService{
+		tokens:      tokens,
+		creds:       creds,
+		passkeys:    passkeys,
+		uow:         uow,
+		revocations: revocations,
+		secret:      secret,
+		rp:          webauthn.RelyingParty{ID: opts.RPID, Name: opts.RPName, Origins: opts.Origins},
+		timeout:     opts.Timeout,
+		clock:       clock.OrSystem(clk),
+	}
+}
+
+func (s *passkeyService) BeginRegistration(ctx context.Context, subject string) (*PasskeyRegistration, error) {
+	name := subject
+	if cred, err := credentialOf(ctx, s.creds, subject); err == nil {
+		name = cred.ID
+	} else if !errors.Is(err, ErrAccountNotFound) {
+		return nil, err
+	}
+	// An authenticator holds one passkey per account
+	exclude, err := s.descriptors(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	challenge := webauthn.NewChallenge()
+	session, err := s.session(ctx, "passkey-registration", subject, challenge)
+	if err != nil {
+		return nil, err
+	}
+	user := webauthn.UserEntity{ID: []byte(subject), Name: name, DisplayName: name}
+	return &PasskeyRegistration{Session: session, PublicKey: s.rp.CreationOptions(challenge, user, exclude, s.timeout)}, nil
+}
+
+func (s *passkeyService) FinishRegistration(ctx context.Context, subject, session, name string, r *webauthn.Registration) (*PasskeyInfo, error) {
+	claims, err := s.ceremony(ctx, session, "passkey-registration")
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject != subject {
+		return nil, ErrInvalidPasskey
+	}
+	challenge, _ := base64.RawURLEncoding.DecodeString(claims.Challenge)
+	cred, err := s.rp.VerifyRegistration(r, challenge)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+	if name == "" {
+		name = "passkey"
+	}
+	passkey := &model.Passkey{
+		ID:         base64.RawURLEncoding.EncodeToString(cred.ID),
+		Subject:    subject,
+		Name:       name,
+		PublicKey:  base64.RawURLEncoding.EncodeToString(cred.PublicKey),
+		SignCount:  int64(cred.SignCount),
+		Transports: strings.Join(cred.Transports, ","),
+		TenantID:   tenant.From(ctx),
+		CreatedAt:  s.clock.Now().UTC().Truncate(time.Second),
+	}
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		// Credential IDs are random, so a known one is a replayed registration
+		if _, err := s.passkeys.GetByID(ctx, passkey.ID); err == nil {
+			return fmt.Errorf("%w: passkey is already registered", ErrInvalidPasskey)
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to look up passkey: %w", err)
+		}
+		if _, err := s.passkeys.Create(ctx, passkey); err != nil {
+			return fmt.Errorf("failed to store passkey: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	info := passkeyInfo(*passkey)
+	return &info, nil
+}
+
+func (s *passkeyService) BeginLogin(ctx context.Context, email string) (*PasskeyLogin, error) {
+	var subject string
+	var allow []webauthn.CredentialDescriptor
+	if email != "" {
+		cred, err := s.creds.GetByID(ctx, normalizeEmail(email))
+		switch {
+		case err == nil && cred.TenantID == tenant.From(ctx):
+			subject = cred.Subject
+			if allow, err = s.descriptors(ctx, subject); err != nil {
+				return nil, err
+			}
+		case err == nil || errors.Is(err, repository.ErrNotFound):
+			subject = newID() // no account, so no passkey, has it
+		default:
+			return nil, fmt.Errorf("failed to look up credential: %w", err)
+		}
+	}
+	challenge := webauthn.NewChallenge()
+	session, err := s.session(ctx, "passkey-login", subject, challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyLogin{Session: session, PublicKey: s.rp.RequestOptions(challenge, allow, s.timeout)}, nil
+}
+
+func (s *passkeyService) FinishLogin(ctx context.Context, session string, a *webauthn.Assertion) (*Token, error) {
+	claims, err := s.ceremony(ctx, session, "passkey-login")
+	if err != nil {
+		return nil, err
+	}
+	challenge, _ := base64.RawURLEncoding.DecodeString(claims.Challenge)
+	var subject string
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		passkey, err := s.passkeys.GetByID(ctx, base64.RawURLEncoding.EncodeToString(a.RawID))
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: unknown passkey", ErrInvalidPasskey)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up passkey: %w", err)
+		}
+		// The passkey must be the account's the login began for, and
+		// registered in this tenant
+		if passkey.TenantID != claims.Tenant || (claims.Subject != "" && passkey.Subject != claims.Subject) ||
+			(len(a.Response.UserHandle) > 0 && string(a.Response.UserHandle) != passkey.Subject) {
+			return fmt.Errorf("%w: passkey of another account", ErrInvalidPasskey)
+		}
+		key, err := base64.RawURLEncoding.DecodeString(passkey.PublicKey)
+		if err != nil {
+			return fmt.Errorf("stored passkey %s: %w", passkey.ID, err)
+		}
+		count, err := s.rp.VerifyAssertion(a, challenge, key, uint32(passkey.SignCount))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+		}
+		passkey.SignCount = int64(count)
+		passkey.LastUsedAt = s.clock.Now().UTC().Truncate(time.Second)
+		if _, err := s.passkeys.Update(ctx, passkey); err != nil {
+			return fmt.Errorf("failed to store passkey: %w", err)
+		}
+		subject = passkey.Subject
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens.Issue(ctx, subject)
+}
+
+func (s *passkeyService) List(ctx context.Context, subject string) ([]PasskeyInfo, error) {
+	out := []PasskeyInfo{}
+	err := s.passkeys.Stream(ctx, func(p model.Passkey) error {
+		if p.Subject == subject {
+			out = append(out, passkeyInfo(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *passkeyService) Delete(ctx context.Context, subject, id string) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		passkey, err := s.passkeys.GetByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && passkey.Subject != subject) {
+			return ErrPasskeyNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up passkey: %w", err)
+		}
+		if err := s.passkeys.Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete passkey: %w", err)
+		}
+		return nil
+	})
+}
+
+// descriptors returns the passkeys of subject as a ceremony names them.
+func (s *passkeyService) descriptors(ctx context.Context, subject string) ([]webauthn.CredentialDescriptor, error) {
+	var out []webauthn.CredentialDescriptor
+	err := s.passkeys.Stream(ctx, func(p model.Passkey) error {
+		if p.Subject != subject {
+			return nil
+		}
+		id, err := base64.RawURLEncoding.DecodeString(p.ID)
+		if err != nil {
+			return fmt.Errorf("stored passkey %s: %w", p.ID, err)
+		}
+		d := webauthn.CredentialDescriptor{Type: "public-key", ID: id}
+		if p.Transports != "" {
+			d.Transports = strings.Split(p.Transports, ",")
+		}
+		out = append(out, d)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	return out, nil
+}
+
+// session signs the state of a ceremony begun for subject with challenge.
+func (s *passkeyService) session(ctx context.Context, use, subject string, challenge []byte) (string, error) {
+	now := s.clock.Now()
+	claims := ceremonyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newID(),
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.timeout)),
+		},
+		Use:       use,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Tenant:    tenant.From(ctx),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign passkey session: %w", err)
+	}
+	return signed, nil
+}
+
+// ceremony verifies session and uses it up: each challenge is answered
+// once. Every failure is ErrInvalidPasskey.
+func (s *passkeyService) ceremony(ctx context.Context, session, use string) (*ceremonyClaims, error) {
+	var claims ceremonyClaims
+	_, err := jwt.ParseWithClaims(session, &claims, func(*jwt.Token) (any, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.clock.Now))
+	if err != nil || claims.Use != use || claims.ID == "" || claims.Challenge == "" || claims.Tenant != tenant.From(ctx) {
+		return nil, ErrInvalidPasskey
+	}
+	first, err := s.revocations.Revoke(ctx, "passkey:"+claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrInvalidPasskey
+	}
+	return &claims, nil
+}
+
+func passkeyInfo(p model.Passkey) PasskeyInfo {
+	info := PasskeyInfo{ID: p.ID, Name: p.Name, CreatedAt: p.CreatedAt}
+	if !p.LastUsedAt.IsZero() {
+		used := p.LastUsedAt
+		info.LastUsedAt = &used
+	}
+	return info
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// IdentityProvider checks the password of a login for AuthService.Authenticate
+// (POST /auth/login), selected by auth.login_provider: the credentials of
+// Register (local, the default) or a directory server (ldap, see
+// NewLDAPProvider). OIDC providers are not among them, as their users log
+// in at the provider instead (see OIDCService), but all three lead to the
+// same local accounts.
+type IdentityProvider interface {
+	// Authenticate returns the account login and password belong to in the
+	// tenant on ctx: ErrInvalidCredentials if there is none, a *LockedError
+	// if it is locked out, or ErrProviderUnavailable if the provider cannot
+	// be reached.
+	Authenticate(ctx context.Context, login, password string) (*Principal, error)
+}
+
+// Principal is the account a provider authenticated.
+type Principal struct {
+	Subject string   // account ID
+	Tenant  string   // tenant of the account; "" without tenancy
+	Roles   []string // held for the session on top of those auth.roles grants the account, e.g. for directory groups
+}
+
+// credentialProvider is the IdentityProvider of registered credentials,
+// which locks an account for lockout after maxFailed consecutive wrong
+// passwords.
+type credentialProvider struct {
+	creds     repository.CredentialRepository
+	uow       repository.UnitOfWork
+	locks     repository.Locks
+	clock     clock.Clock
+	maxFailed int
+	lockout   time.Duration
+}
+
+func (p *credentialProvider) Authenticate(ctx context.Context, email, password string) (*Principal, error) {
+	email = normalizeEmail(email)
+	cred, err := p.creds.GetByID(ctx, email)
+	if err == nil && cred.TenantID != tenant.From(ctx) {
+		// An account logs in to the tenant it registered in only
+		err = repository.ErrNotFound
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		// Spend the same time as a wrong password so unknown emails don't stand out
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up credential: %w", err)
+	}
+
+	now := p.clock.Now()
+	if now.Before(cred.LockedUntil) {
+		return nil, &LockedError{Until: cred.LockedUntil}
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password)); err != nil {
+		return nil, p.recordFailure(ctx, cred.ID, now)
+	}
+	if cred.FailedAttempts > 0 || !cred.LockedUntil.IsZero() {
+		cred.FailedAttempts = 0
+		cred.LockedUntil = time.Time{}
+		if _, err := p.creds.Update(ctx, cred); err != nil {
+			return nil, fmt.Errorf("failed to reset failed logins: %w", err)
+		}
+	}
+	return &Principal{Subject: cred.Subject, Tenant: cred.TenantID}, nil
+}
+
+// recordFailure counts a wrong password against the credential id, locking
+// it once the limit is reached, and returns the error to report to the
+// client. The count is read again under the credential's lock, so that no
+// concurrent failure is lost.
+func (p *credentialProvider) recordFailure(ctx context.Context, id string, now time.Time) error {
+	var result error = ErrInvalidCredentials
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		release, err := p.locks.Lock(ctx, "credentials:"+id)
+		if err != nil {
+			return err
+		}
+		defer release()
+		cred, err := p.creds.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if now.Before(cred.LockedUntil) {
+			// Locked by a concurrent failure meanwhile
+			result = &LockedError{Until: cred.LockedUntil}
+			return nil
+		}
+		cred.FailedAttempts++
+		if cred.FailedAttempts >= p.maxFailed {
+			cred.FailedAttempts = 0
+			cred.LockedUntil = now.Add(p.lockout)
+			result = &LockedError{Until: cred.LockedUntil}
+			log.Printf("WARNING: auth: locked %s for %s after %d failed logins", cred.ID, p.lockout, p.maxFailed)
+		}
+		_, err = p.creds.Update(ctx, cred)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record failed login: %w", err)
+	}
+	return result
+}
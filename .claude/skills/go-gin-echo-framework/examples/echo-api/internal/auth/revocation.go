@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/lifecycle"
+)
+
+const sweepInterval = time.Minute
+
+// Revocations remembers revoked token and token family IDs until the tokens
+// they cover have expired anyway. Implementations must be safe for
+// concurrent use.
+type Revocations interface {
+	// Revoke marks id revoked until until and reports whether it was not
+	// revoked before, so exactly one of several concurrent callers wins.
+	Revoke(ctx context.Context, id string, until time.Time) (bool, error)
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// NewRevocationStore builds the Revocations for backend ("memory" or "redis").
+func NewRevocationStore(ctx context.Context, lc *lifecycle.Manager, backend, redisURL string, clk clock.Clock) (Revocations, error) {
+	switch backend {
+	case "redis":
+		return NewRedisRevocations(ctx, lc, redisURL, "revoked:", clk)
+	case "memory", "":
+		return NewMemoryRevocations(clk), nil
+	default:
+		return nil, fmt.Errorf("unknown revocation backend: %s", backend)
+	}
+}
+
+type memoryRevocations struct {
+	mu        sync.Mutex
+	until     map[string]time.Time
+	lastSweep time.Time
+	clock     clock.Clock
+}
+
+// NewMemoryRevocations returns process-local Revocations. A logout on one
+// instance is not seen by the others, so use the Redis store when running
+// several replicas. Entries expire by clk (nil is the system clock).
+func NewMemoryRevocations(clk clock.Clock) Revocations {
+	return &memoryRevocations{until: make(map[string]time.Time), clock: clock.OrSystem(clk)}
+}
+
+func (r *memoryRevocations) Revoke(ctx context.Context, id string, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	r.sweep(now)
+	if t, ok := r.until[id]; ok && now.Before(t) {
+		return false, nil
+	}
+	r.until[id] = until
+	return true, nil
+}
+
+func (r *memoryRevocations) IsRevoked(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.until[id]
+	return ok && r.clock.Now().Before(t), nil
+}
+
+// sweep drops entries whose tokens have expired. Must be called with r.mu held.
+func (r *memoryRevocations) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < sweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for id, t := range r.until {
+		if !now.Before(t) {
+			delete(r.until, id)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/lifecycle"
+)
+
+type redisRevocations struct {
+	client *redis.Client
+	prefix string
+	clock  clock.Clock
+}
+
+// NewRedisRevocations returns Revocations shared by every replica connected
+// to the Redis instance at url. Entries expire with the tokens they cover;
+// clk (nil is the system clock) converts their expiry times to Redis TTLs.
+func NewRedisRevocations(ctx context.Context, lc *lifecycle.Manager, url, prefix string, clk clock.Clock) (Revocations, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	lc.RegisterCloser("revocation redis client", 0, client)
+	return &redisRevocations{client: client, prefix: prefix, clock: clock.OrSystem(clk)}, nil
+}
+
+func (r *redisRevocations) Revoke(ctx context.Context, id string, until time.Time) (bool, error) {
+	ttl := until.Sub(r.clock.Now())
+	if ttl <= 0 {
+		return true, nil // already expired; nothing left to revoke
+	}
+	set, err := r.client.SetNX(ctx, r.prefix+id, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis revoke: %w", err)
+	}
+	return set, nil
+}
+
+func (r *redisRevocations) IsRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.prefix+id).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis revocation lookup: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Ping verifies the Redis connection; it satisfies health.Pinger.
+func (r *redisRevocations) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
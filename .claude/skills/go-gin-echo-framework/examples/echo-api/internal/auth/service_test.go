@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+var testOptions = Options{
+	Secret:          []byte("0123456789abcdef0123456789abcdef"),
+	Issuer:          "test",
+	TokenTTL:        time.Minute,
+	RefreshTTL:      time.Hour,
+	MaxFailedLogins: 3,
+	LockoutDuration: time.Hour,
+}
+
+// migratedDB returns a migrated SQLite database, so the tests cover the real
+// schema.
+func migratedDB(t *testing.T) (*sql.DB, repository.Dialect) {
+	t.Helper()
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return db, dialect
+}
+
+func newCredentials(t *testing.T) (repository.CredentialRepository, repository.UnitOfWork) {
+	db, dialect := migratedDB(t)
+	return repository.NewSQLRepository[model.Credential](db, dialect, "credentials", "credential"), repository.NewSQLUnitOfWork(db)
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+
+	account, err := svc.Register(ctx, " Ada@Example.com ", "correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Email != "ada@example.com" {
+		t.Errorf("email = %q, want it normalized", account.Email)
+	}
+	stored, err := creds.GetByID(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.PasswordHash, "$2a$") {
+		t.Errorf("password hash = %q, want bcrypt", stored.PasswordHash)
+	}
+
+	token, err := svc.Authenticate(ctx, "ADA@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.Verify(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != account.ID {
+		t.Errorf("subject = %q, want %q", claims.Subject, account.ID)
+	}
+}
+
+func TestRegisterRejectsTakenEmailAndWeakPassword(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	created := 0
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), func(context.Context, string, string) (string, error) {
+		created++
+		return "user-1", nil
+	}, testOptions)
+
+	if _, err := svc.Register(ctx, "ada@example.com", "correct horse", "Ada"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Register(ctx, "Ada@Example.com", "another one", "Ada"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("duplicate email: err = %v, want ErrEmailTaken", err)
+	}
+	if _, err := svc.Register(ctx, "bob@example.com", "short", "Bob"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("short password: err = %v, want ErrInvalidPassword", err)
+	}
+	if created != 1 {
+		t.Errorf("created %d accounts, want 1", created)
+	}
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	clk := clock.NewFake(time.Now())
+	opts := testOptions
+	opts.Clock = clk
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(clk), nil, opts)
+	if _, err := svc.Register(ctx, "ada@example.com", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < testOptions.MaxFailedLogins; i++ {
+		if _, err := svc.Authenticate(ctx, "ada@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: err = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+	var locked *LockedError
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "wrong"); !errors.As(err, &locked) {
+		t.Fatalf("last allowed failure: err = %v, want LockedError", err)
+	}
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); !errors.As(err, &locked) {
+		t.Fatalf("correct password while locked: err = %v, want LockedError", err)
+	}
+
+	clk.Advance(testOptions.LockoutDuration - time.Second)
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); !errors.As(err, &locked) {
+		t.Fatalf("correct password a second before the lock ends: err = %v, want LockedError", err)
+	}
+	clk.Advance(time.Second)
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Fatalf("after lockout: %v", err)
+	}
+	cred, err := creds.GetByID(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.FailedAttempts != 0 || !cred.LockedUntil.IsZero() {
+		t.Errorf("after successful login: attempts = %d, locked until %v; want reset", cred.FailedAttempts, cred.LockedUntil)
+	}
+}
+
+func TestTokensExpireWithTheClock(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	clk := clock.NewFake(time.Now())
+	opts := testOptions
+	opts.Clock = clk
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(clk), nil, opts)
+	if _, err := svc.Register(ctx, "ada@example.com", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+	token, err := svc.Authenticate(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(testOptions.TokenTTL - time.Second)
+	if _, err := svc.Verify(ctx, token.AccessToken); err != nil {
+		t.Fatalf("access token a second before it expires: %v", err)
+	}
+	clk.Advance(time.Second)
+	if _, err := svc.Verify(ctx, token.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired access token: err = %v, want ErrInvalidToken", err)
+	}
+	// The refresh token outlives it and starts a new access token's lifetime
+	refreshed, err := svc.Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh after the access token expired: %v", err)
+	}
+	if _, err := svc.Verify(ctx, refreshed.AccessToken); err != nil {
+		t.Fatalf("refreshed access token: %v", err)
+	}
+	clk.Advance(testOptions.RefreshTTL)
+	if _, err := svc.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired refresh token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestLoginRejectsUnknownEmail(t *testing.T) {
+	creds, uow := newCredentials(t)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+	if _, err := svc.Authenticate(context.Background(), "nobody@example.com", "whatever"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLoginIsBoundToTheTenantRegisteredIn(t *testing.T) {
+	acme := tenant.With(context.Background(), "acme")
+	creds, uow := newCredentials(t)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+	if _, err := svc.Register(acme, "ada@example.com", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"another tenant": tenant.With(context.Background(), "globex"),
+		"no tenant":      context.Background(),
+	} {
+		if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("login to %s: err = %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+	token, err := svc.Authenticate(acme, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.Verify(context.Background(), token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Tenant != "acme" {
+		t.Errorf("access token of tenant %q, want acme", claims.Tenant)
+	}
+	// Refreshed tokens stay in the tenant, wherever they are refreshed
+	refreshed, err := svc.Refresh(context.Background(), token.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err = svc.Verify(context.Background(), refreshed.AccessToken); err != nil || claims.Tenant != "acme" {
+		t.Errorf("refreshed token of tenant %q (%v), want acme", claims.Tenant, err)
+	}
+}
+
+func TestVerifyRejectsForeignAndExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+
+	other := testOptions
+	other.Secret = []byte("another-secret-another-secret-xx")
+	foreign, err := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, other).(*authService).issue(Claims{Subject: "user-1", Family: "family-1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := svc.(*authService).issue(Claims{Subject: "user-1", Family: "family-1"}, time.Now().Add(-2*testOptions.TokenTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{
+		"foreign": foreign.AccessToken,
+		"expired": expired.AccessToken,
+		"refresh": expired.RefreshToken, // still valid, but not an access token
+		"garbage": "not.a.jwt",
+	} {
+		if _, err := svc.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s token: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+// login registers ada@example.com and returns her first token pair.
+func login(t *testing.T, svc AuthService) *Token {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := svc.Register(ctx, "ada@example.com", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+	token, err := svc.Authenticate(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+	first := login(t, svc)
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh did not rotate the refresh token")
+	}
+	if _, err := svc.Verify(ctx, second.AccessToken); err != nil {
+		t.Fatalf("rotated access token: %v", err)
+	}
+
+	// Replaying the rotated token revokes the family, including the live pair
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused refresh token: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh after reuse: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Verify(ctx, second.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token after reuse: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestLogoutRevokesOnlyItsFamily(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+	session := login(t, svc)
+	other, err := svc.Authenticate(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Logout(ctx, session.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Verify(ctx, session.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token after logout: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh after logout: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Verify(ctx, other.AccessToken); err != nil {
+		t.Errorf("other session after logout: %v", err)
+	}
+	if err := svc.Logout(ctx, session.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("logout with an access token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestDeactivatedAccountsCannotStartOrRefreshSessions(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	active := true
+	opts := testOptions
+	opts.Active = func(context.Context, string) (bool, error) { return active, nil }
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, opts)
+	session := login(t, svc)
+	claims, err := svc.Verify(ctx, session.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active = false
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("login: err = %v, want ErrAccountDisabled", err)
+	}
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("login with a wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := svc.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Issue(ctx, claims.Subject); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Issue: err = %v, want ErrAccountDisabled", err)
+	}
+
+	active = true
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Errorf("login after reactivation: %v", err)
+	}
+}
+
+func TestConcurrentFailedLoginsAreAllCounted(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	opts := testOptions
+	opts.MaxFailedLogins = 100
+	svc := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, opts)
+	if _, err := svc.Register(ctx, "ada@example.com", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Authenticate(ctx, "ada@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("err = %v, want ErrInvalidCredentials", err)
+			}
+		}()
+	}
+	wg.Wait()
+	cred, err := creds.GetByID(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.FailedAttempts != n {
+		t.Errorf("attempts = %d, want %d", cred.FailedAttempts, n)
+	}
+}
@@ -0,0 +1,154 @@
+package blob
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSignature is returned by Local.Verify for a URL it did not sign or that
+// has expired.
+var ErrSignature = errors.New("invalid or expired signature")
+
+// Local keeps blobs as files under a directory, at the path of their key.
+// It does not record content types: Get sniffs them from the first bytes
+// (http.DetectContentType), as uploads are checked the same way. Its signed
+// URLs are paths under a prefix the API serves, carrying the expiry and an
+// HMAC of the key and expiry.
+type Local struct {
+	dir    string
+	prefix string
+	key    []byte
+}
+
+// NewLocal returns the store of the files under dir, whose signed URLs are
+// under prefix, e.g. /blobs. An empty signing key is replaced by a random
+// one.
+func NewLocal(dir, prefix string, key []byte) *Local {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Local{dir: dir, prefix: strings.TrimSuffix(prefix, "/"), key: key}
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file next to its own, then renames
+// it, so that readers see either the old blob or the whole new one.
+func (l *Local) Put(_ context.Context, key string, r io.Reader, size int64, _ string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("blob: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("blob: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n != size {
+		err = fmt.Errorf("got %d bytes, want %d", n, size)
+	}
+	if err != nil {
+		return fmt.Errorf("blob: put %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("blob: %w", err)
+	}
+	return nil
+}
+
+func (l *Local) Get(_ context.Context, key string) (io.ReadCloser, Info, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("blob: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		f.Close()
+		return nil, Info{}, ErrNotFound
+	}
+	r := bufio.NewReaderSize(f, 512)
+	head, _ := r.Peek(512)
+	info := Info{ContentType: http.DetectContentType(head), Size: stat.Size(), ModTime: stat.ModTime()}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, info, nil
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("blob: %w", err)
+	}
+	return nil
+}
+
+// SignURL returns <prefix>/<key>?expires=<unix seconds>&signature=<hmac>.
+func (l *Local) SignURL(key string, expires time.Time) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return l.prefix + "/" + strings.Join(segments, "/") + "?expires=" + unix + "&signature=" + l.sign(key, unix), nil
+}
+
+// Verify checks the expires and signature query parameters of a URL made by
+// SignURL for key at now, returning ErrSignature if they do not match.
+func (l *Local) Verify(key, expires, signature string, now time.Time) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(l.sign(key, expires))) {
+		return ErrSignature
+	}
+	if !now.Before(time.Unix(unix, 0)) {
+		return fmt.Errorf("%w: expired at %s", ErrSignature, time.Unix(unix, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,279 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+)
+
+// unsignedPayload stands for the body in signatures: uploads are streamed,
+// so their hash is not known before they are sent.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3 keeps blobs as the objects of a bucket, spoken to over the S3 REST API
+// with requests signed by AWS Signature Version 4. Its signed URLs are
+// presigned GET URLs of the objects.
+type S3 struct {
+	base      *url.URL // of the bucket; an object is at base/<key>
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	clock     clock.Clock
+}
+
+// NewS3 returns the store of the objects of opts.Bucket: at AWS, addressed
+// virtual-hosted style, or at opts.Endpoint, addressed path-style. A nil
+// client is http.DefaultClient.
+func NewS3(opts Options, client *http.Client, clk clock.Clock) (*S3, error) {
+	raw := "https://" + opts.Bucket + ".s3." + opts.Region + ".amazonaws.com"
+	if opts.Endpoint != "" {
+		raw = strings.TrimSuffix(opts.Endpoint, "/") + "/" + url.PathEscape(opts.Bucket)
+	}
+	base, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("blob: s3 endpoint: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("blob: s3 endpoint: want http or https, got %q", base.Scheme)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3{
+		base:      base,
+		region:    opts.Region,
+		accessKey: opts.AccessKeyID,
+		secretKey: opts.SecretAccessKey.Reveal(),
+		client:    client,
+		clock:     clk,
+	}, nil
+}
+
+// Ping checks that the bucket exists and the credentials may use it.
+func (s *S3) Ping(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, "", nil, 0, "")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, Info{}, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, Info{}, err
+	}
+	info := Info{ContentType: resp.Header.Get("Content-Type"), Size: resp.ContentLength}
+	info.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.Body, info, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// SignURL returns a presigned GET URL of the object, whose signature only
+// covers the host header.
+func (s *S3) SignURL(key string, expires time.Time) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	now := s.clock.Now().UTC()
+	ttl := int64(expires.Sub(now) / time.Second)
+	if ttl < 1 || ttl > int64(MaxURLTTL/time.Second) {
+		return "", fmt.Errorf("blob: s3 URLs expire within 1s and %v", MaxURLTTL)
+	}
+	target := s.object(key)
+	date := now.Format("20060102T150405Z")
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.accessKey + "/" + s.scope(date)},
+		"X-Amz-Date":          {date},
+		"X-Amz-Expires":       {strconv.FormatInt(ttl, 10)},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	header := http.Header{"Host": {target.Host}}
+	signature := s.signature(http.MethodGet, target, query, header, unsignedPayload, date)
+	target.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
+
+// do sends a signed request for the object under key, or the bucket if key
+// is empty, with the size bytes of body. Responses other than 2xx fail,
+// 404 with ErrNotFound; the caller closes the body of the others.
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	target := s.base
+	if key != "" {
+		target = s.object(key)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	date := s.clock.Now().UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	signed := http.Header{
+		"Host":                 {req.URL.Host},
+		"X-Amz-Content-Sha256": {unsignedPayload},
+		"X-Amz-Date":           {date},
+	}
+	signature := s.signature(method, req.URL, nil, signed, unsignedPayload, date)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+s.scope(date)+
+		", SignedHeaders="+signedHeaders(signed)+", Signature="+signature)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("blob: s3: %w", err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("blob: s3: %s %s: %s: %s", method, target.Path, resp.Status, bytes.TrimSpace(msg))
+}
+
+// object is the URL of the object under key, escaped as signatures
+// require so that the path sent is the one signed.
+func (s *S3) object(key string) *url.URL {
+	u := s.base.JoinPath(strings.Split(key, "/")...)
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return u
+}
+
+// scope is the credential scope of a request on date (yyyymmddThhmmssZ).
+func (s *S3) scope(date string) string {
+	return date[:8] + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature is the Signature Version 4 of a request: the HMAC, with a key
+// derived from the secret key, date and region, of the hash of its
+// canonical form.
+func (s *S3) signature(method string, target *url.URL, query url.Values, header http.Header, payload, date string) string {
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+	var headers strings.Builder
+	for _, name := range sortedHeaders(header) {
+		headers.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+	canonical := strings.Join([]string{
+		method,
+		uriEncode(path, false),
+		canonicalQuery(query),
+		headers.String(),
+		signedHeaders(header),
+		payload,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + date + "\n" + s.scope(date) + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date[:8], s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sortedHeaders(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func signedHeaders(header http.Header) string {
+	return strings.Join(sortedHeaders(header), ";")
+}
+
+// canonicalQuery encodes query sorted by name, as signatures require.
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes every byte of s but unreserved characters (and
+// slashes unless encodeSlash), which is stricter than package url.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// lruStore is an in-process, size-bounded Store used when Redis is not configured.
+type lruStore struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	clock    clock.Clock
+}
+
+// NewLRUStore keeps up to capacity entries, expiring them by clk (nil is
+// the system clock).
+func NewLRUStore(capacity int, clk clock.Clock) Store {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	return &lruStore{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		clock:    clock.OrSystem(clk),
+	}
+}
+
+func (s *lruStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && s.clock.Now().After(entry.expiresAt) {
+		s.removeElement(el)
+		return nil, ErrMiss
+	}
+	s.ll.MoveToFront(el)
+	return entry.value, nil
+}
+
+func (s *lruStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = s.clock.Now().Add(ttl)
+	}
+
+	if el, ok := s.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		s.ll.MoveToFront(el)
+		return nil
+	}
+
+	s.items[key] = s.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for s.ll.Len() > s.capacity {
+		s.removeElement(s.ll.Back())
+	}
+	return nil
+}
+
+func (s *lruStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := s.items[key]; ok {
+			s.removeElement(el)
+		}
+	}
+	return nil
+}
+
+// Flush drops every entry; it satisfies Flusher.
+func (s *lruStore) Flush(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.ll.Len()
+	s.ll.Init()
+	clear(s.items)
+	return n, nil
+}
+
+func (s *lruStore) removeElement(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.items, el.Value.(*lruEntry).key)
+}
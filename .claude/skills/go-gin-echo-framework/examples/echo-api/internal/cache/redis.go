@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/your-username/echo-api/internal/lifecycle"
+)
+
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to the Redis instance at url (e.g. redis://localhost:6379/0).
+// All keys are namespaced with prefix so several apps can share one Redis.
+func NewRedisStore(ctx context.Context, lc *lifecycle.Manager, url, prefix string) (Store, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	lc.RegisterCloser("cache redis client", 0, client)
+	return &redisStore{client: client, prefix: prefix}, nil
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get: %w", err)
+	}
+	return val, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set: %w", err)
+	}
+	return nil
+}
+
+func (s *redisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+	if err := s.client.Del(ctx, prefixed...).Err(); err != nil {
+		return fmt.Errorf("redis del: %w", err)
+	}
+	return nil
+}
+
+// Flush deletes the keys under the store's prefix, scanning them in
+// batches rather than flushing the database other apps may share; it
+// satisfies Flusher.
+func (s *redisStore) Flush(ctx context.Context) (int, error) {
+	n := 0
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 500).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := s.client.Del(ctx, batch...).Err(); err != nil {
+				return n, fmt.Errorf("redis del: %w", err)
+			}
+			n += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return n, fmt.Errorf("redis scan: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := s.client.Del(ctx, batch...).Err(); err != nil {
+			return n, fmt.Errorf("redis del: %w", err)
+		}
+		n += len(batch)
+	}
+	return n, nil
+}
+
+// Ping verifies the Redis connection; it satisfies health.Pinger.
+func (s *redisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
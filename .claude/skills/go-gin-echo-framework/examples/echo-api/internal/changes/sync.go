@@ -0,0 +1,136 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// ConflictStrategy tells offline clients how the server resolves concurrent edits.
+const ConflictStrategy = "server-wins"
+
+// Record is one entity in a delta, with the time of its latest change so
+// clients can detect conflicts with their own pending local edits.
+type Record[T any] struct {
+	ID        string    `json:"id"`
+	ChangedAt time.Time `json:"changed_at"`
+	Data      *T        `json:"data"`
+}
+
+type Tombstone struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ConflictHints describe how a client should reconcile local edits with a delta.
+type ConflictHints struct {
+	Strategy string `json:"strategy"`
+	// Conflicts lists IDs in this delta that changed more than once since the
+	// client's token; local edits to them were almost certainly based on stale data.
+	Conflicts []string `json:"conflicts"`
+}
+
+// Delta is the payload of a sync round-trip. When Reset is true the client's
+// token was missing or too old and Created holds the full dataset, which
+// replaces everything the client has.
+type Delta[T any] struct {
+	Token   string        `json:"token"`
+	Reset   bool          `json:"reset"`
+	Created []Record[T]   `json:"created"`
+	Updated []Record[T]   `json:"updated"`
+	Deleted []Tombstone   `json:"deleted"`
+	Hints   ConflictHints `json:"conflict_hints"`
+}
+
+// Source gives the sync builder read access to the current entity state.
+type Source[T any] struct {
+	Get    func(ctx context.Context, id string) (*T, error)
+	List   func(ctx context.Context) ([]T, error)
+	IDOf   func(*T) string
+	Absent func(error) bool // reports whether Get's error means the entity no longer exists
+}
+
+// BuildDelta collapses the feed's changes since token into the final
+// created/updated/deleted state per entity. An empty or expired token yields a
+// full snapshot with Reset set. Only the changes of the tenant of ctx are
+// read, as src reads only its entities.
+func BuildDelta[T any](ctx context.Context, feed *Feed, token string, src Source[T]) (*Delta[T], error) {
+	delta := &Delta[T]{
+		Created: []Record[T]{},
+		Updated: []Record[T]{},
+		Deleted: []Tombstone{},
+		Hints:   ConflictHints{Strategy: ConflictStrategy, Conflicts: []string{}},
+	}
+
+	var pending []Change
+	var err error
+	if token != "" {
+		pending, delta.Token, err = feed.Since(tenant.From(ctx), token)
+	}
+	if token == "" || errors.Is(err, ErrTokenExpired) {
+		return snapshot(ctx, feed, delta, src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type state struct {
+		first, last Change
+		count       int
+	}
+	order := []string{}
+	byID := map[string]*state{}
+	for _, ch := range pending {
+		st, ok := byID[ch.ID]
+		if !ok {
+			st = &state{first: ch}
+			byID[ch.ID] = st
+			order = append(order, ch.ID)
+		}
+		st.last = ch
+		st.count++
+	}
+
+	for _, id := range order {
+		st := byID[id]
+		if st.count > 1 {
+			delta.Hints.Conflicts = append(delta.Hints.Conflicts, id)
+		}
+		if st.last.Op == OpDeleted {
+			delta.Deleted = append(delta.Deleted, Tombstone{ID: id, DeletedAt: st.last.At})
+			continue
+		}
+		entity, err := src.Get(ctx, id)
+		if err != nil {
+			if src.Absent(err) { // deleted after the feed snapshot; the next sync reports it
+				continue
+			}
+			return nil, err
+		}
+		rec := Record[T]{ID: id, ChangedAt: st.last.At, Data: entity}
+		if st.first.Op == OpCreated {
+			delta.Created = append(delta.Created, rec)
+		} else {
+			delta.Updated = append(delta.Updated, rec)
+		}
+	}
+	return delta, nil
+}
+
+func snapshot[T any](ctx context.Context, feed *Feed, delta *Delta[T], src Source[T]) (*Delta[T], error) {
+	// Take the token before listing so changes racing with the listing are
+	// re-delivered on the next sync rather than lost.
+	delta.Token = feed.Token()
+	delta.Reset = true
+	all, err := src.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := feed.clock.Now().UTC()
+	for i := range all {
+		delta.Created = append(delta.Created, Record[T]{ID: src.IDOf(&all[i]), ChangedAt: now, Data: &all[i]})
+	}
+	return delta, nil
+}
@@ -0,0 +1,131 @@
+// Package clock abstracts the current time and tickers. Components whose
+// behaviour depends on time (token expiry, lockouts, TTLs, token buckets,
+// heartbeats) take a Clock instead of calling time.Now, so their tests can
+// use a Fake and step through hours of expiry logic without sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules ticks.
+type Clock interface {
+	Now() time.Time
+	// NewTicker returns a Ticker that ticks every d, like time.NewTicker.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the subset of *time.Ticker that components use.
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// System is the real clock.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// OrSystem returns c, or System when c is nil; constructors use it so that
+// callers, tests in particular, can leave the clock unset.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
+
+// Fake is a Clock that only moves when told to. Its tickers tick during
+// Advance, at most once per call like a real ticker whose reader is slow.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers map[*fakeTicker]struct{}
+}
+
+// NewFake returns a Fake set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, tickers: map[*fakeTicker]struct{}{}}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d and ticks every ticker that came due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for t := range f.tickers {
+		if f.now.Before(t.next) {
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+		for !f.now.Before(t.next) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// Tickers returns the number of running tickers, so a test can wait for a
+// goroutine to have started its ticker before advancing.
+func (f *Fake) Tickers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tickers)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, c: make(chan time.Time, 1), period: d, next: f.now.Add(d)}
+	f.tickers[t] = struct{}{}
+	return t
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	t.clock.tickers[t] = struct{}{}
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	delete(t.clock.tickers, t)
+}
@@ -0,0 +1,192 @@
+// Package compat decides what happens to request input the API does not
+// describe. Strict rejects unknown JSON fields and query parameters with 400;
+// Lenient accepts them, ignores them and logs each occurrence so operators can
+// see which clients still send them before switching back to Strict.
+//
+// Both modes run the validator: compatibility only covers input outside the
+// schema, never values that break it.
+package compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Mode is the compatibility setting.
+type Mode string
+
+const (
+	Strict  Mode = "strict"
+	Lenient Mode = "lenient"
+)
+
+// Valid reports whether m is Strict or Lenient.
+func (m Mode) Valid() bool {
+	return m == Strict || m == Lenient
+}
+
+type contextKey struct{}
+
+type requestInfo struct {
+	mode  Mode
+	label string // "POST /users/ from curl/8.5.0", for log lines
+}
+
+// Middleware records the current mode on each request's context for
+// DecodeJSON and CheckQuery. mode is called per request, so a reloaded config
+// applies to the next request.
+func Middleware(mode func() Mode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := requestInfo{
+				mode:  mode(),
+				label: r.Method + " " + r.URL.Path + " from " + r.UserAgent(),
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, info)))
+		})
+	}
+}
+
+// WithMode returns a context that DecodeJSON and CheckQuery treat as mode,
+// for callers outside an HTTP request such as tests.
+func WithMode(ctx context.Context, mode Mode) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestInfo{mode: mode, label: "request"})
+}
+
+// FromContext returns the mode recorded by Middleware, or Strict.
+func FromContext(ctx context.Context) Mode {
+	info, _ := ctx.Value(contextKey{}).(requestInfo)
+	if info.mode == "" {
+		return Strict
+	}
+	return info.mode
+}
+
+// DecodeJSON unmarshals one JSON value from data into dst. Unknown fields of
+// a top-level object, and in Strict mode anything after the value, are
+// rejected or logged according to the mode on ctx.
+func DecodeJSON(ctx context.Context, data []byte, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("request body is empty")
+		}
+		return err
+	}
+	mode := FromContext(ctx)
+	if mode == Strict && dec.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+	unknown := unknownFields(data, dst)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if mode == Strict {
+		return fmt.Errorf("unknown field(s) %s", strings.Join(unknown, ", "))
+	}
+	logIgnored(ctx, "field(s)", unknown)
+	return nil
+}
+
+// DecodeRequest reads r's body and decodes it with DecodeJSON.
+func DecodeRequest(r *http.Request, dst any) error {
+	if r.Body == nil {
+		return errors.New("request body is empty")
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	return DecodeJSON(r.Context(), data, dst)
+}
+
+// CheckQuery rejects or logs query parameters of r other than allowed.
+func CheckQuery(r *http.Request, allowed ...string) error {
+	var unknown []string
+	for name := range r.URL.Query() {
+		if !contains(allowed, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	if FromContext(r.Context()) == Strict {
+		return fmt.Errorf("unknown query parameter(s) %s", strings.Join(unknown, ", "))
+	}
+	logIgnored(r.Context(), "query parameter(s)", unknown)
+	return nil
+}
+
+func logIgnored(ctx context.Context, what string, names []string) {
+	info, _ := ctx.Value(contextKey{}).(requestInfo)
+	if info.label == "" {
+		info.label = "request"
+	}
+	log.Printf("WARNING: lenient mode ignored unknown %s %s in %s", what, strings.Join(names, ", "), info.label)
+}
+
+// unknownFields returns the keys of the JSON object in data that no field of
+// dst's struct type decodes, sorted. Like encoding/json, names match the json
+// tag or field name case-insensitively; embedded structs are not followed.
+func unknownFields(data []byte, dst any) []string {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil {
+		return nil // not an object
+	}
+	t := reflect.TypeOf(dst)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var known []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		known = append(known, name)
+	}
+	var unknown []string
+	for key := range obj {
+		if !containsFold(known, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package compat
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+type item struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price,omitempty"`
+	Note  string  `json:"-"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    Mode
+		body    string
+		wantErr string
+	}{
+		{"known fields", Strict, `{"id":"1","name":"a","price":2}`, ""},
+		{"field names match case-insensitively", Strict, `{"NAME":"a"}`, ""},
+		{"strict unknown field", Strict, `{"name":"a","colour":"red","age":3}`, "unknown field(s) age, colour"},
+		{"strict ignored field is unknown", Strict, `{"name":"a","-":"x"}`, "unknown field(s) -"},
+		{"strict trailing data", Strict, `{"name":"a"} {"name":"b"}`, "unexpected data after the JSON value"},
+		{"lenient unknown field", Lenient, `{"name":"a","colour":"red"}`, ""},
+		{"lenient trailing data", Lenient, `{"name":"a"} x`, ""},
+		{"empty body", Lenient, ``, "request body is empty"},
+		{"type mismatch fails in both modes", Lenient, `{"price":"2"}`, "json: cannot unmarshal string into Go struct field item.price of type float64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got item
+			err := DecodeJSON(WithMode(context.Background(), tt.mode), []byte(tt.body), &got)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.Name != "a" {
+					t.Fatalf("decoded %+v, want name a", got)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckQuery(t *testing.T) {
+	for _, mode := range []Mode{Strict, Lenient} {
+		r := httptest.NewRequest("GET", "/items/?token=x&page=2&debug", nil)
+		r = r.WithContext(WithMode(r.Context(), mode))
+		err := CheckQuery(r, "token")
+		if mode == Strict && (err == nil || err.Error() != "unknown query parameter(s) debug, page") {
+			t.Errorf("strict: error = %v", err)
+		}
+		if mode == Lenient && err != nil {
+			t.Errorf("lenient: unexpected error %v", err)
+		}
+	}
+}
+
+func TestFromContextDefaultsToStrict(t *testing.T) {
+	if m := FromContext(context.Background()); m != Strict {
+		t.Fatalf("FromContext = %q, want strict", m)
+	}
+}
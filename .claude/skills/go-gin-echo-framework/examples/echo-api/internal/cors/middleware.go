@@ -0,0 +1,20 @@
+package cors
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware applies p to every request and answers preflight requests
+// itself, before authentication or rate limiting see them.
+func Middleware(p *Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if p.Apply(c.Response().Header(), c.Request()) {
+				return c.NoContent(http.StatusNoContent)
+			}
+			return next(c)
+		}
+	}
+}
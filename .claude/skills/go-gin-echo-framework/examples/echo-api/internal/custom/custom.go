@@ -0,0 +1,325 @@
+// Package custom lets tenants extend entities with fields of their own:
+// typed values under the custom object of an item, checked against the
+// Schema of its collection. custom_fields declares the schemas, for every
+// tenant and for each tenant on top; GET /<collection>/schema returns the
+// one a request sees, and lists keep the items whose custom fields match
+// custom.<name>=<value> query parameters.
+package custom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// Prefix starts the query parameters that filter lists on custom fields.
+const Prefix = "custom."
+
+// Type is the type of the values of a field.
+type Type string
+
+const (
+	String  Type = "string"
+	Number  Type = "number"
+	Integer Type = "integer"
+	Boolean Type = "boolean"
+)
+
+// noun names a value of the type, e.g. "an integer".
+func (t Type) noun() string {
+	if t == Integer {
+		return "an integer"
+	}
+	return "a " + string(t)
+}
+
+// Field describes a custom field and the values it accepts.
+type Field struct {
+	Type        Type     `yaml:"type" json:"type"`
+	Required    bool     `yaml:"required" json:"required,omitempty"` // checked on every create and update
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Enum        []string `yaml:"enum" json:"enum,omitempty"`       // of strings, the values allowed
+	Pattern     string   `yaml:"pattern" json:"pattern,omitempty"` // of strings, a regular expression they match
+	Min         *float64 `yaml:"min" json:"min,omitempty"`         // the least number, or length of a string
+	Max         *float64 `yaml:"max" json:"max,omitempty"`         // the greatest number, or length of a string
+}
+
+// Validate checks the type of the field and that its constraints apply to it.
+func (f Field) Validate() error {
+	switch f.Type {
+	case String, Number, Integer, Boolean:
+	default:
+		return fmt.Errorf("type must be string, number, integer or boolean (got %q)", f.Type)
+	}
+	if f.Type != String && (len(f.Enum) > 0 || f.Pattern != "") {
+		return fmt.Errorf("enum and pattern only apply to strings")
+	}
+	if f.Type == Boolean && (f.Min != nil || f.Max != nil) {
+		return fmt.Errorf("min and max do not apply to booleans")
+	}
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		return fmt.Errorf("min %v is greater than max %v", *f.Min, *f.Max)
+	}
+	if f.Pattern != "" {
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return fmt.Errorf("pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// check returns v as the field stores it, numbers as float64, or why the
+// field does not accept it.
+func (f Field) check(v any) (any, error) {
+	switch f.Type {
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if len(f.Enum) > 0 && !slices.Contains(f.Enum, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(f.Enum, ", "))
+		}
+		if f.Pattern != "" && !regexp.MustCompile(f.Pattern).MatchString(s) {
+			return nil, fmt.Errorf("must match %s", f.Pattern)
+		}
+		if err := f.bound(float64(utf8.RuneCountInString(s)), "characters long"); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case Number, Integer:
+		n, ok := number(v)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("must be %s", f.Type.noun())
+		}
+		if f.Type == Integer && n != math.Trunc(n) {
+			return nil, errors.New("must be an integer")
+		}
+		if err := f.bound(n, ""); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("must be a boolean")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("has unknown type %q", f.Type)
+}
+
+// bound checks n, a number or the length of a string, against Min and Max.
+func (f Field) bound(n float64, unit string) error {
+	if unit != "" {
+		unit = " " + unit
+	}
+	if f.Min != nil && n < *f.Min {
+		return fmt.Errorf("must be at least %v%s", *f.Min, unit)
+	}
+	if f.Max != nil && n > *f.Max {
+		return fmt.Errorf("must be at most %v%s", *f.Max, unit)
+	}
+	return nil
+}
+
+// parse reads a value of the field from a query parameter.
+func (f Field) parse(s string) (any, error) {
+	switch f.Type {
+	case Number, Integer:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be %s", f.Type.noun())
+		}
+		return n, nil
+	case Boolean:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	}
+	return s, nil
+}
+
+// number returns the numeric values of JSON and the stores as a float64.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+var fieldName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Schema is the custom fields of a collection, by name.
+type Schema map[string]Field
+
+// Validate checks the names and fields of the schema.
+func (s Schema) Validate() error {
+	for _, name := range s.names() {
+		if !fieldName.MatchString(name) {
+			return fmt.Errorf("%s: names must be lowercase letters, digits and _, starting with a letter", name)
+		}
+		if err := s[name].Validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Check returns values as the schema stores them, or an error naming the
+// first field that is unknown, missing while required or of a value the
+// field does not accept. An empty result is nil.
+func (s Schema) Check(values map[string]any) (map[string]any, error) {
+	for _, name := range sortedKeys(values) {
+		if _, ok := s[name]; !ok {
+			return nil, fmt.Errorf("%s%s is not a custom field", Prefix, name)
+		}
+	}
+	var out map[string]any
+	for _, name := range s.names() {
+		v, ok := values[name]
+		if !ok || v == nil {
+			if s[name].Required {
+				return nil, fmt.Errorf("%s%s is required", Prefix, name)
+			}
+			continue
+		}
+		v, err := s[name].check(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s%s %w", Prefix, name, err)
+		}
+		if out == nil {
+			out = map[string]any{}
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// Params returns the query parameters that filter on the fields of the
+// schema, sorted.
+func (s Schema) Params() []string {
+	params := s.names()
+	for i, name := range params {
+		params[i] = Prefix + name
+	}
+	return params
+}
+
+func (s Schema) names() []string {
+	return sortedKeys(s)
+}
+
+// Filter keeps the items whose custom fields equal its values, by name.
+type Filter map[string]any
+
+// ParseFilter reads the custom.<name>=<value> parameters of query naming
+// a field of s into a Filter. Other parameters are ignored: compat.CheckQuery
+// rejects those it does not allow.
+func ParseFilter(s Schema, query url.Values) (Filter, error) {
+	var f Filter
+	for param, values := range query {
+		name, ok := strings.CutPrefix(param, Prefix)
+		if !ok {
+			continue
+		}
+		field, ok := s[name]
+		if !ok {
+			continue
+		}
+		v, err := field.parse(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", param, err)
+		}
+		if f == nil {
+			f = Filter{}
+		}
+		f[name] = v
+	}
+	return f, nil
+}
+
+// Match reports whether values hold every value of the filter.
+func (f Filter) Match(values map[string]any) bool {
+	for name, want := range f {
+		got := values[name]
+		if n, ok := number(got); ok {
+			got = n
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Options declare the custom fields of each collection.
+type Options struct {
+	Collections map[string]Schema            `yaml:"collections"` // of every tenant, by collection, e.g. products
+	Tenants     map[string]map[string]Schema `yaml:"tenants"`     // added by a tenant, by tenant and collection
+}
+
+// Validate checks the tenants and schemas.
+func (o Options) Validate() error {
+	for _, collection := range sortedKeys(o.Collections) {
+		if err := o.Collections[collection].Validate(); err != nil {
+			return fmt.Errorf("collections.%s.%w", collection, err)
+		}
+	}
+	for _, id := range sortedKeys(o.Tenants) {
+		if err := tenant.Check(id); err != nil {
+			return fmt.Errorf("tenants.%s: %w", id, err)
+		}
+		for _, collection := range sortedKeys(o.Tenants[id]) {
+			if err := o.Tenants[id][collection].Validate(); err != nil {
+				return fmt.Errorf("tenants.%s.%s.%w", id, collection, err)
+			}
+		}
+	}
+	return nil
+}
+
+// For returns the custom fields of collection in tenant id, "" being
+// none: those of every tenant and the tenant's own, which replace any of
+// the same name.
+func (o Options) For(id, collection string) Schema {
+	s := Schema{}
+	for name, f := range o.Collections[collection] {
+		s[name] = f
+	}
+	if id != "" {
+		for name, f := range o.Tenants[id][collection] {
+			s[name] = f
+		}
+	}
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
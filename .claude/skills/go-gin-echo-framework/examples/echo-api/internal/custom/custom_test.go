@@ -0,0 +1,99 @@
+package custom
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestCheckValidatesAndNormalizesValues(t *testing.T) {
+	s := Schema{
+		"team":  {Type: String, Enum: []string{"compilers", "languages"}, Required: true},
+		"code":  {Type: String, Pattern: `^[A-Z]{3}$`},
+		"bio":   {Type: String, Max: ptr(5)},
+		"seats": {Type: Integer, Min: ptr(1), Max: ptr(10)},
+		"score": {Type: Number},
+		"vip":   {Type: Boolean},
+	}
+
+	got, err := s.Check(map[string]any{"team": "compilers", "seats": float64(3), "score": int32(2), "vip": true, "code": nil})
+	want := map[string]any{"team": "compilers", "seats": float64(3), "score": float64(2), "vip": true}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Check = %v, %v; want %v", got, err, want)
+	}
+
+	for msg, values := range map[string]map[string]any{
+		"custom.team is required":                         {},
+		"custom.team must be one of compilers, languages": {"team": "hardware"},
+		"custom.code must match ^[A-Z]{3}$":               {"team": "compilers", "code": "abc"},
+		"custom.bio must be at most 5 characters long":    {"team": "compilers", "bio": "ünïcödé"},
+		"custom.seats must be an integer":                 {"team": "compilers", "seats": 1.5},
+		"custom.seats must be at least 1":                 {"team": "compilers", "seats": float64(0)},
+		"custom.score must be a number":                   {"team": "compilers", "score": "2"},
+		"custom.vip must be a boolean":                    {"team": "compilers", "vip": "yes"},
+		"custom.floor is not a custom field":              {"team": "compilers", "floor": float64(3)},
+	} {
+		if _, err := s.Check(values); err == nil || err.Error() != msg {
+			t.Errorf("Check(%v) = %v, want %q", values, err, msg)
+		}
+	}
+
+	if got, err := (Schema{}).Check(nil); got != nil || err != nil {
+		t.Errorf("Check of no fields = %v, %v", got, err)
+	}
+}
+
+func TestFiltersParseValuesByType(t *testing.T) {
+	s := Schema{"team": {Type: String}, "seats": {Type: Integer}, "vip": {Type: Boolean}}
+	query := url.Values{"custom.seats": {"3"}, "custom.vip": {"true"}, "custom.floor": {"2"}, "fields": {"name"}}
+	f, err := ParseFilter(s, query)
+	if err != nil || !reflect.DeepEqual(f, Filter{"seats": float64(3), "vip": true}) {
+		t.Fatalf("ParseFilter = %v, %v", f, err)
+	}
+	if !f.Match(map[string]any{"seats": float64(3), "vip": true, "team": "x"}) || !f.Match(map[string]any{"seats": int64(3), "vip": true}) {
+		t.Error("filter misses a match")
+	}
+	if f.Match(map[string]any{"seats": float64(3)}) || f.Match(nil) {
+		t.Error("filter matches items lacking a field")
+	}
+	if _, err := ParseFilter(s, url.Values{"custom.seats": {"many"}}); err == nil || err.Error() != "custom.seats must be an integer" {
+		t.Errorf("ParseFilter of a malformed number: %v", err)
+	}
+}
+
+func TestTenantsAddFieldsToThoseOfEveryTenant(t *testing.T) {
+	o := Options{
+		Collections: map[string]Schema{"products": {"team": {Type: String}}},
+		Tenants:     map[string]map[string]Schema{"acme": {"products": {"team": {Type: String, Required: true}, "seats": {Type: Integer}}}},
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.For("acme", "products"); len(got) != 2 || !got["team"].Required {
+		t.Errorf("fields of acme: %v", got)
+	}
+	if got := o.For("globex", "products"); len(got) != 1 || got["team"].Required {
+		t.Errorf("fields of globex: %v", got)
+	}
+	if got := o.For("", "orders"); len(got) != 0 {
+		t.Errorf("fields of an undeclared collection: %v", got)
+	}
+
+	for opts, msg := range map[*Options]string{
+		{Collections: map[string]Schema{"products": {"Team": {Type: String}}}}:                             "collections.products.Team: names must be",
+		{Collections: map[string]Schema{"products": {"team": {Type: "date"}}}}:                             "collections.products.team: type must be",
+		{Collections: map[string]Schema{"products": {"seats": {Type: Integer, Enum: []string{}}}}}:         "",
+		{Collections: map[string]Schema{"products": {"seats": {Type: Integer, Pattern: "x"}}}}:             "collections.products.seats: enum and pattern only apply to strings",
+		{Collections: map[string]Schema{"products": {"seats": {Type: Integer, Min: ptr(2), Max: ptr(1)}}}}: "collections.products.seats: min 2 is greater than max 1",
+		{Collections: map[string]Schema{"products": {"code": {Type: String, Pattern: "("}}}}:               "collections.products.code: pattern:",
+		{Tenants: map[string]map[string]Schema{"Acme": {}}}:                                                "tenants.Acme:",
+	} {
+		err := opts.Validate()
+		if msg == "" && err != nil || msg != "" && (err == nil || !strings.HasPrefix(err.Error(), msg)) {
+			t.Errorf("Validate(%+v) = %v, want %q", *opts, err, msg)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MessageHandler processes one Message received from a broker. Wrap it in
+// inbox.Inbox.Handle so that redelivered messages are processed once.
+type MessageHandler func(ctx context.Context, m Message) error
+
+// Consumer receives the messages that an EventPublisher of another
+// instance or service published to a broker.
+type Consumer interface {
+	// Consume calls fn for each message, one at a time, until ctx is done
+	// and returns nil then, or returns an error receiving fails with.
+	Consume(ctx context.Context, fn MessageHandler) error
+}
+
+// Decode parses the Message of data, as Encode wrote it.
+func Decode(data []byte) (Message, error) {
+	var m Message
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
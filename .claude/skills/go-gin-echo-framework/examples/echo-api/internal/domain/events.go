@@ -0,0 +1,94 @@
+// Package domain carries typed domain events out of the service layer. The
+// CRUD service emits Created, Updated and Deleted events once a write has
+// committed, and an EventPublisher hands them to in-process subscribers
+// (InProc) or to a broker (NATS, Kafka) for other services to consume
+// with a Consumer.
+//
+// Unlike the event bus behind the streaming endpoints (internal/events),
+// which serves API clients, domain events are meant for integration:
+// they are typed for Go subscribers and encoded as Message on the wire.
+// Package eventschema checks their payloads against what the consuming
+// services read.
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/your-username/echo-api/internal/model"
+)
+
+// Event is something that happened to one aggregate, such as a product.
+type Event interface {
+	// EventName is "<resource>.<op>", e.g. "product.created". Brokers route
+	// on it.
+	EventName() string
+	// AggregateID is the ID of the entity the event is about. Brokers
+	// order the events of one aggregate by it.
+	AggregateID() string
+}
+
+// Created is emitted when an entity was created.
+type Created[T model.Entity] struct {
+	Resource string    `json:"resource"` // singular resource name, e.g. "product"
+	Entity   T         `json:"entity"`
+	At       time.Time `json:"at"`
+}
+
+func (e Created[T]) EventName() string   { return e.Resource + ".created" }
+func (e Created[T]) AggregateID() string { return e.Entity.GetID() }
+
+// Updated is emitted when an entity was replaced; Entity is the new state.
+type Updated[T model.Entity] struct {
+	Resource string    `json:"resource"`
+	Entity   T         `json:"entity"`
+	At       time.Time `json:"at"`
+}
+
+func (e Updated[T]) EventName() string   { return e.Resource + ".updated" }
+func (e Updated[T]) AggregateID() string { return e.Entity.GetID() }
+
+// Deleted is emitted when an entity was deleted. T only types the event,
+// so subscribers can tell the deletes of one resource from another's.
+type Deleted[T model.Entity] struct {
+	Resource string    `json:"resource"`
+	ID       string    `json:"id"`
+	At       time.Time `json:"at"`
+}
+
+func (e Deleted[T]) EventName() string   { return e.Resource + ".deleted" }
+func (e Deleted[T]) AggregateID() string { return e.ID }
+
+// Events returns an event of each kind the CRUD service of T publishes for
+// resource, e.g. to derive their schemas (see package eventschema).
+func Events[T model.Entity](resource string) []Event {
+	return []Event{Created[T]{Resource: resource}, Updated[T]{Resource: resource}, Deleted[T]{Resource: resource}}
+}
+
+// Message is the wire form of an event on a broker.
+type Message struct {
+	// ID identifies events relayed from the outbox, which are delivered at
+	// least once; consumers deduplicate by it (see package inbox).
+	ID          string          `json:"id,omitempty"`
+	Name        string          `json:"name"` // EventName, e.g. "product.created"
+	AggregateID string          `json:"aggregate_id"`
+	Data        json.RawMessage `json:"data"` // the event as JSON
+}
+
+// Identified is implemented by events that carry a Message ID.
+type Identified interface {
+	EventID() string
+}
+
+// Encode returns the Message of e as JSON.
+func Encode(e Event) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	m := Message{Name: e.EventName(), AggregateID: e.AggregateID(), Data: data}
+	if id, ok := e.(Identified); ok {
+		m.ID = id.EventID()
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/your-username/echo-api/internal/lifecycle"
+)
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher writes every event to topic as a Message keyed by its
+// aggregate ID, so the events of one entity land on one partition in order.
+// The event name is also set as the "event" header for consumers that
+// filter without decoding. One of brokers must be reachable at startup.
+func NewKafkaPublisher(ctx context.Context, lc *lifecycle.Manager, brokers []string, topic string) (EventPublisher, error) {
+	var errs []error
+	reachable := false
+	for _, broker := range brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		conn.Close()
+		reachable = true
+		break
+	}
+	if !reachable {
+		return nil, fmt.Errorf("failed to connect to kafka: %w", errors.Join(errs...))
+	}
+
+	w := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		WriteTimeout:           publishTimeout,
+	}
+	lc.RegisterCloser("kafka publisher", 0, w)
+	return &kafkaPublisher{writer: w}, nil
+}
+
+// Publish waits for every in-sync replica to have acknowledged the message.
+func (p *kafkaPublisher) Publish(ctx context.Context, e Event) error {
+	data, err := Encode(e)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", e.EventName(), err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(e.AggregateID()),
+		Value:   data,
+		Headers: []kafka.Header{{Key: "event", Value: []byte(e.EventName())}},
+	})
+	if err != nil {
+		return fmt.Errorf("kafka publish %s: %w", e.EventName(), err)
+	}
+	return nil
+}
+
+// maxRetryBackoff caps the wait before a consumer retries a message its
+// handler failed.
+const maxRetryBackoff = 30 * time.Second
+
+type kafkaConsumer struct {
+	reader *kafka.Reader
+}
+
+// NewKafkaConsumer reads topic as a member of the consumer group, so the
+// instances of one group share its partitions. The offset of a message is
+// committed once the handler succeeds; until then the handler is retried
+// with a growing backoff, holding back the messages behind it, so an
+// aggregate's events are processed in order. After a rebalance or a crash
+// the group resumes from the last commit and redelivers what followed it.
+// The reader is closed by lc.
+func NewKafkaConsumer(lc *lifecycle.Manager, brokers []string, topic, group string) Consumer {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: group,
+	})
+	lc.RegisterCloser("kafka consumer", 0, r)
+	return &kafkaConsumer{reader: r}
+}
+
+func (c *kafkaConsumer) Consume(ctx context.Context, fn MessageHandler) error {
+	for {
+		km, err := c.reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("kafka consume: %w", err)
+		}
+		if m, err := Decode(km.Value); err != nil {
+			log.Printf("WARNING: kafka consume: skipped undecodable message at offset %d of partition %d: %v", km.Offset, km.Partition, err)
+		} else if !retry(ctx, m, fn) {
+			return nil
+		}
+		if err := c.reader.CommitMessages(ctx, km); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("kafka commit: %w", err)
+		}
+	}
+}
+
+// retry calls fn for m until it succeeds, waiting between failures, and
+// returns false if ctx was done first.
+func retry(ctx context.Context, m Message, fn MessageHandler) bool {
+	wait := 100 * time.Millisecond
+	for {
+		err := fn(ctx, m)
+		if err == nil {
+			return true
+		}
+		log.Printf("WARNING: %s %s: %v; retrying in %s", m.Name, m.ID, err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return false
+		}
+		wait = min(2*wait, maxRetryBackoff)
+	}
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "github.com/your-username/echo-api/internal/model"
+
+// The events of the product resource.
+type (
+	ProductCreated = Created[model.Product]
+	ProductUpdated = Updated[model.Product]
+	ProductDeleted = Deleted[model.Product]
+)
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServeSSE writes the events of sub to w as Server-Sent Events, each with
+// its Seq as id and its Op as event type, until the client goes away or the
+// subscription ends. A comment line every heartbeat keeps proxies from
+// closing the connection while no events arrive. The server's write
+// timeout is lifted for the stream. A subscription dropped for falling
+// behind ends the stream with a "dropped" comment; the client should resync
+// and reconnect.
+func ServeSSE(w http.ResponseWriter, r *http.Request, sub *Subscription, heartbeat time.Duration) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // not every wrapper unwraps; the stream then ends at the write timeout
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx would buffer the stream otherwise
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	rc.Flush()
+
+	tick := sub.bus.clock.NewTicker(heartbeat)
+	defer tick.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					fmt.Fprint(w, ": dropped\n\n")
+					rc.Flush()
+				}
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Op, data); err != nil {
+				return
+			}
+			tick.Reset(heartbeat)
+		case <-tick.C():
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message is what a WebSocket connection sends in either direction, as a
+// JSON text frame.
+//
+// Clients send "subscribe" and "unsubscribe" with IDs. A connection starts
+// out receiving every ID, unless it was opened with some; subscribing to IDs
+// from there narrows it to exactly those, later subscribes add to them,
+// unsubscribes remove, and a subscribe without IDs goes back to all. Every
+// change is answered with "subscriptions", the resulting set.
+//
+// The server sends "event" for each matching event, "subscriptions", and
+// "error" for messages it did not understand.
+type Message struct {
+	Type  string   `json:"type"`
+	IDs   []string `json:"ids,omitempty"`
+	All   bool     `json:"all,omitempty"` // in "subscriptions": every ID is received
+	Event *Event   `json:"event,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// WebSocketOptions configure WebSockets.
+type WebSocketOptions struct {
+	Heartbeat time.Duration // ping interval; a connection is dropped after two without a pong
+	Buffer    int           // events queued per connection before it is dropped for falling behind
+
+	// AllowOrigin admits browser connections from other origins than the
+	// API's own; nil admits none.
+	AllowOrigin func(origin string) bool
+}
+
+const (
+	writeWait  = 10 * time.Second // for one frame to be written
+	closeGrace = time.Second      // for the client to answer a close frame
+)
+
+// WebSockets serves event subscriptions over WebSocket connections, the
+// bidirectional counterpart of ServeSSE. Connections are hijacked from the
+// HTTP server, which no longer tracks them, so WebSockets does: Shutdown
+// closes them cleanly and waits for them.
+type WebSockets struct {
+	bus      *Bus
+	opts     WebSocketOptions
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	stopping bool
+	stop     chan struct{}
+	conns    sync.WaitGroup
+}
+
+func NewWebSockets(bus *Bus, opts WebSocketOptions) *WebSockets {
+	ws := &WebSockets{bus: bus, opts: opts, stop: make(chan struct{})}
+	ws.upgrader.CheckOrigin = ws.checkOrigin
+	return ws
+}
+
+// checkOrigin admits non-browser clients, which send no Origin, the API's
+// own origin and those opts.AllowOrigin accepts.
+func (ws *WebSockets) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return ws.opts.AllowOrigin != nil && ws.opts.AllowOrigin(origin)
+}
+
+// Serve upgrades the request and sends the events matching f until either
+// side closes the connection. The IDs of f are the connection's initial
+// subscriptions. Once shutting down, requests are refused with 503.
+func (ws *WebSockets) Serve(w http.ResponseWriter, r *http.Request, f Filter) {
+	ws.mu.Lock()
+	if ws.stopping {
+		ws.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Shutting down"})
+		return
+	}
+	ws.conns.Add(1)
+	ws.mu.Unlock()
+	defer ws.conns.Done()
+
+	conn, err := ws.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader has answered
+	}
+	defer conn.Close()
+
+	ids := f.IDs
+	f.IDs = nil // matched here, as the subscriptions change
+	sub := ws.bus.Subscribe(f, ws.opts.Buffer)
+	defer sub.Close()
+	c := &wsConn{conn: conn, all: len(ids) == 0, ids: map[string]bool{}}
+	for _, id := range ids {
+		c.ids[id] = true
+	}
+	c.run(sub, ws.opts.Heartbeat, ws.stop)
+}
+
+// Shutdown refuses new connections and closes the open ones with 1001
+// (going away), waiting for them until ctx is done.
+func (ws *WebSockets) Shutdown(ctx context.Context) error {
+	ws.mu.Lock()
+	if !ws.stopping {
+		ws.stopping = true
+		close(ws.stop)
+	}
+	ws.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ws.conns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wsConn is one connection. Only run writes to it; the reader goroutine
+// hands the client's messages over.
+type wsConn struct {
+	conn *websocket.Conn
+	all  bool
+	ids  map[string]bool
+}
+
+func (c *wsConn) run(sub *Subscription, heartbeat time.Duration, stop <-chan struct{}) {
+	received := make(chan Message)
+	gone := make(chan struct{}) // the client closed the connection, or stopped answering pings
+	quit := make(chan struct{})
+	defer close(quit)
+	go c.read(heartbeat, received, gone, quit)
+
+	ping := time.NewTicker(heartbeat)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					c.close(websocket.CloseTryAgainLater, "fell behind; resync and reconnect", gone)
+				} else {
+					c.close(websocket.CloseGoingAway, "shutting down", gone)
+				}
+				return
+			}
+			if c.all || c.ids[e.ID] {
+				err = c.send(Message{Type: "event", Event: &e})
+			}
+		case m := <-received:
+			err = c.send(c.handle(m))
+		case <-ping.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		case <-stop:
+			c.close(websocket.CloseGoingAway, "shutting down", gone)
+			return
+		case <-gone:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// read delivers the client's messages until the connection fails. Every
+// frame, pongs included, extends the read deadline.
+func (c *wsConn) read(heartbeat time.Duration, received chan<- Message, gone, quit chan struct{}) {
+	defer close(gone)
+	c.conn.SetReadLimit(64 << 10)
+	extend := func(string) error { return c.conn.SetReadDeadline(time.Now().Add(2 * heartbeat)) }
+	extend("")
+	c.conn.SetPongHandler(extend)
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		extend("")
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			m = Message{Type: "error", Error: "messages must be JSON: " + err.Error()}
+		}
+		select {
+		case received <- m:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// handle applies a client message and returns the answer.
+func (c *wsConn) handle(m Message) Message {
+	switch m.Type {
+	case "error":
+		return m
+	case "subscribe":
+		if len(m.IDs) == 0 {
+			c.all, c.ids = true, map[string]bool{}
+			break
+		}
+		c.all = false
+		for _, id := range m.IDs {
+			c.ids[id] = true
+		}
+	case "unsubscribe":
+		if c.all {
+			return Message{Type: "error", Error: "subscribed to every ID; subscribe to some first"}
+		}
+		for _, id := range m.IDs {
+			delete(c.ids, id)
+		}
+	default:
+		return Message{Type: "error", Error: `unknown message type; use "subscribe" or "unsubscribe"`}
+	}
+	ids := make([]string, 0, len(c.ids))
+	for id := range c.ids {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return Message{Type: "subscriptions", IDs: ids, All: c.all}
+}
+
+func (c *wsConn) send(m Message) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteJSON(m)
+}
+
+// close sends a close frame and waits briefly for the client's, so the
+// connection ends cleanly on both sides.
+func (c *wsConn) close(code int, reason string, gone <-chan struct{}) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	select {
+	case <-gone:
+	case <-time.After(closeGrace):
+	}
+}
@@ -0,0 +1,261 @@
+// Package eventschema keeps the domain events compatible with the services
+// that consume them. A consumer team commits the part of each event's
+// payload it reads, as a JSON Schema, to consumers/<consumer>.json; Check
+// derives the schemas of the published events from their Go types and
+// reports every expectation they no longer meet, so that renaming a field,
+// changing its type or making it optional fails startup and the tests
+// instead of the consumer:
+//
+//	{
+//	  "consumer": "analytics",
+//	  "events": {
+//	    "product.created": {
+//	      "type": "object",
+//	      "required": ["entity", "at"],
+//	      "properties": {
+//	        "entity": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}},
+//	        "at": {"type": "string", "format": "date-time"}
+//	      }
+//	    }
+//	  }
+//	}
+//
+// Adding fields and events is always compatible. Of the JSON Schema
+// keywords, Check understands type, format, nullable (as in OpenAPI),
+// properties, required and items; it ignores the others.
+package eventschema
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-username/echo-api/internal/domain"
+)
+
+//go:embed consumers/*.json
+var consumers embed.FS
+
+// Schema is the subset of JSON Schema Check compares. An empty Type
+// accepts any value.
+type Schema struct {
+	Type       string             `json:"type,omitempty"` // object, array, string, integer, number or boolean
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+// Consumer is what one consuming service reads of the events it handles,
+// by event name.
+type Consumer struct {
+	Name   string             `json:"consumer"`
+	Events map[string]*Schema `json:"events"`
+}
+
+// Registered returns the consumers committed to consumers/.
+func Registered() ([]Consumer, error) {
+	sub, err := fs.Sub(consumers, "consumers")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load reads the consumers of the *.json files in fsys, in file name
+// order.
+func Load(fsys fs.FS) ([]Consumer, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	var all []Consumer
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var c Consumer
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if c.Name == "" {
+			c.Name = strings.TrimSuffix(name, ".json")
+		}
+		all = append(all, c)
+	}
+	return all, nil
+}
+
+// Of returns the schema of v's JSON encoding, following the rules of
+// encoding/json: fields are named by their json tag, omitempty makes them
+// optional, embedded structs are inlined and pointers may be null.
+func Of(v any) *Schema {
+	return of(reflect.TypeOf(v))
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func of(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := of(t.Elem())
+		s.Nullable = true
+		return s
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		return &Schema{} // encodes itself, e.g. json.RawMessage
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: of(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", Nullable: true}
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(s, t)
+		sort.Strings(s.Required)
+		return s
+	}
+	return &Schema{}
+}
+
+// addFields adds the JSON fields of struct type t to s.
+func addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(s, ft)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = of(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// Schemas returns the schemas of events by their EventName.
+func Schemas(events []domain.Event) map[string]*Schema {
+	schemas := make(map[string]*Schema, len(events))
+	for _, e := range events {
+		schemas[e.EventName()] = Of(e)
+	}
+	return schemas
+}
+
+// Check reports every expectation of consumers that the published events
+// do not meet: events no longer published, and fields removed, of another
+// type or format, no longer always present, or null where the consumer
+// does not expect it.
+func Check(published []domain.Event, consumers ...Consumer) error {
+	schemas := Schemas(published)
+	var problems []error
+	for _, c := range consumers {
+		names := make([]string, 0, len(c.Events))
+		for name := range c.Events {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			actual, ok := schemas[name]
+			if !ok {
+				problems = append(problems, fmt.Errorf("%s reads %s, which is no longer published", c.Name, name))
+				continue
+			}
+			for _, p := range compare("$", c.Events[name], actual) {
+				problems = append(problems, fmt.Errorf("%s reads %s: %s", c.Name, name, p))
+			}
+		}
+	}
+	if err := errors.Join(problems...); err != nil {
+		return fmt.Errorf("domain events break their consumers:\n%w", err)
+	}
+	return nil
+}
+
+// compare returns how actual, at path, fails to meet expected.
+func compare(path string, expected, actual *Schema) []string {
+	if expected == nil || actual == nil {
+		return nil
+	}
+	if expected.Type != "" && expected.Type != actual.Type && !(expected.Type == "number" && actual.Type == "integer") {
+		if actual.Type == "" {
+			return []string{fmt.Sprintf("%s may be any value, want %s", path, expected.Type)}
+		}
+		return []string{fmt.Sprintf("%s is %s, want %s", path, actual.Type, expected.Type)}
+	}
+	var problems []string
+	if expected.Format != "" && expected.Format != actual.Format {
+		problems = append(problems, fmt.Sprintf("%s has format %q, want %q", path, actual.Format, expected.Format))
+	}
+	if actual.Nullable && !expected.Nullable {
+		problems = append(problems, fmt.Sprintf("%s may be null", path))
+	}
+	var names []string
+	for name := range expected.Properties {
+		names = append(names, name)
+	}
+	for _, name := range expected.Required {
+		if _, ok := expected.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, ok := actual.Properties[name]
+		switch {
+		case !ok && actual.Properties != nil:
+			problems = append(problems, fmt.Sprintf("%s.%s is no longer sent", path, name))
+		case !ok:
+			// a map or any value: its properties are not known
+		case slices.Contains(expected.Required, name) && !slices.Contains(actual.Required, name):
+			problems = append(problems, fmt.Sprintf("%s.%s may be omitted", path, name))
+			fallthrough
+		default:
+			problems = append(problems, compare(path+"."+name, expected.Properties[name], field)...)
+		}
+	}
+	return append(problems, compare(path+"[]", expected.Items, actual.Items)...)
+}
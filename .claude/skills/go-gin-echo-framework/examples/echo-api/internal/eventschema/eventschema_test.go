@@ -0,0 +1,74 @@
+package eventschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/model"
+)
+
+type noted struct {
+	ID    string          `json:"id"`
+	Email string          `json:"email,omitempty"`
+	Count int             `json:"count"`
+	Tags  []string        `json:"tags"`
+	Note  *string         `json:"note"`
+	At    time.Time       `json:"at"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func (noted) EventName() string     { return "note.created" }
+func (n noted) AggregateID() string { return n.ID }
+
+func TestCheckReportsWhatConsumersCanNoLongerRead(t *testing.T) {
+	var consumer Consumer
+	err := json.Unmarshal([]byte(`{
+		"consumer": "mailer",
+		"events": {
+			"note.created": {
+				"type": "object",
+				"required": ["id", "email", "count"],
+				"properties": {
+					"id": {"type": "string"},
+					"count": {"type": "number"},
+					"title": {"type": "string"},
+					"tags": {"type": "array", "nullable": true, "items": {"type": "string"}},
+					"note": {"type": "string"},
+					"at": {"type": "string", "format": "date"},
+					"data": {"type": "object"}
+				}
+			},
+			"note.deleted": {"type": "object"}
+		}
+	}`), &consumer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Check([]domain.Event{noted{}}, consumer)
+	want := []string{
+		"domain events break their consumers:",
+		`mailer reads note.created: $.at has format "date-time", want "date"`,
+		"mailer reads note.created: $.data may be any value, want object",
+		"mailer reads note.created: $.email may be omitted",
+		"mailer reads note.created: $.note may be null",
+		"mailer reads note.created: $.title is no longer sent",
+		"mailer reads note.deleted, which is no longer published",
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("Check = %v, want\n%s", err, strings.Join(want, "\n"))
+	}
+}
+
+func TestRegisteredConsumersReadTheProductEvents(t *testing.T) {
+	consumers, err := Registered()
+	if err != nil || len(consumers) == 0 {
+		t.Fatalf("Registered = %v, %v", consumers, err)
+	}
+	if err := Check(domain.Events[model.Product]("product"), consumers...); err != nil {
+		t.Error(err)
+	}
+}
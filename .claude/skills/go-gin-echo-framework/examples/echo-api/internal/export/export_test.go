@@ -0,0 +1,87 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/blob"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/idgen"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+type item struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestExporter(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	store := blob.NewLocal(t.TempDir(), "/blobs", []byte("key"))
+	items := map[string][]item{"acme": {{"1", "Ann"}, {"2", "Bob"}}}
+	sources := map[string]Source{
+		"items": Stream(func(ctx context.Context, fn func(item) error) error {
+			for _, it := range items[tenant.From(ctx)] {
+				if err := fn(it); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+		"broken": func(context.Context) (any, int, error) { return nil, 0, errors.New("disk on fire") },
+	}
+	x := New(store, clk, idgen.NewSequential("export-"), Options{MaxPending: 2, Retention: time.Hour}, time.Minute, sources)
+	acme, globex := tenant.With(context.Background(), "acme"), tenant.With(context.Background(), "globex")
+
+	e, err := x.Request(acme, "items", "csv")
+	if err != nil || e.Status != Pending {
+		t.Fatalf("Request = %+v, %v", e, err)
+	}
+	broken, _ := x.Request(acme, "broken", "json")
+	if _, err := x.Request(acme, "items", "json"); !errors.Is(err, ErrBusy) {
+		t.Errorf("Request beyond max_pending: %v", err)
+	}
+	for collection, format := range map[string]string{"users": "csv", "items": "xlsx"} {
+		if _, err := x.Request(globex, collection, format); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Request of %s as %s: %v", collection, format, err)
+		}
+	}
+
+	if err := x.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("Run: %v", err)
+	}
+	if _, err := x.Get(globex, e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get in another tenant: %v", err)
+	}
+	if got, _ := x.Get(acme, broken.ID); got.Status != Failed || got.Error != "disk on fire" {
+		t.Errorf("Get of the broken export = %+v", got)
+	}
+	got, err := x.Get(acme, e.ID)
+	if err != nil || got.Status != Done || got.Items != 2 || !strings.HasPrefix(got.URL, "/blobs/exports/acme/"+e.ID+".csv?") {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	r, _, err := store.Get(context.Background(), "exports/acme/"+e.ID+".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, _ := io.ReadAll(r)
+	r.Close()
+	if want := "id,name\n1,Ann\n2,Bob\n"; string(file) != want {
+		t.Errorf("file = %q, want %q", file, want)
+	}
+
+	clk.Advance(2 * time.Hour)
+	if err := x.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.Get(acme, e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after the retention: %v", err)
+	}
+	if _, _, err := store.Get(context.Background(), "exports/acme/"+e.ID+".csv"); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("file after the retention: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package fakedata generates realistic fake people, products and prices for
+// seeding, mock mode and tests. A Faker is deterministic: the same seed and
+// locale always produce the same sequence of values, so seeded databases and
+// test fixtures are reproducible. Emails and SKUs are always ASCII; names
+// and product names are in the locale's language and script.
+package fakedata
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// Name is a word in the locale's script with its ASCII transliteration,
+// used where only ASCII is valid (email addresses, SKUs).
+type Name struct {
+	Text  string
+	ASCII string
+}
+
+// Locale holds the vocabulary and conventions of one language and region.
+type Locale struct {
+	Tag         string // BCP 47, e.g. de-DE
+	FirstNames  []Name
+	LastNames   []Name
+	FamilyFirst bool   // full names are written "last first"
+	Products    []Name // what a shop sells; ASCII is the SKU category
+	Series      []string
+	Decimals    int     // price precision of the currency; 0 for yen
+	MinPrice    float64 // price range, in the currency's major unit
+	MaxPrice    float64
+}
+
+// DefaultLocale is used for tags Locales has no match for.
+const DefaultLocale = "en-US"
+
+// Locales are the built-in locales by tag. Add to them before creating
+// Fakers.
+var Locales = map[string]*Locale{
+	"en-US": {
+		Tag:        "en-US",
+		FirstNames: ascii("Ada", "Alan", "Grace", "Barbara", "Ken", "Margaret", "Dennis", "Frances", "John", "Radia"),
+		LastNames:  ascii("Lovelace", "Turing", "Hopper", "Liskov", "Thompson", "Hamilton", "Ritchie", "Allen", "Backus", "Perlman"),
+		Products: []Name{
+			{"Desk Lamp", "LMP"}, {"Office Chair", "CHR"}, {"Notebook", "NTB"}, {"Water Bottle", "BTL"},
+			{"Backpack", "BPK"}, {"Headphones", "HPH"}, {"Coffee Mug", "MUG"}, {"Keyboard", "KBD"},
+		},
+		Series:   series,
+		Decimals: 2, MinPrice: 2, MaxPrice: 500,
+	},
+	"de-DE": {
+		Tag:        "de-DE",
+		FirstNames: []Name{{"Jürgen", "juergen"}, {"Anna", "anna"}, {"Lukas", "lukas"}, {"Sophie", "sophie"}, {"Käthe", "kaethe"}, {"Felix", "felix"}, {"Marie", "marie"}, {"Jonas", "jonas"}},
+		LastNames:  []Name{{"Müller", "mueller"}, {"Schmidt", "schmidt"}, {"Schröder", "schroeder"}, {"Weiß", "weiss"}, {"Becker", "becker"}, {"Hoffmann", "hoffmann"}, {"Krüger", "krueger"}, {"Zuse", "zuse"}},
+		Products: []Name{
+			{"Schreibtischlampe", "LMP"}, {"Bürostuhl", "CHR"}, {"Notizbuch", "NTB"}, {"Trinkflasche", "BTL"},
+			{"Rucksack", "BPK"}, {"Kopfhörer", "HPH"}, {"Kaffeebecher", "MUG"}, {"Tastatur", "KBD"},
+		},
+		Series:   series,
+		Decimals: 2, MinPrice: 2, MaxPrice: 450,
+	},
+	"fr-FR": {
+		Tag:        "fr-FR",
+		FirstNames: []Name{{"Élodie", "elodie"}, {"François", "francois"}, {"Chloé", "chloe"}, {"Léa", "lea"}, {"Hugo", "hugo"}, {"Agnès", "agnes"}, {"Jérôme", "jerome"}, {"Camille", "camille"}},
+		LastNames:  []Name{{"Martin", "martin"}, {"Bernard", "bernard"}, {"Lefèvre", "lefevre"}, {"Girard", "girard"}, {"Rousseau", "rousseau"}, {"Fontaine", "fontaine"}, {"Chevalier", "chevalier"}, {"Moreau", "moreau"}},
+		Products: []Name{
+			{"Lampe de bureau", "LMP"}, {"Chaise de bureau", "CHR"}, {"Carnet", "NTB"}, {"Gourde", "BTL"},
+			{"Sac à dos", "BPK"}, {"Casque audio", "HPH"}, {"Tasse à café", "MUG"}, {"Clavier", "KBD"},
+		},
+		Series:   series,
+		Decimals: 2, MinPrice: 2, MaxPrice: 450,
+	},
+	"ja-JP": {
+		Tag:         "ja-JP",
+		FirstNames:  []Name{{"花子", "hanako"}, {"太郎", "taro"}, {"陽菜", "hina"}, {"蓮", "ren"}, {"結衣", "yui"}, {"大翔", "hiroto"}, {"美咲", "misaki"}, {"健", "ken"}},
+		LastNames:   []Name{{"佐藤", "sato"}, {"鈴木", "suzuki"}, {"高橋", "takahashi"}, {"田中", "tanaka"}, {"伊藤", "ito"}, {"渡辺", "watanabe"}, {"山本", "yamamoto"}, {"中村", "nakamura"}},
+		FamilyFirst: true,
+		Products: []Name{
+			{"デスクライト", "LMP"}, {"オフィスチェア", "CHR"}, {"ノート", "NTB"}, {"水筒", "BTL"},
+			{"リュック", "BPK"}, {"ヘッドホン", "HPH"}, {"マグカップ", "MUG"}, {"キーボード", "KBD"},
+		},
+		Series:   series,
+		Decimals: 0, MinPrice: 300, MaxPrice: 60000,
+	},
+}
+
+// series are model names, shared by the locales as brands usually are.
+var series = []string{"Aero", "Nova", "Orbit", "Pixel", "Terra", "Vento", "Zen", "Atlas"}
+
+// emailDomains are reserved for documentation (RFC 2606), so generated
+// addresses never reach anyone.
+var emailDomains = []string{"example.com", "example.net", "example.org"}
+
+func ascii(names ...string) []Name {
+	out := make([]Name, len(names))
+	for i, n := range names {
+		out[i] = Name{Text: n, ASCII: strings.ToLower(n)}
+	}
+	return out
+}
+
+// LocaleFor returns the locale for a BCP 47 tag such as "de-AT" or an
+// Accept-Language header value: the first tag with an exact match wins,
+// then the first whose language matches, then DefaultLocale.
+func LocaleFor(tags string) *Locale {
+	var candidates []string
+	for _, t := range strings.Split(tags, ",") {
+		t, _, _ = strings.Cut(strings.TrimSpace(t), ";") // drop ;q=0.8
+		candidates = append(candidates, t)
+	}
+	for _, t := range candidates {
+		for tag, l := range Locales {
+			if strings.EqualFold(tag, t) {
+				return l
+			}
+		}
+	}
+	for _, t := range candidates {
+		lang, _, _ := strings.Cut(t, "-")
+		for tag, l := range Locales {
+			if prefix, _, _ := strings.Cut(tag, "-"); lang != "" && strings.EqualFold(prefix, lang) && !ambiguous(prefix, tag) {
+				return l
+			}
+		}
+	}
+	return Locales[DefaultLocale]
+}
+
+// ambiguous reports whether another locale shares tag's language and sorts
+// before it, so language-only matches are deterministic.
+func ambiguous(lang, tag string) bool {
+	for other := range Locales {
+		if p, _, _ := strings.Cut(other, "-"); strings.EqualFold(p, lang) && other < tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Person is a fake individual; Email is derived from the name.
+type Person struct {
+	FirstName string
+	LastName  string
+	Name      string // full name in the locale's order
+	Email     string
+}
+
+// Faker draws fake values. It is not safe for concurrent use.
+type Faker struct {
+	rand   *rand.Rand
+	locale *Locale
+	emails map[string]bool // issued addresses, kept unique
+}
+
+// New returns a Faker for the locale LocaleFor picks for tags.
+func New(seed uint64, tags string) *Faker {
+	return &Faker{rand: rand.New(rand.NewPCG(seed, 0)), locale: LocaleFor(tags), emails: map[string]bool{}}
+}
+
+// Locale returns the locale in use.
+func (f *Faker) Locale() *Locale {
+	return f.locale
+}
+
+// Rand returns the source of f's values, for drawing further values that
+// stay deterministic.
+func (f *Faker) Rand() *rand.Rand {
+	return f.rand
+}
+
+// ID returns a 32 character hex identifier, like those the services assign.
+func (f *Faker) ID() string {
+	return fmt.Sprintf("%016x%016x", f.rand.Uint64(), f.rand.Uint64())
+}
+
+// Person returns a new person with an email address no earlier call of f
+// returned.
+func (f *Faker) Person() Person {
+	first, last := pick(f.rand, f.locale.FirstNames), pick(f.rand, f.locale.LastNames)
+	p := Person{FirstName: first.Text, LastName: last.Text, Name: f.fullName(first, last)}
+	local := first.ASCII + "." + last.ASCII
+	domain := pick(f.rand, emailDomains)
+	p.Email = local + "@" + domain
+	for n := 2; f.emails[p.Email]; n++ {
+		p.Email = fmt.Sprintf("%s%d@%s", local, n, domain)
+	}
+	f.emails[p.Email] = true
+	return p
+}
+
+// Name returns a full name.
+func (f *Faker) Name() string {
+	return f.fullName(pick(f.rand, f.locale.FirstNames), pick(f.rand, f.locale.LastNames))
+}
+
+func (f *Faker) fullName(first, last Name) string {
+	if f.locale.FamilyFirst {
+		return last.Text + " " + first.Text
+	}
+	return first.Text + " " + last.Text
+}
+
+// Email returns a unique email address.
+func (f *Faker) Email() string {
+	return f.Person().Email
+}
+
+// ProductName returns a product name such as "Desk Lamp Nova".
+func (f *Faker) ProductName() string {
+	return pick(f.rand, f.locale.Products).Text + " " + pick(f.rand, f.locale.Series)
+}
+
+// SKU returns a stock keeping unit such as "LMP-NOVA-04821".
+func (f *Faker) SKU() string {
+	return fmt.Sprintf("%s-%s-%05d", pick(f.rand, f.locale.Products).ASCII, strings.ToUpper(pick(f.rand, f.locale.Series)), f.rand.IntN(100000))
+}
+
+// Price returns a price in the locale's currency, rounded to its precision
+// and ending in .99 or .49 ("psychological" pricing) when it has cents.
+func (f *Faker) Price() float64 {
+	l := f.locale
+	// Log-uniform, so cheap items are as common as expensive ones per decade
+	p := math.Exp(math.Log(l.MinPrice) + f.rand.Float64()*(math.Log(l.MaxPrice)-math.Log(l.MinPrice)))
+	if l.Decimals == 0 {
+		step := math.Pow(10, math.Max(0, math.Floor(math.Log10(p))-1)) // two significant digits
+		return math.Round(p/step) * step
+	}
+	cents := []float64{0.99, 0.49}[f.rand.IntN(2)]
+	return math.Max(math.Floor(p), 1) - 1 + cents
+}
+
+// Word returns one of a fixed set of neutral words, for free-text fields.
+func (f *Faker) Word() string {
+	return words[f.rand.IntN(len(words))]
+}
+
+var words = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliett"}
+
+func pick[T any](r *rand.Rand, from []T) T {
+	return from[r.IntN(len(from))]
+}
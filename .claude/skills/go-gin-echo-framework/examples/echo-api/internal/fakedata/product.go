@@ -0,0 +1,18 @@
+package fakedata
+
+import "github.com/your-username/echo-api/internal/model"
+
+// Product returns a product with a generated ID. Products have no SKU field
+// yet; use SKU where one is needed.
+func (f *Faker) Product() model.Product {
+	return model.Product{ID: f.ID(), Name: f.ProductName(), Price: f.Price()}
+}
+
+// Products returns n products.
+func (f *Faker) Products(n int) []model.Product {
+	out := make([]model.Product, n)
+	for i := range out {
+		out[i] = f.Product()
+	}
+	return out
+}
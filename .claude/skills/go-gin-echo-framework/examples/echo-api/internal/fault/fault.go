@@ -0,0 +1,83 @@
+// Package fault injects errors into the fakes of repotest, servicetest and
+// authtest, so that tests can cover how their callers handle a failing
+// dependency without a concrete one to break:
+//
+//	products := servicetest.NewFake[model.Product](nil)
+//	products.Faults.Fail("GetAll", errors.New("connection refused"))
+//	products.Faults.FailNext(fault.Any, 2, context.DeadlineExceeded)
+//
+// Operations are named after the methods of the faked interface.
+package fault
+
+import "sync"
+
+// Any matches every operation.
+const Any = "*"
+
+// Injector decides which calls of a fake fail. The zero value fails none;
+// it is safe for concurrent use.
+type Injector struct {
+	mu    sync.Mutex
+	rules []*rule
+	calls map[string]int
+}
+
+type rule struct {
+	op    string
+	err   error
+	times int // calls left to fail; < 0 is every call
+}
+
+// Fail makes every later call of op fail with err, until Reset.
+func (in *Injector) Fail(op string, err error) {
+	in.add(&rule{op: op, err: err, times: -1})
+}
+
+// FailNext makes the next n calls of op fail with err; the calls after
+// them succeed again.
+func (in *Injector) FailNext(op string, n int, err error) {
+	in.add(&rule{op: op, err: err, times: n})
+}
+
+func (in *Injector) add(r *rule) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.rules = append(in.rules, r)
+}
+
+// Reset drops every rule and the call counts.
+func (in *Injector) Reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.rules = nil
+	in.calls = nil
+}
+
+// Calls returns how often op was called since the last Reset, failed calls
+// included.
+func (in *Injector) Calls(op string) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.calls[op]
+}
+
+// Check counts a call of op and returns the error it fails with, or nil.
+// Fakes call it first thing in every method; the rule added first wins.
+func (in *Injector) Check(op string) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.calls == nil {
+		in.calls = map[string]int{}
+	}
+	in.calls[op]++
+	for _, r := range in.rules {
+		if r.times == 0 || (r.op != op && r.op != Any) {
+			continue
+		}
+		if r.times > 0 {
+			r.times--
+		}
+		return r.err
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Package fields implements sparse fieldsets: a client asks for
+// ?fields=id,name and gets only those fields of each item back. Fields are
+// named as in the JSON output, and a dotted name selects inside a nested
+// struct, slice of structs or pointer to one (address.city).
+//
+// A Projection copies values into a struct type built to hold just the
+// selected fields, with the same JSON names and options and in the same
+// order, so every render.Format writes it like the original; only XML,
+// which names elements after their type, calls each one item.
+package fields
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Set is a parsed fields parameter: the names selected at one level, each
+// mapped to the names selected inside it. A nil Set, as a whole or for one
+// name, selects everything.
+type Set map[string]Set
+
+// Parse reads a comma-separated list of dotted field names. An empty list
+// is the nil Set. Selecting a field whole also selects everything a dotted
+// name asks for inside it.
+func Parse(s string) (Set, error) {
+	if s == "" {
+		return nil, nil
+	}
+	set := Set{}
+	for _, path := range strings.Split(s, ",") {
+		names := strings.Split(strings.TrimSpace(path), ".")
+		level := set
+		for i, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("fields: empty name in %q", path)
+			}
+			sub, seen := level[name]
+			if i == len(names)-1 {
+				level[name] = nil
+				break
+			}
+			if seen && sub == nil {
+				break // already selected whole
+			}
+			if !seen {
+				sub = Set{}
+				level[name] = sub
+			}
+			level = sub
+		}
+	}
+	return set, nil
+}
+
+// String formats s as Parse reads it, with the names sorted.
+func (s Set) String() string {
+	var paths []string
+	for name, sub := range s {
+		if sub == nil {
+			paths = append(paths, name)
+			continue
+		}
+		for _, path := range strings.Split(sub.String(), ",") {
+			paths = append(paths, name+"."+path)
+		}
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+// Projection selects a Set of fields from values of one type.
+type Projection struct {
+	src reflect.Type
+	p   *projector // nil selects everything
+}
+
+type cacheKey struct {
+	t   reflect.Type
+	set string
+}
+
+var cache sync.Map // cacheKey -> *projector
+
+// New compiles the projection of values of type t, a struct or pointer to
+// one, to set. It fails when set names a field t does not have in its JSON
+// form, or selects inside a field that has no fields.
+func New(t reflect.Type, set Set) (*Projection, error) {
+	if set == nil {
+		return &Projection{src: t}, nil
+	}
+	key := cacheKey{t, set.String()}
+	if p, ok := cache.Load(key); ok {
+		return &Projection{src: t, p: p.(*projector)}, nil
+	}
+	p, err := compile(t, set, "")
+	if err != nil {
+		return nil, err
+	}
+	cache.Store(key, p)
+	return &Projection{src: t, p: p}, nil
+}
+
+// Apply returns the projection of v, which is a value of the compiled type,
+// a pointer to one or a slice of them; anything else panics.
+func (pr *Projection) Apply(v any) any {
+	if pr.p == nil {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Type() == pr.src:
+		return pr.p.project(rv).Interface()
+	case rv.Kind() == reflect.Pointer && rv.Type().Elem() == pr.src:
+		if rv.IsNil() {
+			return reflect.Zero(reflect.PointerTo(pr.p.typ)).Interface()
+		}
+		out := reflect.New(pr.p.typ)
+		pr.p.fill(out.Elem(), rv.Elem())
+		return out.Interface()
+	case rv.Kind() == reflect.Slice && rv.Type().Elem() == pr.src:
+		out := reflect.MakeSlice(reflect.SliceOf(pr.p.typ), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			pr.p.fill(out.Index(i), rv.Index(i))
+		}
+		return out.Interface()
+	}
+	panic(fmt.Sprintf("fields: projection of %s applied to %T", pr.src, v))
+}
+
+// Project projects v, a struct, pointer to one or slice of them, to set.
+func Project(v any, set Set) (any, error) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	pr, err := New(t, set)
+	if err != nil {
+		return nil, err
+	}
+	return pr.Apply(v), nil
+}
+
+// projector builds values of a projected type from values of the original.
+type projector struct {
+	typ  reflect.Type
+	fill func(dst, src reflect.Value) // dst is a settable value of typ
+}
+
+func (p *projector) project(src reflect.Value) reflect.Value {
+	dst := reflect.New(p.typ).Elem()
+	p.fill(dst, src)
+	return dst
+}
+
+func whole(t reflect.Type) *projector {
+	return &projector{typ: t, fill: func(dst, src reflect.Value) { dst.Set(src) }}
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// compile builds the projector of t to set; path names t in errors.
+func compile(t reflect.Type, set Set, path string) (*projector, error) {
+	if set == nil {
+		return whole(t), nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem, err := compile(t.Elem(), set, path)
+		if err != nil {
+			return nil, err
+		}
+		return &projector{typ: reflect.PointerTo(elem.typ), fill: func(dst, src reflect.Value) {
+			if !src.IsNil() {
+				dst.Set(elem.project(src.Elem()).Addr())
+			}
+		}}, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := compile(t.Elem(), set, path)
+		if err != nil {
+			return nil, err
+		}
+		return &projector{typ: reflect.SliceOf(elem.typ), fill: func(dst, src reflect.Value) {
+			if src.Kind() == reflect.Slice && src.IsNil() {
+				return
+			}
+			out := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				elem.fill(out.Index(i), src.Index(i))
+			}
+			dst.Set(out)
+		}}, nil
+	case reflect.Struct:
+		if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) ||
+			reflect.PointerTo(t).Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+			break // encoded as a scalar, e.g. time.Time
+		}
+		return compileStruct(t, set, path)
+	}
+	if path == "" {
+		path = t.String()
+	}
+	return nil, fmt.Errorf("fields: %s has no fields to select", path)
+}
+
+// field is a struct field as encoding/json sees it.
+type field struct {
+	name  string // JSON name
+	opts  string // JSON tag options, e.g. ",omitempty"
+	index []int
+	typ   reflect.Type
+}
+
+// jsonFields lists the exported fields of t in their JSON order, promoting
+// those of untagged embedded structs as encoding/json does.
+func jsonFields(t reflect.Type, index []int) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" && opts == "" {
+			continue
+		}
+		idx := append(index[:len(index):len(index)], i)
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			out = append(out, jsonFields(ft, idx)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if opts != "" {
+			opts = "," + opts
+		}
+		out = append(out, field{name: name, opts: opts, index: idx, typ: f.Type})
+	}
+	return out
+}
+
+func compileStruct(t reflect.Type, set Set, path string) (*projector, error) {
+	if path != "" {
+		path += "."
+	}
+	var (
+		structFields []reflect.StructField
+		indexes      [][]int
+		projectors   []*projector
+		found        = map[string]bool{}
+	)
+	for _, f := range jsonFields(t, nil) {
+		sub, ok := set[f.name]
+		if !ok || found[f.name] {
+			continue
+		}
+		found[f.name] = true
+		p, err := compile(f.typ, sub, path+f.name)
+		if err != nil {
+			return nil, err
+		}
+		structFields = append(structFields, reflect.StructField{
+			Name: fmt.Sprintf("F%d", len(structFields)),
+			Type: p.typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:"%s%s"`, f.name, f.opts)),
+		})
+		indexes = append(indexes, f.index)
+		projectors = append(projectors, p)
+	}
+	var unknown []string
+	for name := range set {
+		if !found[name] {
+			unknown = append(unknown, path+name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("fields: unknown field %s", strings.Join(unknown, ", "))
+	}
+
+	return &projector{typ: reflect.StructOf(structFields), fill: func(dst, src reflect.Value) {
+		for i, p := range projectors {
+			v, err := src.FieldByIndexErr(indexes[i])
+			if err != nil {
+				continue // inside a nil embedded pointer
+			}
+			p.fill(dst.Field(i), v)
+		}
+	}}, nil
+}
@@ -0,0 +1,160 @@
+// Package flags holds feature flags: named switches declared in the
+// feature_flags section of the config file, with their defaults, which
+// routes check with Require, services with Enabled, e.g.
+// flags.Enabled(ctx, "new-pricing"), and admins toggle at runtime (PUT
+// /admin/flags/:name).
+//
+// A flag is evaluated per request, by its caller and tenant, once
+// Middleware has run. The plan of a request's tenant (see package plan)
+// may set a flag for its tenants in place of the default; otherwise a Rule,
+// from the config file, a file of rules or a remote Provider, turns a flag
+// off by default on for the callers and tenants it targets. A toggle
+// overrides all of these until it is cleared or the process restarts, and
+// holds in this instance only; change the config file to toggle a flag for
+// good, or on every instance.
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknown is returned for a flag the config file does not declare.
+	ErrUnknown = errors.New("unknown feature flag")
+	// ErrDisabled is returned by Check for a flag that is off.
+	ErrDisabled = errors.New("feature is not enabled")
+)
+
+// Flag is the state of one flag.
+type Flag struct {
+	Name       string `json:"name"`
+	Enabled    bool   `json:"enabled"`
+	Default    bool   `json:"default"`        // as the config file sets it
+	Overridden bool   `json:"overridden"`     // toggled at runtime, Enabled differing from Default or not
+	Rule       *Rule  `json:"rule,omitempty"` // who the flag is on for beyond Default
+}
+
+// Set is the flags of the config file and their runtime overrides. It is
+// safe for concurrent use.
+type Set struct {
+	declared func() map[string]bool
+	planned  func(ctx context.Context) (string, map[string]bool)
+	rules    Provider
+
+	mu        sync.RWMutex
+	overrides map[string]bool
+}
+
+// New serves the flags declared returns, e.g. those of the current config
+// file, so a reload adds and removes flags. planned returns the plan of a
+// request's context and the flags it sets, e.g. from plan.Options.Of, or
+// "" and nil; nil sets none. rules returns the rules of the flags; nil
+// has none.
+func New(declared func() map[string]bool, planned func(ctx context.Context) (string, map[string]bool), rules Provider) *Set {
+	if planned == nil {
+		planned = func(context.Context) (string, map[string]bool) { return "", nil }
+	}
+	if rules == nil {
+		rules = ProviderFunc(func(context.Context) map[string]Rule { return nil })
+	}
+	return &Set{declared: declared, planned: planned, rules: rules, overrides: map[string]bool{}}
+}
+
+// Enabled reports whether flag name is on for ctx: its override if
+// toggled, else its setting in the plan of ctx, else on if its default or
+// rule says so for the caller and tenant of ctx. Undeclared flags are off.
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	return s.Check(ctx, name) == nil
+}
+
+// Check is Enabled, failing with ErrUnknown for an undeclared flag and
+// ErrDisabled, naming the plan that leaves it out if any, for a flag that
+// is off.
+func (s *Set) Check(ctx context.Context, name string) error {
+	def, ok := s.declared()[name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	return s.check(ctx, name, def, s.rules.Rules(ctx))
+}
+
+// Evaluate returns whether each declared flag is on for ctx, by name.
+func (s *Set) Evaluate(ctx context.Context) map[string]bool {
+	declared, rules := s.declared(), s.rules.Rules(ctx)
+	out := make(map[string]bool, len(declared))
+	for name, def := range declared {
+		out[name] = s.check(ctx, name, def, rules) == nil
+	}
+	return out
+}
+
+func (s *Set) check(ctx context.Context, name string, def bool, rules map[string]Rule) error {
+	s.mu.RLock()
+	on, toggled := s.overrides[name]
+	s.mu.RUnlock()
+	if !toggled {
+		plan, set := s.planned(ctx)
+		if planned, ok := set[name]; ok {
+			if !planned {
+				return fmt.Errorf("%w: %s is not included in the %s plan", ErrDisabled, name, plan)
+			}
+			return nil
+		}
+		rule, ok := rules[name]
+		on = def || ok && rule.matches(ctx, name)
+	}
+	if !on {
+		return fmt.Errorf("%w: %s", ErrDisabled, name)
+	}
+	return nil
+}
+
+// Toggle overrides flag name, failing with ErrUnknown if it is not
+// declared.
+func (s *Set) Toggle(name string, on bool) (Flag, error) {
+	def, ok := s.declared()[name]
+	if !ok {
+		return Flag{}, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides[name] = on
+	return Flag{Name: name, Enabled: on, Default: def, Overridden: true}, nil
+}
+
+// Clear drops the override of flag name, returning it to its default,
+// failing with ErrUnknown if it is not declared.
+func (s *Set) Clear(name string) (Flag, error) {
+	def, ok := s.declared()[name]
+	if !ok {
+		return Flag{}, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.overrides, name)
+	return Flag{Name: name, Enabled: def, Default: def}, nil
+}
+
+// All returns every declared flag, by name, with its rule.
+func (s *Set) All(ctx context.Context) []Flag {
+	declared, rules := s.declared(), s.rules.Rules(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make([]Flag, 0, len(declared))
+	for name, def := range declared {
+		f := Flag{Name: name, Enabled: def, Default: def}
+		if on, ok := s.overrides[name]; ok {
+			f.Enabled, f.Overridden = on, true
+		}
+		if r, ok := rules[name]; ok {
+			f.Rule = &r
+		}
+		all = append(all, f)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
@@ -0,0 +1,194 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+func TestTogglesOverrideTheConfigUntilCleared(t *testing.T) {
+	declared := map[string]bool{"new-search": false, "dark-mode": true}
+	s := New(func() map[string]bool { return declared }, nil, nil)
+	ctx := context.Background()
+
+	if s.Enabled(ctx, "new-search") || !s.Enabled(ctx, "dark-mode") || s.Enabled(ctx, "undeclared") {
+		t.Fatalf("defaults: new-search %t, dark-mode %t", s.Enabled(ctx, "new-search"), s.Enabled(ctx, "dark-mode"))
+	}
+	if _, err := s.Toggle("new-search", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Toggle("undeclared", true); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Toggle of an undeclared flag: %v", err)
+	}
+	if !s.Enabled(ctx, "new-search") {
+		t.Error("new-search is off after being toggled on")
+	}
+	all := s.All(ctx)
+	if len(all) != 2 || all[1] != (Flag{Name: "new-search", Enabled: true, Overridden: true}) {
+		t.Errorf("All = %+v", all)
+	}
+
+	if _, err := s.Clear("new-search"); err != nil || s.Enabled(ctx, "new-search") {
+		t.Errorf("new-search after Clear: %t, %v", s.Enabled(ctx, "new-search"), err)
+	}
+
+	// A reload dropping a flag turns it off, override or not
+	s.Toggle("dark-mode", true)
+	delete(declared, "dark-mode")
+	if s.Enabled(ctx, "dark-mode") || len(s.All(ctx)) != 1 {
+		t.Errorf("dark-mode after its removal: %t, All = %+v", s.Enabled(ctx, "dark-mode"), s.All(ctx))
+	}
+}
+
+func TestPlansSetFlagsForTheirTenants(t *testing.T) {
+	declared := map[string]bool{"search": true, "export": false}
+	type planKey struct{}
+	s := New(func() map[string]bool { return declared }, func(ctx context.Context) (string, map[string]bool) {
+		if ctx.Value(planKey{}) == nil {
+			return "", nil
+		}
+		return "free", map[string]bool{"search": false}
+	}, nil)
+	free := context.WithValue(context.Background(), planKey{}, true)
+
+	if err := s.Check(free, "search"); !errors.Is(err, ErrDisabled) || !strings.Contains(err.Error(), "free plan") {
+		t.Errorf("search on the free plan: %v", err)
+	}
+	if !s.Enabled(context.Background(), "search") || s.Enabled(free, "export") {
+		t.Error("flags the plan does not set should keep their defaults")
+	}
+	// A toggle holds for every plan
+	s.Toggle("search", true)
+	if !s.Enabled(free, "search") {
+		t.Error("search on the free plan is off after being toggled on")
+	}
+}
+
+func TestRulesTurnFlagsOnForTheirTargets(t *testing.T) {
+	declared := map[string]bool{"new-pricing": false}
+	rules := map[string]Rule{"new-pricing": {Users: []string{"user-1"}, Tenants: []string{"acme"}}}
+	s := New(func() map[string]bool { return declared }, nil, ProviderFunc(func(context.Context) map[string]Rule { return rules }))
+	caller := func(subject string) context.Context {
+		return auth.WithCaller(context.Background(), &auth.Caller{Subject: subject})
+	}
+
+	if !s.Enabled(caller("user-1"), "new-pricing") || s.Enabled(caller("user-2"), "new-pricing") || s.Enabled(context.Background(), "new-pricing") {
+		t.Error("new-pricing should be on for user-1 only")
+	}
+	if !s.Enabled(tenant.With(caller("user-2"), "acme"), "new-pricing") {
+		t.Error("new-pricing is off in acme")
+	}
+	if got := s.Evaluate(caller("user-1")); !got["new-pricing"] {
+		t.Errorf("Evaluate = %v", got)
+	}
+
+	// A share of callers, which keep their answer as the share grows
+	on := func() map[string]bool {
+		in := map[string]bool{}
+		for i := range 1000 {
+			subject := fmt.Sprintf("user-%d", i+100)
+			if s.Enabled(caller(subject), "new-pricing") {
+				in[subject] = true
+			}
+		}
+		return in
+	}
+	rules["new-pricing"] = Rule{Percent: 10}
+	ten := on()
+	rules["new-pricing"] = Rule{Percent: 50}
+	fifty := on()
+	if len(ten) < 50 || len(ten) > 150 || len(fifty) < 400 || len(fifty) > 600 {
+		t.Errorf("10%% turned %d of 1000 callers on, 50%% %d", len(ten), len(fifty))
+	}
+	for subject := range ten {
+		if !fifty[subject] {
+			t.Errorf("%s lost the flag as the share grew", subject)
+		}
+	}
+
+	// Toggles override rules
+	s.Toggle("new-pricing", false)
+	rules["new-pricing"] = Rule{Users: []string{"user-1"}}
+	if s.Enabled(caller("user-1"), "new-pricing") {
+		t.Error("new-pricing is on for user-1 after being toggled off")
+	}
+}
+
+func TestFileRulesAreRereadWhenChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	write("new-pricing: {tenants: [acme]}\n", start)
+	f, err := NewFile(path, time.Minute, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Rules(context.Background())["new-pricing"].Tenants; len(got) != 1 || got[0] != "acme" {
+		t.Fatalf("rules of new-pricing: %v", got)
+	}
+
+	write("new-pricing: {percent: 20}\n", start.Add(time.Second))
+	if f.Rules(context.Background())["new-pricing"].Percent != 0 {
+		t.Error("the file was reread before refresh")
+	}
+	clk.Advance(time.Minute)
+	if got := f.Rules(context.Background())["new-pricing"]; got.Percent != 20 {
+		t.Errorf("rules after the change: %+v", got)
+	}
+
+	// An invalid file keeps the last good rules
+	write("new-pricing: {percent: 200}\n", start.Add(2*time.Second))
+	clk.Advance(time.Minute)
+	if got := f.Rules(context.Background())["new-pricing"]; got.Percent != 20 {
+		t.Errorf("rules after an invalid change: %+v", got)
+	}
+	if _, err := NewFile(path, time.Minute, clk); err == nil {
+		t.Error("NewFile accepted an invalid file")
+	}
+}
+
+func TestRemoteRulesAreFetched(t *testing.T) {
+	var body atomic.Value
+	body.Store(`{"new-pricing": {"users": ["user-1"]}}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer srv.Close()
+
+	r := NewRemote(srv.URL, time.Minute, srv.Client(), clock.NewFake(time.Now()))
+	if got := r.Rules(context.Background())["new-pricing"].Users; len(got) != 1 {
+		t.Fatalf("rules of new-pricing: %v", got)
+	}
+	body.Store(`{"new-pricing": {"percent": 30}}`)
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Rules(context.Background())["new-pricing"]; got.Percent != 30 {
+		t.Errorf("rules after a refresh: %+v", got)
+	}
+	body.Store(`not json`)
+	if err := r.Refresh(context.Background()); err == nil || r.Rules(context.Background())["new-pricing"].Percent != 30 {
+		t.Errorf("a failed fetch should keep the last rules: %v", err)
+	}
+}
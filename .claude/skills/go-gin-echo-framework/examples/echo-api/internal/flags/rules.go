@@ -0,0 +1,279 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/tenant"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule turns a flag that is off by default on for some requests: those of
+// the callers and tenants it lists, and a share of every caller, so a
+// feature can be rolled out gradually.
+type Rule struct {
+	Users   []string `yaml:"users" json:"users,omitempty"`     // callers by account ID
+	Tenants []string `yaml:"tenants" json:"tenants,omitempty"` // see package tenant
+	Percent int      `yaml:"percent" json:"percent,omitempty"` // of logged-in callers, 0-100; a caller keeps its answer as the share grows
+}
+
+// Validate checks the tenants and share of the rule.
+func (r Rule) Validate() error {
+	if r.Percent < 0 || r.Percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100 (got %d)", r.Percent)
+	}
+	for _, id := range r.Tenants {
+		if err := tenant.Check(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule of flag name turns it on for ctx, by
+// its caller (see auth.Identify) and tenant.
+func (r Rule) matches(ctx context.Context, name string) bool {
+	if id := tenant.From(ctx); id != "" {
+		for _, t := range r.Tenants {
+			if t == id {
+				return true
+			}
+		}
+	}
+	caller := auth.CallerFromContext(ctx)
+	if caller == nil {
+		return false
+	}
+	for _, u := range r.Users {
+		if u == caller.Subject {
+			return true
+		}
+	}
+	// Hashed with the flag so each rollout picks different callers
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + caller.Subject))
+	return int(h.Sum32()%100) < r.Percent
+}
+
+// Provider supplies the rules of the flags, by flag name. Rules of
+// undeclared flags are ignored.
+type Provider interface {
+	Rules(ctx context.Context) map[string]Rule
+}
+
+// ProviderFunc adapts a function, e.g. one reading the current config
+// file, to a Provider.
+type ProviderFunc func(ctx context.Context) map[string]Rule
+
+func (f ProviderFunc) Rules(ctx context.Context) map[string]Rule {
+	return f(ctx)
+}
+
+// Chain merges the rules of providers, the rule a later provider has for
+// a flag replacing those of earlier ones.
+func Chain(providers ...Provider) Provider {
+	return ProviderFunc(func(ctx context.Context) map[string]Rule {
+		merged := map[string]Rule{}
+		for _, p := range providers {
+			for name, r := range p.Rules(ctx) {
+				merged[name] = r
+			}
+		}
+		return merged
+	})
+}
+
+// Options say where the rules of the flags come from: the config file,
+// a file of their own and a remote provider, the latter winning.
+type Options struct {
+	Rules   map[string]Rule `yaml:"rules"`   // by flag name
+	File    string          `yaml:"file"`    // YAML rules by flag name, reread when it changes
+	URL     string          `yaml:"url"`     // serving JSON rules by flag name
+	Refresh time.Duration   `yaml:"refresh"` // how often File is checked and URL fetched
+}
+
+// Validate checks the rules, and the refresh and URL of the providers
+// used.
+func (o Options) Validate() error {
+	names := make([]string, 0, len(o.Rules))
+	for name := range o.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := o.Rules[name].Validate(); err != nil {
+			return fmt.Errorf("rules.%s: %w", name, err)
+		}
+	}
+	if (o.File != "" || o.URL != "") && o.Refresh <= 0 {
+		return errors.New("refresh must be positive")
+	}
+	if o.URL != "" {
+		if u, err := url.Parse(o.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http(s) URL (got %q)", o.URL)
+		}
+	}
+	return nil
+}
+
+// File serves the rules of a YAML file, checked for changes at most once
+// per refresh. A file that becomes unreadable or invalid is logged and
+// ignored, keeping the last good rules in place.
+type File struct {
+	path    string
+	refresh time.Duration
+	clock   clock.Clock
+
+	mu       sync.Mutex
+	rules    map[string]Rule
+	modified time.Time
+	checked  time.Time
+}
+
+// NewFile reads the rules of path, failing if it cannot.
+func NewFile(path string, refresh time.Duration, clk clock.Clock) (*File, error) {
+	f := &File{path: path, refresh: refresh, clock: clock.OrSystem(clk)}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if f.rules, err = readRules(path); err != nil {
+		return nil, err
+	}
+	f.modified, f.checked = info.ModTime(), f.clock.Now()
+	return f, nil
+}
+
+func (f *File) Rules(context.Context) map[string]Rule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.clock.Now().Sub(f.checked) < f.refresh {
+		return f.rules
+	}
+	f.checked = f.clock.Now()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		log.Printf("WARNING: flag rules: %v", err)
+		return f.rules
+	}
+	if info.ModTime().Equal(f.modified) {
+		return f.rules
+	}
+	rules, err := readRules(f.path)
+	if err != nil {
+		log.Printf("WARNING: flag rules rejected, keeping previous rules: %v", err)
+		return f.rules
+	}
+	f.rules, f.modified = rules, info.ModTime()
+	return f.rules
+}
+
+func readRules(path string) (map[string]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string]Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, checkRules(rules)
+}
+
+// Remote serves the rules a remote provider returns as JSON, fetched in
+// the background once they are older than refresh, so requests never
+// wait on it. A failed fetch is logged, keeping the last good rules.
+type Remote struct {
+	url     string
+	client  *http.Client
+	refresh time.Duration
+	clock   clock.Clock
+
+	mu       sync.Mutex
+	rules    map[string]Rule
+	fetched  time.Time
+	fetching bool
+}
+
+// NewRemote fetches the rules of url with client, nil being a client with
+// a 10s timeout, starting without rules if it cannot.
+func NewRemote(url string, refresh time.Duration, client *http.Client, clk clock.Clock) *Remote {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	r := &Remote{url: url, client: client, refresh: refresh, clock: clock.OrSystem(clk)}
+	if err := r.Refresh(context.Background()); err != nil {
+		log.Printf("WARNING: flag rules: %v", err)
+	}
+	return r
+}
+
+func (r *Remote) Rules(context.Context) map[string]Rule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clock.Now().Sub(r.fetched) >= r.refresh && !r.fetching {
+		r.fetching = true
+		go func() {
+			if err := r.Refresh(context.Background()); err != nil {
+				log.Printf("WARNING: flag rules: %v", err)
+			}
+		}()
+	}
+	return r.rules
+}
+
+// Refresh fetches the rules now.
+func (r *Remote) Refresh(ctx context.Context) error {
+	rules, err := r.fetch(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetched, r.fetching = r.clock.Now(), false
+	if err != nil {
+		return err
+	}
+	r.rules = rules
+	return nil
+}
+
+func (r *Remote) fetch(ctx context.Context) (map[string]Rule, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", r.url, resp.Status)
+	}
+	var rules map[string]Rule
+	if err := json.NewDecoder(resp.Body).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.url, err)
+	}
+	return rules, checkRules(rules)
+}
+
+// checkRules validates rules read from a provider.
+func checkRules(rules map[string]Rule) error {
+	for name, r := range rules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package golden compares test output with snapshot files committed under
+// testdata/golden, so a change to a response payload shows up as a diff in
+// review instead of passing silently. Values that differ between runs
+// (generated IDs, timestamps, tokens) are replaced with numbered
+// placeholders before comparing.
+//
+// After an intended change, rewrite the snapshots and review the diff:
+//
+//	GOLDEN_UPDATE=1 go test ./...
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Rule replaces every match of Pattern with <Name-N>, numbering distinct
+// matched values in order of appearance, so equal values stay recognisably
+// equal: the ID a create returns and the one in the following GET are both
+// <user-id-1>. A Pattern with a capturing group replaces only the group.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Same    bool // replace every match with <Name>: whether two values are equal is chance (timestamps)
+}
+
+// Rules normalizing values every API response may contain.
+var (
+	Timestamps = Rule{Name: "time", Pattern: regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})`), Same: true}
+	HTTPDates  = Rule{Name: "date", Pattern: regexp.MustCompile(`(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun), \d{2} (?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \d{4} \d{2}:\d{2}:\d{2} GMT`), Same: true}
+	Durations  = Rule{Name: "duration", Pattern: regexp.MustCompile(`"duration": "([0-9.]+(?:ns|µs|ms|s|m|h)+)"`), Same: true}
+	JWTs       = Rule{Name: "jwt", Pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)}
+)
+
+// Normalizer applies its rules, in order, to output before comparing it.
+type Normalizer struct {
+	Rules []Rule
+}
+
+// New returns a Normalizer for rules after the common JWTs, Timestamps,
+// HTTPDates and Durations.
+func New(rules ...Rule) *Normalizer {
+	return &Normalizer{Rules: append([]Rule{JWTs, Timestamps, HTTPDates, Durations}, rules...)}
+}
+
+// Normalize replaces the dynamic values in b with placeholders.
+func (n *Normalizer) Normalize(b []byte) []byte {
+	s := string(b)
+	for _, r := range n.Rules {
+		seen := map[string]int{}
+		s = r.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			value, prefix, suffix := match, "", ""
+			if r.Pattern.NumSubexp() > 0 {
+				loc := r.Pattern.FindStringSubmatchIndex(match)
+				if loc[2] >= 0 {
+					value, prefix, suffix = match[loc[2]:loc[3]], match[:loc[2]], match[loc[3]:]
+				}
+			}
+			if r.Same {
+				return prefix + "<" + r.Name + ">" + suffix
+			}
+			id, ok := seen[value]
+			if !ok {
+				id = len(seen) + 1
+				seen[value] = id
+			}
+			return fmt.Sprintf("%s<%s-%d>%s", prefix, r.Name, id, suffix)
+		})
+	}
+	return []byte(s)
+}
+
+// Assert compares got, normalized, with testdata/golden/<name>.golden, or
+// writes it there when GOLDEN_UPDATE is set. JSON is indented first so the
+// snapshots diff line by line.
+func (n *Normalizer) Assert(t testing.TB, name string, got []byte) {
+	t.Helper()
+	got = n.Normalize(Indent(got))
+	path := filepath.Join("testdata", "golden", filepath.FromSlash(name)+".golden")
+	if os.Getenv("GOLDEN_UPDATE") != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run the test with GOLDEN_UPDATE=1 to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the snapshot (rerun with GOLDEN_UPDATE=1 if the change is intended):\n%s", path, diff(string(want), string(got)))
+	}
+}
+
+// Indent pretty-prints b if it is JSON and returns it unchanged otherwise.
+// Sections separated by a blank line are indented separately, so a
+// snapshot can hold a header block followed by a JSON body.
+func Indent(b []byte) []byte {
+	parts := bytes.Split(b, []byte("\n\n"))
+	for i, p := range parts {
+		var out bytes.Buffer
+		if json.Indent(&out, bytes.TrimSpace(p), "", "  ") == nil {
+			parts[i] = out.Bytes()
+		}
+	}
+	out := bytes.Join(parts, []byte("\n\n"))
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// diff lists the lines of want and got from the first one that differs.
+func diff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "first difference at line %d\n", i+1)
+	for j := i; j < len(w) && j < i+10; j++ {
+		fmt.Fprintf(&b, "- %s\n", w[j])
+	}
+	for j := i; j < len(g) && j < i+10; j++ {
+		fmt.Fprintf(&b, "+ %s\n", g[j])
+	}
+	return b.String()
+}
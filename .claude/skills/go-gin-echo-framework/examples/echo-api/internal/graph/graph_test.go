@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/service"
+	"github.com/your-username/echo-api/internal/util"
+)
+
+// countingService counts the lookups that reach the service.
+type countingService struct {
+	service.ProductService
+	gets, batches atomic.Int32
+}
+
+func (s *countingService) GetByID(ctx context.Context, id string) (*model.Product, error) {
+	s.gets.Add(1)
+	return s.ProductService.GetByID(ctx, id)
+}
+
+func (s *countingService) GetByIDs(ctx context.Context, ids []string) ([]model.Product, error) {
+	s.batches.Add(1)
+	return s.ProductService.GetByIDs(ctx, ids)
+}
+
+func newProducts(t *testing.T) *countingService {
+	t.Helper()
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewSQLRepository[model.Product](db, dialect, "products", "product")
+	return &countingService{ProductService: service.NewProductService(repo, repository.NewSQLUnitOfWork(db), nil, nil, nil, nil, nil, nil)}
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// do posts query to h, as caller if not nil.
+func do(t *testing.T, h http.Handler, caller *auth.Caller, query string) response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if caller != nil {
+		req = req.WithContext(auth.WithCaller(req.Context(), caller))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%d %s: %v", w.Code, w.Body, err)
+	}
+	return resp
+}
+
+func TestProductLookupsAreBatched(t *testing.T) {
+	products := newProducts(t)
+	for _, p := range []model.Product{{ID: "p1", Name: "Widget"}, {ID: "p2", Name: "Gadget"}} {
+		if _, err := products.Create(context.Background(), &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewHandler(products, util.NewCustomValidator())
+
+	resp := do(t, h, nil, `{ a: product(id: "p1") { name } b: product(id: "p2") { name } c: product(id: "p3") { name } again: product(id: "p1") { id } }`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %+v", resp.Errors)
+	}
+	got := string(resp.Data["a"]) + string(resp.Data["b"]) + string(resp.Data["c"]) + string(resp.Data["again"])
+	if got != `{"name":"Widget"}{"name":"Gadget"}null{"id":"p1"}` {
+		t.Fatalf("data %s", got)
+	}
+	if gets, batches := products.gets.Load(), products.batches.Load(); gets != 0 || batches != 1 {
+		t.Fatalf("%d GetByID and %d GetByIDs calls, want one batch", gets, batches)
+	}
+}
+
+func TestMutationsRequireACaller(t *testing.T) {
+	h := NewHandler(newProducts(t), util.NewCustomValidator())
+	create := `mutation { createProduct(input: {name: " Widget ", price: 9.999}) { id name price } }`
+
+	resp := do(t, h, nil, create)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "UNAUTHENTICATED" {
+		t.Fatalf("anonymous createProduct: %+v", resp.Errors)
+	}
+
+	caller := &auth.Caller{Subject: "account-1", Method: auth.MethodJWT}
+	resp = do(t, h, caller, create)
+	var created model.Product
+	if err := json.Unmarshal(resp.Data["createProduct"], &created); err != nil || len(resp.Errors) > 0 {
+		t.Fatalf("createProduct: %v %+v", err, resp.Errors)
+	}
+	if created.ID == "" || created.Name != "Widget" || created.Price != 10 {
+		t.Fatalf("created %+v, want it normalized by the service", created)
+	}
+
+	resp = do(t, h, caller, `mutation { createProduct(input: {name: "Gadget", price: -1}) { id } }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "BAD_USER_INPUT" {
+		t.Fatalf("invalid createProduct: %+v", resp.Errors)
+	}
+	resp = do(t, h, caller, `mutation { deleteProduct(id: "missing") }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Fatalf("deleteProduct of a missing product: %+v", resp.Errors)
+	}
+}
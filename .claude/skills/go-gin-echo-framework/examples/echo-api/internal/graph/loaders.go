@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/vikstrous/dataloadgen"
+
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// loaderWait is how long a loader collects IDs before fetching them. The
+// resolvers of a query's fields run concurrently, so this is the time they
+// have to ask.
+const loaderWait = 2 * time.Millisecond
+
+// loaders batch and cache the lookups of one request.
+type loaders struct {
+	product *dataloadgen.Loader[string, *model.Product]
+}
+
+type loadersKey struct{}
+
+// withLoaders returns a copy of ctx with fresh loaders reading from products.
+func withLoaders(ctx context.Context, products service.ProductService) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		product: dataloadgen.NewLoader(byID(products.GetByIDs), dataloadgen.WithWait(loaderWait)),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// byID adapts a batch lookup to a loader's fetch function, which returns a
+// value per key: nil for missing IDs.
+func byID[T model.Entity](get func(ctx context.Context, ids []string) ([]T, error)) func(context.Context, []string) ([]*T, []error) {
+	return func(ctx context.Context, ids []string) ([]*T, []error) {
+		found, err := get(ctx, ids)
+		if err != nil {
+			return nil, []error{err}
+		}
+		index := make(map[string]*T, len(found))
+		for i := range found {
+			index[found[i].GetID()] = &found[i]
+		}
+		out := make([]*T, len(ids))
+		for i, id := range ids {
+			out[i] = index[id]
+		}
+		return out, nil
+	}
+}
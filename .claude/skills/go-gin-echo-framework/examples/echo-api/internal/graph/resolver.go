@@ -0,0 +1,14 @@
+package graph
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// Resolver holds the services the resolvers call. gqlgen generates this
+// file once and leaves it alone afterwards.
+type Resolver struct {
+	products  service.ProductService
+	validator echo.Validator
+}
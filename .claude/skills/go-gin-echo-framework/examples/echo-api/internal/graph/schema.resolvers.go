@@ -0,0 +1,60 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/your-username/echo-api/internal/model"
+)
+
+// CreateProduct is the resolver for the createProduct field.
+func (r *mutationResolver) CreateProduct(ctx context.Context, input ProductInput) (*model.Product, error) {
+	product, err := r.productFromInput("", input)
+	if err != nil {
+		return nil, err
+	}
+	return r.products.Create(ctx, product)
+}
+
+// UpdateProduct is the resolver for the updateProduct field.
+func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, input ProductInput) (*model.Product, error) {
+	product, err := r.productFromInput(id, input)
+	if err != nil {
+		return nil, err
+	}
+	return r.products.Update(ctx, product)
+}
+
+// DeleteProduct is the resolver for the deleteProduct field.
+func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (bool, error) {
+	if err := r.products.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context) ([]model.Product, error) {
+	products, err := r.products.GetAll(ctx)
+	if products == nil && err == nil {
+		products = []model.Product{}
+	}
+	return products, err
+}
+
+// Product is the resolver for the product field.
+func (r *queryResolver) Product(ctx context.Context, id string) (*model.Product, error) {
+	return loadersFrom(ctx).product.Load(ctx, id)
+}
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -0,0 +1,29 @@
+package grpcapi
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Multiplex hands HTTP/2 requests with a gRPC content type to s and passes
+// everything else on. Registered as the first Pre middleware, it serves
+// gRPC and HTTP on one port with gRPC calls skipping the HTTP middleware;
+// start the server with Echo.StartH2CServer so that gRPC clients connect
+// without TLS.
+//
+// The server's read and write timeouts apply to gRPC calls as well, which
+// cuts long-lived streams short; leave them unset, or use a port of its
+// own, for streaming clients.
+func Multiplex(s *Server) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+				s.ServeHTTP(c.Response(), r)
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
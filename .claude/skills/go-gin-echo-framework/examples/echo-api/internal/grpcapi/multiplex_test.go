@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestMultiplexServesGRPCAndHTTPOnOnePort(t *testing.T) {
+	s := New()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	e := echo.New()
+	e.Pre(Multiplex(s))
+	e.GET("/products", func(c echo.Context) error {
+		return c.String(http.StatusOK, "rest "+c.Path())
+	})
+	srv := httptest.NewServer(h2c.NewHandler(e, &http2.Server{}))
+	defer srv.Close()
+	defer s.Stop()
+
+	resp, err := http.Get(srv.URL + "/products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "rest /products" {
+		t.Fatalf("HTTP got %q", body)
+	}
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(srv.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	status, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("gRPC health is %v", status.Status)
+	}
+}
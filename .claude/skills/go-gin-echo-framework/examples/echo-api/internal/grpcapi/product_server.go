@@ -0,0 +1,124 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/mapping"
+	productsv1 "github.com/your-username/echo-api/internal/pb/products/v1"
+	"github.com/your-username/echo-api/internal/service"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// maxBulkItems bounds a single BulkCreateProducts stream; longer streams are
+// cut off and reported as truncated.
+const maxBulkItems = 10000
+
+type ProductServer struct {
+	productsv1.UnimplementedProductServiceServer
+	productService service.ProductService
+	validator      echo.Validator
+	feed           *changes.Feed
+}
+
+// NewProductServer validates items with the same validator as the REST handlers.
+func NewProductServer(productService service.ProductService, v echo.Validator, feed *changes.Feed) *ProductServer {
+	return &ProductServer{productService: productService, validator: v, feed: feed}
+}
+
+// BulkCreateProducts creates each item before reading the next, so the
+// client's sends are paced by the server's receive window. Item failures are
+// collected rather than aborting the stream.
+func (s *ProductServer) BulkCreateProducts(stream productsv1.ProductService_BulkCreateProductsServer) error {
+	ctx := stream.Context()
+	resp := &productsv1.BulkCreateProductsResponse{}
+	for {
+		if resp.Processed == maxBulkItems {
+			resp.Truncated = true
+			return stream.SendAndClose(resp)
+		}
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+		index := resp.Processed
+		resp.Processed++
+
+		product := mapping.ProductFromCreateRequest(req)
+		if err := s.validator.Validate(&product); err != nil {
+			resp.Failed = append(resp.Failed, failure(index, codes.InvalidArgument, validationMessage(err)))
+			continue
+		}
+		created, err := s.productService.Create(ctx, &product)
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			resp.Failed = append(resp.Failed, failure(index, codes.Internal, err.Error()))
+			continue
+		}
+		resp.Created = append(resp.Created, &productsv1.CreatedItem{
+			Index:   index,
+			Product: mapping.ProductToProto(*created),
+		})
+	}
+}
+
+// WatchProducts long-polls the change feed and forwards each batch until the
+// client disconnects or the feed is closed on shutdown. Only the changes of
+// the tenant of the call are forwarded.
+func (s *ProductServer) WatchProducts(req *productsv1.WatchProductsRequest, stream productsv1.ProductService_WatchProductsServer) error {
+	ctx := stream.Context()
+	token := req.GetToken()
+	if token == "" {
+		token = s.feed.Token()
+	}
+	for {
+		pending, next, err := s.feed.Wait(ctx, tenant.From(ctx), token)
+		switch {
+		case errors.Is(err, changes.ErrTokenExpired):
+			return status.Error(codes.FailedPrecondition, "change token expired; relist and watch from a fresh token")
+		case errors.Is(err, changes.ErrInvalidToken):
+			return status.Error(codes.InvalidArgument, "invalid change token")
+		case err != nil:
+			return status.Errorf(codes.Internal, "failed to watch products: %v", err)
+		}
+		// Wait has no deadline here, so an empty result means the client left or the feed closed.
+		if len(pending) == 0 {
+			if err := ctx.Err(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+			return nil
+		}
+
+		msgs, err := mapping.ChangesToProto(pending)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(&productsv1.WatchProductsResponse{Changes: msgs, Token: next}); err != nil {
+			return err
+		}
+		token = next
+	}
+}
+
+func failure(index int32, code codes.Code, message string) *productsv1.FailedItem {
+	return &productsv1.FailedItem{Index: index, Code: code.String(), Message: message}
+}
+
+func validationMessage(err error) string {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return fmt.Sprint(httpErr.Message)
+	}
+	return err.Error()
+}
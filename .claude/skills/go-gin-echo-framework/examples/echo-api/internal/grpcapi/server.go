@@ -0,0 +1,58 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server is a gRPC server, bound to its listener or served through Multiplex.
+type Server struct {
+	*grpc.Server
+	lis net.Listener
+}
+
+// Listen binds addr and returns a server ready for service registration.
+// Reflection is enabled so tools such as grpcurl can discover the services.
+func Listen(addr string, opts ...grpc.ServerOption) (*Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := New(opts...)
+	s.lis = lis
+	return s, nil
+}
+
+// New returns a server without a listener of its own, to be served on the
+// HTTP port through Multiplex.
+func New(opts ...grpc.ServerOption) *Server {
+	s := grpc.NewServer(opts...)
+	reflection.Register(s)
+	return &Server{Server: s}
+}
+
+// Serve accepts connections on the listener bound by Listen until Shutdown
+// is called.
+func (s *Server) Serve() error {
+	return s.Server.Serve(s.lis)
+}
+
+// Shutdown stops accepting new streams and waits for active ones to finish,
+// cancelling them once ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// APIKeyHandler lets a logged-in account manage the API keys its machine
+// clients authenticate with. Mount it behind auth.Identify and auth.Require.
+type APIKeyHandler struct {
+	keys auth.APIKeyService
+}
+
+func NewAPIKeyHandler(keys auth.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" validate:"required,max=100"` // what the key is for, e.g. "ci"
+}
+
+// Register mounts GET /, POST / and DELETE /:id on g.
+func (h *APIKeyHandler) Register(g *echo.Group) {
+	g.GET("/", h.List)
+	g.POST("/", h.Create)
+	g.DELETE("/:id", h.Delete)
+}
+
+// @Summary List API keys
+// @Description Lists the calling account's API keys. Secrets are never returned after creation.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} auth.APIKeyInfo
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/api-keys [get]
+func (h *APIKeyHandler) List(c echo.Context) error {
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "API keys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	keys, err := h.keys.List(c.Request().Context(), owner)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// @Summary Create an API key
+// @Description Creates an API key for the calling account. The returned `key` is shown only once; send it in the X-API-Key header. An account holds at most auth.max_api_keys keys, see GET /usage.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param key body handler.CreateAPIKeyRequest true "Key name"
+// @Success 201 {object} auth.CreatedAPIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 402 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/api-keys [post]
+func (h *APIKeyHandler) Create(c echo.Context) error {
+	var req CreateAPIKeyRequest
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "API keys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	key, err := h.keys.Create(c.Request().Context(), owner, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrQuota) {
+			// At auth.max_api_keys already
+			return c.JSON(http.StatusPaymentRequired, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, key)
+}
+
+// @Summary Delete an API key
+// @Description Deletes one of the calling account's API keys; it stops working immediately.
+// @Tags Auth
+// @Param id path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/api-keys/{id} [delete]
+func (h *APIKeyHandler) Delete(c echo.Context) error {
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "API keys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := h.keys.Delete(c.Request().Context(), owner, c.Param("id")); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// sessionOwner returns the calling account if it called with a login
+// session: API keys, passkeys and authenticator apps are managed with one
+// only, so a leaked key cannot be used to mint more keys or add sign-ins of
+// its own. Guests have not logged in.
+func sessionOwner(c echo.Context) (string, bool) {
+	caller := auth.CallerFromContext(c.Request().Context())
+	if caller == nil || caller.Method != auth.MethodJWT || caller.Guest {
+		return "", false
+	}
+	return caller.Subject, true
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/archive"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// ArchiveHandler exports every collection to a portable archive and imports
+// one back (see package archive). Mount it behind auth.RequireAdmin, with
+// middleware that accepts archive.MediaType bodies.
+type ArchiveHandler struct {
+	archive *archive.Archive
+}
+
+func NewArchiveHandler(a *archive.Archive) *ArchiveHandler {
+	return &ArchiveHandler{archive: a}
+}
+
+// Register mounts GET /export and POST /import on e, behind m. They are
+// routes of e itself: a group without a prefix would run m for every
+// unknown route.
+func (h *ArchiveHandler) Register(e *echo.Echo, m ...echo.MiddlewareFunc) {
+	e.GET("/export", h.Export, m...)
+	e.POST("/import", h.Import, m...)
+}
+
+// Validation adapts v to archive.New, reporting a failure by its message.
+func Validation(v echo.Validator) func(item any) error {
+	return func(item any) error {
+		err := v.Validate(item)
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return fmt.Errorf("%v", httpErr.Message)
+		}
+		return err
+	}
+}
+
+// @Summary Export all collections
+// @Description Downloads a zip of one NDJSON file per collection, e.g. products.ndjson, and manifest.json listing them with the schema version and count of their items. Items are read as they are written, so a failure midway can only cut the archive short, which makes it unreadable by import.
+// @Tags Admin
+// @Produce zip
+// @Success 200 "Zip archive"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /export [get]
+func (h *ArchiveHandler) Export(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, archive.MediaType)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="export.zip"`)
+	res.WriteHeader(http.StatusOK)
+	ctx := c.Request().Context()
+	if err := h.archive.Export(ctx, res); err != nil && ctx.Err() == nil {
+		log.Printf("export: stopped early: %v", err)
+	}
+	return nil
+}
+
+// @Summary Import collections
+// @Description Writes the items of an archive made by GET /export, possibly on another deployment or database backend, all or none. Collections at a schema version newer than the server's are refused. Items whose ID exists are handled by conflict: skip keeps the existing item, overwrite replaces it, merge updates the fields the imported item has. Imported items are validated, audited and published like any other write.
+// @Tags Admin
+// @Accept zip
+// @Produce json
+// @Param archive body string true "Zip archive"
+// @Param conflict query string false "skip (default), overwrite or merge"
+// @Success 200 {array} archive.Result
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /import [post]
+func (h *ArchiveHandler) Import(c echo.Context) error {
+	if err := checkQuery(c, "conflict"); err != nil {
+		return err
+	}
+	strategy := archive.Skip
+	if s := c.QueryParam("conflict"); s != "" {
+		strategy = archive.Strategy(s)
+	}
+	if !slices.Contains(archive.Strategies, strategy) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("conflict must be one of %v", archive.Strategies)})
+	}
+	// A zip is read from its end, so the archive is held in memory, up to
+	// the group's max_body_bytes
+	data, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	results, err := h.archive.Import(c.Request().Context(), bytes.NewReader(data), int64(len(data)), strategy)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalid) || errors.Is(err, service.ErrInvalid) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/audit"
+)
+
+// AuditHandler lists the audit trail of writes. Mount it behind
+// auth.RequireAdmin.
+type AuditHandler struct {
+	rec *audit.Recorder
+}
+
+func NewAuditHandler(rec *audit.Recorder) *AuditHandler {
+	return &AuditHandler{rec: rec}
+}
+
+// Register mounts the audit trail on g itself.
+func (h *AuditHandler) Register(g *echo.Group) {
+	g.GET("", h.List)
+}
+
+// @Summary List audit entries
+// @Description Lists the recorded creates, updates and deletes, newest first, with who made them, in which request, and the item before and after. Every filter given must match.
+// @Tags Admin
+// @Produce json
+// @Param resource query string false "Singular resource name, e.g. product"
+// @Param subject query string false "ID of the item written"
+// @Param actor query string false "Caller as in access logs, e.g. an account ID"
+// @Param limit query int false "Entries to return, at most 1000 (default 100)"
+// @Success 200 {array} audit.Entry
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func (h *AuditHandler) List(c echo.Context) error {
+	if err := checkQuery(c, "resource", "subject", "actor", "limit"); err != nil {
+		return err
+	}
+	q := audit.Query{Resource: c.QueryParam("resource"), SubjectID: c.QueryParam("subject"), Actor: c.QueryParam("actor")}
+	if s := c.QueryParam("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > audit.MaxLimit {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a number between 1 and 1000"})
+		}
+		q.Limit = n
+	}
+	entries, err := h.rec.List(c.Request().Context(), q)
+	if err != nil {
+		if errors.Is(err, audit.ErrNoReader) {
+			return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+)
+
+type AuthHandler struct {
+	auth auth.AuthService
+}
+
+func NewAuthHandler(authService auth.AuthService) *AuthHandler {
+	return &AuthHandler{auth: authService}
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// MFALoginRequest completes a login that answered 401 with an mfa_token.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,len=6,numeric"` // shown by the authenticator app
+}
+
+// RefreshRequest carries the refresh token of the previous login or refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Register mounts POST /register, /login, /login/mfa, /refresh and /logout
+// on g.
+func (h *AuthHandler) Register(g *echo.Group) {
+	g.POST("/register", h.SignUp)
+	g.POST("/login", h.Login)
+	g.POST("/login/mfa", h.LoginMFA)
+	g.POST("/guest", h.StartGuest)
+	g.POST("/refresh", h.Refresh)
+	g.POST("/logout", h.Logout)
+}
+
+// @Summary Register an account
+// @Description Creates a password login. The password is stored as a bcrypt hash. Refused with 403 while `auth.login_provider` is `ldap`, as the directory holds the accounts.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param account body handler.RegisterRequest true "Email and password (8 to 72 bytes)"
+// @Success 201 {object} auth.Account
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/register [post]
+func (h *AuthHandler) SignUp(c echo.Context) error {
+	var req RegisterRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	account, err := h.auth.Register(c.Request().Context(), req.Email, req.Password, "")
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusCreated, account)
+}
+
+// @Summary Log in
+// @Description Exchanges an email and password for a JWT access token. After `auth.max_failed_logins` consecutive wrong passwords the account is locked for `auth.lockout_duration`; Retry-After gives the seconds left. An account its identity provider deactivated over SCIM gets 403. With `auth.login_provider: ldap` the email is the directory login instead, checked by binding as the user it finds, and the token carries the roles of the user's groups (`auth.ldap.roles`); 502 means the directory could not be reached. An account with an authenticator app gets 401 with an `mfa_token` instead, to send with a code of the app to /auth/login/mfa within 5 minutes.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body handler.LoginRequest true "Email (or directory login) and password"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 423 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security none
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c echo.Context) error {
+	var req LoginRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	token, err := h.auth.Authenticate(c.Request().Context(), req.Email, req.Password)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, token)
+}
+
+// @Summary Complete a login with a one-time code
+// @Description Exchanges the `mfa_token` of a password login and the current code of the account's authenticator app for a JWT access token. The token is good for one attempt: after a wrong code, log in with the password again. Each code is accepted once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param login body handler.MFALoginRequest true "MFA token of the login and a code of the authenticator app"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c echo.Context) error {
+	var req MFALoginRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	token, err := h.auth.CompleteMFA(c.Request().Context(), req.MFAToken, req.Code)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, token)
+}
+
+// @Summary Start a guest session
+// @Description Starts the session of a guest, who uses the API without registering: what it creates is owned by it (`owner_id`) until an account claims it at /auth/guest/claim with the guest's access token, which ends the session. Guests refresh like any session but cannot manage API keys, passkeys or authenticator apps.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.Token
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/guest [post]
+func (h *AuthHandler) StartGuest(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	token, err := h.auth.StartGuest(c.Request().Context())
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, token)
+}
+
+// @Summary Refresh an access token
+// @Description Exchanges a refresh token for a new access and refresh token pair. Each refresh token is single use: presenting one again revokes every token issued since the login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body handler.RefreshRequest true "Refresh token from the login or the previous refresh"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	var req RefreshRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	token, err := h.auth.Refresh(c.Request().Context(), req.RefreshToken)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, token)
+}
+
+// @Summary Log out
+// @Description Revokes the refresh token's whole token family, including access tokens that have not expired yet.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body handler.RefreshRequest true "Current refresh token"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c echo.Context) error {
+	var req RefreshRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if err := h.auth.Logout(c.Request().Context(), req.RefreshToken); err != nil {
+		return h.fail(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) fail(c echo.Context, err error) error {
+	var locked *auth.LockedError
+	var mfa *auth.MFARequiredError
+	switch {
+	case errors.As(err, &locked):
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(time.Until(locked.Until).Seconds())+1))
+		return c.JSON(http.StatusLocked, map[string]string{"error": err.Error()})
+	case errors.As(err, &mfa):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error(), "mfa_token": mfa.Token})
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrInvalidCode):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrAccountDisabled), errors.Is(err, auth.ErrRegistrationClosed):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrProviderUnavailable):
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrEmailTaken):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrInvalidPassword):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
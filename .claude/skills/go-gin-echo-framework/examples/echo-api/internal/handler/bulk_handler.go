@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/confirm"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// maxBulk caps the IDs of one bulk operation.
+const maxBulk = 100
+
+// BulkHandler serves operations on many products at once. Being easy to
+// get wrong and hard to undo, each is confirmed in two calls (see package
+// confirm) unless confirmations is nil.
+type BulkHandler struct {
+	productService service.ProductService
+	confirmations  *confirm.Store
+}
+
+func NewBulkHandler(productService service.ProductService, confirmations *confirm.Store) *BulkHandler {
+	return &BulkHandler{
+		productService: productService,
+		confirmations:  confirmations,
+	}
+}
+
+// @Summary Delete products
+// @Description Deletes the products with the given IDs, all or none. Unless confirmations are disabled, a call without confirm deletes nothing and answers with the products it would delete and a token; repeating it with that token as confirm before the token expires deletes them. A token confirms only the call it was issued for, by the same caller, once.
+// @Tags Product
+// @Produce json
+// @Param ids query string true "Comma-separated IDs, at most 100"
+// @Param confirm query string false "Token of the first call"
+// @Success 200 {object} confirm.Challenge[model.Product]
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /products [delete]
+func (h *BulkHandler) DeleteProducts(c echo.Context) error {
+	if err := checkQuery(c, "ids", "confirm"); err != nil {
+		return err
+	}
+	return deleteMany(c, h.productService, h.confirmations, "products")
+}
+
+// deleteMany deletes the items of svc named by the ids query parameter,
+// plural naming them, in two calls when confirmations is not nil.
+func deleteMany[T model.Entity](c echo.Context, svc service.CrudService[T], confirmations *confirm.Store, plural string) error {
+	ids, err := idList(c.QueryParam("ids"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	ctx := c.Request().Context()
+	if confirmations != nil {
+		// The token is bound to the operation, its IDs and the caller
+		key := "delete " + plural + " " + strings.Join(ids, ",")
+		if caller := auth.CallerFromContext(ctx); caller != nil {
+			key += " by " + caller.String()
+		}
+		token := c.QueryParam("confirm")
+		if token == "" {
+			items, err := svc.GetByIDs(ctx, ids)
+			if err != nil {
+				return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+			}
+			if missing := missingIDs(ids, items); len(missing) > 0 {
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "not found: " + strings.Join(missing, ", ")})
+			}
+			token, expires := confirmations.Issue(key)
+			return c.JSON(http.StatusOK, confirm.Challenge[T]{
+				Operation: fmt.Sprintf("delete %d %s", len(items), plural),
+				Affected:  items,
+				Token:     token,
+				ExpiresAt: expires,
+			})
+		}
+		if err := confirmations.Redeem(token, key); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if err := svc.DeleteMany(ctx, ids); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "some of the " + plural + " were not found"})
+		}
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// idList parses a comma-separated list of IDs into a sorted set of at most
+// maxBulk.
+func idList(s string) ([]string, error) {
+	if s == "" {
+		return nil, errors.New("ids is required")
+	}
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			return nil, errors.New("ids must not contain empty IDs")
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) > maxBulk {
+		return nil, fmt.Errorf("ids must name at most %d items", maxBulk)
+	}
+	return ids, nil
+}
+
+// missingIDs returns the ids without an item in items.
+func missingIDs[T model.Entity](ids []string, items []T) []string {
+	found := make(map[string]bool, len(items))
+	for _, item := range items {
+		found[item.GetID()] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/cache"
+)
+
+// CacheHandler lets admins flush the read-through cache of the
+// repositories. Mount it behind auth.RequireAdmin; with a nil store (the
+// cache disabled) it answers 501.
+type CacheHandler struct {
+	store cache.Store
+}
+
+func NewCacheHandler(store cache.Store) *CacheHandler {
+	return &CacheHandler{store: store}
+}
+
+// Register mounts the flush on /flush.
+func (h *CacheHandler) Register(g *echo.Group) {
+	g.POST("/flush", h.Flush)
+}
+
+// @Summary Flush the cache
+// @Description Drops every entry of the repositories' cache, e.g. after changing the database by hand; reads fill it again. Answers with the number of entries dropped.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/cache/flush [post]
+func (h *CacheHandler) Flush(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	f, ok := h.store.(cache.Flusher)
+	if !ok {
+		return c.JSON(http.StatusNotImplemented, map[string]string{"error": "the cache is not enabled"})
+	}
+	n, err := f.Flush(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]int{"flushed": n})
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+const (
+	defaultChangesWait = 30 * time.Second
+	maxChangesWait     = 60 * time.Second
+)
+
+type ChangesHandler struct {
+	feed *changes.Feed
+}
+
+func NewChangesHandler(feed *changes.Feed) *ChangesHandler {
+	return &ChangesHandler{
+		feed: feed,
+	}
+}
+
+type changesResponse struct {
+	Changes []changes.Change `json:"changes"`
+	Token   string           `json:"token"`
+}
+
+// @Summary Long-poll for product changes
+// @Description Blocks until products of the request's tenant change after the given token or the wait expires. Omit `since` to obtain an initial token.
+// @Tags Product
+// @Produce json
+// @Param since query string false "Token returned by a previous call"
+// @Param wait query string false "Maximum time to block, e.g. 30s (max 60s)"
+// @Success 200 {object} changesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Security none
+// @Router /products/changes [get]
+func (h *ChangesHandler) GetChanges(c echo.Context) error {
+	if err := checkQuery(c, "since", "wait"); err != nil {
+		return err
+	}
+	since := c.QueryParam("since")
+	if since == "" {
+		return c.JSON(http.StatusOK, changesResponse{Changes: []changes.Change{}, Token: h.feed.Token()})
+	}
+
+	wait := defaultChangesWait
+	if raw := c.QueryParam("wait"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "wait must be a non-negative duration, e.g. 30s"})
+		}
+		wait = min(d, maxChangesWait)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), wait)
+	defer cancel()
+
+	pending, next, err := h.feed.Wait(ctx, tenant.From(ctx), since)
+	if err != nil {
+		if errors.Is(err, changes.ErrTokenExpired) {
+			return c.JSON(http.StatusGone, map[string]string{"error": "Change token expired, resync required"})
+		}
+		if errors.Is(err, changes.ErrInvalidToken) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid change token"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, changesResponse{Changes: pending, Token: next})
+}
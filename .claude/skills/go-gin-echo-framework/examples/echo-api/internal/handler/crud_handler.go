@@ -0,0 +1,419 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/compat"
+	"github.com/your-username/echo-api/internal/custom"
+	"github.com/your-username/echo-api/internal/fields"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/render"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// CrudHandler serves the standard REST routes for one resource on top of a
+// CrudService. Document a resource with a `@Resource <path> <model>`
+// annotation on its constructor for each path it is mounted on, next to
+// @Security for its auth requirement; internal/openapi expands them into the
+// eight operations below.
+type CrudHandler[T model.Entity, P model.EntityPtr[T]] struct {
+	service service.CrudService[T]
+	name    string // display name used in error messages, e.g. "Product"
+}
+
+func NewCrudHandler[T model.Entity, P model.EntityPtr[T]](svc service.CrudService[T], name string) *CrudHandler[T, P] {
+	return &CrudHandler[T, P]{
+		service: svc,
+		name:    name,
+	}
+}
+
+// streamFlush is how many items Stream writes between flushes.
+const streamFlush = 100
+
+// Register mounts GET /, GET /stream, GET /schema, GET /:id, POST /,
+// PUT /:id, DELETE /:id and POST /:id/undo-delete on g.
+func (h *CrudHandler[T, P]) Register(g *echo.Group) {
+	g.GET("/", h.List)
+	g.GET("/stream", h.Stream)
+	g.GET("/schema", h.Schema)
+	g.GET("/:id", h.Get)
+	g.POST("/", h.Create)
+	g.PUT("/:id", h.Update)
+	g.DELETE("/:id", h.Delete)
+	g.POST("/:id/undo-delete", h.UndoDelete)
+}
+
+func (h *CrudHandler[T, P]) List(c echo.Context) error {
+	filter, err := h.listQuery(c)
+	if err != nil {
+		return err
+	}
+	proj, err := h.projection(c)
+	if err != nil {
+		return err
+	}
+	format, err := negotiate(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	items, err := h.service.GetAll(ctx)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	items = keep(items, filter)
+	if notModified(c, lastModified(items, h.service.LastDelete())) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return write(c, format, proj.Apply(items))
+}
+
+// Stream lists every item as NDJSON, writing each as it is read from the
+// repository so memory use does not grow with the list. Without the whole
+// list there is no Last-Modified; a failure after the first item can only
+// end the stream early, and is logged.
+func (h *CrudHandler[T, P]) Stream(c echo.Context) error {
+	filter, err := h.listQuery(c)
+	if err != nil {
+		return err
+	}
+	proj, err := h.projection(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	res := c.Response()
+	enc := json.NewEncoder(res)
+	n := 0
+	err = h.service.Stream(ctx, func(item T) error {
+		if !matchesCustom(item, filter) {
+			return nil
+		}
+		if n == 0 {
+			res.Header().Set(echo.HeaderContentType, render.NDJSON.MediaType+"; charset=utf-8")
+			res.WriteHeader(http.StatusOK)
+		}
+		if err := enc.Encode(proj.Apply(item)); err != nil {
+			return err
+		}
+		if n++; n%streamFlush == 0 {
+			res.Flush()
+		}
+		return ctx.Err() // the client went away
+	})
+	switch {
+	case err != nil && n == 0:
+		return h.fail(c, err)
+	case err != nil:
+		if ctx.Err() == nil {
+			log.Printf("stream %s list: stopped after %d items: %v", h.name, n, err)
+		}
+		return nil
+	case n == 0:
+		return c.Blob(http.StatusOK, render.NDJSON.MediaType+"; charset=utf-8", nil)
+	}
+	return nil
+}
+
+// Schema returns the custom fields of the items in the caller's scope, by
+// name; none unless the service is service.CustomFielded.
+func (h *CrudHandler[T, P]) Schema(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, h.customFields(c.Request().Context()))
+}
+
+func (h *CrudHandler[T, P]) Get(c echo.Context) error {
+	if err := checkQuery(c, "fields"); err != nil {
+		return err
+	}
+	proj, err := h.projection(c)
+	if err != nil {
+		return err
+	}
+	format, err := negotiate(c)
+	if err != nil {
+		return err
+	}
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	item, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if notModified(c, lastModified([]T{*item}, time.Time{})) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return write(c, format, proj.Apply(item))
+}
+
+func (h *CrudHandler[T, P]) Create(c echo.Context) error {
+	var item T
+	if err := checkQuery(c, "dry_run"); err != nil {
+		return err
+	}
+	if err := dryRun(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&item); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&item); err != nil {
+		return err
+	}
+	if item.GetID() != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": errClientID.Error()})
+	}
+	model.KeepReadOnly(&item, new(T))
+
+	ctx := c.Request().Context()
+	created, err := h.service.Create(ctx, &item)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// Update decodes the body onto the stored item, so that the fields it
+// leaves out keep their values.
+func (h *CrudHandler[T, P]) Update(c echo.Context) error {
+	id := c.Param("id")
+	if err := checkQuery(c, "dry_run"); err != nil {
+		return err
+	}
+	if err := dryRun(c); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	stored, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	item := *stored
+	if err := c.Bind(&item); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&item); err != nil {
+		return err
+	}
+	model.KeepReadOnly(&item, stored) // the ID from the path among them
+
+	updated, err := h.service.Update(ctx, &item)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h *CrudHandler[T, P]) Delete(c echo.Context) error {
+	if err := checkQuery(c, "dry_run"); err != nil {
+		return err
+	}
+	if err := dryRun(c); err != nil {
+		return err
+	}
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	if err := h.service.Delete(ctx, id); err != nil {
+		return h.fail(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UndoDelete restores an item deleted within the trash window, answering
+// 404 once the window has passed.
+func (h *CrudHandler[T, P]) UndoDelete(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	restored, err := h.service.Restore(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, restored)
+}
+
+func (h *CrudHandler[T, P]) fail(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": h.name + " not found"})
+	case errors.Is(err, service.ErrInvalid):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrConflict):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+}
+
+// statusOf is the status of a service error that the calling handler has no
+// more specific status for: 403 across tenants, 402 beyond a quota, e.g.
+// of a tenant's plan, 500 otherwise.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrQuota):
+		return http.StatusPaymentRequired
+	}
+	return http.StatusInternalServerError
+}
+
+// customFields returns the custom fields of the items in the scope of ctx.
+func (h *CrudHandler[T, P]) customFields(ctx context.Context) custom.Schema {
+	if c, ok := h.service.(service.CustomFielded); ok {
+		if schema := c.CustomFields(ctx); schema != nil {
+			return schema
+		}
+	}
+	return custom.Schema{}
+}
+
+// listQuery checks the query of a list, which may select fields and filter
+// on custom fields (custom.<name>=<value>); it is a 400 error when a
+// filter's value is not one of its field's.
+func (h *CrudHandler[T, P]) listQuery(c echo.Context) (custom.Filter, error) {
+	schema := h.customFields(c.Request().Context())
+	if err := checkQuery(c, append([]string{"fields"}, schema.Params()...)...); err != nil {
+		return nil, err
+	}
+	filter, err := custom.ParseFilter(schema, c.QueryParams())
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return filter, nil
+}
+
+// keep returns the items matching filter.
+func keep[T any](items []T, filter custom.Filter) []T {
+	if len(filter) == 0 {
+		return items
+	}
+	kept := []T{}
+	for _, item := range items {
+		if matchesCustom(item, filter) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// matchesCustom reports whether the custom fields of item match filter.
+func matchesCustom[T any](item T, filter custom.Filter) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	e, ok := any(item).(model.Extensible)
+	return ok && filter.Match(e.CustomFields())
+}
+
+// projection compiles the fields query parameter (see package fields) for
+// T; it is a 400 error when malformed or naming a field T does not have.
+func (h *CrudHandler[T, P]) projection(c echo.Context) (*fields.Projection, error) {
+	set, err := fields.Parse(c.QueryParam("fields"))
+	var proj *fields.Projection
+	if err == nil {
+		proj, err = fields.New(reflect.TypeFor[T](), set)
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return proj, nil
+}
+
+// negotiate picks the response format from the Accept header (see
+// render.Default), answering 406 when none is acceptable.
+func negotiate(c echo.Context) (render.Format, error) {
+	c.Response().Header().Add("Vary", "Accept")
+	format, ok := render.Default.Negotiate(c.Request().Header.Get("Accept"))
+	if !ok {
+		return format, echo.NewHTTPError(http.StatusNotAcceptable, "supported media types: "+strings.Join(render.Default.MediaTypes(), ", "))
+	}
+	return format, nil
+}
+
+// write sends v with status 200 in format. JSON goes through echo's
+// serializer like every other response.
+func write(c echo.Context, format render.Format, v any) error {
+	if format.MediaType == render.JSON.MediaType {
+		return c.JSON(http.StatusOK, v)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, format.MediaType+"; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	return format.Encode(c.Response(), v)
+}
+
+// lastModified is the newest modification time of items and deleted, or
+// zero when T does not record one (see model.Timestamped).
+func lastModified[T any](items []T, deleted time.Time) time.Time {
+	if _, ok := any(new(T)).(model.Timestamped); !ok {
+		return time.Time{}
+	}
+	latest := deleted
+	for _, item := range items {
+		if t := any(item).(model.Timestamped).LastModified(); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// notModified sets Last-Modified and reports whether the request's
+// If-Modified-Since makes the body unnecessary, so the handler can answer
+// 304. HTTP dates have a resolution of one second. As RFC 9110 prescribes,
+// the date is ignored when the request also carries If-None-Match.
+func notModified(c echo.Context, modified time.Time) bool {
+	if modified.IsZero() {
+		return false
+	}
+	modified = modified.UTC().Truncate(time.Second)
+	c.Response().Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	req := c.Request()
+	if req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// checkQuery rejects query parameters other than allowed in strict mode and
+// logs them in lenient mode.
+func checkQuery(c echo.Context, allowed ...string) error {
+	if err := compat.CheckQuery(c.Request(), allowed...); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// dryRun puts the request in dry-run mode (see service.WithDryRun) when the
+// dry_run query parameter or else the X-Dry-Run header is true, marking the
+// response with X-Dry-Run; a value that is not a boolean is a 400 error.
+func dryRun(c echo.Context) error {
+	v := c.QueryParam("dry_run")
+	if v == "" {
+		v = c.Request().Header.Get("X-Dry-Run")
+	}
+	if v == "" {
+		return nil
+	}
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "dry_run must be true or false")
+	}
+	if on {
+		c.SetRequest(c.Request().WithContext(service.WithDryRun(c.Request().Context())))
+		c.Response().Header().Set("X-Dry-Run", "true")
+	}
+	return nil
+}
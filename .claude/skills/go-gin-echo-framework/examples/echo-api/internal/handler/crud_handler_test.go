@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/service/servicetest"
+	"github.com/your-username/echo-api/internal/util"
+)
+
+func serve(t *testing.T, register func(g *echo.Group)) func(method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	e.Validator = util.NewCustomValidator()
+	register(e.Group(""))
+	return func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func TestCrudHandlerMapsServiceErrors(t *testing.T) {
+	products := servicetest.NewFake[model.Product](nil)
+	products.Seed(model.Product{ID: "p1", Name: "Lamp", Price: 20})
+	do := serve(t, NewCrudHandler[model.Product](products, "product").Register)
+
+	if w := do(http.MethodGet, "/p1", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Lamp"`) {
+		t.Errorf("GET /p1: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/", `{"name": "Desk", "price": 120}`); w.Code != http.StatusCreated || products.Faults.Calls("Create") != 1 {
+		t.Errorf("POST /: %d %s", w.Code, w.Body)
+	}
+
+	products.Faults.FailNext("GetAll", 1, errors.New("connection refused"))
+	if w := do(http.MethodGet, "/", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("GET / with a failing service: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/", ""); w.Code != http.StatusOK {
+		t.Errorf("GET / after the failure: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCrudHandlerUpdateKeepsFieldsTheBodyLeavesOut(t *testing.T) {
+	products := servicetest.NewFake[model.Product](nil)
+	products.Seed(model.Product{ID: "p1", Name: "Lamp", Price: 20, Custom: model.Attributes{"color": "red"}})
+	do := serve(t, NewCrudHandler[model.Product](products, "product").Register)
+
+	if w := do(http.MethodPut, "/p1", `{"name": "Desk lamp"}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT /p1: %d %s", w.Code, w.Body)
+	}
+	if p, _ := products.GetByID(context.Background(), "p1"); p.Name != "Desk lamp" || p.Price != 20 || p.Custom["color"] != "red" {
+		t.Errorf("after leaving out price and custom: %+v", p)
+	}
+	if w := do(http.MethodPut, "/p1", `{"price": 0, "custom": {"size": "XL"}}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT /p1: %d %s", w.Code, w.Body)
+	}
+	if p, _ := products.GetByID(context.Background(), "p1"); p.Name != "Desk lamp" || p.Price != 0 || len(p.Custom) != 1 || p.Custom["size"] != "XL" {
+		t.Errorf("after setting price and custom: %+v", p)
+	}
+	if w := do(http.MethodPut, "/missing", `{"name": "Desk"}`); w.Code != http.StatusNotFound {
+		t.Errorf("PUT /missing: %d %s", w.Code, w.Body)
+	}
+}
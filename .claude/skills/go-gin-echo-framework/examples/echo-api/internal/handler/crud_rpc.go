@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/compat"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/rpc"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// errClientID refuses a create that names its item: IDs are assigned by the
+// service, so clients cannot pick or guess them.
+var errClientID = errors.New("id is assigned by the server and must not be sent")
+
+type idParams struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// RegisterCrudRPC exposes svc as "<prefix>.list/get/create/update/delete"
+// JSON-RPC methods. Params are decoded under the request's compatibility mode
+// and checked by the same validator as the REST handlers.
+func RegisterCrudRPC[T model.Entity](s *rpc.Server, prefix string, svc service.CrudService[T], v echo.Validator) {
+	decode := func(ctx context.Context, params json.RawMessage, dst any) error {
+		if len(params) == 0 {
+			return rpc.InvalidParams(errors.New("params are required"))
+		}
+		if err := compat.DecodeJSON(ctx, params, dst); err != nil {
+			return rpc.InvalidParams(err)
+		}
+		if err := v.Validate(dst); err != nil {
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				return rpc.InvalidParams(fmt.Errorf("%v", httpErr.Message))
+			}
+			return rpc.InvalidParams(err)
+		}
+		return nil
+	}
+
+	s.Register(prefix+".list", func(ctx context.Context, _ json.RawMessage) (any, error) {
+		return svc.GetAll(ctx)
+	})
+	s.Register(prefix+".get", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p idParams
+		if err := decode(ctx, params, &p); err != nil {
+			return nil, err
+		}
+		return svc.GetByID(ctx, p.ID)
+	})
+	s.Register(prefix+".create", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var item T
+		if err := decode(ctx, params, &item); err != nil {
+			return nil, err
+		}
+		if item.GetID() != "" {
+			return nil, rpc.InvalidParams(errClientID)
+		}
+		model.KeepReadOnly(&item, new(T))
+		return svc.Create(ctx, &item)
+	})
+	s.Register(prefix+".update", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var item T
+		if err := decode(ctx, params, &item); err != nil {
+			return nil, err
+		}
+		if item.GetID() == "" {
+			return nil, rpc.InvalidParams(errors.New("id is required"))
+		}
+		return svc.Update(ctx, &item)
+	})
+	s.Register(prefix+".delete", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p idParams
+		if err := decode(ctx, params, &p); err != nil {
+			return nil, err
+		}
+		return nil, svc.Delete(ctx, p.ID)
+	})
+}
+
+// MapRPCError translates service errors into typed JSON-RPC errors.
+func MapRPCError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return rpc.NewError(rpc.CodeNotFound, "Not found")
+	case errors.Is(err, service.ErrInvalid):
+		return rpc.InvalidParams(err)
+	case errors.Is(err, service.ErrForbidden):
+		return rpc.NewError(rpc.CodeForbidden, "Forbidden")
+	case errors.Is(err, service.ErrQuota):
+		return rpc.NewError(rpc.CodePaymentRequired, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/outbox"
+)
+
+// maxDeadLetters caps the dead letters of one list.
+const maxDeadLetters = 1000
+
+// DeadLetterHandler lets admins inspect the outbox events set aside after
+// their last failed publish, then requeue or discard them. Mount it behind
+// auth.RequireAdmin; with a nil relay (the outbox disabled) it answers 501.
+type DeadLetterHandler struct {
+	relay *outbox.Relay
+}
+
+func NewDeadLetterHandler(relay *outbox.Relay) *DeadLetterHandler {
+	return &DeadLetterHandler{relay: relay}
+}
+
+// Register mounts the dead letters on g itself and their requeue on
+// /requeue.
+func (h *DeadLetterHandler) Register(g *echo.Group) {
+	g.GET("", h.List)
+	g.DELETE("", h.Discard)
+	g.POST("/requeue", h.Requeue)
+}
+
+// DeadLetterIDs is the request of a requeue.
+type DeadLetterIDs struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=1000"`
+}
+
+// disabled answers 501 if the outbox is disabled.
+func (h *DeadLetterHandler) disabled(c echo.Context) error {
+	return c.JSON(http.StatusNotImplemented, map[string]string{"error": "the outbox is not enabled"})
+}
+
+// @Summary List dead letters
+// @Description Lists the outbox events set aside after `outbox.max_attempts` failed publishes, the longest set aside first, with the error of their last attempt. Their count and age are reported by /readyz.
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Dead letters to return, at most 1000 (default 100)"
+// @Success 200 {array} outbox.DeadLetter
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/dead-letters [get]
+func (h *DeadLetterHandler) List(c echo.Context) error {
+	if err := checkQuery(c, "limit"); err != nil {
+		return err
+	}
+	if h.relay == nil {
+		return h.disabled(c)
+	}
+	limit := 100
+	if s := c.QueryParam("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxDeadLetters {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a number between 1 and 1000"})
+		}
+		limit = n
+	}
+	letters, err := h.relay.DeadLetters(c.Request().Context(), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, letters)
+}
+
+// @Summary Requeue dead letters
+// @Description Returns the given dead letters to the relay with fresh attempts; IDs of other events are ignored. A requeued event is published after the events its aggregate recorded since.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param ids body handler.DeadLetterIDs true "Dead letters to requeue"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/dead-letters/requeue [post]
+func (h *DeadLetterHandler) Requeue(c echo.Context) error {
+	var req DeadLetterIDs
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if h.relay == nil {
+		return h.disabled(c)
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	n, err := h.relay.Requeue(c.Request().Context(), req.IDs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"requeued": n})
+}
+
+// @Summary Discard dead letters
+// @Description Deletes the given dead letters, or all of them; IDs of other events are ignored.
+// @Tags Admin
+// @Produce json
+// @Param ids query string false "Comma-separated IDs of the dead letters"
+// @Param all query bool false "Discard every dead letter instead"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/dead-letters [delete]
+func (h *DeadLetterHandler) Discard(c echo.Context) error {
+	if err := checkQuery(c, "ids", "all"); err != nil {
+		return err
+	}
+	if h.relay == nil {
+		return h.disabled(c)
+	}
+	var ids []string
+	switch {
+	case c.QueryParam("all") == "true":
+	case c.QueryParam("ids") != "":
+		ids = strings.Split(c.QueryParam("ids"), ",")
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ids or all=true is required"})
+	}
+	n, err := h.relay.Discard(c.Request().Context(), ids)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"discarded": n})
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/events"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+type EventsHandler struct {
+	bus       *events.Bus
+	sockets   *events.WebSockets
+	heartbeat time.Duration
+	buffer    int
+}
+
+// NewEventsHandler streams the events published to bus, writing a heartbeat
+// on idle streams and dropping clients that fall buffer events behind. The
+// WebSocket endpoints are served by sockets.
+func NewEventsHandler(bus *events.Bus, sockets *events.WebSockets, heartbeat time.Duration, buffer int) *EventsHandler {
+	return &EventsHandler{
+		bus:       bus,
+		sockets:   sockets,
+		heartbeat: heartbeat,
+		buffer:    buffer,
+	}
+}
+
+// @Summary Stream product changes
+// @Description Server-Sent Events for every product created, updated or deleted while connected: the event type is the op, the id the event's sequence number and the data the event as JSON. Comment lines are sent as heartbeats. Clients that fall too far behind are disconnected after a `: dropped` comment and should resync through /products/sync.
+// @Tags Product
+// @Produce event-stream
+// @Param op query string false "Comma-separated ops to receive: created, updated, deleted"
+// @Param id query string false "Comma-separated product IDs to receive events for"
+// @Success 200 {object} events.Event
+// @Failure 400 {object} map[string]string
+// @Security none
+// @Router /products/events [get]
+func (h *EventsHandler) ProductEvents(c echo.Context) error {
+	if err := checkQuery(c, "op", "id"); err != nil {
+		return err
+	}
+	filter, err := events.ParseFilter("product", c.QueryParam("op"), c.QueryParam("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter.Tenant = tenant.From(c.Request().Context())
+	sub := h.bus.Subscribe(filter, h.buffer)
+	defer sub.Close()
+	events.ServeSSE(c.Response(), c.Request(), sub, h.heartbeat)
+	return nil
+}
+
+// @Summary Subscribe to product changes over WebSocket
+// @Description Upgrades to a WebSocket that sends every product created, updated or deleted while connected as an `event` message. The client narrows or widens the products it receives with `{"type": "subscribe", "ids": [...]}` and `{"type": "unsubscribe", "ids": [...]}`, each answered with the resulting `subscriptions`; a subscribe without IDs receives all products again. The server pings on idle connections and closes with 1001 when shutting down, or with 1013 when the client fell too far behind; resync through /products/sync before reconnecting.
+// @Tags Product
+// @Produce json
+// @Param op query string false "Comma-separated ops to receive: created, updated, deleted"
+// @Param id query string false "Comma-separated product IDs to subscribe to initially"
+// @Success 101 {object} events.Message
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security none
+// @Router /products/ws [get]
+func (h *EventsHandler) ProductSocket(c echo.Context) error {
+	if err := checkQuery(c, "op", "id"); err != nil {
+		return err
+	}
+	filter, err := events.ParseFilter("product", c.QueryParam("op"), c.QueryParam("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter.Tenant = tenant.From(c.Request().Context())
+	h.sockets.Serve(c.Response(), c.Request(), filter)
+	return nil
+}
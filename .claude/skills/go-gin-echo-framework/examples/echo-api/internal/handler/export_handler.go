@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/export"
+	"github.com/your-username/echo-api/internal/worker"
+)
+
+// exportJob is the name of the background job that writes exports.
+const exportJob = "exports"
+
+// ExportHandler accepts exports of a collection and reports on them, the
+// files being written by the exports job of jobs; with a nil exporter
+// (jobs.exports empty) it answers 501.
+type ExportHandler struct {
+	exports *export.Exporter
+	jobs    *worker.Worker
+	wait    time.Duration // for room in a full job queue
+}
+
+func NewExportHandler(exports *export.Exporter, jobs *worker.Worker, wait time.Duration) *ExportHandler {
+	return &ExportHandler{exports: exports, jobs: jobs, wait: wait}
+}
+
+// Register mounts GET /:id on g, the group of /exports.
+func (h *ExportHandler) Register(g *echo.Group) {
+	g.GET("/:id", h.Get)
+}
+
+// disabled answers 501 if exports are disabled.
+func (h *ExportHandler) disabled(c echo.Context) error {
+	return c.JSON(http.StatusNotImplemented, map[string]string{"error": "exports are not enabled"})
+}
+
+// @Summary Export products
+// @Description Accepts an export of the products as a CSV or JSON file and answers at once: the file is written to the blob store in the background, and the export polled at the Location of the response until its status is done (with a URL of the file) or failed. A tenant has at most exports.max_pending exports waiting.
+// @Tags Product
+// @Produce json
+// @Param format query string false "File format, csv or json (default json)"
+// @Success 202 {object} export.Export
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security none
+// @Router /products/export [post]
+func (h *ExportHandler) ExportProducts(c echo.Context) error {
+	if err := checkQuery(c, "format"); err != nil {
+		return err
+	}
+	if h.exports == nil {
+		return h.disabled(c)
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	e, err := h.exports.Request(c.Request().Context(), "products", format)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	// A full queue only delays the export to the job's next scheduled run
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.wait)
+	defer cancel()
+	h.jobs.Enqueue(ctx, exportJob)
+
+	c.Response().Header().Set(echo.HeaderLocation, "/exports/"+e.ID)
+	c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+	return c.JSON(http.StatusAccepted, e)
+}
+
+// @Summary Get an export
+// @Description Reports how far an export of the caller's tenant got. While it is pending or running, Retry-After gives the seconds to wait before polling again; once done, url downloads the file until expires_at (blob.url_ttl). Exports are forgotten, and their files deleted, exports.retention after they finish.
+// @Tags Export
+// @Produce json
+// @Param id path string true "Export ID"
+// @Success 200 {object} export.Export
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security none
+// @Router /exports/{id} [get]
+func (h *ExportHandler) Get(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if h.exports == nil {
+		return h.disabled(c)
+	}
+	e, err := h.exports.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if e.Status == export.Pending || e.Status == export.Running {
+		c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+	}
+	return c.JSON(http.StatusOK, e)
+}
+
+func (h *ExportHandler) fail(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, export.ErrInvalid):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, export.ErrBusy):
+		c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+		return c.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+	case errors.Is(err, export.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Export not found"})
+	default:
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+}
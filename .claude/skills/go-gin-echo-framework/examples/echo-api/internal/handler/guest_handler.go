@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// GuestHandler lets a logged-in account claim the session of a guest,
+// taking over what the guest created. Mount it behind auth.Identify and
+// auth.Require.
+type GuestHandler struct {
+	guests auth.GuestService
+}
+
+func NewGuestHandler(guests auth.GuestService) *GuestHandler {
+	return &GuestHandler{guests: guests}
+}
+
+// ClaimGuestRequest names the guest session to claim.
+type ClaimGuestRequest struct {
+	GuestToken string `json:"guest_token" validate:"required"` // the guest's current access token
+}
+
+// Register mounts POST /claim on g.
+func (h *GuestHandler) Register(g *echo.Group) {
+	g.POST("/claim", h.Claim)
+}
+
+// @Summary Claim a guest session
+// @Description Hands everything the guest of `guest_token` owns to the calling account in one transaction, e.g. after the guest registered or logged in, and ends the guest's session. A guest is claimed once, in the tenant it started in.
+// @Tags Auth
+// @Accept json
+// @Param guest body handler.ClaimGuestRequest true "Access token of the guest"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/guest/claim [post]
+func (h *GuestHandler) Claim(c echo.Context) error {
+	var req ClaimGuestRequest
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "guest sessions can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	if err := h.guests.Claim(c.Request().Context(), owner, req.GuestToken); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidGuest):
+			// Not 401: the session is fine, the guest's token is not
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, service.ErrForbidden):
+			return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
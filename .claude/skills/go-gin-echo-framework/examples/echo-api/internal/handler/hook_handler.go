@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/hooks"
+	"github.com/your-username/echo-api/internal/worker"
+)
+
+// hookJob is the name of the background job that delivers events to the
+// registered webhooks.
+const hookJob = "hooks"
+
+// HookHandler lets callers register webhooks that the domain events of
+// their tenant are delivered to, by the hooks job of jobs, and debug their
+// deliveries; with a nil dispatcher (jobs.hooks empty) it answers 501.
+// Each account sees only the hooks it registered. Mount it behind
+// auth.Require.
+type HookHandler struct {
+	hooks *hooks.Dispatcher
+	jobs  *worker.Worker
+	wait  time.Duration // for room in a full job queue
+}
+
+func NewHookHandler(dispatcher *hooks.Dispatcher, jobs *worker.Worker, wait time.Duration) *HookHandler {
+	return &HookHandler{hooks: dispatcher, jobs: jobs, wait: wait}
+}
+
+// Register mounts GET /, POST /, DELETE /:id, GET /:id/deliveries and
+// POST /:id/deliveries/:delivery/redeliver on g, the group of /hooks.
+func (h *HookHandler) Register(g *echo.Group) {
+	g.GET("/", h.List)
+	g.POST("/", h.Create)
+	g.DELETE("/:id", h.Delete)
+	g.GET("/:id/deliveries", h.Deliveries)
+	g.POST("/:id/deliveries/:delivery/redeliver", h.Redeliver)
+}
+
+var (
+	errHooksDisabled = errors.New("hooks are not enabled")
+	errNoCaller      = errors.New("no caller")
+)
+
+// owner returns the account whose hooks the request is about, failing with
+// errHooksDisabled if hooks are disabled.
+func (h *HookHandler) owner(c echo.Context) (string, error) {
+	if h.hooks == nil {
+		return "", errHooksDisabled
+	}
+	caller := auth.CallerFromContext(c.Request().Context())
+	if caller == nil {
+		return "", errNoCaller
+	}
+	return caller.Subject, nil
+}
+
+// @Summary List webhooks
+// @Description Lists the webhooks the caller registered, oldest first. Secrets are never returned after registration.
+// @Tags Hook
+// @Produce json
+// @Success 200 {array} hooks.Hook
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks [get]
+func (h *HookHandler) List(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	owner, err := h.owner(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, h.hooks.List(c.Request().Context(), owner))
+}
+
+// @Summary Register a webhook
+// @Description Registers a URL that the events of the listed types (e.g. product.created, or * for all) in the caller's tenant are POSTed to once their write commits, as a JSON message {id, name, aggregate_id, data}; the URL must be of a public host unless hooks.allow_private is set. Each delivery carries X-Hook-ID, X-Hook-Delivery (the message's id, the same on every attempt) and X-Hook-Event, and is signed in X-Hook-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of the time, a dot and the body under the secret>. The returned `secret`, generated unless given, is shown only once. A delivery answered with anything but a 2xx is retried after hooks.backoff, doubling up to hooks.max_backoff, until it failed hooks.attempts times and is dead.
+// @Tags Hook
+// @Accept json
+// @Produce json
+// @Param hook body hooks.Registration true "URL, event types and optional secret"
+// @Success 201 {object} hooks.Hook
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks [post]
+func (h *HookHandler) Create(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	owner, err := h.owner(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	var req hooks.Registration
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	hook, err := h.hooks.Register(c.Request().Context(), owner, req)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	c.Response().Header().Set(echo.HeaderLocation, "/hooks/"+hook.ID+"/deliveries")
+	return c.JSON(http.StatusCreated, hook)
+}
+
+// @Summary Delete a webhook
+// @Description Deletes a webhook the caller registered with its delivery log; its pending deliveries are not made.
+// @Tags Hook
+// @Param id path string true "Hook ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks/{id} [delete]
+func (h *HookHandler) Delete(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	owner, err := h.owner(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if err := h.hooks.Delete(c.Request().Context(), owner, c.Param("id")); err != nil {
+		return h.fail(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary List the deliveries of a webhook
+// @Description Lists the latest deliveries of a webhook the caller registered, newest first, to debug a failing one: each has its status (pending, delivered or dead), attempts, the HTTP status and error of its last attempt and, while pending, when it is tried next. At most hooks.log_size deliveries are kept; the oldest finished ones make room for new ones.
+// @Tags Hook
+// @Produce json
+// @Param id path string true "Hook ID"
+// @Success 200 {array} hooks.Delivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks/{id}/deliveries [get]
+func (h *HookHandler) Deliveries(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	owner, err := h.owner(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	deliveries, err := h.hooks.Deliveries(c.Request().Context(), owner, c.Param("id"))
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Redeliver an event to a webhook
+// @Description Makes a delivery of a webhook the caller registered pending again, with hooks.attempts attempts of its own, typically a dead one once the receiver is fixed. It is delivered in the background.
+// @Tags Hook
+// @Produce json
+// @Param id path string true "Hook ID"
+// @Param delivery path string true "Delivery ID"
+// @Success 202 {object} hooks.Delivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks/{id}/deliveries/{delivery}/redeliver [post]
+func (h *HookHandler) Redeliver(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	owner, err := h.owner(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	d, err := h.hooks.Redeliver(c.Request().Context(), owner, c.Param("id"), c.Param("delivery"))
+	if err != nil {
+		return h.fail(c, err)
+	}
+	// A full queue only delays the delivery to the job's next scheduled run
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.wait)
+	defer cancel()
+	h.jobs.Enqueue(ctx, hookJob)
+	return c.JSON(http.StatusAccepted, d)
+}
+
+func (h *HookHandler) fail(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errHooksDisabled):
+		return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+	case errors.Is(err, errNoCaller):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	case errors.Is(err, hooks.ErrInvalid):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, hooks.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Hook not found"})
+	case errors.Is(err, hooks.ErrNoDelivery):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Delivery not found"})
+	default:
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+}
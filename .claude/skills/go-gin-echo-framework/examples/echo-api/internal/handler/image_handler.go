@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/blob"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/service"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// ImageHandler stores the images of products in a blob.Store, under
+// products/<tenant>/<id>/<name>. Uploads are multipart forms, checked
+// against the size and sniffed media types of blob.Options; mount it in a
+// route group that accepts multipart/form-data bodies that large. Images are
+// kept when their product is deleted, so that undoing the delete restores
+// them.
+type ImageHandler struct {
+	productService service.ProductService
+	store          blob.Store
+	opts           blob.Options
+	clock          clock.Clock
+}
+
+func NewImageHandler(productService service.ProductService, store blob.Store, opts blob.Options, clk clock.Clock) *ImageHandler {
+	return &ImageHandler{
+		productService: productService,
+		store:          store,
+		opts:           opts,
+		clock:          clk,
+	}
+}
+
+// Register mounts POST /:id/images, GET /:id/images/:name and
+// GET /:id/images/:name/url on g, the group of /products.
+func (h *ImageHandler) Register(g *echo.Group) {
+	g.POST("/:id/images", h.Upload)
+	g.GET("/:id/images/:name", h.Download)
+	g.GET("/:id/images/:name/url", h.URL)
+}
+
+// Image is a stored image, with a signed URL that downloads it without
+// credentials until ExpiresAt.
+type Image struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// imageName is the names images may have: their file name, without paths.
+var imageName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// @Summary Upload an image of a product
+// @Description Stores the file of the multipart form as an image of the product, under its file name (letters, digits, ., _ and -), replacing any image of that name. The media type is sniffed from the content and must be one of blob.types; the file is at most blob.max_size bytes. The response carries a URL that downloads the image until expires_at (blob.url_ttl).
+// @Tags Product
+// @Accept mpfd
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param file formData file true "The image"
+// @Success 201 {object} handler.Image
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /products/{id}/images [post]
+func (h *ImageHandler) Upload(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	if _, err := h.productService.GetByID(ctx, id); err != nil {
+		return h.fail(c, err)
+	}
+	file, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+	case err != nil:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "file is required: a multipart/form-data body with the image in its file field"})
+	}
+	if !imageName.MatchString(file.Filename) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "file names are letters, digits, ., _ and -, starting with a letter or digit"})
+	}
+	if file.Size > h.opts.MaxSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("images are at most %d bytes", h.opts.MaxSize)})
+	}
+	f, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !slices.Contains(h.opts.Types, contentType) {
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": fmt.Sprintf("images are %s, not %s", strings.Join(h.opts.Types, ", "), contentType)})
+	}
+
+	key := imageKey(ctx, id, file.Filename)
+	if err := h.store.Put(ctx, key, io.MultiReader(bytes.NewReader(head[:n]), f), file.Size, contentType); err != nil {
+		return h.fail(c, err)
+	}
+	img, err := h.image(key, file.Filename, blob.Info{ContentType: contentType, Size: file.Size})
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusCreated, img)
+}
+
+// @Summary Download an image of a product
+// @Description Streams the image from the blob store.
+// @Tags Product
+// @Produce image/png,image/jpeg,image/gif,image/webp
+// @Param id path string true "Product ID"
+// @Param name path string true "Image name"
+// @Success 200 "The image"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /products/{id}/images/{name} [get]
+func (h *ImageHandler) Download(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	key, err := h.key(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.serve(c, key)
+}
+
+// @Summary Get a signed URL of an image
+// @Description Signs a URL that downloads the image without credentials until expires_at (blob.url_ttl): a presigned URL of the S3 backend, or one under /blobs of the local one.
+// @Tags Product
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param name path string true "Image name"
+// @Success 200 {object} handler.Image
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /products/{id}/images/{name}/url [get]
+func (h *ImageHandler) URL(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	key, err := h.key(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	r, info, err := h.store.Get(c.Request().Context(), key)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	r.Close()
+	img, err := h.image(key, c.Param("name"), info)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, img)
+}
+
+// @Summary Download a blob by signed URL
+// @Description Streams a blob of the local backend, an image or the file of an export, to the holder of a URL signed for it, e.g. by GET /products/{id}/images/{name}/url or GET /exports/{id}, until it expires. The S3 backend signs URLs of its own, so with it every blob is 404.
+// @Tags Blob
+// @Produce image/png,image/jpeg,image/gif,image/webp,text/csv,application/json
+// @Param key path string true "Blob key"
+// @Param expires query int true "Expiry of the URL, in Unix seconds"
+// @Param signature query string true "Signature of the URL"
+// @Success 200 "The blob"
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /blobs/{key} [get]
+func (h *ImageHandler) Blob(c echo.Context) error {
+	if err := checkQuery(c, "expires", "signature"); err != nil {
+		return err
+	}
+	local, ok := h.store.(*blob.Local)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Blob not found"})
+	}
+	// Mounted at /blobs/*key, whose wildcard echo names *
+	key := c.Param("*")
+	if err := local.Verify(key, c.QueryParam("expires"), c.QueryParam("signature"), h.clock.Now()); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+	return h.serve(c, key)
+}
+
+// key is the blob key of the image named by the request, failing with
+// service.ErrNotFound when its product does not exist.
+func (h *ImageHandler) key(c echo.Context) (string, error) {
+	ctx := c.Request().Context()
+	if _, err := h.productService.GetByID(ctx, c.Param("id")); err != nil {
+		return "", err
+	}
+	if !imageName.MatchString(c.Param("name")) {
+		return "", blob.ErrNotFound
+	}
+	return imageKey(ctx, c.Param("id"), c.Param("name")), nil
+}
+
+// imageKey is the key of an image of product id in the tenant of ctx, if
+// any.
+func imageKey(ctx context.Context, id, name string) string {
+	return path.Join("products", tenant.From(ctx), id, name)
+}
+
+// image describes the image under key with a freshly signed URL.
+func (h *ImageHandler) image(key, name string, info blob.Info) (Image, error) {
+	expires := h.clock.Now().Add(h.opts.URLTTL).UTC().Truncate(time.Second)
+	url, err := h.store.SignURL(key, expires)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Name: name, ContentType: info.ContentType, Size: info.Size, URL: url, ExpiresAt: expires}, nil
+}
+
+// serve streams the blob under key.
+func (h *ImageHandler) serve(c echo.Context, key string) error {
+	r, info, err := h.store.Get(c.Request().Context(), key)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	defer r.Close()
+	if info.Size >= 0 {
+		c.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(info.Size, 10))
+	}
+	return c.Stream(http.StatusOK, info.ContentType, r)
+}
+
+func (h *ImageHandler) fail(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Product not found"})
+	case errors.Is(err, blob.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
+	default:
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+}
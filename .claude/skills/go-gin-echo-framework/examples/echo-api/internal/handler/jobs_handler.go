@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/worker"
+)
+
+// JobsHandler lets admins run a background job out of its schedule, e.g.
+// to purge the trash before a demo. Mount it behind auth.RequireAdmin.
+type JobsHandler struct {
+	jobs *worker.Worker
+	wait time.Duration // for room in a full queue before answering 503
+}
+
+func NewJobsHandler(jobs *worker.Worker, wait time.Duration) *JobsHandler {
+	return &JobsHandler{jobs: jobs, wait: wait}
+}
+
+// Register mounts POST /:name/run on g.
+func (h *JobsHandler) Register(g *echo.Group) {
+	g.POST("/:name/run", h.Run)
+}
+
+// JobRun is the response of a run request.
+type JobRun struct {
+	Job    string `json:"job"`
+	Status string `json:"status"` // always "queued"
+}
+
+// @Summary Run a job now
+// @Description Queues a run of the background job, started within a second once the job is not running. When the queue (`jobs.queue`) stays full for `jobs.enqueue_wait` the run is refused with 503; Retry-After gives the seconds to wait.
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Job name, e.g. trash purge"
+// @Success 202 {object} handler.JobRun
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/jobs/{name}/run [post]
+func (h *JobsHandler) Run(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	name := c.Param("name")
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.wait)
+	defer cancel()
+	var full *worker.QueueFullError
+	switch err := h.jobs.Enqueue(ctx, name); {
+	case errors.Is(err, worker.ErrUnknownJob):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.As(err, &full):
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(full.RetryAfter.Seconds()))))
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, JobRun{Job: name, Status: "queued"})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// OIDCHandler serves browser logins with external identity providers (see
+// auth.OIDCService).
+type OIDCHandler struct {
+	oidc auth.OIDCService
+}
+
+func NewOIDCHandler(oidc auth.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc}
+}
+
+// Query parameters providers add to the callback besides code and state.
+var callbackParams = []string{"code", "state", "error", "error_description", "error_uri", "scope", "authuser", "hd", "prompt", "iss"}
+
+// Register mounts GET /login and /callback on g.
+func (h *OIDCHandler) Register(g *echo.Group) {
+	g.GET("/login", h.Login)
+	g.GET("/callback", h.Callback)
+}
+
+// @Summary Log in with an external provider
+// @Description Redirects the browser to the provider's login page using the authorization code flow with PKCE. The provider redirects back to /auth/oidc/callback.
+// @Tags Auth
+// @Param provider query string true "Configured provider name, e.g. google or github"
+// @Success 302 "Redirect to the provider"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security none
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c echo.Context) error {
+	if err := checkQuery(c, "provider"); err != nil {
+		return err
+	}
+	provider := c.QueryParam("provider")
+	if provider == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "provider is required"})
+	}
+	login, err := h.oidc.Begin(c.Request().Context(), provider)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	c.SetCookie(login.Cookie)
+	return c.Redirect(http.StatusFound, login.URL)
+}
+
+// @Summary Complete an external login
+// @Description The provider's redirect target. Verifies the login, links the external account to a local one on first use (by verified email, or a new account) and returns a token pair as /auth/login does.
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c echo.Context) error {
+	if err := checkQuery(c, callbackParams...); err != nil {
+		return err
+	}
+	if reason := c.QueryParam("error"); reason != "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "login failed at provider: " + reason})
+	}
+	var value string
+	if cookie, err := c.Cookie(auth.OIDCCookie); err == nil {
+		value = cookie.Value
+	}
+	token, err := h.oidc.Complete(c.Request().Context(), value, c.QueryParam("state"), c.QueryParam("code"))
+	if err != nil {
+		return h.fail(c, err)
+	}
+	c.SetCookie(&http.Cookie{Name: auth.OIDCCookie, Path: c.Request().URL.Path, MaxAge: -1, HttpOnly: true})
+	return c.JSON(http.StatusOK, token)
+}
+
+func (h *OIDCHandler) fail(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrUnknownProvider):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrInvalidLogin):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrAccountDisabled):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrProviderUnavailable):
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalid):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/webauthn"
+)
+
+// PasskeyHandler serves the WebAuthn ceremonies of passkeys: adding them
+// to a logged-in account, and logging in with them instead of a password.
+type PasskeyHandler struct {
+	passkeys auth.PasskeyService
+}
+
+func NewPasskeyHandler(passkeys auth.PasskeyService) *PasskeyHandler {
+	return &PasskeyHandler{passkeys: passkeys}
+}
+
+// FinishPasskeyRegistrationRequest is the browser's answer to the options
+// of /auth/passkeys/register/begin.
+type FinishPasskeyRegistrationRequest struct {
+	Session    string                 `json:"session" validate:"required"` // as returned with the options
+	Name       string                 `json:"name" validate:"max=100"`     // e.g. "laptop"; default "passkey"
+	Credential *webauthn.Registration `json:"credential" validate:"required"`
+}
+
+// BeginPasskeyLoginRequest names the account logging in, or nobody for the
+// authenticator to offer its passkeys of the service.
+type BeginPasskeyLoginRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+// FinishPasskeyLoginRequest is the browser's answer to the options of
+// /auth/passkeys/login/begin.
+type FinishPasskeyLoginRequest struct {
+	Session    string              `json:"session" validate:"required"` // as returned with the options
+	Credential *webauthn.Assertion `json:"credential" validate:"required"`
+}
+
+// Register mounts GET /, DELETE /:id, and POST /register/begin and
+// /register/finish on g, for the calling account's passkeys. Mount it
+// behind auth.Identify and auth.Require.
+func (h *PasskeyHandler) Register(g *echo.Group) {
+	g.GET("/", h.List)
+	g.DELETE("/:id", h.Delete)
+	g.POST("/register/begin", h.BeginRegistration)
+	g.POST("/register/finish", h.FinishRegistration)
+}
+
+// RegisterLogin mounts POST /begin and /finish on g, which log in.
+func (h *PasskeyHandler) RegisterLogin(g *echo.Group) {
+	g.POST("/begin", h.BeginLogin)
+	g.POST("/finish", h.FinishLogin)
+}
+
+// @Summary List passkeys
+// @Description Lists the passkeys of the calling account, oldest first.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} auth.PasskeyInfo
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys [get]
+func (h *PasskeyHandler) List(c echo.Context) error {
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "passkeys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	list, err := h.passkeys.List(c.Request().Context(), owner)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// @Summary Start adding a passkey
+// @Description Returns the options to pass to `navigator.credentials.create({publicKey})` (binary fields are base64url, as `PublicKeyCredential.parseCreationOptionsFromJSON` takes them) and the session to send back with its result to /auth/passkeys/register/finish within `auth.passkeys.timeout`. The account's passkeys are excluded, so an authenticator holds one of them.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.PasskeyRegistration
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys/register/begin [post]
+func (h *PasskeyHandler) BeginRegistration(c echo.Context) error {
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "passkeys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	begun, err := h.passkeys.BeginRegistration(c.Request().Context(), owner)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, begun)
+}
+
+// @Summary Add a passkey
+// @Description Verifies the credential the browser created (`PublicKeyCredential.toJSON()`) for the session of /auth/passkeys/register/begin and adds it to the calling account. Authenticators must verify the user; attestation is not checked.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param registration body handler.FinishPasskeyRegistrationRequest true "Session, passkey name and created credential"
+// @Success 201 {object} auth.PasskeyInfo
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys/register/finish [post]
+func (h *PasskeyHandler) FinishRegistration(c echo.Context) error {
+	var req FinishPasskeyRegistrationRequest
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "passkeys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	info, err := h.passkeys.FinishRegistration(c.Request().Context(), owner, req.Session, req.Name, req.Credential)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPasskey) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, info)
+}
+
+// @Summary Delete a passkey
+// @Description Removes one of the calling account's passkeys; it no longer logs in. Sessions it started are kept.
+// @Tags Auth
+// @Param id path string true "Passkey ID, the base64url credential ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys/{id} [delete]
+func (h *PasskeyHandler) Delete(c echo.Context) error {
+	owner, ok := sessionOwner(c)
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "passkeys can only be managed with the bearer access token of a login"})
+	}
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := h.passkeys.Delete(c.Request().Context(), owner, c.Param("id")); err != nil {
+		if errors.Is(err, auth.ErrPasskeyNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Start a passkey login
+// @Description Returns the options to pass to `navigator.credentials.get({publicKey})` and the session to send back with its result to /auth/passkeys/login/finish within `auth.passkeys.timeout`. With an email the options allow that account's passkeys; without one the authenticator offers its discoverable passkeys, so the user need not type anything. Accounts without a passkey log in with their password, and their authenticator app if they have one.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param login body handler.BeginPasskeyLoginRequest true "Email of the account, or none"
+// @Success 200 {object} auth.PasskeyLogin
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/passkeys/login/begin [post]
+func (h *PasskeyHandler) BeginLogin(c echo.Context) error {
+	var req BeginPasskeyLoginRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	begun, err := h.passkeys.BeginLogin(c.Request().Context(), req.Email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, begun)
+}
+
+// @Summary Log in with a passkey
+// @Description Verifies the assertion the browser returned (`PublicKeyCredential.toJSON()`) for the session of /auth/passkeys/login/begin and exchanges it for a JWT access token. A passkey verifies the user itself, so no one-time code is asked for. Each session is good for one attempt.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param login body handler.FinishPasskeyLoginRequest true "Session and assertion"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/passkeys/login/finish [post]
+func (h *PasskeyHandler) FinishLogin(c echo.Context) error {
+	var req FinishPasskeyLoginRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	token, err := h.passkeys.FinishLogin(c.Request().Context(), req.Session, req.Credential)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidPasskey):
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		case errors.Is(err, auth.ErrAccountDisabled):
+			return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+	}
+	return c.JSON(http.StatusOK, token)
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http/pprof"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterPprof mounts the runtime profiles of net/http/pprof on g, which
+// must be /debug/pprof: the index on /, and /profile, /trace, /heap and so
+// on below it. Profiles expose internals and CPU profiles cost CPU while
+// they run, so mount it behind auth.RequireAdmin.
+func RegisterPprof(g *echo.Group) {
+	g.GET("/*", servePprof)
+}
+
+func servePprof(c echo.Context) error {
+	w, r := c.Response(), c.Request()
+	switch c.Param("*") {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Index(w, r) // the index, or a named profile such as heap
+	}
+	return nil
+}
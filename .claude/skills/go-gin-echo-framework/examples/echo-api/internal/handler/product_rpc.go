@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/rpc"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// RegisterProductRPC exposes the product service as "products.*" JSON-RPC methods.
+func RegisterProductRPC(s *rpc.Server, productService service.ProductService, v echo.Validator) {
+	RegisterCrudRPC[model.Product](s, "products", productService, v)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/recorder"
+)
+
+// RecorderHandler lets admins capture the traffic of one route or caller
+// while debugging a client issue. Mount it behind auth.RequireAdmin.
+type RecorderHandler struct {
+	rec *recorder.Recorder
+}
+
+func NewRecorderHandler(rec *recorder.Recorder) *RecorderHandler {
+	return &RecorderHandler{rec: rec}
+}
+
+// Register mounts the recorder status and filter on g itself, the recorded
+// exchanges on /exchanges and their HAR export on /har.
+func (h *RecorderHandler) Register(g *echo.Group) {
+	g.GET("", h.Status)
+	g.PUT("", h.Start)
+	g.DELETE("", h.Stop)
+	g.GET("/exchanges", h.List)
+	g.DELETE("/exchanges", h.Clear)
+	g.GET("/har", h.ExportHAR)
+}
+
+// @Summary Get recorder status
+// @Description Reports whether requests are being recorded, for which filter, and how many exchanges are held.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} recorder.Status
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/recorder [get]
+func (h *RecorderHandler) Status(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, h.rec.Status())
+}
+
+// @Summary Start recording
+// @Description Records full request/response pairs of the requests matching the filter (a route pattern such as /users/:id, a caller's account ID, or both) on this instance, replacing any previous filter. Sensitive headers and fields are redacted.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param filter body recorder.Filter true "Requests to record"
+// @Success 200 {object} recorder.Status
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/recorder [put]
+func (h *RecorderHandler) Start(c echo.Context) error {
+	var filter recorder.Filter
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&filter); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := h.rec.Start(filter); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, h.rec.Status())
+}
+
+// @Summary Stop recording
+// @Description Stops recording; exchanges recorded so far stay available.
+// @Tags Admin
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/recorder [delete]
+func (h *RecorderHandler) Stop(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	h.rec.Stop()
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary List recorded exchanges
+// @Description Lists the recorded request/response pairs, oldest first.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} recorder.Exchange
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/recorder/exchanges [get]
+func (h *RecorderHandler) List(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, h.rec.Exchanges())
+}
+
+// @Summary Export recorded exchanges as HAR
+// @Description Downloads the recorded request/response pairs, oldest first, as an HTTP Archive (HAR 1.2) file for browser developer tools and API clients. Redacted values stay redacted.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} recorder.HAR
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/recorder/har [get]
+func (h *RecorderHandler) ExportHAR(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="echo-api-recording.har"`)
+	return c.JSON(http.StatusOK, recorder.ToHAR(h.rec.Exchanges(), "echo-api"))
+}
+
+// @Summary Clear recorded exchanges
+// @Description Drops every recorded exchange without changing whether recording is on.
+// @Tags Admin
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/recorder/exchanges [delete]
+func (h *RecorderHandler) Clear(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	h.rec.Clear()
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/scim"
+	"github.com/your-username/echo-api/internal/secret"
+)
+
+// scimParams are the query parameters of SCIM reads; attributes and
+// excludedAttributes are accepted and ignored, every attribute is returned.
+var scimParams = []string{"attributes", "excludedAttributes"}
+
+// SCIMHandler serves the SCIM 2.0 Users resource to an identity provider,
+// which authenticates with the bearer token of scim.token rather than as a
+// caller, so mount it in a route group without authentication; with an
+// empty token it answers 501. Responses, errors included, are SCIM JSON.
+type SCIMHandler struct {
+	scim  *scim.Service
+	token secret.Secret
+}
+
+func NewSCIMHandler(service *scim.Service, token secret.Secret) *SCIMHandler {
+	return &SCIMHandler{scim: service, token: token}
+}
+
+// Register mounts GET /ServiceProviderConfig, GET /Users, POST /Users,
+// GET /Users/:id and PATCH /Users/:id on g, the group of /scim/v2.
+func (h *SCIMHandler) Register(g *echo.Group) {
+	g.GET("/ServiceProviderConfig", h.Config)
+	g.GET("/Users", h.List)
+	g.POST("/Users", h.Create)
+	g.GET("/Users/:id", h.Get)
+	g.PATCH("/Users/:id", h.Patch)
+}
+
+// authorize returns the error to answer with, a 501 if SCIM is disabled and
+// a 401 unless the request carries the token, or nil.
+func (h *SCIMHandler) authorize(c echo.Context) *scim.Error {
+	if h.token.Reveal() == "" {
+		return scim.NewError(http.StatusNotImplemented, "", "SCIM is not enabled")
+	}
+	token, _ := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	// Hashed to compare in constant time whatever the length
+	got, want := sha256.Sum256([]byte(token)), sha256.Sum256([]byte(h.token.Reveal()))
+	if subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+		return scim.NewError(http.StatusUnauthorized, "", "Unauthorized")
+	}
+	return nil
+}
+
+// @Summary Get the SCIM service provider configuration
+// @Description Tells identity providers what the SCIM endpoint supports: PATCH and equality filters, no bulk operations, sorting, ETags or password changes.
+// @Tags SCIM
+// @Produce application/scim+json
+// @Success 200 {object} scim.ServiceProviderConfig
+// @Failure 401 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) Config(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if e := h.authorize(c); e != nil {
+		return h.fail(c, e)
+	}
+	return h.respond(c, http.StatusOK, h.scim.Config())
+}
+
+// @Summary List provisioned users
+// @Description Lists the users provisioned in scim.tenant, oldest first, that match filter: conditions of id, externalId, userName, displayName, emails.value or active, the eq operator and a value, joined by and, e.g. userName eq "ada@example.com". Pages start at the 1-based startIndex and hold count users, at most scim.max_results.
+// @Tags SCIM
+// @Produce application/scim+json
+// @Param filter query string false "Filter, e.g. userName eq \"ada@example.com\""
+// @Param startIndex query int false "1-based index of the first user"
+// @Param count query int false "Users per page"
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) List(c echo.Context) error {
+	if err := checkQuery(c, append([]string{"filter", "startIndex", "count"}, scimParams...)...); err != nil {
+		return err
+	}
+	if e := h.authorize(c); e != nil {
+		return h.fail(c, e)
+	}
+	startIndex, err1 := strconv.Atoi(cmp.Or(c.QueryParam("startIndex"), "1"))
+	count, err2 := strconv.Atoi(cmp.Or(c.QueryParam("count"), "-1"))
+	if err := errors.Join(err1, err2); err != nil {
+		return h.fail(c, scim.NewError(http.StatusBadRequest, "invalidValue", "startIndex and count must be integers"))
+	}
+	list, err := h.scim.List(c.Request().Context(), c.QueryParam("filter"), startIndex, count)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respond(c, http.StatusOK, list)
+}
+
+// @Summary Provision a user
+// @Description Creates an account in scim.tenant for a user of the identity provider, with a login by its primary email (or the userName if it is one) and no password: it logs in with the provider's single sign-on. The account that registered with that email, if any, is adopted instead. Attributes other than userName, externalId, name, displayName, emails and active are ignored. A userName or email already provisioned is a 409.
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param user body scim.User true "The user"
+// @Success 201 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) Create(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if e := h.authorize(c); e != nil {
+		return h.fail(c, e)
+	}
+	var req scim.User
+	if err := h.decode(c, &req); err != nil {
+		return h.fail(c, err)
+	}
+	user, err := h.scim.Create(c.Request().Context(), req)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	c.Response().Header().Set(echo.HeaderLocation, user.Meta.Location)
+	return h.respond(c, http.StatusCreated, user)
+}
+
+// @Summary Get a provisioned user
+// @Tags SCIM
+// @Produce application/scim+json
+// @Param id path string true "User ID, that of its account"
+// @Success 200 {object} scim.User
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) Get(c echo.Context) error {
+	if err := checkQuery(c, scimParams...); err != nil {
+		return err
+	}
+	if e := h.authorize(c); e != nil {
+		return h.fail(c, e)
+	}
+	user, err := h.scim.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respond(c, http.StatusOK, user)
+}
+
+// @Summary Change or deactivate a provisioned user
+// @Description Applies add, replace and remove operations to userName, externalId, name, displayName, emails and active, with a path or as an object of attributes without one. Replacing active with false deactivates the user: its logins and session refreshes are refused with 403 and its API keys deleted, and the access tokens it holds expire within auth.token_ttl; true reactivates it. Changing the email moves its login.
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param id path string true "User ID"
+// @Param patch body scim.PatchRequest true "Operations"
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) Patch(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if e := h.authorize(c); e != nil {
+		return h.fail(c, e)
+	}
+	var req scim.PatchRequest
+	if err := h.decode(c, &req); err != nil {
+		return h.fail(c, err)
+	}
+	user, err := h.scim.Patch(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respond(c, http.StatusOK, user)
+}
+
+// decode reads the body into dst, returning a 400 if it is malformed.
+// Unlike c.Bind it accepts unknown attributes whatever the compatibility
+// mode: providers send many that are not kept, and extension schemas of
+// their own.
+func (h *SCIMHandler) decode(c echo.Context, dst any) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(dst); err != nil {
+		return scim.NewError(http.StatusBadRequest, "invalidSyntax", "%v", err)
+	}
+	return nil
+}
+
+func (h *SCIMHandler) respond(c echo.Context, status int, body any) error {
+	c.Response().Header().Set(echo.HeaderContentType, scim.ContentType)
+	return c.JSON(status, body)
+}
+
+func (h *SCIMHandler) fail(c echo.Context, err error) error {
+	var e *scim.Error
+	if !errors.As(err, &e) {
+		e = scim.NewError(statusOf(err), "", "%v", err)
+	}
+	return h.respond(c, e.HTTPStatus(), e)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/search"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+type SearchHandler struct {
+	productService service.ProductService
+	products       search.Searcher
+}
+
+func NewSearchHandler(productService service.ProductService, products search.Searcher) *SearchHandler {
+	return &SearchHandler{
+		productService: productService,
+		products:       products,
+	}
+}
+
+// @Summary Search products
+// @Description Finds the products whose name contains any of the words of q, best matches first, with the matched words highlighted in <em> (as HTML-escaped text).
+// @Tags Product
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param limit query int false "Matches per page, at most 100 (default 20)"
+// @Param offset query int false "Matches to skip"
+// @Success 200 {object} search.Page[model.Product]
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /products/search [get]
+func (h *SearchHandler) SearchProducts(c echo.Context) error {
+	if err := checkQuery(c, "q", "limit", "offset"); err != nil {
+		return err
+	}
+	q, err := searchQuery(c)
+	if err != nil {
+		return err
+	}
+	res, err := h.products.Search(c.Request().Context(), q)
+	if err != nil {
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+	ids := make([]string, len(res.Hits))
+	for i, hit := range res.Hits {
+		ids[i] = hit.ID
+	}
+	items, err := h.productService.GetByIDs(c.Request().Context(), ids)
+	if err != nil {
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, matches(q, res, items))
+}
+
+// searchQuery reads q, limit and offset, failing with a 400 when one is
+// invalid.
+func searchQuery(c echo.Context) (search.Query, error) {
+	q := search.Query{Text: c.QueryParam("q"), Limit: 20}
+	if q.Text == "" {
+		return q, echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+	if s := c.QueryParam("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > search.MaxLimit {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "limit must be a number between 1 and 100")
+		}
+		q.Limit = n
+	}
+	if s := c.QueryParam("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "offset must be a number of at least 0")
+		}
+		q.Offset = n
+	}
+	return q, nil
+}
+
+// matches pairs the hits of res with their items, in the order of the hits.
+// Hits of items deleted since they were indexed are left out.
+func matches[T model.Entity](q search.Query, res search.Results, items []T) search.Page[T] {
+	byID := make(map[string]T, len(items))
+	for _, item := range items {
+		byID[item.GetID()] = item
+	}
+	page := search.Page[T]{Total: res.Total, Offset: q.Offset, Limit: q.Limit, Hits: []search.Match[T]{}}
+	for _, hit := range res.Hits {
+		if item, ok := byID[hit.ID]; ok {
+			page.Hits = append(page.Hits, search.Match[T]{Item: item, Score: hit.Score, Highlights: hit.Highlights})
+		}
+	}
+	return page
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+type SyncHandler struct {
+	productService service.ProductService
+	feed           *changes.Feed
+}
+
+func NewSyncHandler(productService service.ProductService, feed *changes.Feed) *SyncHandler {
+	return &SyncHandler{
+		productService: productService,
+		feed:           feed,
+	}
+}
+
+// @Summary Delta-sync products
+// @Description Returns products created, updated and deleted since the client's sync token. Without a token, or with an expired one, the full dataset is returned with `reset` set.
+// @Tags Product
+// @Produce json
+// @Param token query string false "Sync token from the previous response"
+// @Success 200 {object} changes.Delta[model.Product]
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /products/sync [get]
+func (h *SyncHandler) SyncProducts(c echo.Context) error {
+	if err := checkQuery(c, "token"); err != nil {
+		return err
+	}
+	delta, err := changes.BuildDelta(c.Request().Context(), h.feed, c.QueryParam("token"), changes.Source[model.Product]{
+		Get:    h.productService.GetByID,
+		List:   h.productService.GetAll,
+		IDOf:   func(p *model.Product) string { return p.GetID() },
+		Absent: func(err error) bool { return errors.Is(err, service.ErrNotFound) },
+	})
+	if err != nil {
+		if errors.Is(err, changes.ErrInvalidToken) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid sync token"})
+		}
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, delta)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/testmode"
+)
+
+// TestModeHandler lets end-to-end tests move the fake clock and inspect the
+// outbound effects captured in test mode. It is mounted in test mode only
+// and, like the other test infrastructure, left out of the API spec.
+type TestModeHandler struct {
+	harness *testmode.Harness
+}
+
+func NewTestModeHandler(harness *testmode.Harness) *TestModeHandler {
+	return &TestModeHandler{harness: harness}
+}
+
+// Register mounts the clock on /clock and the effects on /effects.
+func (h *TestModeHandler) Register(g *echo.Group) {
+	g.GET("/clock", h.Now)
+	g.POST("/clock", h.Advance)
+	g.GET("/effects", h.Effects)
+	g.DELETE("/effects", h.ClearEffects)
+}
+
+// clockResponse is the time on the fake clock.
+type clockResponse struct {
+	Now time.Time `json:"now"`
+}
+
+// advanceRequest moves the fake clock forward.
+type advanceRequest struct {
+	By string `json:"by"` // a Go duration, e.g. "90s" or "24h"
+}
+
+// Now reports the time on the fake clock.
+func (h *TestModeHandler) Now(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, clockResponse{Now: h.harness.Clock.Now().UTC()})
+}
+
+// Advance moves the fake clock forward, firing the tickers that come due,
+// and reports the new time.
+func (h *TestModeHandler) Advance(c echo.Context) error {
+	var req advanceRequest
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	d, err := time.ParseDuration(req.By)
+	if err != nil || d < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "by must be a non-negative duration such as 90s"})
+	}
+	h.harness.Clock.Advance(d)
+	return c.JSON(http.StatusOK, clockResponse{Now: h.harness.Clock.Now().UTC()})
+}
+
+// Effects lists the captured outbound effects, oldest first, selected by
+// ?kind= (event or http), ?target= and ?subject=; all of them by default.
+func (h *TestModeHandler) Effects(c echo.Context) error {
+	if err := checkQuery(c, "kind", "target", "subject"); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, h.harness.Effects.Find(testmode.Query{
+		Kind:    c.QueryParam("kind"),
+		Target:  c.QueryParam("target"),
+		Subject: c.QueryParam("subject"),
+	}))
+}
+
+// ClearEffects forgets the captured effects.
+func (h *TestModeHandler) ClearEffects(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	h.harness.Effects.Reset()
+	return c.NoContent(http.StatusNoContent)
+}
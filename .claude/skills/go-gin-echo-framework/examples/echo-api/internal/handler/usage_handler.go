@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/service"
+)
+
+// UsageHandler reports how much of its quotas the caller uses. Mount it
+// behind auth.Require.
+type UsageHandler struct {
+	meters []service.Meter
+}
+
+func NewUsageHandler(meters ...service.Meter) *UsageHandler {
+	return &UsageHandler{meters: meters}
+}
+
+// Register mounts the usage on g itself.
+func (h *UsageHandler) Register(g *echo.Group) {
+	g.GET("", h.Get)
+}
+
+// @Summary Get quota usage
+// @Description Returns, by collection, how many items the caller's scope holds and the most it may: its tenant's under plans.tiers.<plan>.max_items, its account's API keys under auth.max_api_keys. A missing limit is unlimited.
+// @Tags Usage
+// @Produce json
+// @Success 200 {object} map[string]service.Usage
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /usage [get]
+func (h *UsageHandler) Get(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	usage, err := service.Measure(c.Request().Context(), h.meters)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/webhook"
+	"github.com/your-username/echo-api/internal/worker"
+)
+
+// webhookJob is the name of the background job that handles webhook
+// deliveries.
+const webhookJob = "webhooks"
+
+// WebhookHandler receives the webhooks of third-party services, handled by
+// the webhooks job of jobs; with a nil receiver (jobs.webhooks empty) it
+// answers 501. Deliveries authenticate with their provider's signature, so
+// mount it in a route group without authentication.
+type WebhookHandler struct {
+	webhooks *webhook.Receiver
+	jobs     *worker.Worker
+	wait     time.Duration // for room in a full job queue
+}
+
+func NewWebhookHandler(webhooks *webhook.Receiver, jobs *worker.Worker, wait time.Duration) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks, jobs: jobs, wait: wait}
+}
+
+// Register mounts POST /:provider on g, the group of /webhooks.
+func (h *WebhookHandler) Register(g *echo.Group) {
+	g.POST("/:provider", h.Receive)
+}
+
+// @Summary Receive a webhook
+// @Description Accepts a delivery of a webhook provider (github, with webhooks.github.secret set) and answers at once: 202 when it is queued for the webhooks job, 200 when it was accepted before, within webhooks.replay_window, or its event is not handled. The delivery must be signed by the provider, within webhooks.tolerance of now if the provider signs a timestamp, and its payload valid for its event. At most webhooks.max_queued deliveries wait; more get 503, for the provider to redeliver them later.
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param X-Hub-Signature-256 header string false "sha256= and the hex HMAC-SHA256 of the body (github)"
+// @Param X-GitHub-Delivery header string false "Delivery GUID (github)"
+// @Param X-GitHub-Event header string false "Event name (github)"
+// @Param payload body map[string]any true "The event"
+// @Success 200 {object} webhook.Receipt
+// @Success 202 {object} webhook.Receipt
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security none
+// @Router /webhooks/{provider} [post]
+func (h *WebhookHandler) Receive(c echo.Context) error {
+	if err := checkQuery(c); err != nil {
+		return err
+	}
+	if h.webhooks == nil {
+		return c.JSON(http.StatusNotImplemented, map[string]string{"error": "webhooks are not enabled"})
+	}
+	body, err := io.ReadAll(c.Request().Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+	case err != nil:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	receipt, err := h.webhooks.Receive(c.Param("provider"), c.Request().Header, body)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if receipt.Status != webhook.Queued {
+		return c.JSON(http.StatusOK, receipt)
+	}
+	// A full job queue only delays the delivery to the job's next scheduled run
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.wait)
+	defer cancel()
+	h.jobs.Enqueue(ctx, webhookJob)
+	return c.JSON(http.StatusAccepted, receipt)
+}
+
+func (h *WebhookHandler) fail(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, webhook.ErrUnknownProvider):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook provider not found"})
+	case errors.Is(err, webhook.ErrSignature):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, webhook.ErrInvalid):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, webhook.ErrBusy):
+		c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(statusOf(err), map[string]string{"error": err.Error()})
+	}
+}
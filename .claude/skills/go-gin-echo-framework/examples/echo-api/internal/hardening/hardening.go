@@ -0,0 +1,139 @@
+// Package hardening sets the security response headers of a route group and
+// rejects requests it should never process: bodies over a size limit and
+// POST, PUT or PATCH bodies of a media type the group does not accept. Each
+// route group has its own Options; the defaults come from the middleware
+// preset (see pipeline.Presets).
+package hardening
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Off disables a header that would otherwise be inherited.
+const Off = "off"
+
+// Options are the settings of one route group. Unset fields take their value
+// from the defaults passed to WithDefaults.
+type Options struct {
+	HSTS                  string   `yaml:"hsts"`                    // Strict-Transport-Security; only send it when the API is served over HTTPS
+	ContentSecurityPolicy string   `yaml:"content_security_policy"` // for the rare HTML response; JSON needs none of its allowances
+	FrameOptions          string   `yaml:"frame_options"`           // X-Frame-Options: DENY or SAMEORIGIN
+	ReferrerPolicy        string   `yaml:"referrer_policy"`
+	MaxBodyBytes          int64    `yaml:"max_body_bytes"` // larger request bodies get 413; -1 is unlimited
+	ContentTypes          []string `yaml:"content_types"`  // media types accepted on POST, PUT and PATCH, e.g. image/*; empty accepts any
+}
+
+// WithDefaults fills every unset field of o from d.
+func (o Options) WithDefaults(d Options) Options {
+	if o.HSTS == "" {
+		o.HSTS = d.HSTS
+	}
+	if o.ContentSecurityPolicy == "" {
+		o.ContentSecurityPolicy = d.ContentSecurityPolicy
+	}
+	if o.FrameOptions == "" {
+		o.FrameOptions = d.FrameOptions
+	}
+	if o.ReferrerPolicy == "" {
+		o.ReferrerPolicy = d.ReferrerPolicy
+	}
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = d.MaxBodyBytes
+	}
+	if o.ContentTypes == nil {
+		o.ContentTypes = d.ContentTypes
+	}
+	return o
+}
+
+// Validate reports every setting a Policy could not enforce.
+func (o Options) Validate() error {
+	var errs []error
+	if o.HSTS != "" && o.HSTS != Off && !strings.HasPrefix(o.HSTS, "max-age=") {
+		errs = append(errs, fmt.Errorf("hsts must start with max-age= or be %q (got %q)", Off, o.HSTS))
+	}
+	if f := strings.ToUpper(o.FrameOptions); f != "" && f != "DENY" && f != "SAMEORIGIN" && o.FrameOptions != Off {
+		errs = append(errs, fmt.Errorf("frame_options must be DENY, SAMEORIGIN or %q (got %q)", Off, o.FrameOptions))
+	}
+	if o.MaxBodyBytes < -1 {
+		errs = append(errs, errors.New("max_body_bytes must be positive, or -1 for unlimited"))
+	}
+	for _, t := range o.ContentTypes {
+		if typ, sub, ok := strings.Cut(t, "/"); !ok || typ == "" || typ == "*" || sub == "" || strings.ContainsAny(t, "; ") {
+			errs = append(errs, fmt.Errorf("content type %q must be a media type such as application/json or image/*", t))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Policy enforces one group's Options. It is safe for concurrent use.
+type Policy struct {
+	headers      [][2]string
+	maxBodyBytes int64 // 0 or -1: unlimited
+	contentTypes []string
+}
+
+// New returns the policy of o, which must be valid.
+func New(o Options) *Policy {
+	p := &Policy{maxBodyBytes: o.MaxBodyBytes}
+	add := func(name, value string) {
+		if value != "" && value != Off {
+			p.headers = append(p.headers, [2]string{name, value})
+		}
+	}
+	add("Strict-Transport-Security", o.HSTS)
+	add("X-Content-Type-Options", "nosniff") // never useful to turn off
+	add("X-Frame-Options", strings.ToUpper(o.FrameOptions))
+	add("Content-Security-Policy", o.ContentSecurityPolicy)
+	add("Referrer-Policy", o.ReferrerPolicy)
+	for _, t := range o.ContentTypes {
+		p.contentTypes = append(p.contentTypes, strings.ToLower(t))
+	}
+	return p
+}
+
+// Apply sets the headers on w, limits the body of r to the policy's size and
+// returns the status and message to reject r with, or 0 to let it through.
+// A handler that sets a header itself (e.g. the CSP of an HTML page) wins.
+func (p *Policy) Apply(w http.ResponseWriter, r *http.Request) (int, string) {
+	h := w.Header()
+	for _, kv := range p.headers {
+		h.Set(kv[0], kv[1])
+	}
+
+	if p.maxBodyBytes > 0 {
+		if r.ContentLength > p.maxBodyBytes {
+			return http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", p.maxBodyBytes)
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, p.maxBodyBytes) // chunked bodies declare no length
+		}
+	}
+
+	if len(p.contentTypes) > 0 && hasBody(r) && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch) {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !p.accepts(mediaType) {
+			return http.StatusUnsupportedMediaType, fmt.Sprintf("Content-Type must be %s", strings.Join(p.contentTypes, " or "))
+		}
+	}
+	return 0, ""
+}
+
+func (p *Policy) accepts(mediaType string) bool {
+	for _, t := range p.contentTypes {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBody reports whether r carries a body, so bodiless POSTs (e.g. a
+// logout) need no Content-Type.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
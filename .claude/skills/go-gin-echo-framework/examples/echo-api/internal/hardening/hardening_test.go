@@ -0,0 +1,87 @@
+package hardening
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplySetsHeaders(t *testing.T) {
+	p := New(Options{HSTS: "max-age=60", FrameOptions: "sameorigin", ContentSecurityPolicy: Off, ReferrerPolicy: "no-referrer"})
+	w := httptest.NewRecorder()
+	if status, _ := p.Apply(w, httptest.NewRequest(http.MethodGet, "/", nil)); status != 0 {
+		t.Fatalf("GET rejected with %d", status)
+	}
+	for name, want := range map[string]string{
+		"Strict-Transport-Security": "max-age=60",
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "SAMEORIGIN",
+		"Content-Security-Policy":   "",
+		"Referrer-Policy":           "no-referrer",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestApplyRejectsRequests(t *testing.T) {
+	p := New(Options{MaxBodyBytes: 16, ContentTypes: []string{"application/json", "image/*"}})
+	tests := []struct {
+		name, method, contentType, body string
+		want                            int
+	}{
+		{"json", http.MethodPost, "application/json; charset=utf-8", `{}`, 0},
+		{"wildcard", http.MethodPut, "image/png", "png", 0},
+		{"media type case", http.MethodPatch, "Application/JSON", `{}`, 0},
+		{"form", http.MethodPost, "application/x-www-form-urlencoded", "a=b", http.StatusUnsupportedMediaType},
+		{"missing", http.MethodPost, "", `{}`, http.StatusUnsupportedMediaType},
+		{"bodiless POST", http.MethodPost, "", "", 0},
+		{"DELETE is not checked", http.MethodDelete, "text/plain", "x", 0},
+		{"too large", http.MethodPost, "application/json", strings.Repeat("x", 17), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if status, msg := p.Apply(httptest.NewRecorder(), r); status != tt.want {
+				t.Fatalf("status = %d (%s), want %d", status, msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLimitsUndeclaredBodies(t *testing.T) {
+	p := New(Options{MaxBodyBytes: 4})
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long"))
+	r.ContentLength = -1 // chunked
+	if status, _ := p.Apply(httptest.NewRecorder(), r); status != 0 {
+		t.Fatalf("rejected with %d before reading", status)
+	}
+	var tooLarge *http.MaxBytesError
+	if _, err := io.ReadAll(r.Body); !errors.As(err, &tooLarge) {
+		t.Fatalf("reading the body: %v, want a MaxBytesError", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	got := Options{HSTS: Off, ContentTypes: []string{}}.WithDefaults(Options{HSTS: "max-age=1", MaxBodyBytes: 10, ContentTypes: []string{"application/json"}})
+	if got.HSTS != Off || got.MaxBodyBytes != 10 || got.ContentTypes == nil || len(got.ContentTypes) != 0 {
+		t.Errorf("WithDefaults = %+v", got)
+	}
+
+	if err := (Options{HSTS: "max-age=1", FrameOptions: "deny", MaxBodyBytes: -1, ContentTypes: []string{"image/*"}}).Validate(); err != nil {
+		t.Errorf("valid options: %v", err)
+	}
+	err := Options{HSTS: "1 year", FrameOptions: "ALLOW-FROM x", MaxBodyBytes: -2, ContentTypes: []string{"json", "*/*"}}.Validate()
+	for _, want := range []string{"hsts", "frame_options", "max_body_bytes", `"json"`, `"*/*"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %s", err, want)
+		}
+	}
+}
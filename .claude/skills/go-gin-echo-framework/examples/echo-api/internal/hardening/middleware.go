@@ -0,0 +1,18 @@
+package hardening
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware applies p to the requests of a route group, rejecting those it
+// refuses before authentication or rate limiting spend work on them.
+func Middleware(p *Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if status, msg := p.Apply(c.Response(), c.Request()); status != 0 {
+				return c.JSON(status, map[string]string{"error": msg})
+			}
+			return next(c)
+		}
+	}
+}
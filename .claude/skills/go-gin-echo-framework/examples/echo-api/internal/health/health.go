@@ -0,0 +1,216 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultTimeout = 2 * time.Second
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// CheckFunc reports a dependency as healthy by returning nil.
+type CheckFunc func(ctx context.Context) error
+
+// DetailFunc is a CheckFunc that also returns details shown with its
+// result, such as the depth of a queue it judged.
+type DetailFunc func(ctx context.Context) (details any, err error)
+
+// Kind selects which probe a check belongs to. Liveness checks should only
+// cover the process itself; anything external belongs in readiness, or a
+// flaky dependency would get the pod restarted instead of drained.
+type Kind int
+
+const (
+	Readiness Kind = iota
+	Liveness
+)
+
+type check struct {
+	name    string
+	kind    Kind
+	timeout time.Duration
+	fn      DetailFunc
+}
+
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	Details  any    `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Registry holds the registered checks and serves the /healthz and /readyz probes.
+type Registry struct {
+	mu       sync.RWMutex
+	checks   []check
+	draining atomic.Bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check. A zero timeout means DefaultTimeout.
+func (r *Registry) Register(name string, kind Kind, timeout time.Duration, fn CheckFunc) {
+	r.RegisterDetailed(name, kind, timeout, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+}
+
+// RegisterDetailed adds a check that reports details, e.g. of a background
+// subsystem. A zero timeout means DefaultTimeout.
+func (r *Registry) RegisterDetailed(name string, kind Kind, timeout time.Duration, fn DetailFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, kind: kind, timeout: timeout, fn: fn})
+}
+
+// SetDraining marks the instance as not ready, e.g. at the start of shutdown
+// so load balancers stop routing to it while in-flight requests finish.
+func (r *Registry) SetDraining() {
+	r.draining.Store(true)
+}
+
+// Run executes all checks of kind concurrently, each under its own timeout.
+func (r *Registry) Run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	var checks []check
+	for _, c := range r.checks {
+		if c.kind == kind {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := runCheck(ctx, c)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = res
+			if res.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	if kind == Readiness && r.draining.Load() {
+		report.Status = StatusDown
+		report.Checks["shutdown"] = CheckResult{Status: StatusDown, Error: "instance is shutting down", Duration: "0s"}
+	}
+	return report
+}
+
+func runCheck(parent context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		details any
+		err     error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", p)}
+			}
+		}()
+		details, err := c.fn(ctx)
+		done <- outcome{details, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-ctx.Done():
+		o.err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	res := CheckResult{Status: StatusUp, Duration: time.Since(start).Round(time.Microsecond).String(), Details: o.details}
+	if o.err != nil {
+		res.Status = StatusDown
+		res.Error = o.err.Error()
+	}
+	return res
+}
+
+// LivenessHandler serves /healthz.
+func (r *Registry) LivenessHandler() http.Handler {
+	return r.handler(Liveness)
+}
+
+// ReadinessHandler serves /readyz.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return r.handler(Readiness)
+}
+
+func (r *Registry) handler(kind Kind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), kind)
+		status := http.StatusOK
+		if report.Status == StatusDown {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// HTTPCheck reports a downstream HTTP dependency as healthy when GET url
+// answers with a status below 500.
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return errors.New("unexpected status " + resp.Status)
+		}
+		return nil
+	}
+}
+
+// Pinger is implemented by dependencies that can verify their connection,
+// such as Redis-backed stores and database pools.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
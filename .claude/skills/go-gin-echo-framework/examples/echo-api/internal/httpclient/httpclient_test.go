@@ -0,0 +1,135 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/requestid"
+	"github.com/your-username/echo-api/internal/resilience"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestClientPropagates(t *testing.T) {
+	got := make(chan http.Header, 1)
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header
+	}))
+	defer downstream.Close()
+	client := New(Options{UserAgent: "echo-api"}, nil, nil).Client()
+
+	// A span of the caller, as a tracer would put in the context
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	e := echo.New()
+	e.Use(requestid.Middleware())
+	e.GET("/", func(c echo.Context) error {
+		req, _ := http.NewRequestWithContext(trace.ContextWithSpanContext(c.Request().Context(), sc), http.MethodGet, downstream.URL, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	h := <-got
+	if want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"; h.Get("Traceparent") != want {
+		t.Errorf("traceparent = %q, want %q", h.Get("Traceparent"), want)
+	}
+	if h.Get(echo.HeaderXRequestID) != "req-1" || h.Get("User-Agent") != "echo-api" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+	defer slow.Close()
+	clients := New(Options{Timeout: 20 * time.Millisecond, ResponseHeaderTimeout: time.Second}, nil, nil)
+
+	read := func(c *http.Client) error {
+		res, err := c.Get(slow.URL)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, err = io.ReadAll(res.Body)
+		return err
+	}
+	if err := read(clients.Client()); err == nil {
+		t.Error("Client outlived its timeout")
+	}
+	if err := read(clients.Streaming()); err != nil {
+		t.Errorf("Streaming: %v", err)
+	}
+}
+
+func TestBreakersRetry(t *testing.T) {
+	var calls atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+	breakers := resilience.NewRegistry(resilience.Options{Enabled: true, Retries: 1, Backoff: time.Millisecond, FailureThreshold: 5, OpenFor: time.Minute}, nil)
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, flaky.URL, nil)
+	res, err := New(Options{}, nil, breakers).Client().Do(req)
+	if err != nil || res.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("GET = %v, %v after %d calls", res, err, calls.Load())
+	}
+	res.Body.Close()
+}
+
+func TestPublicRefusesAddressesThatAreNotPublic(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer local.Close()
+	clients := New(Options{}, nil, nil)
+	res, err := clients.Client().Get(local.URL)
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	res.Body.Close()
+	// localhost resolves to the loopback address only once it is dialed
+	u := strings.Replace(local.URL, "127.0.0.1", "localhost", 1)
+	if _, err := clients.Public().Get(u); !errors.Is(err, ErrNotPublic) {
+		t.Errorf("Public to %s: %v, want ErrNotPublic", u, err)
+	}
+
+	for addr, want := range map[string]bool{
+		"93.184.215.14": true, "2606:2800:21f:cb07:6820:80da:af6b:8b2c": true,
+		"127.0.0.1": false, "::1": false, "10.1.2.3": false, "172.16.0.1": false, "192.168.1.1": false,
+		"169.254.169.254": false, "fe80::1": false, "fd00::1": false, "0.0.0.0": false, "::ffff:127.0.0.1": false,
+	} {
+		if got := IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Options{Timeout: time.Second}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (Options{DialTimeout: -time.Second, MaxConnsPerHost: -1}).Validate(); err == nil {
+		t.Error("negative settings accepted")
+	}
+}
@@ -0,0 +1,84 @@
+package idgen
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+)
+
+func TestTimeBasedIDsSortByCreationTime(t *testing.T) {
+	formats := map[string]*regexp.Regexp{
+		"uuidv7": regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		"ulid":   regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`),
+		"ksuid":  regexp.MustCompile(`^[0-9A-Za-z]{27}$`),
+	}
+	for strategy, format := range formats {
+		t.Run(strategy, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			g, err := New(strategy, clk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prev := g.NewID()
+			for i := 0; i < 100; i++ {
+				clk.Advance(time.Second)
+				id := g.NewID()
+				if !format.MatchString(id) {
+					t.Fatalf("%q is not a %s", id, strategy)
+				}
+				if id <= prev {
+					t.Fatalf("%q created after %q sorts before it", id, prev)
+				}
+				prev = id
+			}
+		})
+	}
+}
+
+func TestTimestampsAreEncoded(t *testing.T) {
+	// The example of the ULID specification, 01ARYZ6S41TSV4RRFFQ69G5FAV
+	clk := clock.NewFake(time.UnixMilli(1469918176385))
+	if id := NewULID(clk).NewID(); id[:10] != "01ARYZ6S41" {
+		t.Errorf("ULID %s, want the timestamp 01ARYZ6S41", id)
+	}
+	if id := NewUUIDv7(clk).NewID(); id[:13] != "01563df3-6481" {
+		t.Errorf("UUIDv7 %s, want the timestamp 01563df3-6481", id)
+	}
+}
+
+func TestSeededIDsRepeat(t *testing.T) {
+	for _, strategy := range []string{"uuidv7", "ulid", "ksuid"} {
+		run := func(seed int64) []string {
+			g, err := NewSeeded(strategy, clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return []string{g.NewID(), g.NewID(), g.NewID()}
+		}
+		first, again, other := run(1), run(1), run(2)
+		if !slices.Equal(first, again) || slices.Equal(first, other) {
+			t.Errorf("%s: seed 1 gave %v then %v, seed 2 %v; want the same IDs for the same seed only", strategy, first, again, other)
+		}
+	}
+}
+
+func TestSequential(t *testing.T) {
+	g, err := New("sequential", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"id-1", "id-2", "id-3"} {
+		if id := g.NewID(); id != want {
+			t.Fatalf("NewID() = %q, want %q", id, want)
+		}
+	}
+}
+
+func TestUnknownStrategy(t *testing.T) {
+	if _, err := New("uuidv4", nil); err == nil {
+		t.Fatal("expected an unknown strategy to be rejected")
+	}
+}
@@ -0,0 +1,98 @@
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+func migratedDB(t *testing.T) (*sql.DB, repository.Dialect) {
+	t.Helper()
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return db, dialect
+}
+
+func TestRedeliveriesAreProcessedOnce(t *testing.T) {
+	db, dialect := migratedDB(t)
+	in := New(db, dialect, nil, time.Hour)
+	products := repository.NewSQLRepository[model.Product](db, dialect, "products", "product")
+	ctx := context.Background()
+
+	// The handler's write commits or rolls back with the inbox entry
+	fail := errors.New("downstream unavailable")
+	calls := 0
+	handle := in.Handle("product-copier", func(ctx context.Context, m domain.Message) error {
+		calls++
+		if _, err := products.Create(ctx, &model.Product{ID: m.AggregateID, Name: "Lamp", Price: 20}); err != nil {
+			return err
+		}
+		if calls == 1 {
+			return fail
+		}
+		return nil
+	})
+	m := domain.Message{ID: "m1", Name: "product.created", AggregateID: "p1"}
+	if err := handle(ctx, m); !errors.Is(err, fail) {
+		t.Fatalf("first delivery: %v, want %v", err, fail)
+	}
+	for range 2 {
+		if err := handle(ctx, m); err != nil {
+			t.Fatalf("redelivery: %v", err)
+		}
+	}
+	if all, err := products.GetAll(ctx); err != nil || len(all) != 1 || calls != 2 {
+		t.Fatalf("after 3 deliveries: %d products (%v), handler called %d times, want 1 product and 2 calls", len(all), err, calls)
+	}
+
+	// Other consumers process the message too, and messages without an ID
+	// every time
+	other := 0
+	count := in.Handle("product-counter", func(context.Context, domain.Message) error { other++; return nil })
+	count(ctx, m)
+	count(ctx, domain.Message{Name: "product.created", AggregateID: "p2"})
+	count(ctx, domain.Message{Name: "product.created", AggregateID: "p2"})
+	if other != 3 {
+		t.Fatalf("second consumer called %d times, want 3", other)
+	}
+}
+
+func TestPurgeForgetsMessagesAfterRetention(t *testing.T) {
+	db, dialect := migratedDB(t)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	in := New(db, dialect, clk, time.Hour)
+	calls := 0
+	handle := in.Handle("c", func(context.Context, domain.Message) error { calls++; return nil })
+	m := domain.Message{ID: "m1"}
+	if err := handle(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(time.Hour)
+	if n, err := in.Purge(context.Background()); err != nil || n != 0 {
+		t.Fatalf("purged %d (%v) after exactly the retention, want 0", n, err)
+	}
+	clk.Advance(time.Second)
+	if n, err := in.Purge(context.Background()); err != nil || n != 1 {
+		t.Fatalf("purged %d (%v) after the retention, want 1", n, err)
+	}
+	handle(context.Background(), m)
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want a forgotten message processed again", calls)
+	}
+}
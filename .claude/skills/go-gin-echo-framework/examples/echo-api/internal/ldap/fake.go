@@ -0,0 +1,138 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Fake is a directory of fixed entries served over LDAP on a local port,
+// for tests. Binds check the passwords it was given, and searches need a
+// bound connection, as most directories require.
+type Fake struct {
+	URL string // ldap://127.0.0.1:<port>
+
+	entries   []Entry
+	passwords map[string]string // by lower-cased DN
+	ln        net.Listener
+	wg        sync.WaitGroup
+}
+
+// NewFake serves entries until Close; passwords are those of the entries
+// that can bind, by DN.
+func NewFake(entries []Entry, passwords map[string]string) (*Fake, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	f := &Fake{URL: "ldap://" + ln.Addr().String(), entries: entries, passwords: map[string]string{}, ln: ln}
+	for dn, password := range passwords {
+		f.passwords[strings.ToLower(dn)] = password
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.wg.Add(1)
+			go func() {
+				defer f.wg.Done()
+				f.serve(conn)
+			}()
+		}
+	}()
+	return f, nil
+}
+
+// Close stops the server once the connections it serves are closed.
+func (f *Fake) Close() error {
+	err := f.ln.Close()
+	f.wg.Wait()
+	return err
+}
+
+func (f *Fake) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	bound := false
+	for {
+		msg, err := readPacket(r)
+		if err != nil || len(msg.children) < 2 {
+			return
+		}
+		id, op := msg.children[0].int(), msg.children[1]
+		reply := func(tag byte, children ...*packet) {
+			conn.Write(sequence(integer(tagInteger, id), constructed(classApplication, tag, children...)).bytes())
+		}
+		done := func(tag byte, code int, message string) {
+			reply(tag, integer(tagEnumerated, int64(code)), octets(""), octets(message))
+		}
+		switch op.tag {
+		case opBindRequest:
+			dn, password := string(op.child(1).value), string(op.child(2).value)
+			want, ok := f.passwords[strings.ToLower(dn)]
+			bound = ok && password != "" && password == want
+			if bound {
+				done(opBindResponse, ResultSuccess, "")
+			} else {
+				done(opBindResponse, ResultInvalidCredentials, "invalid credentials")
+			}
+		case opSearchRequest:
+			if !bound {
+				done(opSearchDone, 50, "bind first") // insufficientAccessRights
+				continue
+			}
+			filter, err := decodeFilter(op.child(6))
+			if err != nil {
+				done(opSearchDone, 2, err.Error()) // protocolError
+				continue
+			}
+			base, limit := strings.ToLower(string(op.child(0).value)), int(op.child(3).int())
+			var wanted []string
+			for _, a := range op.child(7).children {
+				wanted = append(wanted, string(a.value))
+			}
+			sent, code := 0, ResultSuccess
+			for _, e := range f.entries {
+				if !strings.HasSuffix(strings.ToLower(e.DN), base) || !filter.matches(e) {
+					continue
+				}
+				if limit > 0 && sent == limit {
+					code = ResultSizeLimitExceeded
+					break
+				}
+				attrs := sequence()
+				for name, values := range e.Attributes {
+					if len(wanted) > 0 && !containsFold(wanted, name) {
+						continue
+					}
+					set := constructed(classUniversal, tagSet)
+					for _, v := range values {
+						set.children = append(set.children, octets(v))
+					}
+					attrs.children = append(attrs.children, sequence(octets(name), set))
+				}
+				reply(opSearchEntry, octets(e.DN), attrs)
+				sent++
+			}
+			done(opSearchDone, code, "")
+		case opUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Context tags of the kinds of filter (RFC 4511 section 4.5.1).
+const (
+	filterAnd        = 0
+	filterOr         = 1
+	filterNot        = 2
+	filterEquality   = 3
+	filterSubstrings = 4
+	filterGreater    = 5
+	filterLess       = 6
+	filterPresent    = 7
+	filterApprox     = 8
+)
+
+// Tags of the parts of a substrings filter.
+const (
+	subInitial = 0
+	subAny     = 1
+	subFinal   = 2
+)
+
+// filter is a parsed search filter.
+type filter struct {
+	kind     byte
+	attr     string
+	value    string   // of equality, greater, less and approx
+	parts    []string // of substrings: initial, any..., final, "" where absent
+	children []filter // of and, or and not
+}
+
+// EscapeFilter escapes s for use as a value in a filter, so that a login
+// cannot change the meaning of the filter it is put in.
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := range len(s) {
+		switch c := s[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, `\%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ValidateFilter reports why Search would refuse the filter s, if it would.
+func ValidateFilter(s string) error {
+	_, err := parseFilter(s)
+	return err
+}
+
+// parseFilter parses the string form of a filter (RFC 4515), such as
+// (&(objectClass=person)(|(uid=ada)(mail=ada@example.com))). Extensible
+// matches are not supported.
+func parseFilter(s string) (filter, error) {
+	f, rest, err := parseFilterAt(strings.TrimSpace(s))
+	if err != nil {
+		return filter{}, fmt.Errorf("ldap: filter %q: %w", s, err)
+	}
+	if rest != "" {
+		return filter{}, fmt.Errorf("ldap: filter %q: unexpected %q", s, rest)
+	}
+	return f, nil
+}
+
+func parseFilterAt(s string) (filter, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return filter{}, "", fmt.Errorf("expected (")
+	}
+	s = s[1:]
+	var f filter
+	switch {
+	case strings.HasPrefix(s, "&"), strings.HasPrefix(s, "|"), strings.HasPrefix(s, "!"):
+		f.kind = map[byte]byte{'&': filterAnd, '|': filterOr, '!': filterNot}[s[0]]
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilterAt(s)
+			if err != nil {
+				return filter{}, "", err
+			}
+			f.children = append(f.children, child)
+			s = rest
+		}
+		if len(f.children) == 0 || f.kind == filterNot && len(f.children) != 1 {
+			return filter{}, "", fmt.Errorf("wrong number of filters in a %s", map[byte]string{filterAnd: "&", filterOr: "|", filterNot: "!"}[f.kind])
+		}
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return filter{}, "", fmt.Errorf("expected )")
+		}
+		item, err := parseItem(s[:end])
+		if err != nil {
+			return filter{}, "", err
+		}
+		f, s = item, s[end:]
+	}
+	if !strings.HasPrefix(s, ")") {
+		return filter{}, "", fmt.Errorf("expected )")
+	}
+	return f, s[1:], nil
+}
+
+// parseItem parses a simple filter such as mail=*@example.com, without
+// its parentheses.
+func parseItem(s string) (filter, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq < 1 {
+		return filter{}, fmt.Errorf("expected an attribute and a value in %q", s)
+	}
+	attr, raw := s[:eq], s[eq+1:]
+	f := filter{kind: filterEquality}
+	switch attr[len(attr)-1] {
+	case '>':
+		f.kind, attr = filterGreater, attr[:len(attr)-1]
+	case '<':
+		f.kind, attr = filterLess, attr[:len(attr)-1]
+	case '~':
+		f.kind, attr = filterApprox, attr[:len(attr)-1]
+	}
+	if attr == "" || strings.ContainsAny(attr, "()&|!*\\ ") {
+		return filter{}, fmt.Errorf("invalid attribute %q", attr)
+	}
+	f.attr = attr
+	if f.kind == filterEquality && raw == "*" {
+		f.kind = filterPresent
+		return f, nil
+	}
+	// Unescaped asterisks split the value of a substrings filter
+	pieces := strings.Split(raw, "*")
+	for i, piece := range pieces {
+		v, err := unescape(piece)
+		if err != nil {
+			return filter{}, err
+		}
+		pieces[i] = v
+	}
+	if len(pieces) == 1 {
+		f.value = pieces[0]
+		return f, nil
+	}
+	if f.kind != filterEquality {
+		return filter{}, fmt.Errorf("wildcards are only allowed with =")
+	}
+	f.kind, f.parts = filterSubstrings, pieces
+	return f, nil
+}
+
+// unescape decodes the \XX escapes of a filter value.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("truncated escape in %q", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// packet returns the BER encoding of f.
+func (f filter) packet() *packet {
+	switch f.kind {
+	case filterAnd, filterOr, filterNot:
+		p := constructed(classContext, f.kind)
+		for _, c := range f.children {
+			p.children = append(p.children, c.packet())
+		}
+		return p
+	case filterPresent:
+		return primitive(classContext, filterPresent, []byte(f.attr))
+	case filterSubstrings:
+		subs := sequence()
+		for i, part := range f.parts {
+			tag := byte(subAny)
+			switch i {
+			case 0:
+				tag = subInitial
+			case len(f.parts) - 1:
+				tag = subFinal
+			}
+			if part != "" {
+				subs.children = append(subs.children, primitive(classContext, tag, []byte(part)))
+			}
+		}
+		return constructed(classContext, filterSubstrings, octets(f.attr), subs)
+	default:
+		return constructed(classContext, f.kind, octets(f.attr), octets(f.value))
+	}
+}
+
+// decodeFilter is the inverse of filter.packet.
+func decodeFilter(p *packet) (filter, error) {
+	if p.class != classContext || p.tag > filterApprox {
+		return filter{}, fmt.Errorf("ldap: unsupported filter")
+	}
+	f := filter{kind: p.tag}
+	switch p.tag {
+	case filterAnd, filterOr, filterNot:
+		for _, c := range p.children {
+			child, err := decodeFilter(c)
+			if err != nil {
+				return filter{}, err
+			}
+			f.children = append(f.children, child)
+		}
+		if p.tag == filterNot && len(f.children) != 1 {
+			return filter{}, fmt.Errorf("ldap: a not filter holds one filter")
+		}
+	case filterPresent:
+		f.attr = string(p.value)
+	case filterSubstrings:
+		f.attr = string(p.child(0).value)
+		var initial, final string
+		var middle []string
+		for _, sub := range p.child(1).children {
+			switch sub.tag {
+			case subInitial:
+				initial = string(sub.value)
+			case subAny:
+				middle = append(middle, string(sub.value))
+			case subFinal:
+				final = string(sub.value)
+			}
+		}
+		f.parts = append(append([]string{initial}, middle...), final)
+	default:
+		f.attr, f.value = string(p.child(0).value), string(p.child(1).value)
+	}
+	return f, nil
+}
+
+// matches reports whether e meets f, comparing values case-insensitively
+// as most directory attributes are.
+func (f filter) matches(e Entry) bool {
+	switch f.kind {
+	case filterAnd:
+		for _, c := range f.children {
+			if !c.matches(e) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, c := range f.children {
+			if c.matches(e) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return !f.children[0].matches(e)
+	}
+	for _, v := range e.Values(f.attr) {
+		v := strings.ToLower(v)
+		switch want := strings.ToLower(f.value); f.kind {
+		case filterPresent:
+			return true
+		case filterEquality, filterApprox:
+			if v == want {
+				return true
+			}
+		case filterGreater:
+			if v >= want {
+				return true
+			}
+		case filterLess:
+			if v <= want {
+				return true
+			}
+		case filterSubstrings:
+			if matchSubstrings(v, f.parts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSubstrings reports whether v starts with the first of parts, ends
+// with the last and holds the others in order between them.
+func matchSubstrings(v string, parts []string) bool {
+	first, last := strings.ToLower(parts[0]), strings.ToLower(parts[len(parts)-1])
+	if !strings.HasPrefix(v, first) {
+		return false
+	}
+	v = v[len(first):]
+	for _, part := range parts[1 : len(parts)-1] {
+		part = strings.ToLower(part)
+		i := strings.Index(v, part)
+		if i < 0 {
+			return false
+		}
+		v = v[i+len(part):]
+	}
+	return len(v) >= len(last) && strings.HasSuffix(v, last)
+}
@@ -0,0 +1,259 @@
+// Package ldap is a minimal LDAPv3 client (RFC 4511): simple binds and
+// subtree searches over ldap:// or ldaps://, which is what checking
+// passwords against a directory such as Active Directory or OpenLDAP takes.
+// Fake is a directory of fixed entries that stands in for one in tests.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Result codes of interest (RFC 4511 appendix A).
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+// Application tags of the protocol operations.
+const (
+	opBindRequest     = 0
+	opBindResponse    = 1
+	opUnbindRequest   = 2
+	opSearchRequest   = 3
+	opSearchEntry     = 4
+	opSearchDone      = 5
+	opSearchResultRef = 19
+)
+
+// Error is an operation the server refused.
+type Error struct {
+	Code    int    // result code
+	Message string // diagnostic message of the server
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ldap: result %d: %s", e.Code, e.Message)
+}
+
+// IsInvalidCredentials reports whether err is a bind refused for a wrong
+// DN or password.
+func IsInvalidCredentials(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == ResultInvalidCredentials
+}
+
+// Entry is an object a search found.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string // by name as the server spelt it
+}
+
+// Values returns the values of the attribute name, matched
+// case-insensitively as attribute names are.
+func (e Entry) Values(name string) []string {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Value returns the first value of the attribute name, or "".
+func (e Entry) Value(name string) string {
+	if v := e.Values(name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Conn is a connection to a directory server. It is not safe for
+// concurrent use.
+type Conn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	lastID int64
+	stop   func() bool
+}
+
+// Dial connects to the server of rawURL, ldap://host[:389] or
+// ldaps://host[:636], verifying the certificate of ldaps servers with
+// tlsConfig (nil is the default configuration). ctx bounds the connection
+// and every operation on it: once it is done, the connection is closed.
+func Dial(ctx context.Context, rawURL string, tlsConfig *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: %w", err)
+	}
+	host := u.Host
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		dial = (&net.Dialer{}).DialContext
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = u.Hostname()
+		}
+		dial = (&tls.Dialer{Config: tlsConfig}).DialContext
+	default:
+		return nil, fmt.Errorf("ldap: want an ldap:// or ldaps:// URL, got %q", rawURL)
+	}
+	conn, err := dial(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return &Conn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		stop: context.AfterFunc(ctx, func() { conn.Close() }),
+	}, nil
+}
+
+// Close ends the session and closes the connection.
+func (c *Conn) Close() error {
+	c.stop()
+	c.send(primitive(classApplication, opUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+// Bind authenticates the connection as dn with password. An empty
+// password is refused without asking the server, which would take it for
+// an unauthenticated bind and succeed whatever dn; connections are
+// anonymous until they bind. A wrong DN or password is an *Error with
+// ResultInvalidCredentials.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return &Error{Code: ResultInvalidCredentials, Message: "empty password"}
+	}
+	id, err := c.send(constructed(classApplication, opBindRequest,
+		integer(tagInteger, 3), octets(dn), primitive(classContext, 0, []byte(password))))
+	if err != nil {
+		return err
+	}
+	op, err := c.read(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != opBindResponse {
+		return fmt.Errorf("ldap: unexpected response %d to a bind", op.tag)
+	}
+	return result(op)
+}
+
+// Search returns the entries under base that match filter, a string such
+// as (&(objectClass=person)(uid=ada)), with the given attributes (none is
+// every one). It stops at limit entries (0 is the server's limit), which
+// is not an error. Build filters from user input with EscapeFilter.
+func (c *Conn) Search(base, filterString string, attributes []string, limit int) ([]Entry, error) {
+	f, err := parseFilter(filterString)
+	if err != nil {
+		return nil, err
+	}
+	attrs := sequence()
+	for _, a := range attributes {
+		attrs.children = append(attrs.children, octets(a))
+	}
+	id, err := c.send(constructed(classApplication, opSearchRequest,
+		octets(base),
+		integer(tagEnumerated, 2), // wholeSubtree
+		integer(tagEnumerated, 0), // neverDerefAliases
+		integer(tagInteger, int64(limit)),
+		integer(tagInteger, 0), // no time limit but the connection's
+		boolean(false),
+		f.packet(),
+		attrs))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for {
+		op, err := c.read(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case opSearchEntry:
+			e := Entry{DN: string(op.child(0).value), Attributes: map[string][]string{}}
+			for _, attr := range op.child(1).children {
+				var values []string
+				for _, v := range attr.child(1).children {
+					values = append(values, string(v.value))
+				}
+				e.Attributes[string(attr.child(0).value)] = values
+			}
+			entries = append(entries, e)
+		case opSearchResultRef:
+			// Referrals to other servers are not followed
+		case opSearchDone:
+			err := result(op)
+			var e *Error
+			if errors.As(err, &e) && e.Code == ResultSizeLimitExceeded && limit > 0 && len(entries) >= limit {
+				err = nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response %d to a search", op.tag)
+		}
+	}
+}
+
+// send writes the message of op and returns its ID.
+func (c *Conn) send(op *packet) (int64, error) {
+	c.lastID++
+	if _, err := c.conn.Write(sequence(integer(tagInteger, c.lastID), op).bytes()); err != nil {
+		return 0, fmt.Errorf("ldap: %w", err)
+	}
+	return c.lastID, nil
+}
+
+// read returns the operation of the next message, which must answer the
+// message id.
+func (c *Conn) read(id int64) (*packet, error) {
+	msg, err := readPacket(c.r)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: %w", err)
+	}
+	if msg.tag != tagSequence || len(msg.children) < 2 {
+		return nil, errors.New("ldap: malformed message")
+	}
+	if got := msg.children[0].int(); got != id {
+		return nil, fmt.Errorf("ldap: response to message %d, want %d", got, id)
+	}
+	op := msg.children[1]
+	if op.class != classApplication {
+		return nil, errors.New("ldap: malformed message")
+	}
+	return op, nil
+}
+
+// result returns the *Error of an LDAPResult unless it is a success.
+func result(op *packet) error {
+	if code := int(op.child(0).int()); code != ResultSuccess {
+		return &Error{Code: code, Message: string(op.child(2).value)}
+	}
+	return nil
+}
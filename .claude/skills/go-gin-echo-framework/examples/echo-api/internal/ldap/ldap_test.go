@@ -0,0 +1,125 @@
+package ldap
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newFake(t *testing.T) *Fake {
+	t.Helper()
+	f, err := NewFake([]Entry{
+		{DN: "cn=svc,dc=example,dc=com", Attributes: map[string][]string{"cn": {"svc"}}},
+		{DN: "uid=ada,ou=people,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"}, "uid": {"ada"}, "mail": {"ada@example.com"},
+			"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+		}},
+		{DN: "uid=alan,ou=people,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"}, "uid": {"alan"}, "mail": {"alan@example.com"},
+		}},
+	}, map[string]string{
+		"cn=svc,dc=example,dc=com":            "svc-secret",
+		"uid=ada,ou=people,dc=example,dc=com": "lovelace",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func dial(t *testing.T, f *Fake) *Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	conn, err := Dial(ctx, f.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBind(t *testing.T) {
+	conn := dial(t, newFake(t))
+	if err := conn.Bind("uid=ada,ou=people,dc=example,dc=com", "lovelace"); err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"wrong", ""} {
+		if err := conn.Bind("uid=ada,ou=people,dc=example,dc=com", password); !IsInvalidCredentials(err) {
+			t.Errorf("Bind with %q = %v, want invalid credentials", password, err)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	conn := dial(t, newFake(t))
+	if _, err := conn.Search("dc=example,dc=com", "(uid=ada)", nil, 0); err == nil {
+		t.Error("anonymous search succeeded")
+	}
+	if err := conn.Bind("cn=svc,dc=example,dc=com", "svc-secret"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := conn.Search("ou=people,dc=example,dc=com", "(&(objectClass=person)(uid="+EscapeFilter("ada")+"))", []string{"mail", "memberOf"}, 2)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Search = %v, %v", entries, err)
+	}
+	e := entries[0]
+	if e.DN != "uid=ada,ou=people,dc=example,dc=com" || e.Value("MAIL") != "ada@example.com" || len(e.Values("memberof")) != 1 || e.Value("uid") != "" {
+		t.Errorf("entry = %+v", e)
+	}
+	entries, err = conn.Search("dc=example,dc=com", "(mail=*@example.com)", nil, 1)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("limited Search = %v, %v", entries, err)
+	}
+	// An escaped login matches itself only, wildcards included
+	entries, err = conn.Search("dc=example,dc=com", "(uid="+EscapeFilter("a*")+")", nil, 0)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Search of an escaped wildcard = %v, %v", entries, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	e := Entry{DN: "uid=ada", Attributes: map[string][]string{"uid": {"ada"}, "mail": {"Ada@Example.com"}, "age": {"36"}}}
+	for s, want := range map[string]bool{
+		"(uid=ada)":                   true,
+		"(uid=ADA)":                   true,
+		"(mail=ada@*)":                true,
+		"(mail=*@example.*)":          true,
+		"(mail=*@example.org)":        false,
+		"(cn=*)":                      false,
+		"(&(uid=ada)(!(mail=x)))":     true,
+		"(|(uid=alan)(age>=30))":      true,
+		"(age<=30)":                   false,
+		`(uid=\61da)`:                 true,
+		"(&(uid=ada)(|(cn=x)(sn=y)))": false,
+		"(&(objectClass=*)(uid=ada))": false,
+		"(!(|(uid=alan)(uid=grace)))": true,
+	} {
+		f, err := parseFilter(s)
+		if err != nil {
+			t.Errorf("parseFilter(%q): %v", s, err)
+			continue
+		}
+		// What is matched is what went over the wire
+		decoded, err := decodeFilter(f.packet())
+		if err != nil {
+			t.Errorf("decodeFilter(%q): %v", s, err)
+			continue
+		}
+		if got := decoded.matches(e); got != want {
+			t.Errorf("%s matches = %v, want %v", s, got, want)
+		}
+	}
+	for _, s := range []string{"", "uid=ada", "(uid=ada", "(=ada)", "(!(a=1)(b=2))", "(&)", "(uid=ada)x", `(uid=\6)`, "(a>=b*)"} {
+		if _, err := parseFilter(s); err == nil {
+			t.Errorf("parseFilter(%q) succeeded", s)
+		}
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	if got, want := EscapeFilter(`a*(b)\c`+"\x00"), `a\2a\28b\29\5cc\00`; got != want {
+		t.Errorf("EscapeFilter = %q, want %q", got, want)
+	}
+}
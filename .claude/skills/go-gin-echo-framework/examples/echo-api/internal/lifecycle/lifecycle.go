@@ -0,0 +1,154 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a component's shutdown when it registers without one.
+const DefaultTimeout = 5 * time.Second
+
+// CloseFunc releases a component's resources. It should return promptly once
+// ctx is done.
+type CloseFunc func(ctx context.Context) error
+
+// StartFunc starts a component's background work, such as serving or
+// polling, and returns once it is under way.
+type StartFunc func(ctx context.Context) error
+
+// Hook is the lifecycle of one component. Either function may be nil.
+type Hook struct {
+	Name    string
+	Timeout time.Duration // bounds Stop; zero means DefaultTimeout
+	Start   StartFunc
+	Stop    CloseFunc
+}
+
+type hook struct {
+	Hook
+	started bool
+}
+
+// Manager coordinates startup and shutdown of long-lived components (HTTP
+// server, DB pools, Redis clients, workers, tracer providers). Components
+// register their hooks as they are constructed; Start runs the start hooks
+// in registration order once everything is built, and Shutdown runs the
+// closers in reverse registration order so that anything started later, and
+// possibly depending on earlier components, is drained first.
+type Manager struct {
+	mu      sync.Mutex
+	hooks   []*hook
+	started bool
+	closed  bool
+}
+
+func New() *Manager {
+	return &Manager{}
+}
+
+// Register adds a closer. A zero timeout means DefaultTimeout.
+func (m *Manager) Register(name string, timeout time.Duration, fn CloseFunc) {
+	m.Append(Hook{Name: name, Timeout: timeout, Stop: fn})
+}
+
+// Append adds the hooks of a component. A component with a start hook is
+// only stopped if it was started, so that one built but never run (with
+// -describe, or after an earlier start hook failed) is not waited for.
+func (m *Manager) Append(h Hook) {
+	if h.Timeout <= 0 {
+		h.Timeout = DefaultTimeout
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, &hook{Hook: h})
+}
+
+// RegisterCloser adapts a plain Close() error, e.g. a *redis.Client.
+func (m *Manager) RegisterCloser(name string, timeout time.Duration, c interface{ Close() error }) {
+	m.Register(name, timeout, func(context.Context) error { return c.Close() })
+}
+
+// Start runs the start hooks in registration order, stopping at the first
+// that fails. Later calls are no-ops; hooks appended after Start are not
+// started. After an error, Shutdown stops what did start.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.started || m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.started = true
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	for _, h := range hooks {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				return fmt.Errorf("%s: %w", h.Name, err)
+			}
+		}
+		m.mu.Lock()
+		h.started = true
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Shutdown runs every registered closer once, each bounded by its own
+// timeout and by ctx, and returns the combined errors. Later calls are no-ops.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		m.mu.Lock()
+		h := *hooks[i]
+		m.mu.Unlock()
+		if h.Stop == nil || (h.Start != nil && !h.started) {
+			continue
+		}
+		start := time.Now()
+		if err := run(ctx, h); err != nil {
+			log.Printf("shutdown: %s failed after %s: %v", h.Name, time.Since(start).Round(time.Millisecond), err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.Name, err))
+			continue
+		}
+		log.Printf("shutdown: %s stopped in %s", h.Name, time.Since(start).Round(time.Millisecond))
+	}
+	return errors.Join(errs...)
+}
+
+// run calls h.Stop under its timeout. A closer that ignores its context is
+// abandoned when the deadline passes so one stuck component cannot block the rest.
+func run(parent context.Context, h hook) error {
+	ctx, cancel := context.WithTimeout(parent, h.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- h.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s: %w", h.Timeout, ctx.Err())
+	}
+}
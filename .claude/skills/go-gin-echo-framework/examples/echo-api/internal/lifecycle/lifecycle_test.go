@@ -0,0 +1,33 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOnlyStartedComponentsAreStopped(t *testing.T) {
+	var events []string
+	record := func(event string) func(context.Context) error {
+		return func(context.Context) error {
+			events = append(events, event)
+			return nil
+		}
+	}
+	m := New()
+	m.Register("pool", 0, record("stop pool"))
+	m.Append(Hook{Name: "jobs", Start: record("start jobs"), Stop: record("stop jobs")})
+	m.Append(Hook{Name: "server", Start: func(context.Context) error { return errors.New("address in use") }, Stop: record("stop server")})
+	m.Append(Hook{Name: "relay", Start: record("start relay"), Stop: record("stop relay")})
+
+	if err := m.Start(context.Background()); err == nil || err.Error() != "server: address in use" {
+		t.Fatalf("Start = %v, want the server's error", err)
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(events, ", "), "start jobs, stop jobs, stop pool"; got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+}
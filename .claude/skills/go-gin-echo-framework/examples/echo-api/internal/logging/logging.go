@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/your-username/echo-api/config"
+)
+
+// level is shared by the installed handler so it can be changed at runtime.
+var level slog.LevelVar
+
+// Setup installs a process-wide slog logger built from cfg. It also becomes
+// the sink for the standard log package, so existing log.Printf calls are
+// emitted in the configured format.
+func Setup(cfg config.LoggingConfig) {
+	level.Set(SlogLevel(cfg.Level))
+	opts := &slog.HandlerOptions{Level: &level}
+
+	var h slog.Handler
+	if cfg.Format == "json" {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		h = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(h))
+}
+
+// SetLevel changes the minimum level of the installed logger.
+func SetLevel(l config.LogLevel) {
+	level.Set(SlogLevel(l))
+}
+
+// SlogLevel maps a config level to a slog.Level. Config validation admits
+// the config.LogLevels only, so any other value is a programming error.
+func SlogLevel(l config.LogLevel) slog.Level {
+	switch l {
+	case config.LogDebug:
+		return slog.LevelDebug
+	case config.LogInfo:
+		return slog.LevelInfo
+	case config.LogWarn:
+		return slog.LevelWarn
+	case config.LogError:
+		return slog.LevelError
+	}
+	panic(fmt.Sprintf("logging: unhandled level %q", l))
+}
@@ -0,0 +1,16 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/your-username/echo-api/config"
+)
+
+func TestSlogLevelHandlesEveryLevel(t *testing.T) {
+	for i, l := range config.LogLevels {
+		got := SlogLevel(l) // panics on a level without a case
+		if i > 0 && got <= SlogLevel(config.LogLevels[i-1]) {
+			t.Errorf("%s maps to %v, not above %s", l, got, config.LogLevels[i-1])
+		}
+	}
+}
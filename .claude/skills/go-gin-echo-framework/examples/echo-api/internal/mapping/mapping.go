@@ -0,0 +1,95 @@
+// Package mapping converts between protobuf messages and domain models so
+// transports never build one from the other by hand.
+//
+// Conventions: enums reject unknown and UNSPECIFIED values; timestamps are
+// normalised to UTC.
+package mapping
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/model"
+	productsv1 "github.com/your-username/echo-api/internal/pb/products/v1"
+)
+
+func ProductToProto(p model.Product) *productsv1.Product {
+	return &productsv1.Product{
+		Id:    p.ID,
+		Name:  p.Name,
+		Price: p.Price,
+	}
+}
+
+func ProductFromProto(p *productsv1.Product) model.Product {
+	return model.Product{
+		ID:    p.GetId(),
+		Name:  p.GetName(),
+		Price: p.GetPrice(),
+	}
+}
+
+// ProductFromCreateRequest returns a product without an ID; the service assigns one.
+func ProductFromCreateRequest(req *productsv1.CreateProductRequest) model.Product {
+	return model.Product{
+		Name:  req.GetName(),
+		Price: req.GetPrice(),
+	}
+}
+
+var opToProto = map[changes.Op]productsv1.ChangeOp{
+	changes.OpCreated: productsv1.ChangeOp_CHANGE_OP_CREATED,
+	changes.OpUpdated: productsv1.ChangeOp_CHANGE_OP_UPDATED,
+	changes.OpDeleted: productsv1.ChangeOp_CHANGE_OP_DELETED,
+}
+
+var opFromProto = map[productsv1.ChangeOp]changes.Op{
+	productsv1.ChangeOp_CHANGE_OP_CREATED: changes.OpCreated,
+	productsv1.ChangeOp_CHANGE_OP_UPDATED: changes.OpUpdated,
+	productsv1.ChangeOp_CHANGE_OP_DELETED: changes.OpDeleted,
+}
+
+// ChangeToProto fails for an Op with no proto counterpart, so a new Op cannot
+// silently go out as UNSPECIFIED.
+func ChangeToProto(c changes.Change) (*productsv1.Change, error) {
+	op, ok := opToProto[c.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown change op %q", c.Op)
+	}
+	return &productsv1.Change{
+		Seq: c.Seq,
+		Op:  op,
+		Id:  c.ID,
+		At:  timestamppb.New(c.At),
+	}, nil
+}
+
+func ChangeFromProto(p *productsv1.Change) (changes.Change, error) {
+	op, ok := opFromProto[p.GetOp()]
+	if !ok {
+		return changes.Change{}, fmt.Errorf("unknown change op %v", p.GetOp())
+	}
+	if err := p.GetAt().CheckValid(); err != nil {
+		return changes.Change{}, fmt.Errorf("invalid change timestamp: %w", err)
+	}
+	return changes.Change{
+		Seq: p.GetSeq(),
+		Op:  op,
+		ID:  p.GetId(),
+		At:  p.GetAt().AsTime(),
+	}, nil
+}
+
+func ChangesToProto(cs []changes.Change) ([]*productsv1.Change, error) {
+	out := make([]*productsv1.Change, len(cs))
+	for i, c := range cs {
+		p, err := ChangeToProto(c)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = p
+	}
+	return out, nil
+}
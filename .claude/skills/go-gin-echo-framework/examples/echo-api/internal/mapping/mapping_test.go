@@ -0,0 +1,133 @@
+package mapping
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/fakedata"
+	"github.com/your-username/echo-api/internal/model"
+	productsv1 "github.com/your-username/echo-api/internal/pb/products/v1"
+)
+
+var ops = []changes.Op{changes.OpCreated, changes.OpUpdated, changes.OpDeleted}
+
+// randomChange draws an arbitrary change, including the zero and far-future
+// instants and odd nanoseconds, in a non-UTC zone.
+func randomChange(r *rand.Rand) changes.Change {
+	at := time.Unix(r.Int63n(1<<34)-1<<33, r.Int63n(1e9)).In(time.FixedZone("X", 3600*(r.Intn(25)-12)))
+	return changes.Change{
+		Seq: r.Uint64(),
+		Op:  ops[r.Intn(len(ops))],
+		ID:  randomString(r),
+		At:  at,
+	}
+}
+
+func randomString(r *rand.Rand) string {
+	v, _ := quick.Value(reflect.TypeOf(""), r)
+	return v.String()
+}
+
+func TestProductRoundTrip(t *testing.T) {
+	modelFirst := func(id, name string, price float64) bool {
+		p := model.Product{ID: id, Name: name, Price: price}
+		return reflect.DeepEqual(ProductFromProto(ProductToProto(p)), p)
+	}
+	if err := quick.Check(modelFirst, nil); err != nil {
+		t.Error(err)
+	}
+
+	protoFirst := func(id, name string, price float64) bool {
+		p := &productsv1.Product{Id: id, Name: name, Price: price}
+		return proto.Equal(ProductToProto(ProductFromProto(p)), p)
+	}
+	if err := quick.Check(protoFirst, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Realistic values in every locale, including non-Latin scripts
+	for tag := range fakedata.Locales {
+		for _, u := range fakedata.New(1, tag).Products(20) {
+			if got := ProductFromProto(ProductToProto(u)); !reflect.DeepEqual(got, u) {
+				t.Errorf("%s: round trip of %+v gave %+v", tag, u, got)
+			}
+		}
+	}
+}
+
+func TestProductFromCreateRequest(t *testing.T) {
+	f := func(name string, price float64) bool {
+		got := ProductFromCreateRequest(&productsv1.CreateProductRequest{Name: name, Price: price})
+		return reflect.DeepEqual(got, model.Product{Name: name, Price: price})
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChangeRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		c := randomChange(r)
+		p, err := ChangeToProto(c)
+		if err != nil {
+			t.Fatalf("ChangeToProto(%+v): %v", c, err)
+		}
+		got, err := ChangeFromProto(p)
+		if err != nil {
+			t.Fatalf("ChangeFromProto(%v): %v", p, err)
+		}
+		if !got.At.Equal(c.At) || got.At.Location() != time.UTC {
+			t.Fatalf("At = %v, want %v in UTC", got.At, c.At)
+		}
+		c.At = c.At.UTC()
+		if got != c {
+			t.Fatalf("round trip = %+v, want %+v", got, c)
+		}
+
+		back, err := ChangeToProto(got)
+		if err != nil || !proto.Equal(back, p) {
+			t.Fatalf("proto round trip = %v (%v), want %v", back, err, p)
+		}
+	}
+}
+
+func TestChangeEnumValidation(t *testing.T) {
+	if _, err := ChangeToProto(changes.Change{Op: "renamed"}); err == nil {
+		t.Error("ChangeToProto accepted an unknown op")
+	}
+	for _, op := range []productsv1.ChangeOp{productsv1.ChangeOp_CHANGE_OP_UNSPECIFIED, productsv1.ChangeOp(42)} {
+		p := &productsv1.Change{Op: op, At: timestamppb.Now()}
+		if _, err := ChangeFromProto(p); err == nil {
+			t.Errorf("ChangeFromProto accepted op %v", op)
+		}
+	}
+	for _, op := range ops {
+		if _, ok := opToProto[op]; !ok {
+			t.Errorf("op %q has no proto value", op)
+		}
+	}
+	if len(opFromProto) != len(productsv1.ChangeOp_name)-1 {
+		t.Errorf("opFromProto covers %d of %d non-UNSPECIFIED proto values", len(opFromProto), len(productsv1.ChangeOp_name)-1)
+	}
+}
+
+func TestChangeTimestampValidation(t *testing.T) {
+	for name, at := range map[string]*timestamppb.Timestamp{
+		"missing":      nil,
+		"out of range": {Seconds: 1 << 62},
+		"bad nanos":    {Seconds: 1, Nanos: -1},
+	} {
+		p := &productsv1.Change{Op: productsv1.ChangeOp_CHANGE_OP_CREATED, At: at}
+		if _, err := ChangeFromProto(p); err == nil {
+			t.Errorf("%s: ChangeFromProto accepted %v", name, at)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+func TestUpAppliesPendingMigrationsOnce(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	check := Check(db)
+	if err := check(ctx); err == nil {
+		t.Fatal("Check passed on an unmigrated database")
+	}
+
+	applied, err := Up(ctx, db, dialect)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(Versions()) {
+		t.Fatalf("applied %v, want %v", applied, Versions())
+	}
+	if err := check(ctx); err != nil {
+		t.Fatalf("Check after Up: %v", err)
+	}
+
+	again, err := Up(ctx, db, dialect)
+	if err != nil || len(again) != 0 {
+		t.Fatalf("second Up applied %v, %v; want nothing", again, err)
+	}
+	s, err := CurrentStatus(ctx, db)
+	if err != nil || len(s.Pending) != 0 || len(s.Applied) != len(Versions()) {
+		t.Fatalf("CurrentStatus = %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE products (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	price DOUBLE PRECISION NOT NULL DEFAULT 0
+);
@@ -0,0 +1,7 @@
+CREATE TABLE credentials (
+	id TEXT PRIMARY KEY,
+	subject TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	failed_attempts INTEGER NOT NULL DEFAULT 0,
+	locked_until TIMESTAMP NOT NULL
+);
@@ -0,0 +1,12 @@
+CREATE TABLE audit_log (
+	id TEXT PRIMARY KEY,
+	at TIMESTAMP NOT NULL,
+	actor TEXT NOT NULL,
+	request_id TEXT NOT NULL,
+	resource TEXT NOT NULL,
+	op TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	before_state TEXT,
+	after_state TEXT
+);
+CREATE INDEX audit_log_subject ON audit_log (resource, subject_id, at);
@@ -0,0 +1,4 @@
+ALTER TABLE products ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE credentials ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX products_tenant_id ON products (tenant_id, id);
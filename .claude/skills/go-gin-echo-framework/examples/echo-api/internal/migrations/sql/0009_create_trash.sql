@@ -0,0 +1,10 @@
+CREATE TABLE trash (
+	resource TEXT NOT NULL,
+	tenant_id TEXT NOT NULL,
+	id TEXT NOT NULL,
+	item TEXT NOT NULL,
+	deleted_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (resource, tenant_id, id)
+);
+CREATE INDEX trash_expires_at ON trash (expires_at);
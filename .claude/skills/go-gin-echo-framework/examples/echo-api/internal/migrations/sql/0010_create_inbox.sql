@@ -0,0 +1,7 @@
+CREATE TABLE inbox (
+	consumer TEXT NOT NULL,
+	message_id TEXT NOT NULL,
+	processed_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (consumer, message_id)
+);
+CREATE INDEX inbox_processed_at ON inbox (processed_at);
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN custom JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,11 @@
+CREATE TABLE passkeys (
+	id TEXT PRIMARY KEY,
+	subject TEXT NOT NULL,
+	name TEXT NOT NULL,
+	public_key TEXT NOT NULL,
+	sign_count BIGINT NOT NULL,
+	transports TEXT NOT NULL,
+	tenant_id TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,182 @@
+// Package mock answers every operation of an OpenAPI document with fake data
+// that is valid against the operation's response schema, so front ends can
+// be built against the API before its handlers are. Values come from the
+// generators registered in Formats and Fields, which draw on fakedata in the
+// locale of the request's Accept-Language; the same URL always gets the same
+// data.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/echo-api/internal/fakedata"
+)
+
+// Server serves the mock responses of one document. It is safe for
+// concurrent use.
+type Server struct {
+	schemas    map[string]any // components/schemas
+	operations []operation
+}
+
+type operation struct {
+	segments  []string // path split at "/"; "{name}" segments match any value
+	method    string
+	responses map[int]any // status -> JSON schema of the body, nil when it has none
+	success   int         // status served unless the request prefers another
+}
+
+// New parses spec, an OpenAPI 3 document in JSON.
+func New(spec []byte) (*Server, error) {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	s := &Server{schemas: doc.Components.Schemas}
+	for path, ops := range doc.Paths {
+		for method, raw := range ops {
+			var op struct {
+				Responses map[string]struct {
+					Content map[string]struct {
+						Schema any `json:"schema"`
+					} `json:"content"`
+				} `json:"responses"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			o := operation{segments: strings.Split(path, "/"), method: strings.ToUpper(method), responses: map[int]any{}}
+			for code, res := range op.Responses {
+				status, err := strconv.Atoi(code)
+				if err != nil {
+					continue // "default"
+				}
+				o.responses[status] = res.Content["application/json"].Schema
+				if status < 400 && (o.success == 0 || status < o.success) {
+					o.success = status
+				}
+			}
+			if o.success == 0 {
+				return nil, fmt.Errorf("%s %s documents no successful response", o.method, path)
+			}
+			s.operations = append(s.operations, o)
+		}
+	}
+	// Fewer parameters first, so /users/batch wins over /users/{id}
+	sort.SliceStable(s.operations, func(i, j int) bool {
+		return s.operations[i].params() < s.operations[j].params()
+	})
+	return s, nil
+}
+
+// Operations returns the number of mocked operations.
+func (s *Server) Operations() int {
+	return len(s.operations)
+}
+
+// ServeHTTP answers with the operation's successful response, or with the
+// documented response a "Prefer: code=404" header asks for, so clients can
+// exercise their error handling too.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	segments := strings.Split(path, "/")
+	var allowed []string
+	for _, op := range s.operations {
+		params, ok := op.match(segments)
+		if !ok {
+			continue
+		}
+		if op.method != r.Method {
+			allowed = append(allowed, op.method)
+			continue
+		}
+		status := op.success
+		if code, err := strconv.Atoi(strings.TrimPrefix(r.Header.Get("Prefer"), "code=")); err == nil {
+			if _, documented := op.responses[code]; !documented {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Prefer: status %d is not documented for %s %s", code, r.Method, r.URL.Path)})
+				return
+			}
+			status = code
+		}
+		w.Header().Set("X-Mock", "true")
+		schema := op.responses[status]
+		if schema == nil {
+			if status >= 300 && status < 400 {
+				w.Header().Set("Location", "/")
+			}
+			w.WriteHeader(status)
+			return
+		}
+		h := fnv.New64a()
+		h.Write([]byte(r.Method + " " + r.URL.RequestURI()))
+		g := &generator{schemas: s.schemas, params: params, fake: fakedata.New(h.Sum64(), r.Header.Get("Accept-Language"))}
+		if status >= 400 && isErrorMap(schema) {
+			writeJSON(w, status, map[string]string{"error": http.StatusText(status)})
+			return
+		}
+		writeJSON(w, status, g.value(schema, "", 0))
+		return
+	}
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+}
+
+func (op operation) params() int {
+	n := 0
+	for _, s := range op.segments {
+		if strings.HasPrefix(s, "{") {
+			n++
+		}
+	}
+	return n
+}
+
+// match reports whether the request path segments address op and returns
+// the path parameters.
+func (op operation) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(op.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, want := range op.segments {
+		if strings.HasPrefix(want, "{") && strings.HasSuffix(want, "}") {
+			params[want[1:len(want)-1]] = segments[i]
+		} else if want != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// isErrorMap reports whether schema is the {"error": "..."} body of the
+// handlers' failures, a map of strings.
+func isErrorMap(schema any) bool {
+	s, _ := schema.(map[string]any)
+	values, _ := s["additionalProperties"].(map[string]any)
+	return s["properties"] == nil && values["type"] == "string"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
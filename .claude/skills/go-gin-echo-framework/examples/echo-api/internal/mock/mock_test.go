@@ -0,0 +1,117 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const spec = `{
+  "paths": {
+    "/widgets": {
+      "get": {"responses": {"200": {"content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Widget"}}}}}}}
+    },
+    "/widgets/{id}": {
+      "get": {"responses": {
+        "200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Widget"}}}},
+        "404": {"content": {"application/json": {"schema": {"type": "object", "additionalProperties": {"type": "string"}}}}}
+      }},
+      "delete": {"responses": {"204": {"description": "No Content"}}}
+    }
+  },
+  "components": {"schemas": {
+    "Widget": {"type": "object", "required": ["id"], "properties": {
+      "id": {"type": "string"},
+      "owner_email": {"type": "string"},
+      "created_at": {"type": "string", "format": "date-time"},
+      "size": {"type": "integer"},
+      "color": {"type": "string", "enum": ["red", "green"]},
+      "parent": {"$ref": "#/components/schemas/Widget"}
+    }}
+  }}
+}`
+
+func serve(t *testing.T, s *Server, method, target string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestServerGeneratesSchemaValidResponses(t *testing.T) {
+	s, err := New([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Operations() != 3 {
+		t.Fatalf("Operations() = %d, want 3", s.Operations())
+	}
+
+	w := serve(t, s, http.MethodGet, "/widgets/w-42")
+	if w.Code != http.StatusOK || w.Header().Get("X-Mock") != "true" {
+		t.Fatalf("GET /widgets/w-42 = %d %v", w.Code, w.Header())
+	}
+	var widget struct {
+		ID         string    `json:"id"`
+		OwnerEmail string    `json:"owner_email"`
+		CreatedAt  time.Time `json:"created_at"`
+		Size       *int      `json:"size"`
+		Color      string    `json:"color"`
+		Parent     *struct {
+			ID string `json:"id"`
+		} `json:"parent"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &widget); err != nil {
+		t.Fatalf("body %s: %v", w.Body, err)
+	}
+	if widget.ID != "w-42" {
+		t.Errorf("id = %q, want the path parameter", widget.ID)
+	}
+	if widget.Parent == nil || widget.Parent.ID == "" || widget.Parent.ID == "w-42" {
+		t.Errorf("parent = %+v, want a generated nested widget", widget.Parent)
+	}
+	if widget.Size == nil || widget.CreatedAt.IsZero() || (widget.Color != "red" && widget.Color != "green") {
+		t.Errorf("widget = %s", w.Body)
+	}
+	if again := serve(t, s, http.MethodGet, "/widgets/w-42"); again.Body.String() != w.Body.String() {
+		t.Error("the same URL got different data")
+	}
+
+	var list []map[string]any
+	if w := serve(t, s, http.MethodGet, "/widgets/"); json.Unmarshal(w.Body.Bytes(), &list) != nil || len(list) == 0 {
+		t.Errorf("GET /widgets/ = %d %s, want a non-empty array", w.Code, w.Body)
+	}
+}
+
+func TestServerStatuses(t *testing.T) {
+	s, err := New([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method, target, prefer string
+		want                   int
+		body                   string
+	}{
+		{http.MethodDelete, "/widgets/1", "", http.StatusNoContent, ""},
+		{http.MethodGet, "/widgets/1", "code=404", http.StatusNotFound, `{"error":"Not Found"}` + "\n"},
+		{http.MethodGet, "/widgets/1", "code=500", http.StatusBadRequest, ""},
+		{http.MethodPost, "/widgets/1", "", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/gadgets", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(t, s, tt.method, tt.target, "Prefer", tt.prefer)
+		if w.Code != tt.want || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s %s (Prefer %q) = %d %s, want %d %s", tt.method, tt.target, tt.prefer, w.Code, w.Body, tt.want, tt.body)
+		}
+	}
+	if w := serve(t, s, http.MethodPost, "/widgets/1"); w.Header().Get("Allow") != "DELETE, GET" {
+		t.Errorf("Allow = %q", w.Header().Get("Allow"))
+	}
+}
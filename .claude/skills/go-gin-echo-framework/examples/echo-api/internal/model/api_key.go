@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// APIKey is a machine client's credential. Only a hash of the secret part
+// is stored; like Credential it is never served as is.
+type APIKey struct {
+	ID        string    `json:"id" bson:"_id"`
+	Owner     string    `json:"owner" bson:"owner"` // account ID the key authenticates as
+	Name      string    `json:"name" bson:"name"`
+	Hash      string    `json:"hash" bson:"hash"` // hex SHA-256 of the secret
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	TenantID  string    `json:"tenant_id" bson:"tenant_id"` // the tenant the key was created in, the only one it can access
+}
+
+func (k APIKey) GetID() string { return k.ID }
+
+func (k *APIKey) SetID(id string) { k.ID = id }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Credential is the password login of one account, keyed by its normalized
+// email. It is stored alongside the other resources but never served.
+type Credential struct {
+	ID             string    `json:"id" bson:"_id"`          // lower-cased email
+	Subject        string    `json:"subject" bson:"subject"` // account ID that tokens are issued for
+	PasswordHash   string    `json:"password_hash" bson:"password_hash"`
+	FailedAttempts int       `json:"failed_attempts" bson:"failed_attempts"` // consecutive, reset on success
+	LockedUntil    time.Time `json:"locked_until" bson:"locked_until"`
+	TenantID       string    `json:"tenant_id" bson:"tenant_id"` // the only tenant the account can log in to; "" without tenancy
+}
+
+func (c Credential) GetID() string { return c.ID }
+
+func (c *Credential) SetID(id string) { c.ID = id }
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Entity is implemented by every model served through the generic CRUD stack
+// (repository.CrudRepository, service.CrudService and handler.CrudHandler).
+type Entity interface {
+	GetID() string
+}
+
+// EntityPtr is the pointer form of an Entity, through which its ID is assigned.
+type EntityPtr[T any] interface {
+	*T
+	Entity
+	SetID(id string)
+}
+
+// Timestamped is implemented by models that record when they last changed.
+// The CRUD service stamps them on every write and the handlers report the
+// time as Last-Modified, answering conditional GETs with 304 Not Modified.
+type Timestamped interface {
+	LastModified() time.Time
+}
+
+// TimestampedPtr is the pointer form of a Timestamped model, through which
+// the service stamps it.
+type TimestampedPtr interface {
+	Timestamped
+	Touch(t time.Time)
+}
+
+// Created is implemented by models that record when they were created. The
+// CRUD service stamps them on create and keeps the time on every update.
+type Created interface {
+	Created() time.Time
+}
+
+// CreatedPtr is the pointer form of a Created model, through which the
+// service stamps it.
+type CreatedPtr interface {
+	Created
+	SetCreated(t time.Time)
+}
+
+// Tenanted is implemented by models that belong to a tenant. With column
+// isolation (see repository.NewColumnTenantRepository) the tenant is stored
+// with each item and scopes every read and write; with either isolation,
+// repository.NewTenantAuditRepository checks it.
+type Tenanted interface {
+	Tenant() string
+}
+
+// TenantedPtr is the pointer form of a Tenanted model, through which the
+// repository stamps it.
+type TenantedPtr interface {
+	Tenanted
+	SetTenant(id string)
+}
+
+// Owned is implemented by models that belong to the account that created
+// them, such as the drafts of a guest (see auth.GuestService). The CRUD
+// service stamps them on create, keeps the owner on every update, and
+// hands them to another account with Transfer.
+type Owned interface {
+	Owner() string
+}
+
+// OwnedPtr is the pointer form of an Owned model, through which the service
+// stamps it.
+type OwnedPtr interface {
+	Owned
+	SetOwner(id string)
+}
+
+// Extensible is implemented by models carrying custom fields, which
+// tenants define (see package custom). service.Extended checks them on
+// every create and update.
+type Extensible interface {
+	CustomFields() Attributes
+}
+
+// ExtensiblePtr is the pointer form of an Extensible model, through which
+// the service stores the checked fields.
+type ExtensiblePtr interface {
+	Extensible
+	SetCustomFields(a Attributes)
+}
+
+// KeepReadOnly copies the fields of src tagged readonly:"true", which the
+// server sets, onto dst, so that a request body decoded onto dst cannot
+// set them.
+func KeepReadOnly[T any](dst, src *T) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	if d.Kind() != reflect.Struct {
+		return
+	}
+	for _, f := range reflect.VisibleFields(d.Type()) {
+		if f.IsExported() && f.Tag.Get("readonly") == "true" {
+			d.FieldByIndex(f.Index).Set(s.FieldByIndex(f.Index))
+		}
+	}
+}
+
+// Attributes are the values of custom fields, by name. SQL stores them as
+// a JSON object, MongoDB as a subdocument.
+type Attributes map[string]any
+
+// Value encodes a as a JSON object, {} when empty.
+func (a Attributes) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// UnmarshalJSON replaces a rather than adding to it, so that an update
+// decoded onto a stored item sets exactly the fields it sends.
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*a = m
+	return nil
+}
+
+// Scan decodes a JSON object, leaving a nil when it is empty.
+func (a *Attributes) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("model: cannot scan %T into Attributes", src)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if len(m) == 0 {
+		m = nil
+	}
+	*a = m
+	return nil
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Passkey is a WebAuthn credential an account logs in with instead of its
+// password (see auth.PasskeyService). Its ID is the credential ID,
+// base64url-encoded; like Credential it is never served as is.
+type Passkey struct {
+	ID         string    `json:"id" bson:"_id"`
+	Subject    string    `json:"subject" bson:"subject"`       // account ID that tokens are issued for
+	Name       string    `json:"name" bson:"name"`             // given at registration, e.g. "laptop"
+	PublicKey  string    `json:"public_key" bson:"public_key"` // base64url COSE_Key
+	SignCount  int64     `json:"sign_count" bson:"sign_count"` // of the last login, which the next must exceed
+	Transports string    `json:"transports" bson:"transports"` // comma-separated, as the authenticator reported them
+	TenantID   string    `json:"tenant_id" bson:"tenant_id"`   // the tenant it was registered in, the only one it logs in to
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" bson:"last_used_at"` // zero until the first login
+}
+
+func (p Passkey) GetID() string { return p.ID }
+
+func (p *Passkey) SetID(id string) { p.ID = id }
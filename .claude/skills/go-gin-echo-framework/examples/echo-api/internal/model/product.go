@@ -1,7 +1,68 @@
 package model
 
+import (
+	"math"
+	"time"
+)
+
 type Product struct {
-	ID    string  `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	ID        string     `json:"id" bson:"_id" readonly:"true"` // assigned by the service on create
+	Name      string     `json:"name" bson:"name" validate:"required"`
+	Price     float64    `json:"price" bson:"price" validate:"gte=0"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at" readonly:"true"`                   // set by the service on create
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at" readonly:"true"`                   // set by the service on every write
+	TenantID  string     `json:"tenant_id,omitempty" bson:"tenant_id,omitempty" readonly:"true"` // set by the repository when tenancy is enabled
+	OwnerID   string     `json:"owner_id,omitempty" bson:"owner_id,omitempty" readonly:"true"`   // the account that created it, set by the service on create
+	Custom    Attributes `json:"custom,omitempty" bson:"custom,omitempty"`                       // see GET /products/schema
+}
+
+func (p Product) GetID() string { return p.ID }
+
+func (p *Product) SetID(id string) { p.ID = id }
+
+func (p Product) LastModified() time.Time { return p.UpdatedAt }
+
+func (p *Product) Touch(t time.Time) { p.UpdatedAt = t }
+
+func (p Product) Created() time.Time { return p.CreatedAt }
+
+func (p *Product) SetCreated(t time.Time) { p.CreatedAt = t }
+
+func (p Product) Tenant() string { return p.TenantID }
+
+func (p *Product) SetTenant(id string) { p.TenantID = id }
+
+func (p Product) Owner() string { return p.OwnerID }
+
+func (p *Product) SetOwner(id string) { p.OwnerID = id }
+
+func (p Product) CustomFields() Attributes { return p.Custom }
+
+func (p *Product) SetCustomFields(a Attributes) { p.Custom = a }
+
+// ProductV2 is a Product as version 2 of the HTTP API represents it: the
+// price is a whole number of cents, price_cents, instead of a fraction.
+// Version 2 handlers convert with Product.V2 and ProductV2.V1 and share the
+// Product service, hooks and storage with version 1.
+type ProductV2 struct {
+	ID         string     `json:"id" readonly:"true"`
+	Name       string     `json:"name" validate:"required"`
+	PriceCents int64      `json:"price_cents" validate:"gte=0"`
+	CreatedAt  time.Time  `json:"created_at" readonly:"true"`
+	UpdatedAt  time.Time  `json:"updated_at" readonly:"true"`
+	Custom     Attributes `json:"custom,omitempty"`
+}
+
+func (p Product) V2() ProductV2 {
+	return ProductV2{ID: p.ID, Name: p.Name, PriceCents: int64(math.Round(p.Price * 100)), CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, Custom: p.Custom}
 }
+
+func (p ProductV2) V1() Product {
+	return Product{ID: p.ID, Name: p.Name, Price: float64(p.PriceCents) / 100, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, Custom: p.Custom}
+}
+
+func (p ProductV2) GetID() string { return p.ID }
+
+func (p *ProductV2) SetID(id string) { p.ID = id }
+
+func (p ProductV2) LastModified() time.Time { return p.UpdatedAt }
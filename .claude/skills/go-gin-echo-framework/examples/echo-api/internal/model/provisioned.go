@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Provisioned is an account an identity provider manages over SCIM (see
+// package scim). Its ID is the ID of the account; the credential of its
+// email, if any, is how it logs in.
+type Provisioned struct {
+	ID          string    `json:"id" bson:"_id"`
+	UserName    string    `json:"user_name" bson:"user_name"`     // unique per tenant, compared case-insensitively
+	ExternalID  string    `json:"external_id" bson:"external_id"` // the provider's own ID
+	GivenName   string    `json:"given_name" bson:"given_name"`
+	FamilyName  string    `json:"family_name" bson:"family_name"`
+	DisplayName string    `json:"display_name" bson:"display_name"`
+	Email       string    `json:"email" bson:"email"`   // lower-cased; "" without one
+	Active      bool      `json:"active" bson:"active"` // false refuses the account's logins
+	TenantID    string    `json:"tenant_id" bson:"tenant_id"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+func (p Provisioned) GetID() string { return p.ID }
+
+func (p *Provisioned) SetID(id string) { p.ID = id }
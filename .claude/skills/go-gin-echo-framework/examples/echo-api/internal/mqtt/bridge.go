@@ -0,0 +1,239 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/rpc"
+	"github.com/your-username/echo-api/internal/secret"
+)
+
+// Topics, relative to Options.TopicPrefix:
+//
+//	events/<entity>/<op>                   change events, published by the bridge
+//	tenants/<tenant>/events/<entity>/<op>  change events of a tenant, with tenancy
+//	clients/<client>/commands              JSON-RPC commands, published by clients
+//	clients/<client>/responses             JSON-RPC responses, published by the bridge
+//
+// The broker's ACLs should restrict each client to its own clients/<client>/#
+// subtree, and with tenancy to the tenants/<tenant>/# subtree of its tenant;
+// the bridge additionally checks the per-client token in every command.
+const (
+	eventsTopic   = "events"
+	tenantsTopic  = "tenants"
+	clientsTopic  = "clients"
+	commandsLeaf  = "commands"
+	responsesLeaf = "responses"
+)
+
+const (
+	qos            = 1
+	publishTimeout = 5 * time.Second
+	commandTimeout = 10 * time.Second
+)
+
+type Options struct {
+	BrokerURL   secret.Secret            // e.g. tcp://localhost:1883 or ssl://broker:8883; may include user:password
+	ClientID    string                   // MQTT client id of the bridge itself
+	TopicPrefix string                   // e.g. "echo-api"
+	Clients     map[string]secret.Secret // client id -> token accepted in its commands
+}
+
+// Envelope is the payload of a command message. Request is a JSON-RPC 2.0
+// request or batch, dispatched to the same methods as POST /rpc.
+type Envelope struct {
+	Token   string          `json:"token"`
+	Request json.RawMessage `json:"request"`
+}
+
+// Event is the payload of a change event message.
+type Event struct {
+	Entity string     `json:"entity"`
+	Op     changes.Op `json:"op"`
+	ID     string     `json:"id"`
+	Seq    uint64     `json:"seq"`
+	At     time.Time  `json:"at"`
+}
+
+// Bridge connects the service layer to an MQTT broker: it publishes entity
+// change events and executes authenticated command messages.
+type Bridge struct {
+	client  paho.Client
+	opts    Options
+	rpc     *rpc.Server
+	tokens  map[string][32]byte
+	ctx     context.Context // cancelled by Close
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	closeMu sync.Once
+}
+
+// New connects to the broker and subscribes to command topics. Commands are
+// dispatched to server; call Publish to stream a change feed.
+func New(opts Options, server *rpc.Server) (*Bridge, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bridge{opts: opts, rpc: server, tokens: map[string][32]byte{}, ctx: ctx, cancel: cancel}
+	for id, token := range opts.Clients {
+		b.tokens[id] = sha256.Sum256([]byte(token.Reveal()))
+	}
+
+	commands := b.topic(clientsTopic, "+", commandsLeaf)
+	co := paho.NewClientOptions().
+		AddBroker(opts.BrokerURL.Reveal()).
+		SetClientID(opts.ClientID).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("WARNING: mqtt connection lost: %v", err)
+		}).
+		SetOnConnectHandler(func(c paho.Client) {
+			// (Re)subscribe on every connect; the session may not have persisted.
+			tok := c.Subscribe(commands, qos, func(_ paho.Client, m paho.Message) {
+				b.handleCommand(m)
+			})
+			if tok.WaitTimeout(publishTimeout) && tok.Error() != nil {
+				log.Printf("WARNING: mqtt subscribe %s: %v", commands, tok.Error())
+			}
+		})
+
+	b.client = paho.NewClient(co)
+	tok := b.client.Connect()
+	if !tok.WaitTimeout(publishTimeout) {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to mqtt broker: timed out after %s", publishTimeout)
+	}
+	if err := tok.Error(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to mqtt broker: %w", err)
+	}
+	return b, nil
+}
+
+// Publish streams every change recorded in feed to events/<entity>/<op>,
+// or to the events topic of its tenant, until the bridge or the feed is
+// closed. Changes are read from the current
+// position; history is not replayed.
+func (b *Bridge) Publish(entity string, feed *changes.Feed) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		token := feed.Token()
+		for {
+			pending, next, err := feed.WaitAll(b.ctx, token)
+			if errors.Is(err, changes.ErrTokenExpired) {
+				log.Printf("WARNING: mqtt bridge fell behind the %s change feed; some events were not published", entity)
+				token = feed.Token()
+				continue
+			}
+			if err != nil {
+				log.Printf("WARNING: mqtt bridge stopped publishing %s events: %v", entity, err)
+				return
+			}
+			// Wait has no deadline here, so an empty result means the bridge or feed was closed.
+			if len(pending) == 0 {
+				return
+			}
+			for _, c := range pending {
+				b.publishEvent(entity, c)
+			}
+			token = next
+		}
+	}()
+}
+
+func (b *Bridge) publishEvent(entity string, c changes.Change) {
+	payload, err := json.Marshal(Event{Entity: entity, Op: c.Op, ID: c.ID, Seq: c.Seq, At: c.At})
+	if err != nil {
+		log.Printf("WARNING: mqtt encode event: %v", err)
+		return
+	}
+	topic := []string{eventsTopic, entity, string(c.Op)}
+	if c.Tenant != "" {
+		topic = append([]string{tenantsTopic, c.Tenant}, topic...)
+	}
+	b.publish(b.topic(topic...), payload)
+}
+
+func (b *Bridge) handleCommand(m paho.Message) {
+	client, ok := b.commandClient(m.Topic())
+	if !ok {
+		return
+	}
+	reply := b.topic(clientsTopic, client, responsesLeaf)
+
+	var env Envelope
+	if err := json.Unmarshal(m.Payload(), &env); err != nil || len(env.Request) == 0 {
+		b.publish(reply, rpc.ErrorResponse(rpc.NewError(rpc.CodeParseError, "Parse error")))
+		return
+	}
+	if !b.authenticate(client, env.Token) {
+		log.Printf("WARNING: mqtt rejected command from client %q: invalid token", client)
+		b.publish(reply, rpc.ErrorResponse(rpc.NewError(rpc.CodeUnauthorized, "Unauthorized")))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, commandTimeout)
+	defer cancel()
+	if out, ok := b.rpc.Handle(ctx, env.Request); ok {
+		b.publish(reply, out)
+	}
+}
+
+// commandClient extracts <client> from <prefix>/clients/<client>/commands.
+func (b *Bridge) commandClient(topic string) (string, bool) {
+	rest, ok := strings.CutPrefix(topic, b.topic(clientsTopic)+"/")
+	if !ok {
+		return "", false
+	}
+	client, leaf, ok := strings.Cut(rest, "/")
+	return client, ok && leaf == commandsLeaf && client != ""
+}
+
+func (b *Bridge) authenticate(client, token string) bool {
+	want, ok := b.tokens[client]
+	got := sha256.Sum256([]byte(token))
+	return ok && subtle.ConstantTimeCompare(want[:], got[:]) == 1
+}
+
+func (b *Bridge) publish(topic string, payload []byte) {
+	tok := b.client.Publish(topic, qos, false, payload)
+	if !tok.WaitTimeout(publishTimeout) {
+		log.Printf("WARNING: mqtt publish %s: timed out", topic)
+		return
+	}
+	if err := tok.Error(); err != nil {
+		log.Printf("WARNING: mqtt publish %s: %v", topic, err)
+	}
+}
+
+func (b *Bridge) topic(parts ...string) string {
+	return strings.Join(append([]string{b.opts.TopicPrefix}, parts...), "/")
+}
+
+// Ping reports whether the broker connection is up; it satisfies health.Pinger.
+func (b *Bridge) Ping(context.Context) error {
+	if !b.client.IsConnectionOpen() {
+		return errors.New("not connected to mqtt broker")
+	}
+	return nil
+}
+
+// Close stops publishing, waits for in-flight events and disconnects.
+func (b *Bridge) Close() error {
+	b.closeMu.Do(func() {
+		b.cancel()
+		b.wg.Wait()
+		b.client.Disconnect(250)
+	})
+	return nil
+}
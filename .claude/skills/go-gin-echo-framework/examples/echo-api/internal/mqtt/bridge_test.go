@@ -0,0 +1,172 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/rpc"
+)
+
+// fakeClient records what the bridge publishes instead of sending it to a
+// broker.
+type fakeClient struct {
+	paho.Client
+	published map[string][]byte
+}
+
+func (c *fakeClient) Publish(topic string, _ byte, _ bool, payload any) paho.Token {
+	c.published[topic] = payload.([]byte)
+	return doneToken{}
+}
+
+type doneToken struct{}
+
+func (doneToken) Wait() bool                     { return true }
+func (doneToken) WaitTimeout(time.Duration) bool { return true }
+func (doneToken) Done() <-chan struct{}          { return nil }
+func (doneToken) Error() error                   { return nil }
+
+type message struct {
+	paho.Message
+	topic   string
+	payload []byte
+}
+
+func (m message) Topic() string   { return m.topic }
+func (m message) Payload() []byte { return m.payload }
+
+// newBridge returns a bridge under prefix app whose only client, sensor-1,
+// sends token s3cret, and whose RPC server answers ping with pong.
+func newBridge(t *testing.T) (*Bridge, *fakeClient) {
+	t.Helper()
+	server := rpc.NewServer()
+	server.Register("ping", func(context.Context, json.RawMessage) (any, error) { return "pong", nil })
+	client := &fakeClient{published: map[string][]byte{}}
+	b := &Bridge{
+		client: client,
+		opts:   Options{TopicPrefix: "app"},
+		rpc:    server,
+		tokens: map[string][32]byte{"sensor-1": sha256.Sum256([]byte("s3cret"))},
+		ctx:    context.Background(),
+	}
+	return b, client
+}
+
+func TestAuthenticate(t *testing.T) {
+	b, _ := newBridge(t)
+	tests := []struct {
+		client, token string
+		want          bool
+	}{
+		{"sensor-1", "s3cret", true},
+		{"sensor-1", "s3cret ", false},
+		{"sensor-1", "", false},
+		{"sensor-2", "s3cret", false},
+		{"sensor-2", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := b.authenticate(tt.client, tt.token); got != tt.want {
+			t.Errorf("authenticate(%q, %q) = %v, want %v", tt.client, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestCommandClient(t *testing.T) {
+	b, _ := newBridge(t)
+	tests := []struct {
+		topic  string
+		client string
+		ok     bool
+	}{
+		{"app/clients/sensor-1/commands", "sensor-1", true},
+		{"app/clients/sensor-1/responses", "", false},
+		{"app/clients/sensor-1/commands/extra", "", false},
+		{"app/clients/sensor-1/other/commands", "", false},
+		{"app/clients//commands", "", false},
+		{"app/clients/sensor-1", "", false},
+		{"other/clients/sensor-1/commands", "", false},
+		{"app/tenants/acme/clients/sensor-1/commands", "", false},
+		{"clients/sensor-1/commands", "", false},
+	}
+	for _, tt := range tests {
+		client, ok := b.commandClient(tt.topic)
+		if ok != tt.ok || ok && client != tt.client {
+			t.Errorf("commandClient(%q) = %q, %v, want %q, %v", tt.topic, client, ok, tt.client, tt.ok)
+		}
+	}
+}
+
+func TestHandleCommand(t *testing.T) {
+	ping := `{"jsonrpc": "2.0", "method": "ping", "id": 1}`
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+		reply   string // "" when nothing is published
+		code    int    // of the error replied, 0 for a result
+	}{
+		{"authenticated", "app/clients/sensor-1/commands", `{"token": "s3cret", "request": ` + ping + `}`, "app/clients/sensor-1/responses", 0},
+		{"wrong token", "app/clients/sensor-1/commands", `{"token": "guess", "request": ` + ping + `}`, "app/clients/sensor-1/responses", rpc.CodeUnauthorized},
+		{"another client's token", "app/clients/sensor-2/commands", `{"token": "s3cret", "request": ` + ping + `}`, "app/clients/sensor-2/responses", rpc.CodeUnauthorized},
+		{"no request", "app/clients/sensor-1/commands", `{"token": "s3cret"}`, "app/clients/sensor-1/responses", rpc.CodeParseError},
+		{"not JSON", "app/clients/sensor-1/commands", `ping`, "app/clients/sensor-1/responses", rpc.CodeParseError},
+		{"not a command topic", "app/clients/sensor-1/responses", `{"token": "s3cret", "request": ` + ping + `}`, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, client := newBridge(t)
+			b.handleCommand(message{topic: tt.topic, payload: []byte(tt.payload)})
+			if tt.reply == "" {
+				if len(client.published) != 0 {
+					t.Fatalf("published %v", client.published)
+				}
+				return
+			}
+			out, ok := client.published[tt.reply]
+			if !ok || len(client.published) != 1 {
+				t.Fatalf("published %v, want a reply on %s", client.published, tt.reply)
+			}
+			var resp struct {
+				Result string
+				Error  *rpc.Error
+			}
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.code == 0 && (resp.Error != nil || resp.Result != "pong"):
+				t.Errorf("reply %s, want pong", out)
+			case tt.code != 0 && (resp.Error == nil || resp.Error.Code != tt.code):
+				t.Errorf("reply %s, want error %d", out, tt.code)
+			}
+		})
+	}
+}
+
+func TestPublishEvent(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		change changes.Change
+		topic  string
+	}{
+		{changes.Change{Op: changes.OpCreated, ID: "1", Seq: 1, At: at}, "app/events/product/created"},
+		{changes.Change{Op: changes.OpDeleted, ID: "2", Seq: 2, At: at, Tenant: "acme"}, "app/tenants/acme/events/product/deleted"},
+	}
+	for _, tt := range tests {
+		b, client := newBridge(t)
+		b.publishEvent("product", tt.change)
+		var got Event
+		if err := json.Unmarshal(client.published[tt.topic], &got); err != nil || len(client.published) != 1 {
+			t.Fatalf("published %v, want an event on %s", client.published, tt.topic)
+		}
+		if want := (Event{Entity: "product", Op: tt.change.Op, ID: tt.change.ID, Seq: tt.change.Seq, At: at}); got != want {
+			t.Errorf("event = %+v, want %+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package notify posts operational events to the incoming webhooks of chat
+// services that accept Slack's {"text": ...} messages (Slack, Mattermost,
+// Rocket.Chat and others): deploys, spikes in the rate of server errors and
+// growth of the outbox's dead letters. Each event type is posted to a
+// webhook of its own, as a message rendered from a text/template, at most
+// once per Route.Every; the messages dropped in between are counted in the
+// next one.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/secret"
+)
+
+// The event types, with the fields their templates get.
+const (
+	Deploy      = "deploy"       // Version, Environment, Host
+	ErrorRate   = "error_rate"   // Rate (0 to 1), Errors, Requests, Window
+	DeadLetters = "dead_letters" // Added, Total
+)
+
+// Templates are the default templates of the event types.
+var Templates = map[string]string{
+	Deploy:      "Deployed {{.Version}} to {{.Environment}} on {{.Host}}",
+	ErrorRate:   "Error rate at {{percent .Rate}}: {{.Errors}} of {{.Requests}} requests failed with a 5xx in the last {{.Window}}",
+	DeadLetters: "{{.Added}} more events dead-lettered, {{.Total}} waiting; see GET /admin/dead-letters",
+}
+
+var funcs = template.FuncMap{
+	"percent": func(rate float64) string { return fmt.Sprintf("%.1f%%", rate*100) },
+}
+
+// Options configure where each event type is posted and what is a spike.
+type Options struct {
+	Version   string           `yaml:"version"`    // announced by the deploy event; empty is the VCS revision stamped into the binary
+	Events    map[string]Route `yaml:"events"`     // event type -> where it is posted; types left out are not
+	ErrorRate RateOptions      `yaml:"error_rate"` // checked by the notify job, over the time since its last run
+}
+
+// Route is where and how often one event type is posted.
+type Route struct {
+	URL      secret.Secret `yaml:"url"`      // of the incoming webhook, which is its credential
+	Template string        `yaml:"template"` // text/template of the message; empty is the type's default of Templates
+	Every    time.Duration `yaml:"every"`    // at most one message per interval; 0 posts every one
+}
+
+// RateOptions set when the rate of server errors is a spike.
+type RateOptions struct {
+	Threshold   float64 `yaml:"threshold"`    // share of responses that are 5xx, e.g. 0.05
+	MinRequests int     `yaml:"min_requests"` // fewer requests are never a spike
+}
+
+// Validate checks the routes and their templates.
+func (o Options) Validate() error {
+	var errs []error
+	for _, event := range sortedKeys(o.Events) {
+		r := o.Events[event]
+		if _, ok := Templates[event]; !ok {
+			errs = append(errs, fmt.Errorf("events.%s: event types are %s", event, strings.Join(sortedKeys(Templates), ", ")))
+			continue
+		}
+		if u, err := url.Parse(r.URL.Reveal()); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("events.%s.url must be an http or https URL", event))
+		}
+		if _, err := r.template(event); err != nil {
+			errs = append(errs, fmt.Errorf("events.%s.template: %v", event, err))
+		}
+		if r.Every < 0 {
+			errs = append(errs, fmt.Errorf("events.%s.every must not be negative", event))
+		}
+	}
+	if o.ErrorRate.Threshold <= 0 || o.ErrorRate.Threshold > 1 {
+		errs = append(errs, errors.New("error_rate.threshold must be above 0 and at most 1"))
+	}
+	if o.ErrorRate.MinRequests < 1 {
+		errs = append(errs, errors.New("error_rate.min_requests must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
+
+func (r Route) template(event string) (*template.Template, error) {
+	text := r.Template
+	if text == "" {
+		text = Templates[event]
+	}
+	return template.New(event).Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Version returns configured, or else the VCS revision of the binary, or
+// else its module version.
+func Version(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && s.Value != "" {
+			return s.Value
+		}
+	}
+	return info.Main.Version
+}
+
+type route struct {
+	url   string
+	tmpl  *template.Template
+	every time.Duration
+
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+}
+
+// Notifier posts the event types of its Options. It is safe for concurrent
+// use.
+type Notifier struct {
+	client *http.Client
+	clock  clock.Clock
+	routes map[string]*route
+}
+
+// New returns a Notifier of opts, which must be valid, posting with client
+// (nil is http.DefaultClient).
+func New(client *http.Client, clk clock.Clock, opts Options) (*Notifier, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	n := &Notifier{client: client, clock: clock.OrSystem(clk), routes: map[string]*route{}}
+	for event, r := range opts.Events {
+		tmpl, err := r.template(event)
+		if err != nil {
+			return nil, fmt.Errorf("notify: %s: %w", event, err)
+		}
+		n.routes[event] = &route{url: r.URL.Reveal(), tmpl: tmpl, every: r.Every}
+	}
+	return n, nil
+}
+
+// Posts reports whether event is posted anywhere.
+func (n *Notifier) Posts(event string) bool {
+	_, ok := n.routes[event]
+	return ok
+}
+
+// Notify posts event with the fields of its template, unless event is not
+// posted or was posted less than its Route.Every ago. Its template also
+// gets Suppressed, the messages dropped since the last one, which are
+// mentioned after the message.
+func (n *Notifier) Notify(ctx context.Context, event string, fields map[string]any) error {
+	r, ok := n.routes[event]
+	if !ok {
+		return nil
+	}
+	now := n.clock.Now()
+	r.mu.Lock()
+	if r.every > 0 && !r.last.IsZero() && now.Sub(r.last) < r.every {
+		r.suppressed++
+		r.mu.Unlock()
+		return nil
+	}
+	suppressed := r.suppressed
+	r.last, r.suppressed = now, 0
+	r.mu.Unlock()
+
+	data := map[string]any{"Suppressed": suppressed}
+	for k, v := range fields {
+		data[k] = v
+	}
+	var text strings.Builder
+	if err := r.tmpl.Execute(&text, data); err != nil {
+		return fmt.Errorf("notify %s: %w", event, err)
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&text, " (%d more since the last message)", suppressed)
+	}
+	body, _ := json.Marshal(map[string]string{"text": text.String()})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notify %s: %w", event, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// The URL is a credential: report the error without it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("notify %s: %w", event, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("notify %s: webhook answered %s", event, resp.Status)
+	}
+	return nil
+}
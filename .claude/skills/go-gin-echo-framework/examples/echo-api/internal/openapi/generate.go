@@ -0,0 +1,744 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generate builds an OpenAPI 3.0 document from the swag-style annotations in
+// the module rooted at root: general info (title, version, description,
+// BasePath) from root/main.go, operations from every function under
+// root/internal carrying a Router annotation, and component schemas from the
+// Go struct types those operations reference.
+//
+// `@Security <scheme>` requires a scheme declared in main.go with
+// `@securityDefinitions.bearer <scheme>`; `@Security none` marks an
+// operation public (an empty security list).
+func Generate(root string) ([]byte, error) {
+	g := &generator{
+		fset:    token.NewFileSet(),
+		types:   map[string]typeDecl{},
+		schemas: map[string]any{},
+		paths:   map[string]map[string]any{},
+		schemes: map[string]any{},
+	}
+
+	mainFile, err := parser.ParseFile(g.fset, filepath.Join(root, "main.go"), nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse main.go: %w", err)
+	}
+	info := g.info(mainFile)
+
+	var files []*ast.File
+	err = filepath.WalkDir(filepath.Join(root, "internal"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		f, err := parser.ParseFile(g.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		files = append(files, f)
+		g.collectTypes(f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Doc != nil {
+				g.operation(f.Name.Name, fn)
+			}
+		}
+	}
+	for _, u := range g.securityUses {
+		if g.schemes[u.scheme] == nil {
+			g.errorf(u.pos, "@Security %s is not declared with @securityDefinitions.bearer in main.go", u.scheme)
+		}
+	}
+	if err := errors.Join(g.errs...); err != nil {
+		return nil, err
+	}
+
+	components := map[string]any{"schemas": g.schemas}
+	if len(g.schemes) > 0 {
+		components["securitySchemes"] = g.schemes
+	}
+	doc := map[string]any{
+		"openapi":    "3.0.3",
+		"info":       info,
+		"paths":      g.paths,
+		"components": components,
+	}
+	if base := g.basePath; base != "" && base != "/" {
+		doc["servers"] = []any{map[string]any{"url": base}}
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+type typeDecl struct {
+	pkg    string
+	params []string
+	expr   ast.Expr
+}
+
+type generator struct {
+	fset     *token.FileSet
+	types    map[string]typeDecl // "pkg.Name" -> declaration
+	schemas  map[string]any      // components.schemas
+	paths    map[string]map[string]any
+	basePath string
+	schemes  map[string]any // components.securitySchemes
+	errs     []error
+
+	securityUses []securityUse // checked against schemes once all files are read
+}
+
+type securityUse struct {
+	pos    token.Pos
+	scheme string
+}
+
+func (g *generator) errorf(pos token.Pos, format string, args ...any) {
+	g.errs = append(g.errs, fmt.Errorf("%s: %s", g.fset.Position(pos), fmt.Sprintf(format, args...)))
+}
+
+// annotations returns the "@Key value" lines of a comment group.
+func annotations(groups ...*ast.CommentGroup) [][2]string {
+	var out [][2]string
+	for _, cg := range groups {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			line := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(line, "@") {
+				continue
+			}
+			key, value, _ := strings.Cut(line[1:], " ")
+			out = append(out, [2]string{key, strings.TrimSpace(value)})
+		}
+	}
+	return out
+}
+
+func (g *generator) info(f *ast.File) map[string]any {
+	info := map[string]any{"title": "API", "version": "1.0"}
+	for _, a := range annotations(f.Comments...) {
+		switch a[0] {
+		case "title":
+			info["title"] = a[1]
+		case "version":
+			info["version"] = a[1]
+		case "description":
+			info["description"] = a[1]
+		case "BasePath":
+			g.basePath = a[1]
+		case "securityDefinitions.bearer":
+			g.schemes[a[1]] = map[string]any{"type": "http", "scheme": "bearer"}
+		}
+	}
+	return info
+}
+
+func (g *generator) collectTypes(f *ast.File) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			td := typeDecl{pkg: f.Name.Name, expr: ts.Type}
+			if ts.TypeParams != nil {
+				for _, field := range ts.TypeParams.List {
+					for _, name := range field.Names {
+						td.params = append(td.params, name.Name)
+					}
+				}
+			}
+			g.types[f.Name.Name+"."+ts.Name.Name] = td
+		}
+	}
+}
+
+func (g *generator) operation(pkg string, fn *ast.FuncDecl) {
+	anns := annotations(fn.Doc)
+	resources := 0
+	for _, a := range anns {
+		if a[0] == "Resource" {
+			g.resource(pkg, fn, a[1], anns)
+			resources++
+		}
+	}
+	if resources == 0 {
+		g.addOperation(pkg, fn, fn.Name.Name, anns)
+	}
+}
+
+// resource expands `@Resource <path> <model>` into the eight operations served
+// by handler.CrudHandler. Other annotations on fn (e.g. Tags) apply to all of
+// them. A handler mounted on several paths has one @Resource for each; the
+// operation IDs of a path under a version segment end in it, e.g.
+// GetUsersV2 for /api/v2/users.
+func (g *generator) resource(pkg string, fn *ast.FuncDecl, value string, anns [][2]string) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		g.errorf(fn.Pos(), "malformed @Resource %q", value)
+		return
+	}
+	path, typ := strings.TrimSuffix(fields[0], "/"), fields[1]
+	plural := path[strings.LastIndex(path, "/")+1:]
+	typeName := typ[strings.LastIndex(typ, ".")+1:]
+	singular := strings.ToLower(typeName)
+	pluralName := strings.ToUpper(plural[:1]) + plural[1:]
+	var version string
+	for _, segment := range strings.Split(path, "/") {
+		if n, ok := strings.CutPrefix(segment, "v"); ok && n != "" && strings.Trim(n, "0123456789") == "" {
+			version = "V" + n
+		}
+	}
+
+	var shared [][2]string
+	for _, a := range anns {
+		if a[0] != "Resource" {
+			shared = append(shared, a)
+		}
+	}
+	op := func(id string, lines ...string) {
+		all := append([][2]string{}, shared...)
+		for _, l := range lines {
+			key, v, _ := strings.Cut(l, " ")
+			all = append(all, [2]string{key, v})
+		}
+		g.addOperation(pkg, fn, id+version, all)
+	}
+	idParam := `Param id path string true "Resource ID"`
+	bodyParam := func(verb string) string {
+		return fmt.Sprintf(`Param %s body %s true "Resource object to %s"`, singular, typ, verb)
+	}
+	failure := func(code int) string { return fmt.Sprintf("Failure %d {object} map[string]string", code) }
+	fieldsParam := `Param fields query string false "Comma-separated JSON names of the fields to return, dotted to select inside nested objects, e.g. id,name; all when omitted"`
+	dryRun := `Param dry_run query bool false "Run the checks and return the result without storing anything"`
+	dryRunHeader := `Param X-Dry-Run header bool false "Same as dry_run, which takes precedence"`
+	op("Get"+pluralName,
+		"Summary Get all "+plural, "Description Get a list of all "+plural+"; custom.<name>=<value> keeps those whose custom field <name> (see "+path+"/schema) equals value", "Accept json", "Produce json,xml,csv,ndjson",
+		fieldsParam, "Success 200 {array} "+typ, failure(400), failure(500), "Router "+path+" [get]")
+	op("Stream"+pluralName,
+		"Summary Stream all "+plural, "Description Stream every "+singular+" as one line of JSON, without loading the whole list into memory; custom.<name>=<value> filters them like the list", "Accept json", "Produce ndjson",
+		fieldsParam, "Success 200 {array} "+typ, failure(400), failure(500), "Router "+path+"/stream [get]")
+	op("Get"+typeName+"Schema",
+		"Summary Get the custom fields of "+plural, "Description Get the custom fields "+plural+" carry in the caller's tenant, by name, with the values each accepts (custom_fields)", "Accept json", "Produce json",
+		"Success 200 {object} custom.Schema", failure(400), "Router "+path+"/schema [get]")
+	op("Get"+typeName+"ByID",
+		"Summary Get a "+singular+" by ID", "Description Get a single "+singular+" by its ID", "Accept json", "Produce json,xml,csv,ndjson",
+		idParam, fieldsParam, "Success 200 {object} "+typ, failure(400), failure(404), failure(500), "Router "+path+"/{id} [get]")
+	op("Create"+typeName,
+		"Summary Create a new "+singular, "Description Create a new "+singular+" with the provided data. The server assigns its ID and timestamps (the read-only fields); a body with an id is refused with 400", "Accept json", "Produce json",
+		bodyParam("create"), dryRun, dryRunHeader, "Success 201 {object} "+typ, failure(400), failure(402), failure(500), "Router "+path+" [post]")
+	op("Update"+typeName,
+		"Summary Update an existing "+singular, "Description Update a "+singular+" by ID with the provided data", "Accept json", "Produce json",
+		idParam, bodyParam("update"), dryRun, dryRunHeader, "Success 200 {object} "+typ, failure(400), failure(404), failure(500), "Router "+path+"/{id} [put]")
+	op("Delete"+typeName,
+		"Summary Delete a "+singular, "Description Delete a "+singular+" by its ID", "Accept json", "Produce json",
+		idParam, dryRun, dryRunHeader, `Success 204 "No Content"`, failure(404), failure(500), "Router "+path+"/{id} [delete]")
+	op("UndoDelete"+typeName,
+		"Summary Undo the delete of a "+singular, "Description Restore a "+singular+" deleted within the trash window (trash.window)", "Accept json", "Produce json",
+		idParam, "Success 200 {object} "+typ, failure(402), failure(404), failure(409), failure(500), "Router "+path+"/{id}/undo-delete [post]")
+}
+
+func (g *generator) addOperation(pkg string, fn *ast.FuncDecl, defaultID string, anns [][2]string) {
+	op := map[string]any{}
+	responses := map[string]any{}
+	var params []any
+	var path, method string
+	consumes := "application/json"
+	produces := []string{"application/json"}
+
+	for _, a := range anns {
+		key, value := a[0], a[1]
+		switch key {
+		case "Summary":
+			op["summary"] = value
+		case "Description":
+			if prev, ok := op["description"].(string); ok {
+				value = prev + "\n" + value
+			}
+			op["description"] = value
+		case "ID":
+			op["operationId"] = value
+		case "Tags":
+			var tags []string
+			for _, t := range strings.Split(value, ",") {
+				tags = append(tags, strings.TrimSpace(t))
+			}
+			op["tags"] = tags
+		case "Accept":
+			consumes = mimeType(value)
+		case "Produce":
+			produces = nil
+			for _, p := range strings.Split(value, ",") {
+				produces = append(produces, mimeType(strings.TrimSpace(p)))
+			}
+		case "Param":
+			g.param(pkg, fn, value, consumes, op, &params)
+		case "Success", "Failure":
+			types := produces
+			if key == "Failure" {
+				types = []string{"application/json"} // errors are always JSON
+			}
+			code, resp, ok := g.response(pkg, fn, value, types)
+			if ok {
+				responses[code] = resp
+			}
+		case "Security":
+			reqs, _ := op["security"].([]any)
+			if value != "none" {
+				reqs = append(reqs, map[string]any{value: []string{}})
+				g.securityUses = append(g.securityUses, securityUse{fn.Pos(), value})
+			}
+			op["security"] = append([]any{}, reqs...) // never nil: none encodes as []
+		case "Router":
+			p, m, ok := strings.Cut(value, " ")
+			method = strings.ToLower(strings.Trim(strings.TrimSpace(m), "[]"))
+			if !ok || method == "" {
+				g.errorf(fn.Pos(), "malformed @Router %q", value)
+				return
+			}
+			path = p
+		}
+	}
+	if path == "" {
+		return
+	}
+	if op["operationId"] == nil {
+		op["operationId"] = defaultID
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if len(responses) == 0 {
+		responses["default"] = map[string]any{"description": "Unspecified"}
+	}
+	op["responses"] = responses
+
+	if g.paths[path] == nil {
+		g.paths[path] = map[string]any{}
+	}
+	if _, dup := g.paths[path][method]; dup {
+		g.errorf(fn.Pos(), "duplicate operation %s %s", strings.ToUpper(method), path)
+		return
+	}
+	g.paths[path][method] = op
+}
+
+// param handles `name in type required "description"`. The formData
+// params of an operation are the properties of its form body.
+func (g *generator) param(pkg string, fn *ast.FuncDecl, value, consumes string, op map[string]any, params *[]any) {
+	fields, desc := splitDescription(value)
+	if len(fields) < 4 {
+		g.errorf(fn.Pos(), "malformed @Param %q", value)
+		return
+	}
+	name, in, typ := fields[0], fields[1], fields[2]
+	required, _ := strconv.ParseBool(fields[3])
+
+	if in == "body" {
+		schema, err := g.typeSchema(pkg, typ)
+		if err != nil {
+			g.errorf(fn.Pos(), "@Param %s: %v", name, err)
+			return
+		}
+		body := map[string]any{
+			"required": required,
+			"content":  map[string]any{consumes: map[string]any{"schema": schema}},
+		}
+		if desc != "" {
+			body["description"] = desc
+		}
+		op["requestBody"] = body
+		return
+	}
+	if in == "formData" {
+		// A property of the form body; a file is its binary content
+		body, _ := op["requestBody"].(map[string]any)
+		if body == nil {
+			schema := map[string]any{"type": "object", "properties": map[string]any{}}
+			body = map[string]any{"required": true, "content": map[string]any{consumes: map[string]any{"schema": schema}}}
+			op["requestBody"] = body
+		}
+		schema := body["content"].(map[string]any)[consumes].(map[string]any)["schema"].(map[string]any)
+		prop := primitive(typ)
+		if typ == "file" {
+			prop["format"] = "binary"
+		}
+		if desc != "" {
+			prop["description"] = desc
+		}
+		schema["properties"].(map[string]any)[name] = prop
+		if required {
+			list, _ := schema["required"].([]string)
+			schema["required"] = append(list, name)
+		}
+		return
+	}
+
+	p := map[string]any{
+		"name":     name,
+		"in":       in,
+		"required": required || in == "path",
+		"schema":   primitive(typ),
+	}
+	if desc != "" {
+		p["description"] = desc
+	}
+	*params = append(*params, p)
+}
+
+// response handles `code {object|array} Type "description"` and `code "description"`.
+func (g *generator) response(pkg string, fn *ast.FuncDecl, value string, mediaTypes []string) (string, map[string]any, bool) {
+	fields, desc := splitDescription(value)
+	if len(fields) == 0 {
+		g.errorf(fn.Pos(), "malformed response %q", value)
+		return "", nil, false
+	}
+	code := fields[0]
+	if desc == "" {
+		if n, err := strconv.Atoi(code); err == nil {
+			desc = http.StatusText(n)
+		}
+	}
+	resp := map[string]any{"description": desc}
+	if len(fields) >= 3 {
+		schema, err := g.typeSchema(pkg, fields[2])
+		if err != nil {
+			g.errorf(fn.Pos(), "response %s: %v", code, err)
+			return "", nil, false
+		}
+		if fields[1] == "{array}" {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		content := map[string]any{}
+		for _, t := range mediaTypes {
+			content[t] = map[string]any{"schema": schema}
+		}
+		resp["content"] = content
+	}
+	return code, resp, true
+}
+
+// splitDescription separates the whitespace-delimited fields of an annotation
+// from its trailing quoted description.
+func splitDescription(value string) ([]string, string) {
+	var desc string
+	if i := strings.Index(value, `"`); i >= 0 {
+		desc = strings.Trim(strings.TrimSpace(value[i:]), `"`)
+		value = value[:i]
+	}
+	return strings.Fields(value), desc
+}
+
+func mimeType(short string) string {
+	switch short {
+	case "json":
+		return "application/json"
+	case "xml":
+		return "application/xml"
+	case "csv":
+		return "text/csv"
+	case "ndjson":
+		return "application/x-ndjson"
+	case "plain":
+		return "text/plain"
+	case "event-stream":
+		return "text/event-stream"
+	case "zip":
+		return "application/zip"
+	case "mpfd":
+		return "multipart/form-data"
+	case "x-www-form-urlencoded":
+		return "application/x-www-form-urlencoded"
+	}
+	return short
+}
+
+func primitive(typ string) map[string]any {
+	switch typ {
+	case "int", "integer":
+		return map[string]any{"type": "integer"}
+	case "number":
+		return map[string]any{"type": "number"}
+	case "bool", "boolean":
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// typeSchema resolves a type as written in an annotation, e.g. model.User,
+// map[string]string or changes.Delta[model.User], relative to pkg.
+func (g *generator) typeSchema(pkg, typ string) (map[string]any, error) {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %q", typ)
+	}
+	return g.schema(pkg, expr, nil)
+}
+
+func (g *generator) schema(pkg string, expr ast.Expr, subst map[string]map[string]any) (map[string]any, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if s, ok := subst[t.Name]; ok {
+			return s, nil
+		}
+		if s, ok := builtin(t.Name); ok {
+			return s, nil
+		}
+		return g.ref(pkg, pkg+"."+t.Name, nil, subst)
+	case *ast.SelectorExpr:
+		x, _ := t.X.(*ast.Ident)
+		if x == nil {
+			return nil, fmt.Errorf("unsupported type %s", types.ExprString(expr))
+		}
+		switch name := x.Name + "." + t.Sel.Name; name {
+		case "time.Time":
+			return map[string]any{"type": "string", "format": "date-time"}, nil
+		case "time.Duration":
+			return map[string]any{"type": "integer", "format": "int64"}, nil
+		case "json.RawMessage":
+			return map[string]any{}, nil
+		case "http.Header":
+			return map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "array", "items": map[string]any{"type": "string"}}}, nil
+		default:
+			return g.ref(pkg, name, nil, subst)
+		}
+	case *ast.StarExpr:
+		return g.schema(pkg, t.X, subst)
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && id.Name == "byte" {
+			return map[string]any{"type": "string", "format": "byte"}, nil
+		}
+		items, err := g.schema(pkg, t.Elt, subst)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case *ast.MapType:
+		values, err := g.schema(pkg, t.Value, subst)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case *ast.InterfaceType:
+		return map[string]any{}, nil
+	case *ast.StructType:
+		return g.object(pkg, t, subst)
+	case *ast.IndexExpr:
+		return g.generic(pkg, t.X, []ast.Expr{t.Index}, subst)
+	case *ast.IndexListExpr:
+		return g.generic(pkg, t.X, t.Indices, subst)
+	}
+	return nil, fmt.Errorf("unsupported type %s", types.ExprString(expr))
+}
+
+func builtin(name string) (map[string]any, bool) {
+	switch name {
+	case "string", "error":
+		return map[string]any{"type": "string"}, true
+	case "bool":
+		return map[string]any{"type": "boolean"}, true
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+		return map[string]any{"type": "integer"}, true
+	case "int64", "uint64":
+		return map[string]any{"type": "integer", "format": "int64"}, true
+	case "float32", "float64":
+		return map[string]any{"type": "number"}, true
+	case "any":
+		return map[string]any{}, true
+	}
+	return nil, false
+}
+
+func (g *generator) generic(pkg string, base ast.Expr, args []ast.Expr, subst map[string]map[string]any) (map[string]any, error) {
+	var key string
+	switch b := base.(type) {
+	case *ast.Ident:
+		key = pkg + "." + b.Name
+	case *ast.SelectorExpr:
+		key = types.ExprString(b)
+	default:
+		return nil, fmt.Errorf("unsupported generic type %s", types.ExprString(base))
+	}
+	return g.ref(pkg, key, args, subst)
+}
+
+// ref registers the named type (instantiated with args) as a component
+// schema and returns a reference to it.
+func (g *generator) ref(pkg, key string, args []ast.Expr, subst map[string]map[string]any) (map[string]any, error) {
+	decl, ok := g.types[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s", key)
+	}
+	if len(args) != len(decl.params) {
+		return nil, fmt.Errorf("type %s expects %d type arguments, got %d", key, len(decl.params), len(args))
+	}
+
+	name := key
+	inner := map[string]map[string]any{}
+	for i, arg := range args {
+		s, err := g.schema(pkg, arg, subst)
+		if err != nil {
+			return nil, err
+		}
+		inner[decl.params[i]] = s
+		name += "-" + schemaName(s, arg)
+	}
+
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, done := g.schemas[name]; done {
+		return ref, nil
+	}
+	g.schemas[name] = map[string]any{} // placeholder breaks cycles
+	s, err := g.schema(decl.pkg, decl.expr, inner)
+	if err != nil {
+		delete(g.schemas, name)
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	g.schemas[name] = s
+	return ref, nil
+}
+
+// schemaName names a type argument inside an instantiated component name.
+func schemaName(s map[string]any, arg ast.Expr) string {
+	if r, ok := s["$ref"].(string); ok {
+		return strings.TrimPrefix(r, "#/components/schemas/")
+	}
+	return strings.NewReplacer("[", "_", "]", "", " ", "", "*", "").Replace(types.ExprString(arg))
+}
+
+func (g *generator) object(pkg string, st *ast.StructType, subst map[string]map[string]any) (map[string]any, error) {
+	props := map[string]any{}
+	var required []string
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		jsonName, jsonOpts, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+
+		if len(field.Names) == 0 && jsonName == "" {
+			// Embedded struct: its fields are promoted into this object.
+			emb, err := g.schema(pkg, field.Type, subst)
+			if err != nil {
+				return nil, err
+			}
+			if r, ok := emb["$ref"].(string); ok {
+				emb, _ = g.schemas[strings.TrimPrefix(r, "#/components/schemas/")].(map[string]any)
+			}
+			if p, ok := emb["properties"].(map[string]any); ok {
+				for k, v := range p {
+					props[k] = v
+				}
+			}
+			if r, ok := emb["required"].([]string); ok {
+				required = append(required, r...)
+			}
+			continue
+		}
+
+		s, err := g.schema(pkg, field.Type, subst)
+		if err != nil {
+			return nil, err
+		}
+		if doc := fieldDoc(field); doc != "" {
+			if _, isRef := s["$ref"]; isRef {
+				s = map[string]any{"allOf": []any{s}, "description": doc}
+			} else {
+				s = withDescription(s, doc)
+			}
+		}
+		// swag's readonly tag: set by the server, ignored in requests
+		if tag.Get("readonly") == "true" {
+			if _, isRef := s["$ref"]; isRef {
+				s = map[string]any{"allOf": []any{s}}
+			}
+			s = withValue(s, "readOnly", true)
+		}
+
+		names := field.Names
+		for _, n := range names {
+			if !n.IsExported() {
+				continue
+			}
+			name := jsonName
+			if name == "" {
+				name = n.Name
+			}
+			props[name] = s
+			if isRequired(tag) && !strings.Contains(jsonOpts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		out["required"] = required
+	}
+	return out, nil
+}
+
+func isRequired(tag reflect.StructTag) bool {
+	for _, key := range []string{"binding", "validate"} {
+		for _, rule := range strings.Split(tag.Get(key), ",") {
+			if rule == "required" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fieldDoc(field *ast.Field) string {
+	for _, cg := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if cg != nil {
+			return strings.TrimSpace(cg.Text())
+		}
+	}
+	return ""
+}
+
+func withDescription(s map[string]any, doc string) map[string]any {
+	return withValue(s, "description", doc)
+}
+
+func withValue(s map[string]any, key string, value any) map[string]any {
+	out := make(map[string]any, len(s)+1)
+	for k, v := range s {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
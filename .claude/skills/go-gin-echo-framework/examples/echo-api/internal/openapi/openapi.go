@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The spec is generated from the handler annotations; run `go generate ./...`
+// after changing them. Verify catches a stale spec in CI.
+//
+//go:generate go run ../../cmd/openapi-gen -root ../.. -o openapi.json
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the generated OpenAPI 3 document.
+func Spec() []byte {
+	return spec
+}
+
+// Handler serves the OpenAPI document as JSON.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+}
+
+var uiPage = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: {{.}}, dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`))
+
+// uiPolicy allows exactly what uiPage loads, replacing the API's stricter
+// Content-Security-Policy.
+const uiPolicy = "default-src 'none'; script-src https://unpkg.com 'unsafe-inline'; style-src https://unpkg.com; img-src data: https:; connect-src 'self'; frame-ancestors 'none'"
+
+// UIHandler serves a Swagger UI page rendering the document at specURL.
+// The UI assets are loaded from a CDN.
+func UIHandler(specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", uiPolicy)
+		uiPage.Execute(w, specURL)
+	})
+}
+
+// Route is a registered method and path in router syntax, e.g. GET /users/:id.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Key identifies r as the spec does: "GET /users/{id}".
+func (r Route) Key() string {
+	return strings.ToUpper(r.Method) + " " + normalizePath(r.Path)
+}
+
+// Security returns the security schemes required by each documented
+// operation, by Route.Key; public operations (@Security none) have none.
+func Security() (map[string][]string, error) {
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Security []map[string]json.RawMessage `json:"security"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid embedded spec: %w", err)
+	}
+	out := map[string][]string{}
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			var schemes []string
+			for _, requirement := range op.Security {
+				for scheme := range requirement {
+					schemes = append(schemes, scheme)
+				}
+			}
+			sort.Strings(schemes)
+			out[strings.ToUpper(method)+" "+path] = schemes
+		}
+	}
+	return out, nil
+}
+
+// Verify reports every mismatch between the generated spec and the routes a
+// router actually serves: routes missing from the spec, documented operations
+// with no route, and operations without auth metadata (an @Security
+// annotation, "none" for public routes). Paths listed in undocumented (e.g.
+// /healthz) are excluded from the first check.
+func Verify(routes []Route, undocumented ...string) error {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return fmt.Errorf("invalid embedded spec: %w", err)
+	}
+
+	var problems []string
+	documented := map[string]bool{}
+	for path, ops := range doc.Paths {
+		for method, raw := range ops {
+			key := strings.ToUpper(method) + " " + path
+			documented[key] = true
+			var op struct {
+				Security *[]json.RawMessage `json:"security"`
+			}
+			if json.Unmarshal(raw, &op) == nil && op.Security == nil {
+				problems = append(problems, "documented operation "+key+" has no @Security annotation (use @Security none for public routes)")
+			}
+		}
+	}
+	skip := map[string]bool{}
+	for _, p := range undocumented {
+		skip[normalizePath(p)] = true
+	}
+
+	served := map[string]bool{}
+	for _, r := range routes {
+		key := r.Key()
+		served[key] = true
+		if !documented[key] && !skip[normalizePath(r.Path)] {
+			problems = append(problems, "route "+key+" is not in the OpenAPI spec")
+		}
+	}
+	for key := range documented {
+		if !served[key] {
+			problems = append(problems, "documented operation "+key+" has no route")
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	errs := make([]error, len(problems))
+	for i, p := range problems {
+		errs[i] = errors.New(p)
+	}
+	return errors.Join(errs...)
+}
+
+// normalizePath converts router syntax to OpenAPI syntax: /users/:id/ -> /users/{id}.
+func normalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	path = strings.Join(segments, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeadLetter is an event set aside after MaxAttempts failed publishes.
+type DeadLetter struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	FailedAt    time.Time       `json:"failed_at"`
+}
+
+// DeadLetters returns up to limit dead letters, the longest set aside first
+// and those set aside together in the order they were recorded.
+func (r *Relay) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	query := fmt.Sprintf("SELECT id, name, aggregate_id, payload, attempts, last_error, created_at, failed_at FROM outbox WHERE failed_at IS NOT NULL ORDER BY failed_at, created_at, id LIMIT %d", limit)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	defer rows.Close()
+	letters := []DeadLetter{}
+	for rows.Next() {
+		var d DeadLetter
+		var payload string
+		var lastError sql.NullString
+		if err := rows.Scan(&d.ID, &d.Name, &d.AggregateID, &payload, &d.Attempts, &lastError, &d.CreatedAt, &d.FailedAt); err != nil {
+			return nil, fmt.Errorf("failed to read dead letters: %w", err)
+		}
+		d.Payload = json.RawMessage(payload)
+		d.LastError = lastError.String
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
+
+// Requeue returns the dead letters of ids to the relay with fresh attempts
+// and returns how many it requeued; ids of other events are ignored. A
+// requeued event goes out after those its aggregate recorded since, so
+// requeue only events whose order no longer matters to consumers.
+func (r *Relay) Requeue(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	in, args := r.in(2, ids)
+	query := fmt.Sprintf("UPDATE outbox SET failed_at = NULL, attempts = 0, next_attempt_at = %s WHERE failed_at IS NOT NULL AND id IN (%s)", r.dialect.Placeholder(1), in)
+	res, err := r.db.ExecContext(ctx, query, append([]any{r.clock.Now().UTC()}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue dead letters: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Discard deletes the dead letters of ids, or every dead letter if ids is
+// nil, and returns how many it deleted; ids of other events are ignored.
+func (r *Relay) Discard(ctx context.Context, ids []string) (int64, error) {
+	query := "DELETE FROM outbox WHERE failed_at IS NOT NULL"
+	var args []any
+	if ids != nil {
+		if len(ids) == 0 {
+			return 0, nil
+		}
+		var in string
+		in, args = r.in(1, ids)
+		query += " AND id IN (" + in + ")"
+	}
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to discard dead letters: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// in returns the placeholders of ids, numbered from first, and their args.
+func (r *Relay) in(first int, ids []string) (string, []any) {
+	params := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		params[i] = r.dialect.Placeholder(first + i)
+		args[i] = id
+	}
+	return strings.Join(params, ", "), args
+}
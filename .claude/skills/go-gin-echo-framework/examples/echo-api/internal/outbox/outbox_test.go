@@ -0,0 +1,287 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+var testOptions = Options{Enabled: true, PollInterval: time.Second, BatchSize: 10, MaxAttempts: 3, Retention: time.Hour}
+
+func migratedDB(t *testing.T) (*sql.DB, repository.Dialect) {
+	t.Helper()
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return db, dialect
+}
+
+// broker records the messages it accepts and rejects the events in
+// poison, by "<event> <aggregate>".
+type broker struct {
+	poison   map[string]bool
+	messages []domain.Message
+}
+
+func (b *broker) Publish(_ context.Context, e domain.Event) error {
+	if b.poison[e.EventName()+" "+e.AggregateID()] {
+		return errors.New("message rejected")
+	}
+	data, err := domain.Encode(e)
+	if err != nil {
+		return err
+	}
+	var m domain.Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	b.messages = append(b.messages, m)
+	return nil
+}
+
+// names returns the "<event> <aggregate>" of each accepted message.
+func (b *broker) names() []string {
+	var out []string
+	for _, m := range b.messages {
+		out = append(out, m.Name+" "+m.AggregateID)
+	}
+	return out
+}
+
+func created(id string) domain.Event {
+	return domain.Created[model.Product]{Resource: "product", Entity: model.Product{ID: id, Name: "Lamp", Price: 20}}
+}
+
+func deleted(id string) domain.Event {
+	return domain.Deleted[model.Product]{Resource: "product", ID: id}
+}
+
+func relay(t *testing.T, r *Relay) int {
+	t.Helper()
+	n, err := r.Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestEventsAreRecordedOnlyWithTheirWrite(t *testing.T) {
+	db, dialect := migratedDB(t)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := NewWriter(db, dialect, clk, nil)
+	uow := repository.NewSQLUnitOfWork(db)
+	ctx := context.Background()
+
+	err := uow.Do(ctx, func(ctx context.Context) error {
+		if err := w.Publish(ctx, created("u1")); err != nil {
+			t.Fatal(err)
+		}
+		return errors.New("the write failed")
+	})
+	if err == nil {
+		t.Fatal("expected the unit of work to fail")
+	}
+	if err := uow.Do(ctx, func(ctx context.Context) error { return w.Publish(ctx, created("u2")) }); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &broker{}
+	r := NewRelay(db, dialect, b, clk, testOptions)
+	if n := relay(t, r); n != 1 || !slices.Equal(b.names(), []string{"product.created u2"}) {
+		t.Fatalf("relayed %d: %v, want only the committed event", n, b.names())
+	}
+	m := b.messages[0]
+	var data struct {
+		Entity model.Product `json:"entity"`
+	}
+	if err := json.Unmarshal(m.Data, &data); err != nil || m.ID == "" || data.Entity.ID != "u2" {
+		t.Fatalf("message %+v does not carry an ID and the event (%v)", m, err)
+	}
+	if n := relay(t, r); n != 0 {
+		t.Fatalf("relayed %d events again, want 0", n)
+	}
+}
+
+func TestRelayRetriesInOrderAndSetsPoisonAside(t *testing.T) {
+	db, dialect := migratedDB(t)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := NewWriter(db, dialect, clk, nil)
+	ctx := context.Background()
+	for _, e := range []domain.Event{created("bad"), created("ok"), deleted("bad"), deleted("ok")} {
+		clk.Advance(time.Millisecond) // created_at orders the outbox
+		if err := w.Publish(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := &broker{poison: map[string]bool{"product.created bad": true}}
+	r := NewRelay(db, dialect, b, clk, testOptions)
+	// The failing aggregate holds back its own later events only
+	relay(t, r)
+	if want := []string{"product.created ok", "product.deleted ok"}; !slices.Equal(b.names(), want) {
+		t.Fatalf("published %v, want %v", b.names(), want)
+	}
+	// The retry waits for its backoff: 1s after the first failure, 2s after the second
+	clk.Advance(999 * time.Millisecond)
+	if n := relay(t, r); n != 0 {
+		t.Fatalf("relayed %d before the backoff passed", n)
+	}
+	clk.Advance(time.Millisecond)
+	relay(t, r)
+	clk.Advance(2 * time.Second)
+	relay(t, r) // third failure: set aside, and the aggregate's next event goes ahead
+	if want := []string{"product.created ok", "product.deleted ok", "product.deleted bad"}; !slices.Equal(b.names(), want) {
+		t.Fatalf("published %v, want %v", b.names(), want)
+	}
+	clk.Advance(time.Hour)
+	if n := relay(t, r); n != 0 {
+		t.Fatalf("relayed %d after the poison event was set aside, want 0", n)
+	}
+
+	var attempts int
+	var lastError string
+	var failedAt sql.NullTime
+	err := db.QueryRowContext(ctx, "SELECT attempts, last_error, failed_at FROM outbox WHERE name = 'product.created' AND aggregate_id = 'bad'").Scan(&attempts, &lastError, &failedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != testOptions.MaxAttempts || lastError != "message rejected" || !failedAt.Valid {
+		t.Fatalf("poison event: attempts %d, last error %q, failed at %v", attempts, lastError, failedAt)
+	}
+}
+
+func TestPurgeDeletesPublishedEventsAfterRetention(t *testing.T) {
+	db, dialect := migratedDB(t)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := NewWriter(db, dialect, clk, nil).Publish(context.Background(), created("u1")); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRelay(db, dialect, &broker{}, clk, testOptions)
+	relay(t, r)
+
+	count := func() int {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	purge := func() int64 {
+		n, err := r.Purge(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	clk.Advance(testOptions.Retention)
+	if n := purge(); n != 0 || count() != 1 {
+		t.Fatalf("purged %d entries after exactly the retention, want 0", n)
+	}
+	clk.Advance(time.Second)
+	if n := purge(); n != 1 || count() != 0 {
+		t.Fatalf("purged %d entries after the retention, want 1", n)
+	}
+}
+
+func TestHealthCheckFailsWhenTheRelayFallsBehind(t *testing.T) {
+	db, dialect := migratedDB(t)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &broker{poison: map[string]bool{"product.created u1": true}}
+	r := NewRelay(db, dialect, b, clk, testOptions)
+	check := r.HealthCheck(time.Minute, 1)
+	ctx := context.Background()
+
+	if details, err := check(ctx); err != nil || details.(Backlog).Pending != 0 {
+		t.Fatalf("empty outbox: %v, %v", details, err)
+	}
+	w := NewWriter(db, dialect, clk, nil)
+	if err := w.Publish(ctx, created("u1")); err != nil {
+		t.Fatal(err)
+	}
+	relay(t, r) // fails, to be retried
+	clk.Advance(time.Minute)
+	details, err := check(ctx)
+	if backlog := details.(Backlog); err != nil || backlog.Pending != 1 || backlog.Oldest == nil {
+		t.Fatalf("one event a minute old: %+v, %v", details, err)
+	}
+	clk.Advance(time.Second)
+	if _, err := check(ctx); err == nil {
+		t.Fatal("an event over a minute old passed the check")
+	}
+
+	delete(b.poison, "product.created u1")
+	clk.Advance(time.Hour)
+	relay(t, r)
+	for _, id := range []string{"u2", "u3"} {
+		if err := w.Publish(ctx, created(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := check(ctx); err == nil {
+		t.Fatal("2 pending events passed a limit of 1")
+	}
+}
+
+func TestDeadLettersCanBeRequeuedOrDiscarded(t *testing.T) {
+	db, dialect := migratedDB(t)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := NewWriter(db, dialect, clk, nil)
+	ctx := context.Background()
+	for _, id := range []string{"u1", "u2", "u3"} {
+		clk.Advance(time.Millisecond)
+		if err := w.Publish(ctx, created(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := &broker{poison: map[string]bool{"product.created u1": true, "product.created u2": true, "product.created u3": true}}
+	opts := testOptions
+	opts.MaxAttempts = 1
+	r := NewRelay(db, dialect, b, clk, opts)
+	relay(t, r)
+
+	letters, err := r.DeadLetters(ctx, 10)
+	if err != nil || len(letters) != 3 || letters[0].AggregateID != "u1" || letters[0].LastError != "message rejected" || letters[0].Attempts != 1 {
+		t.Fatalf("DeadLetters = %+v, %v", letters, err)
+	}
+	if backlog, err := r.Backlog(ctx); err != nil || backlog.Pending != 0 || backlog.DeadLetters != 3 || backlog.OldestDeadLetter == nil {
+		t.Fatalf("Backlog = %+v, %v", backlog, err)
+	}
+
+	// Only dead letters are requeued, with fresh attempts
+	delete(b.poison, "product.created u1")
+	if n, err := r.Requeue(ctx, []string{letters[0].ID, "unknown"}); err != nil || n != 1 {
+		t.Fatalf("Requeue = %d, %v, want 1", n, err)
+	}
+	if n, err := r.Requeue(ctx, []string{letters[0].ID}); err != nil || n != 0 {
+		t.Fatalf("Requeue of a pending event = %d, %v, want 0", n, err)
+	}
+	if n := relay(t, r); n != 1 || !slices.Equal(b.names(), []string{"product.created u1"}) {
+		t.Fatalf("relayed %d: %v, want the requeued event", n, b.names())
+	}
+
+	if n, err := r.Discard(ctx, []string{letters[1].ID}); err != nil || n != 1 {
+		t.Fatalf("Discard = %d, %v, want 1", n, err)
+	}
+	if n, err := r.Discard(ctx, nil); err != nil || n != 1 {
+		t.Fatalf("Discard of all = %d, %v, want 1", n, err)
+	}
+	if backlog, err := r.Backlog(ctx); err != nil || backlog.DeadLetters != 0 {
+		t.Fatalf("Backlog after Discard = %+v, %v", backlog, err)
+	}
+}
@@ -0,0 +1,260 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/health"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+// maxBackoff caps the wait before a failed event is retried.
+const maxBackoff = 10 * time.Minute
+
+// Relay publishes the pending events of the outbox to a broker.
+type Relay struct {
+	db        *sql.DB
+	dialect   repository.Dialect
+	publisher domain.EventPublisher
+	opts      Options
+	clock     clock.Clock
+
+	pending, published, retry, fail, purge string
+	count, oldest                          string
+	deadCount, deadOldest                  string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRelay relays the outbox of db to publisher, polling by clk (nil is
+// the system clock). On Postgres, several instances may relay at once:
+// each claims its batch with SKIP LOCKED. On SQLite the database is locked
+// while a batch is published.
+func NewRelay(db *sql.DB, dialect repository.Dialect, publisher domain.EventPublisher, clk clock.Clock, opts Options) *Relay {
+	p := dialect.Placeholder
+	pending := fmt.Sprintf("SELECT id, name, aggregate_id, payload, attempts, next_attempt_at FROM outbox WHERE published_at IS NULL AND failed_at IS NULL ORDER BY created_at, id LIMIT %d", opts.BatchSize)
+	if dialect == repository.DialectPostgres {
+		pending += " FOR UPDATE SKIP LOCKED"
+	}
+	return &Relay{
+		db:         db,
+		dialect:    dialect,
+		publisher:  publisher,
+		opts:       opts,
+		clock:      clock.OrSystem(clk),
+		pending:    pending,
+		published:  fmt.Sprintf("UPDATE outbox SET published_at = %s WHERE id = %s", p(1), p(2)),
+		retry:      fmt.Sprintf("UPDATE outbox SET attempts = %s, last_error = %s, next_attempt_at = %s WHERE id = %s", p(1), p(2), p(3), p(4)),
+		fail:       fmt.Sprintf("UPDATE outbox SET attempts = %s, last_error = %s, failed_at = %s WHERE id = %s", p(1), p(2), p(3), p(4)),
+		purge:      fmt.Sprintf("DELETE FROM outbox WHERE published_at < %s", p(1)),
+		count:      "SELECT COUNT(*) FROM outbox WHERE published_at IS NULL AND failed_at IS NULL",
+		oldest:     "SELECT created_at FROM outbox WHERE published_at IS NULL AND failed_at IS NULL ORDER BY created_at, id LIMIT 1",
+		deadCount:  "SELECT COUNT(*) FROM outbox WHERE failed_at IS NOT NULL",
+		deadOldest: "SELECT failed_at FROM outbox WHERE failed_at IS NOT NULL ORDER BY failed_at, id LIMIT 1",
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start relays every PollInterval until Shutdown.
+func (r *Relay) Start() {
+	go func() {
+		defer close(r.done)
+		tick := r.clock.NewTicker(r.opts.PollInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C():
+				// A batch published in full likely left more behind
+				for {
+					n, err := r.Relay(context.Background())
+					if err != nil {
+						log.Printf("WARNING: outbox relay: %v", err)
+					}
+					if err != nil || n < r.opts.BatchSize {
+						break
+					}
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown stops the relay after the batch in flight.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	close(r.stop)
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// entry is a pending row of the outbox.
+type entry struct {
+	stored
+	attempts int
+	due      time.Time
+}
+
+// Relay publishes one batch of due events and returns how many it
+// published. An aggregate's events wait behind its first one that is not
+// due or fails to publish, so they reach the broker in order.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entries, err := r.claim(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	now := r.clock.Now().UTC()
+	blocked := map[string]bool{}
+	published := 0
+	for _, e := range entries {
+		if blocked[e.aggregateID] || now.Before(e.due) {
+			blocked[e.aggregateID] = true
+			continue
+		}
+		pubErr := r.publisher.Publish(ctx, e.stored)
+		if pubErr == nil {
+			_, err = tx.ExecContext(ctx, r.published, now, e.id)
+			published++
+		} else {
+			err = r.failed(ctx, tx, e, pubErr, now)
+			blocked[e.aggregateID] = e.attempts+1 < r.opts.MaxAttempts
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to update outbox entry %s: %w", e.id, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, r.purge, now.Add(-r.opts.Retention)); err != nil {
+		return 0, fmt.Errorf("failed to purge published outbox entries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox batch: %w", err)
+	}
+	return published, nil
+}
+
+// Purge deletes the events published more than Retention ago and returns
+// how many it deleted. It runs as a scheduled job rather than with every
+// batch, so relaying stays cheap.
+func (r *Relay) Purge(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, r.purge, r.clock.Now().UTC().Add(-r.opts.Retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge published outbox entries: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Backlog is the state of the events waiting to be published, and of the
+// dead letters: those set aside after MaxAttempts.
+type Backlog struct {
+	Pending          int        `json:"pending"`
+	Oldest           *time.Time `json:"oldest,omitempty"` // when the oldest pending event was recorded
+	DeadLetters      int        `json:"dead_letters"`
+	OldestDeadLetter *time.Time `json:"oldest_dead_letter,omitempty"` // when the oldest dead letter was set aside
+}
+
+// Backlog counts the events waiting to be published, including those
+// waiting for a retry, and the dead letters.
+func (r *Relay) Backlog(ctx context.Context) (Backlog, error) {
+	var b Backlog
+	var err error
+	if b.Pending, b.Oldest, err = r.tally(ctx, r.count, r.oldest); err != nil {
+		return b, err
+	}
+	b.DeadLetters, b.OldestDeadLetter, err = r.tally(ctx, r.deadCount, r.deadOldest)
+	return b, err
+}
+
+// tally runs a count query and, if it counts any, the query of the oldest
+// time among them.
+func (r *Relay) tally(ctx context.Context, count, oldest string) (int, *time.Time, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, count).Scan(&n); err != nil {
+		return 0, nil, fmt.Errorf("failed to count outbox: %w", err)
+	}
+	if n == 0 {
+		return 0, nil, nil
+	}
+	var at time.Time
+	if err := r.db.QueryRowContext(ctx, oldest).Scan(&at); errors.Is(err, sql.ErrNoRows) {
+		return n, nil, nil
+	} else if err != nil {
+		return n, nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return n, &at, nil
+}
+
+// HealthCheck returns a readiness check that reports the Backlog and fails
+// when its oldest event has waited longer than maxLag or more than
+// maxPending events wait. 0 disables either limit. Dead letters wait for an
+// admin rather than the relay, so they are reported but never fail it.
+func (r *Relay) HealthCheck(maxLag time.Duration, maxPending int) health.DetailFunc {
+	return func(ctx context.Context) (any, error) {
+		b, err := r.Backlog(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if b.Oldest != nil {
+			if lag := r.clock.Now().Sub(*b.Oldest); maxLag > 0 && lag > maxLag {
+				return b, fmt.Errorf("oldest pending event waited %s, longer than %s", lag.Round(time.Second), maxLag)
+			}
+		}
+		if maxPending > 0 && b.Pending > maxPending {
+			return b, fmt.Errorf("%d events pending, more than %d", b.Pending, maxPending)
+		}
+		return b, nil
+	}
+}
+
+func (r *Relay) claim(ctx context.Context, tx *sql.Tx) ([]entry, error) {
+	rows, err := tx.QueryContext(ctx, r.pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	defer rows.Close()
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		var payload string
+		if err := rows.Scan(&e.id, &e.name, &e.aggregateID, &payload, &e.attempts, &e.due); err != nil {
+			return nil, fmt.Errorf("failed to read outbox: %w", err)
+		}
+		e.payload = []byte(payload)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// failed records a failed publish of e: it is retried after a backoff
+// doubling from PollInterval, or set aside once MaxAttempts is reached.
+func (r *Relay) failed(ctx context.Context, tx *sql.Tx, e entry, pubErr error, now time.Time) error {
+	attempts := e.attempts + 1
+	if attempts >= r.opts.MaxAttempts {
+		log.Printf("WARNING: outbox: giving up on %s %s (%s) after %d attempts: %v", e.name, e.aggregateID, e.id, attempts, pubErr)
+		_, err := tx.ExecContext(ctx, r.fail, attempts, pubErr.Error(), now, e.id)
+		return err
+	}
+	backoff := r.opts.PollInterval
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	_, err := tx.ExecContext(ctx, r.retry, attempts, pubErr.Error(), now.Add(min(backoff, maxBackoff)), e.id)
+	return err
+}
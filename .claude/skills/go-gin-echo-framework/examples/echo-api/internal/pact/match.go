@@ -0,0 +1,340 @@
+package pact
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rule applies matchers to the values at a path such as body.items.*.id.
+type rule struct {
+	path     []string
+	matchers []matcher
+	or       bool // v3 "combine": "OR"; one passing matcher suffices
+}
+
+type matcher struct {
+	Match string `json:"match"`
+	Regex string `json:"regex"`
+	Min   *int   `json:"min"`
+	Max   *int   `json:"max"`
+	Value string `json:"value"` // for include
+}
+
+type rules []rule
+
+// parseRules reads v2 rules ({"$.body.id": {"match": "type"}}) and v3 rules
+// ({"body": {"$.id": {"matchers": [{"match": "type"}]}}}) alike.
+func parseRules(raw json.RawMessage) (rules, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil {
+		return nil, fmt.Errorf("matchingRules: %w", err)
+	}
+	var out rules
+	for key, value := range top {
+		if strings.HasPrefix(key, "$") { // v2
+			var m matcher
+			if err := json.Unmarshal(value, &m); err != nil {
+				return nil, fmt.Errorf("matchingRules %s: %w", key, err)
+			}
+			path := parsePath(key)
+			if len(path) == 2 && path[0] == "headers" {
+				path[1] = strings.ToLower(path[1])
+			}
+			out = append(out, rule{path: path, matchers: []matcher{m}})
+			continue
+		}
+		var byPath map[string]struct {
+			Matchers []matcher `json:"matchers"`
+			Combine  string    `json:"combine"`
+		}
+		if err := json.Unmarshal(value, &byPath); err != nil {
+			return nil, fmt.Errorf("matchingRules.%s: %w", key, err)
+		}
+		for p, r := range byPath {
+			var path []string
+			switch key {
+			case "body":
+				path = append([]string{"body"}, parsePath(p)...)
+			case "header", "headers":
+				path = []string{"headers", strings.ToLower(p)}
+			default:
+				continue // path and query rules constrain the consumer's requests
+			}
+			out = append(out, rule{path: path, matchers: r.Matchers, or: strings.EqualFold(r.Combine, "OR")})
+		}
+	}
+	return out, nil
+}
+
+// parsePath splits a JSONPath such as $.items[*].id or $['a b'] into its
+// segments, without the leading $.
+func parsePath(p string) []string {
+	p = strings.TrimPrefix(p, "$")
+	var out []string
+	for p != "" {
+		switch {
+		case p[0] == '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			out = append(out, p[:end])
+			p = p[end:]
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return append(out, p[1:])
+			}
+			out = append(out, strings.Trim(p[1:end], `'"`))
+			p = p[end+1:]
+		default:
+			return append(out, p)
+		}
+	}
+	return out
+}
+
+// exact returns the most specific rule for path: literal segments win over
+// wildcards.
+func (rs rules) exact(path []string) (rule, bool) {
+	var best rule
+	bestWildcards := -1
+	for _, r := range rs {
+		if len(r.path) != len(path) {
+			continue
+		}
+		wildcards, ok := 0, true
+		for i, seg := range r.path {
+			if seg == "*" {
+				wildcards++
+			} else if seg != path[i] {
+				ok = false
+				break
+			}
+		}
+		if ok && (bestWildcards < 0 || wildcards < bestWildcards) {
+			best, bestWildcards = r, wildcards
+		}
+	}
+	return best, bestWildcards >= 0
+}
+
+// compare reports how actual differs from expected at path. Without a rule
+// values must be equal, objects may have more keys than expected and arrays
+// must have the same length; under a type rule (typed) only JSON types must
+// match and every element of an array is matched against the first expected
+// one, as for a consumer's eachLike.
+func (rs rules) compare(path []string, expected, actual any) []string {
+	return rs.compareTyped(path, expected, actual, false, nil)
+}
+
+func (rs rules) compareTyped(path []string, expected, actual any, typed bool, problems []string) []string {
+	where := render(path)
+	if r, ok := rs.exact(path); ok {
+		if found := r.check(where, expected, actual); len(found) > 0 {
+			return append(problems, found...)
+		}
+		if !r.cascades() {
+			return problems
+		}
+		typed = true
+	}
+
+	switch e := expected.(type) {
+	case map[string]any:
+		a, ok := actual.(map[string]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s is %s, want an object", where, jsonType(actual)))
+		}
+		keys := make([]string, 0, len(e))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, ok := a[k]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s is missing", render(append(path, k))))
+				continue
+			}
+			problems = rs.compareTyped(append(path[:len(path):len(path)], k), e[k], v, typed, problems)
+		}
+	case []any:
+		a, ok := actual.([]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s is %s, want an array", where, jsonType(actual)))
+		}
+		if !typed && len(a) != len(e) {
+			return append(problems, fmt.Sprintf("%s has %d elements, want %d", where, len(a), len(e)))
+		}
+		if typed && len(e) == 0 {
+			break
+		}
+		for i := range a {
+			want := e[0]
+			if !typed {
+				want = e[i]
+			}
+			problems = rs.compareTyped(append(path[:len(path):len(path)], strconv.Itoa(i)), want, a[i], typed, problems)
+		}
+	default:
+		if typed {
+			if jsonType(expected) != jsonType(actual) {
+				problems = append(problems, fmt.Sprintf("%s is %s, want %s", where, jsonType(actual), jsonType(expected)))
+			}
+		} else if !reflect.DeepEqual(expected, actual) {
+			problems = append(problems, fmt.Sprintf("%s is %s, want %s", where, show(actual), show(expected)))
+		}
+	}
+	return problems
+}
+
+// cascades reports whether r matches by type, which applies to the values
+// nested in the one it is defined for.
+func (r rule) cascades() bool {
+	for _, m := range r.matchers {
+		if m.kind() == "type" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rule) check(where string, expected, actual any) []string {
+	var problems []string
+	for _, m := range r.matchers {
+		found := m.check(where, expected, actual)
+		if r.or && len(found) == 0 {
+			return nil
+		}
+		problems = append(problems, found...)
+	}
+	return problems
+}
+
+// kind is the matcher's type; v2 rules imply it from the other fields.
+func (m matcher) kind() string {
+	switch {
+	case m.Match != "":
+		return m.Match
+	case m.Regex != "":
+		return "regex"
+	default:
+		return "type" // {"min": 1}
+	}
+}
+
+func (m matcher) check(where string, expected, actual any) []string {
+	fail := func(format string, args ...any) []string {
+		return []string{where + " " + fmt.Sprintf(format, args...)}
+	}
+	switch m.kind() {
+	case "type":
+		if jsonType(expected) != jsonType(actual) {
+			return fail("is %s, want %s", jsonType(actual), jsonType(expected))
+		}
+		if a, ok := actual.([]any); ok {
+			if m.Min != nil && len(a) < *m.Min {
+				return fail("has %d elements, want at least %d", len(a), *m.Min)
+			}
+			if m.Max != nil && len(a) > *m.Max {
+				return fail("has %d elements, want at most %d", len(a), *m.Max)
+			}
+		}
+	case "regex":
+		re, err := regexp.Compile("^(?:" + m.Regex + ")$")
+		if err != nil {
+			return fail("has an invalid regex rule: %v", err)
+		}
+		if s, ok := scalar(actual); !ok || !re.MatchString(s) {
+			return fail("is %s, want a match for /%s/", show(actual), m.Regex)
+		}
+	case "equality":
+		if !reflect.DeepEqual(expected, actual) {
+			return fail("is %s, want %s", show(actual), show(expected))
+		}
+	case "include":
+		if s, ok := actual.(string); !ok || !strings.Contains(s, m.Value) {
+			return fail("is %s, want it to include %q", show(actual), m.Value)
+		}
+	case "integer":
+		if n, ok := actual.(float64); !ok || n != float64(int64(n)) {
+			return fail("is %s, want an integer", show(actual))
+		}
+	case "decimal", "number":
+		if _, ok := actual.(float64); !ok {
+			return fail("is %s, want a number", show(actual))
+		}
+	case "boolean":
+		if _, ok := actual.(bool); !ok {
+			return fail("is %s, want a boolean", show(actual))
+		}
+	case "null":
+		if actual != nil {
+			return fail("is %s, want null", show(actual))
+		}
+	case "date", "time", "timestamp", "datetime":
+		if _, ok := actual.(string); !ok {
+			return fail("is %s, want a %s string", show(actual), m.kind())
+		}
+	default:
+		return fail("has an unsupported %q matcher", m.kind())
+	}
+	return nil
+}
+
+func scalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64, bool:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func show(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// render formats path as the JSONPath the consumer's rules use.
+func render(path []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range path {
+		if _, err := strconv.Atoi(seg); err == nil {
+			b.WriteString("[" + seg + "]")
+		} else {
+			b.WriteString("." + seg)
+		}
+	}
+	return b.String()
+}
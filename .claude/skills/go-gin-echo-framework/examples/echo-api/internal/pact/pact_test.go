@@ -0,0 +1,145 @@
+package pact
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// provider serves GET /things/{id} for the thing it was given and echoes
+// POSTed bodies.
+func provider(things map[string]any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			body["id"] = "generated"
+			json.NewEncoder(w).Encode(body)
+			return
+		}
+		thing, ok := things[strings.TrimPrefix(r.URL.Path, "/things/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Thing not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(thing)
+	})
+}
+
+const contract = `{
+  "consumer": {"name": "web"},
+  "provider": {"name": "things-api"},
+  "interactions": [
+    {
+      "description": "v3 rules and a provider state",
+      "providerStates": [{"name": "a thing exists", "params": {"name": "lamp"}}],
+      "request": {
+        "method": "GET", "path": "/things/1",
+        "generators": {"path": {"type": "ProviderState", "expression": "/things/${id}"}}
+      },
+      "response": {
+        "status": 200,
+        "headers": {"Content-Type": "application/json"},
+        "body": {"id": "1", "name": "lamp", "tags": [{"label": "x"}], "price": 9.99},
+        "matchingRules": {"body": {
+          "$.id": {"matchers": [{"match": "regex", "regex": "t-[0-9]+"}]},
+          "$.tags": {"matchers": [{"match": "type", "min": 1}]},
+          "$.price": {"combine": "OR", "matchers": [{"match": "integer"}, {"match": "decimal"}]}
+        }}
+      }
+    },
+    {
+      "description": "v2 rules",
+      "providerState": "a thing exists",
+      "request": {"method": "POST", "path": "/things", "body": {"name": "desk"}},
+      "response": {
+        "status": 201,
+        "body": {"id": "anything", "name": "desk"},
+        "matchingRules": {"$.body.id": {"match": "type"}}
+      }
+    }
+  ],
+  "metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`
+
+func verifier(things map[string]any) *Verifier {
+	return &Verifier{States: map[string]StateFunc{
+		"a thing exists": func(params map[string]any) (map[string]any, error) {
+			things["t-7"] = map[string]any{"id": "t-7", "name": params["name"], "tags": []any{map[string]any{"label": "new"}, map[string]any{"label": "sale"}}, "price": 12, "extra": true}
+			return map[string]any{"id": "t-7"}, nil
+		},
+	}}
+}
+
+func TestVerifyHonouredContract(t *testing.T) {
+	c, err := Parse([]byte(contract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range c.Interactions {
+		things := map[string]any{}
+		if err := verifier(things).Verify(provider(things), i); err != nil {
+			t.Errorf("%s:\n%v", i.Description, err)
+		}
+	}
+}
+
+func TestVerifyReportsBreakingChanges(t *testing.T) {
+	c, _ := Parse([]byte(contract))
+	i := c.Interactions[0]
+	tests := []struct {
+		name  string
+		thing map[string]any
+		want  []string
+	}{
+		{"renamed field", map[string]any{"id": "t-7", "title": "lamp", "tags": []any{map[string]any{"label": "x"}}, "price": 1}, []string{"$.body.name is missing"}},
+		{"changed type", map[string]any{"id": "t-7", "name": "lamp", "tags": []any{map[string]any{"label": 3}}, "price": "1"}, []string{"$.body.tags[0].label is a number, want a string", "$.body.price"}},
+		{"changed format", map[string]any{"id": "7", "name": "lamp", "tags": []any{map[string]any{"label": "x"}}, "price": 1}, []string{`$.body.id is "7", want a match for /t-[0-9]+/`}},
+		{"emptied array", map[string]any{"id": "t-7", "name": "lamp", "tags": []any{}, "price": 1}, []string{"$.body.tags has 0 elements, want at least 1"}},
+		{"changed value", map[string]any{"id": "t-7", "name": "desk", "tags": []any{map[string]any{"label": "x"}}, "price": 1}, []string{`$.body.name is "desk", want "lamp"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			things := map[string]any{}
+			v := &Verifier{States: map[string]StateFunc{
+				"a thing exists": func(map[string]any) (map[string]any, error) {
+					things["t-7"] = tt.thing
+					return map[string]any{"id": "t-7"}, nil
+				},
+			}}
+			err := v.Verify(provider(things), i)
+			for _, want := range tt.want {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %v, want it to report %q", err, want)
+				}
+			}
+		})
+	}
+
+	if err := (&Verifier{}).Verify(provider(nil), i); err == nil || !strings.Contains(err.Error(), `provider state "a thing exists" is not implemented`) {
+		t.Errorf("missing state: %v", err)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	for path, want := range map[string][]string{
+		"$.body.items[*].id": {"body", "items", "*", "id"},
+		"$['a b'][0]":        {"a b", "0"},
+		"$":                  nil,
+	} {
+		if got := parsePath(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("parsePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestParseRejectsUnsupportedSpecifications(t *testing.T) {
+	if _, err := Parse([]byte(`{"metadata": {"pactSpecification": {"version": "4.0"}}}`)); err == nil {
+		t.Error("a v4 pact was accepted")
+	}
+}
@@ -0,0 +1,59 @@
+package payloadlog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware logs the bodies of every request and its response, as the
+// client sent and received them. requestid.Middleware must run first.
+// Handler errors are rendered here, so the logged response is the one the
+// client gets.
+func Middleware(l *Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := l.CaptureRequest(c.Request())
+			res := c.Response()
+			w := &bodyWriter{ResponseWriter: res.Writer, body: l.NewBody()}
+			res.Writer = w
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			res.Writer = w.ResponseWriter
+
+			l.Log(c.Request().Context(), Exchange{
+				RequestID: res.Header().Get(echo.HeaderXRequestID),
+				Method:    c.Request().Method,
+				URL:       c.Request().URL.RequestURI(),
+				Status:    res.Status,
+				Duration:  time.Since(start),
+				Request:   req,
+				Response:  w.body.Capture(res.Header().Get(echo.HeaderContentType)),
+			})
+			return nil
+		}
+	}
+}
+
+// bodyWriter passes the response through while keeping a copy of its body.
+type bodyWriter struct {
+	http.ResponseWriter
+	body *Body
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streamed responses (echo.Response.Flush) working while logged.
+func (w *bodyWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,160 @@
+// Package payloadlog writes the request and response bodies of every
+// request to the log, for debugging what clients actually send and
+// receive. It is off by default: bodies are large and hold personal data.
+// Bodies are cut at a size limit, and the fields named by the redaction
+// rules (see package redact) are replaced before anything is logged.
+package payloadlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/your-username/echo-api/internal/redact"
+)
+
+// Options turn payload logging on.
+type Options struct {
+	Enabled      bool     `yaml:"enabled"`
+	MaxBodyBytes int      `yaml:"max_body_bytes"` // logged per body; the rest is cut off
+	Redact       []string `yaml:"redact"`         // query parameters and JSON or form fields, case-insensitive; see package redact
+}
+
+// Validate checks the size limit of enabled logging and the redaction rules.
+func (o Options) Validate() error {
+	if o.Enabled && o.MaxBodyBytes <= 0 {
+		return errors.New("max_body_bytes must be positive")
+	}
+	return redact.Validate(o.Redact)
+}
+
+// Capture is one side of an exchange.
+type Capture struct {
+	ContentType string
+	Body        string
+	Truncated   bool // Body was cut at Options.MaxBodyBytes
+}
+
+// Exchange is one request and the response it got.
+type Exchange struct {
+	RequestID string
+	Method    string
+	URL       string // path and query
+	Status    int
+	Duration  time.Duration
+	Request   Capture
+	Response  Capture
+}
+
+// Logger redacts exchanges and logs them. It is safe for concurrent use.
+type Logger struct {
+	opts     Options
+	redactor *redact.Redactor
+	log      *slog.Logger
+}
+
+// New logs with log, nil being slog.Default() at the time of each line.
+func New(opts Options, log *slog.Logger) *Logger {
+	return &Logger{opts: opts, redactor: redact.New(nil, opts.Redact), log: log}
+}
+
+// CaptureRequest reads up to Options.MaxBodyBytes of the request body and
+// puts it back, so the handler still reads the whole body.
+func (l *Logger) CaptureRequest(req *http.Request) Capture {
+	c := Capture{ContentType: req.Header.Get("Content-Type")}
+	if req.Body == nil || req.Body == http.NoBody {
+		return c
+	}
+	head, err := io.ReadAll(io.LimitReader(req.Body, int64(l.opts.MaxBodyBytes)+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+	if err != nil {
+		c.Body, c.Truncated = string(head), true
+		return c
+	}
+	if len(head) > l.opts.MaxBodyBytes {
+		head, c.Truncated = head[:l.opts.MaxBodyBytes], true
+	}
+	c.Body = string(head)
+	return c
+}
+
+// NewBody returns an empty response body capture.
+func (l *Logger) NewBody() *Body {
+	return &Body{limit: l.opts.MaxBodyBytes}
+}
+
+// Log writes e, redacted, as one line at level info.
+func (l *Logger) Log(ctx context.Context, e Exchange) {
+	log := l.log
+	if log == nil {
+		log = slog.Default()
+	}
+	attrs := []slog.Attr{
+		slog.String("request_id", e.RequestID),
+		slog.String("method", e.Method),
+		slog.String("url", l.redactor.URL(e.URL)),
+		slog.Int("status", e.Status),
+		slog.Float64("duration_ms", float64(e.Duration.Microseconds())/1000),
+	}
+	attrs = append(attrs, l.body("request", e.Request)...)
+	attrs = append(attrs, l.body("response", e.Response)...)
+	log.LogAttrs(ctx, slog.LevelInfo, "payload", attrs...)
+}
+
+// body returns the attributes of one side of an exchange, prefixed with
+// side: the redacted body, or a placeholder for binary ones.
+func (l *Logger) body(side string, c Capture) []slog.Attr {
+	if c.Body == "" {
+		return nil
+	}
+	body := l.redactor.Body(c.ContentType, c.Body)
+	if !textual(c.ContentType) {
+		body = fmt.Sprintf("[%d bytes of %s]", len(c.Body), c.ContentType)
+	}
+	attrs := []slog.Attr{slog.String(side+"_body", body)}
+	if c.Truncated {
+		attrs = append(attrs, slog.Bool(side+"_truncated", true))
+	}
+	return attrs
+}
+
+// textual reports whether a body of contentType can be logged as is.
+func textual(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") || mediaType == "application/x-www-form-urlencoded"
+}
+
+// Body accumulates up to Options.MaxBodyBytes of a response body as the
+// framework's response writer passes it on.
+type Body struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write records p, up to the limit.
+func (b *Body) Write(p []byte) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		p, b.truncated = p[:max(room, 0)], true
+	}
+	b.buf.Write(p)
+}
+
+// Capture returns the captured body, of the media type in contentType.
+func (b *Body) Capture(contentType string) Capture {
+	return Capture{ContentType: contentType, Body: b.buf.String(), Truncated: b.truncated}
+}
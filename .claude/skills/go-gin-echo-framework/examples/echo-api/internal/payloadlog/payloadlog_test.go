@@ -0,0 +1,76 @@
+package payloadlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/requestid"
+)
+
+func TestMiddlewareLogsRedactedBodies(t *testing.T) {
+	var out bytes.Buffer
+	l := New(Options{Enabled: true, MaxBodyBytes: 64, Redact: []string{"password", "user.token"}}, slog.New(slog.NewJSONHandler(&out, nil)))
+	router := echo.New()
+	router.Use(requestid.Middleware(), Middleware(l))
+	router.POST("/login", func(c echo.Context) error {
+		var body map[string]any
+		if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]any{"user": map[string]any{"name": body["name"], "token": "t"}, "token": "kept"})
+	})
+	router.GET("/file", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, "application/zip", []byte(strings.Repeat("z", 100)))
+	})
+
+	var line map[string]any
+	serve := func(req *http.Request) {
+		t.Helper()
+		out.Reset()
+		line = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+			t.Fatalf("log = %q: %v", out.String(), err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login?password=p", strings.NewReader(`{"name": "Ann", "password": "hunter22"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serve(req)
+	want := map[string]any{
+		"msg":           "payload",
+		"method":        "POST",
+		"url":           "/login?password=%5BREDACTED%5D",
+		"status":        float64(200),
+		"request_body":  `{"name":"Ann","password":"[REDACTED]"}`,
+		"response_body": `{"token":"kept","user":{"name":"Ann","token":"[REDACTED]"}}`,
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %v", k, line[k], v)
+		}
+	}
+	if line["request_id"] == "" {
+		t.Error("no request_id")
+	}
+
+	// Cut at 64 bytes, the request body no longer parses
+	req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"name": "`+strings.Repeat("a", 100)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serve(req)
+	if line["status"] != float64(200) || line["request_truncated"] != true || line["request_body"] != "[REDACTED: unparseable JSON body]" {
+		t.Errorf("truncated request: %v", line)
+	}
+
+	serve(httptest.NewRequest(http.MethodGet, "/file", nil))
+	if line["response_body"] != "[64 bytes of application/zip]" || line["response_truncated"] != true || line["request_body"] != nil {
+		t.Errorf("binary response: %v", line)
+	}
+}
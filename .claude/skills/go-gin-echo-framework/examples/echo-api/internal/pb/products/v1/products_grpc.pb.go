@@ -0,0 +1,239 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: products/v1/products.proto
+
+package productsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	ProductService_BulkCreateProducts_FullMethodName = "/products.v1.ProductService/BulkCreateProducts"
+	ProductService_WatchProducts_FullMethodName      = "/products.v1.ProductService/WatchProducts"
+)
+
+// ProductServiceClient is the client API for ProductService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProductServiceClient interface {
+	// BulkCreateProducts creates one product per streamed message and reports
+	// the outcome of every item once the client closes the stream.
+	//
+	// Flow control: each item is created before the next one is read, so a
+	// client sending faster than the server can write blocks on its HTTP/2
+	// send window rather than queueing items in server memory.
+	//
+	// Partial failure: invalid items do not abort the stream. They are listed
+	// in failed with their zero-based index; everything else is created. A
+	// stream longer than the server limit is cut off with truncated set, and
+	// items from processed onward should be resent.
+	BulkCreateProducts(ctx context.Context, opts ...grpc.CallOption) (ProductService_BulkCreateProductsClient, error)
+	// WatchProducts streams product changes as they happen, starting after
+	// token (or from now when empty). Each message carries the token to resume
+	// from. An expired token fails with FAILED_PRECONDITION: relist and watch
+	// from a fresh token.
+	WatchProducts(ctx context.Context, in *WatchProductsRequest, opts ...grpc.CallOption) (ProductService_WatchProductsClient, error)
+}
+
+type productServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProductServiceClient(cc grpc.ClientConnInterface) ProductServiceClient {
+	return &productServiceClient{cc}
+}
+
+func (c *productServiceClient) BulkCreateProducts(ctx context.Context, opts ...grpc.CallOption) (ProductService_BulkCreateProductsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProductService_ServiceDesc.Streams[0], ProductService_BulkCreateProducts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &productServiceBulkCreateProductsClient{ClientStream: stream}
+	return x, nil
+}
+
+type ProductService_BulkCreateProductsClient interface {
+	Send(*CreateProductRequest) error
+	CloseAndRecv() (*BulkCreateProductsResponse, error)
+	grpc.ClientStream
+}
+
+type productServiceBulkCreateProductsClient struct {
+	grpc.ClientStream
+}
+
+func (x *productServiceBulkCreateProductsClient) Send(m *CreateProductRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *productServiceBulkCreateProductsClient) CloseAndRecv() (*BulkCreateProductsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkCreateProductsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *productServiceClient) WatchProducts(ctx context.Context, in *WatchProductsRequest, opts ...grpc.CallOption) (ProductService_WatchProductsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProductService_ServiceDesc.Streams[1], ProductService_WatchProducts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &productServiceWatchProductsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProductService_WatchProductsClient interface {
+	Recv() (*WatchProductsResponse, error)
+	grpc.ClientStream
+}
+
+type productServiceWatchProductsClient struct {
+	grpc.ClientStream
+}
+
+func (x *productServiceWatchProductsClient) Recv() (*WatchProductsResponse, error) {
+	m := new(WatchProductsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ProductServiceServer is the server API for ProductService service.
+// All implementations must embed UnimplementedProductServiceServer
+// for forward compatibility
+type ProductServiceServer interface {
+	// BulkCreateProducts creates one product per streamed message and reports
+	// the outcome of every item once the client closes the stream.
+	//
+	// Flow control: each item is created before the next one is read, so a
+	// client sending faster than the server can write blocks on its HTTP/2
+	// send window rather than queueing items in server memory.
+	//
+	// Partial failure: invalid items do not abort the stream. They are listed
+	// in failed with their zero-based index; everything else is created. A
+	// stream longer than the server limit is cut off with truncated set, and
+	// items from processed onward should be resent.
+	BulkCreateProducts(ProductService_BulkCreateProductsServer) error
+	// WatchProducts streams product changes as they happen, starting after
+	// token (or from now when empty). Each message carries the token to resume
+	// from. An expired token fails with FAILED_PRECONDITION: relist and watch
+	// from a fresh token.
+	WatchProducts(*WatchProductsRequest, ProductService_WatchProductsServer) error
+	mustEmbedUnimplementedProductServiceServer()
+}
+
+// UnimplementedProductServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedProductServiceServer struct {
+}
+
+func (UnimplementedProductServiceServer) BulkCreateProducts(ProductService_BulkCreateProductsServer) error {
+	return status.Errorf(codes.Unimplemented, "method BulkCreateProducts not implemented")
+}
+func (UnimplementedProductServiceServer) WatchProducts(*WatchProductsRequest, ProductService_WatchProductsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProducts not implemented")
+}
+func (UnimplementedProductServiceServer) mustEmbedUnimplementedProductServiceServer() {}
+
+// UnsafeProductServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProductServiceServer will
+// result in compilation errors.
+type UnsafeProductServiceServer interface {
+	mustEmbedUnimplementedProductServiceServer()
+}
+
+func RegisterProductServiceServer(s grpc.ServiceRegistrar, srv ProductServiceServer) {
+	s.RegisterService(&ProductService_ServiceDesc, srv)
+}
+
+func _ProductService_BulkCreateProducts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ProductServiceServer).BulkCreateProducts(&productServiceBulkCreateProductsServer{ServerStream: stream})
+}
+
+type ProductService_BulkCreateProductsServer interface {
+	SendAndClose(*BulkCreateProductsResponse) error
+	Recv() (*CreateProductRequest, error)
+	grpc.ServerStream
+}
+
+type productServiceBulkCreateProductsServer struct {
+	grpc.ServerStream
+}
+
+func (x *productServiceBulkCreateProductsServer) SendAndClose(m *BulkCreateProductsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *productServiceBulkCreateProductsServer) Recv() (*CreateProductRequest, error) {
+	m := new(CreateProductRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ProductService_WatchProducts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchProductsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProductServiceServer).WatchProducts(m, &productServiceWatchProductsServer{ServerStream: stream})
+}
+
+type ProductService_WatchProductsServer interface {
+	Send(*WatchProductsResponse) error
+	grpc.ServerStream
+}
+
+type productServiceWatchProductsServer struct {
+	grpc.ServerStream
+}
+
+func (x *productServiceWatchProductsServer) Send(m *WatchProductsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ProductService_ServiceDesc is the grpc.ServiceDesc for ProductService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProductService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "products.v1.ProductService",
+	HandlerType: (*ProductServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BulkCreateProducts",
+			Handler:       _ProductService_BulkCreateProducts_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchProducts",
+			Handler:       _ProductService_WatchProducts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "products/v1/products.proto",
+}
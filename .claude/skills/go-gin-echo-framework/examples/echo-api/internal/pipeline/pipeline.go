@@ -0,0 +1,156 @@
+// Package pipeline installs middleware as named stages in the order Policy
+// prescribes and verifies each stage's declared dependencies, so a misordered
+// chain (logging outside recovery, rate limiting before auth) fails startup
+// instead of misbehaving at request time. It is generic over the framework's
+// middleware type.
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Stage names, outermost first.
+const (
+	Recovery    = "recovery"
+	RequestID   = "request-id"
+	Logging     = "logging"
+	Payload     = "payload"
+	CORS        = "cors"
+	Security    = "security"
+	Auth        = "auth"
+	Tenant      = "tenant"
+	Recorder    = "recorder"
+	RateLimit   = "ratelimit"
+	TenantLimit = "tenant-ratelimit"
+	Flags       = "flags"
+	Handler     = "handler"
+)
+
+// Policy is the required order of the stages; a chain may skip any of them
+// but never reorder them. Handler is implicit and always last. Security runs
+// inside CORS so its rejections still carry the headers browsers need to
+// read them, Tenant inside Auth so it can read the caller's tenant, and
+// Payload outside Security so it logs the requests Security rejects too.
+// TenantLimit, the rate limit of a tenant's plan, runs inside the caller's
+// RateLimit so one caller cannot spend the budget of the whole tenant.
+// Flags runs last so requests that are turned away cost no evaluation.
+var Policy = []string{Recovery, RequestID, Logging, Payload, CORS, Security, Auth, Tenant, Recorder, RateLimit, TenantLimit, Flags, Handler}
+
+// Stage is one named middleware. Requires lists the stages that must wrap it,
+// e.g. logging requires request-id so every access log line carries the ID.
+type Stage[M any] struct {
+	Name       string
+	Requires   []string
+	Middleware M
+}
+
+// Chain is a verified sequence of global stages plus the route-level stages
+// added per scope with Extend.
+type Chain[M any] struct {
+	stages []Stage[M]
+	scopes map[string][]Stage[M] // scope -> route-level stages
+}
+
+// New verifies stages as the global chain, in the order given.
+func New[M any](stages ...Stage[M]) (*Chain[M], error) {
+	if err := verify(stages); err != nil {
+		return nil, err
+	}
+	return &Chain[M]{stages: stages, scopes: map[string][]Stage[M]{}}, nil
+}
+
+// Middleware returns the global middleware, outermost first.
+func (c *Chain[M]) Middleware() []M {
+	return middleware(c.stages)
+}
+
+// Extend verifies stages as route-level middleware running inside the global
+// chain for the routes of scope (e.g. a route group), records the scope for
+// Describe and returns the stages' middleware in order.
+func (c *Chain[M]) Extend(scope string, stages ...Stage[M]) ([]M, error) {
+	all := append(append(append([]Stage[M]{}, c.stages...), c.scopes[scope]...), stages...)
+	if err := verify(all); err != nil {
+		return nil, fmt.Errorf("%s: %w", scope, err)
+	}
+	c.scopes[scope] = append(c.scopes[scope], stages...)
+	return middleware(stages), nil
+}
+
+// String renders the global order, e.g. "recovery → request-id → handler".
+func (c *Chain[M]) String() string {
+	return render(c.stages)
+}
+
+// Describe renders the effective order of the global chain and of every
+// extended scope, one per line.
+func (c *Chain[M]) Describe() string {
+	lines := []string{"global: " + c.String()}
+	scopes := make([]string, 0, len(c.scopes))
+	for scope := range c.scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		lines = append(lines, scope+": "+render(append(append([]Stage[M]{}, c.stages...), c.scopes[scope]...)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// verify reports every stage that is unknown, duplicated, out of Policy
+// order or missing a required stage before it.
+func verify[M any](stages []Stage[M]) error {
+	rank := map[string]int{}
+	for i, name := range Policy {
+		rank[name] = i
+	}
+	var errs []error
+	seen := map[string]bool{}
+	last := "" // highest-ranked stage so far
+	for _, s := range stages {
+		r, known := rank[s.Name]
+		switch {
+		case s.Name == Handler:
+			errs = append(errs, errors.New("the handler stage is implicit and cannot be installed"))
+			continue
+		case !known:
+			errs = append(errs, fmt.Errorf("unknown stage %q (policy: %s)", s.Name, strings.Join(Policy, " → ")))
+			continue
+		}
+		if seen[s.Name] {
+			errs = append(errs, fmt.Errorf("stage %q is installed twice", s.Name))
+			continue
+		}
+		if last != "" && r < rank[last] {
+			errs = append(errs, fmt.Errorf("stage %q must run before %q (policy: %s)", s.Name, last, strings.Join(Policy, " → ")))
+		}
+		for _, dep := range s.Requires {
+			if !seen[dep] {
+				errs = append(errs, fmt.Errorf("stage %q requires %q to run before it", s.Name, dep))
+			}
+		}
+		seen[s.Name] = true
+		if last == "" || r > rank[last] {
+			last = s.Name
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func middleware[M any](stages []Stage[M]) []M {
+	out := make([]M, len(stages))
+	for i, s := range stages {
+		out[i] = s.Middleware
+	}
+	return out
+}
+
+func render[M any](stages []Stage[M]) string {
+	names := make([]string, 0, len(stages)+1)
+	for _, s := range stages {
+		names = append(names, s.Name)
+	}
+	return strings.Join(append(names, Handler), " → ")
+}
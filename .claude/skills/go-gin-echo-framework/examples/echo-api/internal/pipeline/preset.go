@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/your-username/echo-api/internal/cors"
+	"github.com/your-username/echo-api/internal/hardening"
+)
+
+// Preset bundles the middleware settings of one environment; see Presets.
+type Preset struct {
+	Name           string
+	VerboseLogging bool    // access log lines include user agent and body sizes
+	LogSampleRate  float64 // fraction of requests logged; server errors always are
+	RateLimit      bool    // install the per-group rate limit stage
+
+	// CORS holds the defaults for the settings under cors: left unset. The
+	// cors stage is only installed when some origin is allowed.
+	CORS cors.Options
+
+	// Security holds the defaults for the settings under security: left
+	// unset, for every route group.
+	Security hardening.Options
+}
+
+// Presets are selected with middleware.preset, which defaults to the
+// environment name.
+var Presets = map[string]Preset{
+	"development": {Name: "development", VerboseLogging: true, LogSampleRate: 1, CORS: localCORS, Security: localSecurity},
+	"test":        {Name: "test", CORS: localCORS, Security: localSecurity}, // quiet: only server errors are logged
+	"staging":     {Name: "staging", LogSampleRate: 1, RateLimit: true, CORS: strictCORS, Security: strictSecurity},
+	"production":  {Name: "production", LogSampleRate: 0.1, RateLimit: true, CORS: strictCORS, Security: strictSecurity},
+}
+
+// Headers scripts may read on cross-origin responses.
+var corsExposed = []string{"Location", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-Request-ID", "X-API-Version", "Deprecation", "Sunset", "Link"}
+
+var (
+	// localCORS lets front ends on any local port call the API with any
+	// headers, so development needs no configuration.
+	localCORS = cors.Options{
+		AllowedOrigins: []string{"http://localhost", "http://localhost:*", "http://127.0.0.1", "http://127.0.0.1:*"},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"*"},
+		ExposedHeaders: corsExposed,
+		MaxAge:         time.Minute,
+	}
+	// strictCORS allows no origin until cors.allowed_origins lists them, and
+	// then only the headers the API reads.
+	strictCORS = cors.Options{
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-API-Key", "X-Request-ID", "X-API-Version", "X-Response-Envelope"},
+		ExposedHeaders: corsExposed,
+		MaxAge:         10 * time.Minute,
+	}
+)
+
+var (
+	// strictSecurity is what a JSON API served over HTTPS needs: no
+	// framing, no resources loaded on its behalf, no downgrade to HTTP.
+	strictSecurity = hardening.Options{
+		HSTS:                  "max-age=31536000; includeSubDomains",
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		MaxBodyBytes:          1 << 20,
+		ContentTypes:          []string{"application/json"},
+	}
+	// localSecurity enforces the same limits but sends no HSTS, which
+	// would make browsers refuse plain HTTP to localhost for a year.
+	localSecurity = func() hardening.Options {
+		o := strictSecurity
+		o.HSTS = hardening.Off
+		return o
+	}()
+)
+
+// ShouldLog reports whether the access log line of a request that ended with
+// status is written: always for 5xx, otherwise with probability LogSampleRate.
+func (p Preset) ShouldLog(status int) bool {
+	return status >= 500 || p.LogSampleRate >= 1 || (p.LogSampleRate > 0 && rand.Float64() < p.LogSampleRate)
+}
@@ -0,0 +1,45 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tiers := map[string]Plan{"free": {RateLimit: "60/m", MaxItems: map[string]int{"products": 10}}, "pro": {}}
+	for _, tc := range []struct {
+		name string
+		opts Options
+		err  string // "" is valid
+	}{
+		{name: "none", opts: Options{}},
+		{name: "plans", opts: Options{Tiers: tiers, Default: "free", Tenants: map[string]string{"acme": "pro"}}},
+		{name: "tenants without plans", opts: Options{Tenants: map[string]string{"acme": "pro"}}, err: "tiers must declare"},
+		{name: "no default", opts: Options{Tiers: tiers}, err: "default must name a plan"},
+		{name: "unknown plan", opts: Options{Tiers: tiers, Default: "free", Tenants: map[string]string{"acme": "gold"}}, err: `unknown plan "gold"`},
+		{name: "bad tenant", opts: Options{Tiers: tiers, Default: "free", Tenants: map[string]string{"Acme": "pro"}}, err: "invalid tenant"},
+		{name: "bad rate limit", opts: Options{Tiers: map[string]Plan{"free": {RateLimit: "lots"}}, Default: "free"}, err: "tiers.free.rate_limit"},
+		{name: "no items", opts: Options{Tiers: map[string]Plan{"free": {MaxItems: map[string]int{"products": 0}}}, Default: "free"}, err: "must be positive"},
+	} {
+		err := tc.opts.Validate()
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%s: %v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestTenantsWithoutAPlanGetTheDefault(t *testing.T) {
+	opts := Options{Tiers: map[string]Plan{"free": {RateLimit: "60/m"}, "pro": {}}, Default: "free", Tenants: map[string]string{"acme": "pro"}}
+	if name, p, ok := opts.Of("acme"); !ok || name != "pro" || !p.Limit().Unlimited() {
+		t.Errorf("acme: %s %+v %t, want pro", name, p, ok)
+	}
+	if name, p, ok := opts.Of("globex"); !ok || name != "free" || p.Limit().Burst != 60 {
+		t.Errorf("globex: %s %+v %t, want free", name, p, ok)
+	}
+	if _, _, ok := opts.Of(""); ok {
+		t.Error("a request without a tenant has a plan")
+	}
+	if _, _, ok := (Options{}).Of("acme"); ok {
+		t.Error("a tenant has a plan while none is declared")
+	}
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/lifecycle"
+)
+
+// takeScript atomically refills and takes from a bucket stored as a hash.
+// KEYS[1] bucket key; ARGV: burst, rate (tokens/s), now (ms), ttl (ms).
+// Returns {allowed (0/1), tokens * 1000}.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - last) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "last", now)
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, math.floor(tokens * 1000)}
+`)
+
+type redisStore struct {
+	client *redis.Client
+	prefix string
+	clock  clock.Clock
+}
+
+// NewRedisStore returns a Store shared by every replica connected to the
+// Redis instance at url. The refill time comes from clk (nil is the system
+// clock) rather than the Redis server, so replicas' clocks should be in sync.
+func NewRedisStore(ctx context.Context, lc *lifecycle.Manager, url, prefix string, clk clock.Clock) (Store, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	lc.RegisterCloser("rate limit redis client", 0, client)
+	return &redisStore{client: client, prefix: prefix, clock: clock.OrSystem(clk)}, nil
+}
+
+func (s *redisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	// Expire idle buckets once they would have refilled completely.
+	ttl := int64(math.Ceil(limit.Per.Seconds()*1000)) + 1000
+	vals, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		limit.Burst, limit.Rate(), s.clock.Now().UnixMilli(), ttl).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("redis rate limit: %w", err)
+	}
+	return bucketResult(limit, float64(vals[1])/1000, vals[0] == 1), nil
+}
+
+// Ping verifies the Redis connection; it satisfies health.Pinger.
+func (s *redisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
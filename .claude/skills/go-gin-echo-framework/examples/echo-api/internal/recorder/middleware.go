@@ -0,0 +1,72 @@
+package recorder
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/auth"
+)
+
+// Middleware records the requests the active filter selects, matching routes
+// by their registered pattern (c.Path) and callers as identified by
+// auth.Identify, which must run first. Handler errors are rendered here, so
+// the recorded response is the one the client gets.
+func Middleware(rec *Recorder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var subject string
+			if caller := auth.CallerFromContext(c.Request().Context()); caller != nil {
+				subject = caller.Subject
+			}
+			if !rec.Wants(c.Request().Method, c.Path(), subject) {
+				return next(c)
+			}
+
+			start := time.Now()
+			req := rec.CaptureRequest(c.Request())
+			res := c.Response()
+			w := &bodyWriter{ResponseWriter: res.Writer, body: rec.NewBody()}
+			res.Writer = w
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			res.Writer = w.ResponseWriter
+
+			rec.Add(Exchange{
+				Time:       start,
+				DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+				RequestID:  res.Header().Get(echo.HeaderXRequestID),
+				Method:     c.Request().Method,
+				Origin:     origin(c.Request()),
+				URL:        c.Request().URL.RequestURI(),
+				Route:      c.Path(),
+				Subject:    subject,
+				Status:     res.Status,
+				Request:    req,
+				Response:   w.body.Message(res.Header()),
+			})
+			return nil
+		}
+	}
+}
+
+// bodyWriter passes the response through while keeping a copy of its body.
+type bodyWriter struct {
+	http.ResponseWriter
+	body *Body
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streamed responses (echo.Response.Flush) working while recorded.
+func (w *bodyWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
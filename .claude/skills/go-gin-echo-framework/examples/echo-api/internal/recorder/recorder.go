@@ -0,0 +1,240 @@
+// Package recorder captures full request/response pairs of selected traffic
+// into a ring buffer, for debugging client issues that are hard to reproduce.
+// Nothing is recorded until an admin starts the recorder for a route, a
+// caller or both (see Filter). Sensitive headers, query parameters and JSON
+// or form fields are redacted before an exchange is stored.
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-username/echo-api/internal/redact"
+)
+
+// Redacted replaces the value of every redacted header and field.
+const Redacted = redact.Redacted
+
+// Options configures a Recorder.
+type Options struct {
+	Capacity      int      `yaml:"capacity"`       // exchanges kept; the oldest is dropped first
+	MaxBodyBytes  int      `yaml:"max_body_bytes"` // captured per body; the rest is cut off
+	RedactHeaders []string `yaml:"redact_headers"` // request and response headers, case-insensitive
+	RedactFields  []string `yaml:"redact_fields"`  // query parameters and JSON or form fields, case-insensitive; see package redact
+}
+
+// Validate checks the redaction rules.
+func (o Options) Validate() error {
+	return redact.Validate(o.RedactFields)
+}
+
+// Filter selects the requests to record. Every field that is set must match.
+type Filter struct {
+	Method  string `json:"method,omitempty"`  // e.g. POST; empty matches every method
+	Route   string `json:"route,omitempty"`   // route pattern as registered, e.g. /users/:id
+	Subject string `json:"subject,omitempty"` // account ID of the caller
+}
+
+// Validate rejects filters that would record all traffic.
+func (f Filter) Validate() error {
+	if f.Route == "" && f.Subject == "" {
+		return errors.New("route or subject is required")
+	}
+	return nil
+}
+
+// Matches reports whether a request for route by the caller subject ("" when
+// anonymous) is selected.
+func (f Filter) Matches(method, route, subject string) bool {
+	return (f.Method == "" || strings.EqualFold(f.Method, method)) &&
+		(f.Route == "" || f.Route == route) &&
+		(f.Subject == "" || f.Subject == subject)
+}
+
+// Message is one captured side of an exchange.
+type Message struct {
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"` // Body was cut at Options.MaxBodyBytes
+}
+
+// Exchange is one recorded request and the response it got, as the handlers
+// saw and produced them.
+type Exchange struct {
+	ID         int       `json:"id"` // increases across Clear
+	Time       time.Time `json:"time"`
+	DurationMS float64   `json:"duration_ms"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Origin     string    `json:"origin"` // scheme and host the client used, e.g. https://api.example.com
+	URL        string    `json:"url"`    // path and query
+	Route      string    `json:"route"`
+	Subject    string    `json:"subject,omitempty"`
+	Status     int       `json:"status"`
+	Request    Message   `json:"request"`
+	Response   Message   `json:"response"`
+}
+
+// Status describes the recorder for its admin endpoints.
+type Status struct {
+	Recording bool    `json:"recording"`
+	Filter    *Filter `json:"filter,omitempty"`
+	Capacity  int     `json:"capacity"`
+	Count     int     `json:"count"` // exchanges currently held
+}
+
+// Recorder holds the active filter and the most recent exchanges. It is safe
+// for concurrent use; requests that are not recorded only load the filter.
+type Recorder struct {
+	opts     Options
+	redactor *redact.Redactor
+	filter   atomic.Pointer[Filter]
+
+	mu   sync.Mutex
+	ring []Exchange // oldest at next once full
+	next int
+	seq  int
+}
+
+// New returns a stopped recorder.
+func New(opts Options) *Recorder {
+	return &Recorder{opts: opts, redactor: redact.New(opts.RedactHeaders, opts.RedactFields), ring: make([]Exchange, 0, opts.Capacity)}
+}
+
+// Start records the requests f selects from now on, replacing any previous
+// filter. Exchanges recorded so far are kept.
+func (r *Recorder) Start(f Filter) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	r.filter.Store(&f)
+	return nil
+}
+
+// Stop ends recording; the recorded exchanges stay available.
+func (r *Recorder) Stop() {
+	r.filter.Store(nil)
+}
+
+// Wants reports whether the request should be recorded.
+func (r *Recorder) Wants(method, route, subject string) bool {
+	f := r.filter.Load()
+	return f != nil && f.Matches(method, route, subject)
+}
+
+// Status returns the current filter and buffer occupancy.
+func (r *Recorder) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Status{Filter: r.filter.Load(), Capacity: r.opts.Capacity, Count: len(r.ring)}
+	s.Recording = s.Filter != nil
+	return s
+}
+
+// Add redacts e, numbers it and stores it, dropping the oldest exchange when
+// the buffer is full.
+func (r *Recorder) Add(e Exchange) {
+	e.URL = r.redactor.URL(e.URL)
+	e.Request = r.redact(e.Request)
+	e.Response = r.redact(e.Response)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	e.ID = r.seq
+	if len(r.ring) < r.opts.Capacity {
+		r.ring = append(r.ring, e)
+		return
+	}
+	r.ring[r.next] = e
+	r.next = (r.next + 1) % len(r.ring)
+}
+
+// Exchanges returns the recorded exchanges, oldest first.
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Exchange, 0, len(r.ring))
+	out = append(out, r.ring[r.next:]...)
+	return append(out, r.ring[:r.next]...)
+}
+
+// Clear drops every recorded exchange.
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ring = r.ring[:0]
+	r.next = 0
+}
+
+// CaptureRequest reads up to Options.MaxBodyBytes of the request body for
+// recording and puts it back, so the handler still reads the whole body.
+func (r *Recorder) CaptureRequest(req *http.Request) Message {
+	m := Message{Header: req.Header.Clone()}
+	if req.Body == nil || req.Body == http.NoBody {
+		return m
+	}
+	head, err := io.ReadAll(io.LimitReader(req.Body, int64(r.opts.MaxBodyBytes)+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+	if err != nil {
+		m.Body, m.Truncated = string(head), true
+		return m
+	}
+	if len(head) > r.opts.MaxBodyBytes {
+		head, m.Truncated = head[:r.opts.MaxBodyBytes], true
+	}
+	m.Body = string(head)
+	return m
+}
+
+// redact returns m with the configured headers and body fields replaced.
+func (r *Recorder) redact(m Message) Message {
+	r.redactor.Header(m.Header)
+	m.Body = r.redactor.Body(m.Header.Get("Content-Type"), m.Body)
+	return m
+}
+
+// origin returns the scheme and host of r as the client addressed it,
+// honouring X-Forwarded-Proto from a TLS-terminating proxy.
+func origin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// Body accumulates up to Options.MaxBodyBytes of a response body as the
+// framework's response writer passes it on.
+type Body struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// NewBody returns an empty response body capture.
+func (r *Recorder) NewBody() *Body {
+	return &Body{limit: r.opts.MaxBodyBytes}
+}
+
+// Write records p, up to the limit.
+func (b *Body) Write(p []byte) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		p, b.truncated = p[:max(room, 0)], true
+	}
+	b.buf.Write(p)
+}
+
+// Message returns the captured body with the response's header.
+func (b *Body) Message(h http.Header) Message {
+	return Message{Header: h.Clone(), Body: b.buf.String(), Truncated: b.truncated}
+}
@@ -0,0 +1,164 @@
+// Package render encodes handler results in the media type a client asks
+// for in its Accept header: JSON, XML, CSV (for spreadsheets and exports) or
+// NDJSON (one record per line, for streaming consumers). Handlers of both
+// frameworks negotiate through the same Registry; error responses stay JSON.
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder writes v, a model or a slice of models, to w.
+type Encoder func(w io.Writer, v any) error
+
+// Format is an Encoder and the media type it produces.
+type Format struct {
+	MediaType string // e.g. text/csv, without parameters
+	Encode    Encoder
+}
+
+// The formats of Default.
+var (
+	JSON   = Format{MediaType: "application/json", Encode: encodeJSON}
+	XML    = Format{MediaType: "application/xml", Encode: encodeXML}
+	CSV    = Format{MediaType: "text/csv", Encode: encodeCSV}
+	NDJSON = Format{MediaType: "application/x-ndjson", Encode: encodeNDJSON}
+)
+
+// Default is the registry the CRUD handlers negotiate with.
+var Default = NewRegistry(JSON, XML, CSV, NDJSON)
+
+// Registry holds the formats a server offers, keyed by media type. The first
+// one is served when the client states no preference.
+type Registry struct {
+	mu      sync.RWMutex
+	formats []Format
+}
+
+// NewRegistry offers formats in order of preference.
+func NewRegistry(formats ...Format) *Registry {
+	r := &Registry{}
+	for _, f := range formats {
+		r.Register(f)
+	}
+	return r
+}
+
+// Register adds f, or replaces the format of the same media type.
+func (r *Registry) Register(f Format) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.formats {
+		if r.formats[i].MediaType == f.MediaType {
+			r.formats[i] = f
+			return
+		}
+	}
+	r.formats = append(r.formats, f)
+}
+
+// MediaTypes lists the offered media types in order of preference.
+func (r *Registry) MediaTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, len(r.formats))
+	for i, f := range r.formats {
+		out[i] = f.MediaType
+	}
+	return out
+}
+
+// Negotiate picks the format the Accept header ranks highest, preferring the
+// registry's order between equals, and reports false when the client accepts
+// none of them. An empty header accepts anything.
+func (r *Registry) Negotiate(accept string) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.formats) == 0 {
+		return Format{}, false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return r.formats[0], true
+	}
+	ranges := parseAccept(accept)
+	var best Format
+	bestQ := 0.0
+	for _, f := range r.formats {
+		if q := quality(ranges, f.MediaType); q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaRange is one entry of an Accept header, such as text/* or
+// application/json;q=0.5.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var out []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		out = append(out, mediaRange{typ, subtype, q})
+	}
+	return out
+}
+
+// quality is the q-value of the most specific range matching mediaType, or 0.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// encodeNDJSON writes each element of a slice as one line of JSON, or v
+// itself when it is not a slice.
+func encodeNDJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	items, ok := elements(v)
+	if !ok {
+		return enc.Encode(v)
+	}
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
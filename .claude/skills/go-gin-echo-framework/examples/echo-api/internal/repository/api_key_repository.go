@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/your-username/echo-api/internal/model"
+
+type APIKeyRepository = CrudRepository[model.APIKey]
+
+// NewAPIKeyRepository returns an empty in-memory repository of its own.
+func NewAPIKeyRepository() APIKeyRepository {
+	return NewMemoryRepository[model.APIKey]("API key")
+}
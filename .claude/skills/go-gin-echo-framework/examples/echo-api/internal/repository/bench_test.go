@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository/repotest"
+)
+
+type backend struct {
+	name    string
+	factory repotest.Factory[model.Product]
+}
+
+// BenchmarkRepositories compares the backends and decorators under each of
+// repotest.Workloads and prints a table of the results:
+//
+//	go test -run '^$' -bench Repositories ./internal/repository
+func BenchmarkRepositories(b *testing.B) {
+	backends := []backend{
+		{"memory", newMemory},
+		{"memory+changes", changeTracked(newMemory)},
+		{"sqlite", newSQLite},
+		{"sqlite+cache", cached(newSQLite)},
+	}
+	if os.Getenv("POSTGRES_URL") != "" {
+		postgres := newPostgres(b)
+		backends = append(backends, backend{"postgres", postgres}, backend{"postgres+cache", cached(postgres)})
+	}
+	if os.Getenv("REDIS_URL") != "" {
+		backends = append(backends, backend{"sqlite+redis", redisCached(b, newSQLite)})
+	}
+	if os.Getenv("MONGODB_URL") != "" {
+		mongo := newMongo(b)
+		backends = append(backends, backend{"mongodb", mongo}, backend{"mongodb+cache", cached(mongo)})
+	}
+
+	var table repotest.Table
+	for _, w := range repotest.Workloads {
+		for _, be := range backends {
+			b.Run(w.Name+"/"+be.name, func(b *testing.B) {
+				table.Add(be.name, repotest.Benchmark(b, be.factory, newProduct, w))
+			})
+		}
+	}
+	table.Print(os.Stdout)
+}
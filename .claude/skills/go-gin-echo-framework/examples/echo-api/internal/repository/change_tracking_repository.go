@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// changeTrackingRepository records every successful write to a change feed
+// so clients can sync incrementally instead of re-listing, under the tenant
+// of the write. Inside a UnitOfWork the entry is recorded once the
+// transaction commits.
+type changeTrackingRepository[T model.Entity] struct {
+	CrudRepository[T]
+	feed *changes.Feed
+}
+
+func NewChangeTrackingRepository[T model.Entity](next CrudRepository[T], feed *changes.Feed) CrudRepository[T] {
+	return &changeTrackingRepository[T]{
+		CrudRepository: next,
+		feed:           feed,
+	}
+}
+
+func (r *changeTrackingRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	created, err := r.CrudRepository.Create(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	r.record(ctx, changes.OpCreated, (*created).GetID())
+	return created, nil
+}
+
+func (r *changeTrackingRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	updated, err := r.CrudRepository.Update(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	r.record(ctx, changes.OpUpdated, (*updated).GetID())
+	return updated, nil
+}
+
+func (r *changeTrackingRepository[T]) Delete(ctx context.Context, id string) error {
+	if err := r.CrudRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.record(ctx, changes.OpDeleted, id)
+	return nil
+}
+
+func (r *changeTrackingRepository[T]) record(ctx context.Context, op changes.Op, id string) {
+	tenantID := tenant.From(ctx)
+	AfterCommit(ctx, func() { r.feed.Record(tenantID, op, id) })
+}
@@ -0,0 +1,228 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/cache"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/fakedata"
+	"github.com/your-username/echo-api/internal/lifecycle"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/repository/repotest"
+	"github.com/your-username/echo-api/internal/resilience"
+)
+
+// newProduct draws a product, updated some time in 2026.
+func newProduct(f *fakedata.Faker, id string) model.Product {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.Rand().Int64N(int64(365 * 24 * time.Hour))))
+	return model.Product{ID: id, Name: f.ProductName(), Price: f.Price(), UpdatedAt: at.Truncate(time.Millisecond)}
+}
+
+func newMemory(t testing.TB) repository.CrudRepository[model.Product] {
+	return repository.NewMemoryRepository[model.Product]("product")
+}
+
+// newSQLite returns a repository on a migrated in-memory SQLite database.
+func newSQLite(t testing.TB) repository.CrudRepository[model.Product] {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return repository.NewSQLRepository[model.Product](db, dialect, "products", "product")
+}
+
+// newPostgres returns a factory of repositories on the migrated database
+// at POSTGRES_URL, emptied for each, skipping t when it is not set.
+func newPostgres(t testing.TB) repotest.Factory[model.Product] {
+	url := os.Getenv("POSTGRES_URL")
+	if url == "" {
+		t.Skip("POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		if _, err := db.ExecContext(ctx, "DELETE FROM products"); err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewSQLRepository[model.Product](db, dialect, "products", "product")
+	}
+}
+
+// newMongo returns a factory of repositories on fresh collections of the
+// database at MONGODB_URL, skipping t when it is not set.
+func newMongo(t testing.TB) repotest.Factory[model.Product] {
+	url := os.Getenv("MONGODB_URL")
+	if url == "" {
+		t.Skip("MONGODB_URL is not set")
+	}
+	db, err := repository.OpenMongo(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Client().Disconnect(context.Background()) })
+	var n atomic.Int64
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		coll := db.Collection(fmt.Sprintf("products_conformance_%d_%d", time.Now().UnixNano(), n.Add(1)))
+		t.Cleanup(func() { coll.Drop(context.Background()) })
+		return repository.NewMongoRepository[model.Product](coll, "product")
+	}
+}
+
+// cached decorates the repositories of next with an LRU cache.
+func cached(next repotest.Factory[model.Product]) repotest.Factory[model.Product] {
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		return repository.NewCachedRepository(next(t), "products", cache.NewLRUStore(1024, nil), time.Minute, &cache.Metrics{})
+	}
+}
+
+// redisCached decorates the repositories of next with a cache in the Redis
+// at REDIS_URL, under a key prefix of each's own, skipping t when it is not
+// set.
+func redisCached(t testing.TB, next repotest.Factory[model.Product]) repotest.Factory[model.Product] {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL is not set")
+	}
+	lc := lifecycle.New()
+	t.Cleanup(func() { lc.Shutdown(context.Background()) })
+	var n atomic.Int64
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		prefix := fmt.Sprintf("conformance:%d:%d:", time.Now().UnixNano(), n.Add(1))
+		store, err := cache.NewRedisStore(context.Background(), lc, url, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewCachedRepository(next(t), "products", store, time.Minute, &cache.Metrics{})
+	}
+}
+
+// changeTracked decorates the repositories of next with a change feed.
+func changeTracked(next repotest.Factory[model.Product]) repotest.Factory[model.Product] {
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		return repository.NewChangeTrackingRepository(next(t), changes.NewFeed(1000, nil))
+	}
+}
+
+// resilient guards the repositories of next with a circuit breaker.
+func resilient(next repotest.Factory[model.Product]) repotest.Factory[model.Product] {
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		breakers := resilience.NewRegistry(resilience.Options{Enabled: true, Timeout: time.Second, Retries: 2, Backoff: time.Millisecond, FailureThreshold: 5, OpenFor: time.Minute}, nil)
+		return repository.NewResilientRepository(next(t), breakers, "repository:products")
+	}
+}
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newMemory, newProduct)
+}
+
+// TestMemoryRepositoryIsSafeForConcurrentUse has workers write their own
+// records and read everyone's at once, as concurrent requests do. Run it
+// with the race detector:
+//
+//	go test -race -run ConcurrentUse ./internal/repository
+func TestMemoryRepositoryIsSafeForConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewProductRepository()
+	const workers, records = 8, 200
+	f := fakedata.New(1, fakedata.DefaultLocale)
+	products := make([][]model.Product, workers)
+	for w := range products {
+		for i := 0; i < records; i++ {
+			products[w] = append(products[w], newProduct(f, fmt.Sprintf("product-%d-%02d", w, i)))
+		}
+	}
+
+	start := make(chan struct{}) // so that the workers overlap
+	var wg sync.WaitGroup
+	for w := range products {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i, p := range products[w] {
+				if _, err := repo.Create(ctx, &p); err != nil {
+					t.Error(err)
+					return
+				}
+				p.Price++
+				if _, err := repo.Update(ctx, &p); err != nil {
+					t.Error(err)
+					return
+				}
+				other := products[(w+1)%workers][i].ID
+				repo.GetByID(ctx, other) // may not exist yet
+				repo.GetByIDs(ctx, []string{p.ID, other})
+				repo.GetAll(ctx)
+				if i%2 == 0 {
+					if err := repo.Delete(ctx, p.ID); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if all, err := repo.GetAll(ctx); err != nil || len(all) != workers*records/2 {
+		t.Errorf("GetAll = %d records, %v; want %d", len(all), err, workers*records/2)
+	}
+	if all, _ := repository.NewProductRepository().GetAll(ctx); len(all) != 0 {
+		t.Errorf("a new repository holds %d records, want none", len(all))
+	}
+}
+
+func TestFakeRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(testing.TB) repository.CrudRepository[model.Product] {
+		return repotest.NewFake[model.Product]("product")
+	}, newProduct)
+}
+
+func TestSQLRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newSQLite, newProduct)
+}
+
+func TestPostgresRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newPostgres(t), newProduct)
+}
+
+func TestMongoRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newMongo(t), newProduct)
+}
+
+func TestCachedRepositoryConformance(t *testing.T) {
+	repotest.Run(t, cached(newMemory), newProduct)
+}
+
+func TestRedisCachedRepositoryConformance(t *testing.T) {
+	repotest.Run(t, redisCached(t, newSQLite), newProduct)
+}
+
+func TestChangeTrackingRepositoryConformance(t *testing.T) {
+	repotest.Run(t, changeTracked(newMemory), newProduct)
+}
+
+func TestResilientRepositoryConformance(t *testing.T) {
+	repotest.Run(t, resilient(newMemory), newProduct)
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/your-username/echo-api/internal/model"
+
+type CredentialRepository = CrudRepository[model.Credential]
+
+// NewCredentialRepository returns an empty in-memory repository of its own.
+func NewCredentialRepository() CredentialRepository {
+	return NewMemoryRepository[model.Credential]("credential")
+}
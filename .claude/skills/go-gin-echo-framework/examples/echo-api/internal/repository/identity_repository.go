@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/your-username/echo-api/internal/model"
+
+type IdentityRepository = CrudRepository[model.Identity]
+
+// NewIdentityRepository returns an empty in-memory repository of its own.
+func NewIdentityRepository() IdentityRepository {
+	return NewMemoryRepository[model.Identity]("identity")
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/schema"
+)
+
+type mongoRepository[T model.Entity] struct {
+	coll *mongo.Collection
+	name string // singular resource name used in error messages
+}
+
+// NewMongoRepository stores T as documents in coll, keyed by _id. Models map
+// their ID field with a bson:"_id" tag. Documents of an older schema
+// version are upgraded as they are read (see package schema), with the ID
+// as "id".
+func NewMongoRepository[T model.Entity](coll *mongo.Collection, name string) CrudRepository[T] {
+	return &mongoRepository[T]{coll: coll, name: name}
+}
+
+func (r *mongoRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	cur, err := r.coll.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.coll.Name(), err)
+	}
+	all, err := r.all(ctx, cur)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.coll.Name(), err)
+	}
+	return all, nil
+}
+
+func (r *mongoRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	cur, err := r.coll.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", r.coll.Name(), err)
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var item T
+		if err := r.decode(cur.Current, &item); err != nil {
+			return fmt.Errorf("failed to list %s: %w", r.coll.Name(), err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func (r *mongoRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	raw, err := r.coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Raw()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	var item T
+	if err == nil {
+		err = r.decode(raw, &item)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %w", r.name, id, err)
+	}
+	return &item, nil
+}
+
+func (r *mongoRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: uniqueIDs(ids)}}}}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", r.coll.Name(), err)
+	}
+	found, err := r.all(ctx, cur)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", r.coll.Name(), err)
+	}
+	if found == nil {
+		found = []T{}
+	}
+	return found, nil
+}
+
+func (r *mongoRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	doc, err := r.document(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", r.name, err)
+	}
+	if _, err := r.coll.InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%s with ID %s: %w", r.name, (*item).GetID(), ErrExists)
+		}
+		return nil, fmt.Errorf("failed to create %s: %w", r.name, err)
+	}
+	return item, nil
+}
+
+func (r *mongoRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	id := (*item).GetID()
+	doc, err := r.document(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s %s: %w", r.name, id, err)
+	}
+	res, err := r.coll.ReplaceOne(ctx, bson.D{{Key: "_id", Value: id}}, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s %s: %w", r.name, id, err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrNotFound
+	}
+	return item, nil
+}
+
+func (r *mongoRepository[T]) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", r.name, id, err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// all decodes the documents of cur.
+func (r *mongoRepository[T]) all(ctx context.Context, cur *mongo.Cursor) ([]T, error) {
+	defer cur.Close(ctx)
+	var all []T
+	for cur.Next(ctx) {
+		var item T
+		if err := r.decode(cur.Current, &item); err != nil {
+			return nil, err
+		}
+		all = append(all, item)
+	}
+	return all, cur.Err()
+}
+
+// decode decodes raw into item, upgrading it first if it was written at an
+// older schema version.
+func (r *mongoRepository[T]) decode(raw bson.Raw, item *T) error {
+	version := 1
+	if v, err := raw.LookupErr(schema.Field); err == nil {
+		n, ok := v.AsInt64OK()
+		if !ok {
+			return fmt.Errorf("%s is not a number", schema.Field)
+		}
+		version = int(n)
+	}
+	if version == schema.Version(item) {
+		return bson.Unmarshal(raw, item)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	delete(doc, schema.Field)
+	doc["id"] = doc["_id"]
+	delete(doc, "_id")
+	if err := schema.UpgradeDocument(doc, version, item); err != nil {
+		return err
+	}
+	doc["_id"] = doc["id"]
+	delete(doc, "id")
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, item)
+}
+
+// document returns item as it is stored, with its schema version from
+// version 2 on.
+func (r *mongoRepository[T]) document(item *T) (any, error) {
+	version := schema.Version(item)
+	if version == 1 {
+		return item, nil
+	}
+	data, err := bson.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return append(doc, bson.E{Key: schema.Field, Value: version}), nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// connectTimeout bounds the initial ping, so a wrong URL fails startup quickly.
+const connectTimeout = 10 * time.Second
+
+// OpenSQL connects to a postgres:// or sqlite: URL and checks the connection.
+// SQLite URLs name a file (sqlite:///var/lib/app.db, sqlite://app.db) or
+// sqlite::memory:, with driver options in the query string.
+func OpenSQL(ctx context.Context, rawURL string) (*sql.DB, Dialect, error) {
+	scheme, rest, _ := strings.Cut(rawURL, ":")
+	var (
+		db      *sql.DB
+		dialect Dialect
+		err     error
+	)
+	switch scheme {
+	case "postgres", "postgresql":
+		dialect = DialectPostgres
+		db, err = sql.Open("pgx", rawURL)
+	case "sqlite":
+		dialect = DialectSQLite
+		db, err = sql.Open("sqlite", strings.TrimPrefix(rest, "//"))
+	default:
+		return nil, "", fmt.Errorf("unsupported SQL database scheme %q", scheme)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open %s database: %w", dialect, err)
+	}
+	if dialect == DialectSQLite {
+		// SQLite allows one writer; a single connection also keeps a
+		// :memory: database shared instead of one per pooled connection.
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, "", fmt.Errorf("failed to connect to %s database: %w", dialect, err)
+	}
+	return db, dialect, nil
+}
+
+// OpenMongo connects to a mongodb:// or mongodb+srv:// URL and returns the
+// database named by its path, e.g. mongodb://localhost:27017/app.
+func OpenMongo(ctx context.Context, rawURL string) (*mongo.Database, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB URL: %w", err)
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	if name == "" {
+		return nil, errors.New("MongoDB URL must name a database, e.g. mongodb://localhost:27017/app")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MongoDB: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	return client.Database(name), nil
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/your-username/echo-api/internal/model"
+
+type ProvisionedRepository = CrudRepository[model.Provisioned]
+
+// NewProvisionedRepository returns an empty in-memory repository of its own.
+func NewProvisionedRepository() ProvisionedRepository {
+	return NewMemoryRepository[model.Provisioned]("provisioned account")
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/your-username/echo-api/internal/model"
+)
+
+// ErrQuota is returned by the Create of NewQuotaRepository when the scope
+// of the context already holds as many items as its quota allows.
+var ErrQuota = errors.New("quota exceeded")
+
+// Quota is the most items a scope, e.g. a tenant or an account, may hold.
+type Quota struct {
+	Scope string // e.g. "tenant:acme"; "" is none, leaving creates uncapped and usage unmetered
+	Max   int    // 0 is unlimited
+	Per   string // who Max applies to, for messages, e.g. "in the free plan" or "per account"
+}
+
+// QuotaRepository is a repository whose creates are held to a quota.
+type QuotaRepository[T model.Entity] interface {
+	CrudRepository[T]
+	// Usage returns how many items the scope of ctx holds, with its quota.
+	Usage(ctx context.Context) (int, Quota, error)
+}
+
+type quotaRepository[T model.Entity] struct {
+	CrudRepository[T]
+	uow   UnitOfWork
+	locks Locks
+	name  string
+	quota func(ctx context.Context) Quota
+	holds func(ctx context.Context, item T) bool
+}
+
+// NewQuotaRepository holds the creates of next, restores from the trash
+// included, to the quota returns for the context. A create locks the scope
+// in locks, counts its items and writes the new one in a uow transaction,
+// so concurrent creates, on any instance, cannot overshoot the quota. The
+// items of a scope are those next streams, e.g. of its tenant, that holds
+// reports as the scope's, e.g. by owner; nil holds all of them. Counting
+// streams them, so keep quotas to collections of modest size. name is the
+// plural used in messages and lock keys.
+func NewQuotaRepository[T model.Entity](next CrudRepository[T], uow UnitOfWork, locks Locks, name string, quota func(ctx context.Context) Quota, holds func(ctx context.Context, item T) bool) QuotaRepository[T] {
+	if holds == nil {
+		holds = func(context.Context, T) bool { return true }
+	}
+	return &quotaRepository[T]{CrudRepository: next, uow: uow, locks: locks, name: name, quota: quota, holds: holds}
+}
+
+func (r *quotaRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	q := r.quota(ctx)
+	if q.Scope == "" || q.Max <= 0 {
+		return r.CrudRepository.Create(ctx, item)
+	}
+	var created *T
+	err := r.uow.Do(ctx, func(ctx context.Context) error {
+		release, err := r.locks.Lock(ctx, r.name+":"+q.Scope)
+		if err != nil {
+			return fmt.Errorf("failed to lock the quota of %s: %w", r.name, err)
+		}
+		defer release()
+		n, err := r.count(ctx)
+		if err != nil {
+			return err
+		}
+		if n >= q.Max {
+			return fmt.Errorf("%w: at most %d %s %s", ErrQuota, q.Max, r.name, q.Per)
+		}
+		created, err = r.CrudRepository.Create(ctx, item)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *quotaRepository[T]) Usage(ctx context.Context) (int, Quota, error) {
+	q := r.quota(ctx)
+	if q.Scope == "" {
+		return 0, q, nil
+	}
+	n, err := r.count(ctx)
+	return n, q, err
+}
+
+func (r *quotaRepository[T]) count(ctx context.Context) (int, error) {
+	n := 0
+	err := r.CrudRepository.Stream(ctx, func(item T) error {
+		if r.holds(ctx, item) {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", r.name, err)
+	}
+	return n, nil
+}
+
+// Locks serializes the reads and writes made under a key, e.g. the quota
+// checks of a scope.
+type Locks interface {
+	// Lock blocks until no other transaction holds key and takes it until
+	// the transaction on ctx ends, or release is called where the backend
+	// has no transactions.
+	Lock(ctx context.Context, key string) (release func(), err error)
+}
+
+// memoryLocks are the Locks of the in-memory repositories, whose writes
+// are visible as soon as they are made.
+type memoryLocks struct {
+	mu   sync.Mutex
+	keys map[string]*sync.Mutex
+}
+
+// NewMemoryLocks returns Locks held in this process.
+func NewMemoryLocks() Locks {
+	return &memoryLocks{keys: map[string]*sync.Mutex{}}
+}
+
+func (l *memoryLocks) Lock(_ context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	m, ok := l.keys[key]
+	if !ok {
+		m = &sync.Mutex{}
+		l.keys[key] = m
+	}
+	l.mu.Unlock()
+	m.Lock()
+	return m.Unlock, nil
+}
+
+type sqlLocks struct {
+	db   *sql.DB
+	take string
+}
+
+// NewSQLLocks locks a row of the quota_locks table per key, in the
+// NewSQLUnitOfWork(db) transaction on the context, which releases it.
+func NewSQLLocks(db *sql.DB, dialect Dialect) Locks {
+	return &sqlLocks{db: db, take: fmt.Sprintf(
+		"INSERT INTO quota_locks (scope, taken_at) VALUES (%s, %s) ON CONFLICT (scope) DO UPDATE SET taken_at = excluded.taken_at",
+		dialect.Placeholder(1), dialect.Placeholder(2))}
+}
+
+func (l *sqlLocks) Lock(ctx context.Context, key string) (func(), error) {
+	if !InTransaction(ctx) {
+		return nil, errors.New("SQL locks are held by a transaction")
+	}
+	if _, err := SQLConn(ctx, l.db).ExecContext(ctx, l.take, key, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+type mongoLocks struct {
+	coll *mongo.Collection
+}
+
+// NewMongoLocks locks a document of the quota_locks collection per key, in
+// the NewMongoUnitOfWork transaction on the context: a concurrent
+// transaction taking it conflicts and is retried once the holder ends.
+// Without transactions (a standalone server) quotas may be overshot.
+func NewMongoLocks(db *mongo.Database) Locks {
+	return &mongoLocks{coll: db.Collection("quota_locks")}
+}
+
+func (l *mongoLocks) Lock(ctx context.Context, key string) (func(), error) {
+	_, err := l.coll.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"taken_at": time.Now().UTC()}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
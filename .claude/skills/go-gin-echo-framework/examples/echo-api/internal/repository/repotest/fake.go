@@ -0,0 +1,78 @@
+package repotest
+
+import (
+	"context"
+
+	"github.com/your-username/echo-api/internal/fault"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+)
+
+// Fake is an in-memory CrudRepository for the tests of services and other
+// consumers, e.g. a repository.ProductRepository. It is the repository of
+// NewMemoryRepository, which is safe for concurrent use, failing the calls
+// Faults selects, by method name.
+type Fake[T model.Entity] struct {
+	Faults fault.Injector
+
+	repo repository.CrudRepository[T]
+}
+
+var _ repository.CrudRepository[model.Product] = (*Fake[model.Product])(nil)
+
+// NewFake returns an empty fake; name is the singular used in error
+// messages, e.g. "product".
+func NewFake[T model.Entity](name string) *Fake[T] {
+	return &Fake[T]{repo: repository.NewMemoryRepository[T](name)}
+}
+
+func (f *Fake[T]) GetAll(ctx context.Context) ([]T, error) {
+	if err := f.Faults.Check("GetAll"); err != nil {
+		return nil, err
+	}
+	return f.repo.GetAll(ctx)
+}
+
+// Stream calls fn for a snapshot of the records, so fn may write to the
+// fake.
+func (f *Fake[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	if err := f.Faults.Check("Stream"); err != nil {
+		return err
+	}
+	return f.repo.Stream(ctx, fn)
+}
+
+func (f *Fake[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	if err := f.Faults.Check("GetByID"); err != nil {
+		return nil, err
+	}
+	return f.repo.GetByID(ctx, id)
+}
+
+func (f *Fake[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	if err := f.Faults.Check("GetByIDs"); err != nil {
+		return nil, err
+	}
+	return f.repo.GetByIDs(ctx, ids)
+}
+
+func (f *Fake[T]) Create(ctx context.Context, item *T) (*T, error) {
+	if err := f.Faults.Check("Create"); err != nil {
+		return nil, err
+	}
+	return f.repo.Create(ctx, item)
+}
+
+func (f *Fake[T]) Update(ctx context.Context, item *T) (*T, error) {
+	if err := f.Faults.Check("Update"); err != nil {
+		return nil, err
+	}
+	return f.repo.Update(ctx, item)
+}
+
+func (f *Fake[T]) Delete(ctx context.Context, id string) error {
+	if err := f.Faults.Check("Delete"); err != nil {
+		return err
+	}
+	return f.repo.Delete(ctx, id)
+}
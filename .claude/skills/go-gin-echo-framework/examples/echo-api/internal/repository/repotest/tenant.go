@@ -0,0 +1,251 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/fakedata"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// Tenants are the tenants RunTenantIsolation scopes its calls to. A factory
+// of schema-isolated repositories must serve both.
+var Tenants = []string{"acme", "globex"}
+
+// RunTenantIsolation checks that the tenant-scoped repositories newRepo
+// returns, e.g. a repository.NewColumnTenantRepository over a backend and
+// whatever decorators sit above it, never let a call scoped to one tenant
+// read, change or delete the records of another, nor serve a call without
+// a tenant. Results are audited against the records each tenant created,
+// so a backend need not record the tenant for the suite to tell whose a
+// record is.
+func RunTenantIsolation[T model.Entity](t *testing.T, newRepo Factory[T], gen Generator[T]) {
+	seed := seedOf(t)
+	f := fakedata.New(seed, fakedata.DefaultLocale)
+	scopes := make([]context.Context, len(Tenants))
+	for i, id := range Tenants {
+		scopes[i] = tenant.With(context.Background(), id)
+	}
+
+	// populate has every tenant create n records and returns their IDs, by
+	// tenant
+	populate := func(t *testing.T, repo repository.CrudRepository[T], n int) [][]string {
+		t.Helper()
+		owned := make([][]string, len(Tenants))
+		for i, ctx := range scopes {
+			for range n {
+				item := gen(f, f.ID())
+				if _, err := repo.Create(ctx, &item); err != nil {
+					t.Fatalf("Create in %s: %v", Tenants[i], err)
+				}
+				owned[i] = append(owned[i], item.GetID())
+			}
+			slices.Sort(owned[i])
+		}
+		return owned
+	}
+
+	t.Run("ReadsSeeOnlyTheirTenant", func(t *testing.T) {
+		repo := newRepo(t)
+		owned := populate(t, repo, 3)
+		every := slices.Concat(owned...)
+		for i, ctx := range scopes {
+			all, err := repo.GetAll(ctx)
+			if err != nil {
+				t.Fatalf("GetAll in %s: %v", Tenants[i], err)
+			}
+			mustOwn(t, Tenants[i], "GetAll", idsOf(all), owned[i])
+			var streamed []T
+			if err := repo.Stream(ctx, func(item T) error {
+				streamed = append(streamed, item)
+				return nil
+			}); err != nil {
+				t.Fatalf("Stream in %s: %v", Tenants[i], err)
+			}
+			mustOwn(t, Tenants[i], "Stream", idsOf(streamed), owned[i])
+			found, err := repo.GetByIDs(ctx, every)
+			if err != nil {
+				t.Fatalf("GetByIDs in %s: %v", Tenants[i], err)
+			}
+			mustOwn(t, Tenants[i], "GetByIDs of every tenant's records", idsOf(found), owned[i])
+			for _, id := range every {
+				_, err := repo.GetByID(ctx, id)
+				if mine := slices.Contains(owned[i], id); mine && err != nil || !mine && !errors.Is(err, repository.ErrNotFound) {
+					t.Fatalf("GetByID %s in %s (its own: %t): %v", id, Tenants[i], mine, err)
+				}
+			}
+		}
+	})
+
+	t.Run("WritesCannotReachOtherTenants", func(t *testing.T) {
+		repo := newRepo(t)
+		owned := populate(t, repo, 1)
+		acme, globex := scopes[0], scopes[1]
+		id := owned[1][0]
+		before, err := repo.GetByID(globex, id)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+
+		changed := gen(f, id)
+		if _, err := repo.Update(acme, &changed); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update of a record of %s in %s: %v, want ErrNotFound", Tenants[1], Tenants[0], err)
+		}
+		if err := repo.Delete(acme, id); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete of a record of %s in %s: %v, want ErrNotFound", Tenants[1], Tenants[0], err)
+		}
+		// A tenant of its own may hold a record of the same ID, but must
+		// not take over the other's
+		taken := gen(f, id)
+		repo.Create(acme, &taken)
+
+		after, err := repo.GetByID(globex, id)
+		if err != nil {
+			t.Fatalf("GetByID in %s after the writes of %s: %v", Tenants[1], Tenants[0], err)
+		}
+		mustEqual(t, "record after the writes of another tenant", *after, *before)
+	})
+
+	t.Run("CallsWithoutATenantFail", func(t *testing.T) {
+		repo := newRepo(t)
+		owned := populate(t, repo, 1)
+		ctx := context.Background()
+		item := gen(f, owned[0][0])
+		fresh := gen(f, f.ID())
+		_, getAll := repo.GetAll(ctx)
+		stream := repo.Stream(ctx, func(T) error { return nil })
+		_, get := repo.GetByID(ctx, owned[0][0])
+		_, getMany := repo.GetByIDs(ctx, owned[0])
+		_, create := repo.Create(ctx, &fresh)
+		_, update := repo.Update(ctx, &item)
+		del := repo.Delete(ctx, owned[0][0])
+		for op, err := range map[string]error{"GetAll": getAll, "Stream": stream, "GetByID": get, "GetByIDs": getMany, "Create": create, "Update": update, "Delete": del} {
+			if !errors.Is(err, tenant.ErrMissing) {
+				t.Errorf("%s without a tenant: %v, want tenant.ErrMissing", op, err)
+			}
+		}
+	})
+
+	t.Run("RandomInterleavings", func(t *testing.T) {
+		for i := 0; i < Sequences; i++ {
+			if !runInterleaving(t, newRepo(t), f, gen, scopes) {
+				t.Fatalf("interleaving %d failed; replay with REPOTEST_SEED=%d", i+1, seed)
+			}
+		}
+	})
+}
+
+// runInterleaving applies Steps random operations, each by a random tenant
+// on a record of its own or another's, to repo and to a model of it, and
+// reports whether every tenant saw only its records, as the model has them.
+func runInterleaving[T model.Entity](t *testing.T, repo repository.CrudRepository[T], f *fakedata.Faker, gen Generator[T], scopes []context.Context) bool {
+	t.Helper()
+	want := make([]map[string]T, len(scopes)) // by tenant, then ID
+	for i := range want {
+		want[i] = map[string]T{}
+	}
+	var log []string
+	fail := func(format string, args ...any) bool {
+		t.Errorf("after %s:\n"+format, append([]any{strings.Join(log, ", ")}, args...)...)
+		return false
+	}
+
+	for step := 0; step < Steps; step++ {
+		by, of := f.Rand().IntN(len(scopes)), f.Rand().IntN(len(scopes))
+		ctx := scopes[by]
+		var id string
+		if ids := sortedKeys(want[of]); len(ids) > 0 {
+			id = ids[f.Rand().IntN(len(ids))]
+		}
+		mine := by == of
+		if id == "" || f.Rand().IntN(3) == 0 {
+			item := gen(f, f.ID())
+			log = append(log, Tenants[by]+" Create "+item.GetID())
+			created, err := repo.Create(ctx, &item)
+			if err != nil {
+				return fail("Create: %v", err)
+			}
+			want[by][item.GetID()] = *created
+			continue
+		}
+		switch f.Rand().IntN(3) {
+		case 0:
+			log = append(log, Tenants[by]+" GetByID "+id+" of "+Tenants[of])
+			got, err := repo.GetByID(ctx, id)
+			switch {
+			case mine && err != nil:
+				return fail("GetByID: %v", err)
+			case mine && encode(*got) != encode(want[of][id]):
+				return fail("GetByID = %s, want %s", encode(*got), encode(want[of][id]))
+			case !mine && !errors.Is(err, repository.ErrNotFound):
+				return fail("GetByID of another tenant's record: %v, want ErrNotFound", err)
+			}
+		case 1:
+			item := gen(f, id)
+			log = append(log, Tenants[by]+" Update "+id+" of "+Tenants[of])
+			updated, err := repo.Update(ctx, &item)
+			switch {
+			case mine && err != nil:
+				return fail("Update: %v", err)
+			case mine:
+				want[of][id] = *updated
+			case !errors.Is(err, repository.ErrNotFound):
+				return fail("Update of another tenant's record: %v, want ErrNotFound", err)
+			}
+		case 2:
+			log = append(log, Tenants[by]+" Delete "+id+" of "+Tenants[of])
+			err := repo.Delete(ctx, id)
+			switch {
+			case mine && err != nil:
+				return fail("Delete: %v", err)
+			case mine:
+				delete(want[of], id)
+			case !errors.Is(err, repository.ErrNotFound):
+				return fail("Delete of another tenant's record: %v, want ErrNotFound", err)
+			}
+		}
+	}
+
+	for i, ctx := range scopes {
+		all, err := repo.GetAll(ctx)
+		if err != nil {
+			return fail("GetAll in %s: %v", Tenants[i], err)
+		}
+		got := map[string]string{}
+		for _, item := range all {
+			got[item.GetID()] = encode(item)
+		}
+		expected := map[string]string{}
+		for id, item := range want[i] {
+			expected[id] = encode(item)
+		}
+		if encode(got) != encode(expected) {
+			return fail("GetAll in %s = %s, want %s", Tenants[i], encode(got), encode(expected))
+		}
+	}
+	return true
+}
+
+// idsOf returns the IDs of items, sorted.
+func idsOf[T model.Entity](items []T) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.GetID()
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// mustOwn fails t unless op in tenant returned the records of want, sorted
+// IDs, and no other.
+func mustOwn(t *testing.T, tenant, op string, got, want []string) {
+	t.Helper()
+	if !equalIDs(got, want) {
+		t.Fatalf("%s in %s returned %v, want only its own %v", op, tenant, got, want)
+	}
+}
@@ -0,0 +1,225 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/repository/repotest"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+// ids returns the IDs of items, in order.
+func ids(items []model.Product) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.ID
+	}
+	return out
+}
+
+func TestColumnTenantRepository(t *testing.T) {
+	repo := repository.NewColumnTenantRepository(newMemory(t))
+	acme := tenant.With(context.Background(), "acme")
+	globex := tenant.With(context.Background(), "globex")
+
+	for ctx, id := range map[context.Context]string{acme: "1", globex: "2"} {
+		if _, err := repo.Create(ctx, &model.Product{ID: id, Name: "n", TenantID: "spoofed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := repo.GetAll(acme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != "1" || all[0].TenantID != "acme" {
+		t.Errorf("acme lists %+v, want only product 1 of acme", all)
+	}
+	found, err := repo.GetByIDs(globex, []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(found); len(got) != 1 || got[0] != "2" {
+		t.Errorf("globex gets %v by ID, want [2]", got)
+	}
+	var streamed []string
+	if err := repo.Stream(globex, func(p model.Product) error {
+		streamed = append(streamed, p.ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != 1 || streamed[0] != "2" {
+		t.Errorf("globex streams %v, want [2]", streamed)
+	}
+
+	// acme cannot see, change or delete globex's product
+	if _, err := repo.GetByID(acme, "2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID of another tenant's product: %v", err)
+	}
+	if _, err := repo.Update(acme, &model.Product{ID: "2", Name: "taken"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update of another tenant's product: %v", err)
+	}
+	if err := repo.Delete(acme, "2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete of another tenant's product: %v", err)
+	}
+	if p, err := repo.GetByID(globex, "2"); err != nil || p.Name != "n" {
+		t.Errorf("globex's product after acme's attempts: %+v, %v", p, err)
+	}
+
+	if _, err := repo.GetAll(context.Background()); !errors.Is(err, tenant.ErrMissing) {
+		t.Errorf("GetAll without a tenant: %v", err)
+	}
+	if _, err := repo.Create(context.Background(), &model.Product{ID: "3", Name: "n"}); !errors.Is(err, tenant.ErrMissing) {
+		t.Errorf("Create without a tenant: %v", err)
+	}
+}
+
+func TestColumnTenantRepositoryRequiresTenantedModel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("scoped a model that does not record its tenant")
+		}
+	}()
+	repository.NewColumnTenantRepository(repository.NewMemoryRepository[model.ProductV2]("product"))
+}
+
+func TestSchemaTenantRepositoryOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	repos := map[string]repository.CrudRepository[model.Product]{}
+	for _, id := range []string{"acme", "globex-corp"} {
+		// Preparing is idempotent, as on every startup
+		for range 2 {
+			table, err := repository.PrepareTenantTable(ctx, db, dialect, tenant.Schema(id), "products")
+			if err != nil {
+				t.Fatal(err)
+			}
+			repos[id] = repository.NewSQLRepository[model.Product](db, dialect, table, "product")
+		}
+	}
+	repo := repository.NewSchemaTenantRepository(repos)
+	acme := tenant.With(ctx, "acme")
+	globex := tenant.With(ctx, "globex-corp")
+
+	// The same ID in two tenants names two products
+	if _, err := repo.Create(acme, &model.Product{ID: "1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(globex, &model.Product{ID: "1", Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	for ctx, want := range map[context.Context]string{acme: "Ann", globex: "Bob"} {
+		all, err := repo.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].Name != want {
+			t.Errorf("%s lists %+v, want only %s", tenant.From(ctx), all, want)
+		}
+	}
+	var public int
+	if err := db.QueryRow("SELECT COUNT(*) FROM products").Scan(&public); err != nil || public != 0 {
+		t.Errorf("%d products in the public table (%v), want none", public, err)
+	}
+
+	if _, err := repo.GetAll(tenant.With(ctx, "initech")); !errors.Is(err, tenant.ErrUnknown) {
+		t.Errorf("GetAll of an unknown tenant: %v", err)
+	}
+	if _, err := repo.GetAll(ctx); !errors.Is(err, tenant.ErrMissing) {
+		t.Errorf("GetAll without a tenant: %v", err)
+	}
+}
+
+// columnScoped scopes the repositories of next by the tenant of each item.
+func columnScoped(next repotest.Factory[model.Product]) repotest.Factory[model.Product] {
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		return repository.NewColumnTenantRepository(next(t))
+	}
+}
+
+// schemaScoped serves each of repotest.Tenants from a repository of next.
+func schemaScoped(next repotest.Factory[model.Product]) repotest.Factory[model.Product] {
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		tenants := map[string]repository.CrudRepository[model.Product]{}
+		for _, id := range repotest.Tenants {
+			tenants[id] = next(t)
+		}
+		return repository.NewSchemaTenantRepository(tenants)
+	}
+}
+
+// audited checks the results of the repositories of next against the tenant
+// of each call.
+func audited(next repotest.Factory[model.Product], schema bool) repotest.Factory[model.Product] {
+	return func(t testing.TB) repository.CrudRepository[model.Product] {
+		return repository.NewTenantAuditRepository(next(t), "product", schema)
+	}
+}
+
+func TestColumnTenantIsolation(t *testing.T) {
+	for name, backend := range map[string]func(t *testing.T) repotest.Factory[model.Product]{
+		"memory":   func(*testing.T) repotest.Factory[model.Product] { return newMemory },
+		"sqlite":   func(*testing.T) repotest.Factory[model.Product] { return newSQLite },
+		"postgres": func(t *testing.T) repotest.Factory[model.Product] { return newPostgres(t) },
+		"mongo":    func(t *testing.T) repotest.Factory[model.Product] { return newMongo(t) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			repotest.RunTenantIsolation(t, audited(cached(columnScoped(backend(t))), false), newProduct)
+		})
+	}
+}
+
+func TestSchemaTenantIsolation(t *testing.T) {
+	for name, backend := range map[string]repotest.Factory[model.Product]{
+		"memory": newMemory,
+		"sqlite": newSQLite,
+	} {
+		t.Run(name, func(t *testing.T) {
+			repotest.RunTenantIsolation(t, audited(cached(schemaScoped(backend)), true), newProduct)
+		})
+	}
+}
+
+func TestTenantAuditRepositoryFailsCallsThatLeak(t *testing.T) {
+	// Unscoped, the repository answers every tenant with every product
+	leaky := newMemory(t)
+	for _, p := range []model.Product{{ID: "1", Name: "n", TenantID: "acme"}, {ID: "2", Name: "n", TenantID: "globex"}} {
+		if _, err := leaky.Create(context.Background(), &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := repository.NewTenantAuditRepository(leaky, "product", false)
+	acme := tenant.With(context.Background(), "acme")
+
+	if p, err := repo.GetByID(acme, "1"); err != nil || p.ID != "1" {
+		t.Errorf("GetByID of acme's own product: %+v, %v", p, err)
+	}
+	_, getAll := repo.GetAll(acme)
+	_, get := repo.GetByID(acme, "2")
+	_, getMany := repo.GetByIDs(acme, []string{"1", "2"})
+	stream := repo.Stream(acme, func(model.Product) error { return nil })
+	_, update := repo.Update(acme, &model.Product{ID: "2", Name: "taken", TenantID: "globex"})
+	del := repo.Delete(acme, "2")
+	for op, err := range map[string]error{"GetAll": getAll, "GetByID": get, "GetByIDs": getMany, "Stream": stream, "Update": update, "Delete": del} {
+		if !errors.Is(err, tenant.ErrIsolation) {
+			t.Errorf("%s returning globex's product to acme: %v, want tenant.ErrIsolation", op, err)
+		}
+	}
+	if _, err := leaky.GetByID(context.Background(), "2"); err != nil {
+		t.Errorf("globex's product after acme's delete: %v", err)
+	}
+	if all, err := repo.GetAll(context.Background()); err != nil || len(all) != 2 {
+		t.Errorf("GetAll without a tenant = %d products, %v; want both, unchecked", len(all), err)
+	}
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/your-username/echo-api/internal/model"
+
+type TOTPRepository = CrudRepository[model.TOTPFactor]
+
+// NewTOTPRepository returns an empty in-memory repository of its own.
+func NewTOTPRepository() TOTPRepository {
+	return NewMemoryRepository[model.TOTPFactor]("authenticator app")
+}
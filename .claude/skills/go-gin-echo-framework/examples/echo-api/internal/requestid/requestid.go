@@ -0,0 +1,29 @@
+// Package requestid carries the ID that echo's RequestID middleware gives
+// each request on the request context, for code below the handlers (the
+// audit log) that has no echo.Context.
+package requestid
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+type contextKey struct{}
+
+// Middleware is middleware.RequestID, additionally storing the ID on the
+// request context (see FromContext).
+func Middleware() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), contextKey{}, id)))
+		},
+	})
+}
+
+// FromContext returns the request ID stored by Middleware, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
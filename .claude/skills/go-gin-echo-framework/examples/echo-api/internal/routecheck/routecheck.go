@@ -0,0 +1,88 @@
+// Package routecheck validates the routing table once every route is
+// registered, so mistakes fail startup with one report instead of surfacing
+// as a wrong handler or an undocumented endpoint at request time.
+package routecheck
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/your-username/echo-api/internal/openapi"
+)
+
+// Route is one registration as the router recorded it.
+type Route struct {
+	Method  string
+	Path    string // router syntax, e.g. /users/:id
+	Handler string // handler name, for the report
+}
+
+// Check reports conflicting registrations (see Conflicts) together with every
+// openapi.Verify problem: routes missing from the spec, documented operations
+// with no route and operations without auth metadata. Paths in undocumented
+// are infrastructure routes deliberately absent from the spec.
+func Check(routes []Route, undocumented ...string) error {
+	served := make([]openapi.Route, len(routes))
+	for i, r := range routes {
+		served[i] = openapi.Route{Method: r.Method, Path: r.Path}
+	}
+	if err := errors.Join(Conflicts(routes), openapi.Verify(served, undocumented...)); err != nil {
+		return fmt.Errorf("invalid routes:\n%w", err)
+	}
+	return nil
+}
+
+// Conflicts reports route pairs a router cannot tell apart: the same method
+// and path registered twice (echo keeps the last silently), paths that only
+// differ by a trailing slash, and parameters named differently at the same
+// position under the same prefix (/users/:id vs /users/:userID/orders).
+func Conflicts(routes []Route) error {
+	var problems []string
+	for i, a := range routes {
+		for _, b := range routes[i+1:] {
+			if a.Method != b.Method {
+				continue
+			}
+			if reason := conflict(a.Path, b.Path); reason != "" {
+				problems = append(problems, fmt.Sprintf("%s %s (%s) conflicts with %s (%s): %s",
+					a.Method, a.Path, a.Handler, b.Path, b.Handler, reason))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	errs := make([]error, len(problems))
+	for i, p := range problems {
+		errs[i] = errors.New(p)
+	}
+	return errors.Join(errs...)
+}
+
+func conflict(a, b string) string {
+	if a == b {
+		return "registered twice"
+	}
+	if len(a) > 1 && len(b) > 1 && strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/") {
+		return "paths differ only by a trailing slash"
+	}
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, y := as[i], bs[i]
+		if x == y {
+			continue
+		}
+		if isWildcard(x) && isWildcard(y) {
+			return fmt.Sprintf("wildcards %s and %s share a position", x, y)
+		}
+		return ""
+	}
+	return ""
+}
+
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
@@ -0,0 +1,229 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Standard JSON-RPC 2.0 error codes, plus application codes in the
+// implementation-defined -32000..-32099 range.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	CodeNotFound        = -32004
+	CodeUnauthorized    = -32001
+	CodePaymentRequired = -32002
+	CodeForbidden       = -32003
+	CodeConflict        = -32009
+)
+
+const maxBatch = 50
+
+// Error is a typed JSON-RPC error. Method handlers return it (directly or
+// wrapped) to control the code sent to the client.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// InvalidParams wraps err as a CodeInvalidParams error.
+func InvalidParams(err error) *Error {
+	return &Error{Code: CodeInvalidParams, Message: "Invalid params", Data: err.Error()}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Method handles one RPC method. params is the raw "params" member (may be empty).
+type Method func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server dispatches JSON-RPC 2.0 requests, including batches and
+// notifications, to registered methods over HTTP POST or, via Handle, any
+// other transport.
+type Server struct {
+	methods map[string]Method
+	// MapError translates non-*Error errors (e.g. service.ErrNotFound) into
+	// RPC errors. Unmapped errors become CodeInternalError.
+	MapError func(error) *Error
+}
+
+func NewServer() *Server {
+	return &Server{methods: map[string]Method{}}
+}
+
+func (s *Server) Register(name string, m Method) {
+	s.methods[name] = m
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeJSON(w, response{JSONRPC: "2.0", Error: NewError(CodeParseError, "Parse error"), ID: json.RawMessage("null")})
+		return
+	}
+	if out, ok := s.dispatch(r.Context(), raw); ok {
+		writeJSON(w, out)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Handle executes a single request or batch from a non-HTTP transport and
+// returns the encoded response. ok is false when nothing should be sent back
+// (notifications only).
+func (s *Server) Handle(ctx context.Context, raw []byte) (out []byte, ok bool) {
+	if !json.Valid(raw) {
+		out, _ = json.Marshal(response{JSONRPC: "2.0", Error: NewError(CodeParseError, "Parse error"), ID: json.RawMessage("null")})
+		return out, true
+	}
+	resp, ok := s.dispatch(ctx, raw)
+	if !ok {
+		return nil, false
+	}
+	out, _ = json.Marshal(resp)
+	return out, true
+}
+
+// ErrorResponse encodes a standalone error response with a null id, for
+// transports that reject a message before it reaches the Server.
+func ErrorResponse(err *Error) []byte {
+	out, _ := json.Marshal(response{JSONRPC: "2.0", Error: err, ID: json.RawMessage("null")})
+	return out
+}
+
+// dispatch runs a single request or a batch. ok is false when every request
+// was a notification.
+func (s *Server) dispatch(ctx context.Context, raw json.RawMessage) (any, bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return s.call(ctx, raw)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(raw, &batch); err != nil || len(batch) == 0 {
+		return response{JSONRPC: "2.0", Error: NewError(CodeInvalidRequest, "Invalid Request"), ID: json.RawMessage("null")}, true
+	}
+	if len(batch) > maxBatch {
+		return response{JSONRPC: "2.0", Error: NewError(CodeInvalidRequest, "Batch too large"), ID: json.RawMessage("null")}, true
+	}
+
+	results := make([]*response, len(batch))
+	var wg sync.WaitGroup
+	for i, item := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, ok := s.call(ctx, item); ok {
+				results[i] = &resp
+			}
+		}()
+	}
+	wg.Wait()
+
+	out := make([]response, 0, len(results))
+	for _, resp := range results {
+		if resp != nil {
+			out = append(out, *resp)
+		}
+	}
+	// a batch of notifications only gets no response
+	return out, len(out) > 0
+}
+
+// call executes a single request. ok is false for notifications, which get no response.
+func (s *Server) call(ctx context.Context, raw json.RawMessage) (resp response, ok bool) {
+	resp = response{JSONRPC: "2.0", ID: json.RawMessage("null")}
+
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = NewError(CodeInvalidRequest, "Invalid Request")
+		return resp, true
+	}
+	notification := len(req.ID) == 0
+	if !notification {
+		resp.ID = req.ID
+	}
+
+	m, found := s.methods[req.Method]
+	if !found {
+		resp.Error = NewError(CodeMethodNotFound, "Method not found: "+req.Method)
+		return resp, !notification
+	}
+
+	result, err := s.invoke(ctx, m, req.Params)
+	if err != nil {
+		resp.Error = s.toError(err)
+	} else {
+		resp.Result = result
+		if resp.Result == nil {
+			resp.Result = struct{}{}
+		}
+	}
+	return resp, !notification
+}
+
+func (s *Server) invoke(ctx context.Context, m Method, params json.RawMessage) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = NewError(CodeInternalError, "Internal error")
+		}
+	}()
+	return m(ctx, params)
+}
+
+func (s *Server) toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	if s.MapError != nil {
+		if mapped := s.MapError(err); mapped != nil {
+			return mapped
+		}
+	}
+	return NewError(CodeInternalError, err.Error())
+}
+
+// Decode unmarshals params into dst, reporting failures as CodeInvalidParams.
+func Decode(params json.RawMessage, dst any) error {
+	if len(params) == 0 {
+		return InvalidParams(errors.New("params are required"))
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return InvalidParams(err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
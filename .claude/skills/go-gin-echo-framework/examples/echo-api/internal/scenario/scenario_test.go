@@ -0,0 +1,123 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// server stores POSTed things under generated IDs and serves them back.
+func server() http.Handler {
+	var mu sync.Mutex
+	things := map[string]map[string]any{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == http.MethodPost {
+			var thing map[string]any
+			json.NewDecoder(r.Body).Decode(&thing)
+			thing["id"] = "t-1"
+			thing["tags"] = []string{"a", "b"}
+			things["t-1"] = thing
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(thing)
+			return
+		}
+		thing, ok := things[strings.TrimPrefix(r.URL.Path, "/things/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Thing not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(thing)
+	})
+}
+
+const walkthrough = `
+name: things
+vars: {label: "lamp-${run}"}
+steps:
+  - name: create
+    request:
+      method: POST
+      path: /things
+      body: {name: "${label}", price: 2}
+    expect:
+      status: 201
+      body: {$.name: "${label}", $.price: 2.0, "$.tags[1]": b}
+      length: {$.tags: 2}
+    extract: {id: $.id}
+  - name: read
+    request: {method: GET, path: "/things/${id}"}
+    expect:
+      body: {$.id: t-1}
+      matches: {$.name: ^lamp-}
+      absent: [$.secret, "$.tags[2]"]
+`
+
+func TestRunExtractsAndInterpolates(t *testing.T) {
+	s, err := Parse([]byte(walkthrough))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	r := &Runner{Client: Handler(server()), Log: &log}
+	if err := r.Run(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(log.String(), "ok   things:"); got != 2 {
+		t.Fatalf("log reports %d passed steps, want 2:\n%s", got, log.String())
+	}
+}
+
+func TestRunReportsEveryMissedExpectation(t *testing.T) {
+	s, err := Parse([]byte(`
+name: wrong
+steps:
+  - name: read a missing thing
+    request: {method: GET, path: /things/nope}
+    expect:
+      status: 200
+      headers: {Content-Type: text/plain}
+      body: {$.error: Not found, $.id: nope}
+      length: {$.items: 1}
+  - name: never runs
+    request: {method: GET, path: /things/nope}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = (&Runner{Client: Handler(server())}).Run(context.Background(), s)
+	if err == nil {
+		t.Fatal("expected the step to fail")
+	}
+	for _, want := range []string{
+		`step 1 "read a missing thing"`,
+		"status 404, want 200",
+		`header Content-Type = "application/json; charset=utf-8", want "text/plain"`,
+		`$.error = "Thing not found", want "Not found"`,
+		`$.id is missing, want "nope"`,
+		"$.items has length -1, want 1",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestParseRejectsInvalidScenarios(t *testing.T) {
+	for name, src := range map[string]string{
+		"unknown field": "name: x\nsteps: [{request: {method: GET, path: /}, expected: {}}]",
+		"no name":       "steps: []",
+		"relative path": "name: x\nsteps: [{request: {method: GET, path: things}}]",
+		"bad pattern":   "name: x\nsteps: [{request: {method: GET, path: /}, expect: {matches: {$: '('}}}]",
+	} {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package schema versions the stored form of entities, so that records
+// written under an older schema are migrated lazily, as they are read,
+// rather than all at once. A model whose stored form changes implements
+// Versioned, listing an Upgrade from each version to the next; it is at
+// version len(SchemaUpgrades())+1. An upgraded record is written at the
+// current version on its next write.
+//
+// Records carry their version in the schema_version field, except at
+// version 1: every record written before its model was versioned is at
+// version 1. SQL tables are not versioned this way; their columns are
+// migrated up front by package migrations.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Field holds the schema version of a record.
+const Field = "schema_version"
+
+// ErrNewer is returned for records of a version newer than the model's,
+// written by a newer deployment.
+var ErrNewer = errors.New("newer schema version")
+
+// Upgrade changes doc, a record at one version, to the next version in
+// place. doc is keyed by the JSON field names of the model, holding the
+// values as the store decoded them.
+type Upgrade func(doc map[string]any) error
+
+// Versioned is implemented by models whose stored form changed.
+type Versioned interface {
+	// SchemaUpgrades returns the upgrades from version 1 to 2, 2 to 3 and
+	// so on. Append one for every change; never edit or remove one, since
+	// records of every version may still be stored.
+	SchemaUpgrades() []Upgrade
+}
+
+// upgrades returns the upgrades of the model of item, a value or a pointer.
+func upgrades(item any) []Upgrade {
+	if v, ok := item.(Versioned); ok {
+		return v.SchemaUpgrades()
+	}
+	if rv := reflect.ValueOf(item); rv.IsValid() && rv.Kind() != reflect.Pointer {
+		ptr := reflect.New(rv.Type())
+		if v, ok := ptr.Interface().(Versioned); ok {
+			return v.SchemaUpgrades()
+		}
+	}
+	return nil
+}
+
+// Version returns the current version of the model of item.
+func Version(item any) int {
+	return len(upgrades(item)) + 1
+}
+
+// Marshal returns the JSON of item, with its version in Field from
+// version 2 on.
+func Marshal(item any) ([]byte, error) {
+	data, err := json.Marshal(item)
+	v := Version(item)
+	if err != nil || v == 1 {
+		return data, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("schema: %T is not a JSON object", item)
+	}
+	stamp := `{"` + Field + `":` + strconv.Itoa(v)
+	if string(data) == "{}" {
+		return []byte(stamp + "}"), nil
+	}
+	return append([]byte(stamp+","), data[1:]...), nil
+}
+
+// Unmarshal decodes data, written by Marshal at any version up to the
+// current one of item's model, into item, upgrading it first.
+func Unmarshal(data []byte, item any) error {
+	if len(upgrades(item)) == 0 {
+		return json.Unmarshal(data, item)
+	}
+	doc, err := document(data)
+	if err != nil {
+		return err
+	}
+	version := 1
+	if v, ok := doc[Field]; ok {
+		n, _ := v.(json.Number)
+		i, err := n.Int64()
+		if err != nil {
+			return fmt.Errorf("schema: %s %v is not a number", Field, v)
+		}
+		version = int(i)
+		delete(doc, Field)
+	}
+	return decode(doc, version, item)
+}
+
+// UpgradeJSON returns data, the JSON of item's model at version, at the
+// current version, for records whose version is known from elsewhere,
+// such as an archive manifest.
+func UpgradeJSON(data []byte, version int, item any) ([]byte, error) {
+	if version == Version(item) {
+		return data, nil
+	}
+	doc, err := document(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := UpgradeDocument(doc, version, item); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// UpgradeDocument upgrades doc, a record of item's model at version, to
+// the current version in place, for stores that decode records themselves.
+func UpgradeDocument(doc map[string]any, version int, item any) error {
+	us := upgrades(item)
+	switch {
+	case version < 1:
+		return fmt.Errorf("schema: invalid version %d", version)
+	case version > len(us)+1:
+		return fmt.Errorf("%w %d of %T, this server has %d", ErrNewer, version, item, len(us)+1)
+	}
+	for v := version; v <= len(us); v++ {
+		if err := us[v-1](doc); err != nil {
+			return fmt.Errorf("schema: upgrade %T from version %d: %w", item, v, err)
+		}
+	}
+	return nil
+}
+
+func document(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep numbers exactly as stored
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func decode(doc map[string]any, version int, item any) error {
+	if err := UpgradeDocument(doc, version, item); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, item)
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// contact went through three versions:
+// 1: {"id", "name", "email"}, name being the full name
+// 2: name split into first_name and last_name
+// 3: email replaced by the list emails
+type contact struct {
+	ID        string   `json:"id"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Emails    []string `json:"emails"`
+}
+
+func (contact) SchemaUpgrades() []Upgrade {
+	return []Upgrade{
+		func(doc map[string]any) error {
+			name, _ := doc["name"].(string)
+			first, last, _ := strings.Cut(name, " ")
+			doc["first_name"], doc["last_name"] = first, last
+			delete(doc, "name")
+			return nil
+		},
+		func(doc map[string]any) error {
+			if email, _ := doc["email"].(string); email != "" {
+				doc["emails"] = []any{email}
+			}
+			delete(doc, "email")
+			return nil
+		},
+	}
+}
+
+func TestUnmarshalUpgradesEveryVersion(t *testing.T) {
+	want := contact{ID: "c1", FirstName: "Ada", LastName: "Lovelace", Emails: []string{"ada@example.com"}}
+	stored := map[int]string{
+		1: `{"id": "c1", "name": "Ada Lovelace", "email": "ada@example.com"}`,
+		2: `{"schema_version": 2, "id": "c1", "first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com"}`,
+		3: `{"schema_version": 3, "id": "c1", "first_name": "Ada", "last_name": "Lovelace", "emails": ["ada@example.com"]}`,
+	}
+	for v := 1; v <= Version(contact{}); v++ {
+		data, ok := stored[v]
+		if !ok {
+			t.Errorf("no record of version %d", v)
+			continue
+		}
+		var got contact
+		if err := Unmarshal([]byte(data), &got); err != nil {
+			t.Errorf("version %d: %v", v, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d = %+v, want %+v", v, got, want)
+		}
+	}
+
+	var got contact
+	if err := Unmarshal([]byte(`{"schema_version": 4, "id": "c1"}`), &got); !errors.Is(err, ErrNewer) {
+		t.Errorf("version 4: err = %v, want ErrNewer", err)
+	}
+}
+
+func TestMarshalStampsTheVersion(t *testing.T) {
+	c := contact{ID: "c1", FirstName: "Ada"}
+	data, err := Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc[Field] != float64(3) || doc["id"] != "c1" {
+		t.Errorf("Marshal = %s", data)
+	}
+	var back contact
+	if err := Unmarshal(data, &back); err != nil || back.FirstName != "Ada" {
+		t.Errorf("Unmarshal = %+v, %v", back, err)
+	}
+
+	// Records of version 1 are stored as they always were
+	type note struct {
+		ID string `json:"id"`
+	}
+	if data, _ := Marshal(note{ID: "n1"}); string(data) != `{"id":"n1"}` {
+		t.Errorf("Marshal(version 1) = %s", data)
+	}
+}
+
+func TestUpgradeJSON(t *testing.T) {
+	data, err := UpgradeJSON([]byte(`{"id": "c1", "name": "Ada Lovelace"}`), 1, &contact{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c contact
+	if err := json.Unmarshal(data, &c); err != nil || c.LastName != "Lovelace" {
+		t.Errorf("UpgradeJSON = %s, %v", data, err)
+	}
+	if _, err := UpgradeJSON([]byte(`{}`), 0, &contact{}); err == nil {
+		t.Error("UpgradeJSON accepted version 0")
+	}
+}
@@ -0,0 +1,132 @@
+package scim
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/your-username/echo-api/internal/model"
+)
+
+// Filter selects users by equality, the subset of RFC 7644 filters that
+// identity providers use to look a user up before creating it, e.g.
+// userName eq "ada@example.com" or externalId eq "00u1" and active eq true.
+// The zero Filter matches every user.
+type Filter []condition
+
+// condition is one "attribute eq value" of a filter.
+type condition struct {
+	attr  string // lower-cased, one of filterable
+	value string // true or false for active
+}
+
+// filterable maps the attributes a filter may test to whether their values
+// are compared case-sensitively.
+var filterable = map[string]bool{
+	"id":           true,
+	"externalid":   true,
+	"username":     false,
+	"displayname":  false,
+	"emails":       false,
+	"emails.value": false,
+	"active":       true,
+}
+
+// ParseFilter parses the filter query parameter of a list: conditions of
+// an attribute, the eq operator and a JSON string (or true or false for
+// active), joined by and. Anything else is an invalidFilter error.
+func ParseFilter(s string) (Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	var f Filter
+	for len(tokens) > 0 {
+		if len(f) > 0 {
+			if !strings.EqualFold(tokens[0], "and") {
+				return nil, invalidFilter("expected and, got %q", tokens[0])
+			}
+			tokens = tokens[1:]
+		}
+		if len(tokens) < 3 {
+			return nil, invalidFilter("expected an attribute, eq and a value")
+		}
+		attr, op, value := strings.ToLower(tokens[0]), tokens[1], tokens[2]
+		tokens = tokens[3:]
+		if _, ok := filterable[attr]; !ok {
+			return nil, invalidFilter("attribute %q cannot be filtered on", attr)
+		}
+		if !strings.EqualFold(op, "eq") {
+			return nil, invalidFilter("operator %q is not supported, only eq", op)
+		}
+		if attr == "active" {
+			if value != "true" && value != "false" {
+				return nil, invalidFilter("active must be compared with true or false")
+			}
+		} else if v, err := strconv.Unquote(value); err != nil || !strings.HasPrefix(value, `"`) {
+			return nil, invalidFilter("the value of %s must be a string", attr)
+		} else {
+			value = v
+		}
+		f = append(f, condition{attr: attr, value: value})
+	}
+	return f, nil
+}
+
+// tokenize splits s at spaces outside quoted strings.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if s[0] == '"' {
+			end = -1
+			for i := 1; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+				} else if s[i] == '"' {
+					end = i + 1
+					break
+				}
+			}
+			if end < 0 {
+				return nil, invalidFilter("unterminated string")
+			}
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		tokens = append(tokens, s[:end])
+		s = s[end:]
+	}
+	return tokens, nil
+}
+
+// Matches reports whether p meets every condition of f.
+func (f Filter) Matches(p model.Provisioned) bool {
+	for _, c := range f {
+		var got string
+		switch c.attr {
+		case "id":
+			got = p.ID
+		case "externalid":
+			got = p.ExternalID
+		case "username":
+			got = p.UserName
+		case "displayname":
+			got = p.DisplayName
+		case "emails", "emails.value":
+			got = p.Email
+		case "active":
+			got = strconv.FormatBool(p.Active)
+		}
+		if filterable[c.attr] && got != c.value || !filterable[c.attr] && !strings.EqualFold(got, c.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func invalidFilter(format string, args ...any) *Error {
+	return NewError(http.StatusBadRequest, "invalidFilter", "invalid filter: "+format, args...)
+}
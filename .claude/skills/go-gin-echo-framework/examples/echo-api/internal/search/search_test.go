@@ -0,0 +1,179 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/tenant"
+)
+
+func ids(res Results) []string {
+	var out []string
+	for _, h := range res.Hits {
+		out = append(out, h.ID)
+	}
+	return out
+}
+
+func newMemory(t *testing.T, docs ...Document) *Memory {
+	t.Helper()
+	m := NewMemory()
+	if err := m.Index(context.Background(), docs...); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMemoryRanksRarerAndMoreFrequentWordsFirst(t *testing.T) {
+	m := newMemory(t,
+		Document{ID: "a", Fields: map[string]string{"name": "Desk lamp", "description": "A lamp for the desk"}},
+		Document{ID: "b", Fields: map[string]string{"name": "Floor lamp", "description": "Lamp, lamp, lamp"}},
+		Document{ID: "c", Fields: map[string]string{"name": "Desk", "description": "Oak"}},
+		Document{ID: "d", Fields: map[string]string{"name": "Chair"}},
+	)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"lamp", []string{"b", "a"}},
+		{"DESK", []string{"c", "a"}},
+		{"oak desk lamp", []string{"c", "a", "b"}},
+		{"sofa", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		res, err := m.Search(context.Background(), Query{Text: tt.text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(res); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.text, got, tt.want)
+		}
+		if res.Total != len(tt.want) {
+			t.Errorf("%q: total %d, want %d", tt.text, res.Total, len(tt.want))
+		}
+	}
+}
+
+func TestMemoryHighlightsMatchedWordsAsHTML(t *testing.T) {
+	m := newMemory(t, Document{ID: "a", Fields: map[string]string{"name": "Lamp <b>& shade</b>", "email": "lamp@example.com", "note": "none"}})
+	res, err := m.Search(context.Background(), Query{Text: "lamp shade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"name":  "<em>Lamp</em> &lt;b&gt;&amp; <em>shade</em>&lt;/b&gt;",
+		"email": "<em>lamp</em>@example.com",
+	}
+	if got := res.Hits[0].Highlights; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMemoryPagesAndReplacesAndRemoves(t *testing.T) {
+	ctx := context.Background()
+	m := newMemory(t,
+		Document{ID: "a", Fields: map[string]string{"name": "lamp"}},
+		Document{ID: "b", Fields: map[string]string{"name": "lamp"}},
+		Document{ID: "c", Fields: map[string]string{"name": "lamp"}},
+	)
+	res, _ := m.Search(ctx, Query{Text: "lamp", Offset: 1, Limit: 1})
+	if got := ids(res); res.Total != 3 || !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("page 2: got %v of %d", got, res.Total)
+	}
+	res, _ = m.Search(ctx, Query{Text: "lamp", Offset: 5})
+	if res.Total != 3 || len(res.Hits) != 0 {
+		t.Errorf("past the end: got %v of %d", ids(res), res.Total)
+	}
+
+	m.Index(ctx, Document{ID: "a", Fields: map[string]string{"name": "chair"}})
+	m.Remove(ctx, "b", "missing")
+	res, _ = m.Search(ctx, Query{Text: "lamp chair"})
+	if got := ids(res); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("got %v, want [a c]", got)
+	}
+	if x := m.tenants[""]; len(x.postings["lamp"]) != 1 || x.lengths["b"] != 0 || x.words != 2 {
+		t.Errorf("stale index: %v %v %d", x.postings, x.lengths, x.words)
+	}
+}
+
+func TestMemoryCountsAndPagesTheHitsOfTheTenant(t *testing.T) {
+	acme := tenant.With(context.Background(), "acme")
+	globex := tenant.With(context.Background(), "globex")
+	m := NewMemory()
+	m.Index(acme, Document{ID: "a", Fields: map[string]string{"name": "lamp"}}, Document{ID: "b", Fields: map[string]string{"name": "lamp"}})
+	m.Index(globex, Document{ID: "a", Fields: map[string]string{"name": "lamp shade"}}, Document{ID: "g", Fields: map[string]string{"name": "lamp"}})
+	m.Remove(globex, "g")
+
+	res, _ := m.Search(acme, Query{Text: "lamp", Offset: 1})
+	if got := ids(res); res.Total != 2 || !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("acme page 2: got %v of %d, want [b] of 2", got, res.Total)
+	}
+	res, _ = m.Search(globex, Query{Text: "lamp"})
+	if got := ids(res); res.Total != 1 || !reflect.DeepEqual(got, []string{"a"}) || res.Hits[0].Highlights["name"] != "<em>lamp</em> shade" {
+		t.Errorf("globex: got %+v, want its own a", res)
+	}
+	res, _ = m.Search(context.Background(), Query{Text: "lamp"})
+	if res.Total != 0 || len(res.Hits) != 0 {
+		t.Errorf("without a tenant: got %v of %d", ids(res), res.Total)
+	}
+}
+
+func productDocument(p model.Product) Document {
+	return Document{ID: p.ID, Fields: map[string]string{"name": p.Name}}
+}
+
+func TestIndexingRepositoryIndexesCommittedWrites(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	uow := repository.NewSQLUnitOfWork(db)
+	base := repository.NewSQLRepository[model.Product](db, dialect, "products", "product")
+	base.Create(ctx, &model.Product{ID: "p-0", Name: "Desk lamp"})
+
+	m := NewMemory()
+	n, err := Reindex(ctx, base, m, productDocument)
+	if err != nil || n != 1 {
+		t.Fatalf("reindexed %d: %v", n, err)
+	}
+	repo := NewIndexingRepository(base, m, productDocument)
+
+	rollback := errors.New("rollback")
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		repo.Create(ctx, &model.Product{ID: "p-1", Name: "Oak chair"})
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if _, err := repo.Create(ctx, &model.Product{ID: "p-2", Name: "Oak chair"}); err != nil {
+			return err
+		}
+		if _, err := repo.Update(ctx, &model.Product{ID: "p-0", Name: "Brass lamp"}); err != nil {
+			return err
+		}
+		return repo.Delete(ctx, "p-2")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for text, want := range map[string][]string{"oak": nil, "desk": nil, "brass": {"p-0"}} {
+		res, _ := m.Search(ctx, Query{Text: text})
+		if got := ids(res); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", text, got, want)
+		}
+	}
+}
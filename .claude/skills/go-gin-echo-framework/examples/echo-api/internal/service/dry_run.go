@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+type dryRunKey struct{}
+
+// WithDryRun returns a copy of ctx on which writes are only tried: Create,
+// Update and Delete run their Before hooks and check that the item exists,
+// then return what the write would have returned without storing it, so
+// neither the After hooks, the audit entry nor the events happen. Created
+// items keep the ID the client or a hook gave them, if any; the generator
+// is not drawn from. Anything the Before hooks write is rolled back where
+// the UnitOfWork supports it.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx came from WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	return ctx.Value(dryRunKey{}) != nil
+}
+
+// errDryRun ends the unit of work of a dry run, rolling it back; the
+// operation then reports success.
+var errDryRun = errors.New("dry run")
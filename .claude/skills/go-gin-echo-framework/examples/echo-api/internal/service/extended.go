@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/your-username/echo-api/internal/custom"
+	"github.com/your-username/echo-api/internal/model"
+)
+
+// CustomFielded is implemented by services whose items carry custom fields:
+// Extended services and those Mapped over one.
+type CustomFielded interface {
+	// CustomFields returns the custom fields of the items in the scope of
+	// ctx, e.g. its tenant.
+	CustomFields(ctx context.Context) custom.Schema
+}
+
+// Extended checks the custom fields of every item next creates or updates
+// against the schema returns for the request, failing with ErrInvalid, and
+// stores them as the schema does. Items of a model that is not
+// model.Extensible pass unchecked.
+func Extended[T model.Entity](next CrudService[T], schema func(ctx context.Context) custom.Schema) CrudService[T] {
+	return &extendedService[T]{CrudService: next, schema: schema}
+}
+
+type extendedService[T model.Entity] struct {
+	CrudService[T]
+	schema func(ctx context.Context) custom.Schema
+}
+
+func (s *extendedService[T]) CustomFields(ctx context.Context) custom.Schema {
+	return s.schema(ctx)
+}
+
+func (s *extendedService[T]) Create(ctx context.Context, item *T) (*T, error) {
+	if err := s.check(ctx, item); err != nil {
+		return nil, err
+	}
+	return s.CrudService.Create(ctx, item)
+}
+
+func (s *extendedService[T]) Update(ctx context.Context, item *T) (*T, error) {
+	if err := s.check(ctx, item); err != nil {
+		return nil, err
+	}
+	return s.CrudService.Update(ctx, item)
+}
+
+func (s *extendedService[T]) check(ctx context.Context, item *T) error {
+	e, ok := any(item).(model.ExtensiblePtr)
+	if !ok {
+		return nil
+	}
+	values, err := s.schema(ctx).Check(e.CustomFields())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	e.SetCustomFields(values)
+	return nil
+}
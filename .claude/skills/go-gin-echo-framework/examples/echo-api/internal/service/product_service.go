@@ -2,16 +2,46 @@ package service
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/your-username/echo-api/internal/audit"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/events"
+	"github.com/your-username/echo-api/internal/idgen"
 	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/trash"
 )
 
-var ErrNotFound = errors.New("not found")
+type ProductService = CrudService[model.Product]
+
+func NewProductService(productRepo repository.ProductRepository, uow repository.UnitOfWork, bus *events.Bus, publisher domain.EventPublisher, rec *audit.Recorder, bin *trash.Bin, clk clock.Clock, ids idgen.Generator) ProductService {
+	return NewCrudService[model.Product](productRepo, uow, bus, publisher, rec, bin, clk, ids, "product", Hooks[model.Product]{
+		Merge:        mergeProduct,
+		BeforeCreate: normalizeProduct,
+		BeforeUpdate: normalizeProduct,
+	})
+}
+
+// mergeProduct keeps the custom fields of a product that an update leaves
+// out; an empty custom object clears them. A price of 0 is a price, so it
+// always comes from the update: the handlers decode a PUT onto the stored
+// product, so that one leaving the price out keeps it.
+func mergeProduct(stored model.Product, p *model.Product) {
+	if p.Custom == nil {
+		p.Custom = stored.Custom
+	}
+}
 
-type ProductService interface {
-	GetAllProducts(ctx context.Context) ([]model.Product, error)
-	GetProductByID(ctx context.Context, id string) (*model.Product, error)
-	CreateProduct(ctx context.Context, product *model.Product) (*model.Product, error)
-	UpdateProduct(ctx context.Context, product *model.Product) (*model.Product, error)
-	DeleteProduct(ctx context.Context, id string) error
+// normalizeProduct trims the name and rounds prices to whole cents.
+func normalizeProduct(_ context.Context, p *model.Product) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Price = math.Round(p.Price*100) / 100
+	if p.Name == "" {
+		return fmt.Errorf("%w: name must not be blank", ErrInvalid)
+	}
+	return nil
 }
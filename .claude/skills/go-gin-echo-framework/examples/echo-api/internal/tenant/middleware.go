@@ -0,0 +1,32 @@
+package tenant
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware scopes every request to the tenant r resolves, answering 400
+// when it names none or a malformed one, 403 when the caller belongs to
+// another tenant and 404 when the tenant is not served. It runs after
+// authentication so r can see the caller.
+func Middleware(r *Resolver) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, err := r.Resolve(c.Request())
+			if err != nil {
+				status := http.StatusBadRequest
+				switch {
+				case errors.Is(err, ErrForbidden):
+					status = http.StatusForbidden
+				case errors.Is(err, ErrUnknown):
+					status = http.StatusNotFound
+				}
+				return c.JSON(status, map[string]string{"error": err.Error()})
+			}
+			c.SetRequest(c.Request().WithContext(With(c.Request().Context(), id)))
+			return next(c)
+		}
+	}
+}
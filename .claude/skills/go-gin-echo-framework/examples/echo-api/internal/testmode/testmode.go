@@ -0,0 +1,188 @@
+// Package testmode makes the whole app deterministic for end-to-end tests.
+// A Harness holds what production takes from the outside world: the clock
+// (a clock.Fake that only moves when told to), the ID generator (seeded)
+// and the outbound effects (recorded instead of performed). Together with
+// config.EnableTestMode, which keeps all state in process, a sequence of
+// requests gives the same responses on every run.
+//
+// Secrets stay random by design: API keys, the state and nonce of OIDC
+// logins and request IDs.
+package testmode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/idgen"
+)
+
+// Options configure test mode.
+type Options struct {
+	Enabled bool      `yaml:"enabled"` // fake clock, seeded IDs, in-process state, captured effects
+	Seed    int64     `yaml:"seed"`    // random bits of generated IDs
+	Start   time.Time `yaml:"start"`   // where the fake clock starts
+}
+
+// Harness is the deterministic outside world of one app instance.
+type Harness struct {
+	Clock   *clock.Fake
+	IDs     idgen.Generator
+	Effects *Effects
+}
+
+// New builds the harness, generating IDs of the given idgen strategy.
+func New(opts Options, strategy string) (*Harness, error) {
+	clk := clock.NewFake(opts.Start)
+	ids, err := idgen.NewSeeded(strategy, clk, opts.Seed)
+	if err != nil {
+		return nil, err
+	}
+	return &Harness{Clock: clk, IDs: ids, Effects: &Effects{clock: clk}}, nil
+}
+
+// Effect kinds recorded by Effects.
+const (
+	KindEvent = "event" // a domain event, published in process as well
+	KindHTTP  = "http"  // an outbound HTTP request, answered 204 No Content
+)
+
+// Effect is an outbound effect of the app.
+type Effect struct {
+	Seq     int             `json:"seq"`
+	At      time.Time       `json:"at"`
+	Kind    string          `json:"kind"`
+	Target  string          `json:"target"`            // event name, or "<method> <url>"
+	Subject string          `json:"subject,omitempty"` // aggregate ID of an event
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// Decode unmarshals the body of the effect into v. The body of an event
+// is a domain.Message.
+func (e Effect) Decode(v any) error {
+	if e.Body == nil {
+		return fmt.Errorf("%s %s has no JSON body", e.Kind, e.Target)
+	}
+	return json.Unmarshal(e.Body, v)
+}
+
+// Query selects effects; empty fields match anything.
+type Query struct {
+	Kind    string
+	Target  string
+	Subject string
+}
+
+func (q Query) matches(e Effect) bool {
+	return (q.Kind == "" || e.Kind == q.Kind) &&
+		(q.Target == "" || e.Target == q.Target) &&
+		(q.Subject == "" || e.Subject == q.Subject)
+}
+
+// Effects records outbound effects in the order they happen.
+type Effects struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	seq     int
+	effects []Effect
+}
+
+// Record appends an effect about subject, which may be "". body is kept
+// if it is JSON.
+func (e *Effects) Record(kind, target, subject string, body []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seq++
+	effect := Effect{Seq: e.seq, At: e.clock.Now().UTC(), Kind: kind, Target: target, Subject: subject}
+	if json.Valid(body) {
+		effect.Body = body
+	}
+	e.effects = append(e.effects, effect)
+}
+
+// List returns the recorded effects of kind, or all of them if kind is "".
+func (e *Effects) List(kind string) []Effect {
+	return e.Find(Query{Kind: kind})
+}
+
+// Find returns the recorded effects selected by q, oldest first. Tests
+// assert on it, e.g. that product.created was published for a given ID:
+//
+//	effects.Find(testmode.Query{Kind: testmode.KindEvent, Target: "product.created", Subject: id})
+func (e *Effects) Find(q Query) []Effect {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := []Effect{}
+	for _, effect := range e.effects {
+		if q.matches(effect) {
+			out = append(out, effect)
+		}
+	}
+	return out
+}
+
+// Reset forgets the recorded effects; numbering carries on.
+func (e *Effects) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.effects = nil
+}
+
+// Publisher records domain events and passes them on to next, so that
+// in-process subscribers still run. It must not wrap a broker publisher:
+// test mode runs with domain_events.publisher inproc.
+func (e *Effects) Publisher(next domain.EventPublisher) domain.EventPublisher {
+	return &publisher{effects: e, next: next}
+}
+
+type publisher struct {
+	effects *Effects
+	next    domain.EventPublisher
+}
+
+func (p *publisher) Publish(ctx context.Context, ev domain.Event) error {
+	data, err := domain.Encode(ev)
+	if err != nil {
+		return err
+	}
+	p.effects.Record(KindEvent, ev.EventName(), ev.AggregateID(), data)
+	return p.next.Publish(ctx, ev)
+}
+
+// Client returns an HTTP client that records requests instead of sending
+// them, answering each with 204 No Content.
+func (e *Effects) Client() *http.Client {
+	return &http.Client{Transport: roundTripper{e}}
+}
+
+type roundTripper struct{ effects *Effects }
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	rt.effects.Record(KindHTTP, req.Method+" "+req.URL.String(), "", body)
+	return &http.Response{
+		StatusCode: http.StatusNoContent,
+		Status:     "204 No Content",
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package testmode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/model"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestHarnessesWithTheSameSeedAgree(t *testing.T) {
+	run := func() []string {
+		h, err := New(Options{Enabled: true, Seed: 7, Start: start}, "uuidv7")
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := h.IDs.NewID()
+		h.Clock.Advance(time.Hour)
+		return []string{first, h.IDs.NewID(), h.Clock.Now().String()}
+	}
+	if a, b := run(), run(); strings.Join(a, " ") != strings.Join(b, " ") {
+		t.Fatalf("two runs gave %v and %v", a, b)
+	}
+}
+
+func TestEffectsAreRecordedInOrder(t *testing.T) {
+	h, err := New(Options{Enabled: true, Start: start}, "sequential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inproc := domain.NewInProc()
+	var delivered int
+	domain.Subscribe(inproc, func(context.Context, domain.Created[model.Product]) error {
+		delivered++
+		return nil
+	})
+	pub := h.Effects.Publisher(inproc)
+	if err := pub.Publish(context.Background(), domain.Created[model.Product]{Resource: "product", Entity: model.Product{ID: "id-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	h.Clock.Advance(time.Minute)
+	resp, err := h.Effects.Client().Post("https://hooks.example.com/products", "application/json", strings.NewReader(`{"id":"id-1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if delivered != 1 || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delivered %d in process, outbound answered %d", delivered, resp.StatusCode)
+	}
+	all := h.Effects.List("")
+	if len(all) != 2 || all[0].Target != "product.created" || all[1].Target != "POST https://hooks.example.com/products" ||
+		string(all[1].Body) != `{"id":"id-1"}` || !all[1].At.Equal(start.Add(time.Minute)) {
+		t.Fatalf("recorded %+v", all)
+	}
+	if events := h.Effects.List(KindEvent); len(events) != 1 || events[0].Seq != 1 {
+		t.Fatalf("events %+v, want the first effect only", events)
+	}
+	h.Effects.Reset()
+	if all := h.Effects.List(""); len(all) != 0 {
+		t.Fatalf("%d effects after Reset", len(all))
+	}
+}
+
+func TestFindSelectsEventsBySubject(t *testing.T) {
+	h, err := New(Options{Enabled: true, Start: start}, "sequential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := h.Effects.Publisher(domain.NewInProc())
+	for i, id := range []string{"id-1", "id-2"} {
+		if err := pub.Publish(context.Background(), domain.Created[model.Product]{Resource: "product", Entity: model.Product{ID: id, Price: float64(i + 1)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found := h.Effects.Find(Query{Kind: KindEvent, Target: "product.created", Subject: "id-2"})
+	if len(found) != 1 || found[0].Seq != 2 {
+		t.Fatalf("found %+v, want the second event", found)
+	}
+	var msg domain.Message
+	if err := found[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	var created domain.Created[model.Product]
+	if err := json.Unmarshal(msg.Data, &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Entity.Price != 2 {
+		t.Fatalf("decoded %+v", created)
+	}
+	if none := h.Effects.Find(Query{Target: "product.deleted"}); len(none) != 0 {
+		t.Fatalf("found %+v for an event never published", none)
+	}
+}
@@ -0,0 +1,114 @@
+package transform
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuleValidate(t *testing.T) {
+	valid := Rule{Path: "/legacy", Methods: []string{"GET"}, RewritePath: "/users", SetResponseHeaders: map[string]string{"X-Legacy": "1"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate(%+v) = %v", valid, err)
+	}
+	for _, r := range []Rule{
+		{Path: "legacy"},
+		{Path: "/legacy", RewritePath: "users"},
+		{Path: "/legacy", Methods: []string{"get"}},
+		{Path: "/legacy", RemoveRequestHeaders: []string{"X Bad"}},
+		{Path: "/legacy", ResponseFields: map[string]string{"": "name"}},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", r)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	rule := Rule{
+		Path:               "/legacy",
+		RewritePath:        "/users",
+		SetResponseHeaders: map[string]string{"X-Legacy": "1"},
+		ResponseFields:     map[string]string{"name": "full_name", "email": ""},
+	}
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		lines       []string
+		flush       bool
+		want        string
+	}{
+		{"json", "/legacy/1", "application/json; charset=utf-8", []string{`{"id":"1","name":"Ada","email":"ada@example.com"}`}, false, `{"full_name":"Ada","id":"1"}`},
+		{"no rule", "/users/1", "application/json", []string{`{"name":"Ada"}`}, false, `{"name":"Ada"}`},
+		{"text", "/legacy/1", "text/plain", []string{"name"}, false, "name"},
+		{"invalid json", "/legacy/1", "application/json", []string{`{"name":`}, false, `{"name":`},
+		{"ndjson stream", "/legacy/", "application/x-ndjson", []string{"{\"name\":\"Ada\"}\n", "{\"name\":\"Bob\"}\n"}, true, "{\"name\":\"Ada\"}\n{\"name\":\"Bob\"}\n"},
+		{"flushed json", "/legacy/", "application/json", []string{"[", `{"name":"Ada"}`, "]"}, true, `[{"name":"Ada"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			h := Middleware(func() []Rule { return []Rule{rule} })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				for _, line := range tt.lines {
+					w.Write([]byte(line))
+					if tt.flush {
+						w.(http.Flusher).Flush()
+					}
+				}
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != http.StatusCreated || w.Body.String() != tt.want {
+				t.Errorf("got %d %q, want %q", w.Code, w.Body, tt.want)
+			}
+			if w.Flushed != tt.flush {
+				t.Errorf("flushed = %v", w.Flushed)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q", got)
+			}
+			matched := tt.path != "/users/1"
+			if got := w.Header().Get("X-Legacy") == "1"; got != matched {
+				t.Errorf("X-Legacy set = %v, want %v", got, matched)
+			}
+			if matched && path != "/users"+tt.path[len("/legacy"):] {
+				t.Errorf("handler saw %s", path)
+			}
+		})
+	}
+}
+
+// hijacker is a ResponseWriter whose connection can be taken over.
+type hijacker struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	for _, rule := range []Rule{
+		{Path: "/ws", SetResponseHeaders: map[string]string{"X-Legacy": "1"}},
+		{Path: "/ws", ResponseFields: map[string]string{"name": "full_name"}},
+	} {
+		h := Middleware(func() []Rule { return []Rule{rule} })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+				t.Fatal(err)
+			}
+		}))
+		w := &hijacker{ResponseRecorder: httptest.NewRecorder()}
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+		if !w.hijacked || w.Body.Len() != 0 {
+			t.Errorf("%+v: hijacked = %v, body %q", rule, w.hijacked, w.Body)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/your-username/echo-api/internal/compat"
+)
+
+// CompatBinder is echo's DefaultBinder with JSON bodies decoded under the
+// request's compatibility mode: unknown fields are rejected in strict mode
+// and logged in lenient mode.
+type CompatBinder struct {
+	echo.DefaultBinder
+}
+
+func NewCompatBinder() *CompatBinder {
+	return &CompatBinder{}
+}
+
+func (b *CompatBinder) Bind(i interface{}, c echo.Context) error {
+	if err := b.BindPathParams(c, i); err != nil {
+		return err
+	}
+	req := c.Request()
+	if req.Method == http.MethodGet || req.Method == http.MethodDelete || req.Method == http.MethodHead {
+		if err := b.BindQueryParams(c, i); err != nil {
+			return err
+		}
+	}
+	if !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return b.BindBody(c, i)
+	}
+	return compat.DecodeRequest(req, i)
+}
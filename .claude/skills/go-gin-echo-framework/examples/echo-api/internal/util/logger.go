@@ -0,0 +1,76 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/your-username/echo-api/internal/auth"
+)
+
+// accessLine mirrors the fields of echo's default Logger format, plus the
+// authenticated caller.
+type accessLine struct {
+	Time         string `json:"time"`
+	ID           string `json:"id"`
+	RemoteIP     string `json:"remote_ip"`
+	Host         string `json:"host"`
+	Method       string `json:"method"`
+	URI          string `json:"uri"`
+	Caller       string `json:"caller,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	Status       int    `json:"status"`
+	Error        string `json:"error"`
+	Latency      int64  `json:"latency"`
+	LatencyHuman string `json:"latency_human"`
+	BytesIn      string `json:"bytes_in,omitempty"`
+	BytesOut     int64  `json:"bytes_out,omitempty"`
+}
+
+// NewLogger is echo's JSON access log, written only for requests where
+// sample(status) is true. verbose adds the user agent and body sizes.
+func NewLogger(verbose bool, sample func(status int) bool) echo.MiddlewareFunc {
+	out := json.NewEncoder(os.Stdout)
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		HandleError:      true, // let the error handler set the status before it is logged
+		LogLatency:       true,
+		LogRemoteIP:      true,
+		LogHost:          true,
+		LogMethod:        true,
+		LogURI:           true,
+		LogStatus:        true,
+		LogError:         true,
+		LogRequestID:     true,
+		LogUserAgent:     verbose,
+		LogContentLength: verbose,
+		LogResponseSize:  verbose,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			if !sample(v.Status) {
+				return nil
+			}
+			line := accessLine{
+				Time:         v.StartTime.Format(time.RFC3339Nano),
+				ID:           v.RequestID,
+				RemoteIP:     v.RemoteIP,
+				Host:         v.Host,
+				Method:       v.Method,
+				URI:          v.URI,
+				UserAgent:    v.UserAgent,
+				Status:       v.Status,
+				Latency:      v.Latency.Nanoseconds(),
+				LatencyHuman: v.Latency.String(),
+				BytesIn:      v.ContentLength,
+				BytesOut:     v.ResponseSize,
+			}
+			if caller, ok := c.Get(auth.CallerKey).(*auth.Caller); ok {
+				line.Caller = caller.String()
+			}
+			if v.Error != nil {
+				line.Error = v.Error.Error()
+			}
+			return out.Encode(line)
+		},
+	})
+}
@@ -0,0 +1,203 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Major types of CBOR data items (RFC 8949 section 3.1).
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+)
+
+// maxDepth bounds the nesting of decoded items, so a hostile client
+// cannot exhaust the stack.
+const maxDepth = 16
+
+var errTruncated = errors.New("truncated CBOR")
+
+// decodeCBOR decodes the data item at the start of b and returns it with
+// the bytes after it. Unsigned and negative integers decode to int64, byte
+// strings to []byte, text to string, arrays to []any, maps to map[any]any
+// and false, true and null to bool and nil. Authenticators encode with
+// definite lengths (CTAP2 canonical CBOR); indefinite lengths and floats
+// are refused.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeItem(b, 0)
+}
+
+func decodeItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxDepth {
+		return nil, nil, errors.New("CBOR nested too deeply")
+	}
+	if len(b) == 0 {
+		return nil, nil, errTruncated
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	if major == majorSimple {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22:
+			return nil, b, nil
+		}
+		return nil, nil, fmt.Errorf("unsupported CBOR simple value %d", info)
+	}
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(b) < n {
+			return nil, nil, errTruncated
+		}
+		for _, c := range b[:n] {
+			arg = arg<<8 | uint64(c)
+		}
+		b = b[n:]
+	default:
+		return nil, nil, errors.New("indefinite-length CBOR is not supported")
+	}
+
+	switch major {
+	case majorUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("CBOR integer overflows int64")
+		}
+		return int64(arg), b, nil
+	case majorNegative:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("CBOR integer overflows int64")
+		}
+		return -1 - int64(arg), b, nil
+	case majorBytes, majorText:
+		if arg > uint64(len(b)) {
+			return nil, nil, errTruncated
+		}
+		s := b[:arg]
+		if major == majorText {
+			return string(s), b[arg:], nil
+		}
+		return append([]byte(nil), s...), b[arg:], nil
+	case majorArray:
+		if arg > uint64(len(b)) { // every item takes a byte at least
+			return nil, nil, errTruncated
+		}
+		items := make([]any, 0, arg)
+		for range arg {
+			item, rest, err := decodeItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items, b = append(items, item), rest
+		}
+		return items, b, nil
+	case majorMap:
+		if arg > uint64(len(b))/2 {
+			return nil, nil, errTruncated
+		}
+		m := make(map[any]any, arg)
+		for range arg {
+			k, rest, err := decodeItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("CBOR map keys must be integers or text")
+			}
+			v, rest, err := decodeItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, dup := m[k]; dup {
+				return nil, nil, fmt.Errorf("duplicate CBOR map key %v", k)
+			}
+			m[k], b = v, rest
+		}
+		return m, b, nil
+	default: // majorTag: the tagged item stands for itself
+		return decodeItem(b, depth+1)
+	}
+}
+
+// encodeCBOR encodes v, made of the types decodeCBOR returns and int, with
+// map keys in the canonical order of CTAP2. It serves the Authenticator.
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v < 0 {
+			return head(majorNegative, uint64(-1-v))
+		}
+		return head(majorUnsigned, uint64(v))
+	case []byte:
+		return append(head(majorBytes, uint64(len(v))), v...)
+	case string:
+		return append(head(majorText, uint64(len(v))), v...)
+	case []any:
+		out := head(majorArray, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case map[any]any:
+		type pair struct{ k, v []byte }
+		pairs := make([]pair, 0, len(v))
+		for k, item := range v {
+			pairs = append(pairs, pair{encodeCBOR(k), encodeCBOR(item)})
+		}
+		// Shorter keys first, then bytewise (RFC 7049 section 3.9)
+		sort.Slice(pairs, func(i, j int) bool {
+			a, b := pairs[i].k, pairs[j].k
+			if len(a) != len(b) {
+				return len(a) < len(b)
+			}
+			return string(a) < string(b)
+		})
+		out := head(majorMap, uint64(len(v)))
+		for _, p := range pairs {
+			out = append(append(out, p.k...), p.v...)
+		}
+		return out
+	case bool:
+		if v {
+			return []byte{majorSimple<<5 | 21}
+		}
+		return []byte{majorSimple<<5 | 20}
+	case nil:
+		return []byte{majorSimple<<5 | 22}
+	}
+	panic(fmt.Sprintf("webauthn: cannot encode %T as CBOR", v))
+}
+
+// head encodes the initial bytes of an item of the major type with arg.
+func head(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= math.MaxUint8:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
@@ -0,0 +1,103 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms of the signatures verified (RFC 9053), in the order
+// CreationOptions offers them.
+const (
+	AlgES256 = -7   // ECDSA with P-256 and SHA-256, what most authenticators use
+	AlgEdDSA = -8   // Ed25519
+	AlgRS256 = -257 // RSASSA-PKCS1-v1_5 with SHA-256, for Windows Hello
+)
+
+// Parameters of a COSE_Key (RFC 9052 section 7, RFC 9053 section 7).
+const (
+	coseKty = 1
+	coseAlg = 3
+
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	curveP256    = 1
+	curveEd25519 = 6
+)
+
+// publicKey is a parsed COSE_Key and the algorithm it signs with.
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey parses the COSE_Key encoding of a credential's public key,
+// as stored from its registration.
+func parsePublicKey(b []byte) (*publicKey, error) {
+	v, rest, err := decodeCBOR(b)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	m, ok := v.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, errors.New("public key is not a COSE_Key")
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+	param := func(label int64) []byte {
+		b, _ := m[label].([]byte)
+		return b
+	}
+	curve, _ := m[int64(-1)].(int64)
+
+	switch {
+	case kty == ktyEC2 && alg == AlgES256:
+		x, y := param(-2), param(-3)
+		if curve != curveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("ES256 key is not on P-256")
+		}
+		// ecdh checks that the point is on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("ES256 key: %w", err)
+		}
+		return &publicKey{alg: alg, key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}}, nil
+	case kty == ktyOKP && alg == AlgEdDSA:
+		x := param(-2)
+		if curve != curveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("EdDSA key is not an Ed25519 key")
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case kty == ktyRSA && alg == AlgRS256:
+		n, e := param(-1), param(-2)
+		exp := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31 {
+			return nil, errors.New("RS256 key is shorter than 2048 bits or has an invalid exponent")
+		}
+		return &publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+}
+
+// verify reports whether sig is the key's signature of data.
+func (k *publicKey) verify(data, sig []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/your-username/echo-api/internal/secret"
+)
+
+// GitHub is the Provider of a GitHub webhook with content type
+// application/json. Its deliveries carry the hex HMAC-SHA256 of the body,
+// keyed with Secret, in X-Hub-Signature-256, their GUID in
+// X-GitHub-Delivery and their event in X-GitHub-Event. GitHub signs no
+// timestamp, so its replays are refused for Options.ReplayWindow only.
+type GitHub struct {
+	Secret secret.Secret `yaml:"secret"` // of the webhook; empty disables it
+}
+
+// Verify implements Provider.
+func (g GitHub) Verify(header http.Header, body []byte) (Delivery, error) {
+	sig, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	if !ok {
+		return Delivery{}, fmt.Errorf("%w: X-Hub-Signature-256 must be sha256= and the HMAC of the body", ErrSignature)
+	}
+	got, err := hex.DecodeString(sig)
+	mac := hmac.New(sha256.New, []byte(g.Secret.Reveal()))
+	mac.Write(body)
+	if err != nil || !hmac.Equal(got, mac.Sum(nil)) {
+		return Delivery{}, fmt.Errorf("%w: X-Hub-Signature-256 does not match the body", ErrSignature)
+	}
+	d := Delivery{ID: header.Get("X-GitHub-Delivery"), Event: header.Get("X-GitHub-Event")}
+	if d.ID == "" || d.Event == "" {
+		return Delivery{}, fmt.Errorf("%w: X-GitHub-Delivery and X-GitHub-Event are required", ErrInvalid)
+	}
+	return d, nil
+}
+
+// GitHubPing is the payload of the ping event, sent when a webhook is
+// created.
+type GitHubPing struct {
+	Zen    string `json:"zen"`
+	HookID int64  `json:"hook_id" validate:"required"`
+}
+
+// GitHubPush is the part of the payload of the push event the example
+// handler reads.
+type GitHubPush struct {
+	Ref        string           `json:"ref" validate:"required"`
+	After      string           `json:"after" validate:"required"` // commit the ref points to now
+	Repository GitHubRepository `json:"repository"`
+	Commits    []GitHubCommit   `json:"commits" validate:"dive"`
+}
+
+type GitHubRepository struct {
+	FullName string `json:"full_name" validate:"required"`
+}
+
+type GitHubCommit struct {
+	ID      string `json:"id" validate:"required"`
+	Message string `json:"message"`
+}
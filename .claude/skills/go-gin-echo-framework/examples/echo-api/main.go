@@ -2,71 +2,1164 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/your-username/echo-api/config"
+	"github.com/your-username/echo-api/internal/apiversion"
+	"github.com/your-username/echo-api/internal/archive"
+	"github.com/your-username/echo-api/internal/audit"
+	"github.com/your-username/echo-api/internal/auth"
+	"github.com/your-username/echo-api/internal/batch"
+	"github.com/your-username/echo-api/internal/blob"
+	"github.com/your-username/echo-api/internal/cache"
+	"github.com/your-username/echo-api/internal/changes"
+	"github.com/your-username/echo-api/internal/clock"
+	"github.com/your-username/echo-api/internal/compat"
+	"github.com/your-username/echo-api/internal/compression"
+	"github.com/your-username/echo-api/internal/confirm"
+	"github.com/your-username/echo-api/internal/container"
+	"github.com/your-username/echo-api/internal/cors"
+	"github.com/your-username/echo-api/internal/custom"
+	"github.com/your-username/echo-api/internal/describe"
+	"github.com/your-username/echo-api/internal/domain"
+	"github.com/your-username/echo-api/internal/envelope"
+	"github.com/your-username/echo-api/internal/events"
+	"github.com/your-username/echo-api/internal/eventschema"
+	"github.com/your-username/echo-api/internal/export"
+	"github.com/your-username/echo-api/internal/fixtures"
+	"github.com/your-username/echo-api/internal/flags"
+	"github.com/your-username/echo-api/internal/graph"
+	"github.com/your-username/echo-api/internal/grpcapi"
 	"github.com/your-username/echo-api/internal/handler"
+	"github.com/your-username/echo-api/internal/hardening"
+	"github.com/your-username/echo-api/internal/health"
+	"github.com/your-username/echo-api/internal/hooks"
+	"github.com/your-username/echo-api/internal/httpclient"
+	"github.com/your-username/echo-api/internal/idgen"
+	"github.com/your-username/echo-api/internal/lifecycle"
+	"github.com/your-username/echo-api/internal/logging"
+	"github.com/your-username/echo-api/internal/migrations"
+	"github.com/your-username/echo-api/internal/mock"
+	"github.com/your-username/echo-api/internal/model"
+	"github.com/your-username/echo-api/internal/mqtt"
+	"github.com/your-username/echo-api/internal/notify"
+	"github.com/your-username/echo-api/internal/openapi"
+	"github.com/your-username/echo-api/internal/outbox"
+	"github.com/your-username/echo-api/internal/payloadlog"
+	productsv1 "github.com/your-username/echo-api/internal/pb/products/v1"
+	"github.com/your-username/echo-api/internal/pipeline"
+	"github.com/your-username/echo-api/internal/ratelimit"
+	"github.com/your-username/echo-api/internal/recorder"
 	"github.com/your-username/echo-api/internal/repository"
+	"github.com/your-username/echo-api/internal/requestid"
+	"github.com/your-username/echo-api/internal/resilience"
+	"github.com/your-username/echo-api/internal/routecheck"
+	"github.com/your-username/echo-api/internal/rpc"
+	"github.com/your-username/echo-api/internal/scim"
+	"github.com/your-username/echo-api/internal/search"
 	"github.com/your-username/echo-api/internal/service"
+	"github.com/your-username/echo-api/internal/tenant"
+	"github.com/your-username/echo-api/internal/testmode"
+	"github.com/your-username/echo-api/internal/transform"
+	"github.com/your-username/echo-api/internal/trash"
+	"github.com/your-username/echo-api/internal/util"
+	"github.com/your-username/echo-api/internal/webhook"
+	"github.com/your-username/echo-api/internal/worker"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/net/http2"
 )
 
+// Infrastructure routes that are intentionally absent from the OpenAPI spec.
+var undocumented = []string{"/healthz", "/readyz", "/metrics/cache", "/debug/breakers", "/rpc", "/batch", "/openapi.json", "/docs", "/testmode/clock", "/testmode/effects", "/graphql", "/debug/pprof/*"}
+
+// @title Echo API
+// @version 1.0
+// @description Example product service built with Echo.
+// @BasePath /
+// @securityDefinitions.bearer BearerAuth
+// @securityDefinitions.bearer SCIMToken
 func main() {
-	e := echo.New()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	logging.Setup(cfg.Logging)
 
-	// Middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+	// Components register their hooks here as they are built; see startup
+	// and shutdown below
+	lc := lifecycle.New()
 
-	// Simple health check endpoint
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]string{"status": "UP"})
+	// Reload the config file on change; components subscribe or read Current()
+	watcher, err := config.NewWatcher(cfg, os.Args[1:])
+	if err != nil {
+		log.Fatalf("config watcher: %v", err)
+	}
+	lc.RegisterCloser("config watcher", 0, watcher)
+	watcher.Subscribe(func(old, new *config.Config) {
+		if old.Logging.Level != new.Logging.Level {
+			logging.SetLevel(new.Logging.Level)
+		}
 	})
 
-	// Initialize Product components
-	productRepo := repository.NewProductRepository()
-	productService := service.NewProductService(productRepo)
-	productHandler := handler.NewProductHandler(productService)
+	e := newServer(newContainer(cfg, watcher, lc))
 
-	// Product routes
-	productRoutes := e.Group("/products")
-	{
-		productRoutes.GET("/", productHandler.GetProducts)
-		productRoutes.GET("/:id", productHandler.GetProductByID)
-		productRoutes.POST("/", productHandler.CreateProduct)
-		productRoutes.PUT("/:id", productHandler.UpdateProduct)
-		productRoutes.DELETE("/:id", productHandler.DeleteProduct)
+	// Summarise what is being served; -describe prints it and stops here
+	var routes []openapi.Route
+	for _, r := range e.Routes() {
+		if r.Method != echo.RouteNotFound {
+			routes = append(routes, openapi.Route{Method: r.Method, Path: r.Path})
+		}
+	}
+	description, err := describe.New(cfg, routes)
+	if err != nil {
+		log.Fatalf("describe: %v", err)
+	}
+	if cfg.DescribeOnly() {
+		if err := description.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("describe: %v", err)
+		}
+		lc.Shutdown(context.Background())
+		return
 	}
+	description.Log(slog.Default())
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Start the components' background work: jobs, relays, listeners
+	if err := lc.Start(context.Background()); err != nil {
+		lc.Shutdown(context.Background())
+		log.Fatalf("start: %v", err)
 	}
 
 	// Graceful shutdown
 	go func() {
-		if err := e.Start(":" + port); err != nil && err != http.ErrServerClosed {
+		start := func() error { return e.Start(":" + cfg.Server.Port) }
+		if cfg.GRPC.Multiplex {
+			// Plaintext HTTP/2 for the gRPC clients sharing the port
+			start = func() error { return e.StartH2CServer(":"+cfg.Server.Port, &http2.Server{}) }
+		}
+		if err := start(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
 
-	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 5 seconds.
+	// Wait for interrupt signal to gracefully shutdown the server within server.shutdown_timeout.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := e.Shutdown(ctx); err != nil {
+	// Drain components in reverse registration order, each within its own timeout
+	if err := lc.Shutdown(context.Background()); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
 	log.Println("Server exiting")
 }
+
+// newServer wires the repositories, services and routes over the shared
+// components of c (see newContainer) and registers their hooks with its
+// lifecycle. The returned HTTP server has not been started, nor have the
+// hooks.
+func newServer(c *container.Container) *echo.Echo {
+	cfg, watcher, lc := get[*config.Config](c), get[*config.Watcher](c), c.Lifecycle()
+
+	e := echo.New()
+	e.HideBanner = cfg.Environment == config.Production
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Validator = util.NewCustomValidator()
+	e.Binder = util.NewCompatBinder()
+	// RealIP, which keys per-client rate limits, believes X-Forwarded-For
+	// from server.trusted_proxies only
+	e.IPExtractor = ipExtractor(cfg.TrustedProxies())
+
+	// gRPC multiplexed on the server port is dispatched ahead of all HTTP
+	// middleware; its services are registered with the others below
+	var grpcServer *grpcapi.Server
+	if cfg.GRPC.Multiplex {
+		grpcServer = grpcapi.New()
+		e.Pre(grpcapi.Multiplex(grpcServer))
+	}
+
+	// Record every registration: the router keeps only the last handler for a
+	// duplicate method and path, which the startup route check must still see
+	var routes []routecheck.Route
+	e.OnAddRouteHandler = func(_ string, r echo.Route, _ echo.HandlerFunc, _ []echo.MiddlewareFunc) {
+		if r.Method != echo.RouteNotFound { // catch-alls added by groups with middleware
+			routes = append(routes, routecheck.Route{Method: r.Method, Path: r.Path, Handler: r.Name})
+		}
+	}
+
+	// Middleware
+	// Responses are counted for the error rate of notify.events in their
+	// final form, around all other middleware
+	requests := get[*notify.Requests](c) // nil unless events are posted
+	if requests != nil {
+		e.Pre(wrapResponseMiddleware(requests.Middleware))
+	}
+	// Compression is registered next so it wraps the other rewriting
+	// middleware and encodes bodies in their final form
+	e.Pre(wrapResponseMiddleware(compression.Middleware(cfg.Compression)))
+	// Gateway-style transformation rules run before routing so path rewrites
+	// pick the handler; the rules are reloaded with the config file
+	e.Pre(wrapResponseMiddleware(transform.Middleware(func() []transform.Rule {
+		return watcher.Current().Transforms
+	})))
+	// Unversioned /api requests are rewritten to the version they negotiate;
+	// the versioned routes are mounted with the product routes below
+	apiVersions := []apiversion.Version{1, 2}
+	latest := apiVersions[len(apiVersions)-1]
+	e.Pre(echo.WrapMiddleware(apiversion.Rewrite(apiVersions, latest)))
+	// Strict or lenient handling of unknown request fields and query
+	// parameters, applied by the binder; reloaded with the config file
+	e.Pre(echo.WrapMiddleware(compat.Middleware(func() compat.Mode {
+		return watcher.Current().Compatibility
+	})))
+	// Legacy clients opt into the {"data", "meta", "error"} envelope per
+	// request; it wraps the handlers' output before any transformation
+	e.Pre(wrapResponseMiddleware(envelope.Middleware(func() envelope.Options {
+		return watcher.Current().Envelope
+	})))
+
+	// Global middleware as ordered stages (see pipeline.Policy), bundled per
+	// environment by the middleware preset (see pipeline.Presets)
+	preset := cfg.MiddlewarePreset()
+	global := []pipeline.Stage[echo.MiddlewareFunc]{
+		{Name: pipeline.Recovery, Middleware: middleware.Recover()},
+		{Name: pipeline.RequestID, Middleware: requestid.Middleware()},
+		{Name: pipeline.Logging, Requires: []string{pipeline.RequestID}, Middleware: util.NewLogger(preset.VerboseLogging, preset.ShouldLog)}, // logs the ID as "id"
+	}
+	if cfg.PayloadLog.Enabled {
+		global = append(global, pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.Payload, Requires: []string{pipeline.RequestID}, Middleware: payloadlog.Middleware(payloadlog.New(cfg.PayloadLog, nil))})
+	}
+	corsOptions := cfg.CORSOptions()
+	if len(corsOptions.AllowedOrigins) > 0 {
+		global = append(global, pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.CORS, Middleware: cors.Middleware(cors.New(corsOptions))})
+	}
+	stages, err := pipeline.New(global...)
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+	e.Use(stages.Middleware()...)
+
+	// Security headers and request limits are set per route group (see
+	// config.SecurityFor); routes outside the groups below use "default"
+	security := func(group string) pipeline.Stage[echo.MiddlewareFunc] {
+		return pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.Security, Middleware: hardening.Middleware(hardening.New(cfg.SecurityFor(group)))}
+	}
+	unscoped, err := stages.Extend("default", security("default"))
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+
+	// Test mode controls, of the fake clock and recorded effects
+	harness := get[*testmode.Harness](c)
+	if harness != nil {
+		handler.NewTestModeHandler(harness).Register(e.Group("/testmode", unscoped...))
+	}
+
+	// Circuit breakers of the calls to the database and other services
+	breakers := get[*resilience.Registry](c)
+	clients := get[*httpclient.Factory](c)
+	outbound := clients.Client()
+	// Liveness and readiness probes; dependencies register checks as they are built
+	healthChecks := get[*health.Registry](c)
+	e.GET("/healthz", echo.WrapHandler(healthChecks.LivenessHandler()), unscoped...)
+	e.GET("/readyz", echo.WrapHandler(healthChecks.ReadinessHandler()), unscoped...)
+
+	// Mock mode ends here: documented operations answer with generated data
+	// and no storage, services or handlers are built
+	if cfg.Mock {
+		return newMockServer(cfg, lc, e, unscoped)
+	}
+
+	clk, ids := get[clock.Clock](c), get[idgen.Generator](c)
+	db := get[*database](c)
+
+	// With tenancy, the plan of each tenant caps the items of a collection
+	// it holds (plans.tiers.<plan>.max_items); usage is metered at /usage
+	planQuota := func(collection string) func(ctx context.Context) repository.Quota {
+		return func(ctx context.Context) repository.Quota {
+			id := tenant.From(ctx)
+			if id == "" {
+				return repository.Quota{Scope: "all"}
+			}
+			name, p, _ := watcher.Current().Plans.Of(id)
+			return repository.Quota{Scope: "tenant:" + id, Max: p.MaxItems[collection], Per: "in the " + name + " plan"}
+		}
+	}
+
+	// Initialize Product components
+	productRepo := newRepository(db, "products", "product", repository.NewProductRepository)
+	if cfg.Tenancy.Enabled {
+		// Scope products to the tenant of each request, by their tenant_id or
+		// in tables of each tenant's own
+		productRepo, err = newTenantRepository(context.Background(), cfg.Tenancy, db, productRepo, "products", "product")
+		if err != nil {
+			log.Fatalf("tenancy: %v", err)
+		}
+	}
+	if cfg.Resilience.Enabled {
+		productRepo = repository.NewResilientRepository(productRepo, breakers, "repository:products")
+	}
+	productQuota := repository.NewQuotaRepository(productRepo, db.uow, db.locks, "products", planQuota("products"), nil)
+	productRepo = productQuota
+
+	// Record writes to a change feed for long-polling and delta-sync clients
+	productChanges := changes.NewFeed(1000, clk)
+	productRepo = repository.NewChangeTrackingRepository(productRepo, productChanges)
+
+	// Index committed writes for full-text search; the in-memory index
+	// starts from what is already stored, with tenancy in the tenants listed
+	productSearch, err := newSearcher(cfg.Search, "products", outbound, healthChecks, cfg.Health.Timeout)
+	if err != nil {
+		log.Fatalf("search: %v", err)
+	}
+	if _, ok := productSearch.(*search.Memory); ok {
+		scopes := []context.Context{context.Background()}
+		if cfg.Tenancy.Enabled {
+			scopes = nil
+			for _, id := range cfg.Tenancy.Tenants {
+				scopes = append(scopes, tenant.With(context.Background(), id))
+			}
+		}
+		for _, ctx := range scopes {
+			if _, err := search.Reindex(ctx, productRepo, productSearch, productDocument); err != nil {
+				log.Fatalf("search: %v", err)
+			}
+		}
+	}
+	productRepo = search.NewIndexingRepository(productRepo, productSearch, productDocument)
+
+	// Wrap repositories with a read-through cache unless disabled (cache.ttl=0)
+	cacheMetrics := &cache.Metrics{}
+	cacheStore := get[cache.Store](c)
+	if cacheStore != nil {
+		productRepo = repository.NewCachedRepository(productRepo, "products", cacheStore, cfg.Cache.TTL, cacheMetrics)
+	}
+	if cfg.Tenancy.Enabled && cfg.Tenancy.Audit {
+		// Above the cache, so a leak from any layer is caught
+		productRepo = repository.NewTenantAuditRepository(productRepo, "product", cfg.Tenancy.Isolation == tenant.IsolationSchema)
+	}
+
+	// Committed writes are published to the bus for the event streams, and
+	// as typed domain events to the publisher selected by domain_events
+	bus := get[*events.Bus](c)
+	publisher := get[domain.EventPublisher](c)
+	// With the outbox, the services publish to it instead
+	relay := get[*outbox.Relay](c)
+	if relay != nil {
+		publisher = outbox.NewWriter(db.sql, db.dialect, clk, ids)
+	}
+	// Background jobs run on the schedules of the jobs section
+	jobs := get[*worker.Worker](c)
+	// With tenancy every tenant has lists of its own, which a job has no
+	// tenant to refresh for
+	if r, ok := productRepo.(repository.Refresher); ok && cfg.Jobs.CacheRefresh != "" && !cfg.Tenancy.Enabled {
+		// A refresh slower than the TTL would cache an already stale list
+		if err := jobs.Register("cache refresh", cfg.Jobs.CacheRefresh, cfg.Cache.TTL, r.Refresh); err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+	}
+	// Deleted items can be restored for trash.window, after which the purge
+	// job deletes them for good
+	bin := get[*trash.Bin](c)
+	if bin != nil && cfg.Jobs.TrashPurge != "" {
+		err := jobs.Register("trash purge", cfg.Jobs.TrashPurge, 0, func(ctx context.Context) error {
+			n, err := bin.Purge(ctx)
+			slog.Debug("trash purged", "deleted", n)
+			return err
+		})
+		if err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+	}
+	if relay != nil && cfg.Jobs.OutboxPurge != "" {
+		err := jobs.Register("outbox purge", cfg.Jobs.OutboxPurge, 0, func(ctx context.Context) error {
+			n, err := relay.Purge(ctx)
+			slog.Debug("outbox purged", "deleted", n)
+			return err
+		})
+		if err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+	}
+	// Operational events are posted to chat webhooks: this deploy, and
+	// spikes of server errors and growth of the dead letters by a job
+	if notifier := get[*notify.Notifier](c); notifier != nil && cfg.Jobs.Notify != "" {
+		var deadLetters func(ctx context.Context) (int, error)
+		if relay != nil {
+			deadLetters = func(ctx context.Context) (int, error) {
+				b, err := relay.Backlog(ctx)
+				return b.DeadLetters, err
+			}
+		}
+		if err := jobs.Register("notify", cfg.Jobs.Notify, 0, notify.NewMonitor(notifier, requests, deadLetters, clk, cfg.Notify.ErrorRate).Run); err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+	}
+	if p, ok := publisher.(*domain.InProc); ok {
+		// In-process subscribers are typed by the event they handle
+		domain.Subscribe(p, func(_ context.Context, e domain.ProductCreated) error {
+			slog.Debug("product created", "id", e.Entity.ID)
+			return nil
+		})
+	}
+	// Domain events are also delivered to the webhooks callers register at
+	// /hooks, by a job run on its schedule and after every write, through a
+	// client that connects to public addresses only unless
+	// hooks.allow_private is set
+	var dispatcher *hooks.Dispatcher
+	if cfg.Jobs.Hooks != "" {
+		var names []string
+		for _, e := range domain.Events[model.Product]("product") {
+			names = append(names, e.EventName())
+		}
+		hookClient := clients.Public()
+		if cfg.Hooks.AllowPrivate {
+			hookClient = outbound
+		}
+		dispatcher = hooks.New(hookClient, clk, ids, cfg.Hooks, names)
+		if err := jobs.Register("hooks", cfg.Jobs.Hooks, 0, dispatcher.Run); err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+		publisher = dispatcher.Publisher(publisher, func() {
+			// A full queue only delays the deliveries to the job's next scheduled run
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Jobs.EnqueueWait)
+			defer cancel()
+			jobs.Enqueue(ctx, "hooks")
+		})
+	}
+	hookHandler := handler.NewHookHandler(dispatcher, jobs, cfg.Jobs.EnqueueWait)
+	if harness != nil {
+		publisher = harness.Effects.Publisher(publisher)
+	}
+	// Every write is audited, with its caller and the item before and after,
+	// to the sinks of the audit section
+	auditor := get[*audit.Recorder](c)
+	// Custom fields of each collection, those of every tenant and the
+	// request's tenant's own (custom_fields), checked on every write
+	customFields := func(collection string) func(ctx context.Context) custom.Schema {
+		return func(ctx context.Context) custom.Schema {
+			return watcher.Current().CustomFields.For(tenant.From(ctx), collection)
+		}
+	}
+	productService := service.Extended(service.NewProductService(productRepo, db.uow, bus, publisher, auditor, bin, clk, ids), customFields("products"))
+	productHandler := handler.NewProductHandler(productService)
+	changesHandler := handler.NewChangesHandler(productChanges)
+	syncHandler := handler.NewSyncHandler(productService, productChanges)
+	searchHandler := handler.NewSearchHandler(productService, productSearch)
+	blobs := get[blob.Store](c)
+	imageHandler := handler.NewImageHandler(productService, blobs, cfg.Blob, clk)
+	// Exports are written to the blob store by a job, run on its schedule
+	// and whenever one is requested
+	var exports *export.Exporter
+	if cfg.Jobs.Exports != "" {
+		exports = export.New(blobs, clk, ids, cfg.Exports, cfg.Blob.URLTTL, map[string]export.Source{"products": export.Stream(productService.Stream)})
+		if err := jobs.Register("exports", cfg.Jobs.Exports, 0, exports.Run); err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+	}
+	exportHandler := handler.NewExportHandler(exports, jobs, cfg.Jobs.EnqueueWait)
+	// Webhook deliveries are handled by a job too; the GitHub webhook is an
+	// example integration, logging pushes
+	var webhooks *webhook.Receiver
+	if cfg.Jobs.Webhooks != "" {
+		webhooks = webhook.New(handler.Validation(e.Validator), clk, cfg.Webhooks)
+		if cfg.Webhooks.GitHub.Secret != "" {
+			webhooks.Register("github", cfg.Webhooks.GitHub, map[string]webhook.Handler{
+				"ping": webhook.On(func(_ context.Context, _ webhook.Delivery, p *webhook.GitHubPing) error {
+					slog.Info("github webhook created", "hook", p.HookID, "zen", p.Zen)
+					return nil
+				}),
+				"push": webhook.On(func(_ context.Context, d webhook.Delivery, p *webhook.GitHubPush) error {
+					slog.Info("github push", "delivery", d.ID, "repository", p.Repository.FullName, "ref", p.Ref, "after", p.After, "commits", len(p.Commits))
+					return nil
+				}),
+			})
+		}
+		if err := jobs.Register("webhooks", cfg.Jobs.Webhooks, 0, webhooks.Run); err != nil {
+			log.Fatalf("jobs: %v", err)
+		}
+	}
+	webhookHandler := handler.NewWebhookHandler(webhooks, jobs, cfg.Jobs.EnqueueWait)
+	// Bulk deletes are confirmed with a token from a first call
+	var confirmations *confirm.Store
+	if cfg.Confirm.Enabled {
+		confirmations = confirm.NewStore(clk, cfg.Confirm.TTL)
+	}
+	bulkHandler := handler.NewBulkHandler(productService, confirmations)
+	sockets := events.NewWebSockets(bus, events.WebSocketOptions{
+		Heartbeat:   cfg.Events.Heartbeat,
+		Buffer:      cfg.Events.Buffer,
+		AllowOrigin: cors.New(corsOptions).Allows,
+	})
+	eventsHandler := handler.NewEventsHandler(bus, sockets, cfg.Events.Heartbeat, cfg.Events.Buffer)
+
+	// Password logins; credentials live in the same database. Logouts and
+	// reused refresh tokens are recorded in the revocation store
+	revocations := get[auth.Revocations](c)
+	credentialRepo := newRepository(db, "credentials", "credential", repository.NewCredentialRepository)
+	// Accounts provisioned by an identity provider log in while it has them active
+	provisionedRepo := newRepository(db, "provisioned", "provisioned account", repository.NewProvisionedRepository)
+	// External identities, of OAuth2/OIDC providers and the directory,
+	// linked to local accounts
+	identityRepo := newRepository(db, "identities", "identity", repository.NewIdentityRepository)
+	// Passwords are checked against the directory with auth.login_provider: ldap
+	var loginProvider auth.IdentityProvider
+	if cfg.Auth.LoginProvider == "ldap" {
+		loginProvider = auth.NewLDAPProvider(credentialRepo, identityRepo, db.uow, nil, cfg.LDAP(), clk, ids)
+	}
+	// Authenticator apps, whose codes complete the password logins of
+	// accounts that enrolled one
+	totpRepo := newRepository(db, "totp_factors", "authenticator app", repository.NewTOTPRepository)
+	authService := auth.NewAuthService(credentialRepo, db.uow, revocations, nil, auth.Options{
+		Secret:          []byte(cfg.Auth.JWTSecret.Reveal()),
+		Issuer:          "echo-api",
+		TokenTTL:        cfg.Auth.TokenTTL,
+		RefreshTTL:      cfg.Auth.RefreshTTL,
+		MaxFailedLogins: cfg.Auth.MaxFailedLogins,
+		LockoutDuration: cfg.Auth.LockoutDuration,
+		Clock:           clk,
+		IDs:             ids,
+		Locks:           db.locks,
+		Active:          scim.Active(provisionedRepo),
+		Provider:        loginProvider,
+		Factors:         totpRepo,
+	})
+	authHandler := handler.NewAuthHandler(authService)
+	mfaHandler := handler.NewMFAHandler(auth.NewMFAService(totpRepo, credentialRepo, db.uow, "echo-api", clk))
+
+	// Passkeys, logging in without a password for the relying party of
+	// auth.passkeys
+	passkeyRepo := newRepository(db, "passkeys", "passkey", repository.NewPasskeyRepository)
+	passkeyService := auth.NewPasskeyService(authService, credentialRepo, passkeyRepo, db.uow, revocations, []byte(cfg.Auth.JWTSecret.Reveal()), cfg.Passkeys(), clk)
+	passkeyHandler := handler.NewPasskeyHandler(passkeyService)
+
+	// Logins with external OAuth2/OIDC providers
+	oidcService := auth.NewOIDCService(authService, credentialRepo, identityRepo, db.uow, nil, auth.OIDCOptions{
+		Secret:    []byte(cfg.Auth.JWTSecret.Reveal()),
+		Providers: cfg.OIDCProviders(),
+		Client:    outbound,
+		Clock:     clk,
+		IDs:       ids,
+	})
+	oidcHandler := handler.NewOIDCHandler(oidcService)
+
+	// API keys for machine clients, accepted wherever access tokens are,
+	// at most auth.max_api_keys per account
+	owner := func(ctx context.Context) string {
+		if caller := auth.CallerFromContext(ctx); caller != nil {
+			return caller.Subject
+		}
+		return ""
+	}
+	apiKeyQuota := repository.NewQuotaRepository(newRepository(db, "api_keys", "API key", repository.NewAPIKeyRepository), db.uow, db.locks, "API keys", func(ctx context.Context) repository.Quota {
+		if owner(ctx) == "" {
+			return repository.Quota{}
+		}
+		return repository.Quota{Scope: "account:" + owner(ctx), Max: watcher.Current().Auth.MaxAPIKeys, Per: "per account"}
+	}, func(ctx context.Context, k model.APIKey) bool {
+		return k.Owner == owner(ctx)
+	})
+	var apiKeyRepo repository.APIKeyRepository = apiKeyQuota
+	apiKeyService := auth.NewAPIKeyService(apiKeyRepo, clk)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+
+	// SCIM provisioning of accounts by an identity provider, in the tenant
+	// of scim.tenant
+	scimService := scim.New(provisionedRepo, credentialRepo, apiKeyRepo, db.uow, nil, clk, ids, cfg.SCIM)
+	scimHandler := handler.NewSCIMHandler(scimService, cfg.SCIM.Token)
+
+	// Per-client rate limiting, configured per route group, and with tenancy
+	// per tenant, by its plan, whatever the preset
+	limitStore := get[ratelimit.Store](c)
+	// Request/response capture for debugging, off until an admin starts it
+	// for a route or caller
+	rec := recorder.New(cfg.Recorder)
+	recorderHandler := handler.NewRecorderHandler(rec)
+
+	// With tenancy, requests are scoped to the tenant they name, which an
+	// authenticated caller must have logged in to
+	var tenants *tenant.Resolver
+	if cfg.Tenancy.Enabled {
+		tenants = tenant.NewResolver(cfg.Tenancy, func(ctx context.Context) (string, bool) {
+			if caller := auth.CallerFromContext(ctx); caller != nil {
+				return caller.Tenant, true
+			}
+			return "", false
+		})
+	}
+
+	// Feature flags of the config file, as the plan of a request's tenant
+	// sets them and the rules of flag_rules target them, which admins
+	// toggle under /admin/flags. The rule files and remote provider are
+	// refreshed by the system clock, as they change in the real world.
+	flagRules := []flags.Provider{flags.ProviderFunc(func(context.Context) map[string]flags.Rule {
+		return watcher.Current().FlagRules.Rules
+	})}
+	if cfg.FlagRules.File != "" {
+		file, err := flags.NewFile(cfg.FlagRules.File, cfg.FlagRules.Refresh, nil)
+		if err != nil {
+			log.Fatalf("flag rules: %v", err)
+		}
+		flagRules = append(flagRules, file)
+	}
+	if cfg.FlagRules.URL != "" {
+		flagRules = append(flagRules, flags.NewRemote(cfg.FlagRules.URL, cfg.FlagRules.Refresh, outbound, nil))
+	}
+	featureFlags := flags.New(func() map[string]bool {
+		return watcher.Current().FeatureFlags
+	}, func(ctx context.Context) (string, map[string]bool) {
+		name, p, _ := watcher.Current().Plans.Of(tenant.From(ctx))
+		return name, p.Features
+	}, flags.Chain(flagRules...))
+
+	// Route-level stages of each group: the caller, if any, is identified
+	// from a bearer token or API key, the request scoped to its tenant if
+	// tenancy is enabled, recorded if selected, rate limited unless the
+	// preset disables it, held to the rate limit of its tenant's plan, then
+	// given the feature flags of its caller and tenant
+	identify := pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.Auth, Middleware: auth.Identify(authService, apiKeyService)}
+	groupMiddleware := func(group string) []echo.MiddlewareFunc {
+		scoped := []pipeline.Stage[echo.MiddlewareFunc]{security(group), identify}
+		if tenants != nil {
+			scoped = append(scoped, pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.Tenant, Requires: []string{pipeline.Auth}, Middleware: tenant.Middleware(tenants)})
+		}
+		scoped = append(scoped, pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.Recorder, Requires: []string{pipeline.Auth}, Middleware: recorder.Middleware(rec)})
+		if preset.RateLimit {
+			scoped = append(scoped, pipeline.Stage[echo.MiddlewareFunc]{
+				Name: pipeline.RateLimit,
+				Middleware: ratelimit.Middleware(limitStore, group, func() ratelimit.Limit {
+					return watcher.Current().RateLimitFor(group)
+				}),
+			})
+		}
+		if tenants != nil {
+			scoped = append(scoped, pipeline.Stage[echo.MiddlewareFunc]{
+				Name:     pipeline.TenantLimit,
+				Requires: []string{pipeline.Tenant},
+				Middleware: ratelimit.TenantMiddleware(limitStore, func(id string) ratelimit.Limit {
+					_, p, _ := watcher.Current().Plans.Of(id)
+					return p.Limit()
+				}),
+			})
+		}
+		scoped = append(scoped, pipeline.Stage[echo.MiddlewareFunc]{Name: pipeline.Flags, Requires: []string{pipeline.Auth}, Middleware: flags.Middleware(featureFlags)})
+		mw, err := stages.Extend(group, scoped...)
+		if err != nil {
+			log.Fatalf("middleware: %v", err)
+		}
+		return mw
+	}
+
+	// Auth routes, limited separately from the API to slow down password
+	// guessing and one-time codes; managing API keys, passkeys and
+	// authenticator apps requires a logged-in caller, and so does claiming
+	// a guest (mounted below, once the collections guests own are known)
+	authRoutes := e.Group("/auth", groupMiddleware("auth")...)
+	{
+		authHandler.Register(authRoutes)
+		oidcHandler.Register(authRoutes.Group("/oidc"))
+		apiKeyHandler.Register(authRoutes.Group("/api-keys", auth.Require()))
+		passkeyHandler.RegisterLogin(authRoutes.Group("/passkeys/login"))
+		passkeyHandler.Register(authRoutes.Group("/passkeys", auth.Require()))
+		mfaHandler.Register(authRoutes.Group("/mfa/totp", auth.Require()))
+	}
+
+	// Product routes
+	productMiddleware := groupMiddleware("products")
+	productRoutes := e.Group("/products", productMiddleware...)
+	{
+		productHandler.Register(productRoutes)
+		productRoutes.GET("/changes", changesHandler.GetChanges)
+		productRoutes.GET("/sync", syncHandler.SyncProducts)
+		productRoutes.GET("/search", searchHandler.SearchProducts, flags.Require(featureFlags, "search"))
+		productRoutes.POST("/export", exportHandler.ExportProducts)
+		productRoutes.DELETE("/", bulkHandler.DeleteProducts)
+		productRoutes.GET("/events", eventsHandler.ProductEvents)
+		productRoutes.GET("/ws", eventsHandler.ProductSocket)
+	}
+
+	// Images of products, in a route group of its own whose security
+	// settings accept multipart forms, and the downloads of the local blob
+	// store by signed URL, which carry their own credentials
+	imageHandler.Register(e.Group("/products", groupMiddleware("images")...))
+	blobMiddleware, err := stages.Extend("blobs", security("blobs"))
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+	e.GET("/blobs/*key", imageHandler.Blob, blobMiddleware...)
+	exportHandler.Register(e.Group("/exports", groupMiddleware("exports")...))
+
+	// Webhooks, whose deliveries are signed by their provider rather than
+	// sent by a caller
+	webhookMiddleware, err := stages.Extend("webhooks", security("webhooks"))
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+	webhookHandler.Register(e.Group("/webhooks", webhookMiddleware...))
+	// Webhooks of callers, which the domain events of their tenant are
+	// delivered to
+	hookHandler.Register(e.Group("/hooks", append(groupMiddleware("hooks"), auth.Require())...))
+
+	// SCIM, whose identity provider authenticates with scim.token rather
+	// than as a caller
+	scimMiddleware, err := stages.Extend("scim", security("scim"))
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+	scimHandler.Register(e.Group("/scim/v2", scimMiddleware...))
+
+	// Versioned product routes: each version under /api/vN with handlers of
+	// its own over the same service, announcing its lifecycle (api_versions)
+	// on every response. Requests to /api/products are routed by their
+	// Accept header, to the latest version by default
+	versioned := func(v apiversion.Version, group []echo.MiddlewareFunc) []echo.MiddlewareFunc {
+		policy := apiversion.New(v, latest, cfg.APIVersions[v.String()], clk)
+		return append(slices.Clone(group), apiversion.Middleware(policy))
+	}
+	productHandler.Register(e.Group("/api/v1/products", versioned(1, productMiddleware)...))
+	handler.NewProductV2Handler(productService).Register(e.Group("/api/v2/products", versioned(2, productMiddleware)...))
+
+	// Collections in the archives of /export and /import; a version is
+	// bumped when the JSON of its items changes incompatibly
+	archived := []archive.Source{archive.Resource("products", productService)}
+
+	// Domain events the services publish, checked against what their
+	// consumers read (see package eventschema)
+	published := domain.Events[model.Product]("product")
+
+	// Collections seeded in development, created at startup where missing
+	// and restored by POST /admin/reset (see package fixtures)
+	seeded := []fixtures.Collection{fixtures.Resource("products", productService)}
+
+	// Collections held to quotas, whose usage GET /usage reports
+	metered := []service.Meter{service.Metered("products", productQuota), service.Metered("api_keys", apiKeyQuota)}
+
+	// Collections whose items guests own, taken over by the accounts that
+	// claim them
+	claimed := []auth.OwnershipTransfer{productService.Transfer}
+
+	// scaffold:routes (go run ./cmd/scaffold inserts new resources above this line)
+
+	// JSON-RPC 2.0 transport over the same services, behind the same middleware as REST
+	rpcServer := rpc.NewServer()
+	rpcServer.MapError = handler.MapRPCError
+	handler.RegisterProductRPC(rpcServer, productService, e.Validator)
+	e.POST("/rpc", echo.WrapHandler(rpcServer), groupMiddleware("rpc")...)
+
+	// GraphQL over the same services; @auth fields rely on the caller
+	// identified by the group middleware
+	e.POST("/graphql", echo.WrapHandler(graph.NewHandler(productService, e.Validator)), groupMiddleware("graphql")...)
+
+	// Quota usage of the caller, its tenant's and its own
+	handler.NewUsageHandler(metered...).Register(e.Group("/usage", append(groupMiddleware("usage"), auth.Require())...))
+
+	// Claiming guest sessions, started at POST /auth/guest, by logged-in
+	// accounts
+	guests := auth.NewGuestService(authService, db.uow, revocations, cfg.Auth.RefreshTTL, clk, claimed...)
+	handler.NewGuestHandler(guests).Register(authRoutes.Group("/guest", auth.Require()))
+
+	// Admin routes, restricted to the admin role (auth.roles and auth.admins,
+	// reloaded with the config file) of callers who logged in, never
+	// recorded themselves, and served on admin.port alone if it is set
+	admin := e
+	if cfg.Admin.Port != "" {
+		admin = echo.New()
+		admin.HideBanner, admin.HidePort = true, true
+		admin.Server.ReadTimeout = cfg.Server.ReadTimeout
+		admin.Server.WriteTimeout = cfg.Server.WriteTimeout
+		admin.Validator = e.Validator
+		admin.Binder = e.Binder
+		admin.IPExtractor = e.IPExtractor
+		admin.OnAddRouteHandler = e.OnAddRouteHandler
+		admin.Use(stages.Middleware()...)
+	}
+	adminMiddleware, err := stages.Extend("admin", security("admin"), identify)
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+	roles := func() auth.Roles {
+		return watcher.Current().Roles()
+	}
+	adminOnly := append(adminMiddleware, auth.RequireAdmin(roles), auth.RequireLogin())
+	accounts := auth.NewAccountService(credentialRepo, identityRepo, apiKeyRepo, passkeyRepo, totpRepo, db.uow, revocations, roles, cfg.Auth.RefreshTTL, clk)
+	var seed *fixtures.Fixtures // nil unless fixtures are loaded
+	if cfg.Environment == config.Development && cfg.Fixtures.Dir != "" {
+		seed = fixtures.New(os.DirFS(cfg.Fixtures.Dir), db.uow, handler.Validation(e.Validator), cfg.IDStrategy(), seeded...)
+		results, err := seed.Load(context.Background())
+		if err != nil {
+			log.Fatalf("fixtures: %v", err)
+		}
+		for _, r := range results {
+			log.Printf("fixtures: %s: %d created, %d kept", r.Collection, r.Created, r.Skipped)
+		}
+	}
+	adminRoutes := admin.Group("/admin", adminOnly...)
+	{
+		handler.NewAccountsHandler(accounts).Register(adminRoutes.Group("/accounts"))
+		handler.NewCacheHandler(cacheStore).Register(adminRoutes.Group("/cache"))
+		handler.NewConfigHandler(func() map[string]string {
+			return watcher.Current().Redacted()
+		}).Register(adminRoutes.Group("/config"))
+		handler.NewFlagsHandler(featureFlags).Register(adminRoutes.Group("/flags"))
+		recorderHandler.Register(adminRoutes.Group("/recorder"))
+		handler.NewAuditHandler(auditor).Register(adminRoutes.Group("/audit"))
+		handler.NewJobsHandler(jobs, cfg.Jobs.EnqueueWait).Register(adminRoutes.Group("/jobs"))
+		handler.NewDeadLetterHandler(relay).Register(adminRoutes.Group("/dead-letters"))
+		handler.NewFixturesHandler(seed).Register(adminRoutes.Group("/reset"))
+	}
+	if cfg.Profiling.Pprof {
+		handler.RegisterPprof(admin.Group("/debug/pprof", adminOnly...))
+	}
+	// Circuit breaker states and cache hit/miss counters, which reveal the
+	// dependencies and traffic of the service
+	admin.GET("/debug/breakers", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, breakers.Snapshot())
+	}, adminOnly...)
+	admin.GET("/metrics/cache", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, cacheMetrics.Snapshot())
+	}, adminOnly...)
+
+	// Export and import of every collection, for admins who logged in too and
+	// next to the admin routes, with security settings of their own that
+	// accept large zip bodies
+	archiveMiddleware, err := stages.Extend("archive", security("archive"), identify)
+	if err != nil {
+		log.Fatalf("middleware: %v", err)
+	}
+	handler.NewArchiveHandler(archive.New(db.uow, handler.Validation(e.Validator), clk, archived...)).Register(admin, append(archiveMiddleware, auth.RequireAdmin(roles), auth.RequireLogin())...)
+
+	// Optional MQTT bridge: publishes change events and runs commands through the RPC methods
+	if cfg.MQTT.BrokerURL != "" {
+		bridge, err := mqtt.New(mqtt.Options{
+			BrokerURL:   cfg.MQTT.BrokerURL,
+			ClientID:    cfg.MQTT.ClientID,
+			TopicPrefix: cfg.MQTT.TopicPrefix,
+			Clients:     cfg.MQTT.Clients,
+		}, rpcServer)
+		if err != nil {
+			log.Fatalf("mqtt: %v", err)
+		}
+		lc.RegisterCloser("mqtt bridge", 0, bridge)
+		healthChecks.Register("mqtt", health.Readiness, cfg.Health.Timeout, bridge.Ping)
+		bridge.Publish("products", productChanges)
+	}
+
+	// Optional gRPC server for bulk transfer over streams, on a port of its
+	// own or multiplexed with HTTP on the server port
+	var serveGRPC lifecycle.StartFunc // nil when multiplexed
+	if cfg.GRPC.Port != "" && cfg.DescribeOnly() {
+		grpcServer = grpcapi.New() // -describe builds the services but never listens
+	} else if cfg.GRPC.Port != "" {
+		grpcServer, err = grpcapi.Listen(":" + cfg.GRPC.Port)
+		if err != nil {
+			log.Fatalf("grpc: %v", err)
+		}
+		serveGRPC = started(func() {
+			go func() {
+				if err := grpcServer.Serve(); err != nil {
+					log.Fatalf("grpc: %v", err)
+				}
+			}()
+		})
+	}
+	if grpcServer != nil {
+		productsv1.RegisterProductServiceServer(grpcServer, grpcapi.NewProductServer(productService, e.Validator, productChanges))
+		lc.Append(lifecycle.Hook{Name: "grpc server", Timeout: cfg.Server.ShutdownTimeout, Start: serveGRPC, Stop: grpcServer.Shutdown})
+	}
+
+	// Batch endpoint: sub-requests are dispatched back through the router
+	e.POST("/batch", echo.WrapHandler(batch.NewHandler(e, batch.Options{MaxRequests: 20, Concurrency: 4})), unscoped...)
+
+	// API documentation generated from the handler annotations (go generate ./...)
+	e.GET("/openapi.json", echo.WrapHandler(openapi.Handler()), unscoped...)
+	e.GET("/docs", echo.WrapHandler(openapi.UIHandler("/openapi.json")), unscoped...)
+
+	// Fail fast on conflicting routes, undocumented handlers and missing auth metadata
+	if err := routecheck.Check(routes, undocumented...); err != nil {
+		log.Fatal(err)
+	}
+	// and on domain events their consumers can no longer read
+	eventConsumers, err := eventschema.Registered()
+	if err != nil {
+		log.Fatalf("event schemas: %v", err)
+	}
+	if err := eventschema.Check(published, eventConsumers...); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("middleware: preset %s", preset.Name)
+	if len(corsOptions.AllowedOrigins) > 0 {
+		log.Printf("middleware: cors allows %s", strings.Join(corsOptions.AllowedOrigins, ", "))
+	} else {
+		log.Printf("middleware: cors allows no origins")
+	}
+	for _, line := range strings.Split(stages.Describe(), "\n") {
+		log.Printf("middleware: %s", line)
+	}
+
+	lc.Register("http server", cfg.Server.ShutdownTimeout, e.Shutdown)
+	if admin != e {
+		lc.Append(lifecycle.Hook{Name: "admin server", Timeout: cfg.Server.ShutdownTimeout, Stop: admin.Shutdown, Start: started(func() {
+			go func() {
+				if err := admin.Start(":" + cfg.Admin.Port); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("admin: %v", err)
+				}
+			}()
+		})})
+	}
+	// Registered after the server so it closes first, releasing long-poll requests
+	lc.Register("change feed", 0, func(context.Context) error {
+		productChanges.Close()
+		return nil
+	})
+	// Likewise: Shutdown does not wait for event streams, which would
+	// otherwise hold it until the timeout
+	lc.Register("event streams", 0, func(context.Context) error {
+		bus.Close()
+		return nil
+	})
+	// WebSockets are hijacked, so Shutdown does not see them either: close
+	// them first and wait for the clients to acknowledge
+	lc.Register("websockets", cfg.Server.ShutdownTimeout, sockets.Shutdown)
+	// Registered last so it runs first: fail readiness before the server stops accepting
+	lc.Register("readiness", 0, func(context.Context) error {
+		healthChecks.SetDraining()
+		return nil
+	})
+
+	return e
+}
+
+// newMockServer serves the API documentation and answers every documented
+// operation on e with data generated from its response schema, behind the
+// unscoped middleware.
+func newMockServer(cfg *config.Config, lc *lifecycle.Manager, e *echo.Echo, unscoped []echo.MiddlewareFunc) *echo.Echo {
+	mocks, err := mock.New(openapi.Spec())
+	if err != nil {
+		log.Fatalf("mock: %v", err)
+	}
+	e.GET("/openapi.json", echo.WrapHandler(openapi.Handler()), unscoped...)
+	e.GET("/docs", echo.WrapHandler(openapi.UIHandler("/openapi.json")), unscoped...)
+	e.Any("/*", echo.WrapHandler(mocks), unscoped...)
+	log.Printf("mock: serving generated responses for %d operations", mocks.Operations())
+
+	lc.Register("http server", cfg.Server.ShutdownTimeout, e.Shutdown)
+	return e
+}
+
+// database is the persistence backend selected by the scheme of database.url.
+// At most one of sql and mongo is set; neither means in-memory.
+type database struct {
+	sql     *sql.DB
+	dialect repository.Dialect
+	mongo   *mongo.Database
+	uow     repository.UnitOfWork // transactions spanning this database's repositories
+	locks   repository.Locks      // held by uow transactions, e.g. for quotas
+}
+
+// openDatabase connects to the backend named by cfg.URL, applies pending
+// SQL migrations when cfg.Migrate is set, and registers its shutdown hook and
+// readiness checks.
+func openDatabase(ctx context.Context, cfg config.DatabaseConfig, lc *lifecycle.Manager, checks *health.Registry, timeout time.Duration) (*database, error) {
+	scheme, _, _ := strings.Cut(cfg.URL.Reveal(), ":")
+	switch scheme {
+	case "postgres", "postgresql", "sqlite":
+		db, dialect, err := repository.OpenSQL(ctx, cfg.URL.Reveal())
+		if err != nil {
+			return nil, err
+		}
+		lc.RegisterCloser("database", 0, db)
+		if cfg.Migrate {
+			applied, err := migrations.Up(ctx, db, dialect)
+			for _, v := range applied {
+				log.Printf("Applied migration %s", v)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		checks.Register("database", health.Readiness, timeout, db.PingContext)
+		checks.Register("migrations", health.Readiness, timeout, migrations.Check(db))
+		return &database{sql: db, dialect: dialect, uow: repository.NewSQLUnitOfWork(db), locks: repository.NewSQLLocks(db, dialect)}, nil
+	case "mongodb", "mongodb+srv":
+		db, err := repository.OpenMongo(ctx, cfg.URL.Reveal())
+		if err != nil {
+			return nil, err
+		}
+		lc.Register("database", 0, db.Client().Disconnect)
+		checks.Register("database", health.Readiness, timeout, func(ctx context.Context) error {
+			return db.Client().Ping(ctx, nil)
+		})
+		uow, err := repository.NewMongoUnitOfWork(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		return &database{mongo: db, uow: uow, locks: repository.NewMongoLocks(db)}, nil
+	default: // "in-memory"
+		return &database{uow: repository.NewNoopUnitOfWork(), locks: repository.NewMemoryLocks()}, nil
+	}
+}
+
+// newAuditor returns the recorder writing to the sinks named by opts, or nil
+// if there are none. The database sink is the audit_log table on the SQL
+// backends and a bounded in-memory log with the in-memory database.
+// Entries get UUIDv7s of their own, so that auditing does not shift
+// sequential entity IDs.
+func newAuditor(opts audit.Options, db *database, lc *lifecycle.Manager, clk clock.Clock) (*audit.Recorder, error) {
+	if len(opts.Sinks) == 0 {
+		return nil, nil
+	}
+	var sinks []audit.Sink
+	for _, name := range opts.Sinks {
+		switch name {
+		case audit.SinkDatabase:
+			if db.sql != nil {
+				sinks = append(sinks, audit.NewSQL(db.sql, db.dialect))
+			} else {
+				sinks = append(sinks, audit.NewMemory(10000))
+			}
+		case audit.SinkFile:
+			f, err := audit.OpenFile(opts.File)
+			if err != nil {
+				return nil, err
+			}
+			lc.RegisterCloser("audit file", 0, f)
+			sinks = append(sinks, f)
+		case audit.SinkStdout:
+			sinks = append(sinks, audit.NewStream(os.Stdout))
+		}
+	}
+	return audit.NewRecorder(clk, idgen.NewUUIDv7(clk), sinks...), nil
+}
+
+// newBin returns the trash of deleted items, in the trash table of a SQL
+// database or in process otherwise, or nil when opts.Window is 0.
+func newBin(opts trash.Options, db *database, clk clock.Clock) *trash.Bin {
+	if opts.Window == 0 {
+		return nil
+	}
+	var store trash.Store = trash.NewMemory()
+	if db.sql != nil {
+		store = trash.NewSQL(db.sql, db.dialect)
+	}
+	return trash.NewBin(store, clk, opts.Window)
+}
+
+// newSearcher returns the full-text index of a resource, table naming its
+// Elasticsearch index, whose readiness is then checked.
+func newSearcher(opts search.Options, table string, client *http.Client, checks *health.Registry, timeout time.Duration) (search.Searcher, error) {
+	if opts.Backend != search.BackendElasticsearch {
+		return search.NewMemory(), nil
+	}
+	es, err := search.NewElasticsearch(opts.URL.Reveal(), opts.IndexPrefix+table, client)
+	if err != nil {
+		return nil, err
+	}
+	checks.Register("search", health.Readiness, timeout, es.Ping)
+	return es, nil
+}
+
+// newBlobStore returns the store of uploaded files: the local disk, or an S3
+// bucket whose readiness is then checked.
+func newBlobStore(opts blob.Options, client *http.Client, clk clock.Clock, checks *health.Registry, timeout time.Duration) (blob.Store, error) {
+	if opts.Backend != blob.BackendS3 {
+		return blob.NewLocal(opts.Dir, "/blobs", []byte(opts.SigningKey.Reveal())), nil
+	}
+	s3, err := blob.NewS3(opts, client, clk)
+	if err != nil {
+		return nil, err
+	}
+	checks.Register("blob", health.Readiness, timeout, s3.Ping)
+	return s3, nil
+}
+
+// productDocument is the searchable text of a product.
+func productDocument(p model.Product) search.Document {
+	return search.Document{ID: p.ID, Fields: map[string]string{"name": p.Name}}
+}
+
+// newTenantRepository scopes repo, the repository for table on db, to the
+// tenant on the context of each call. With column isolation repo holds every
+// tenant's items; with schema isolation each tenant listed in opts gets a
+// table of its own (see repository.PrepareTenantTable), a collection named
+// <schema>.<table> on MongoDB or an in-memory store, and repo is not used.
+func newTenantRepository[T model.Entity](ctx context.Context, opts tenant.Options, db *database, repo repository.CrudRepository[T], table, name string) (repository.CrudRepository[T], error) {
+	if opts.Isolation == tenant.IsolationColumn {
+		return repository.NewColumnTenantRepository(repo), nil
+	}
+	tenants := make(map[string]repository.CrudRepository[T], len(opts.Tenants))
+	for _, id := range opts.Tenants {
+		schema := tenant.Schema(id)
+		switch {
+		case db.sql != nil:
+			qualified, err := repository.PrepareTenantTable(ctx, db.sql, db.dialect, schema, table)
+			if err != nil {
+				return nil, err
+			}
+			tenants[id] = repository.NewSQLRepository[T](db.sql, db.dialect, qualified, name)
+		case db.mongo != nil:
+			tenants[id] = repository.NewMongoRepository[T](db.mongo.Collection(schema+"."+table), name)
+		default:
+			tenants[id] = repository.NewMemoryRepository[T](name)
+		}
+	}
+	return repository.NewSchemaTenantRepository(tenants), nil
+}
+
+// newRepository returns the repository for table on db, or inMemory() when
+// database.url is "in-memory". name is the singular used in error messages.
+func newRepository[T model.Entity](db *database, table, name string, inMemory func() repository.CrudRepository[T]) repository.CrudRepository[T] {
+	switch {
+	case db.sql != nil:
+		return repository.NewSQLRepository[T](db.sql, db.dialect, table, name)
+	case db.mongo != nil:
+		return repository.NewMongoRepository[T](db.mongo.Collection(table), name)
+	default:
+		return inMemory()
+	}
+}
+
+// wrapResponseMiddleware adapts net/http middleware that rewrites the
+// response. Unlike echo.WrapMiddleware it renders handler errors inside m, so
+// they are written through the rewritten response rather than after it.
+func wrapResponseMiddleware(m func(http.Handler) http.Handler) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				c.SetResponse(echo.NewResponse(w, c.Echo()))
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			})).ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+	}
+}
+
+// ipExtractor returns the client address of a request, that of X-Forwarded-For
+// if it came through one of proxies, else the address it connects from.
+func ipExtractor(proxies []netip.Prefix) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, p := range proxies {
+		options = append(options, echo.TrustIPRange(&net.IPNet{IP: p.Addr().AsSlice(), Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen())}))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/config"
+	"github.com/your-username/gin-api/internal/handler"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/service/servicetest"
+)
+
+// benchUsers is how many users a benchmarked stack holds.
+const benchUsers = 100
+
+// BenchmarkCRUD serves the user routes, from request to response, on
+// stacks that add one cost at a time:
+//
+//	go test -run '^$' -bench CRUD .
+//
+// "handler" is the UserHandler alone over a fake service, so the presets,
+// whose whole router runs over the in-memory repository, show what each
+// middleware chain costs, and crudBackends show what the database costs on
+// top of the test preset. BenchmarkRepositories in internal/repository
+// compares the repositories without HTTP; loadtest/crud.js drives a
+// running server.
+func BenchmarkCRUD(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	writer := gin.DefaultWriter
+	gin.DefaultWriter = io.Discard // the development preset logs every request
+	b.Cleanup(func() { gin.DefaultWriter = writer })
+
+	type stack struct {
+		name  string
+		build func() http.Handler
+	}
+	stacks := []stack{{"handler", func() http.Handler {
+		router := gin.New()
+		handler.NewUserHandler(servicetest.NewFake[model.User](nil)).Register(router.Group("/users"))
+		return router
+	}}}
+	for _, preset := range []string{"test", "development", "production"} {
+		stacks = append(stacks, stack{"preset=" + preset, func() http.Handler {
+			cfg := config.Default()
+			cfg.Middleware.Preset = preset
+			cfg.RateLimit.Limits = map[string]string{"default": "1000000000/s"} // limited, never refused
+			srv, _ := newTestServerWith(b, cfg)
+			return srv.Handler
+		}})
+	}
+	backends := crudBackends(b)
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stacks = append(stacks, stack{name, func() http.Handler {
+			cfg := config.Default()
+			backends[name](cfg)
+			srv, _ := newTestServerWith(b, cfg)
+			return srv.Handler
+		}})
+	}
+
+	for _, s := range stacks {
+		do := requester(s.build())
+		ids := make([]string, benchUsers)
+		for i := range ids {
+			ids[i] = benchCreate(b, do, i)
+		}
+		b.Run(s.name+"/get", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchExpect(b, do(http.MethodGet, "/users/"+ids[i%len(ids)], "", ""), http.StatusOK)
+			}
+		})
+		b.Run(s.name+"/list", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchExpect(b, do(http.MethodGet, "/users/", "", ""), http.StatusOK)
+			}
+		})
+		b.Run(s.name+"/update", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n := i % len(ids)
+				body := fmt.Sprintf(`{"name": "User %d", "email": "user%d@example.com"}`, n, n)
+				benchExpect(b, do(http.MethodPut, "/users/"+ids[n], "", body), http.StatusOK)
+			}
+		})
+		// A churn creates a user and deletes it, so the number of users
+		// stays at benchUsers.
+		b.Run(s.name+"/churn", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				id := benchCreate(b, do, benchUsers+i)
+				benchExpect(b, do(http.MethodDelete, "/users/"+id, "", ""), http.StatusNoContent)
+			}
+		})
+	}
+}
+
+// benchCreate creates the nth user and returns its ID.
+func benchCreate(b *testing.B, do func(method, path, token, body string) *httptest.ResponseRecorder, n int) string {
+	body := fmt.Sprintf(`{"name": "User %d", "email": "user%d@example.com"}`, n, n)
+	w := do(http.MethodPost, "/users/", "", body)
+	benchExpect(b, w, http.StatusCreated)
+	var created struct{ ID string }
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.ID == "" {
+		b.Fatalf("no id in %s", w.Body)
+	}
+	return created.ID
+}
+
+func benchExpect(b *testing.B, w *httptest.ResponseRecorder, status int) {
+	if w.Code != status {
+		b.Fatalf("got %d, want %d\n%s", w.Code, status, w.Body)
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestErrorsMapToSentinels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		switch r.URL.Path {
+		case "/users/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "User not found"}`))
+		case "/users/":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "name is required"}`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+	users := New(srv.URL, Options{}).Users()
+
+	_, err := users.Get(context.Background(), "missing")
+	var e *Error
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &e) || e.Message != "User not found" || e.RequestID != "req-1" {
+		t.Errorf("Get: err = %#v", err)
+	}
+	if _, err := users.Create(context.Background(), &User{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Create: err = %v, want ErrInvalid", err)
+	}
+	err = users.Delete(context.Background(), "x")
+	if !errors.As(err, &e) || e.Status != http.StatusTeapot || e.Unwrap() != nil || e.Message != "I'm a teapot" {
+		t.Errorf("Delete: err = %#v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": "u1", "name": "Ada"}`))
+	}))
+	defer srv.Close()
+	users := New(srv.URL, Options{Retries: 2, Backoff: time.Millisecond}).Users()
+
+	if u, err := users.Get(context.Background(), "u1"); err != nil || u.Name != "Ada" || calls.Load() != 3 {
+		t.Fatalf("Get = %+v, %v after %d calls, want Ada after 3", u, err, calls.Load())
+	}
+
+	// A create may have been applied: it is sent once
+	calls.Store(0)
+	if _, err := users.Create(context.Background(), &User{Name: "Ada"}); err == nil || calls.Load() != 1 {
+		t.Fatalf("Create: err = %v after %d calls, want 503 after 1", err, calls.Load())
+	}
+}
+
+func TestLoginSetsTheToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/auth/login":
+			w.Write([]byte(`{"access_token": "t1", "token_type": "Bearer", "expires_in": 900}`))
+		case r.Header.Get("Authorization") != "Bearer t1":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "missing or invalid token"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+	c := New(srv.URL, Options{})
+
+	if _, err := c.Users().List(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("List before Login: err = %v, want ErrUnauthorized", err)
+	}
+	if token, err := c.Login(context.Background(), "ada@example.com", "password"); err != nil || token.ExpiresIn != 900 {
+		t.Fatalf("Login = %+v, %v", token, err)
+	}
+	if _, err := c.Users().List(context.Background()); err != nil {
+		t.Fatalf("List after Login: %v", err)
+	}
+
+	// Options.Token takes precedence
+	c = New(srv.URL, Options{Token: func(context.Context) (string, error) { return "t1", nil }})
+	if _, err := c.Users().List(context.Background()); err != nil {
+		t.Fatalf("List with Options.Token: %v", err)
+	}
+}
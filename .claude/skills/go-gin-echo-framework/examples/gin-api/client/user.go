@@ -0,0 +1,13 @@
+package client
+
+import "github.com/your-username/gin-api/internal/model"
+
+// User is model.User, named here for consumers outside this module.
+type User = model.User
+
+type UsersClient = Resource[User]
+
+// Users returns the client of the user routes.
+func (c *Client) Users() *UsersClient {
+	return NewResource[User](c, "/users")
+}
@@ -0,0 +1,74 @@
+// Command scenario runs the YAML walkthroughs in scenarios/ (or the files
+// given) against a running instance and reports every step, exiting
+// non-zero if any scenario fails:
+//
+//	go run ./cmd/scenario -url http://localhost:8080
+//	go run ./cmd/scenario -url https://staging.example.com scenarios/user-lifecycle.yaml
+//
+// The same scenarios run in the tests against the router; see
+// internal/scenario for the format.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/scenario"
+)
+
+func main() {
+	base := flag.String("url", "http://localhost:8080", "base URL of the instance")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout per request")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: scenario [-url URL] [-timeout D] [file.yaml ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	scenarios, err := load(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	r := &scenario.Runner{
+		Base:   strings.TrimSuffix(*base, "/"),
+		Client: &http.Client{Timeout: *timeout},
+		Log:    os.Stdout,
+	}
+	failed := 0
+	for _, s := range scenarios {
+		if err := r.Run(context.Background(), s); err != nil {
+			fmt.Printf("\n%s: %v\n\n", s.Name, err)
+			failed++
+		}
+	}
+	fmt.Printf("%d of %d scenarios passed\n", len(scenarios)-failed, len(scenarios))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// load reads the given files, or every scenario in scenarios/ when none are.
+func load(files []string) ([]scenario.Scenario, error) {
+	if len(files) == 0 {
+		return scenario.Load("scenarios")
+	}
+	var out []scenario.Scenario
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s, err := scenario.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
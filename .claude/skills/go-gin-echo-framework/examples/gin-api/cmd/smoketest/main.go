@@ -0,0 +1,415 @@
+// Command smoketest runs a scripted session against a deployed instance and
+// exits non-zero if any step fails, for post-deploy verification:
+//
+//	go run ./cmd/smoketest -url https://api.example.com
+//
+// The session checks health and readiness, registers an account and logs in
+// (an existing -email is logged into instead), creates, reads and updates a
+// few users, lists them in full and as NDJSON, deletes them, and logs out.
+// The REST list is not paginated, so the listing steps check that every
+// created user appears exactly once. Each step is reported with its
+// duration; a failing one also with the request and response. Users the
+// session created are deleted again even when a step fails; the account is
+// kept, as the API offers no way to remove it.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	base := flag.String("url", "http://localhost:8080", "base URL of the instance")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout per request")
+	email := flag.String("email", "", "account to register or log into (default: a new smoketest+<time>@example.com)")
+	password := flag.String("password", "smoketest-password", "password of the account")
+	records := flag.Int("records", 3, "users to create")
+	flag.Parse()
+
+	if *email == "" {
+		*email = fmt.Sprintf("smoketest+%d@example.com", time.Now().UnixNano())
+	}
+	s := &session{
+		base:     strings.TrimSuffix(*base, "/"),
+		client:   &http.Client{Timeout: *timeout},
+		email:    *email,
+		password: *password,
+		records:  *records,
+	}
+	if !s.run(os.Stdout) {
+		os.Exit(1)
+	}
+}
+
+// session is one run of the scenario. Steps share what earlier ones
+// captured: the tokens and the created users.
+type session struct {
+	base     string
+	client   *http.Client
+	email    string
+	password string
+	records  int
+
+	access, refresh string
+	users           []user    // created and not yet deleted
+	deleted         []string  // IDs
+	last            *exchange // the latest request, for the failure report
+}
+
+type user struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// exchange is a request and its response as sent and received.
+type exchange struct {
+	method, url string
+	request     []byte
+	status      int
+	header      http.Header
+	response    []byte
+}
+
+type step struct {
+	name string
+	run  func(*session) error
+}
+
+var scenario = []step{
+	{"health", (*session).health},
+	{"readiness", (*session).readiness},
+	{"register", (*session).register},
+	{"login", (*session).login},
+	{"create users", (*session).create},
+	{"get users", (*session).get},
+	{"update users", (*session).update},
+	{"list users", (*session).list},
+	{"stream users", (*session).stream},
+	{"delete users", (*session).delete},
+	{"get deleted users", (*session).getDeleted},
+	{"logout", (*session).logout},
+}
+
+// run executes the scenario, stopping at the first failing step, and
+// reports on w. It reports whether every step passed.
+func (s *session) run(w io.Writer) bool {
+	fmt.Fprintf(w, "smoketest %s as %s\n", s.base, s.email)
+	start := time.Now()
+	passed := true
+	for _, st := range scenario {
+		s.last = nil
+		t := time.Now()
+		err := st.run(s)
+		if err == nil {
+			fmt.Fprintf(w, "  ok    %-21s %v\n", st.name, time.Since(t).Round(time.Millisecond))
+			continue
+		}
+		fmt.Fprintf(w, "  FAIL  %-21s %v\n\n%v\n", st.name, time.Since(t).Round(time.Millisecond), err)
+		if s.last != nil {
+			s.last.report(w)
+		}
+		passed = false
+		break
+	}
+	if !passed && len(s.users) > 0 {
+		s.cleanup(w)
+	}
+	result := "passed"
+	if !passed {
+		result = "FAILED"
+	}
+	fmt.Fprintf(w, "\n%s in %v\n", result, time.Since(start).Round(time.Millisecond))
+	return passed
+}
+
+// cleanup deletes the users still left from a failed run.
+func (s *session) cleanup(w io.Writer) {
+	for _, u := range s.users {
+		if _, err := s.do(http.MethodDelete, "/users/"+u.ID, nil, http.StatusNoContent, http.StatusNotFound); err != nil {
+			fmt.Fprintf(w, "\ncleanup: user %s was not deleted: %v\n", u.ID, err)
+		}
+	}
+}
+
+func (s *session) health() error {
+	_, err := s.do(http.MethodGet, "/healthz", nil, http.StatusOK)
+	return err
+}
+
+func (s *session) readiness() error {
+	_, err := s.do(http.MethodGet, "/readyz", nil, http.StatusOK)
+	return err
+}
+
+func (s *session) register() error {
+	_, err := s.do(http.MethodPost, "/auth/register", map[string]string{
+		"name":     "Smoke Test",
+		"email":    s.email,
+		"password": s.password,
+	}, http.StatusCreated, http.StatusConflict)
+	return err
+}
+
+func (s *session) login() error {
+	body, err := s.do(http.MethodPost, "/auth/login", map[string]string{"email": s.email, "password": s.password}, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var tokens struct {
+		Access  string `json:"access_token"`
+		Refresh string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.Access == "" || tokens.Refresh == "" {
+		return fmt.Errorf("no access and refresh token in the response (%v)", err)
+	}
+	s.access, s.refresh = tokens.Access, tokens.Refresh
+	return nil
+}
+
+func (s *session) create() error {
+	run := time.Now().UnixNano()
+	for i := 0; i < s.records; i++ {
+		want := user{Name: fmt.Sprintf("Smoke Test %d", i+1), Email: fmt.Sprintf("smoketest+%d.%d@example.com", run, i+1)}
+		var got user
+		if err := s.decode(http.MethodPost, "/users/", want, http.StatusCreated, &got); err != nil {
+			return err
+		}
+		if got.ID == "" {
+			return errors.New("the created user has no id")
+		}
+		s.users = append(s.users, got)
+		if got.Name != want.Name || got.Email != want.Email {
+			return fmt.Errorf("created %+v, sent %+v", got, want)
+		}
+	}
+	return nil
+}
+
+func (s *session) get() error {
+	for _, want := range s.users {
+		var got user
+		if err := s.decode(http.MethodGet, "/users/"+want.ID, nil, http.StatusOK, &got); err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("got %+v, created %+v", got, want)
+		}
+	}
+	return nil
+}
+
+func (s *session) update() error {
+	for i, u := range s.users {
+		u.Name += " (updated)"
+		var got user
+		if err := s.decode(http.MethodPut, "/users/"+u.ID, u, http.StatusOK, &got); err != nil {
+			return err
+		}
+		if got != u {
+			return fmt.Errorf("updated to %+v, sent %+v", got, u)
+		}
+		s.users[i] = got
+	}
+	return nil
+}
+
+func (s *session) list() error {
+	var all []user
+	if err := s.decode(http.MethodGet, "/users/", nil, http.StatusOK, &all); err != nil {
+		return err
+	}
+	return s.contains(all)
+}
+
+func (s *session) stream() error {
+	body, err := s.do(http.MethodGet, "/users/stream", nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var all []user
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		var u user
+		if err := json.Unmarshal(sc.Bytes(), &u); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		all = append(all, u)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return s.contains(all)
+}
+
+// contains checks that every user the session created is in all, once and
+// as last written.
+func (s *session) contains(all []user) error {
+	seen := map[string]int{}
+	for _, u := range all {
+		seen[u.ID]++
+		for _, want := range s.users {
+			if u.ID == want.ID && u != want {
+				return fmt.Errorf("listed %+v, last written %+v", u, want)
+			}
+		}
+	}
+	for _, u := range s.users {
+		if seen[u.ID] != 1 {
+			return fmt.Errorf("user %s listed %d times among %d users", u.ID, seen[u.ID], len(all))
+		}
+	}
+	return nil
+}
+
+func (s *session) delete() error {
+	for len(s.users) > 0 {
+		if _, err := s.do(http.MethodDelete, "/users/"+s.users[0].ID, nil, http.StatusNoContent); err != nil {
+			return err
+		}
+		s.deleted = append(s.deleted, s.users[0].ID)
+		s.users = s.users[1:]
+	}
+	return nil
+}
+
+func (s *session) getDeleted() error {
+	for _, id := range s.deleted {
+		if _, err := s.do(http.MethodGet, "/users/"+id, nil, http.StatusNotFound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) logout() error {
+	_, err := s.do(http.MethodPost, "/auth/logout", map[string]string{"refresh_token": s.refresh}, http.StatusNoContent)
+	return err
+}
+
+// decode sends the request and unmarshals the JSON response into v.
+func (s *session) decode(method, path string, body any, status int, v any) error {
+	data, err := s.do(method, path, body, status)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding the response: %v", err)
+	}
+	return nil
+}
+
+// do sends body as JSON, with the access token once logged in, and fails
+// unless the response status is one of want.
+func (s *session) do(method, path string, body any, want ...int) ([]byte, error) {
+	x := &exchange{method: method, url: s.base + path}
+	s.last = x
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		x.request = data
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, x.url, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.access != "" {
+		req.Header.Set("Authorization", "Bearer "+s.access)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	x.status, x.header = res.StatusCode, res.Header
+	if x.response, err = io.ReadAll(res.Body); err != nil {
+		return nil, fmt.Errorf("reading the response: %v", err)
+	}
+	for _, status := range want {
+		if res.StatusCode == status {
+			return x.response, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: status %d, want %s", method, path, res.StatusCode, statuses(want))
+}
+
+func statuses(codes []int) string {
+	s := make([]string, len(codes))
+	for i, c := range codes {
+		s[i] = fmt.Sprint(c)
+	}
+	return strings.Join(s, " or ")
+}
+
+// maxReported bounds the bodies printed in a failure report.
+const maxReported = 4096
+
+// report writes the exchange, with bodies truncated and passwords and
+// tokens redacted.
+func (x *exchange) report(w io.Writer) {
+	fmt.Fprintf(w, "\n> %s %s\n", x.method, x.url)
+	if len(x.request) > 0 {
+		fmt.Fprintf(w, "%s\n", truncate(redact(x.request)))
+	}
+	if x.status == 0 {
+		fmt.Fprintln(w, "< no response")
+		return
+	}
+	fmt.Fprintf(w, "< %d %s\n", x.status, http.StatusText(x.status))
+	for _, name := range []string{"Content-Type", "X-Request-Id"} {
+		if v := x.header.Get(name); v != "" {
+			fmt.Fprintf(w, "< %s: %s\n", name, v)
+		}
+	}
+	if len(x.response) > 0 {
+		fmt.Fprintf(w, "%s\n", truncate(redact(x.response)))
+	}
+}
+
+// secrets are the fields redact masks in JSON objects.
+var secrets = []string{"password", "access_token", "refresh_token"}
+
+func redact(b []byte) []byte {
+	var fields map[string]any
+	if json.Unmarshal(b, &fields) != nil {
+		return b
+	}
+	masked := false
+	for _, name := range secrets {
+		if _, ok := fields[name]; ok {
+			fields[name], masked = "[redacted]", true
+		}
+	}
+	if !masked {
+		return b
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+func truncate(b []byte) []byte {
+	if len(b) <= maxReported {
+		return b
+	}
+	return append(b[:maxReported:maxReported], fmt.Sprintf("... (%d bytes)", len(b))...)
+}
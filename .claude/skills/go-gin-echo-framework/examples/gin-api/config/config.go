@@ -1,67 +1,892 @@
 package config
 
 import (
-	"log"
-	"os"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/apiversion"
+	"github.com/your-username/gin-api/internal/audit"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/blob"
+	"github.com/your-username/gin-api/internal/cache"
+	"github.com/your-username/gin-api/internal/compat"
+	"github.com/your-username/gin-api/internal/compression"
+	"github.com/your-username/gin-api/internal/confirm"
+	"github.com/your-username/gin-api/internal/cors"
+	"github.com/your-username/gin-api/internal/custom"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/envelope"
+	"github.com/your-username/gin-api/internal/export"
+	"github.com/your-username/gin-api/internal/flags"
+	"github.com/your-username/gin-api/internal/hardening"
+	"github.com/your-username/gin-api/internal/hooks"
+	"github.com/your-username/gin-api/internal/httpclient"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/notify"
+	"github.com/your-username/gin-api/internal/outbox"
+	"github.com/your-username/gin-api/internal/payloadlog"
+	"github.com/your-username/gin-api/internal/pipeline"
+	"github.com/your-username/gin-api/internal/plan"
+	"github.com/your-username/gin-api/internal/ratelimit"
+	"github.com/your-username/gin-api/internal/recorder"
+	"github.com/your-username/gin-api/internal/resilience"
+	"github.com/your-username/gin-api/internal/scim"
+	"github.com/your-username/gin-api/internal/search"
+	"github.com/your-username/gin-api/internal/secret"
+	"github.com/your-username/gin-api/internal/tenant"
+	"github.com/your-username/gin-api/internal/testmode"
+	"github.com/your-username/gin-api/internal/transform"
+	"github.com/your-username/gin-api/internal/trash"
+	"github.com/your-username/gin-api/internal/webhook"
+	"github.com/your-username/gin-api/internal/worker"
 )
 
-type AppConfig struct {
-	Port        string
-	DatabaseURL string
-	Environment string
-	// Add other configuration fields as needed
+// Config is the fully resolved application configuration. Values are layered
+// in increasing precedence: built-in defaults, config file, environment
+// variables, command-line flags. See Load.
+type Config struct {
+	Environment Environment                  `yaml:"environment"`
+	IDs         string                       `yaml:"ids"` // ID strategy of created entities; see IDStrategy
+	Server      ServerConfig                 `yaml:"server"`
+	Database    DatabaseConfig               `yaml:"database"`
+	Redis       RedisConfig                  `yaml:"redis"`
+	Auth        AuthConfig                   `yaml:"auth"`
+	Logging     LoggingConfig                `yaml:"logging"`
+	Cache       CacheConfig                  `yaml:"cache"`
+	RateLimit   RateLimitConfig              `yaml:"rate_limit"`
+	Health      HealthConfig                 `yaml:"health"`
+	MQTT        MQTTConfig                   `yaml:"mqtt"`
+	GRPC        GRPCConfig                   `yaml:"grpc"`
+	Events      EventsConfig                 `yaml:"events"`
+	Domain      domain.Options               `yaml:"domain_events"` // typed events of the service layer, in process or to a broker
+	Outbox      outbox.Options               `yaml:"outbox"`        // relays domain events from a table written with each change
+	Audit       audit.Options                `yaml:"audit"`         // trail of every write, listed at /admin/audit
+	Search      search.Options               `yaml:"search"`        // full-text index behind GET /users/search
+	Blob        blob.Options                 `yaml:"blob"`          // store of the images under /users/:id/images
+	Exports     export.Options               `yaml:"exports"`       // asynchronous exports of POST /users/export, written to the blob store
+	Webhooks    webhook.Options              `yaml:"webhooks"`      // deliveries of third-party services to POST /webhooks/:provider
+	Hooks       hooks.Options                `yaml:"hooks"`         // deliveries of domain events to the webhooks registered at /hooks
+	SCIM        scim.Options                 `yaml:"scim"`          // accounts provisioned by an identity provider at /scim/v2
+	Confirm     confirm.Options              `yaml:"confirm"`       // two-call confirmation of bulk deletes
+	Tenancy     tenant.Options               `yaml:"tenancy"`       // several tenants served from one deployment, each seeing only its own users
+	Plans       plan.Options                 `yaml:"plans"`         // tiers of service of the tenants: rate limits, feature flags, user counts
+	Trash       trash.Options                `yaml:"trash"`         // deleted users restorable with POST /users/:id/undo-delete
+	Resilience  resilience.Options           `yaml:"resilience"`    // circuit breakers, timeouts and retries around the database and outbound HTTP
+	HTTPClient  httpclient.Options           `yaml:"http_client"`   // timeouts, connection pool and User-Agent of the calls to other services
+	Notify      notify.Options               `yaml:"notify"`        // deploys, error-rate spikes and dead letters posted to chat webhooks
+	Jobs        JobsConfig                   `yaml:"jobs"`          // background jobs run on a schedule
+	TestMode    testmode.Options             `yaml:"test_mode"`     // deterministic end-to-end tests; see EnableTestMode
+	Transforms  []transform.Rule             `yaml:"transforms"`    // first rule matching a request applies
+	Envelope    envelope.Options             `yaml:"envelope"`
+	Middleware  MiddlewareConfig             `yaml:"middleware"`
+	CORS        cors.Options                 `yaml:"cors"`        // unset fields follow the middleware preset; see CORSOptions
+	Security    map[string]hardening.Options `yaml:"security"`    // route group -> headers and request limits; see SecurityFor
+	Recorder    recorder.Options             `yaml:"recorder"`    // request/response capture started from /admin/recorder
+	PayloadLog  payloadlog.Options           `yaml:"payload_log"` // request and response bodies of every request in the log
+	Compression compression.Options          `yaml:"compression"`
+	Profiling   ProfilingConfig              `yaml:"profiling"`
+	Fixtures    FixturesConfig               `yaml:"fixtures"` // seed data of development; see package fixtures
+	Admin       AdminConfig                  `yaml:"admin"`    // where the admin routes are served
+
+	// FeatureFlags are the feature flags and their defaults, set per plan
+	// (Plans) and toggled at runtime with PUT /admin/flags/:name; see
+	// package flags. search turns GET /users/search on and off
+	FeatureFlags map[string]bool `yaml:"feature_flags"`
+	// FlagRules turn feature flags that are off by default on for some
+	// callers and tenants
+	FlagRules flags.Options `yaml:"flag_rules"`
+
+	// CustomFields are the fields tenants add to the items of a
+	// collection, under their custom object; see package custom
+	CustomFields custom.Options `yaml:"custom_fields"`
+
+	// Mock serves generated responses for every documented operation
+	// instead of running the handlers; see internal/mock
+	Mock bool `yaml:"mock"`
+
+	// Compatibility is strict (reject unknown request fields and query
+	// parameters) or lenient (accept and log them while clients migrate).
+	Compatibility compat.Mode `yaml:"compatibility"`
+
+	// APIVersions is the lifecycle of each version under /api/vN, by name
+	// (v1); unlisted versions are current. See internal/apiversion
+	APIVersions map[string]apiversion.Lifecycle `yaml:"api_versions"`
+
+	file     string // config file this was loaded from, if any
+	describe bool   // -describe: print what would be served and exit
+}
+
+type ServerConfig struct {
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For
+	// and X-Real-IP name the client, e.g. of rate limits; empty trusts none,
+	// and clients are known by the address they connect from
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type DatabaseConfig struct {
+	URL     secret.Secret `yaml:"url"`     // "in-memory", postgres://..., sqlite:///path.db or mongodb://host/db
+	Migrate bool          `yaml:"migrate"` // apply pending SQL migrations on startup
+}
+
+type RedisConfig struct {
+	URL secret.Secret `yaml:"url"`
+}
+
+type AuthConfig struct {
+	JWTSecret         secret.Secret `yaml:"jwt_secret"`
+	TokenTTL          time.Duration `yaml:"token_ttl"`
+	RefreshTTL        time.Duration `yaml:"refresh_ttl"`        // lifetime of each rotated refresh token
+	RevocationBackend string        `yaml:"revocation_backend"` // "memory" or "redis"
+	MaxFailedLogins   int           `yaml:"max_failed_logins"`  // consecutive failures before an account is locked
+	LockoutDuration   time.Duration `yaml:"lockout_duration"`
+	MaxAPIKeys        int           `yaml:"max_api_keys"` // per account; 0 is unlimited
+	Admins            []string      `yaml:"admins"`       // account IDs holding the admin role, next to those in roles
+	Roles             auth.Roles    `yaml:"roles"`        // account IDs by role; admin is the role of the admin routes
+
+	// Providers are the external identity providers offered by
+	// /auth/oidc/login?provider=<name>; see auth.KnownProviders for defaults
+	Providers map[string]auth.OIDCProvider `yaml:"providers"`
+
+	// LoginProvider checks the passwords of /auth/login: "local", the
+	// registered ones, or "ldap", those of the directory of LDAP
+	LoginProvider string           `yaml:"login_provider"`
+	LDAP          auth.LDAPOptions `yaml:"ldap"`
+
+	// Passkeys is the relying party of /auth/passkeys; see Config.Passkeys
+	Passkeys auth.PasskeyOptions `yaml:"passkeys"`
+}
+
+type FixturesConfig struct {
+	Dir string `yaml:"dir"` // <collection>.yaml files loaded at startup in development and restored by POST /admin/reset; empty disables
+}
+
+type AdminConfig struct {
+	Port string `yaml:"port"` // serve the admin routes (/admin, /debug/pprof) on this port only; empty serves them on server.port
+}
+
+type ProfilingConfig struct {
+	Pprof bool `yaml:"pprof"` // serve the runtime profiles at /debug/pprof to admins
+}
+
+type MiddlewareConfig struct {
+	Preset string `yaml:"preset"` // development, staging, production or test; empty follows environment
+}
+
+type LoggingConfig struct {
+	Level  LogLevel `yaml:"level"`  // debug, info, warn, error
+	Format string   `yaml:"format"` // text or json
+}
+
+type CacheConfig struct {
+	Backend cache.Backend `yaml:"backend"` // "memory" (in-process LRU) or "redis"
+	TTL     time.Duration `yaml:"ttl"`     // 0 disables caching
+	Size    int           `yaml:"size"`    // max entries for the in-memory LRU
+}
+
+type RateLimitConfig struct {
+	Backend string            `yaml:"backend"` // "memory" or "redis"
+	Limits  map[string]string `yaml:"limits"`  // route group -> "<requests>/<period>"; "default" applies otherwise
+}
+
+type HealthConfig struct {
+	Timeout     time.Duration     `yaml:"timeout"`     // per-check timeout for /readyz
+	Downstreams map[string]string `yaml:"downstreams"` // name -> URL probed with GET by /readyz
+	Background  BackgroundHealth  `yaml:"background"`
+}
+
+// BackgroundHealth sets how far background processing may fall behind
+// before /readyz fails. 0 disables a limit.
+type BackgroundHealth struct {
+	MaxQueuedJobs    int           `yaml:"max_queued_jobs"`    // jobs due but not started
+	MaxHeartbeatAge  time.Duration `yaml:"max_heartbeat_age"`  // since the job scheduler last checked for due jobs
+	MaxOutboxLag     time.Duration `yaml:"max_outbox_lag"`     // wait of the oldest event not yet relayed
+	MaxOutboxPending int           `yaml:"max_outbox_pending"` // events not yet relayed
+}
+
+type MQTTConfig struct {
+	BrokerURL   secret.Secret            `yaml:"broker_url"` // empty disables the bridge; tcp://, ssl://, ws:// or wss://
+	ClientID    string                   `yaml:"client_id"`
+	TopicPrefix string                   `yaml:"topic_prefix"`
+	Clients     map[string]secret.Secret `yaml:"clients"` // client id -> token required in its command messages
+}
+
+type GRPCConfig struct {
+	Port      string `yaml:"port"`      // empty disables the gRPC server, unless multiplexed
+	Multiplex bool   `yaml:"multiplex"` // serve gRPC on server.port alongside HTTP instead of on port
+}
+
+type EventsConfig struct {
+	Heartbeat time.Duration `yaml:"heartbeat"` // comment sent on idle event streams so proxies keep them open
+	Buffer    int           `yaml:"buffer"`    // events queued per stream before a slow client is disconnected
+}
+
+// JobsConfig schedules the background jobs (see worker.ParseSchedule for
+// the syntax) and sets how failed runs are retried.
+type JobsConfig struct {
+	worker.Options `yaml:",inline"`
+	CacheRefresh   string `yaml:"cache_refresh"` // reload the cached user list before it expires; empty disables
+	OutboxPurge    string `yaml:"outbox_purge"`  // delete published outbox events older than outbox.retention; empty disables
+	TrashPurge     string `yaml:"trash_purge"`   // delete for good the deleted items older than trash.window; empty disables
+	Exports        string `yaml:"exports"`       // write the files of pending exports, also run when one is requested; empty disables exports
+	Webhooks       string `yaml:"webhooks"`      // handle queued webhook deliveries, also run when one is received; empty disables webhooks
+	Hooks          string `yaml:"hooks"`         // deliver domain events to the registered webhooks, also run after a write; empty disables /hooks
+	Notify         string `yaml:"notify"`        // post error-rate spikes and dead-letter growth since the last run, with notify.events set; empty disables
+
+	EnqueueWait time.Duration `yaml:"enqueue_wait"` // how long POST /admin/jobs/:name/run waits for room in a full queue before answering 503
+}
+
+// Default returns the configuration used when nothing else is specified.
+// It is suitable for local development only.
+func Default() *Config {
+	return &Config{
+		Environment: Development,
+		Server: ServerConfig{
+			Port:            "8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    75 * time.Second, // leaves room for 60s long-poll requests
+			ShutdownTimeout: 5 * time.Second,
+		},
+		Database: DatabaseConfig{URL: "in-memory"},
+		Redis:    RedisConfig{URL: "redis://localhost:6379/0"},
+		Auth: AuthConfig{
+			TokenTTL:          15 * time.Minute,
+			RefreshTTL:        7 * 24 * time.Hour,
+			RevocationBackend: "memory",
+			MaxFailedLogins:   5,
+			LockoutDuration:   15 * time.Minute,
+			LoginProvider:     "local",
+			Passkeys:          auth.PasskeyOptions{RPID: "localhost", RPName: "gin-api", Timeout: 5 * time.Minute},
+		},
+		Logging: LoggingConfig{Level: "info", Format: "text"},
+		Cache:   CacheConfig{Backend: "memory", TTL: 30 * time.Second, Size: 1024},
+		RateLimit: RateLimitConfig{
+			Backend: "memory",
+			Limits:  map[string]string{"default": "100/m"},
+		},
+		Health: HealthConfig{
+			Timeout: 2 * time.Second,
+			Background: BackgroundHealth{
+				MaxQueuedJobs:    2,
+				MaxHeartbeatAge:  30 * time.Second,
+				MaxOutboxLag:     5 * time.Minute,
+				MaxOutboxPending: 10000,
+			},
+		},
+		Events: EventsConfig{Heartbeat: 15 * time.Second, Buffer: 64},
+		MQTT:   MQTTConfig{ClientID: "gin-api-bridge", TopicPrefix: "gin-api"},
+		Domain: domain.Options{
+			Publisher:    "inproc",
+			NATSURL:      "nats://localhost:4222",
+			KafkaBrokers: []string{"localhost:9092"},
+			Topic:        "gin-api",
+		},
+		Outbox:   outbox.Options{PollInterval: time.Second, BatchSize: 100, MaxAttempts: 10, Retention: 24 * time.Hour},
+		Audit:    audit.Options{Sinks: []string{audit.SinkDatabase}},
+		Search:   search.Options{Backend: search.BackendMemory},
+		Blob:     blob.Options{Backend: blob.BackendLocal, Dir: "data/blobs", MaxSize: 5 << 20, Types: []string{"image/png", "image/jpeg", "image/gif", "image/webp"}, URLTTL: 15 * time.Minute},
+		Exports:  export.Options{MaxPending: 4, Retention: 24 * time.Hour},
+		Webhooks: webhook.Options{Tolerance: 5 * time.Minute, ReplayWindow: 24 * time.Hour, MaxQueued: 1000, Attempts: 5},
+		Hooks:    hooks.Options{Attempts: 8, Backoff: 30 * time.Second, MaxBackoff: time.Hour, Timeout: 10 * time.Second, LogSize: 100},
+		SCIM:     scim.Options{MaxResults: 100},
+		Confirm:  confirm.Options{Enabled: true, TTL: 5 * time.Minute},
+		Notify:   notify.Options{ErrorRate: notify.RateOptions{Threshold: 0.05, MinRequests: 20}},
+		Tenancy: tenant.Options{
+			Sources:   []string{tenant.SourceClaim, tenant.SourceHeader},
+			Header:    "X-Tenant-ID",
+			Isolation: tenant.IsolationColumn,
+		},
+		Trash: trash.Options{Window: 10 * time.Minute},
+		Resilience: resilience.Options{
+			Timeout:          5 * time.Second,
+			Retries:          2,
+			Backoff:          50 * time.Millisecond,
+			FailureThreshold: 5,
+			OpenFor:          30 * time.Second,
+		},
+		HTTPClient: httpclient.Options{
+			Timeout:               30 * time.Second,
+			DialTimeout:           5 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 15 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   10,
+			UserAgent:             "gin-api",
+		},
+		TestMode: testmode.Options{Seed: 1, Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Jobs: JobsConfig{
+			Options:      worker.Options{Retries: 3, Backoff: 10 * time.Second, Queue: 16},
+			CacheRefresh: "@every 25s",
+			OutboxPurge:  "@hourly",
+			TrashPurge:   "@every 1m",
+			Exports:      "@every 1m",
+			Webhooks:     "@every 1m",
+			Hooks:        "@every 10s",
+			Notify:       "@every 1m",
+			EnqueueWait:  2 * time.Second,
+		},
+		Envelope: envelope.Options{
+			Header:        "X-Response-Envelope",
+			VersionHeader: "X-API-Version",
+		},
+		Recorder: recorder.Options{
+			Capacity:      100,
+			MaxBodyBytes:  64 << 10,
+			RedactHeaders: []string{"Authorization", "Cookie", "Set-Cookie", "X-API-Key"},
+			RedactFields:  []string{"password", "token", "access_token", "refresh_token", "guest_token", "mfa_token", "code", "signature", "key", "secret"},
+		},
+		PayloadLog: payloadlog.Options{
+			MaxBodyBytes: 4 << 10,
+			Redact:       []string{"password", "token", "access_token", "refresh_token", "guest_token", "mfa_token", "code", "signature", "key", "secret"},
+		},
+		// Archives for /import are zips, and larger than API requests, as
+		// are the multipart forms of image uploads and webhook payloads;
+		// identity providers send SCIM JSON
+		Security: map[string]hardening.Options{
+			"archive":  {MaxBodyBytes: 64 << 20, ContentTypes: []string{"application/zip"}},
+			"images":   {MaxBodyBytes: 6 << 20, ContentTypes: []string{"multipart/form-data"}},
+			"webhooks": {MaxBodyBytes: 1 << 20, ContentTypes: []string{"application/json"}},
+			"scim":     {ContentTypes: []string{scim.ContentType, "application/json"}},
+		},
+		Compression:   compression.Options{Encodings: []string{"br", "gzip"}, MinBytes: 1024},
+		FeatureFlags:  map[string]bool{"search": true},
+		FlagRules:     flags.Options{Refresh: 30 * time.Second},
+		Compatibility: compat.Strict,
+	}
 }
 
-func LoadConfig() *AppConfig {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Default port
+// validFlag keeps feature flag names usable in /admin/flags/:name.
+var validFlag = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Validate reports every invalid setting at once so misconfiguration can be
+// fixed in a single pass.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if !slices.Contains(Environments, c.Environment) {
+		fail("environment", "must be one of development, staging, production, test (got %q)", c.Environment)
+	}
+
+	if c.TestMode.Enabled && c.Environment != Test {
+		fail("test_mode.enabled", "requires environment test (got %q)", c.Environment)
+	}
+
+	if !oneOf(c.IDs, append([]string{""}, idgen.Strategies...)...) {
+		fail("ids", "must be one of %s (got %q)", strings.Join(idgen.Strategies, ", "), c.IDs)
+	} else if c.IDStrategy() == "sequential" && c.Environment != Test {
+		fail("ids", "sequential IDs restart with the process and collide with stored ones; use them in the test environment only")
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("server.port", "must be a number between 1 and 65535 (got %q)", c.Server.Port)
+	}
+	if c.Server.ReadTimeout <= 0 {
+		fail("server.read_timeout", "must be positive")
+	}
+	if c.Server.WriteTimeout <= 0 {
+		fail("server.write_timeout", "must be positive")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout", "must be positive")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := parseProxy(proxy); err != nil {
+			fail("server.trusted_proxies", "%q is neither an IP address nor a CIDR range", proxy)
+		}
 	}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Println("WARNING: DATABASE_URL not set, using default (in-memory store).")
-		// In a real app, you might want to fatal here or use a default in-memory DB
-		databaseURL = "in-memory"
+	if c.Database.URL == "" {
+		fail("database.url", "is required")
+	} else if c.Database.URL != "in-memory" {
+		u, err := url.Parse(c.Database.URL.Reveal())
+		switch {
+		case err != nil || !oneOf(u.Scheme, "postgres", "postgresql", "sqlite", "mongodb", "mongodb+srv"):
+			fail("database.url", "must be \"in-memory\" or a postgres://, sqlite: or mongodb:// URL")
+		case strings.HasPrefix(u.Scheme, "mongodb") && strings.Trim(u.Path, "/") == "":
+			fail("database.url", "must name a database in a MongoDB URL, e.g. mongodb://localhost:27017/app")
+		}
 	}
 
-	environment := os.Getenv("ENVIRONMENT")
-	if environment == "" {
-		environment = "development"
+	usesRedis := c.Cache.Backend == cache.Redis || c.RateLimit.Backend == "redis" || c.Auth.RevocationBackend == "redis"
+	if usesRedis {
+		if u, err := url.Parse(c.Redis.URL.Reveal()); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			fail("redis.url", "must be a redis:// or rediss:// URL")
+		}
 	}
 
-	return &AppConfig{
-		Port:        port,
-		DatabaseURL: databaseURL,
-		Environment: environment,
+	if c.Environment == Production && len(c.Auth.JWTSecret) < 32 {
+		fail("auth.jwt_secret", "must be at least 32 characters in production")
+	}
+	if c.Auth.TokenTTL <= 0 {
+		fail("auth.token_ttl", "must be positive")
+	}
+	if c.Auth.RefreshTTL < c.Auth.TokenTTL {
+		fail("auth.refresh_ttl", "must be at least auth.token_ttl")
+	}
+	if !oneOf(c.Auth.RevocationBackend, "memory", "redis") {
+		fail("auth.revocation_backend", "must be memory or redis (got %q)", c.Auth.RevocationBackend)
+	}
+	if c.Auth.MaxFailedLogins <= 0 {
+		fail("auth.max_failed_logins", "must be positive")
+	}
+	if c.Auth.LockoutDuration <= 0 {
+		fail("auth.lockout_duration", "must be positive")
+	}
+	if c.Auth.MaxAPIKeys < 0 {
+		fail("auth.max_api_keys", "must not be negative")
+	}
+	for name, p := range c.OIDCProviders() {
+		if err := p.Validate(); err != nil {
+			fail("auth.providers."+name, "%v", err)
+		}
+	}
+	if !oneOf(c.Auth.LoginProvider, "local", "ldap") {
+		fail("auth.login_provider", "must be local or ldap (got %q)", c.Auth.LoginProvider)
+	} else if c.Auth.LoginProvider == "ldap" {
+		if err := c.LDAP().Validate(); err != nil {
+			fail("auth.ldap", "%v", err)
+		}
+	}
+	if err := c.Passkeys().Validate(); err != nil {
+		fail("auth.passkeys", "%v", err)
+	}
+
+	if !slices.Contains(LogLevels, c.Logging.Level) {
+		fail("logging.level", "must be one of debug, info, warn, error (got %q)", c.Logging.Level)
+	}
+	if !oneOf(c.Logging.Format, "text", "json") {
+		fail("logging.format", "must be text or json (got %q)", c.Logging.Format)
+	}
+
+	if !slices.Contains(cache.Backends, c.Cache.Backend) {
+		fail("cache.backend", "must be memory or redis (got %q)", c.Cache.Backend)
+	}
+	if c.Cache.TTL < 0 {
+		fail("cache.ttl", "must not be negative")
+	}
+	if c.Cache.Size <= 0 {
+		fail("cache.size", "must be positive")
+	}
+
+	if !oneOf(c.RateLimit.Backend, "memory", "redis") {
+		fail("rate_limit.backend", "must be memory or redis (got %q)", c.RateLimit.Backend)
+	}
+	for group, raw := range c.RateLimit.Limits {
+		if _, err := ratelimit.ParseLimit(raw); err != nil {
+			fail("rate_limit.limits."+group, "%v", err)
+		}
+	}
+
+	if c.Health.Timeout <= 0 {
+		fail("health.timeout", "must be positive")
+	}
+	for name, raw := range c.Health.Downstreams {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("health.downstreams."+name, "must be an http:// or https:// URL")
+		}
+	}
+	if b := c.Health.Background; b.MaxQueuedJobs < 0 || b.MaxHeartbeatAge < 0 || b.MaxOutboxLag < 0 || b.MaxOutboxPending < 0 {
+		fail("health.background", "limits must not be negative")
+	}
+
+	if c.GRPC.Port != "" {
+		if port, err := strconv.Atoi(c.GRPC.Port); err != nil || port < 1 || port > 65535 {
+			fail("grpc.port", "must be a number between 1 and 65535 (got %q)", c.GRPC.Port)
+		} else if c.GRPC.Port == c.Server.Port {
+			fail("grpc.port", "must differ from server.port")
+		}
+		if c.GRPC.Multiplex {
+			fail("grpc.port", "must be empty when grpc.multiplex is on, which serves gRPC on server.port")
+		}
+	}
+	if c.Admin.Port != "" {
+		if port, err := strconv.Atoi(c.Admin.Port); err != nil || port < 1 || port > 65535 {
+			fail("admin.port", "must be a number between 1 and 65535 (got %q)", c.Admin.Port)
+		} else if c.Admin.Port == c.Server.Port || c.Admin.Port == c.GRPC.Port {
+			fail("admin.port", "must differ from server.port and grpc.port")
+		}
+	}
+	for name := range c.FeatureFlags {
+		if !validFlag.MatchString(name) {
+			fail("feature_flags", "invalid name %q: want lower-case letters, digits, hyphens and underscores", name)
+		}
+	}
+	if err := c.FlagRules.Validate(); err != nil {
+		fail("flag_rules", "%v", err)
+	}
+	for name := range c.FlagRules.Rules {
+		if _, ok := c.FeatureFlags[name]; !ok {
+			fail("flag_rules.rules", "%q is not declared in feature_flags", name)
+		}
+	}
+	if err := c.CustomFields.Validate(); err != nil {
+		fail("custom_fields", "%v", err)
+	}
+
+	if c.Events.Heartbeat <= 0 {
+		fail("events.heartbeat", "must be positive")
+	}
+	if c.Events.Buffer <= 0 {
+		fail("events.buffer", "must be positive")
 	}
+
+	if err := c.Domain.Validate(); err != nil {
+		fail("domain_events", "%v", err)
+	}
+	if c.Outbox.Enabled {
+		if err := c.Outbox.Validate(); err != nil {
+			fail("outbox", "%v", err)
+		}
+		if scheme, _, _ := strings.Cut(c.Database.URL.Reveal(), ":"); !oneOf(scheme, "postgres", "postgresql", "sqlite") {
+			fail("outbox.enabled", "requires a postgres:// or sqlite: database.url")
+		}
+		if !oneOf(c.Domain.Publisher, "nats", "kafka") {
+			fail("outbox.enabled", "requires domain_events.publisher nats or kafka; in-process subscribers need no outbox")
+		}
+	}
+
+	if err := c.Audit.Validate(); err != nil {
+		fail("audit", "%v", err)
+	}
+	if slices.Contains(c.Audit.Sinks, audit.SinkDatabase) && strings.HasPrefix(c.Database.URL.Reveal(), "mongodb") {
+		fail("audit.sinks", "database requires a postgres://, sqlite: or in-memory database.url")
+	}
+
+	if err := c.Search.Validate(); err != nil {
+		fail("search", "%v", err)
+	}
+	if err := c.Blob.Validate(); err != nil {
+		fail("blob", "%v", err)
+	}
+	if err := c.Exports.Validate(); err != nil {
+		fail("exports", "%v", err)
+	}
+	if err := c.Webhooks.Validate(); err != nil {
+		fail("webhooks", "%v", err)
+	}
+	if err := c.Hooks.Validate(); err != nil {
+		fail("hooks", "%v", err)
+	}
+	if err := c.SCIM.Validate(); err != nil {
+		fail("scim", "%v", err)
+	}
+	if c.SCIM.Tenant != "" && !c.Tenancy.Enabled {
+		fail("scim.tenant", "requires tenancy.enabled")
+	}
+	if err := c.Confirm.Validate(); err != nil {
+		fail("confirm", "%v", err)
+	}
+	if err := c.Notify.Validate(); err != nil {
+		fail("notify", "%v", err)
+	}
+	if err := c.Trash.Validate(); err != nil {
+		fail("trash", "%v", err)
+	}
+	if err := c.Tenancy.Validate(); err != nil {
+		fail("tenancy", "%v", err)
+	}
+	if err := c.Plans.Validate(); err != nil {
+		fail("plans", "%v", err)
+	}
+	if c.Plans.Enabled() && !c.Tenancy.Enabled {
+		fail("plans", "require tenancy.enabled, as plans are assigned to tenants")
+	}
+	for _, name := range c.Plans.Names() {
+		for flag := range c.Plans.Tiers[name].Features {
+			if _, ok := c.FeatureFlags[flag]; !ok {
+				fail("plans.tiers."+name+".features", "%q is not declared in feature_flags", flag)
+			}
+		}
+	}
+	if c.Environment == Development && c.Fixtures.Dir != "" && c.Tenancy.Enabled {
+		fail("fixtures.dir", "cannot seed with tenancy enabled, where every item belongs to the tenant of a request")
+	}
+	if err := c.Resilience.Validate(); err != nil {
+		fail("resilience", "%v", err)
+	}
+	if err := c.HTTPClient.Validate(); err != nil {
+		fail("http_client", "%v", err)
+	}
+
+	if err := c.Jobs.Validate(); err != nil {
+		fail("jobs", "%v", err)
+	}
+	if c.Jobs.EnqueueWait < 0 {
+		fail("jobs.enqueue_wait", "must not be negative")
+	}
+	if c.Jobs.CacheRefresh != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.CacheRefresh); err != nil {
+			fail("jobs.cache_refresh", "%v", err)
+		}
+	}
+	if c.Jobs.OutboxPurge != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.OutboxPurge); err != nil {
+			fail("jobs.outbox_purge", "%v", err)
+		}
+	}
+	if c.Jobs.TrashPurge != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.TrashPurge); err != nil {
+			fail("jobs.trash_purge", "%v", err)
+		}
+	}
+	if c.Jobs.Exports != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.Exports); err != nil {
+			fail("jobs.exports", "%v", err)
+		}
+	}
+	if c.Jobs.Webhooks != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.Webhooks); err != nil {
+			fail("jobs.webhooks", "%v", err)
+		}
+	}
+	if c.Jobs.Hooks != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.Hooks); err != nil {
+			fail("jobs.hooks", "%v", err)
+		}
+	}
+	if c.Jobs.Notify != "" {
+		if _, err := worker.ParseSchedule(c.Jobs.Notify); err != nil {
+			fail("jobs.notify", "%v", err)
+		}
+	}
+
+	for i, rule := range c.Transforms {
+		if err := rule.Validate(); err != nil {
+			fail(fmt.Sprintf("transforms[%d]", i), "%v", err)
+		}
+	}
+
+	for field, h := range map[string]string{"envelope.header": c.Envelope.Header, "envelope.version_header": c.Envelope.VersionHeader} {
+		if strings.ContainsAny(h, " :\r\n") {
+			fail(field, "must be a valid header name (got %q)", h)
+		}
+	}
+	if len(c.Envelope.Versions) > 0 && c.Envelope.VersionHeader == "" {
+		fail("envelope.versions", "requires envelope.version_header")
+	}
+
+	if _, ok := pipeline.Presets[c.Middleware.Preset]; c.Middleware.Preset != "" && !ok {
+		fail("middleware.preset", "must be one of development, staging, production, test (got %q)", c.Middleware.Preset)
+	}
+
+	if err := c.CORSOptions().Validate(); err != nil {
+		fail("cors", "%v", err)
+	}
+	for group := range c.Security {
+		if err := c.SecurityFor(group).Validate(); err != nil {
+			fail("security."+group, "%v", err)
+		}
+	}
+
+	if err := c.Compression.Validate(); err != nil {
+		fail("compression", "%v", err)
+	}
+
+	for name, l := range c.APIVersions {
+		if v, err := apiversion.Parse(name); err != nil || v.String() != name {
+			fail("api_versions."+name, "must be named like v1")
+		} else if err := l.Validate(); err != nil {
+			fail("api_versions."+name, "%v", err)
+		}
+	}
+
+	if c.Recorder.Capacity <= 0 {
+		fail("recorder.capacity", "must be positive")
+	}
+	if c.Recorder.MaxBodyBytes <= 0 {
+		fail("recorder.max_body_bytes", "must be positive")
+	}
+	if err := c.Recorder.Validate(); err != nil {
+		fail("recorder.redact_fields", "%v", err)
+	}
+	if err := c.PayloadLog.Validate(); err != nil {
+		fail("payload_log", "%v", err)
+	}
+
+	if !c.Compatibility.Valid() {
+		fail("compatibility", "must be strict or lenient (got %q)", c.Compatibility)
+	}
+
+	if c.MQTT.BrokerURL != "" {
+		if u, err := url.Parse(c.MQTT.BrokerURL.Reveal()); err != nil || !oneOf(u.Scheme, "tcp", "ssl", "ws", "wss") || u.Host == "" {
+			fail("mqtt.broker_url", "must be a tcp://, ssl://, ws:// or wss:// URL")
+		}
+		if c.MQTT.ClientID == "" {
+			fail("mqtt.client_id", "is required when mqtt.broker_url is set")
+		}
+		if c.MQTT.TopicPrefix == "" || strings.ContainsAny(c.MQTT.TopicPrefix, "+#") {
+			fail("mqtt.topic_prefix", "must be non-empty and contain no wildcards")
+		}
+		for id, token := range c.MQTT.Clients {
+			if strings.ContainsAny(id, "/+#") {
+				fail("mqtt.clients", "client id %q must not contain '/', '+' or '#'", id)
+			}
+			if len(token) < 16 {
+				fail("mqtt.clients."+id, "token must be at least 16 characters")
+			}
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
-// GetBoolEnv reads a boolean environment variable with a default value.
-func GetBoolEnv(key string, defaultValue bool) bool {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultValue
+// File returns the path of the config file the configuration was loaded
+// from, or "" when none was used.
+func (c *Config) File() string {
+	return c.file
+}
+
+// DescribeOnly reports whether -describe was given: print the server's
+// description (see internal/describe) instead of serving.
+func (c *Config) DescribeOnly() bool {
+	return c.describe
+}
+
+// RateLimitFor returns the limit for a route group, falling back to "default".
+// Limits are checked by Validate, so parsing cannot fail on a loaded Config.
+func (c *Config) RateLimitFor(group string) ratelimit.Limit {
+	raw, ok := c.RateLimit.Limits[group]
+	if !ok {
+		raw = c.RateLimit.Limits["default"]
 	}
-	b, err := strconv.ParseBool(val)
-	if err != nil {
-		log.Printf("WARNING: Invalid boolean value for %s: %s, using default %v", key, val, defaultValue)
-		return defaultValue
+	limit, _ := ratelimit.ParseLimit(raw)
+	return limit
+}
+
+// TrustedProxies returns server.trusted_proxies as ranges, an address being
+// a range of one. They are checked by Validate, so parsing cannot fail on a
+// loaded Config.
+func (c *Config) TrustedProxies() []netip.Prefix {
+	var out []netip.Prefix
+	for _, proxy := range c.Server.TrustedProxies {
+		if p, err := parseProxy(proxy); err == nil {
+			out = append(out, p)
+		}
 	}
-	return b
+	return out
 }
 
-// GetIntEnv reads an integer environment variable with a default value.
-func GetIntEnv(key string, defaultValue int) int {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultValue
+func parseProxy(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
 	}
-	i, err := strconv.Atoi(val)
+	addr, err := netip.ParseAddr(s)
 	if err != nil {
-		log.Printf("WARNING: Invalid integer value for %s: %s, using default %d", key, val, defaultValue)
-		return defaultValue
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Roles returns the role grants of auth.roles, with the accounts of
+// auth.admins holding the admin role too.
+func (c *Config) Roles() auth.Roles {
+	roles := make(auth.Roles, len(c.Auth.Roles)+1)
+	for role, subjects := range c.Auth.Roles {
+		roles[role] = subjects
+	}
+	roles[auth.RoleAdmin] = append(slices.Clone(c.Auth.Roles[auth.RoleAdmin]), c.Auth.Admins...)
+	return roles
+}
+
+// OIDCProviders returns auth.providers with each provider's defaults applied.
+func (c *Config) OIDCProviders() map[string]auth.OIDCProvider {
+	out := make(map[string]auth.OIDCProvider, len(c.Auth.Providers))
+	for name, p := range c.Auth.Providers {
+		out[name] = p.WithDefaults(name)
+	}
+	return out
+}
+
+// LDAP returns auth.ldap with its defaults applied.
+func (c *Config) LDAP() auth.LDAPOptions {
+	return c.Auth.LDAP.WithDefaults()
+}
+
+// Passkeys returns auth.passkeys, whose origins default to this server's
+// on localhost while rp_id is localhost, for trying passkeys out locally.
+func (c *Config) Passkeys() auth.PasskeyOptions {
+	p := c.Auth.Passkeys
+	if len(p.Origins) == 0 && p.RPID == "localhost" {
+		p.Origins = []string{"http://localhost:" + c.Server.Port}
+	}
+	return p
+}
+
+// testModeSecret signs tokens in test mode unless auth.jwt_secret is set,
+// so that they repeat from run to run.
+const testModeSecret = "test-mode-signing-key-not-secret!"
+
+// EnableTestMode switches test mode on and keeps all state in the process:
+// a fresh SQLite database in memory, in-memory cache, rate limit and
+// revocation stores, and in-process domain events without an outbox or
+// MQTT bridge. Load calls it when test_mode.enabled is set.
+func (c *Config) EnableTestMode() {
+	c.TestMode.Enabled = true
+	c.Database = DatabaseConfig{URL: "sqlite::memory:", Migrate: true}
+	c.Cache.Backend = cache.Memory
+	c.RateLimit.Backend = "memory"
+	c.Auth.RevocationBackend = "memory"
+	c.Domain.Publisher = "inproc"
+	c.Outbox.Enabled = false
+	c.MQTT.BrokerURL = ""
+	if c.Auth.JWTSecret == "" {
+		c.Auth.JWTSecret = testModeSecret
+	}
+}
+
+// IDStrategy returns the idgen strategy named by ids or, when unset,
+// sequential in the test environment and uuidv7 otherwise.
+func (c *Config) IDStrategy() string {
+	switch {
+	case c.IDs != "":
+		return c.IDs
+	case c.Environment == Test:
+		return "sequential"
+	default:
+		return "uuidv7"
+	}
+}
+
+// MiddlewarePreset returns the preset named by middleware.preset, or the one
+// for the environment when unset.
+func (c *Config) MiddlewarePreset() pipeline.Preset {
+	if p, ok := pipeline.Presets[c.Middleware.Preset]; ok {
+		return p
+	}
+	return pipeline.Presets[string(c.Environment)]
+}
+
+// CORSOptions returns the cors settings with unset fields taken from the
+// middleware preset: local origins in development, none in production.
+func (c *Config) CORSOptions() cors.Options {
+	return c.CORS.WithDefaults(c.MiddlewarePreset().CORS)
+}
+
+// SecurityFor returns the hardening settings of a route group: its own,
+// then those under "default", then the middleware preset's. Routes outside
+// any group use "default".
+func (c *Config) SecurityFor(group string) hardening.Options {
+	return c.Security[group].WithDefaults(c.Security["default"]).WithDefaults(c.MiddlewarePreset().Security)
+}
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
 	}
-	return i
+	return false
 }
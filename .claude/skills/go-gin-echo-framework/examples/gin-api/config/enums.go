@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Environment is the deployment environment. It selects the default
+// middleware preset and ID strategy and gates test mode.
+type Environment string
+
+const (
+	Development Environment = "development"
+	Staging     Environment = "staging"
+	Production  Environment = "production"
+	Test        Environment = "test"
+)
+
+// Environments lists every Environment. A switch on Environment must handle
+// each of them; tests range over this list to check that it does.
+var Environments = []Environment{Development, Staging, Production, Test}
+
+// ParseEnvironment returns the Environment named s.
+func ParseEnvironment(s string) (Environment, error) {
+	return parseEnum("environment", s, Environments)
+}
+
+// UnmarshalText rejects unknown environments while the config file,
+// environment variables and flags are read.
+func (e *Environment) UnmarshalText(text []byte) (err error) {
+	*e, err = ParseEnvironment(string(text))
+	return err
+}
+
+// LogLevel is the minimum level of emitted log records.
+type LogLevel string
+
+const (
+	LogDebug LogLevel = "debug"
+	LogInfo  LogLevel = "info"
+	LogWarn  LogLevel = "warn"
+	LogError LogLevel = "error"
+)
+
+// LogLevels lists every LogLevel, least severe first.
+var LogLevels = []LogLevel{LogDebug, LogInfo, LogWarn, LogError}
+
+// ParseLogLevel returns the LogLevel named s.
+func ParseLogLevel(s string) (LogLevel, error) {
+	return parseEnum("log level", s, LogLevels)
+}
+
+func (l *LogLevel) UnmarshalText(text []byte) (err error) {
+	*l, err = ParseLogLevel(string(text))
+	return err
+}
+
+// parseEnum returns the value of values spelled s, naming what it parses
+// and the accepted values if there is none.
+func parseEnum[T ~string](what, s string, values []T) (T, error) {
+	for _, v := range values {
+		if string(v) == s {
+			return v, nil
+		}
+	}
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = string(v)
+	}
+	return "", fmt.Errorf("invalid %s %q (want %s)", what, s, strings.Join(names, ", "))
+}
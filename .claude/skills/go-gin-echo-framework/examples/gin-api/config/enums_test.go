@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/pipeline"
+)
+
+func TestLoadRejectsUnknownEnumValues(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("logging:\n  level: verbose\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load([]string{"-config", file}); err == nil || !strings.Contains(err.Error(), `invalid log level "verbose"`) {
+		t.Errorf("config file: got %v", err)
+	}
+
+	t.Setenv("CACHE_BACKEND", "memcached")
+	_, err := Load([]string{"-environment", "prod"})
+	for _, want := range []string{`env CACHE_BACKEND: invalid cache backend "memcached"`, `flag -environment: invalid environment "prod"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestEveryEnvironmentHasAPreset(t *testing.T) {
+	for _, env := range Environments {
+		if _, err := ParseEnvironment(string(env)); err != nil {
+			t.Fatal(err)
+		}
+		c := Default()
+		c.Environment = env
+		if got := c.MiddlewarePreset(); got.Name != string(env) {
+			t.Errorf("environment %s uses preset %q", env, got.Name)
+		}
+	}
+	if len(pipeline.Presets) != len(Environments) {
+		t.Errorf("%d presets for %d environments", len(pipeline.Presets), len(Environments))
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// binding ties one setting to its environment variable. The matching flag
+// name is derived from the variable: SERVER_READ_TIMEOUT -> -server-read-timeout.
+type binding struct {
+	env   string
+	usage string
+	ptr   any // *string, *int, *bool, *time.Duration or encoding.TextUnmarshaler inside the Config being loaded
+}
+
+func (b binding) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(b.env), "_", "-")
+}
+
+func bindings(c *Config) []binding {
+	return []binding{
+		{"ENVIRONMENT", "deployment environment (development, staging, production, test)", &c.Environment},
+		{"ID_STRATEGY", "ID strategy of created entities (uuidv7, ulid, ksuid, sequential); defaults to sequential in test, uuidv7 otherwise", &c.IDs},
+		{"MIDDLEWARE_PRESET", "middleware bundle (development, staging, production, test); defaults to the environment", &c.Middleware.Preset},
+		{"COMPATIBILITY", "strict rejects unknown request fields and query parameters, lenient logs them", (*string)(&c.Compatibility)},
+		{"MOCK", "serve fake responses generated from the OpenAPI spec instead of running the handlers", &c.Mock},
+		{"PORT", "HTTP listen port", &c.Server.Port},
+		{"SERVER_READ_TIMEOUT", "maximum duration for reading a request", &c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", "maximum duration for writing a response", &c.Server.WriteTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", "grace period for in-flight requests on shutdown", &c.Server.ShutdownTimeout},
+		{"DATABASE_URL", "database URL (postgres://, sqlite:, mongodb://) or \"in-memory\"", &c.Database.URL},
+		{"MIGRATE", "apply pending SQL schema migrations on startup", &c.Database.Migrate},
+		{"REDIS_URL", "Redis URL used by the redis cache, rate limit and revocation backends", &c.Redis.URL},
+		{"JWT_SECRET", "HMAC secret for signing tokens", &c.Auth.JWTSecret},
+		{"TOKEN_TTL", "lifetime of issued access tokens", &c.Auth.TokenTTL},
+		{"REFRESH_TTL", "lifetime of issued refresh tokens", &c.Auth.RefreshTTL},
+		{"REVOCATION_BACKEND", "revoked token store (memory, redis)", &c.Auth.RevocationBackend},
+		{"MAX_FAILED_LOGINS", "consecutive failed logins that lock an account", &c.Auth.MaxFailedLogins},
+		{"LOCKOUT_DURATION", "how long a locked account rejects logins", &c.Auth.LockoutDuration},
+		{"LOGIN_PROVIDER", "checks the passwords of logins (local, ldap)", &c.Auth.LoginProvider},
+		{"LDAP_URL", "directory server of the ldap login provider, ldap:// or ldaps://", &c.Auth.LDAP.URL},
+		{"LDAP_BIND_PASSWORD", "password of auth.ldap.bind_dn", &c.Auth.LDAP.BindPassword},
+		{"LOG_LEVEL", "log level (debug, info, warn, error)", &c.Logging.Level},
+		{"LOG_FORMAT", "log format (text, json)", &c.Logging.Format},
+		{"CACHE_BACKEND", "repository cache backend (memory, redis)", &c.Cache.Backend},
+		{"CACHE_TTL", "repository cache TTL; 0 disables caching", &c.Cache.TTL},
+		{"CACHE_SIZE", "max entries in the in-memory cache", &c.Cache.Size},
+		{"RATE_LIMIT_BACKEND", "rate limit store (memory, redis)", &c.RateLimit.Backend},
+		{"HEALTH_TIMEOUT", "per-check timeout for readiness probes", &c.Health.Timeout},
+		{"GRPC_PORT", "gRPC listen port; empty disables the gRPC server", &c.GRPC.Port},
+		{"ADMIN_PORT", "listen port of the admin routes; empty serves them on PORT", &c.Admin.Port},
+		{"FLAG_RULES_FILE", "YAML file of feature flag rules, reread when it changes", &c.FlagRules.File},
+		{"FLAG_RULES_URL", "remote provider of feature flag rules, as JSON", &c.FlagRules.URL},
+		{"GRPC_MULTIPLEX", "serve gRPC on the HTTP port alongside HTTP", &c.GRPC.Multiplex},
+		{"MQTT_BROKER_URL", "MQTT broker URL; empty disables the MQTT bridge", &c.MQTT.BrokerURL},
+		{"MQTT_CLIENT_ID", "MQTT client id of the bridge", &c.MQTT.ClientID},
+		{"MQTT_TOPIC_PREFIX", "prefix for MQTT event and command topics", &c.MQTT.TopicPrefix},
+		{"DOMAIN_EVENTS_PUBLISHER", "domain event publisher (inproc, nats, kafka)", &c.Domain.Publisher},
+		{"NATS_URL", "NATS server URL of the nats domain event publisher", &c.Domain.NATSURL},
+		{"DOMAIN_EVENTS_TOPIC", "NATS subject prefix or Kafka topic for domain events", &c.Domain.Topic},
+		{"SEARCH_BACKEND", "full-text search backend (memory, elasticsearch)", &c.Search.Backend},
+		{"SEARCH_URL", "Elasticsearch URL of the elasticsearch search backend", &c.Search.URL},
+		{"BLOB_BACKEND", "store of uploaded images (local, s3)", &c.Blob.Backend},
+		{"BLOB_DIR", "directory of the local blob backend", &c.Blob.Dir},
+		{"BLOB_SIGNING_KEY", "HMAC key of the download URLs of the local blob backend", &c.Blob.SigningKey},
+		{"BLOB_BUCKET", "bucket of the s3 blob backend", &c.Blob.Bucket},
+		{"BLOB_REGION", "region of the s3 blob backend", &c.Blob.Region},
+		{"BLOB_ENDPOINT", "URL of an S3-compatible service; empty is AWS", &c.Blob.Endpoint},
+		{"BLOB_ACCESS_KEY_ID", "access key ID of the s3 blob backend", &c.Blob.AccessKeyID},
+		{"BLOB_SECRET_ACCESS_KEY", "secret access key of the s3 blob backend", &c.Blob.SecretAccessKey},
+		{"WEBHOOKS_GITHUB_SECRET", "secret of the GitHub webhook at POST /webhooks/github; empty disables it", &c.Webhooks.GitHub.Secret},
+		{"SCIM_TOKEN", "bearer token of the identity provider provisioning accounts at /scim/v2; empty disables it", &c.SCIM.Token},
+		{"CONFIRM_ENABLED", "confirm bulk deletes with a token from a first call", &c.Confirm.Enabled},
+		{"TENANCY_ENABLED", "serve several tenants, each seeing only its own users", &c.Tenancy.Enabled},
+		{"TENANCY_ISOLATION", "how tenants' users are kept apart (column, schema)", &c.Tenancy.Isolation},
+		{"TENANCY_AUDIT", "fail any repository call that would return another tenant's users", &c.Tenancy.Audit},
+		{"PAYLOAD_LOG", "log the request and response bodies of every request, redacted", &c.PayloadLog.Enabled},
+		{"TRASH_WINDOW", "how long a deleted user can be restored; 0 makes deletes final", &c.Trash.Window},
+		{"RESILIENCE_ENABLED", "guard the database and outbound HTTP with circuit breakers, timeouts and retries", &c.Resilience.Enabled},
+		{"OUTBOX_ENABLED", "record domain events in the outbox table and relay them to the broker", &c.Outbox.Enabled},
+		{"FIXTURES_DIR", "directory of the seed data loaded in development; empty disables", &c.Fixtures.Dir},
+		{"APP_VERSION", "version of the deploy posted to notify.events.deploy; empty is the VCS revision of the binary", &c.Notify.Version},
+		{"TEST_MODE", "fake clock, seeded IDs, in-process state and captured outbound effects (environment test only)", &c.TestMode.Enabled},
+	}
+}
+
+// Load resolves the configuration from defaults, an optional YAML or TOML file
+// (-config flag or CONFIG_FILE), environment variables and the given
+// command-line arguments, in that order of precedence, then validates it.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	binds := bindings(cfg)
+
+	// Flags are collected first (to find the config file) but applied last.
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	describe := fs.Bool("describe", false, "print the resolved config, modules, routes, storage and listeners as JSON and exit without serving")
+	flagValues := map[string]string{}
+	for _, b := range binds {
+		name := b.flagName()
+		set := func(v string) error {
+			flagValues[name] = v
+			return nil
+		}
+		if _, ok := b.ptr.(*bool); ok {
+			fs.BoolFunc(name, b.usage+" (env "+b.env+")", set) // a bare -name means true
+		} else {
+			fs.Func(name, b.usage+" (env "+b.env+")", set)
+		}
+	}
+	var flagLimits []string
+	fs.Func("rate-limit", "per-group rate limit as group=<requests>/<period>, repeatable (env RATE_LIMIT_<GROUP>)", func(v string) error {
+		flagLimits = append(flagLimits, v)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+		cfg.file = *configFile
+	}
+	cfg.describe = *describe
+
+	var errs []error
+	for _, b := range binds {
+		if v, ok := os.LookupEnv(b.env); ok && v != "" {
+			if err := setValue(b.ptr, v); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", b.env, err))
+			}
+		}
+	}
+	for _, kv := range os.Environ() {
+		key, val, _ := strings.Cut(kv, "=")
+		group, ok := strings.CutPrefix(key, "RATE_LIMIT_")
+		if !ok || group == "BACKEND" {
+			continue
+		}
+		cfg.setRateLimit(strings.ToLower(group), val)
+	}
+
+	for _, b := range binds {
+		if v, ok := flagValues[b.flagName()]; ok {
+			if err := setValue(b.ptr, v); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", b.flagName(), err))
+			}
+		}
+	}
+	for _, v := range flagLimits {
+		group, limit, ok := strings.Cut(v, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("flag -rate-limit: expected group=<requests>/<period>, got %q", v))
+			continue
+		}
+		cfg.setRateLimit(group, limit)
+	}
+
+	if cfg.TestMode.Enabled {
+		cfg.EnableTestMode()
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) setRateLimit(group, limit string) {
+	if c.RateLimit.Limits == nil {
+		c.RateLimit.Limits = map[string]string{}
+	}
+	c.RateLimit.Limits[group] = limit
+}
+
+// loadFile decodes path over cfg. Unknown keys are rejected so typos fail
+// loudly instead of being silently ignored.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+	case ".toml":
+		// TOML has no duration type; normalise through YAML so both formats
+		// share the same struct tags, duration parsing and strictness.
+		var raw map[string]any
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(raw); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q (want .yaml, .yml or .toml)", ext)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func setValue(ptr any, raw string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = raw
+	case *int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		*p = i
+	case *bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		*p = v
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		*p = d
+	case encoding.TextUnmarshaler:
+		return p.UnmarshalText([]byte(raw))
+	default:
+		return fmt.Errorf("unsupported config field type %T", ptr)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package apiversion
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware applies p to the routes of one version, answering for a
+// retired version before any handler runs.
+func Middleware(p *Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status, msg := p.Apply(c.Writer, c.Request); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		c.Next()
+	}
+}
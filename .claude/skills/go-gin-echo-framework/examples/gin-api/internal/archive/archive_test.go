@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+func validate(item any) error {
+	if item.(*model.User).Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func newMemory() (*Archive, service.CrudService[model.User]) {
+	uow := repository.NewNoopUnitOfWork()
+	users := service.NewCrudService[model.User](repository.NewMemoryRepository[model.User]("user"), uow, nil, nil, nil, nil, nil, nil, "user", service.Hooks[model.User]{})
+	return New(uow, validate, nil, Resource("users", users)), users
+}
+
+func newSQL(t *testing.T) (*Archive, service.CrudService[model.User]) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	uow := repository.NewSQLUnitOfWork(db)
+	users := service.NewCrudService[model.User](repository.NewSQLRepository[model.User](db, dialect, "users", "user"), uow, nil, nil, nil, nil, nil, nil, "user", service.Hooks[model.User]{})
+	return New(uow, validate, nil, Resource("users", users)), users
+}
+
+func export(t *testing.T, a *Archive) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := a.Export(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// archiveOf zips files, e.g. a manifest and users.ndjson.
+func archiveOf(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, _ := zw.Create(name)
+		io.WriteString(f, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExportImportAcrossBackends(t *testing.T) {
+	ctx := context.Background()
+	from, src := newMemory()
+	for _, u := range []model.User{{ID: "a", Name: "Ann", Email: "ann@example.com"}, {ID: "b", Name: "Bob"}} {
+		if _, err := src.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	to, dst := newSQL(t)
+	r := export(t, from)
+	results, err := to.Import(ctx, r, r.Size(), Skip)
+	if err != nil || len(results) != 1 || results[0] != (Result{Collection: "users", Created: 2}) {
+		t.Fatalf("Import = %+v, %v", results, err)
+	}
+	if u, err := dst.GetByID(ctx, "a"); err != nil || u.Name != "Ann" || u.Email != "ann@example.com" {
+		t.Errorf("imported a = %+v, %v", u, err)
+	}
+}
+
+func TestImportStrategies(t *testing.T) {
+	ctx := context.Background()
+	manifest := `{"format": 1, "collections": [{"name": "users", "file": "users.ndjson", "version": 1, "items": 2}]}`
+	items := `{"id": "a", "name": "Ann Imported"}` + "\n" + `{"id": "b", "name": "Bob"}` + "\n"
+	for strategy, want := range map[Strategy]struct {
+		result Result
+		name   string
+		email  string
+	}{
+		Skip:      {Result{Collection: "users", Created: 1, Skipped: 1}, "Ann", "ann@example.com"},
+		Overwrite: {Result{Collection: "users", Created: 1, Overwritten: 1}, "Ann Imported", ""},
+		Merge:     {Result{Collection: "users", Created: 1, Merged: 1}, "Ann Imported", "ann@example.com"},
+	} {
+		t.Run(string(strategy), func(t *testing.T) {
+			a, users := newSQL(t)
+			if _, err := users.Create(ctx, &model.User{ID: "a", Name: "Ann", Email: "ann@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+			r := archiveOf(t, map[string]string{"manifest.json": manifest, "users.ndjson": items})
+			results, err := a.Import(ctx, r, r.Size(), strategy)
+			if err != nil || len(results) != 1 || results[0] != want.result {
+				t.Fatalf("Import = %+v, %v; want %+v", results, err, want.result)
+			}
+			if u, err := users.GetByID(ctx, "a"); err != nil || u.Name != want.name || u.Email != want.email {
+				t.Errorf("a = %+v, %v", u, err)
+			}
+		})
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	ctx := context.Background()
+	manifest := func(version, items string) string {
+		return `{"format": 1, "collections": [{"name": "users", "file": "users.ndjson", "version": ` + version + `, "items": ` + items + `}]}`
+	}
+	for name, files := range map[string]map[string]string{
+		"no manifest":        {"users.ndjson": ""},
+		"newer format":       {"manifest.json": `{"format": 2}`},
+		"newer version":      {"manifest.json": manifest("2", "0"), "users.ndjson": ""},
+		"unknown collection": {"manifest.json": `{"format": 1, "collections": [{"name": "orders", "file": "orders.ndjson", "version": 1}]}`},
+		"missing file":       {"manifest.json": manifest("1", "0")},
+		"truncated":          {"manifest.json": manifest("1", "2"), "users.ndjson": `{"id": "a", "name": "Ann"}`},
+		"malformed item":     {"manifest.json": manifest("1", "1"), "users.ndjson": `{"id": "a", "name": `},
+		"item without id":    {"manifest.json": manifest("1", "1"), "users.ndjson": `{"name": "Ann"}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a, _ := newSQL(t)
+			r := archiveOf(t, files)
+			if _, err := a.Import(ctx, r, r.Size(), Skip); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Import: %v, want ErrInvalid", err)
+			}
+		})
+	}
+
+	t.Run("invalid item", func(t *testing.T) {
+		a, users := newSQL(t)
+		r := archiveOf(t, map[string]string{"manifest.json": manifest("1", "2"), "users.ndjson": `{"id": "a", "name": "Ann"}` + "\n" + `{"id": "b"}`})
+		_, err := a.Import(ctx, r, r.Size(), Skip)
+		if !errors.Is(err, service.ErrInvalid) || !strings.Contains(err.Error(), "item 2") {
+			t.Fatalf("Import: %v, want ErrInvalid for item 2", err)
+		}
+		if _, err := users.GetByID(ctx, "a"); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("item 1 of a failed import: %v", err)
+		}
+	})
+}
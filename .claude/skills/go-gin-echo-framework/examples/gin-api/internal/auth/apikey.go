@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// APIKeyHeader carries an API key: "X-API-Key: ak_<id>_<secret>".
+const APIKeyHeader = "X-API-Key"
+
+const apiKeyPrefix = "ak_"
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// APIKeyInfo describes a key without its secret.
+type APIKeyInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatedAPIKey is returned once, on creation; the key cannot be recovered
+// later.
+type CreatedAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+type APIKeyService interface {
+	Create(ctx context.Context, owner, name string) (*CreatedAPIKey, error)
+	List(ctx context.Context, owner string) ([]APIKeyInfo, error)
+	Delete(ctx context.Context, owner, id string) error
+	Verify(ctx context.Context, key string) (*Caller, error)
+}
+
+type apiKeyService struct {
+	keys  repository.APIKeyRepository
+	clock clock.Clock
+}
+
+// NewAPIKeyService stores keys in repo. Secrets are random 256-bit values,
+// so a plain SHA-256 is enough to keep stored hashes useless to an attacker.
+// Creation times come from clk (nil is the system clock).
+func NewAPIKeyService(repo repository.APIKeyRepository, clk clock.Clock) APIKeyService {
+	return &apiKeyService{keys: repo, clock: clock.OrSystem(clk)}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, owner, name string) (*CreatedAPIKey, error) {
+	id := newID()[:16]
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	key := &model.APIKey{
+		ID:        id,
+		Owner:     owner,
+		Name:      strings.TrimSpace(name),
+		Hash:      hashSecret(encoded),
+		CreatedAt: s.clock.Now().UTC().Truncate(time.Second),
+		TenantID:  tenant.From(ctx),
+	}
+	if _, err := s.keys.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	return &CreatedAPIKey{APIKeyInfo: info(key), Key: apiKeyPrefix + id + "_" + encoded}, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, owner string) ([]APIKeyInfo, error) {
+	all, err := s.keys.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	out := []APIKeyInfo{}
+	for i := range all {
+		if all[i].Owner == owner {
+			out = append(out, info(&all[i]))
+		}
+	}
+	return out, nil
+}
+
+func (s *apiKeyService) Delete(ctx context.Context, owner, id string) error {
+	key, err := s.keys.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && key.Owner != owner) {
+		return ErrAPIKeyNotFound // other accounts' keys are indistinguishable from missing ones
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get API key: %w", err)
+	}
+	if err := s.keys.Delete(ctx, id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	return nil
+}
+
+func (s *apiKeyService) Verify(ctx context.Context, key string) (*Caller, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !strings.HasPrefix(key, apiKeyPrefix) || !ok || id == "" || secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	stored, err := s.keys.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	return &Caller{Subject: stored.Owner, Method: MethodAPIKey, KeyID: stored.ID, Tenant: stored.TenantID}, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func info(k *model.APIKey) APIKeyInfo {
+	return APIKeyInfo{ID: k.ID, Name: k.Name, CreatedAt: k.CreatedAt}
+}
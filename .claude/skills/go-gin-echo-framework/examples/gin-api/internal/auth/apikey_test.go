@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+func newAPIKeys(t *testing.T) (APIKeyService, repository.APIKeyRepository) {
+	db, dialect := migratedDB(t)
+	repo := repository.NewSQLRepository[model.APIKey](db, dialect, "api_keys", "API key")
+	return NewAPIKeyService(repo, nil), repo
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	keys, repo := newAPIKeys(t)
+
+	created, err := keys.Create(ctx, "user-1", "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(created.Key, stored.Hash) || stored.Hash == "" {
+		t.Errorf("stored hash %q must be non-empty and differ from the key", stored.Hash)
+	}
+
+	caller, err := keys.Verify(ctx, created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*caller, Caller{Subject: "user-1", Method: MethodAPIKey, KeyID: created.ID}) {
+		t.Errorf("caller = %+v", caller)
+	}
+	for _, bad := range []string{created.Key + "x", "ak_" + created.ID + "_", "nonsense"} {
+		if _, err := keys.Verify(ctx, bad); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Verify(%q): err = %v, want ErrInvalidAPIKey", bad, err)
+		}
+	}
+
+	if list, err := keys.List(ctx, "user-2"); err != nil || len(list) != 0 {
+		t.Errorf("other account's keys = %v, %v; want none", list, err)
+	}
+	if err := keys.Delete(ctx, "user-2", created.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("deleting another account's key: err = %v, want ErrAPIKeyNotFound", err)
+	}
+	if err := keys.Delete(ctx, "user-1", created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Verify(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("deleted key: err = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAuthenticateAcceptsBearerOrAPIKey(t *testing.T) {
+	ctx := context.Background()
+	creds, uow := newCredentials(t)
+	tokens := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+	keys, _ := newAPIKeys(t)
+	session := login(t, tokens)
+	key, err := keys.Create(ctx, "user-1", "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := func(name, value string) http.Header {
+		h := http.Header{}
+		h.Set(name, value)
+		return h
+	}
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantMethod string // "" for anonymous
+		wantErr    error
+	}{
+		{"anonymous", http.Header{}, "", nil},
+		{"bearer", header("Authorization", "Bearer "+session.AccessToken), MethodJWT, nil},
+		{"api key", header(APIKeyHeader, key.Key), MethodAPIKey, nil},
+		{"bad bearer", header("Authorization", "Bearer nope"), "", ErrInvalidToken},
+		{"bad api key", header(APIKeyHeader, "ak_nope_nope"), "", ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, err := authenticate(ctx, tt.header, tokens, keys)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			method := ""
+			if caller != nil {
+				method = caller.Method
+			}
+			if method != tt.wantMethod {
+				t.Errorf("method = %q, want %q", method, tt.wantMethod)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package authtest provides a fake AuthService for the tests of handlers
+// and middleware, so they need neither credentials storage nor signed
+// tokens. Its failures are injected through Fake.Faults; see package fault.
+package authtest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/fault"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Lifetimes of the tokens the fake issues.
+const (
+	TokenTTL   = 15 * time.Minute
+	RefreshTTL = 7 * 24 * time.Hour
+)
+
+// Fake is an in-memory AuthService with opaque tokens. It keeps the
+// contract callers rely on: the errors of package auth, refresh tokens
+// that work once, and logout revoking every token of the login. It does
+// not lock accounts out or ask for one-time codes, and accounts log in to
+// any tenant. It is safe for
+// concurrent use.
+type Fake struct {
+	Faults fault.Injector
+
+	mu        sync.Mutex
+	clock     clock.Clock
+	passwords map[string]string       // by email
+	accounts  map[string]auth.Account // by email
+	access    map[string]auth.Claims  // by token
+	refresh   map[string]auth.Claims  // by token; deleted once used
+	revoked   map[string]bool         // by family
+	seq       int
+}
+
+var _ auth.AuthService = (*Fake)(nil)
+
+// NewFake returns a fake without accounts whose tokens expire by clk (nil
+// is the system clock).
+func NewFake(clk clock.Clock) *Fake {
+	return &Fake{
+		clock:     clock.OrSystem(clk),
+		passwords: map[string]string{},
+		accounts:  map[string]auth.Account{},
+		access:    map[string]auth.Claims{},
+		refresh:   map[string]auth.Claims{},
+		revoked:   map[string]bool{},
+	}
+}
+
+// next returns the next number of the fake's IDs and tokens.
+func (f *Fake) next() string {
+	f.seq++
+	return strconv.Itoa(f.seq)
+}
+
+func (f *Fake) Register(ctx context.Context, email, password, name string) (*auth.Account, error) {
+	if err := f.Faults.Check("Register"); err != nil {
+		return nil, err
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(password) < 8 || len(password) > 72 {
+		return nil, auth.ErrInvalidPassword
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, taken := f.accounts[email]; taken {
+		return nil, auth.ErrEmailTaken
+	}
+	account := auth.Account{ID: "account-" + f.next(), Email: email}
+	f.accounts[email] = account
+	f.passwords[email] = password
+	return &account, nil
+}
+
+func (f *Fake) Authenticate(ctx context.Context, email, password string) (*auth.Token, error) {
+	if err := f.Faults.Check("Authenticate"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	account, ok := f.accounts[strings.ToLower(strings.TrimSpace(email))]
+	if !ok || f.passwords[account.Email] != password {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return f.issue(auth.Claims{Subject: account.ID, Family: "family-" + f.next(), Tenant: tenant.From(ctx)}), nil
+}
+
+// CompleteMFA fails: the fake's logins never require a code.
+func (f *Fake) CompleteMFA(ctx context.Context, mfaToken, code string) (*auth.Token, error) {
+	if err := f.Faults.Check("CompleteMFA"); err != nil {
+		return nil, err
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+func (f *Fake) Refresh(ctx context.Context, refreshToken string) (*auth.Token, error) {
+	if err := f.Faults.Check("Refresh"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claims, err := f.valid(f.refresh, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	delete(f.refresh, refreshToken)
+	return f.issue(claims), nil
+}
+
+func (f *Fake) Logout(ctx context.Context, refreshToken string) error {
+	if err := f.Faults.Check("Logout"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claims, err := f.valid(f.refresh, refreshToken)
+	if err != nil {
+		return err
+	}
+	f.revoked[claims.Family] = true
+	return nil
+}
+
+func (f *Fake) Verify(ctx context.Context, token string) (*auth.Claims, error) {
+	if err := f.Faults.Check("Verify"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claims, err := f.valid(f.access, token)
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (f *Fake) Issue(ctx context.Context, subject string) (*auth.Token, error) {
+	if err := f.Faults.Check("Issue"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.issue(auth.Claims{Subject: subject, Family: "family-" + f.next(), Tenant: tenant.From(ctx)}), nil
+}
+
+func (f *Fake) StartGuest(ctx context.Context) (*auth.Token, error) {
+	if err := f.Faults.Check("StartGuest"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := f.next()
+	return f.issue(auth.Claims{Subject: "guest-" + n, Family: "family-" + n, Tenant: tenant.From(ctx), Guest: true}), nil
+}
+
+// issue returns a new pair of tokens of session, in its family.
+func (f *Fake) issue(session auth.Claims) *auth.Token {
+	now := f.clock.Now()
+	n := f.next()
+	access, refresh := "access-"+n, "refresh-"+n
+	session.ExpiresAt = now.Add(TokenTTL)
+	f.access[access] = session
+	session.ExpiresAt = now.Add(RefreshTTL)
+	f.refresh[refresh] = session
+	return &auth.Token{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(TokenTTL.Seconds()),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int(RefreshTTL.Seconds()),
+	}
+}
+
+// valid returns the claims of token in tokens unless it is unknown,
+// expired or revoked.
+func (f *Fake) valid(tokens map[string]auth.Claims, token string) (auth.Claims, error) {
+	claims, ok := tokens[token]
+	if !ok || !f.clock.Now().Before(claims.ExpiresAt) || f.revoked[claims.Family] {
+		return auth.Claims{}, auth.ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Authentication methods reported in Caller.Method.
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// CallerKey is the framework context key under which the middleware stores
+// the *Caller, for access logs.
+const CallerKey = "caller"
+
+// Caller is the authenticated identity of a request.
+type Caller struct {
+	Subject string   // account ID
+	Method  string   // MethodJWT or MethodAPIKey
+	KeyID   string   // set for MethodAPIKey
+	Tenant  string   // the tenant the token or key was issued in; "" without tenancy
+	Roles   []string // granted by the identity provider of the login, see Claims.Roles
+	Guest   bool     // a guest's session, see Claims.Guest
+}
+
+// String renders the caller for logs, e.g. "user-1" or "user-1/key:3f2a".
+func (c *Caller) String() string {
+	if c.KeyID != "" {
+		return c.Subject + "/key:" + c.KeyID
+	}
+	return c.Subject
+}
+
+type callerKey struct{}
+
+// WithCaller returns a copy of ctx carrying caller.
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller stored by the middleware, or nil for
+// anonymous requests.
+func CallerFromContext(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(callerKey{}).(*Caller)
+	return caller
+}
+
+// authenticate identifies the caller from an "Authorization: Bearer" access
+// token or, failing that, an X-API-Key header. It returns nil for requests
+// carrying neither, and ErrInvalidToken or ErrInvalidAPIKey for bad
+// credentials; any other error means a store could not be consulted.
+func authenticate(ctx context.Context, h http.Header, tokens AuthService, keys APIKeyService) (*Caller, error) {
+	if token, ok := strings.CutPrefix(h.Get("Authorization"), "Bearer "); ok {
+		claims, err := tokens.Verify(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return &Caller{Subject: claims.Subject, Method: MethodJWT, Tenant: claims.Tenant, Roles: claims.Roles, Guest: claims.Guest}, nil
+	}
+	if key := h.Get(APIKeyHeader); key != "" {
+		return keys.Verify(ctx, key)
+	}
+	return nil, nil
+}
+
+// unauthorized reports whether err is a credential error rather than an outage.
+func unauthorized(err error) bool {
+	return errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrInvalidAPIKey)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/ldap"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/secret"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// LDAPProviderName is the provider of the identities linking directory
+// users to local accounts.
+const LDAPProviderName = "ldap"
+
+// LDAPOptions configures password logins against a directory server such
+// as Active Directory or OpenLDAP (auth.ldap).
+type LDAPOptions struct {
+	URL            string        `yaml:"url"`             // ldap://host[:389] or ldaps://host[:636]
+	BindDN         string        `yaml:"bind_dn"`         // account that searches for users; empty searches anonymously
+	BindPassword   secret.Secret `yaml:"bind_password"`   // of BindDN
+	BaseDN         string        `yaml:"base_dn"`         // subtree of the users
+	UserFilter     string        `yaml:"user_filter"`     // finds the user of a login, substituted for {login}
+	IDAttribute    string        `yaml:"id_attribute"`    // identifies a user across renames, e.g. entryUUID or objectGUID; empty is the DN
+	EmailAttribute string        `yaml:"email_attribute"` // links a user to the local account registered with the email
+	NameAttribute  string        `yaml:"name_attribute"`  // name of the account created on a user's first login
+	GroupAttribute string        `yaml:"group_attribute"` // DNs of the groups a user is a member of
+	Timeout        time.Duration `yaml:"timeout"`         // of the exchange with the server for one login
+
+	// Roles maps groups to roles: members of any of the groups, by DN,
+	// hold the role for the sessions of their logins
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// WithDefaults fills the settings o leaves empty: the filter and attributes
+// of OpenLDAP's inetOrgPerson and memberOf overlay, and a 10s timeout.
+func (o LDAPOptions) WithDefaults() LDAPOptions {
+	if o.UserFilter == "" {
+		o.UserFilter = "(&(objectClass=person)(uid={login}))"
+	}
+	if o.EmailAttribute == "" {
+		o.EmailAttribute = "mail"
+	}
+	if o.NameAttribute == "" {
+		o.NameAttribute = "cn"
+	}
+	if o.GroupAttribute == "" {
+		o.GroupAttribute = "memberOf"
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 10 * time.Second
+	}
+	return o
+}
+
+// Validate reports the first missing or malformed setting of options with
+// their defaults applied.
+func (o LDAPOptions) Validate() error {
+	u, err := url.Parse(o.URL)
+	switch {
+	case err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "":
+		return errors.New("url must be an ldap:// or ldaps:// URL")
+	case o.BaseDN == "":
+		return errors.New("base_dn is required")
+	case o.BindDN != "" && o.BindPassword == "":
+		return errors.New("bind_password is required with bind_dn")
+	case !strings.Contains(o.UserFilter, "{login}"):
+		return errors.New("user_filter must contain {login}")
+	case o.Timeout <= 0:
+		return errors.New("timeout must be positive")
+	}
+	if err := ldap.ValidateFilter(strings.ReplaceAll(o.UserFilter, "{login}", "x")); err != nil {
+		return fmt.Errorf("user_filter: %v", err)
+	}
+	return nil
+}
+
+type ldapProvider struct {
+	linker
+	opts LDAPOptions
+}
+
+// NewLDAPProvider checks passwords by binding to the directory of opts
+// (with its defaults applied) as the user a login finds. Like an OIDC
+// login, a user's first login links them to a local account: the one
+// registered with their email, which the directory is trusted to vouch for,
+// or one made by createAccount (nil takes IDs from ids, nil is
+// idgen.Default). Lockouts are the directory's own.
+func NewLDAPProvider(creds repository.CredentialRepository, identities repository.IdentityRepository, uow repository.UnitOfWork, createAccount AccountCreator, opts LDAPOptions, clk clock.Clock, ids idgen.Generator) IdentityProvider {
+	if createAccount == nil {
+		createAccount = generatedAccounts(ids)
+	}
+	return &ldapProvider{
+		linker: linker{creds: creds, identities: identities, uow: uow, createAccount: createAccount, clock: clock.OrSystem(clk)},
+		opts:   opts,
+	}
+}
+
+func (p *ldapProvider) Authenticate(ctx context.Context, login, password string) (*Principal, error) {
+	login = strings.TrimSpace(login)
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	dirCtx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+	conn, err := ldap.Dial(dirCtx, p.opts.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, LDAPProviderName, err)
+	}
+	defer conn.Close()
+	if p.opts.BindDN != "" {
+		if err := conn.Bind(p.opts.BindDN, p.opts.BindPassword.Reveal()); err != nil {
+			return nil, fmt.Errorf("%w: %s: bind as %s: %v", ErrProviderUnavailable, LDAPProviderName, p.opts.BindDN, err)
+		}
+	}
+
+	attributes := []string{p.opts.EmailAttribute, p.opts.NameAttribute, p.opts.GroupAttribute}
+	if p.opts.IDAttribute != "" {
+		attributes = append(attributes, p.opts.IDAttribute)
+	}
+	filter := strings.ReplaceAll(p.opts.UserFilter, "{login}", ldap.EscapeFilter(login))
+	entries, err := conn.Search(p.opts.BaseDN, filter, attributes, 2)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, LDAPProviderName, err)
+	}
+	// A login that finds no user, or more than one, proves nobody
+	if len(entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	user := entries[0]
+	if err := conn.Bind(user.DN, password); ldap.IsInvalidCredentials(err) {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, LDAPProviderName, err)
+	}
+
+	subject, err := p.link(ctx, LDAPProviderName, &externalIdentity{
+		Subject:       p.id(user),
+		Email:         user.Value(p.opts.EmailAttribute),
+		EmailVerified: true,
+		Name:          user.Value(p.opts.NameAttribute),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: subject, Tenant: tenant.From(ctx), Roles: p.roles(user.Values(p.opts.GroupAttribute))}, nil
+}
+
+// id returns the stable identifier of user; binary ones such as Active
+// Directory's objectGUID are hex-encoded.
+func (p *ldapProvider) id(user ldap.Entry) string {
+	if p.opts.IDAttribute == "" {
+		return strings.ToLower(user.DN)
+	}
+	id := user.Value(p.opts.IDAttribute)
+	if !utf8.ValidString(id) {
+		return hex.EncodeToString([]byte(id))
+	}
+	return id
+}
+
+// roles returns the roles held by members of groups, sorted.
+func (p *ldapProvider) roles(groups []string) []string {
+	var held []string
+	for role, dns := range p.opts.Roles {
+		if slices.ContainsFunc(dns, func(dn string) bool {
+			return slices.ContainsFunc(groups, func(group string) bool { return sameDN(dn, group) })
+		}) {
+			held = append(held, role)
+		}
+	}
+	sort.Strings(held)
+	return held
+}
+
+// sameDN compares DNs as directories do for the usual attribute types:
+// ignoring case and the spaces around separators.
+func sameDN(a, b string) bool {
+	norm := func(dn string) string {
+		parts := strings.Split(dn, ",")
+		for i, rdn := range parts {
+			k, v, _ := strings.Cut(rdn, "=")
+			parts[i] = strings.TrimSpace(k) + "=" + strings.TrimSpace(v)
+		}
+		return strings.Join(parts, ",")
+	}
+	return strings.EqualFold(norm(a), norm(b))
+}
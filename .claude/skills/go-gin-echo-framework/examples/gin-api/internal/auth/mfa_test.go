@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// newMFA returns an auth service that asks for the codes of the apps of
+// the returned MFA service, both by clk.
+func newMFA(t *testing.T, clk clock.Clock) (AuthService, MFAService) {
+	db, dialect := migratedDB(t)
+	creds := repository.NewSQLRepository[model.Credential](db, dialect, "credentials", "credential")
+	factors := repository.NewSQLRepository[model.TOTPFactor](db, dialect, "totp_factors", "authenticator app")
+	uow := repository.NewSQLUnitOfWork(db)
+	opts := testOptions
+	opts.Clock = clk
+	opts.Factors = factors
+	return NewAuthService(creds, uow, NewMemoryRevocations(clk), nil, opts), NewMFAService(factors, creds, uow, "test", clk)
+}
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	key := []byte("12345678901234567890")
+	for counter, want := range []string{"755224", "287082", "359152", "969429", "338314"} {
+		if got := hotp(key, int64(counter)); got != want {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, want)
+		}
+	}
+}
+
+func TestMFALogin(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	svc, mfa := newMFA(t, clk)
+	account, err := svc.Register(ctx, "ada@example.com", "correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enrollment, err := mfa.EnrollTOTP(ctx, account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(enrollment.URI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Query().Get("secret") != enrollment.Secret || !strings.Contains(uri.Path, "ada@example.com") {
+		t.Fatalf("URI = %s, want an otpauth URI of the secret and email", enrollment.URI)
+	}
+	// Until confirmed, the password is enough
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Fatalf("unconfirmed app: %v", err)
+	}
+	code := func() string {
+		code, err := TOTPCode(enrollment.Secret, clk.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	if err := mfa.ConfirmTOTP(ctx, account.ID, code()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfa.EnrollTOTP(ctx, account.ID); !errors.Is(err, ErrMFAEnrolled) {
+		t.Errorf("enrolling again: err = %v, want ErrMFAEnrolled", err)
+	}
+
+	// login returns the MFA token of a password login
+	login := func() string {
+		t.Helper()
+		_, err := svc.Authenticate(ctx, "ada@example.com", "correct horse")
+		var required *MFARequiredError
+		if !errors.As(err, &required) || required.Token == "" {
+			t.Fatalf("Authenticate = %v, want MFARequiredError", err)
+		}
+		return required.Token
+	}
+	// The confirming code is used up
+	if _, err := svc.CompleteMFA(ctx, login(), code()); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("used code: err = %v, want ErrInvalidCode", err)
+	}
+	clk.Advance(30 * time.Second)
+	mfaToken := login()
+	token, err := svc.CompleteMFA(ctx, mfaToken, code())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := svc.Verify(ctx, token.AccessToken); err != nil || claims.Subject != account.ID {
+		t.Errorf("claims = %+v, %v; want subject %s", claims, err, account.ID)
+	}
+	clk.Advance(30 * time.Second)
+	if _, err := svc.CompleteMFA(ctx, mfaToken, code()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("reused MFA token: err = %v, want ErrInvalidToken", err)
+	}
+	// An MFA token is not an access token, nor the other way round
+	if _, err := svc.Verify(ctx, login()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("MFA token as access token: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.CompleteMFA(ctx, token.AccessToken, code()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token as MFA token: err = %v, want ErrInvalidToken", err)
+	}
+
+	if err := mfa.DisableTOTP(ctx, account.ID, "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("disabling with a wrong code: err = %v, want ErrInvalidCode", err)
+	}
+	if err := mfa.DisableTOTP(ctx, account.ID, code()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Errorf("disabled app: %v", err)
+	}
+}
+
+func TestAcceptCodeAllowsClockDrift(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for drift, want := range map[time.Duration]bool{
+		-30 * time.Second: true,
+		30 * time.Second:  true,
+		-90 * time.Second: false,
+		90 * time.Second:  false,
+	} {
+		code, err := TOTPCode(secret, now.Add(drift))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := acceptCode(&model.TOTPFactor{Secret: secret}, code, now); got != want {
+			t.Errorf("code of now%+v: accepted = %v, want %v", drift, got, want)
+		}
+	}
+}
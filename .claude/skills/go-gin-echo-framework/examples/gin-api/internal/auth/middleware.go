@@ -2,25 +2,90 @@ package auth
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware is a simple example of an authentication middleware.
-func AuthMiddleware() gin.HandlerFunc {
+// Identify records the caller of requests that carry a Bearer access token
+// or an API key in the gin context (CallerKey) and the request context (see
+// CallerFromContext). Requests without credentials pass through anonymously;
+// invalid credentials are rejected, and requests are failed closed when a
+// revocation or key store cannot be reached.
+func Identify(tokens AuthService, keys APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// In a real application, you would validate a token (e.g., JWT) or session.
-		// For demonstration, we'll just check for a specific header.
-		token := c.GetHeader("Authorization")
+		caller, err := authenticate(c.Request.Context(), c.Request.Header, tokens, keys)
+		if unauthorized(err) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if caller != nil {
+			c.Set(CallerKey, caller)
+			c.Request = c.Request.WithContext(WithCaller(c.Request.Context(), caller))
+		}
+		c.Next()
+	}
+}
 
-		if token != "Bearer my-secret-token" {
+// Require rejects anonymous requests on routes behind Identify, and the
+// sessions of guests (see Caller.Guest): those reach only the routes of the
+// items they own, which do not require a caller.
+func Require() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := CallerFromContext(c.Request.Context())
+		if caller == nil {
+			c.Header("WWW-Authenticate", "Bearer")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
+		if caller.Guest {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this route requires an account; register or log in"})
+			return
+		}
+		c.Next()
+	}
+}
 
-		// If authenticated, you might set user information in the context
-		// c.Set("userID", "123")
+// RequireRole is the role-based access control of routes behind Identify:
+// it rejects requests unless the caller's account holds role in the grants
+// roles returns, or the identity provider of the caller's login granted it
+// (see Caller.Roles). roles is consulted on every request so grants can be
+// reloaded at runtime.
+func RequireRole(role string, roles func() Roles) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := CallerFromContext(c.Request.Context())
+		if caller == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if !roles().Has(caller.Subject, role) && !slices.Contains(caller.Roles, role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin is RequireRole(RoleAdmin, roles).
+func RequireAdmin(roles func() Roles) gin.HandlerFunc {
+	return RequireRole(RoleAdmin, roles)
+}
 
+// RequireLogin rejects callers authenticated by an API key on routes behind
+// Identify, for routes too sensitive for a long-lived credential that is
+// often kept in CI settings: the caller must have logged in for a bearer
+// token. Anonymous requests pass, for RequireRole to reject.
+func RequireLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if caller := CallerFromContext(c.Request.Context()); caller != nil && caller.Method != MethodJWT {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this route requires a bearer token from a login, not an API key"})
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,638 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// OIDCCookie carries the state of a login between /auth/oidc/login and the
+// provider's redirect to /auth/oidc/callback.
+const OIDCCookie = "oidc_login"
+
+// oidcLoginTTL bounds how long a user may take at the provider's login page.
+const oidcLoginTTL = 10 * time.Minute
+
+// jwksRefreshInterval rate-limits refetching a provider's signing keys when
+// an ID token names a key that is not cached, e.g. after key rotation.
+const jwksRefreshInterval = time.Minute
+
+var (
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidLogin        = errors.New("invalid or expired external login")
+	ErrProviderUnavailable = errors.New("identity provider unavailable")
+)
+
+// OIDCProvider configures login with one external identity provider. OpenID
+// Connect providers only need Issuer, from which their endpoints and signing
+// keys are discovered. Plain OAuth2 providers such as GitHub set AuthURL,
+// TokenURL and UserInfoURL instead. Providers named "google" or "github"
+// default to those services' public endpoints (see KnownProviders).
+type OIDCProvider struct {
+	Issuer       string        `yaml:"issuer"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret secret.Secret `yaml:"client_secret"`
+	RedirectURL  string        `yaml:"redirect_url"` // this service's /auth/oidc/callback, as registered with the provider
+	Scopes       []string      `yaml:"scopes"`
+	AuthURL      string        `yaml:"auth_url"`
+	TokenURL     string        `yaml:"token_url"`
+	UserInfoURL  string        `yaml:"userinfo_url"`
+	EmailsURL    string        `yaml:"emails_url"` // GitHub-style list of the user's emails with their verification status
+}
+
+// KnownProviders are the defaults applied by WithDefaults.
+var KnownProviders = map[string]OIDCProvider{
+	"google": {
+		Issuer: "https://accounts.google.com",
+		Scopes: []string{"openid", "email", "profile"},
+	},
+	"github": {
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+}
+
+// WithDefaults fills the fields p leaves empty from KnownProviders[name].
+func (p OIDCProvider) WithDefaults(name string) OIDCProvider {
+	known := KnownProviders[name]
+	for _, f := range []struct{ dst, src *string }{
+		{&p.Issuer, &known.Issuer},
+		{&p.AuthURL, &known.AuthURL},
+		{&p.TokenURL, &known.TokenURL},
+		{&p.UserInfoURL, &known.UserInfoURL},
+		{&p.EmailsURL, &known.EmailsURL},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = known.Scopes
+	}
+	return p
+}
+
+// Validate reports the first missing or malformed setting of a provider with
+// its defaults applied.
+func (p OIDCProvider) Validate() error {
+	switch {
+	case p.ClientID == "":
+		return errors.New("client_id is required")
+	case !absoluteURL(p.RedirectURL):
+		return errors.New("redirect_url must be an absolute http:// or https:// URL")
+	case p.Issuer != "" && !absoluteURL(p.Issuer):
+		return errors.New("issuer must be an absolute http:// or https:// URL")
+	case p.Issuer == "" && !(absoluteURL(p.AuthURL) && absoluteURL(p.TokenURL) && absoluteURL(p.UserInfoURL)):
+		return errors.New("issuer, or auth_url, token_url and userinfo_url, are required")
+	}
+	return nil
+}
+
+func absoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// OIDCLogin is where to send a browser to log in with a provider, and the
+// cookie that must come back with the provider's redirect.
+type OIDCLogin struct {
+	URL    string
+	Cookie *http.Cookie
+}
+
+// OIDCService logs users in with external providers using the
+// authorization code flow with PKCE. The first login with an external
+// account links it to a local one: the account registered with the same
+// verified email, or a new account. Later logins map to the same account.
+type OIDCService interface {
+	// Begin starts a login with the named provider.
+	Begin(ctx context.Context, provider string) (*OIDCLogin, error)
+	// Complete exchanges the code the provider redirected back with for the
+	// user's identity and starts a session for the linked local account.
+	// cookie is the value of OIDCCookie set by Begin.
+	Complete(ctx context.Context, cookie, state, code string) (*Token, error)
+}
+
+type OIDCOptions struct {
+	Secret    []byte                  // signs login cookies; empty uses a random per-process key
+	Providers map[string]OIDCProvider // by name, with defaults applied
+	Client    *http.Client            // for provider requests; nil uses a client with a 10s timeout
+	Clock     clock.Clock             // nil is the system clock
+	IDs       idgen.Generator         // account IDs when no AccountCreator is given; nil is idgen.Default
+}
+
+type oidcService struct {
+	linker
+	tokens    AuthService
+	secret    []byte
+	client    *http.Client
+	providers map[string]*provider
+}
+
+// NewOIDCService issues sessions with tokens and links external identities
+// in identities, to credentials' accounts or to ones made by createAccount
+// (nil generates IDs, as in NewAuthService). Providers are discovered on
+// their first login, so one that is down does not prevent startup.
+func NewOIDCService(tokens AuthService, creds repository.CredentialRepository, identities repository.IdentityRepository, uow repository.UnitOfWork, createAccount AccountCreator, opts OIDCOptions) OIDCService {
+	if len(opts.Secret) == 0 {
+		opts.Secret = make([]byte, 32)
+		if _, err := rand.Read(opts.Secret); err != nil {
+			panic(fmt.Sprintf("auth: failed to generate login cookie key: %v", err))
+		}
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if createAccount == nil {
+		createAccount = generatedAccounts(opts.IDs)
+	}
+	s := &oidcService{
+		linker:    linker{creds: creds, identities: identities, uow: uow, createAccount: createAccount, clock: clock.OrSystem(opts.Clock)},
+		tokens:    tokens,
+		secret:    opts.Secret,
+		client:    opts.Client,
+		providers: map[string]*provider{},
+	}
+	for name, cfg := range opts.Providers {
+		s.providers[name] = &provider{name: name, cfg: cfg, client: opts.Client, clock: s.clock}
+	}
+	return s
+}
+
+// loginClaims is the JWT payload of OIDCCookie.
+type loginClaims struct {
+	jwt.RegisteredClaims
+	Provider string `json:"prv"`
+	State    string `json:"st"`
+	Verifier string `json:"vfy"` // PKCE code verifier
+	Nonce    string `json:"non"`
+}
+
+func (s *oidcService) Begin(ctx context.Context, name string) (*OIDCLogin, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	claims := loginClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcLoginTTL)),
+		},
+		Provider: name,
+		State:    newID(),
+		Verifier: randomString(32),
+		Nonce:    newID(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign login cookie: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(claims.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {claims.State},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if p.cfg.Issuer != "" {
+		q.Set("nonce", claims.Nonce)
+	}
+	callback, _ := url.Parse(p.cfg.RedirectURL) // checked by Validate
+	return &OIDCLogin{
+		URL: endpoints.AuthURL + "?" + q.Encode(),
+		Cookie: &http.Cookie{
+			Name:     OIDCCookie,
+			Value:    signed,
+			Path:     callback.Path,
+			MaxAge:   int(oidcLoginTTL.Seconds()),
+			Secure:   callback.Scheme == "https",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode, // sent on the provider's top-level redirect back
+		},
+	}, nil
+}
+
+func (s *oidcService) Complete(ctx context.Context, cookie, state, code string) (*Token, error) {
+	var login loginClaims
+	_, err := jwt.ParseWithClaims(cookie, &login, func(*jwt.Token) (any, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.clock.Now))
+	if err != nil || login.State == "" || subtle.ConstantTimeCompare([]byte(login.State), []byte(state)) != 1 || code == "" {
+		return nil, ErrInvalidLogin
+	}
+	p, ok := s.providers[login.Provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	tokens, err := p.exchange(ctx, code, login.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	var ext *externalIdentity
+	if p.cfg.Issuer != "" {
+		ext, err = p.verifyIDToken(ctx, tokens.IDToken, login.Nonce)
+	} else {
+		ext, err = p.userInfo(ctx, tokens.AccessToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	subject, err := s.link(ctx, p.name, ext)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens.Issue(ctx, subject)
+}
+
+// externalIdentity is what a provider asserts about the user.
+type externalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// linker maps the identities of external providers, OIDC and LDAP alike,
+// to local accounts.
+type linker struct {
+	creds         repository.CredentialRepository
+	identities    repository.IdentityRepository
+	uow           repository.UnitOfWork
+	createAccount AccountCreator
+	clock         clock.Clock
+}
+
+// link returns the local account of ext, linking it on its first login.
+func (s *linker) link(ctx context.Context, providerName string, ext *externalIdentity) (string, error) {
+	id := providerName + ":" + ext.Subject
+	var subject string
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		identity, err := s.identities.GetByID(ctx, id)
+		if err == nil {
+			subject = identity.Subject
+			return nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to look up identity: %w", err)
+		}
+
+		email := normalizeEmail(ext.Email)
+		// Only a verified email proves the external account belongs to
+		// whoever registered that email here
+		if ext.EmailVerified && email != "" {
+			cred, err := s.creds.GetByID(ctx, email)
+			if err == nil {
+				subject = cred.Subject
+			} else if !errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("failed to look up credential: %w", err)
+			}
+		}
+		if subject == "" {
+			if subject, err = s.createAccount(ctx, email, ext.Name); err != nil {
+				return err
+			}
+		}
+		link := &model.Identity{ID: id, Provider: providerName, Subject: subject, Email: email, CreatedAt: s.clock.Now().UTC().Truncate(time.Second)}
+		if _, err := s.identities.Create(ctx, link); err != nil {
+			return fmt.Errorf("failed to store identity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return subject, nil
+}
+
+// provider is one configured provider with its discovered endpoints and
+// signing keys.
+type provider struct {
+	name   string
+	cfg    OIDCProvider
+	client *http.Client
+	clock  clock.Clock
+
+	mu          sync.Mutex
+	discovered  *discovery
+	keys        map[string]crypto.PublicKey // by kid
+	keysFetched time.Time
+}
+
+// discovery is the part of an OpenID Provider Configuration the login uses.
+type discovery struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+// endpoints returns the provider's configured or discovered endpoints.
+// A failed discovery is retried on the next login.
+func (p *provider) endpoints(ctx context.Context) (*discovery, error) {
+	if p.cfg.Issuer == "" {
+		return &discovery{AuthURL: p.cfg.AuthURL, TokenURL: p.cfg.TokenURL}, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered != nil {
+		return p.discovered, nil
+	}
+	var d discovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", "", &d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != p.cfg.Issuer || d.AuthURL == "" || d.TokenURL == "" || d.JWKSURL == "" {
+		return nil, fmt.Errorf("%w: %s: discovery document does not match issuer", ErrProviderUnavailable, p.name)
+	}
+	if p.cfg.TokenURL != "" { // explicitly configured endpoints win
+		d.TokenURL = p.cfg.TokenURL
+	}
+	if p.cfg.AuthURL != "" {
+		d.AuthURL = p.cfg.AuthURL
+	}
+	p.discovered = &d
+	return p.discovered, nil
+}
+
+// tokenResponse is the token endpoint's reply to a code exchange.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *provider) exchange(ctx context.Context, code, verifier string) (*tokenResponse, error) {
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret.Reveal()},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.name, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json") // GitHub answers form-encoded otherwise
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.name, err)
+	}
+	defer res.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&tokens); err != nil && res.StatusCode < 500 {
+		return nil, fmt.Errorf("%w: %s: invalid token response: %v", ErrProviderUnavailable, p.name, err)
+	}
+	switch {
+	case res.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: %s: token endpoint returned %s", ErrProviderUnavailable, p.name, res.Status)
+	case tokens.Error != "" || res.StatusCode != http.StatusOK:
+		// Usually an expired or replayed code; GitHub reports it with 200
+		return nil, fmt.Errorf("%w: %s: %s %s", ErrInvalidLogin, p.name, tokens.Error, tokens.ErrorDescription)
+	case tokens.AccessToken == "" || (p.cfg.Issuer != "" && tokens.IDToken == ""):
+		return nil, fmt.Errorf("%w: %s: token response is missing tokens", ErrProviderUnavailable, p.name)
+	}
+	return &tokens, nil
+}
+
+// idTokenClaims are the ID token claims the login uses.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // some providers send "true"
+	Name          string `json:"name"`
+}
+
+// verifyIDToken checks the ID token's signature against the provider's
+// published keys, its issuer, audience, expiry and nonce.
+func (p *provider) verifyIDToken(ctx context.Context, idToken, nonce string) (*externalIdentity, error) {
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims idTokenClaims
+	var keyErr error
+	_, err = jwt.ParseWithClaims(idToken, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := p.key(ctx, endpoints.JWKSURL, kid)
+		keyErr = err
+		return key, err
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(endpoints.Issuer), jwt.WithAudience(p.cfg.ClientID), jwt.WithExpirationRequired(), jwt.WithTimeFunc(p.clock.Now))
+	if errors.Is(keyErr, ErrProviderUnavailable) {
+		return nil, keyErr
+	}
+	if err != nil || claims.Subject == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrInvalidLogin
+	}
+	return &externalIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+// key returns the signing key kid, refetching the key set when kid is not
+// cached and the last fetch is older than jwksRefreshInterval.
+func (p *provider) key(ctx context.Context, jwksURL, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if p.clock.Now().Sub(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURL, "", &set); err != nil {
+		return nil, err
+	}
+	p.keys = map[string]crypto.PublicKey{}
+	p.keysFetched = p.clock.Now()
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil && (k.Use == "" || k.Use == "sig") {
+			p.keys[k.Kid] = key
+		}
+	}
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// jwk is one RSA or EC key of a JSON Web Key Set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	num := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := num(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := num(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := num(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := num(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// userInfo identifies the user of a plain OAuth2 provider from its user
+// info endpoint and, when configured, its list of verified emails.
+func (p *provider) userInfo(ctx context.Context, accessToken string) (*externalIdentity, error) {
+	var user struct {
+		ID    json.Number `json:"id"`  // GitHub
+		Sub   string      `json:"sub"` // OIDC-style user info
+		Login string      `json:"login"`
+		Name  string      `json:"name"`
+		Email string      `json:"email"`
+	}
+	if err := p.getJSON(ctx, p.cfg.UserInfoURL, accessToken, &user); err != nil {
+		return nil, err
+	}
+	ext := &externalIdentity{Subject: user.Sub, Email: user.Email, Name: user.Name}
+	if ext.Subject == "" {
+		ext.Subject = user.ID.String()
+	}
+	if ext.Subject == "" {
+		return nil, fmt.Errorf("%w: %s: user info has no id", ErrProviderUnavailable, p.name)
+	}
+	if ext.Name == "" {
+		ext.Name = user.Login
+	}
+	if p.cfg.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		// Without the email scope the list is unavailable; fall back to the
+		// unverified profile email
+		if err := p.getJSON(ctx, p.cfg.EmailsURL, accessToken, &emails); err == nil {
+			for _, e := range emails {
+				if e.Primary && e.Verified {
+					ext.Email, ext.EmailVerified = e.Email, true
+				}
+			}
+		}
+	}
+	return ext, nil
+}
+
+// getJSON decodes the JSON answer of a GET to rawURL, authorized with
+// bearer when it is set.
+func (p *provider) getJSON(ctx context.Context, rawURL, bearer string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.name, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s: GET %s returned %s", ErrProviderUnavailable, p.name, rawURL, res.Status)
+	}
+	dec := json.NewDecoder(io.LimitReader(res.Body, 1<<20))
+	dec.UseNumber()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %s: invalid response from %s: %v", ErrProviderUnavailable, p.name, rawURL, err)
+	}
+	return nil
+}
+
+// randomString returns n random bytes, base64url-encoded.
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("auth: failed to generate random value: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// fakeProvider is an OpenID provider that authorizes every login as user.
+type fakeProvider struct {
+	*httptest.Server
+	key  *rsa.PrivateKey
+	user map[string]any // ID token or user info claims
+
+	mu    sync.Mutex
+	codes map[string]url.Values // code -> authorization request
+}
+
+func newFakeProvider(t *testing.T, user map[string]any) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key, user: user, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		auth, ok := p.codes[r.FormValue("code")]
+		delete(p.codes, r.FormValue("code"))
+		p.mu.Unlock()
+		challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || auth.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{"iss": p.URL, "aud": auth.Get("client_id"), "exp": time.Now().Add(time.Minute).Unix(), "nonce": auth.Get("nonce")}
+		for k, v := range p.user {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "provider-access", "id_token": idToken})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer provider-access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(p.user)
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize plays the user's visit to the login URL and returns the state
+// and code of the redirect back.
+func (p *fakeProvider) authorize(t *testing.T, loginURL string) (state, code string) {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code = newID()
+	p.mu.Lock()
+	p.codes[code] = u.Query()
+	p.mu.Unlock()
+	return u.Query().Get("state"), code
+}
+
+type oidcFixture struct {
+	tokens     AuthService
+	oidc       OIDCService
+	identities repository.IdentityRepository
+}
+
+func newOIDCFixture(t *testing.T, providers map[string]OIDCProvider) *oidcFixture {
+	db, dialect := migratedDB(t)
+	creds := repository.NewSQLRepository[model.Credential](db, dialect, "credentials", "credential")
+	identities := repository.NewSQLRepository[model.Identity](db, dialect, "identities", "identity")
+	uow := repository.NewSQLUnitOfWork(db)
+	tokens := NewAuthService(creds, uow, NewMemoryRevocations(nil), nil, testOptions)
+	return &oidcFixture{
+		tokens:     tokens,
+		oidc:       NewOIDCService(tokens, creds, identities, uow, nil, OIDCOptions{Secret: testOptions.Secret, Providers: providers}),
+		identities: identities,
+	}
+}
+
+// login runs a whole external login and returns the local account.
+func (f *oidcFixture) login(t *testing.T, provider string, p *fakeProvider) string {
+	t.Helper()
+	ctx := context.Background()
+	login, err := f.oidc.Begin(ctx, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := p.authorize(t, login.URL)
+	token, err := f.oidc.Complete(ctx, login.Cookie.Value, state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := f.tokens.Verify(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.Subject
+}
+
+func TestOIDCLoginLinksExternalIdentity(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeProvider(t, map[string]any{"sub": "ext-1", "email": "Ada@Example.com", "email_verified": true, "name": "Ada"})
+	f := newOIDCFixture(t, map[string]OIDCProvider{
+		"corp": {Issuer: idp.URL, ClientID: "client", RedirectURL: "https://api.example.com/auth/oidc/callback", Scopes: []string{"openid", "email"}},
+	})
+
+	// A password account with the verified email is reused
+	account, err := f.tokens.Register(ctx, "ada@example.com", "correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.login(t, "corp", idp); got != account.ID {
+		t.Fatalf("first login subject = %q, want the registered account %q", got, account.ID)
+	}
+	identity, err := f.identities.GetByID(ctx, "corp:ext-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != account.ID || identity.Email != "ada@example.com" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if got := f.login(t, "corp", idp); got != account.ID {
+		t.Fatalf("second login subject = %q, want %q", got, account.ID)
+	}
+
+	// An unverified email never takes over an account
+	idp.user = map[string]any{"sub": "ext-2", "email": "ada@example.com", "email_verified": false}
+	if got := f.login(t, "corp", idp); got == account.ID || got == "" {
+		t.Fatalf("unverified login subject = %q, want a new account", got)
+	}
+}
+
+func TestOIDCLoginRejectsForgedOrReplayedCallbacks(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeProvider(t, map[string]any{"sub": "ext-1"})
+	f := newOIDCFixture(t, map[string]OIDCProvider{
+		"corp": {Issuer: idp.URL, ClientID: "client", RedirectURL: "http://localhost/auth/oidc/callback"},
+	})
+
+	if _, err := f.oidc.Begin(ctx, "other"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Begin(other) error = %v, want ErrUnknownProvider", err)
+	}
+	login, err := f.oidc.Begin(ctx, "corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := login.Cookie; !c.HttpOnly || c.Secure || c.Path != "/auth/oidc/callback" {
+		t.Errorf("cookie = %+v, want HttpOnly on the callback path, not Secure over http", c)
+	}
+	state, code := idp.authorize(t, login.URL)
+
+	if _, err := f.oidc.Complete(ctx, login.Cookie.Value, "forged", code); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("wrong state error = %v, want ErrInvalidLogin", err)
+	}
+	other, _ := f.oidc.Begin(ctx, "corp")
+	if _, err := f.oidc.Complete(ctx, other.Cookie.Value, state, code); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("another browser's cookie error = %v, want ErrInvalidLogin", err)
+	}
+	if _, err := f.oidc.Complete(ctx, login.Cookie.Value, state, code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.oidc.Complete(ctx, login.Cookie.Value, state, code); !errors.Is(err, ErrInvalidLogin) {
+		t.Errorf("replayed code error = %v, want ErrInvalidLogin", err)
+	}
+}
+
+func TestOAuth2LoginUsesUserInfo(t *testing.T) {
+	idp := newFakeProvider(t, map[string]any{"id": 42, "login": "octocat", "email": "octo@example.com"})
+	f := newOIDCFixture(t, map[string]OIDCProvider{
+		"github": OIDCProvider{
+			ClientID:    "client",
+			RedirectURL: "http://localhost/auth/oidc/callback",
+			AuthURL:     idp.URL + "/authorize",
+			TokenURL:    idp.URL + "/token",
+			UserInfoURL: idp.URL + "/userinfo",
+			EmailsURL:   idp.URL + "/missing",
+		}.WithDefaults("github"),
+	})
+
+	subject := f.login(t, "github", idp)
+	identity, err := f.identities.GetByID(context.Background(), "github:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != subject || identity.Email != "octo@example.com" {
+		t.Errorf("identity = %+v, want github:42 linked to %s", identity, subject)
+	}
+}
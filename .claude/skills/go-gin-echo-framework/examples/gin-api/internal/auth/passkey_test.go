@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+	"github.com/your-username/gin-api/internal/webauthn"
+)
+
+var testPasskeys = PasskeyOptions{RPID: "example.com", RPName: "Example", Origins: []string{"https://app.example.com"}, Timeout: time.Minute}
+
+// newPasskeys returns a passkey service of tokens whose accounts are
+// registered in the returned auth service.
+func newPasskeys(t *testing.T) (PasskeyService, AuthService) {
+	db, dialect := migratedDB(t)
+	creds := repository.NewSQLRepository[model.Credential](db, dialect, "credentials", "credential")
+	passkeys := repository.NewSQLRepository[model.Passkey](db, dialect, "passkeys", "passkey")
+	uow := repository.NewSQLUnitOfWork(db)
+	revocations := NewMemoryRevocations(nil)
+	tokens := NewAuthService(creds, uow, revocations, nil, testOptions)
+	return NewPasskeyService(tokens, creds, passkeys, uow, revocations, testOptions.Secret, testPasskeys, nil), tokens
+}
+
+// addPasskey registers a passkey of account subject with a.
+func addPasskey(t *testing.T, svc PasskeyService, a *webauthn.Authenticator, subject string) *PasskeyInfo {
+	t.Helper()
+	ctx := context.Background()
+	begun, err := svc.BeginRegistration(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := a.Create(begun.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := svc.FinishRegistration(ctx, subject, begun.Session, "laptop", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens := newPasskeys(t)
+	account, err := tokens.Register(ctx, "ada@example.com", "correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := webauthn.NewAuthenticator("https://app.example.com")
+	added := addPasskey(t, svc, a, account.ID)
+
+	for _, email := range []string{"Ada@example.com", ""} { // by email, and discoverable
+		begun, err := svc.BeginLogin(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertion, err := a.Get(begun.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := svc.FinishLogin(ctx, begun.Session, assertion)
+		if err != nil {
+			t.Fatalf("%q: %v", email, err)
+		}
+		if claims, err := tokens.Verify(ctx, token.AccessToken); err != nil || claims.Subject != account.ID {
+			t.Errorf("%q: claims = %+v, %v; want subject %s", email, claims, err, account.ID)
+		}
+		// Sessions are good for one login
+		if _, err := svc.FinishLogin(ctx, begun.Session, assertion); !errors.Is(err, ErrInvalidPasskey) {
+			t.Errorf("%q: reused session: err = %v, want ErrInvalidPasskey", email, err)
+		}
+	}
+
+	list, err := svc.List(ctx, account.ID)
+	if err != nil || len(list) != 1 || list[0].ID != added.ID || list[0].LastUsedAt == nil {
+		t.Fatalf("List = %+v, %v; want the passkey, used", list, err)
+	}
+	if err := svc.Delete(ctx, "someone-else", added.ID); !errors.Is(err, ErrPasskeyNotFound) {
+		t.Errorf("deleting another account's passkey: err = %v, want ErrPasskeyNotFound", err)
+	}
+	if err := svc.Delete(ctx, account.ID, added.ID); err != nil {
+		t.Fatal(err)
+	}
+	begun, err := svc.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertion, err := a.Get(begun.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FinishLogin(ctx, begun.Session, assertion); !errors.Is(err, ErrInvalidPasskey) {
+		t.Errorf("deleted passkey: err = %v, want ErrInvalidPasskey", err)
+	}
+}
+
+func TestPasskeyLoginIsRefused(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens := newPasskeys(t)
+	authenticators := map[string]*webauthn.Authenticator{}
+	for _, email := range []string{"ada@example.com", "grace@example.com"} {
+		account, err := tokens.Register(ctx, email, "correct horse", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		authenticators[email] = webauthn.NewAuthenticator("https://app.example.com")
+		addPasskey(t, svc, authenticators[email], account.ID)
+	}
+
+	for name, tc := range map[string]struct {
+		email  string // the login is begun for
+		holder string // answers with a passkey of this account
+		origin string
+		finish context.Context
+	}{
+		"other account": {email: "ada@example.com", holder: "grace@example.com"},
+		"unknown email": {email: "nobody@example.com", holder: "ada@example.com"},
+		"other origin":  {holder: "ada@example.com", origin: "https://app.example.com.evil.test"},
+		"other tenant":  {holder: "ada@example.com", finish: tenant.With(ctx, "acme")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			begun, err := svc.BeginLogin(ctx, tc.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A browser would only offer the allowed credentials
+			opts := begun.PublicKey
+			opts.AllowCredentials = nil
+			a := authenticators[tc.holder]
+			if tc.origin != "" {
+				a.Origin = tc.origin
+				defer func() { a.Origin = "https://app.example.com" }()
+			}
+			assertion, err := a.Get(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			finish := ctx
+			if tc.finish != nil {
+				finish = tc.finish
+			}
+			if _, err := svc.FinishLogin(finish, begun.Session, assertion); !errors.Is(err, ErrInvalidPasskey) {
+				t.Errorf("err = %v, want ErrInvalidPasskey", err)
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistrationIsBoundToItsAccount(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newPasskeys(t)
+	begun, err := svc.BeginRegistration(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := webauthn.NewAuthenticator("https://app.example.com").Create(begun.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FinishRegistration(ctx, "user-2", begun.Session, "", r); !errors.Is(err, ErrInvalidPasskey) {
+		t.Errorf("other account: err = %v, want ErrInvalidPasskey", err)
+	}
+}
+
+func TestPasskeyOptionsValidate(t *testing.T) {
+	if err := testPasskeys.Validate(); err != nil {
+		t.Errorf("valid options: %v", err)
+	}
+	for name, opts := range map[string]PasskeyOptions{
+		"rp_id with scheme":  {RPID: "https://example.com", Origins: testPasskeys.Origins, Timeout: time.Minute},
+		"no origins":         {RPID: "example.com", Timeout: time.Minute},
+		"origin of another":  {RPID: "example.com", Origins: []string{"https://example.org"}, Timeout: time.Minute},
+		"lookalike origin":   {RPID: "example.com", Origins: []string{"https://badexample.com"}, Timeout: time.Minute},
+		"origin with a path": {RPID: "example.com", Origins: []string{"https://example.com/login"}, Timeout: time.Minute},
+		"no timeout":         {RPID: "example.com", Origins: testPasskeys.Origins},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%s: Validate accepted %+v", name, opts)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"slices"
+	"sort"
+)
+
+// RoleAdmin is the role of the admin routes (/admin, /debug/pprof, /export
+// and /import).
+const RoleAdmin = "admin"
+
+// Roles grants roles to accounts: the IDs of the accounts holding each
+// role, by role name.
+type Roles map[string][]string
+
+// Has reports whether the account subject holds role.
+func (r Roles) Has(subject, role string) bool {
+	return slices.Contains(r[role], subject)
+}
+
+// Of returns the roles the account subject holds, sorted.
+func (r Roles) Of(subject string) []string {
+	held := []string{}
+	for role, subjects := range r {
+		if slices.Contains(subjects, subject) {
+			held = append(held, role)
+		}
+	}
+	sort.Strings(held)
+	return held
+}
@@ -1,35 +1,458 @@
+// Package auth registers password accounts, verifies logins with lockout
+// after repeated failures, and issues HS256 JWTs: short-lived access tokens
+// and refresh tokens that are rotated on every use. A login starts a token
+// family; logout revokes the whole family, and so does presenting an already
+// rotated refresh token, which means it leaked. Users may also log in with
+// external OAuth2/OIDC providers (see OIDCService), passwords may be checked
+// against a directory rather than the registered ones (see IdentityProvider
+// and NewLDAPProvider), and machine clients use API keys (see
+// APIKeyService); Identify accepts either kind of credential. Passwords may
+// be backed by the codes of an authenticator app (see MFAService), or
+// replaced by passkeys (see PasskeyService). Guests start sessions without
+// logging in, and accounts claim what they created (see GuestService).
 package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrInvalidPassword    = errors.New("password must be 8 to 72 bytes long")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrAccountDisabled    = errors.New("account is deactivated")
+	ErrRegistrationClosed = errors.New("accounts are registered in the directory, not here")
+	ErrInvalidGuest       = errors.New("invalid or expired guest token")
 )
 
+// LockedError is returned by Authenticate for an account that is locked out
+// after too many consecutive failed logins.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "account is locked after too many failed logins"
+}
+
+// Account is a registered login.
+type Account struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// Token is the result of a successful login or refresh.
+type Token struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"` // always "Bearer"
+	ExpiresIn        int    `json:"expires_in"` // seconds
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // seconds
+}
+
+// Claims are the verified contents of an access token.
+type Claims struct {
+	Subject   string
+	Family    string   // shared by every token descending from one login
+	Tenant    string   // the tenant logged in to; "" without tenancy
+	Roles     []string // granted by the identity provider at login, see Principal.Roles
+	Guest     bool     // the session of a guest, see AuthService.StartGuest
+	ExpiresAt time.Time
+}
+
+// tokenClaims is the JWT payload of both token kinds.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Family string   `json:"fam"`
+	Use    string   `json:"use"`              // "access", "refresh" or "mfa"
+	Tenant string   `json:"tenant,omitempty"` // see Claims.Tenant
+	Roles  []string `json:"roles,omitempty"`  // see Claims.Roles
+	Guest  bool     `json:"guest,omitempty"`  // see Claims.Guest
+}
+
+// session returns the Claims of c, which tokens issued from it carry on.
+func (c *tokenClaims) session() Claims {
+	return Claims{Subject: c.Subject, Family: c.Family, Tenant: c.Tenant, Roles: c.Roles, Guest: c.Guest, ExpiresAt: c.ExpiresAt.Time}
+}
+
+// ActiveCheck reports whether the account subject may start or refresh a
+// session, e.g. one its identity provider has not deactivated (see package
+// scim).
+type ActiveCheck func(ctx context.Context, subject string) (bool, error)
+
+// AccountCreator creates the account a registration belongs to, in the
+// registration's transaction, and returns its ID.
+type AccountCreator func(ctx context.Context, email, name string) (string, error)
+
+type Options struct {
+	Secret          []byte          // HMAC key; empty signs with a random per-process key
+	Issuer          string          // iss claim, checked on verification
+	TokenTTL        time.Duration   // lifetime of access tokens
+	RefreshTTL      time.Duration   // lifetime of each refresh token
+	MaxFailedLogins int             // consecutive failures that lock an account
+	LockoutDuration time.Duration   // how long a locked account rejects logins
+	Clock           clock.Clock     // issues and checks expiries; nil is the system clock
+	IDs             idgen.Generator // token, family and guest IDs, and account IDs when no AccountCreator is given; nil is idgen.Default
+	Active          ActiveCheck     // consulted on logins, refreshes and Issue; nil allows every account
+
+	// Locks serialize the counting of each account's failed logins, in the
+	// transactions of uow, so that concurrent wrong passwords cannot outrun
+	// the lockout; nil suits in-memory credentials only.
+	Locks repository.Locks
+
+	// Provider checks the passwords of logins; nil is the credentials of
+	// Register, locked out after MaxFailedLogins. Registering is refused
+	// with any other provider, whose accounts are managed where it keeps them.
+	Provider IdentityProvider
+
+	// Factors are the authenticator apps of MFAService; the logins of
+	// accounts with a confirmed one complete with CompleteMFA. nil asks for
+	// passwords alone.
+	Factors repository.TOTPRepository
+}
+
 type AuthService interface {
-	Authenticate(ctx context.Context, username, password string) (string, error)
-	Authorize(ctx context.Context, token, requiredRole string) (bool, error)
+	Register(ctx context.Context, email, password, name string) (*Account, error)
+	// Authenticate returns *MFARequiredError for the right password of an
+	// account with an authenticator app.
+	Authenticate(ctx context.Context, login, password string) (*Token, error)
+	// CompleteMFA finishes the login of an MFARequiredError with a code of
+	// the account's app. mfaToken is good for one attempt, and a wrong code
+	// returns ErrInvalidCode: the user starts over with the password.
+	CompleteMFA(ctx context.Context, mfaToken, code string) (*Token, error)
+	Refresh(ctx context.Context, refreshToken string) (*Token, error)
+	Logout(ctx context.Context, refreshToken string) error
+	Verify(ctx context.Context, token string) (*Claims, error)
+	// Issue starts a session for an account that authenticated elsewhere,
+	// e.g. with an external identity provider, in the tenant on ctx.
+	Issue(ctx context.Context, subject string) (*Token, error)
+	// StartGuest starts the session of a new guest, an account without a
+	// login, in the tenant on ctx. Its tokens carry Claims.Guest and refresh
+	// like any other; what the guest creates is taken over by the account
+	// that claims it (see GuestService).
+	StartGuest(ctx context.Context) (*Token, error)
+}
+
+type authService struct {
+	creds         repository.CredentialRepository
+	uow           repository.UnitOfWork
+	revocations   Revocations
+	createAccount AccountCreator
+	local         bool // opts.Provider checks the credentials of Register
+	opts          Options
+}
+
+// NewAuthService stores credentials in creds and revoked tokens in
+// revocations. createAccount may be nil, in which case accounts get an ID
+// from opts.IDs and nothing else.
+func NewAuthService(creds repository.CredentialRepository, uow repository.UnitOfWork, revocations Revocations, createAccount AccountCreator, opts Options) AuthService {
+	if len(opts.Secret) == 0 {
+		opts.Secret = make([]byte, 32)
+		if _, err := rand.Read(opts.Secret); err != nil {
+			panic(fmt.Sprintf("auth: failed to generate signing key: %v", err))
+		}
+		log.Printf("WARNING: auth.jwt_secret is not set; tokens are signed with a random key and do not survive a restart")
+	}
+	opts.IDs = idgen.OrDefault(opts.IDs)
+	if createAccount == nil {
+		createAccount = generatedAccounts(opts.IDs)
+	}
+	opts.Clock = clock.OrSystem(opts.Clock)
+	local := opts.Provider == nil
+	if local {
+		if opts.Locks == nil {
+			opts.Locks = repository.NewMemoryLocks()
+		}
+		opts.Provider = &credentialProvider{creds: creds, uow: uow, locks: opts.Locks, clock: opts.Clock, maxFailed: opts.MaxFailedLogins, lockout: opts.LockoutDuration}
+	}
+	return &authService{local: local, creds: creds, uow: uow, revocations: revocations, createAccount: createAccount, opts: opts}
+}
+
+func (s *authService) Register(ctx context.Context, email, password, name string) (*Account, error) {
+	if !s.local {
+		return nil, ErrRegistrationClosed
+	}
+	email = normalizeEmail(email)
+	if len(password) < 8 || len(password) > 72 { // 72 bytes is bcrypt's input limit
+		return nil, ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var account *Account
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.creds.GetByID(ctx, email); err == nil {
+			return ErrEmailTaken
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to look up credential: %w", err)
+		}
+		id, err := s.createAccount(ctx, email, name)
+		if err != nil {
+			return err
+		}
+		cred := &model.Credential{ID: email, Subject: id, PasswordHash: string(hash), TenantID: tenant.From(ctx)}
+		if _, err := s.creds.Create(ctx, cred); err != nil {
+			return fmt.Errorf("failed to store credential: %w", err)
+		}
+		account = &Account{ID: id, Email: email}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *authService) Authenticate(ctx context.Context, login, password string) (*Token, error) {
+	principal, err := s.opts.Provider.Authenticate(ctx, login, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.active(ctx, principal.Subject); err != nil {
+		return nil, err
+	}
+	now := s.opts.Clock.Now()
+	session := Claims{Subject: principal.Subject, Family: s.opts.IDs.NewID(), Tenant: principal.Tenant, Roles: principal.Roles}
+	if s.opts.Factors != nil {
+		factor, err := s.opts.Factors.GetByID(ctx, principal.Subject)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to look up authenticator app: %w", err)
+		}
+		if err == nil && factor.Confirmed {
+			// The login so far, carried to CompleteMFA
+			token, err := s.sign(session, "mfa", now, mfaTokenTTL)
+			if err != nil {
+				return nil, err
+			}
+			return nil, &MFARequiredError{Token: token, ExpiresIn: int(mfaTokenTTL.Seconds())}
+		}
+	}
+	return s.issue(session, now)
+}
+
+func (s *authService) CompleteMFA(ctx context.Context, mfaToken, code string) (*Token, error) {
+	claims, err := s.parse(mfaToken, "mfa")
+	if err != nil || s.opts.Factors == nil {
+		return nil, ErrInvalidToken
+	}
+	// One code per password, or the password would let codes be guessed
+	// until one fits
+	if first, err := s.revocations.Revoke(ctx, "token:"+claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	} else if !first {
+		return nil, ErrInvalidToken
+	}
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		factor, err := s.opts.Factors.GetByID(ctx, claims.Subject)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidToken // disabled since the password was checked
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up authenticator app: %w", err)
+		}
+		if !acceptCode(factor, code, s.opts.Clock.Now()) {
+			return ErrInvalidCode
+		}
+		if _, err := s.opts.Factors.Update(ctx, factor); err != nil {
+			return fmt.Errorf("failed to store authenticator app: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.active(ctx, claims.Subject); err != nil {
+		return nil, err
+	}
+	return s.issue(claims.session(), s.opts.Clock.Now())
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	claims, err := s.parse(refreshToken, "refresh")
+	if err != nil {
+		return nil, err
+	}
+	// A family ends at a logout, an account when an admin deletes it (see
+	// AccountService.Delete)
+	for _, id := range []string{"family:" + claims.Family, "subject:" + claims.Subject} {
+		if revoked, err := s.revocations.IsRevoked(ctx, id); err != nil {
+			return nil, err
+		} else if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+	// Each refresh token is good for one rotation; a second use means it was
+	// copied, so end the session for whoever holds the current one too
+	first, err := s.revocations.Revoke(ctx, "token:"+claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		log.Printf("WARNING: auth: refresh token reused for %s; revoking its token family", claims.Subject)
+		if _, err := s.revocations.Revoke(ctx, "family:"+claims.Family, s.opts.Clock.Now().Add(s.opts.RefreshTTL)); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+	// A deactivated account's sessions end once their access tokens expire
+	if err := s.active(ctx, claims.Subject); errors.Is(err, ErrAccountDisabled) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	// Roles from the identity provider hold until the next login
+	return s.issue(claims.session(), s.opts.Clock.Now())
+}
+
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.parse(refreshToken, "refresh")
+	if err != nil {
+		return err
+	}
+	// Every token of the family expires within RefreshTTL from now
+	_, err = s.revocations.Revoke(ctx, "family:"+claims.Family, s.opts.Clock.Now().Add(s.opts.RefreshTTL))
+	return err
+}
+
+func (s *authService) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims, err := s.parse(token, "access")
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.revocations.IsRevoked(ctx, "family:"+claims.Family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+	session := claims.session()
+	return &session, nil
 }
 
-type authService struct{
-	// Dependencies like user repository, JWT secret, etc.
+func (s *authService) Issue(ctx context.Context, subject string) (*Token, error) {
+	if err := s.active(ctx, subject); err != nil {
+		return nil, err
+	}
+	return s.issue(Claims{Subject: subject, Family: s.opts.IDs.NewID(), Tenant: tenant.From(ctx)}, s.opts.Clock.Now())
 }
 
-func NewAuthService() AuthService {
-	return &authService{}
+func (s *authService) StartGuest(ctx context.Context) (*Token, error) {
+	return s.issue(Claims{Subject: s.opts.IDs.NewID(), Family: s.opts.IDs.NewID(), Tenant: tenant.From(ctx), Guest: true}, s.opts.Clock.Now())
 }
 
-func (s *authService) Authenticate(ctx context.Context, username, password string) (string, error) {
-	// Simulate authentication logic
-	if username == "admin" && password == "password" {
-		return "my-secret-token", nil // Return a dummy token
+// active returns ErrAccountDisabled unless opts.Active allows subject.
+func (s *authService) active(ctx context.Context, subject string) error {
+	if s.opts.Active == nil {
+		return nil
+	}
+	ok, err := s.opts.Active(ctx, subject)
+	if err != nil {
+		return fmt.Errorf("failed to check account: %w", err)
 	}
-	return "", errors.New("invalid credentials")
+	if !ok {
+		return ErrAccountDisabled
+	}
+	return nil
 }
 
-func (s *authService) Authorize(ctx context.Context, token, requiredRole string) (bool, error) {
-	// Simulate authorization logic
-	if token == "my-secret-token" && requiredRole == "admin" {
-		return true, nil
+// issue signs a new access and refresh token pair of session, in its
+// family; its ExpiresAt is ignored.
+func (s *authService) issue(session Claims, now time.Time) (*Token, error) {
+	access, err := s.sign(session, "access", now, s.opts.TokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(session, "refresh", now, s.opts.RefreshTTL)
+	if err != nil {
+		return nil, err
 	}
-	return false, errors.New("unauthorized")
+	return &Token{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.opts.TokenTTL.Seconds()),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int(s.opts.RefreshTTL.Seconds()),
+	}, nil
 }
+
+func (s *authService) sign(session Claims, use string, now time.Time, ttl time.Duration) (string, error) {
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        s.opts.IDs.NewID(),
+			Subject:   session.Subject,
+			Issuer:    s.opts.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Family: session.Family,
+		Use:    use,
+		Tenant: session.Tenant,
+		Roles:  session.Roles,
+		Guest:  session.Guest,
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.opts.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// parse verifies token's signature, issuer and expiry and that it is meant
+// for use. Every failure is ErrInvalidToken.
+func (s *authService) parse(token, use string) (*tokenClaims, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return s.opts.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(s.opts.Issuer), jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.opts.Clock.Now))
+	if err != nil || claims.Use != use || claims.Subject == "" || claims.ID == "" || claims.Family == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// newID returns a random 128-bit hex ID for API keys and login state.
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("auth: failed to generate token ID: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// generatedAccounts is the AccountCreator used when none is given: it
+// takes account IDs from ids (nil is idgen.Default).
+func generatedAccounts(ids idgen.Generator) AccountCreator {
+	ids = idgen.OrDefault(ids)
+	return func(context.Context, string, string) (string, error) {
+		return ids.NewID(), nil
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return hash
+})
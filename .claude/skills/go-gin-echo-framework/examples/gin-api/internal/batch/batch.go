@@ -0,0 +1,189 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// sharedHeaders are copied from the batch request onto every sub-request so
+// they run with the caller's identity (auth, API key, tracing).
+var sharedHeaders = []string{"Authorization", "X-API-Key", "Cookie", "X-Request-ID", "Accept-Language"}
+
+type SubRequest struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type SubResponse struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type Options struct {
+	MaxRequests int // sub-requests accepted per batch
+	Concurrency int // sub-requests executed at once
+	Path        string
+}
+
+// Handler executes a JSON array of sub-requests against next and returns
+// their results in the same order. Sub-requests go through next's full
+// middleware chain, so auth and rate limiting apply to each one. They run
+// concurrently, so a batch must not rely on one sub-request seeing another's effects.
+type Handler struct {
+	next http.Handler
+	opts Options
+}
+
+func NewHandler(next http.Handler, opts Options) *Handler {
+	if opts.MaxRequests <= 0 {
+		opts.MaxRequests = 20
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.Path == "" {
+		opts.Path = "/batch"
+	}
+	return &Handler{next: next, opts: opts}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reqs []SubRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		writeError(w, http.StatusBadRequest, "request body must be a JSON array of sub-requests")
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > h.opts.MaxRequests {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("batch must contain between 1 and %d sub-requests", h.opts.MaxRequests))
+		return
+	}
+
+	results := make([]SubResponse, len(reqs))
+	sem := make(chan struct{}, h.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, sub := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = h.execute(r, sub)
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+func (h *Handler) execute(parent *http.Request, sub SubRequest) SubResponse {
+	res := SubResponse{ID: sub.ID}
+	method := strings.ToUpper(sub.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(sub.Path, "/") || strings.HasPrefix(sub.Path, "//") {
+		return errorResponse(res, http.StatusBadRequest, "path must be an absolute path on this server")
+	}
+	if strings.HasPrefix(sub.Path, h.opts.Path) {
+		return errorResponse(res, http.StatusBadRequest, "nested batch requests are not allowed")
+	}
+
+	req, err := http.NewRequestWithContext(parent.Context(), method, sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return errorResponse(res, http.StatusBadRequest, "invalid sub-request: "+err.Error())
+	}
+	req.RemoteAddr = parent.RemoteAddr
+	req.Host = parent.Host
+	for _, name := range sharedHeaders {
+		if v := parent.Header.Get(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(sub.Body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := newRecorder()
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				rec.status = http.StatusInternalServerError
+				rec.body.Reset()
+			}
+		}()
+		h.next.ServeHTTP(rec, req)
+	}()
+
+	res.Status = rec.status
+	res.Headers = map[string]string{}
+	for k := range rec.header {
+		res.Headers[k] = rec.header.Get(k)
+	}
+	res.Body = bodyJSON(rec)
+	return res
+}
+
+// bodyJSON embeds JSON bodies as-is and any other body as a JSON string.
+func bodyJSON(rec *recorder) json.RawMessage {
+	if rec.body.Len() == 0 {
+		return nil
+	}
+	if strings.Contains(rec.header.Get("Content-Type"), "json") && json.Valid(rec.body.Bytes()) {
+		return rec.body.Bytes()
+	}
+	s, _ := json.Marshal(rec.body.String())
+	return s
+}
+
+func errorResponse(res SubResponse, status int, msg string) SubResponse {
+	res.Status = status
+	res.Body, _ = json.Marshal(map[string]string{"error": msg})
+	return res
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// recorder is a minimal in-memory http.ResponseWriter for sub-requests.
+type recorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
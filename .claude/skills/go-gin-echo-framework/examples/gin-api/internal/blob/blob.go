@@ -0,0 +1,114 @@
+// Package blob stores files, such as the images uploaded to an item, by
+// key: a slash-separated path like users/u-1/avatar.png.
+//
+// A Store keeps blobs on the local disk or in an S3 bucket (or any
+// S3-compatible service, e.g. MinIO), and signs URLs that download a blob
+// without other credentials until they expire: S3 presigned URLs, or for
+// the local disk URLs of the API itself that Local.Verify checks.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// Backend names in Options.Backend.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// MaxURLTTL is the longest lifetime of a signed URL, that of S3.
+const MaxURLTTL = 7 * 24 * time.Hour
+
+// Options select the backend and the uploads it accepts.
+type Options struct {
+	Backend         string        `yaml:"backend"`           // local or s3
+	Dir             string        `yaml:"dir"`               // local: directory of the blobs
+	SigningKey      secret.Secret `yaml:"signing_key"`       // local: HMAC key of signed URLs; empty is a random key, so URLs end with the process
+	Bucket          string        `yaml:"bucket"`            // s3
+	Region          string        `yaml:"region"`            // s3, e.g. us-east-1
+	Endpoint        string        `yaml:"endpoint"`          // s3: URL of an S3-compatible service, addressed path-style, e.g. http://localhost:9000; empty is AWS
+	AccessKeyID     string        `yaml:"access_key_id"`     // s3
+	SecretAccessKey secret.Secret `yaml:"secret_access_key"` // s3
+	MaxSize         int64         `yaml:"max_size"`          // bytes of one upload
+	Types           []string      `yaml:"types"`             // media types uploads may have, sniffed from their content
+	URLTTL          time.Duration `yaml:"url_ttl"`           // lifetime of signed URLs
+}
+
+// Validate checks the backend and its settings, and the upload limits.
+func (o Options) Validate() error {
+	switch o.Backend {
+	case BackendLocal:
+		if o.Dir == "" {
+			return errors.New("dir is required by the local backend")
+		}
+	case BackendS3:
+		if o.Bucket == "" || o.Region == "" {
+			return errors.New("bucket and region are required by the s3 backend")
+		}
+		if o.AccessKeyID == "" || o.SecretAccessKey == "" {
+			return errors.New("access_key_id and secret_access_key are required by the s3 backend")
+		}
+	default:
+		return fmt.Errorf("unknown backend %q (want local or s3)", o.Backend)
+	}
+	if o.MaxSize <= 0 {
+		return errors.New("max_size must be positive")
+	}
+	if len(o.Types) == 0 {
+		return errors.New("types must not be empty")
+	}
+	for _, t := range o.Types {
+		if _, _, err := mime.ParseMediaType(t); err != nil || !strings.Contains(t, "/") {
+			return fmt.Errorf("types: %q is not a media type", t)
+		}
+	}
+	if o.URLTTL <= 0 || o.URLTTL > MaxURLTTL {
+		return fmt.Errorf("url_ttl must be positive and at most %v", MaxURLTTL)
+	}
+	return nil
+}
+
+// ErrNotFound is returned by Get when there is no blob under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// Info describes a stored blob.
+type Info struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Store keeps blobs by key.
+type Store interface {
+	// Put stores the size bytes of r under key, replacing any blob there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob under key, ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete removes the blob under key; a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignURL returns a URL that downloads the blob under key until expires.
+	SignURL(key string, expires time.Time) (string, error)
+}
+
+// CheckKey rejects keys that are empty, absolute or have empty, . or ..
+// segments, which could name a file outside of a Local store.
+func CheckKey(key string) error {
+	if key == "" || strings.ContainsRune(key, '\\') {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/lifecycle"
+)
+
+// ErrMiss is returned by a Store when the key is absent or expired.
+var ErrMiss = errors.New("cache miss")
+
+// Store is the minimal key/value contract the repository decorators need.
+// Values are opaque byte slices so any backend (Redis, in-process LRU) can hold them.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Flusher is implemented by stores that can drop every entry at once, e.g.
+// for POST /admin/cache/flush. Flush returns how many entries it dropped.
+type Flusher interface {
+	Flush(ctx context.Context) (int, error)
+}
+
+// Metrics counts cache hits and misses. It is safe for concurrent use.
+type Metrics struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// Stats is a point-in-time snapshot of Metrics.
+type Stats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+func (m *Metrics) Hit()  { m.hits.Add(1) }
+func (m *Metrics) Miss() { m.misses.Add(1) }
+
+func (m *Metrics) Snapshot() Stats {
+	hits, misses := m.hits.Load(), m.misses.Load()
+	s := Stats{Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		s.HitRatio = float64(hits) / float64(total)
+	}
+	return s
+}
+
+// Backend names where a Store keeps its entries.
+type Backend string
+
+const (
+	Memory Backend = "memory" // in-process LRU
+	Redis  Backend = "redis"
+)
+
+// Backends lists every Backend. NewStore handles each of them.
+var Backends = []Backend{Memory, Redis}
+
+var errUnknownBackend = errors.New("unknown cache backend")
+
+// ParseBackend returns the Backend named s.
+func ParseBackend(s string) (Backend, error) {
+	for _, b := range Backends {
+		if string(b) == s {
+			return b, nil
+		}
+	}
+	return "", fmt.Errorf("invalid cache backend %q (want memory or redis)", s)
+}
+
+// UnmarshalText rejects unknown backends while the configuration is read.
+func (b *Backend) UnmarshalText(text []byte) (err error) {
+	*b, err = ParseBackend(string(text))
+	return err
+}
+
+// NewStore builds the Store for backend. An unreachable Redis is reported
+// as an error rather than silently falling back.
+func NewStore(ctx context.Context, lc *lifecycle.Manager, backend Backend, redisURL string, size int, clk clock.Clock) (Store, error) {
+	switch backend {
+	case Redis:
+		return NewRedisStore(ctx, lc, redisURL, "gin-api:")
+	case Memory:
+		return NewLRUStore(size, clk), nil
+	default:
+		return nil, fmt.Errorf("%w %q", errUnknownBackend, backend)
+	}
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/lifecycle"
+)
+
+func TestNewStoreHandlesEveryBackend(t *testing.T) {
+	for _, b := range Backends {
+		parsed, err := ParseBackend(string(b))
+		if err != nil || parsed != b {
+			t.Fatalf("ParseBackend(%q) = %q, %v", b, parsed, err)
+		}
+		// redis fails on the empty URL, after the switch has picked it
+		if _, err := NewStore(context.Background(), lifecycle.New(), b, "", 10, nil); errors.Is(err, errUnknownBackend) {
+			t.Errorf("NewStore does not handle backend %q", b)
+		}
+	}
+	if _, err := ParseBackend("memcached"); err == nil {
+		t.Fatal("ParseBackend accepted memcached")
+	}
+}
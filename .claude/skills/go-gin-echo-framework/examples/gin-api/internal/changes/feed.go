@@ -0,0 +1,189 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// ErrTokenExpired is returned when a token points before the oldest retained change,
+// meaning the client missed changes and must resync from a full listing.
+var ErrTokenExpired = errors.New("change token expired")
+
+// ErrInvalidToken is returned when a token cannot be parsed.
+var ErrInvalidToken = errors.New("invalid change token")
+
+type Op string
+
+const (
+	OpCreated Op = "created"
+	OpUpdated Op = "updated"
+	OpDeleted Op = "deleted"
+)
+
+type Change struct {
+	Seq    uint64    `json:"seq"`
+	Op     Op        `json:"op"`
+	ID     string    `json:"id"`
+	At     time.Time `json:"at"`
+	Tenant string    `json:"-"` // of the changed entity, "" without tenancy
+}
+
+// Feed is an in-process, bounded log of entity changes that clients can
+// long-poll with an opaque token. It keeps the most recent `retain` changes.
+// The log is shared by all tenants, but Wait and Since return only the
+// changes of the tenant asked for; tokens count every change, so a token
+// can expire on the writes of other tenants.
+type Feed struct {
+	mu      sync.Mutex
+	seq     uint64
+	log     []Change
+	retain  int
+	changed chan struct{} // closed and replaced on every Record to wake waiters
+	closed  chan struct{} // closed by Close to release all waiters
+	clock   clock.Clock
+}
+
+// NewFeed returns a Feed keeping the last retain changes (1000 if retain is
+// not positive), stamped by clk (nil is the system clock).
+func NewFeed(retain int, clk clock.Clock) *Feed {
+	if retain <= 0 {
+		retain = 1000
+	}
+	return &Feed{retain: retain, changed: make(chan struct{}), closed: make(chan struct{}), clock: clock.OrSystem(clk)}
+}
+
+// Close releases every pending Wait, as if its deadline had passed. It is
+// used on shutdown so long-poll requests finish instead of holding the server open.
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.closed:
+	default:
+		close(f.closed)
+	}
+}
+
+// Record appends a change to an entity of tenantID and wakes all waiting
+// pollers.
+func (f *Feed) Record(tenantID string, op Op, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	f.log = append(f.log, Change{Seq: f.seq, Op: op, ID: id, At: f.clock.Now().UTC(), Tenant: tenantID})
+	if len(f.log) > f.retain {
+		f.log = f.log[len(f.log)-f.retain:]
+	}
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// Token returns the token representing "now"; polling with it returns only future changes.
+func (f *Feed) Token() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return encodeToken(f.seq)
+}
+
+// Wait blocks until there are changes of tenantID after token or ctx is
+// done, then returns those changes (possibly none) together with the token
+// to use for the next poll.
+func (f *Feed) Wait(ctx context.Context, tenantID, token string) ([]Change, string, error) {
+	return f.wait(ctx, token, func(c Change) bool { return c.Tenant == tenantID })
+}
+
+// WaitAll is Wait for the changes of every tenant, for consumers that
+// serve them all and keep them apart themselves, such as the MQTT bridge.
+func (f *Feed) WaitAll(ctx context.Context, token string) ([]Change, string, error) {
+	return f.wait(ctx, token, nil)
+}
+
+func (f *Feed) wait(ctx context.Context, token string, match func(Change) bool) ([]Change, string, error) {
+	since, err := decodeToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for {
+		f.mu.Lock()
+		pending, err := f.since(since, match)
+		if err != nil {
+			f.mu.Unlock()
+			return nil, "", err
+		}
+		if len(pending) > 0 {
+			next := encodeToken(f.seq)
+			f.mu.Unlock()
+			return pending, next, nil
+		}
+		// Changes of other tenants need not be read again
+		since = f.seq
+		changed := f.changed
+		f.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-f.closed:
+			return []Change{}, encodeToken(since), nil
+		case <-ctx.Done():
+			return []Change{}, encodeToken(since), nil
+		}
+	}
+}
+
+// Since returns the changes of tenantID after token without blocking, plus
+// the token for the next call.
+func (f *Feed) Since(tenantID, token string) ([]Change, string, error) {
+	since, err := decodeToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pending, err := f.since(since, func(c Change) bool { return c.Tenant == tenantID })
+	if err != nil {
+		return nil, "", err
+	}
+	return pending, encodeToken(f.seq), nil
+}
+
+// since returns a copy of the retained changes after seq that match, or all
+// of them if match is nil. It must be called with f.mu held.
+func (f *Feed) since(seq uint64, match func(Change) bool) ([]Change, error) {
+	if seq > f.seq {
+		return nil, ErrInvalidToken
+	}
+	if len(f.log) > 0 && seq+1 < f.log[0].Seq {
+		return nil, ErrTokenExpired
+	}
+	start := 0
+	if len(f.log) > 0 {
+		start = int(seq + 1 - f.log[0].Seq)
+	}
+	out := make([]Change, 0, len(f.log)-start)
+	for _, c := range f.log[start:] {
+		if match == nil || match(c) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func encodeToken(seq uint64) string {
+	return strconv.FormatUint(seq, 36)
+}
+
+func decodeToken(token string) (uint64, error) {
+	seq, err := strconv.ParseUint(token, 36, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return seq, nil
+}
@@ -0,0 +1,71 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+func TestFeedReturnsTheChangesOfTheTenant(t *testing.T) {
+	f := NewFeed(10, nil)
+	defer f.Close()
+	start := f.Token()
+	f.Record("acme", OpCreated, "1")
+	f.Record("globex", OpCreated, "1")
+	f.Record("globex", OpDeleted, "2")
+	f.Record("acme", OpUpdated, "1")
+
+	got, next, err := f.Since("acme", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Op != OpCreated || got[1].Op != OpUpdated || got[0].Tenant != "acme" {
+		t.Errorf("acme changes = %+v", got)
+	}
+	if got, _, _ := f.Since("", start); len(got) != 0 {
+		t.Errorf("untenanted changes = %+v, want none", got)
+	}
+
+	// A poll is not woken for nor handed the changes of other tenants
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	f.Record("globex", OpUpdated, "1")
+	got, after, err := f.Wait(ctx, "acme", next)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("acme wait = %+v, %v; want none", got, err)
+	}
+	if after == next {
+		t.Error("the token did not move past the changes of globex")
+	}
+	if got, _, _ := f.WaitAll(context.Background(), start); len(got) != 5 {
+		t.Errorf("all changes = %d, want 5", len(got))
+	}
+}
+
+func TestBuildDeltaReadsTheTenantOfTheContext(t *testing.T) {
+	f := NewFeed(10, nil)
+	defer f.Close()
+	start := f.Token()
+	f.Record("acme", OpCreated, "a")
+	f.Record("globex", OpCreated, "g")
+	f.Record("globex", OpDeleted, "x")
+
+	items := map[string]string{"a": "acme's"}
+	delta, err := BuildDelta(tenant.With(context.Background(), "acme"), f, start, Source[string]{
+		Get: func(_ context.Context, id string) (*string, error) {
+			if s, ok := items[id]; ok {
+				return &s, nil
+			}
+			return nil, errors.New("not found")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delta.Created) != 1 || delta.Created[0].ID != "a" || len(delta.Deleted) != 0 {
+		t.Errorf("delta = %+v, want only the creation of a", delta)
+	}
+}
@@ -0,0 +1,63 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeMovesOnlyWhenAdvanced(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Fatalf("Now() = %v, want %v", got, start)
+	}
+	c.Advance(90 * time.Minute)
+	if got, want := c.Now(), start.Add(90*time.Minute); !got.Equal(want) {
+		t.Fatalf("Now() = %v, want %v", got, want)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	c := NewFake(time.Unix(0, 0))
+	tick := c.NewTicker(time.Second)
+
+	ticked := func() bool {
+		select {
+		case <-tick.C():
+			return true
+		default:
+			return false
+		}
+	}
+	c.Advance(999 * time.Millisecond)
+	if ticked() {
+		t.Fatal("ticked before its period")
+	}
+	c.Advance(time.Millisecond)
+	if !ticked() {
+		t.Fatal("did not tick after its period")
+	}
+	// A slow reader gets one tick, and the schedule stays on whole periods
+	c.Advance(3500 * time.Millisecond)
+	if !ticked() || ticked() {
+		t.Fatal("want exactly one tick for several missed periods")
+	}
+	c.Advance(500 * time.Millisecond)
+	if !ticked() {
+		t.Fatal("did not tick on the next whole period")
+	}
+
+	tick.Reset(time.Minute)
+	c.Advance(time.Second)
+	if ticked() {
+		t.Fatal("ticked on the old period after Reset")
+	}
+	tick.Stop()
+	if n := c.Tickers(); n != 0 {
+		t.Fatalf("Tickers() = %d after Stop, want 0", n)
+	}
+	c.Advance(time.Hour)
+	if ticked() {
+		t.Fatal("ticked after Stop")
+	}
+}
@@ -0,0 +1,243 @@
+// Package compression encodes response bodies with brotli or gzip for
+// clients that accept them. Only textual media types above a size threshold
+// are compressed; images, archives and streamed events are sent as is.
+package compression
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Options configure the middleware.
+type Options struct {
+	Encodings []string `yaml:"encodings"` // offered in order of preference: br, gzip; empty disables compression
+	MinBytes  int      `yaml:"min_bytes"` // smaller bodies are not worth the CPU and are sent as is
+}
+
+// Validate rejects encodings the middleware cannot produce.
+func (o Options) Validate() error {
+	var errs []error
+	for _, e := range o.Encodings {
+		if _, ok := encoders[e]; !ok {
+			errs = append(errs, fmt.Errorf("unknown encoding %q (supported: br, gzip)", e))
+		}
+	}
+	if o.MinBytes < 0 {
+		errs = append(errs, errors.New("min_bytes must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(io.Writer)
+}
+
+// encoders pool the writers per encoding; both allocate large windows.
+var encoders = map[string]*sync.Pool{
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(nil, 5) // the default 6 costs far more CPU for little gain on JSON
+	}},
+	"gzip": {New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+}
+
+// Middleware compresses the responses of next. It must wrap every handler
+// that rewrites bodies, so it sees them in their final form.
+func Middleware(o Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(o.Encodings) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), o.Encodings)
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				// The body is not changed, but caches must still know it could be
+				w.Header().Add("Vary", "Accept-Encoding")
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &writer{ResponseWriter: w, encoding: encoding, minBytes: o.MinBytes}
+			defer cw.finish()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiate picks the first of offered with the highest quality in the
+// Accept-Encoding header, or "" for none.
+func negotiate(header string, offered []string) string {
+	if header == "" {
+		return ""
+	}
+	quality := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		quality[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, e := range offered {
+		q, ok := quality[e]
+		if !ok {
+			q, ok = quality["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether responses of contentType shrink enough to be
+// worth encoding.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false // events must reach the client as they are flushed
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "application/x-ndjson", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// writer buffers up to minBytes of the body to decide whether to compress,
+// then either encodes or passes the body through.
+type writer struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder // nil once decided against compressing
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decide(false) // no body to encode
+	}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minBytes {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide writes the header, compressing when the body allows it and
+// buffered says enough of it has been seen, and flushes the buffer.
+func (w *writer) decide(buffered bool) error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+	h := w.Header()
+	if compressible(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+		if buffered && h.Get("Content-Encoding") == "" {
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				h.Set("ETag", "W/"+etag) // the encoded bytes differ; the representation does not
+			}
+			w.enc = encoders[w.encoding].Get().(encoder)
+			w.enc.Reset(w.ResponseWriter)
+		}
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// finish sends a body that stayed below minBytes and ends the encoding.
+func (w *writer) finish() {
+	w.decide(false)
+	if w.enc != nil {
+		w.enc.Close()
+		encoders[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// Flush sends what has been written so far, compressed or not, so streaming
+// handlers work behind the middleware.
+func (w *writer) Flush() {
+	w.decide(len(w.buf) > 0 && len(w.buf) >= w.minBytes)
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through; the connection is no longer HTTP.
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.decided = true
+	return h.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,102 @@
+package compression
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+var body = `{"items": [` + strings.Repeat(`{"name": "Desk Lamp Nova"},`, 100) + `{}]}`
+
+func serve(contentType, payload, acceptEncoding string) *httptest.ResponseRecorder {
+	h := Middleware(Options{Encodings: []string{"br", "gzip"}, MinBytes: 256})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, payload)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", acceptEncoding)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareCompresses(t *testing.T) {
+	for encoding, decode := range map[string]func(io.Reader) (io.Reader, error){
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	} {
+		w := serve("application/json; charset=utf-8", body, encoding)
+		if got := w.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("Content-Encoding = %q, want %s", got, encoding)
+		}
+		if w.Body.Len() >= len(body)/4 {
+			t.Errorf("%s: %d of %d bytes", encoding, w.Body.Len(), len(body))
+		}
+		r, err := decode(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded, _ := io.ReadAll(r); string(decoded) != body {
+			t.Errorf("%s: decoded body differs", encoding)
+		}
+		if w.Header().Get("Vary") != "Accept-Encoding" || w.Header().Get("ETag") != `W/"v1"` {
+			t.Errorf("%s: headers %v", encoding, w.Header())
+		}
+	}
+}
+
+func TestMiddlewareSkips(t *testing.T) {
+	tests := []struct {
+		name, contentType, body, acceptEncoding string
+	}{
+		{"small body", "application/json", `{"id": 1}`, "gzip"},
+		{"binary", "image/png", body, "gzip"},
+		{"event stream", "text/event-stream", body, "gzip"},
+		{"not accepted", "application/json", body, "identity"},
+		{"refused", "application/json", body, "br;q=0, gzip;q=0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.contentType, tt.body, tt.acceptEncoding)
+			if w.Header().Get("Content-Encoding") != "" || w.Body.String() != tt.body {
+				t.Errorf("Content-Encoding %q, body %d bytes", w.Header().Get("Content-Encoding"), w.Body.Len())
+			}
+		})
+	}
+}
+
+func TestNotModifiedHasNoBody(t *testing.T) {
+	h := Middleware(Options{Encodings: []string{"gzip"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("%d %q %v", w.Code, w.Body, w.Header())
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{"br", "gzip"}
+	for header, want := range map[string]string{
+		"gzip, deflate, br":   "br", // equal quality: the server's preference
+		"gzip;q=1, br;q=0.5":  "gzip",
+		"*":                   "br",
+		"*;q=0.1, gzip;q=0.2": "gzip",
+		"deflate":             "",
+		"":                    "",
+	} {
+		if got := negotiate(header, offered); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package confirm guards destructive operations, such as deleting many
+// items at once, with two calls: the first changes nothing and answers with
+// what would change and a token; the second, carrying the token, runs the
+// operation. A token confirms only the request it was issued for, once,
+// and briefly, so a replayed or mistyped request cannot destroy anything
+// its caller has not seen first.
+package confirm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// Options turn confirmations on.
+type Options struct {
+	Enabled bool          `yaml:"enabled"` // false runs guarded operations on the first call
+	TTL     time.Duration `yaml:"ttl"`     // how long a token can be redeemed
+}
+
+// Validate checks that enabled confirmations have a lifetime.
+func (o Options) Validate() error {
+	if o.Enabled && o.TTL <= 0 {
+		return errors.New("ttl must be positive")
+	}
+	return nil
+}
+
+// ErrInvalidToken is returned by Redeem for a token that was not issued for
+// the request, was already redeemed or has expired.
+var ErrInvalidToken = errors.New("invalid or expired confirmation token")
+
+// Challenge answers the first call: what the operation would change, and
+// the token that confirms it.
+type Challenge[T any] struct {
+	Operation string    `json:"operation"` // e.g. "delete 3 users"
+	Affected  []T       `json:"affected"`  // the items the operation would change
+	Token     string    `json:"token"`     // send back as the confirm query parameter
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type pending struct {
+	key     string
+	expires time.Time
+}
+
+// Store issues and redeems tokens. It is held in process, so a token must
+// be redeemed on the instance that issued it.
+type Store struct {
+	clock clock.Clock
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	pending map[string]pending // token -> what it confirms
+}
+
+// NewStore returns a store of tokens valid for ttl on clk (nil is the
+// system clock).
+func NewStore(clk clock.Clock, ttl time.Duration) *Store {
+	return &Store{clock: clock.OrSystem(clk), ttl: ttl, pending: map[string]pending{}}
+}
+
+// Issue returns a token confirming the request identified by key, which
+// must name the operation, its arguments and the caller, and when the
+// token expires.
+func (s *Store) Issue(key string) (string, time.Time) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("confirm: failed to generate token: %v", err))
+	}
+	token := hex.EncodeToString(b)
+	now := s.clock.Now()
+	expires := now.Add(s.ttl).UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, p := range s.pending {
+		if !now.Before(p.expires) {
+			delete(s.pending, t)
+		}
+	}
+	s.pending[token] = pending{key: key, expires: expires}
+	return token, expires
+}
+
+// Redeem uses up token, failing with ErrInvalidToken unless it was issued
+// for key and has not expired. A token that fails is used up too: the
+// caller has to look at a fresh Challenge before trying again.
+func (s *Store) Redeem(token, key string) error {
+	s.mu.Lock()
+	p, ok := s.pending[token]
+	delete(s.pending, token)
+	s.mu.Unlock()
+	if !ok || p.key != key || !s.clock.Now().Before(p.expires) {
+		return ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package confirm
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+func TestRedeem(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewStore(clk, time.Minute)
+
+	token, expires := s.Issue("delete a,b")
+	if want := clk.Now().Add(time.Minute); !expires.Equal(want) {
+		t.Errorf("expires %v, want %v", expires, want)
+	}
+	if err := s.Redeem(token, "delete a,b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Redeem(token, "delete a,b"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("redeemed twice: %v", err)
+	}
+
+	token, _ = s.Issue("delete a,b")
+	if err := s.Redeem(token, "delete a,b,c"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("redeemed for another request: %v", err)
+	}
+	if err := s.Redeem(token, "delete a,b"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("redeemed after a failed attempt: %v", err)
+	}
+
+	token, _ = s.Issue("delete a,b")
+	clk.Advance(time.Minute)
+	if err := s.Redeem(token, "delete a,b"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("redeemed when expired: %v", err)
+	}
+	if err := s.Redeem("made-up", "delete a,b"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("redeemed an unknown token: %v", err)
+	}
+}
+
+func TestIssueForgetsExpiredTokens(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewStore(clk, time.Minute)
+	s.Issue("a")
+	s.Issue("b")
+	clk.Advance(time.Minute)
+	s.Issue("c")
+	if len(s.pending) != 1 {
+		t.Errorf("%d pending tokens, want only the unexpired one", len(s.pending))
+	}
+}
@@ -0,0 +1,130 @@
+// Package container is the registry of the composition root: each component
+// is declared once by a provider, a function building it from the other
+// components it asks the container for, and is built on first use.
+//
+// Because a component's dependencies are built while its provider runs, the
+// hooks they append to the lifecycle.Manager come before its own: Start
+// starts dependencies first and Shutdown drains them last, without main
+// having to keep the order by hand. Components that are never asked for,
+// such as the database of the mock server, are never built.
+//
+// Components are keyed by type. Two components of one type, such as two
+// http.Clients, need distinct named types or a struct holding both.
+package container
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/your-username/gin-api/internal/lifecycle"
+)
+
+var (
+	// ErrNoProvider is returned by Get for a type that was never provided.
+	ErrNoProvider = errors.New("no provider")
+	// ErrCycle is returned by Get for a type whose provider depends on it.
+	ErrCycle = errors.New("dependency cycle")
+)
+
+// Container holds the providers and the components built so far. It is not
+// safe for concurrent use: wiring runs on one goroutine.
+type Container struct {
+	lc        *lifecycle.Manager
+	providers map[reflect.Type]func(*Container) (any, error)
+	built     map[reflect.Type]any
+	building  []reflect.Type // the providers running, outermost first
+}
+
+// New returns an empty container whose components append their hooks to lc.
+func New(lc *lifecycle.Manager) *Container {
+	return &Container{
+		lc:        lc,
+		providers: make(map[reflect.Type]func(*Container) (any, error)),
+		built:     make(map[reflect.Type]any),
+	}
+}
+
+// Lifecycle returns the manager that components append their hooks to.
+func (c *Container) Lifecycle() *lifecycle.Manager {
+	return c.lc
+}
+
+// Provide declares how to build the T. A later provider of the same type
+// replaces an earlier one, so wiring can override a default (in test mode,
+// say) until the T is first built.
+func Provide[T any](c *Container, build func(c *Container) (T, error)) {
+	c.providers[typeOf[T]()] = func(c *Container) (any, error) {
+		return build(c)
+	}
+}
+
+// Supply declares a T that is already built.
+func Supply[T any](c *Container, v T) {
+	t := typeOf[T]()
+	delete(c.providers, t)
+	c.built[t] = v
+}
+
+// Get returns the T, building it and whatever it depends on first if it has
+// not been built yet. A provider that fails is not retried: wiring stops at
+// the first error.
+func Get[T any](c *Container) (T, error) {
+	var zero T
+	t := typeOf[T]()
+	if v, ok := c.built[t]; ok {
+		v, _ := v.(T) // nil for a nil interface or pointer
+		return v, nil
+	}
+	build, ok := c.providers[t]
+	if !ok {
+		return zero, &BuildError{Path: chain(append(c.building, t)), Err: ErrNoProvider}
+	}
+	for i, b := range c.building {
+		if b == t {
+			return zero, &BuildError{Path: chain(append(c.building[i:], t)), Err: ErrCycle}
+		}
+	}
+
+	c.building = append(c.building, t)
+	v, err := build(c)
+	path := chain(c.building)
+	c.building = c.building[:len(c.building)-1]
+	if err != nil {
+		var be *BuildError
+		if errors.As(err, &be) {
+			return zero, err // already names the component that failed
+		}
+		return zero, &BuildError{Path: path, Err: err}
+	}
+	c.built[t] = v
+	got, _ := v.(T)
+	return got, nil
+}
+
+// BuildError is returned by Get when a component cannot be built. Path is
+// the chain of components being built, from the one asked for to the one
+// that failed.
+type BuildError struct {
+	Path string
+	Err  error
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("container: building %s: %v", e.Path, e.Err)
+}
+
+func (e *BuildError) Unwrap() error { return e.Err }
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func chain(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
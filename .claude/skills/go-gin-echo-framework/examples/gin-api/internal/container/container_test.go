@@ -0,0 +1,136 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/lifecycle"
+)
+
+type (
+	db     struct{ name string }
+	repo   struct{ db *db }
+	server struct{ repo *repo }
+)
+
+// provide declares db <- repo <- server, each appending a hook that logs to
+// events.
+func provide(c *Container, events *[]string) {
+	hook := func(name string) {
+		c.Lifecycle().Append(lifecycle.Hook{
+			Name:  name,
+			Start: func(context.Context) error { *events = append(*events, "start "+name); return nil },
+			Stop:  func(context.Context) error { *events = append(*events, "stop "+name); return nil },
+		})
+	}
+	Provide(c, func(c *Container) (*db, error) {
+		hook("db")
+		return &db{name: "main"}, nil
+	})
+	Provide(c, func(c *Container) (*repo, error) {
+		d, err := Get[*db](c)
+		if err != nil {
+			return nil, err
+		}
+		hook("repo")
+		return &repo{db: d}, nil
+	})
+	Provide(c, func(c *Container) (*server, error) {
+		r, err := Get[*repo](c)
+		if err != nil {
+			return nil, err
+		}
+		hook("server")
+		return &server{repo: r}, nil
+	})
+}
+
+func TestDependenciesStartFirstAndStopLast(t *testing.T) {
+	lc := lifecycle.New()
+	c := New(lc)
+	var events []string
+	provide(c, &events)
+
+	s, err := Get[*server](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Get[*server](c)
+	if again != s || s.repo.db.name != "main" {
+		t.Fatalf("got %+v, then %+v; want one server on the main db", s, again)
+	}
+	if err := lc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := lc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "start db, start repo, start server, stop server, stop repo, stop db"
+	if got := strings.Join(events, ", "); got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+}
+
+func TestUnusedComponentsAreNotBuilt(t *testing.T) {
+	c := New(lifecycle.New())
+	var events []string
+	provide(c, &events)
+	Supply(c, &db{name: "supplied"})
+
+	r, err := Get[*repo](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.db.name != "supplied" {
+		t.Fatalf("repo uses db %q, want the supplied one", r.db.name)
+	}
+	if err := c.Lifecycle().Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(events, ", "); got != "start repo" {
+		t.Fatalf("events = %s, want only the repo started", got)
+	}
+}
+
+func TestBuildErrorsNameThePath(t *testing.T) {
+	c := New(lifecycle.New())
+	var events []string
+	provide(c, &events)
+	broken := errors.New("connection refused")
+	Provide(c, func(*Container) (*db, error) { return nil, broken })
+
+	_, err := Get[*server](c)
+	if !errors.Is(err, broken) {
+		t.Fatalf("err = %v, want %v", err, broken)
+	}
+	if want := "container: building *container.server -> *container.repo -> *container.db: connection refused"; err.Error() != want {
+		t.Fatalf("err = %q, want %q", err, want)
+	}
+
+	_, err = Get[*strings.Builder](c)
+	if !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("err = %v, want %v", err, ErrNoProvider)
+	}
+
+	Provide(c, func(c *Container) (*db, error) {
+		_, err := Get[*repo](c)
+		return nil, err
+	})
+	_, err = Get[*repo](c)
+	if !errors.Is(err, ErrCycle) || !strings.Contains(err.Error(), "*container.repo -> *container.db -> *container.repo") {
+		t.Fatalf("err = %v, want the cycle through repo and db", err)
+	}
+}
+
+func TestNilComponents(t *testing.T) {
+	c := New(lifecycle.New())
+	Provide(c, func(*Container) (error, error) { return nil, nil })
+	if v, err := Get[error](c); v != nil || err != nil {
+		t.Fatalf("Get = %v, %v; want a nil component", v, err)
+	}
+	if v, err := Get[error](c); v != nil || err != nil {
+		t.Fatalf("second Get = %v, %v; want the nil component again", v, err)
+	}
+}
@@ -0,0 +1,210 @@
+// Package cors answers Cross-Origin Resource Sharing requests, so browser
+// clients served from another origin can call the API. Which origins,
+// methods and headers are allowed is set per deployment through Options; the
+// defaults come from the middleware preset (see pipeline.Presets).
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options is a CORS policy. Unset fields take their value from the defaults
+// passed to WithDefaults.
+type Options struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`   // e.g. https://app.example.com; one * matches a subdomain or port, a lone * any origin
+	AllowedMethods   []string      `yaml:"allowed_methods"`   // methods beyond the simple GET, HEAD and POST need a preflight
+	AllowedHeaders   []string      `yaml:"allowed_headers"`   // request headers; * allows whatever a preflight asks for
+	ExposedHeaders   []string      `yaml:"exposed_headers"`   // response headers scripts may read besides the safelisted ones
+	AllowCredentials bool          `yaml:"allow_credentials"` // let browsers send cookies and HTTP auth
+	MaxAge           time.Duration `yaml:"max_age"`           // how long browsers may cache a preflight answer
+}
+
+// WithDefaults fills every unset field of o from d. AllowCredentials is
+// never defaulted: it is only enabled explicitly.
+func (o Options) WithDefaults(d Options) Options {
+	if o.AllowedOrigins == nil {
+		o.AllowedOrigins = d.AllowedOrigins
+	}
+	if o.AllowedMethods == nil {
+		o.AllowedMethods = d.AllowedMethods
+	}
+	if o.AllowedHeaders == nil {
+		o.AllowedHeaders = d.AllowedHeaders
+	}
+	if o.ExposedHeaders == nil {
+		o.ExposedHeaders = d.ExposedHeaders
+	}
+	if o.MaxAge == 0 {
+		o.MaxAge = d.MaxAge
+	}
+	return o
+}
+
+// Validate rejects malformed origins and credentials for any origin, which
+// would let every site act with the user's cookies.
+func (o Options) Validate() error {
+	var errs []error
+	for _, origin := range o.AllowedOrigins {
+		if origin == "*" {
+			if o.AllowCredentials {
+				errs = append(errs, errors.New(`"*" cannot be allowed with credentials; list the origins`))
+			}
+			continue
+		}
+		if err := validOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("origin %q: %w", origin, err))
+		}
+	}
+	if o.MaxAge < 0 {
+		errs = append(errs, errors.New("max_age must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func validOrigin(origin string) error {
+	if strings.Count(origin, "*") > 1 {
+		return errors.New("at most one * is allowed")
+	}
+	u, err := url.Parse(strings.Replace(origin, "*", "0", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an http:// or https:// origin")
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return errors.New("must be scheme and host only, without a path")
+	}
+	return nil
+}
+
+// Policy applies validated Options to requests. It is safe for concurrent
+// use.
+type Policy struct {
+	opts        Options
+	anyOrigin   bool
+	origins     map[string]bool // exact, lower-cased
+	patterns    [][2]string     // prefix and suffix around the *
+	methods     map[string]bool
+	anyHeader   bool
+	headers     map[string]bool // lower-cased
+	allowMethod string
+	allowHeader string
+	expose      string
+}
+
+// New returns the policy for opts, which must be valid.
+func New(opts Options) *Policy {
+	p := &Policy{
+		opts:        opts,
+		origins:     map[string]bool{},
+		methods:     map[string]bool{},
+		headers:     map[string]bool{},
+		allowMethod: strings.Join(opts.AllowedMethods, ", "),
+		allowHeader: strings.Join(opts.AllowedHeaders, ", "),
+		expose:      strings.Join(opts.ExposedHeaders, ", "),
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(o)
+		switch prefix, suffix, wild := strings.Cut(o, "*"); {
+		case o == "*":
+			p.anyOrigin = true
+		case wild:
+			p.patterns = append(p.patterns, [2]string{prefix, suffix})
+		default:
+			p.origins[o] = true
+		}
+	}
+	for _, m := range opts.AllowedMethods {
+		p.methods[strings.ToUpper(m)] = true
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			p.anyHeader = true
+		}
+		p.headers[strings.ToLower(h)] = true
+	}
+	return p
+}
+
+// Allows reports whether requests from origin may read responses.
+func (p *Policy) Allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.anyOrigin || p.origins[origin] {
+		return true
+	}
+	for _, pat := range p.patterns {
+		if strings.HasPrefix(origin, pat[0]) && strings.HasSuffix(origin, pat[1]) && len(origin) > len(pat[0])+len(pat[1]) {
+			if middle := origin[len(pat[0]) : len(origin)-len(pat[1])]; !strings.ContainsAny(middle, "/:@") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply sets the CORS headers of the response to r on h. It reports whether
+// r is a preflight request; those are answered by the headers alone, so the
+// caller responds 204 without running the handler. A preflight the policy
+// rejects gets no Access-Control-Allow-* headers, which the browser reports
+// as a CORS error.
+func (p *Policy) Apply(h http.Header, r *http.Request) (preflight bool) {
+	origin := r.Header.Get("Origin")
+	preflight = r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		h.Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	} else if !p.anyOrigin || p.opts.AllowCredentials {
+		h.Add("Vary", "Origin") // the response depends on the requesting origin
+	}
+	if origin == "" || !p.Allows(origin) {
+		return preflight
+	}
+
+	if preflight {
+		requested := r.Header.Get("Access-Control-Request-Headers")
+		if !p.allowsMethod(r.Header.Get("Access-Control-Request-Method")) || !p.allowsHeaders(requested) {
+			return true
+		}
+		h.Set("Access-Control-Allow-Methods", p.allowMethod)
+		if p.anyHeader {
+			h.Set("Access-Control-Allow-Headers", requested)
+		} else if p.allowHeader != "" {
+			h.Set("Access-Control-Allow-Headers", p.allowHeader)
+		}
+		if p.opts.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.opts.MaxAge.Seconds())))
+		}
+	} else if p.expose != "" {
+		h.Set("Access-Control-Expose-Headers", p.expose)
+	}
+	if p.anyOrigin && !p.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return preflight
+}
+
+func (p *Policy) allowsMethod(method string) bool {
+	return p.methods[strings.ToUpper(method)]
+}
+
+// allowsHeaders reports whether every header in the comma-separated list
+// requested may be sent.
+func (p *Policy) allowsHeaders(requested string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		if h = strings.TrimSpace(h); h != "" && !p.headers[strings.ToLower(h)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func request(method, origin string, header ...string) *http.Request {
+	r := httptest.NewRequest(method, "/users/", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return r
+}
+
+func TestPolicyAllows(t *testing.T) {
+	p := New(Options{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org", "http://localhost:*"}})
+	for origin, want := range map[string]bool{
+		"https://app.example.com":        true,
+		"HTTPS://APP.EXAMPLE.COM":        true,
+		"http://app.example.com":         false,
+		"https://api.example.org":        true,
+		"https://a.b.example.org":        true,
+		"https://.example.org":           false,
+		"https://example.org":            false,
+		"https://evil.com:1.example.org": false,
+		"http://localhost:3000":          true,
+		"http://localhost":               false,
+		"http://localhost:3000/x":        false,
+	} {
+		if got := p.Allows(origin); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	p := New(Options{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "PATCH"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	h := http.Header{}
+	if p.Apply(h, request(http.MethodPatch, "https://app.example.com")) {
+		t.Fatal("an actual request was treated as a preflight")
+	}
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" || h.Get("Access-Control-Expose-Headers") != "X-Request-ID" || h.Get("Vary") != "Origin" {
+		t.Errorf("actual request headers = %v", h)
+	}
+	if h.Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("credentials allowed without allow_credentials")
+	}
+
+	h = http.Header{}
+	preflight := request(http.MethodOptions, "https://app.example.com", "Access-Control-Request-Method", "PATCH", "Access-Control-Request-Headers", "content-type, authorization")
+	if !p.Apply(h, preflight) {
+		t.Fatal("preflight not recognised")
+	}
+	if h.Get("Access-Control-Allow-Methods") != "GET, PATCH" || h.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight headers = %v", h)
+	}
+
+	for name, r := range map[string]*http.Request{
+		"other origin":      request(http.MethodOptions, "https://evil.example", "Access-Control-Request-Method", "GET"),
+		"method not listed": request(http.MethodOptions, "https://app.example.com", "Access-Control-Request-Method", "DELETE"),
+		"header not listed": request(http.MethodOptions, "https://app.example.com", "Access-Control-Request-Method", "GET", "Access-Control-Request-Headers", "X-Debug"),
+	} {
+		h := http.Header{}
+		if !p.Apply(h, r) || h.Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("%s: preflight allowed: %v", name, h)
+		}
+	}
+
+	h = http.Header{}
+	if p.Apply(h, request(http.MethodOptions, "")) || len(h) != 1 {
+		t.Errorf("same-origin OPTIONS got %v, want only Vary and the handler to run", h)
+	}
+}
+
+func TestAnyOriginAndHeader(t *testing.T) {
+	p := New(Options{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{"*"}})
+	h := http.Header{}
+	p.Apply(h, request(http.MethodOptions, "https://any.example", "Access-Control-Request-Method", "GET", "Access-Control-Request-Headers", "X-Custom"))
+	if h.Get("Access-Control-Allow-Origin") != "*" || h.Get("Access-Control-Allow-Headers") != "X-Custom" {
+		t.Errorf("preflight headers = %v", h)
+	}
+
+	if err := (Options{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Error("Validate accepted credentials for any origin")
+	}
+	if err := (Options{AllowedOrigins: []string{"https://app.example.com/", "app.example.com", "https://*.*.example.com"}}).Validate(); err == nil {
+		t.Error("Validate accepted malformed origins")
+	}
+	if err := (Options{AllowedOrigins: []string{"https://*.example.com", "http://localhost:*"}}).Validate(); err != nil {
+		t.Errorf("Validate rejected wildcards: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package cors
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware applies p to every request and answers preflight requests
+// itself, before routing, authentication or rate limiting see them.
+func Middleware(p *Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p.Apply(c.Writer.Header(), c.Request) {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,218 @@
+// Package describe summarises what a configured server runs: the resolved
+// configuration with its secrets redacted, the optional modules it enables,
+// the routes it mounts and the auth they require, where it stores data and
+// where it listens. main logs the summary on boot and prints it as JSON for
+// -describe, without serving.
+package describe
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/gin-api/config"
+	"github.com/your-username/gin-api/internal/openapi"
+	"github.com/your-username/gin-api/internal/search"
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// Undocumented is the Route.Auth of infrastructure routes deliberately
+// absent from the OpenAPI spec; their handlers apply their own checks.
+const Undocumented = "undocumented"
+
+// Description is the summary of one server.
+type Description struct {
+	Environment config.Environment `json:"environment"`
+	Storage     Storage            `json:"storage"`
+	Listeners   []Listener         `json:"listeners"`
+	Modules     []Module           `json:"modules"`
+	Routes      []Route            `json:"routes"`
+	Config      map[string]string  `json:"config"` // see config.Config.Redacted
+}
+
+// Storage names the backends, by location with any credentials removed.
+type Storage struct {
+	Database   string `json:"database"`             // "in-memory" or the URL, e.g. postgres://db:5432/app
+	Cache      string `json:"cache,omitempty"`      // repository cache backend, when caching is on
+	RateLimit  string `json:"rate_limit,omitempty"` // rate limit store, when the preset limits requests
+	Revocation string `json:"revocation"`           // revoked token store
+	Redis      string `json:"redis,omitempty"`      // when one of the backends above is redis
+}
+
+// Listener is an address the server accepts connections on.
+type Listener struct {
+	Protocol string `json:"protocol"` // http or grpc
+	Address  string `json:"address"`
+}
+
+// Module is an optional part of the server and whether it is on.
+type Module struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// Route is a mounted route and who may call it.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Auth   string `json:"auth"` // security schemes from the spec, "none" for public routes, or Undocumented
+}
+
+// New describes the server cfg configures, serving routes.
+func New(cfg *config.Config, routes []openapi.Route) (Description, error) {
+	security, err := openapi.Security()
+	if err != nil {
+		return Description{}, err
+	}
+	d := Description{
+		Environment: cfg.Environment,
+		Storage:     storage(cfg),
+		Listeners:   []Listener{{"http", ":" + cfg.Server.Port}},
+		Modules:     modules(cfg),
+		Routes:      make([]Route, 0, len(routes)),
+		Config:      cfg.Redacted(),
+	}
+	if cfg.GRPC.Multiplex {
+		d.Listeners = append(d.Listeners, Listener{"grpc", ":" + cfg.Server.Port})
+	} else if cfg.GRPC.Port != "" {
+		d.Listeners = append(d.Listeners, Listener{"grpc", ":" + cfg.GRPC.Port})
+	}
+	for _, r := range routes {
+		auth := Undocumented
+		if schemes, ok := security[r.Key()]; ok {
+			auth = "none"
+			if len(schemes) > 0 {
+				auth = strings.Join(schemes, ", ")
+			}
+		}
+		d.Routes = append(d.Routes, Route{Method: r.Method, Path: r.Path, Auth: auth})
+	}
+	sort.Slice(d.Routes, func(i, j int) bool {
+		a, b := d.Routes[i], d.Routes[j]
+		return a.Path < b.Path || a.Path == b.Path && a.Method < b.Method
+	})
+	return d, nil
+}
+
+func storage(cfg *config.Config) Storage {
+	s := Storage{Database: location(cfg.Database.URL), Revocation: cfg.Auth.RevocationBackend}
+	if cfg.Cache.TTL > 0 {
+		s.Cache = string(cfg.Cache.Backend)
+	}
+	if cfg.MiddlewarePreset().RateLimit {
+		s.RateLimit = cfg.RateLimit.Backend
+	}
+	if slices.Contains([]string{s.Cache, s.RateLimit, s.Revocation}, "redis") {
+		s.Redis = location(cfg.Redis.URL)
+	}
+	return s
+}
+
+func modules(cfg *config.Config) []Module {
+	cors := cfg.CORSOptions()
+	grpc := "port " + cfg.GRPC.Port
+	if cfg.GRPC.Multiplex {
+		grpc = "multiplexed with HTTP"
+	}
+	var providers []string
+	for name := range cfg.Auth.Providers {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	var jobs []string
+	if cfg.Jobs.CacheRefresh != "" {
+		jobs = append(jobs, "cache_refresh "+cfg.Jobs.CacheRefresh)
+	}
+	if cfg.Jobs.OutboxPurge != "" {
+		jobs = append(jobs, "outbox_purge "+cfg.Jobs.OutboxPurge)
+	}
+	if cfg.Jobs.TrashPurge != "" {
+		jobs = append(jobs, "trash_purge "+cfg.Jobs.TrashPurge)
+	}
+	var notified []string
+	for event := range cfg.Notify.Events {
+		notified = append(notified, event)
+	}
+	sort.Strings(notified)
+	if len(notified) > 0 && cfg.Jobs.Notify != "" {
+		jobs = append(jobs, "notify "+cfg.Jobs.Notify)
+	}
+	index := cfg.Search.Backend
+	if index == search.BackendElasticsearch {
+		index += " " + location(cfg.Search.URL)
+	}
+	return []Module{
+		{"middleware", true, "preset " + cfg.MiddlewarePreset().Name},
+		{"cache", cfg.Cache.TTL > 0, "ttl " + cfg.Cache.TTL.String()},
+		{"rate_limit", cfg.MiddlewarePreset().RateLimit, ""},
+		{"cors", len(cors.AllowedOrigins) > 0, strings.Join(cors.AllowedOrigins, ", ")},
+		{"compression", len(cfg.Compression.Encodings) > 0, strings.Join(cfg.Compression.Encodings, ", ")},
+		{"resilience", cfg.Resilience.Enabled, "open after " + strconv.Itoa(cfg.Resilience.FailureThreshold) + " failures"},
+		{"payload_log", cfg.PayloadLog.Enabled, "max " + strconv.Itoa(cfg.PayloadLog.MaxBodyBytes) + " bytes per body"},
+		{"oidc", len(providers) > 0, strings.Join(providers, ", ")},
+		{"grpc", cfg.GRPC.Multiplex || cfg.GRPC.Port != "", grpc},
+		{"mqtt", cfg.MQTT.BrokerURL != "", location(cfg.MQTT.BrokerURL)},
+		{"domain_events", true, cfg.Domain.Publisher},
+		{"outbox", cfg.Outbox.Enabled, ""},
+		{"audit", len(cfg.Audit.Sinks) > 0, strings.Join(cfg.Audit.Sinks, ", ")},
+		{"search", true, index},
+		{"confirm", cfg.Confirm.Enabled, "ttl " + cfg.Confirm.TTL.String()},
+		{"trash", cfg.Trash.Window > 0, "window " + cfg.Trash.Window.String()},
+		{"notify", len(notified) > 0, strings.Join(notified, ", ")},
+		{"tenancy", cfg.Tenancy.Enabled, cfg.Tenancy.Isolation + " isolation by " + strings.Join(cfg.Tenancy.Sources, ", ")},
+		{"jobs", len(jobs) > 0, strings.Join(jobs, "; ")},
+		{"test_mode", cfg.TestMode.Enabled, ""},
+		{"mock", cfg.Mock, ""},
+	}
+}
+
+// location is a backend URL without its credentials and query, which may
+// carry some (e.g. ?password=).
+func location(s secret.Secret) string {
+	if s.Reveal() == "in-memory" {
+		return "in-memory"
+	}
+	u, err := url.Parse(s.Reveal())
+	if err != nil {
+		return s.String()
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
+
+// WriteJSON writes d as indented JSON.
+func (d Description) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// Log writes d to l as one record per listener, module, route and setting,
+// after a record of the environment and storage.
+func (d Description) Log(l *slog.Logger) {
+	l.Info("describe: server", "environment", d.Environment, "database", d.Storage.Database,
+		"cache", d.Storage.Cache, "rate_limit", d.Storage.RateLimit, "revocation", d.Storage.Revocation, "redis", d.Storage.Redis)
+	for _, ln := range d.Listeners {
+		l.Info("describe: listener", "protocol", ln.Protocol, "address", ln.Address)
+	}
+	for _, m := range d.Modules {
+		l.Info("describe: module", "name", m.Name, "enabled", m.Enabled, "detail", m.Detail)
+	}
+	for _, r := range d.Routes {
+		l.Info("describe: route", "method", r.Method, "path", r.Path, "auth", r.Auth)
+	}
+	keys := make([]string, 0, len(d.Config))
+	for k := range d.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		l.Info("describe: config", "key", k, "value", d.Config[k])
+	}
+}
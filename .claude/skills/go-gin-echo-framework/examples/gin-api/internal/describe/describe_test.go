@@ -0,0 +1,49 @@
+package describe
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/your-username/gin-api/config"
+	"github.com/your-username/gin-api/internal/openapi"
+)
+
+func TestNewDescribesRoutesAndStorageWithoutSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.URL = "postgres://app:hunter2@db:5432/app?sslmode=disable&password=hunter2"
+	cfg.Auth.JWTSecret = "hunter2"
+	cfg.GRPC.Multiplex = true
+
+	d, err := New(cfg, []openapi.Route{
+		{Method: "GET", Path: "/healthz"},
+		{Method: "GET", Path: "/admin/audit"},
+		{Method: "POST", Path: "/auth/login"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{
+		{"GET", "/admin/audit", "BearerAuth"},
+		{"POST", "/auth/login", "none"},
+		{"GET", "/healthz", Undocumented},
+	}
+	for i, r := range want {
+		if d.Routes[i] != r {
+			t.Errorf("route %d = %+v, want %+v", i, d.Routes[i], r)
+		}
+	}
+	if d.Storage.Database != "postgres://db:5432/app" {
+		t.Errorf("database %q, want the location without credentials", d.Storage.Database)
+	}
+	if len(d.Listeners) != 2 || d.Listeners[1] != (Listener{"grpc", ":8080"}) {
+		t.Errorf("listeners %+v, want gRPC multiplexed on the HTTP port", d.Listeners)
+	}
+
+	var out bytes.Buffer
+	if err := d.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out.Bytes(), []byte("hunter2")) {
+		t.Fatalf("description leaks a secret:\n%s", out.String())
+	}
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// InProc delivers events to handlers in the same process, synchronously
+// and in subscription order. It suits a single instance, or reactions that
+// must happen on the instance that made the change.
+type InProc struct {
+	mu       sync.RWMutex
+	handlers []func(ctx context.Context, e Event) error
+}
+
+func NewInProc() *InProc {
+	return &InProc{}
+}
+
+// Subscribe registers fn for the events of type E, e.g.
+//
+//	domain.Subscribe(p, func(ctx context.Context, e domain.UserCreated) error { ... })
+//
+// Subscribing to Event itself receives every event.
+func Subscribe[E Event](p *InProc, fn func(ctx context.Context, e E) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, func(ctx context.Context, e Event) error {
+		if typed, ok := e.(E); ok {
+			return fn(ctx, typed)
+		}
+		return nil
+	})
+}
+
+// Publish runs every matching handler, even after one fails, and returns
+// their errors combined.
+func (p *InProc) Publish(ctx context.Context, e Event) error {
+	p.mu.RLock()
+	handlers := p.handlers
+	p.mu.RUnlock()
+	var errs []error
+	for _, h := range handlers {
+		if err := h(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s handler: %w", e.EventName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
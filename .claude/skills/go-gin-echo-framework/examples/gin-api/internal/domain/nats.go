@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+	"github.com/your-username/gin-api/internal/lifecycle"
+)
+
+type natsPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher connects to the NATS server at url and publishes each
+// event as a Message on <prefix>.<event name>, e.g. gin-api.user.created,
+// so consumers can subscribe to gin-api.user.> or gin-api.*.deleted. The
+// connection reconnects on its own and is drained on shutdown.
+func NewNATSPublisher(lc *lifecycle.Manager, url, prefix string) (EventPublisher, error) {
+	conn, err := nats.Connect(url,
+		nats.Name(prefix+"-domain-events"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("WARNING: nats disconnected: %v", err)
+			}
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	lc.Register("nats publisher", 0, func(context.Context) error {
+		return conn.Drain()
+	})
+	return &natsPublisher{conn: conn, prefix: prefix}, nil
+}
+
+// Publish waits for the server to have received the message, so a lost
+// connection is reported rather than buffered.
+func (p *natsPublisher) Publish(ctx context.Context, e Event) error {
+	data, err := Encode(e)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", e.EventName(), err)
+	}
+	if err := p.conn.Publish(p.prefix+"."+e.EventName(), data); err != nil {
+		return fmt.Errorf("nats publish %s: %w", e.EventName(), err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("nats publish %s: %w", e.EventName(), err)
+	}
+	return nil
+}
+
+type natsConsumer struct {
+	conn           *nats.Conn
+	subject, queue string
+}
+
+// NewNATSConsumer subscribes to subject, e.g. gin-api.user.>, in the queue
+// group, so each message goes to one instance of the group. Core NATS
+// delivers at most once to a subscriber: a failing handler is retried with
+// a growing backoff while later messages wait in the subscription, but the
+// messages in flight when an instance stops are lost. Duplicates still
+// come from the outbox republishing after a crash. The connection is
+// drained by lc.
+func NewNATSConsumer(lc *lifecycle.Manager, url, subject, queue string) (Consumer, error) {
+	conn, err := nats.Connect(url, nats.Name(queue+"-consumer"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	lc.Register("nats consumer", 0, func(context.Context) error {
+		return conn.Drain()
+	})
+	return &natsConsumer{conn: conn, subject: subject, queue: queue}, nil
+}
+
+func (c *natsConsumer) Consume(ctx context.Context, fn MessageHandler) error {
+	sub, err := c.conn.QueueSubscribe(c.subject, c.queue, func(nm *nats.Msg) {
+		m, err := Decode(nm.Data)
+		if err != nil {
+			log.Printf("WARNING: nats consume: skipped undecodable message on %s: %v", nm.Subject, err)
+			return
+		}
+		retry(ctx, m, fn)
+	})
+	if err != nil {
+		return fmt.Errorf("nats subscribe %s: %w", c.subject, err)
+	}
+	<-ctx.Done()
+	return sub.Drain()
+}
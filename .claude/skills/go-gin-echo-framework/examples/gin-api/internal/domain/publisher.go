@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/lifecycle"
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// EventPublisher delivers domain events. The service layer publishes after
+// the write has committed, so an error cannot undo it; it is logged.
+type EventPublisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// TxPublisher is an EventPublisher that records events in the transaction
+// of the write itself, such as the transactional outbox (internal/outbox).
+// The service layer publishes to it before the commit, so the write and
+// its event are kept or rolled back together.
+type TxPublisher interface {
+	EventPublisher
+	PublishesInTransaction()
+}
+
+// publishTimeout bounds one publish to a broker.
+const publishTimeout = 5 * time.Second
+
+// Options select and configure the publisher.
+type Options struct {
+	Publisher    string        `yaml:"publisher"`     // inproc, nats or kafka
+	NATSURL      secret.Secret `yaml:"nats_url"`      // e.g. nats://localhost:4222
+	KafkaBrokers []string      `yaml:"kafka_brokers"` // host:port of at least one broker
+	Topic        string        `yaml:"topic"`         // NATS subject prefix (<topic>.<event name>) or Kafka topic
+}
+
+// Validate checks the settings the selected publisher needs.
+func (o Options) Validate() error {
+	var errs []error
+	switch o.Publisher {
+	case "inproc":
+	case "nats":
+		if u, err := url.Parse(o.NATSURL.Reveal()); err != nil || !oneOf(u.Scheme, "nats", "tls", "ws", "wss") || u.Host == "" {
+			errs = append(errs, errors.New("nats_url must be a nats://, tls://, ws:// or wss:// URL"))
+		}
+		if o.Topic == "" || strings.ContainsAny(o.Topic, " *>") {
+			errs = append(errs, errors.New("topic must be non-empty and contain no spaces or wildcards"))
+		}
+	case "kafka":
+		if len(o.KafkaBrokers) == 0 {
+			errs = append(errs, errors.New("kafka_brokers is required"))
+		}
+		if o.Topic == "" {
+			errs = append(errs, errors.New("topic is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("publisher must be inproc, nats or kafka (got %q)", o.Publisher))
+	}
+	return errors.Join(errs...)
+}
+
+// NewPublisher builds the publisher selected by o. Broker connections are
+// closed by lc; an unreachable broker is reported as an error rather than
+// silently falling back to in-process delivery.
+func NewPublisher(ctx context.Context, lc *lifecycle.Manager, o Options) (EventPublisher, error) {
+	switch o.Publisher {
+	case "nats":
+		return NewNATSPublisher(lc, o.NATSURL.Reveal(), o.Topic)
+	case "kafka":
+		return NewKafkaPublisher(ctx, lc, o.KafkaBrokers, o.Topic)
+	case "inproc", "":
+		return NewInProc(), nil
+	default:
+		return nil, errors.New("unknown domain event publisher: " + o.Publisher)
+	}
+}
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "github.com/your-username/gin-api/internal/model"
+
+// The events of the user resource.
+type (
+	UserCreated = Created[model.User]
+	UserUpdated = Updated[model.User]
+	UserDeleted = Deleted[model.User]
+)
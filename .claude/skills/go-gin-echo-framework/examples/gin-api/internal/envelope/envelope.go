@@ -0,0 +1,216 @@
+// Package envelope wraps JSON responses as {"data": ..., "meta": ..., "error": ...}
+// for legacy clients that expect it, without handlers knowing about it.
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Options decides which requests are enveloped.
+type Options struct {
+	Header        string   `yaml:"header"`         // request header that opts in with "true" or "1"; empty disables
+	VersionHeader string   `yaml:"version_header"` // request header carrying the client version
+	Versions      []string `yaml:"versions"`       // client versions that always get the envelope
+}
+
+// Wants reports whether r asked for the envelope.
+func (o Options) Wants(r *http.Request) bool {
+	if o.Header != "" {
+		if v := strings.ToLower(r.Header.Get(o.Header)); v == "true" || v == "1" {
+			return true
+		}
+	}
+	if o.VersionHeader != "" {
+		if v := r.Header.Get(o.VersionHeader); v != "" {
+			for _, want := range o.Versions {
+				if v == want {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Envelope is the wrapped response body. Exactly one of Data and Error is set.
+type Envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Meta  Meta            `json:"meta"`
+	Error *Error          `json:"error"`
+}
+
+type Meta struct {
+	Status int  `json:"status"`
+	Count  *int `json:"count,omitempty"` // number of items when data is a list
+}
+
+type Error struct {
+	Message string `json:"message"`
+}
+
+// Middleware envelopes the JSON responses of requests that opt in. options is
+// called per request so a reloaded configuration takes effect immediately.
+// Non-JSON, streamed (flushed or hijacked) and empty (e.g. 204) responses are
+// passed through unchanged.
+func Middleware(options func() Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !options().Wants(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &recorder{w: w, header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			rec.flush(w)
+		})
+	}
+}
+
+// Wrap builds the envelope for a JSON body. Error responses carry the
+// handler's {"error": ...} or {"message": ...} text as error.message.
+func Wrap(status int, body []byte) ([]byte, error) {
+	env := Envelope{Meta: Meta{Status: status}}
+	if status >= 400 {
+		var e struct {
+			Error   any `json:"error"`
+			Message any `json:"message"`
+		}
+		_ = json.Unmarshal(body, &e)
+		msg := firstString(e.Error, e.Message)
+		if msg == "" {
+			msg = http.StatusText(status)
+		}
+		env.Data = json.RawMessage("null")
+		env.Error = &Error{Message: msg}
+	} else {
+		if !json.Valid(body) {
+			return nil, errors.New("response body is not valid JSON")
+		}
+		env.Data = json.RawMessage(bytes.TrimSpace(body))
+		var list []json.RawMessage
+		if env.Data[0] == '[' && json.Unmarshal(env.Data, &list) == nil {
+			n := len(list)
+			env.Meta.Count = &n
+		}
+	}
+	out, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func firstString(vs ...any) string {
+	for _, v := range vs {
+		if s, ok := v.(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// recorder holds a JSON response so it can be wrapped, and writes any other
+// through to w as soon as its status is written.
+type recorder struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	through     bool // the response goes to w as is
+	body        bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	if r.through {
+		return r.w.Header()
+	}
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	if mediaType, _, _ := strings.Cut(r.header.Get("Content-Type"), ";"); strings.TrimSpace(mediaType) != "application/json" {
+		r.writeThrough()
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	if r.through {
+		return r.w.Write(b)
+	}
+	return r.body.Write(b)
+}
+
+// Flush sends what is held and the rest of the response unwrapped: a handler
+// that flushes streams, e.g. NDJSON or server-sent events.
+func (r *recorder) Flush() {
+	r.WriteHeader(http.StatusOK)
+	r.writeThrough()
+	if f, ok := r.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through.
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	r.through = true
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to lift the
+// write deadline for an event stream.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.w
+}
+
+// writeThrough writes the status, headers and held body to w, once; the rest
+// of the response follows them unbuffered.
+func (r *recorder) writeThrough() {
+	if r.through {
+		return
+	}
+	r.through = true
+	h := r.w.Header()
+	for k, v := range r.header {
+		h[k] = v
+	}
+	r.w.WriteHeader(r.status)
+	r.w.Write(r.body.Bytes())
+	r.body.Reset()
+}
+
+func (r *recorder) flush(w http.ResponseWriter) {
+	if r.through {
+		return
+	}
+	body := r.body.Bytes()
+	mediaType, _, _ := strings.Cut(r.header.Get("Content-Type"), ";")
+	if strings.TrimSpace(mediaType) == "application/json" && len(body) > 0 {
+		if wrapped, err := Wrap(r.status, body); err == nil {
+			body = wrapped
+		}
+	}
+	h := w.Header()
+	for k, v := range r.header {
+		h[k] = v
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(r.status)
+	w.Write(body)
+}
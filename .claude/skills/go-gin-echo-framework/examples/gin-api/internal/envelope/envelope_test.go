@@ -0,0 +1,105 @@
+package envelope
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusOK, `{"id":"1"}`, `{"data":{"id":"1"},"meta":{"status":200},"error":null}`},
+		{http.StatusOK, `[{"id":"1"},{"id":"2"}]` + "\n", `{"data":[{"id":"1"},{"id":"2"}],"meta":{"status":200,"count":2},"error":null}`},
+		{http.StatusNotFound, `{"error":"User not found"}`, `{"data":null,"meta":{"status":404},"error":{"message":"User not found"}}`},
+		{http.StatusBadRequest, `{"message":"bad"}`, `{"data":null,"meta":{"status":400},"error":{"message":"bad"}}`},
+		{http.StatusInternalServerError, `oops`, `{"data":null,"meta":{"status":500},"error":{"message":"Internal Server Error"}}`},
+	}
+	for _, tt := range tests {
+		got, err := Wrap(tt.status, []byte(tt.body))
+		if err != nil || string(got) != tt.want+"\n" {
+			t.Errorf("Wrap(%d, %s) = %s, %v, want %s", tt.status, tt.body, got, err, tt.want)
+		}
+	}
+	if _, err := Wrap(http.StatusOK, []byte(`{"id":`)); err == nil {
+		t.Error("Wrap of invalid JSON succeeded")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	opts := func() Options { return Options{Header: "X-Response-Envelope"} }
+	tests := []struct {
+		name        string
+		optIn       bool
+		contentType string
+		lines       []string
+		flush       bool
+		want        string
+	}{
+		{"json", true, "application/json; charset=utf-8", []string{`{"id":"1"}`}, false, `{"data":{"id":"1"},"meta":{"status":201},"error":null}` + "\n"},
+		{"not asked", false, "application/json", []string{`{"id":"1"}`}, false, `{"id":"1"}`},
+		{"text", true, "text/plain", []string{"hello"}, false, "hello"},
+		{"ndjson stream", true, "application/x-ndjson", []string{"{}\n", "{}\n", "{}\n"}, true, "{}\n{}\n{}\n"},
+		{"flushed json", true, "application/json", []string{"[", "{}", "]"}, true, "[{}]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				for _, line := range tt.lines {
+					w.Write([]byte(line))
+					if tt.flush {
+						w.(http.Flusher).Flush()
+					}
+				}
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.optIn {
+				req.Header.Set("X-Response-Envelope", "true")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != http.StatusCreated || w.Body.String() != tt.want {
+				t.Errorf("got %d %q, want %q", w.Code, w.Body, tt.want)
+			}
+			if w.Flushed != tt.flush {
+				t.Errorf("flushed = %v", w.Flushed)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q", got)
+			}
+		})
+	}
+}
+
+// hijacker is a ResponseWriter whose connection can be taken over.
+type hijacker struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	h := Middleware(func() Options { return Options{Header: "X-Response-Envelope"} })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("X-Response-Envelope", "1")
+	w := &hijacker{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, req)
+	if !w.hijacked || w.Body.Len() != 0 {
+		t.Errorf("hijacked = %v, body %q", w.hijacked, w.Body)
+	}
+}
@@ -0,0 +1,189 @@
+// Package events fans entity changes out to live subscribers, such as the
+// Server-Sent Events streams of the handlers. The service layer publishes to
+// a Bus once a write has committed; unlike the change feed (internal/changes)
+// the bus keeps no history, so a subscriber sees only what happens while it
+// is subscribed and catches up through the sync endpoints.
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// Event is one committed create, update or delete.
+type Event struct {
+	Seq      uint64     `json:"seq"`      // assigned by the bus, increasing
+	Resource string     `json:"resource"` // singular resource name, e.g. "user"
+	Op       changes.Op `json:"op"`
+	ID       string     `json:"id"`
+	Data     any        `json:"data,omitempty"` // the entity as written; none for deletes
+	At       time.Time  `json:"at"`
+	Tenant   string     `json:"tenant,omitempty"` // the tenant written to; none without tenancy
+}
+
+// Filter selects the events a subscriber receives. Empty fields match
+// everything, except Tenant: a subscriber only ever sees the events of its
+// own tenant, or only untenanted events if it has none.
+type Filter struct {
+	Resource string
+	Ops      []changes.Op
+	IDs      []string
+	Tenant   string
+}
+
+// ParseFilter builds a Filter for resource from comma-separated op and id
+// lists, as given in query parameters.
+func ParseFilter(resource, ops, ids string) (Filter, error) {
+	f := Filter{Resource: resource, IDs: split(ids)}
+	for _, op := range split(ops) {
+		switch o := changes.Op(op); o {
+		case changes.OpCreated, changes.OpUpdated, changes.OpDeleted:
+			f.Ops = append(f.Ops, o)
+		default:
+			return Filter{}, fmt.Errorf("unknown op %q; use created, updated or deleted", op)
+		}
+	}
+	return f, nil
+}
+
+func split(list string) []string {
+	var out []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Match reports whether e passes f.
+func (f Filter) Match(e Event) bool {
+	if e.Tenant != f.Tenant || f.Resource != "" && e.Resource != f.Resource {
+		return false
+	}
+	return contains(f.Ops, e.Op) && contains(f.IDs, e.ID)
+}
+
+func contains[T comparable](set []T, v T) bool {
+	if len(set) == 0 {
+		return true
+	}
+	for _, s := range set {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus delivers published events to every matching subscription. Publish
+// never blocks: a subscriber whose buffer is full is dropped, so one slow
+// client cannot hold up writes or other clients.
+type Bus struct {
+	mu     sync.Mutex
+	seq    uint64
+	subs   map[*Subscription]struct{}
+	closed bool
+	clock  clock.Clock
+}
+
+// NewBus returns a Bus that stamps events, and times the heartbeats of
+// their streams, by clk (nil is the system clock).
+func NewBus(clk clock.Clock) *Bus {
+	return &Bus{subs: map[*Subscription]struct{}{}, clock: clock.OrSystem(clk)}
+}
+
+// Subscription receives the events matching its filter on C until it is
+// closed, by Close, by the bus shutting down or for falling behind.
+type Subscription struct {
+	C <-chan Event
+
+	c       chan Event
+	filter  Filter
+	bus     *Bus
+	dropped bool // set under bus.mu
+}
+
+// Subscribe returns a subscription buffering up to buffer events.
+func (b *Bus) Subscribe(f Filter, buffer int) *Subscription {
+	c := make(chan Event, buffer)
+	s := &Subscription{C: c, c: c, filter: f, bus: b}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Publish stamps e with the next sequence number and the current time and
+// delivers it.
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.seq++
+	e.Seq = b.seq
+	e.At = b.clock.Now().UTC()
+	for s := range b.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			s.dropped = true
+			b.remove(s)
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close ends every subscription and ignores later publishes. It is used on
+// shutdown so event streams finish instead of holding the server open.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.remove(s)
+	}
+}
+
+// remove must be called with b.mu held.
+func (b *Bus) remove(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+// Close unsubscribes; C is closed.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// Dropped reports whether the bus closed the subscription because its
+// buffer was full. Only meaningful once C is closed.
+func (s *Subscription) Dropped() bool {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,264 @@
+// Package export runs exports of collections asynchronously, in the
+// request-reply pattern: Request records a pending export and returns at
+// once, a background job (Run) writes the file of each pending one to a
+// blob store, and Get reports an export's status, with a signed URL of its
+// file once it is done, to callers polling for it.
+//
+// Exports are held in process, so poll the instance that accepted one;
+// their files outlive a restart in the blob store until deleted by hand.
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/blob"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/render"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Status is the stage an export is at.
+type Status string
+
+const (
+	Pending Status = "pending" // waiting for the job
+	Running Status = "running"
+	Done    Status = "done"   // its file can be downloaded
+	Failed  Status = "failed" // see Export.Error
+)
+
+// Formats are the file formats of exports, by name.
+var Formats = map[string]render.Format{"csv": render.CSV, "json": render.JSON}
+
+var (
+	// ErrNotFound is returned by Get for an export that does not exist, or
+	// not in the caller's tenant, or no longer.
+	ErrNotFound = errors.New("export not found")
+	// ErrInvalid is matched by the errors of Request with an unknown
+	// collection or format.
+	ErrInvalid = errors.New("invalid export")
+	// ErrBusy is matched by the error of Request when Options.MaxPending
+	// exports of the tenant are already waiting.
+	ErrBusy = errors.New("too many exports waiting")
+)
+
+// Options configure how many exports wait and how long they are kept.
+type Options struct {
+	MaxPending int           `yaml:"max_pending"` // exports of a tenant waiting for the job; more are refused
+	Retention  time.Duration `yaml:"retention"`   // how long finished exports and their files are kept
+}
+
+// Validate checks the limits.
+func (o Options) Validate() error {
+	var errs []error
+	if o.MaxPending < 1 {
+		errs = append(errs, errors.New("max_pending must be at least 1"))
+	}
+	if o.Retention <= 0 {
+		errs = append(errs, errors.New("retention must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// Export is a requested export of a collection and how far it got.
+type Export struct {
+	ID         string     `json:"id"`
+	Collection string     `json:"collection"`
+	Format     string     `json:"format"`
+	Status     Status     `json:"status"`
+	Items      int        `json:"items"` // in the file, once done
+	Error      string     `json:"error,omitempty"`
+	URL        string     `json:"url,omitempty"`        // downloads the file, once done
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // of URL
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	tenant string
+	key    string // of the file in the blob store
+}
+
+// Source lists the items of a collection, in the tenant of ctx, as a slice
+// that render encodes.
+type Source func(ctx context.Context) (items any, n int, err error)
+
+// Stream adapts the Stream method of a service to a Source.
+func Stream[T any](stream func(ctx context.Context, fn func(item T) error) error) Source {
+	return func(ctx context.Context) (any, int, error) {
+		items := []T{}
+		err := stream(ctx, func(item T) error {
+			items = append(items, item)
+			return nil
+		})
+		return items, len(items), err
+	}
+}
+
+// Exporter keeps the exports of every tenant and writes their files.
+type Exporter struct {
+	store   blob.Store
+	clock   clock.Clock
+	ids     idgen.Generator
+	opts    Options
+	urlTTL  time.Duration
+	sources map[string]Source
+
+	mu      sync.Mutex
+	exports map[string]*Export
+}
+
+// New returns an Exporter of the collections of sources, by name, whose
+// files are written to store and downloaded by URLs valid for urlTTL.
+func New(store blob.Store, clk clock.Clock, ids idgen.Generator, opts Options, urlTTL time.Duration, sources map[string]Source) *Exporter {
+	return &Exporter{
+		store:   store,
+		clock:   clock.OrSystem(clk),
+		ids:     idgen.OrDefault(ids),
+		opts:    opts,
+		urlTTL:  urlTTL,
+		sources: sources,
+		exports: map[string]*Export{},
+	}
+}
+
+// Request records a pending export of collection in format, in the tenant
+// of ctx, for Run to write.
+func (x *Exporter) Request(ctx context.Context, collection, format string) (Export, error) {
+	if _, ok := x.sources[collection]; !ok {
+		return Export{}, fmt.Errorf("%w: %s cannot be exported", ErrInvalid, collection)
+	}
+	if _, ok := Formats[format]; !ok {
+		return Export{}, fmt.Errorf("%w: format must be csv or json", ErrInvalid)
+	}
+	t := tenant.From(ctx)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	waiting := 0
+	for _, e := range x.exports {
+		if e.tenant == t && (e.Status == Pending || e.Status == Running) {
+			waiting++
+		}
+	}
+	if waiting >= x.opts.MaxPending {
+		return Export{}, fmt.Errorf("%w: %d of at most %d; poll them until they finish", ErrBusy, waiting, x.opts.MaxPending)
+	}
+	e := &Export{
+		ID:         x.ids.NewID(),
+		Collection: collection,
+		Format:     format,
+		Status:     Pending,
+		CreatedAt:  x.clock.Now().UTC(),
+		tenant:     t,
+	}
+	e.key = path.Join("exports", t, e.ID+"."+format)
+	x.exports[e.ID] = e
+	return *e, nil
+}
+
+// Get returns the export of the tenant of ctx with the given ID, with a
+// freshly signed URL of its file once it is done.
+func (x *Exporter) Get(ctx context.Context, id string) (Export, error) {
+	x.mu.Lock()
+	e, ok := x.exports[id]
+	if !ok || e.tenant != tenant.From(ctx) {
+		x.mu.Unlock()
+		return Export{}, ErrNotFound
+	}
+	out := *e
+	x.mu.Unlock()
+	if out.Status != Done {
+		return out, nil
+	}
+	expires := x.clock.Now().Add(x.urlTTL).UTC().Truncate(time.Second)
+	url, err := x.store.SignURL(out.key, expires)
+	if err != nil {
+		return Export{}, err
+	}
+	out.URL, out.ExpiresAt = url, &expires
+	return out, nil
+}
+
+// Run writes the files of the pending exports, oldest first, and forgets
+// the exports finished longer than Options.Retention ago, deleting their
+// files. It is the job that exports run in; an export that fails is
+// reported as failed rather than retried.
+func (x *Exporter) Run(ctx context.Context) error {
+	var errs []error
+	for _, e := range x.due() {
+		if err := x.write(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("export %s: %w", e.ID, err))
+		}
+	}
+	return errors.Join(append(errs, x.purge(ctx))...)
+}
+
+// due marks the pending exports running and returns them, oldest first.
+func (x *Exporter) due() []Export {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var out []Export
+	for _, e := range x.exports {
+		if e.Status == Pending {
+			e.Status = Running
+			out = append(out, *e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// write writes the file of e and records how that went.
+func (x *Exporter) write(ctx context.Context, e Export) error {
+	err := func() error {
+		items, n, err := x.sources[e.Collection](tenant.With(ctx, e.tenant))
+		if err != nil {
+			return err
+		}
+		format := Formats[e.Format]
+		var buf bytes.Buffer
+		if err := format.Encode(&buf, items); err != nil {
+			return err
+		}
+		e.Items = n
+		return x.store.Put(ctx, e.key, &buf, int64(buf.Len()), format.MediaType)
+	}()
+
+	now := x.clock.Now().UTC()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	stored := x.exports[e.ID]
+	stored.Status, stored.Items, stored.FinishedAt = Done, e.Items, &now
+	if err != nil {
+		stored.Status, stored.Items, stored.Error = Failed, 0, err.Error()
+	}
+	return err
+}
+
+// purge forgets the exports finished before the retention and deletes
+// their files.
+func (x *Exporter) purge(ctx context.Context) error {
+	cutoff := x.clock.Now().Add(-x.opts.Retention)
+	x.mu.Lock()
+	var expired []*Export
+	for id, e := range x.exports {
+		if e.FinishedAt != nil && e.FinishedAt.Before(cutoff) {
+			expired = append(expired, e)
+			delete(x.exports, id)
+		}
+	}
+	x.mu.Unlock()
+	var errs []error
+	for _, e := range expired {
+		if e.Status == Done {
+			errs = append(errs, x.store.Delete(ctx, e.key))
+		}
+	}
+	return errors.Join(errs...)
+}
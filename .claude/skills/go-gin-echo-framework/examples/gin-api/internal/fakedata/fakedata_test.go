@@ -0,0 +1,84 @@
+package fakedata
+
+import (
+	"math"
+	"regexp"
+	"testing"
+)
+
+func TestFakerIsDeterministic(t *testing.T) {
+	a, b := New(42, "en-US"), New(42, "en-US")
+	for i := 0; i < 50; i++ {
+		if pa, pb := a.Person(), b.Person(); pa != pb {
+			t.Fatalf("draw %d: %+v != %+v", i, pa, pb)
+		}
+		if a.SKU() != b.SKU() || a.Price() != b.Price() || a.ID() != b.ID() {
+			t.Fatalf("draw %d differs", i)
+		}
+	}
+	if New(1, "en-US").ID() == New(2, "en-US").ID() {
+		t.Error("different seeds drew the same ID")
+	}
+}
+
+func TestLocaleFor(t *testing.T) {
+	for tags, want := range map[string]string{
+		"de-DE":                  "de-DE",
+		"DE-de":                  "de-DE",
+		"de-AT":                  "de-DE",
+		"pt-BR, fr;q=0.8":        "fr-FR",
+		"en-GB,ja-JP;q=0.9,ja":   "ja-JP", // an exact match beats a language match
+		"pt-BR":                  DefaultLocale,
+		"":                       DefaultLocale,
+		"ja;q=0.9, *;q=0.1":      "ja-JP",
+		"en-US;q=0.5, de-DE;q=1": "en-US", // q values are not weighed
+	} {
+		if got := LocaleFor(tags).Tag; got != want {
+			t.Errorf("LocaleFor(%q) = %s, want %s", tags, got, want)
+		}
+	}
+}
+
+var (
+	emailPattern = regexp.MustCompile(`^[a-z]+\.[a-z]+[0-9]*@example\.(com|net|org)$`)
+	skuPattern   = regexp.MustCompile(`^[A-Z]{3}-[A-Z]+-[0-9]{5}$`)
+)
+
+func TestValuesAreValidInEveryLocale(t *testing.T) {
+	for tag, locale := range Locales {
+		f := New(7, tag)
+		emails := map[string]bool{}
+		for i := 0; i < 500; i++ {
+			p := f.Person()
+			if !emailPattern.MatchString(p.Email) || emails[p.Email] {
+				t.Fatalf("%s: email %q is not a unique ASCII address", tag, p.Email)
+			}
+			emails[p.Email] = true
+			if p.Name == "" || p.FirstName == "" || p.LastName == "" {
+				t.Fatalf("%s: person %+v", tag, p)
+			}
+
+			if sku := f.SKU(); !skuPattern.MatchString(sku) {
+				t.Fatalf("%s: SKU %q", tag, sku)
+			}
+			price := f.Price()
+			if price < 1 || price > locale.MaxPrice {
+				t.Fatalf("%s: price %v outside (1, %v]", tag, price, locale.MaxPrice)
+			}
+			if scaled := price * math.Pow(10, float64(locale.Decimals)); math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+				t.Fatalf("%s: price %v has more than %d decimals", tag, price, locale.Decimals)
+			}
+		}
+	}
+}
+
+func TestNamesFollowTheLocale(t *testing.T) {
+	p := New(3, "ja-JP").Person()
+	if p.Name != p.LastName+" "+p.FirstName {
+		t.Errorf("ja-JP name %q, want family name first", p.Name)
+	}
+	p = New(3, "de-DE").Person()
+	if p.Name != p.FirstName+" "+p.LastName {
+		t.Errorf("de-DE name %q, want given name first", p.Name)
+	}
+}
@@ -0,0 +1,18 @@
+package fakedata
+
+import "github.com/your-username/gin-api/internal/model"
+
+// User returns a user with a generated ID and a unique email.
+func (f *Faker) User() model.User {
+	p := f.Person()
+	return model.User{ID: f.ID(), Name: p.Name, Email: p.Email}
+}
+
+// Users returns n users.
+func (f *Faker) Users(n int) []model.User {
+	out := make([]model.User, n)
+	for i := range out {
+		out[i] = f.User()
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package fault
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInjector(t *testing.T) {
+	var in Injector
+	down := errors.New("down")
+	slow := errors.New("slow")
+	if err := in.Check("Get"); err != nil {
+		t.Fatalf("zero Injector: %v", err)
+	}
+
+	in.FailNext(Any, 1, slow)
+	in.Fail("Get", down)
+	if err := in.Check("Get"); err != slow {
+		t.Fatalf("first Get = %v, want the rule added first", err)
+	}
+	for range 2 {
+		if err := in.Check("Get"); err != down {
+			t.Fatalf("later Get = %v, want %v", err, down)
+		}
+	}
+	if err := in.Check("Create"); err != nil {
+		t.Fatalf("Create = %v, want nil once FailNext is used up", err)
+	}
+	if in.Calls("Get") != 4 || in.Calls("Create") != 1 {
+		t.Fatalf("Calls = %d Get, %d Create", in.Calls("Get"), in.Calls("Create"))
+	}
+
+	in.Reset()
+	if err := in.Check("Get"); err != nil || in.Calls("Get") != 1 {
+		t.Fatalf("after Reset: %v, %d calls", err, in.Calls("Get"))
+	}
+}
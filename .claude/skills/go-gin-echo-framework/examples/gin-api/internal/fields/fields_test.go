@@ -0,0 +1,134 @@
+package fields
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type line struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+type Audited struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type order struct {
+	ID       string    `json:"id"`
+	Note     string    `json:"note,omitempty"`
+	Ship     address   `json:"ship"`
+	Bill     *address  `json:"bill"`
+	Lines    []line    `json:"lines"`
+	PlacedAt time.Time `json:"placed_at"`
+	secret   string
+	Audited
+}
+
+var sample = order{
+	ID:       "o-1",
+	Ship:     address{Street: "1 Main St", City: "Springfield"},
+	Bill:     &address{Street: "2 Side St", City: "Shelbyville"},
+	Lines:    []line{{SKU: "lamp", Quantity: 2}, {SKU: "fan", Quantity: 1}},
+	PlacedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	secret:   "hidden",
+	Audited:  Audited{CreatedBy: "user-1"},
+}
+
+func project(t *testing.T, v any, fields string) string {
+	t.Helper()
+	set, err := Parse(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Project(v, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      any
+		fields string
+		want   string
+	}{
+		{"top level in declaration order", sample, "placed_at,id", `{"id":"o-1","placed_at":"2026-01-02T03:04:05Z"}`},
+		{"tag options kept", sample, "id,note", `{"id":"o-1"}`},
+		{"nested struct", sample, "ship.city", `{"ship":{"city":"Springfield"}}`},
+		{"nested pointer", sample, "bill.street", `{"bill":{"street":"2 Side St"}}`},
+		{"nil nested pointer", order{}, "bill.street", `{"bill":null}`},
+		{"slice of structs", sample, "lines.sku", `{"lines":[{"sku":"lamp"},{"sku":"fan"}]}`},
+		{"whole field wins", sample, "ship.city,ship", `{"ship":{"street":"1 Main St","city":"Springfield"}}`},
+		{"promoted from an embedded struct", sample, "created_by", `{"created_by":"user-1"}`},
+		{"pointer", &sample, "id", `{"id":"o-1"}`},
+		{"slice", []order{sample, {ID: "o-2"}}, "id,ship.city", `[{"id":"o-1","ship":{"city":"Springfield"}},{"id":"o-2","ship":{"city":""}}]`},
+		{"empty selects everything", line{SKU: "lamp"}, "", `{"sku":"lamp","quantity":0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := project(t, tt.v, tt.fields); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsFieldsTheTypeDoesNotHave(t *testing.T) {
+	tests := map[string]string{
+		"secret,zip":     "fields: unknown field secret, zip",
+		"ship.zip":       "fields: unknown field ship.zip",
+		"lines.sku.code": "fields: lines.sku has no fields to select",
+		"placed_at.year": "fields: placed_at has no fields to select",
+		"Ship":           "fields: unknown field Ship",
+	}
+	for fields, want := range tests {
+		set, err := Parse(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(reflect.TypeFor[order](), set); err == nil || err.Error() != want {
+			t.Errorf("%s: got %v, want %s", fields, err, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	set, err := Parse(" id, ship.city,ship.street,lines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := set.String(), "id,lines,ship.city,ship.street"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	for _, bad := range []string{"id,", "ship..city", ".id"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+func TestProjectionIsCachedPerTypeAndSet(t *testing.T) {
+	set, _ := Parse("id")
+	a, err := New(reflect.TypeFor[order](), set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := New(reflect.TypeFor[order](), Set{"id": nil})
+	if a.p != b.p {
+		t.Error("equal sets compiled twice")
+	}
+}
@@ -0,0 +1,202 @@
+// Package fixtures seeds the repositories with demo data in development.
+// Each collection is seeded from <name>.yaml, <name>.yml or <name>.json in
+// a directory, a list of items as the API encodes them:
+//
+//	# users.yaml
+//	- id: ada
+//	  name: Ada Lovelace
+//	  email: ada@example.com
+//	- name: Grace Hopper # given an ID of the configured strategy
+//	  email: grace@example.com
+//
+// IDs missing from the files are drawn from a generator with a fixed seed
+// and clock, so they are the same on every run and demos can link to them.
+// Items go through the services, whichever repository backend they use,
+// so seeding runs their hooks and is audited and published like any other
+// write.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// seed and epoch fix the IDs given to items without one.
+const seed = 1
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// extensions are those of seed files, in order of preference.
+var extensions = []string{".yaml", ".yml", ".json"}
+
+// Result counts what Load or Reset did with the items of one collection.
+type Result struct {
+	Collection  string `json:"collection"`
+	Created     int    `json:"created"`
+	Skipped     int    `json:"skipped"`
+	Overwritten int    `json:"overwritten"`
+	Deleted     int    `json:"deleted"`
+}
+
+// Collection is a collection fixtures seed; see Resource.
+type Collection struct {
+	Name string // plural resource name, e.g. users
+	seed func(ctx context.Context, items []json.RawMessage, ids idgen.Generator, validate func(any) error, reset bool, r *Result) error
+}
+
+// Resource is the collection of a CRUD resource, written through svc.
+func Resource[T model.Entity, P model.EntityPtr[T]](name string, svc service.CrudService[T]) Collection {
+	return Collection{
+		Name: name,
+		seed: func(ctx context.Context, items []json.RawMessage, ids idgen.Generator, validate func(any) error, reset bool, r *Result) error {
+			seeded := map[string]bool{}
+			for i, data := range items {
+				var item T
+				dec := json.NewDecoder(bytes.NewReader(data))
+				dec.DisallowUnknownFields()
+				if err := dec.Decode(&item); err != nil {
+					return fmt.Errorf("item %d: %w", i+1, err)
+				}
+				if P(&item).GetID() == "" {
+					P(&item).SetID(ids.NewID())
+				}
+				if err := validate(&item); err != nil {
+					return fmt.Errorf("item %d: %w", i+1, err)
+				}
+				id := P(&item).GetID()
+				seeded[id] = true
+				_, err := svc.GetByID(ctx, id)
+				switch {
+				case errors.Is(err, service.ErrNotFound):
+					_, err = svc.Create(ctx, &item)
+					r.Created++
+				case err != nil:
+				case reset:
+					_, err = svc.Update(ctx, &item)
+					r.Overwritten++
+				default:
+					r.Skipped++
+				}
+				if err != nil {
+					return fmt.Errorf("item %d: %w", i+1, err)
+				}
+			}
+			if !reset {
+				return nil
+			}
+			var extra []string
+			err := svc.Stream(ctx, func(item T) error {
+				if !seeded[item.GetID()] {
+					extra = append(extra, item.GetID())
+				}
+				return nil
+			})
+			if err != nil || len(extra) == 0 {
+				return err
+			}
+			r.Deleted = len(extra)
+			return svc.DeleteMany(ctx, extra)
+		},
+	}
+}
+
+// Fixtures seeds collections from the files of a directory.
+type Fixtures struct {
+	fsys        fs.FS
+	uow         repository.UnitOfWork
+	validate    func(item any) error
+	strategy    string
+	collections []Collection
+}
+
+// New seeds collections from the files of fsys, which Load and Reset read
+// anew. Items are validated by validate and given missing IDs of strategy
+// (see idgen.New).
+func New(fsys fs.FS, uow repository.UnitOfWork, validate func(item any) error, strategy string, collections ...Collection) *Fixtures {
+	return &Fixtures{fsys: fsys, uow: uow, validate: validate, strategy: strategy, collections: collections}
+}
+
+// Load creates the seed items that do not exist and leaves the others as
+// they are, e.g. at startup.
+func (f *Fixtures) Load(ctx context.Context) ([]Result, error) {
+	return f.seed(ctx, false)
+}
+
+// Reset restores the seed state: it overwrites the seed items, creates the
+// missing ones and deletes every other item of the seeded collections, in
+// one transaction. Deleted items go to the trash like any other.
+func (f *Fixtures) Reset(ctx context.Context) ([]Result, error) {
+	return f.seed(ctx, true)
+}
+
+func (f *Fixtures) seed(ctx context.Context, reset bool) ([]Result, error) {
+	ids, err := idgen.NewSeeded(f.strategy, clock.NewFake(epoch), seed)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	err = f.uow.Do(ctx, func(ctx context.Context) error {
+		results = results[:0]
+		for _, c := range f.collections {
+			items, err := f.read(c.Name)
+			if err != nil {
+				return err
+			}
+			if items == nil {
+				continue // not seeded
+			}
+			r := Result{Collection: c.Name}
+			if err := c.seed(ctx, items, ids, f.validate, reset, &r); err != nil {
+				return fmt.Errorf("fixtures of %s: %w", c.Name, err)
+			}
+			results = append(results, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// read returns the items of the seed file of collection, or nil if it has
+// none.
+func (f *Fixtures) read(collection string) ([]json.RawMessage, error) {
+	for _, ext := range extensions {
+		data, err := fs.ReadFile(f.fsys, collection+ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// JSON is YAML too; decoding both as YAML and encoding each item
+		// as JSON lets the items decode by their json tags
+		var items []any
+		if err := yaml.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", collection, ext, err)
+		}
+		raw := make([]json.RawMessage, 0, len(items))
+		for i, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s%s: item %d: %w", collection, ext, i+1, err)
+			}
+			raw = append(raw, data)
+		}
+		return raw, nil
+	}
+	return nil, nil
+}
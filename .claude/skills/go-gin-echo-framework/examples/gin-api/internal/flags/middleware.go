@@ -0,0 +1,51 @@
+package flags
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Require answers 403 on the routes of a feature that flag name turns off
+// for the request, e.g. by the plan of its tenant.
+func Require(s *Set, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.Check(c.Request.Context(), name); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Middleware evaluates every flag for each request, by its caller and
+// tenant, into its context for Enabled, so the request sees the flags as
+// they were when it started whatever toggles and reloads land meanwhile.
+// It runs after authentication and tenant resolution.
+func Middleware(s *Set) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		c.Request = c.Request.WithContext(With(ctx, s.Evaluate(ctx)))
+		c.Next()
+	}
+}
+
+type contextKey struct{}
+
+// With returns ctx carrying the flags evaluated for a request, by name.
+// Middleware sets them; tests of services set them directly.
+func With(ctx context.Context, evaluated map[string]bool) context.Context {
+	return context.WithValue(ctx, contextKey{}, evaluated)
+}
+
+// Enabled reports whether flag name is on for the request of ctx, as
+// Middleware evaluated it, e.g. in a service:
+//
+//	if flags.Enabled(ctx, "new-pricing") { ... }
+//
+// It is false outside Middleware and for undeclared flags.
+func Enabled(ctx context.Context, name string) bool {
+	evaluated, _ := ctx.Value(contextKey{}).(map[string]bool)
+	return evaluated[name]
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// countingService counts the lookups that reach the service.
+type countingService struct {
+	service.UserService
+	gets, batches atomic.Int32
+}
+
+func (s *countingService) GetByID(ctx context.Context, id string) (*model.User, error) {
+	s.gets.Add(1)
+	return s.UserService.GetByID(ctx, id)
+}
+
+func (s *countingService) GetByIDs(ctx context.Context, ids []string) ([]model.User, error) {
+	s.batches.Add(1)
+	return s.UserService.GetByIDs(ctx, ids)
+}
+
+func newUsers(t *testing.T) *countingService {
+	t.Helper()
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewSQLRepository[model.User](db, dialect, "users", "user")
+	return &countingService{UserService: service.NewUserService(repo, repository.NewSQLUnitOfWork(db), nil, nil, nil, nil, nil, nil)}
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// do posts query to h, as caller if not nil.
+func do(t *testing.T, h http.Handler, caller *auth.Caller, query string) response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if caller != nil {
+		req = req.WithContext(auth.WithCaller(req.Context(), caller))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%d %s: %v", w.Code, w.Body, err)
+	}
+	return resp
+}
+
+func TestUserLookupsAreBatched(t *testing.T) {
+	users := newUsers(t)
+	for _, u := range []model.User{{ID: "u1", Name: "Ann"}, {ID: "u2", Name: "Bob"}} {
+		if _, err := users.Create(context.Background(), &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewHandler(users)
+
+	resp := do(t, h, nil, `{ a: user(id: "u1") { name } b: user(id: "u2") { name } c: user(id: "u3") { name } again: user(id: "u1") { id } }`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %+v", resp.Errors)
+	}
+	got := string(resp.Data["a"]) + string(resp.Data["b"]) + string(resp.Data["c"]) + string(resp.Data["again"])
+	if got != `{"name":"Ann"}{"name":"Bob"}null{"id":"u1"}` {
+		t.Fatalf("data %s", got)
+	}
+	if gets, batches := users.gets.Load(), users.batches.Load(); gets != 0 || batches != 1 {
+		t.Fatalf("%d GetByID and %d GetByIDs calls, want one batch", gets, batches)
+	}
+}
+
+func TestMutationsRequireACaller(t *testing.T) {
+	h := NewHandler(newUsers(t))
+	create := `mutation { createUser(input: {name: " Ann ", email: "ANN@example.com"}) { id name email } }`
+
+	resp := do(t, h, nil, create)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "UNAUTHENTICATED" {
+		t.Fatalf("anonymous createUser: %+v", resp.Errors)
+	}
+
+	caller := &auth.Caller{Subject: "account-1", Method: auth.MethodJWT}
+	resp = do(t, h, caller, create)
+	var created model.User
+	if err := json.Unmarshal(resp.Data["createUser"], &created); err != nil || len(resp.Errors) > 0 {
+		t.Fatalf("createUser: %v %+v", err, resp.Errors)
+	}
+	if created.ID == "" || created.Name != "Ann" || created.Email != "ann@example.com" {
+		t.Fatalf("created %+v, want it normalized by the service", created)
+	}
+
+	resp = do(t, h, caller, `mutation { createUser(input: {name: "Bob", email: "not an email"}) { id } }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "BAD_USER_INPUT" {
+		t.Fatalf("invalid createUser: %+v", resp.Errors)
+	}
+	resp = do(t, h, caller, `mutation { deleteUser(id: "missing") }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Fatalf("deleteUser of a missing user: %+v", resp.Errors)
+	}
+}
@@ -0,0 +1,9 @@
+package graph
+
+import "github.com/your-username/gin-api/internal/service"
+
+// Resolver holds the services the resolvers call. gqlgen generates this
+// file once and leaves it alone afterwards.
+type Resolver struct {
+	users service.UserService
+}
@@ -0,0 +1,60 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/your-username/gin-api/internal/model"
+)
+
+// CreateUser is the resolver for the createUser field.
+func (r *mutationResolver) CreateUser(ctx context.Context, input UserInput) (*model.User, error) {
+	user, err := userFromInput("", input)
+	if err != nil {
+		return nil, err
+	}
+	return r.users.Create(ctx, user)
+}
+
+// UpdateUser is the resolver for the updateUser field.
+func (r *mutationResolver) UpdateUser(ctx context.Context, id string, input UserInput) (*model.User, error) {
+	user, err := userFromInput(id, input)
+	if err != nil {
+		return nil, err
+	}
+	return r.users.Update(ctx, user)
+}
+
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
+	if err := r.users.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context) ([]model.User, error) {
+	users, err := r.users.GetAll(ctx)
+	if users == nil && err == nil {
+		users = []model.User{}
+	}
+	return users, err
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error) {
+	return loadersFrom(ctx).user.Load(ctx, id)
+}
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -0,0 +1,27 @@
+package grpcapi
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Multiplex serves s and next on one port: HTTP/2 requests with a gRPC
+// content type go to s, everything else to next. HTTP/2 is accepted in
+// plaintext (h2c) so that gRPC clients connect without TLS; HTTP/1.1
+// clients see no difference.
+//
+// The server's read and write timeouts apply to gRPC calls as well, which
+// cuts long-lived streams short; leave them unset, or use a port of its
+// own, for streaming clients.
+func Multiplex(s *Server, next http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			s.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
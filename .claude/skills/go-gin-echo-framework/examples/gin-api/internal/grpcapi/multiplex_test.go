@@ -0,0 +1,49 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestMultiplexServesGRPCAndHTTPOnOnePort(t *testing.T) {
+	s := New()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	rest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "rest "+r.URL.Path)
+	})
+	srv := httptest.NewServer(Multiplex(s, rest))
+	defer srv.Close()
+	defer s.Stop()
+
+	resp, err := http.Get(srv.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "rest /users" {
+		t.Fatalf("HTTP got %q", body)
+	}
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(srv.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	status, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("gRPC health is %v", status.Status)
+	}
+}
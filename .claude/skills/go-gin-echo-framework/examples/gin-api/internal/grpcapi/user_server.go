@@ -0,0 +1,106 @@
+package grpcapi
+
+import (
+	"errors"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/mapping"
+	usersv1 "github.com/your-username/gin-api/internal/pb/users/v1"
+	"github.com/your-username/gin-api/internal/service"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+type UserServer struct {
+	usersv1.UnimplementedUserServiceServer
+	userService service.UserService
+	feed        *changes.Feed
+}
+
+func NewUserServer(userService service.UserService, feed *changes.Feed) *UserServer {
+	return &UserServer{userService: userService, feed: feed}
+}
+
+// ListUsers streams users in ID order. Send blocks while the client's
+// receive window is full, so the loop advances at the consumer's pace and
+// stops as soon as the client goes away.
+func (s *UserServer) ListUsers(req *usersv1.ListUsersRequest, stream usersv1.UserService_ListUsersServer) error {
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+
+	ctx := stream.Context()
+	users, err := s.userService.GetAll(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list users: %v", err)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if after := req.GetAfterId(); after != "" {
+		i := sort.Search(len(users), func(i int) bool { return users[i].ID > after })
+		users = users[i:]
+	}
+
+	for start := 0; start < len(users); start += size {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		page := users[start:min(start+size, len(users))]
+		msg := &usersv1.ListUsersResponse{Users: mapping.UsersToProto(page), LastId: page[len(page)-1].ID}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchUsers long-polls the change feed and forwards each batch until the
+// client disconnects or the feed is closed on shutdown. Only the changes of
+// the tenant of the call are forwarded.
+func (s *UserServer) WatchUsers(req *usersv1.WatchUsersRequest, stream usersv1.UserService_WatchUsersServer) error {
+	ctx := stream.Context()
+	token := req.GetToken()
+	if token == "" {
+		token = s.feed.Token()
+	}
+	for {
+		pending, next, err := s.feed.Wait(ctx, tenant.From(ctx), token)
+		switch {
+		case errors.Is(err, changes.ErrTokenExpired):
+			return status.Error(codes.FailedPrecondition, "change token expired; relist and watch from a fresh token")
+		case errors.Is(err, changes.ErrInvalidToken):
+			return status.Error(codes.InvalidArgument, "invalid change token")
+		case err != nil:
+			return status.Errorf(codes.Internal, "failed to watch users: %v", err)
+		}
+		// Wait has no deadline here, so an empty result means the client left or the feed closed.
+		if len(pending) == 0 {
+			if err := ctx.Err(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+			return nil
+		}
+
+		msgs, err := mapping.ChangesToProto(pending)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(&usersv1.WatchUsersResponse{Changes: msgs, Token: next}); err != nil {
+			return err
+		}
+		token = next
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+)
+
+// AccountsHandler lets admins manage the accounts that log in. Mount it
+// behind auth.RequireAdmin.
+type AccountsHandler struct {
+	accounts auth.AccountService
+}
+
+func NewAccountsHandler(accounts auth.AccountService) *AccountsHandler {
+	return &AccountsHandler{accounts: accounts}
+}
+
+// Register mounts the accounts on g itself, an account on /:id and its
+// unlock on /:id/unlock.
+func (h *AccountsHandler) Register(g *gin.RouterGroup) {
+	g.GET("", h.List)
+	g.DELETE("/:id", h.Delete)
+	g.POST("/:id/unlock", h.Unlock)
+}
+
+// @Summary List accounts
+// @Description Lists the accounts that log in, of every tenant, by email, with their roles (auth.roles), API keys and lockout.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} auth.AccountInfo
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/accounts [get]
+func (h *AccountsHandler) List(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	accounts, err := h.accounts.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+// @Summary Unlock an account
+// @Description Lifts the lockout of an account after too many failed logins and resets its count of failures.
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} auth.AccountInfo
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/accounts/{id}/unlock [post]
+func (h *AccountsHandler) Unlock(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	account, err := h.accounts.Unlock(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// @Summary Delete an account
+// @Description Removes the logins of an account, its password and external identities, with its API keys. Its sessions cannot be refreshed, so it is locked out once its access tokens expire, within auth.token_ttl. The user it registered as is kept.
+// @Tags Admin
+// @Param id path string true "Account ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/accounts/{id} [delete]
+func (h *AccountsHandler) Delete(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	if err := h.accounts.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AccountsHandler) fail(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrAccountNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// APIKeyHandler lets a logged-in account manage the API keys its machine
+// clients authenticate with. Mount it behind auth.Identify and auth.Require.
+type APIKeyHandler struct {
+	keys auth.APIKeyService
+}
+
+func NewAPIKeyHandler(keys auth.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"` // what the key is for, e.g. "ci"
+}
+
+// Register mounts GET /, POST / and DELETE /:id on g.
+func (h *APIKeyHandler) Register(g *gin.RouterGroup) {
+	g.GET("/", h.List)
+	g.POST("/", h.Create)
+	g.DELETE("/:id", h.Delete)
+}
+
+// @Summary List API keys
+// @Description Lists the calling account's API keys. Secrets are never returned after creation.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} auth.APIKeyInfo
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	owner, ok := sessionOwner(c, "API keys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	keys, err := h.keys.List(c.Request.Context(), owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// @Summary Create an API key
+// @Description Creates an API key for the calling account. The returned `key` is shown only once; send it in the X-API-Key header. An account holds at most auth.max_api_keys keys, see GET /usage.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param key body handler.CreateAPIKeyRequest true "Key name"
+// @Success 201 {object} auth.CreatedAPIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 402 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	owner, ok := sessionOwner(c, "API keys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := h.keys.Create(c.Request.Context(), owner, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrQuota) {
+			// At auth.max_api_keys already
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// @Summary Delete an API key
+// @Description Deletes one of the calling account's API keys; it stops working immediately.
+// @Tags Auth
+// @Param id path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/api-keys/{id} [delete]
+func (h *APIKeyHandler) Delete(c *gin.Context) {
+	owner, ok := sessionOwner(c, "API keys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := h.keys.Delete(c.Request.Context(), owner, c.Param("id")); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// sessionOwner returns the calling account, which manages what with a
+// login session only, so a leaked key cannot be used to mint more keys or
+// add sign-ins of its own. Guests have not logged in.
+func sessionOwner(c *gin.Context, what string) (string, bool) {
+	caller := auth.CallerFromContext(c.Request.Context())
+	if caller == nil || caller.Method != auth.MethodJWT || caller.Guest {
+		c.JSON(http.StatusForbidden, gin.H{"error": what + " can only be managed with the bearer access token of a login"})
+		return "", false
+	}
+	return caller.Subject, true
+}
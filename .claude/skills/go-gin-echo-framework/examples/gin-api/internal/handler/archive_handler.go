@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/archive"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// ArchiveHandler exports every collection to a portable archive and imports
+// one back (see package archive). Mount it behind auth.RequireAdmin, in a
+// route group that accepts archive.MediaType bodies.
+type ArchiveHandler struct {
+	archive *archive.Archive
+}
+
+func NewArchiveHandler(a *archive.Archive) *ArchiveHandler {
+	return &ArchiveHandler{archive: a}
+}
+
+// Register mounts GET /export and POST /import on g.
+func (h *ArchiveHandler) Register(g *gin.RouterGroup) {
+	g.GET("/export", h.Export)
+	g.POST("/import", h.Import)
+}
+
+// @Summary Export all collections
+// @Description Downloads a zip of one NDJSON file per collection, e.g. users.ndjson, and manifest.json listing them with the schema version and count of their items. Items are read as they are written, so a failure midway can only cut the archive short, which makes it unreadable by import.
+// @Tags Admin
+// @Produce zip
+// @Success 200 "Zip archive"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /export [get]
+func (h *ArchiveHandler) Export(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	c.Header("Content-Type", archive.MediaType)
+	c.Header("Content-Disposition", `attachment; filename="export.zip"`)
+	c.Status(http.StatusOK)
+	if err := h.archive.Export(c.Request.Context(), c.Writer); err != nil && c.Request.Context().Err() == nil {
+		log.Printf("export: stopped early: %v", err)
+	}
+}
+
+// @Summary Import collections
+// @Description Writes the items of an archive made by GET /export, possibly on another deployment or database backend, all or none. Collections at a schema version newer than the server's are refused. Items whose ID exists are handled by conflict: skip keeps the existing item, overwrite replaces it, merge updates the fields the imported item has. Imported items are validated, audited and published like any other write.
+// @Tags Admin
+// @Accept zip
+// @Produce json
+// @Param archive body string true "Zip archive"
+// @Param conflict query string false "skip (default), overwrite or merge"
+// @Success 200 {array} archive.Result
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /import [post]
+func (h *ArchiveHandler) Import(c *gin.Context) {
+	if !checkQuery(c, "conflict") {
+		return
+	}
+	strategy := archive.Strategy(c.DefaultQuery("conflict", string(archive.Skip)))
+	if !slices.Contains(archive.Strategies, strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("conflict must be one of %v", archive.Strategies)})
+		return
+	}
+	// A zip is read from its end, so the archive is held in memory, up to
+	// the group's max_body_bytes
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.archive.Import(c.Request.Context(), bytes.NewReader(data), int64(len(data)), strategy)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalid) || errors.Is(err, service.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
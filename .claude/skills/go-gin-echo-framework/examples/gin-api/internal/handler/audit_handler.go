@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/audit"
+)
+
+// AuditHandler lists the audit trail of writes. Mount it behind
+// auth.RequireAdmin.
+type AuditHandler struct {
+	rec *audit.Recorder
+}
+
+func NewAuditHandler(rec *audit.Recorder) *AuditHandler {
+	return &AuditHandler{rec: rec}
+}
+
+// Register mounts the audit trail on g itself.
+func (h *AuditHandler) Register(g *gin.RouterGroup) {
+	g.GET("", h.List)
+}
+
+// @Summary List audit entries
+// @Description Lists the recorded creates, updates and deletes, newest first, with who made them, in which request, and the item before and after. Every filter given must match.
+// @Tags Admin
+// @Produce json
+// @Param resource query string false "Singular resource name, e.g. user"
+// @Param subject query string false "ID of the item written"
+// @Param actor query string false "Caller as in access logs, e.g. an account ID"
+// @Param limit query int false "Entries to return, at most 1000 (default 100)"
+// @Success 200 {array} audit.Entry
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func (h *AuditHandler) List(c *gin.Context) {
+	if !checkQuery(c, "resource", "subject", "actor", "limit") {
+		return
+	}
+	q := audit.Query{Resource: c.Query("resource"), SubjectID: c.Query("subject"), Actor: c.Query("actor")}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > audit.MaxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number between 1 and 1000"})
+			return
+		}
+		q.Limit = n
+	}
+	entries, err := h.rec.List(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, audit.ErrNoReader) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
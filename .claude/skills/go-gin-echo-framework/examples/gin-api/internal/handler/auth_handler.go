@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+type AuthHandler struct {
+	auth auth.AuthService
+}
+
+func NewAuthHandler(authService auth.AuthService) *AuthHandler {
+	return &AuthHandler{auth: authService}
+}
+
+// RegisterRequest creates a user together with its password login.
+type RegisterRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// MFALoginRequest completes a login that answered 401 with an mfa_token.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6,numeric"` // shown by the authenticator app
+}
+
+// RefreshRequest carries the refresh token of the previous login or refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Register mounts POST /register, /login, /login/mfa, /guest, /refresh and
+// /logout on g.
+func (h *AuthHandler) Register(g *gin.RouterGroup) {
+	g.POST("/register", h.SignUp)
+	g.POST("/login", h.Login)
+	g.POST("/login/mfa", h.LoginMFA)
+	g.POST("/guest", h.StartGuest)
+	g.POST("/refresh", h.Refresh)
+	g.POST("/logout", h.Logout)
+}
+
+// @Summary Register an account
+// @Description Creates a user and its password login. The password is stored as a bcrypt hash. Refused with 403 while `auth.login_provider` is `ldap`, as the directory holds the accounts.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param account body handler.RegisterRequest true "Name, email and password (8 to 72 bytes)"
+// @Success 201 {object} auth.Account
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/register [post]
+func (h *AuthHandler) SignUp(c *gin.Context) {
+	var req RegisterRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	account, err := h.auth.Register(c.Request.Context(), req.Email, req.Password, req.Name)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, account)
+}
+
+// @Summary Log in
+// @Description Exchanges an email and password for a JWT access token. After `auth.max_failed_logins` consecutive wrong passwords the account is locked for `auth.lockout_duration`; Retry-After gives the seconds left. An account its identity provider deactivated over SCIM gets 403. With `auth.login_provider: ldap` the email is the directory login instead, checked by binding as the user it finds, and the token carries the roles of the user's groups (`auth.ldap.roles`); 502 means the directory could not be reached. An account with an authenticator app gets 401 with an `mfa_token` instead, to send with a code of the app to /auth/login/mfa within 5 minutes.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body handler.LoginRequest true "Email (or directory login) and password"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 423 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security none
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	token, err := h.auth.Authenticate(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// @Summary Complete a login with a one-time code
+// @Description Exchanges the `mfa_token` of a password login and the current code of the account's authenticator app for a JWT access token. The token is good for one attempt: after a wrong code, log in with the password again. Each code is accepted once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param login body handler.MFALoginRequest true "MFA token of the login and a code of the authenticator app"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	token, err := h.auth.CompleteMFA(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// @Summary Start a guest session
+// @Description Starts the session of a guest, who uses the API without registering: what it creates is owned by it (`owner_id`) until an account claims it at /auth/guest/claim with the guest's access token, which ends the session. Guests refresh like any session but cannot manage API keys, passkeys or authenticator apps.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.Token
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/guest [post]
+func (h *AuthHandler) StartGuest(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	token, err := h.auth.StartGuest(c.Request.Context())
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// @Summary Refresh an access token
+// @Description Exchanges a refresh token for a new access and refresh token pair. Each refresh token is single use: presenting one again revokes every token issued since the login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body handler.RefreshRequest true "Refresh token from the login or the previous refresh"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	token, err := h.auth.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// @Summary Log out
+// @Description Revokes the refresh token's whole token family, including access tokens that have not expired yet.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body handler.RefreshRequest true "Current refresh token"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.auth.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) fail(c *gin.Context, err error) {
+	var locked *auth.LockedError
+	var mfa *auth.MFARequiredError
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(locked.Until).Seconds())+1))
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	case errors.As(err, &mfa):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "mfa_token": mfa.Token})
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrAccountDisabled), errors.Is(err, auth.ErrRegistrationClosed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrProviderUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrInvalidPassword), errors.Is(err, service.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/auth/authtest"
+)
+
+func TestAuthHandlerMapsServiceErrors(t *testing.T) {
+	accounts := authtest.NewFake(nil)
+	do := serve(t, NewAuthHandler(accounts).Register)
+	register := `{"name": "Ada", "email": "ada@example.com", "password": "correct horse"}`
+
+	if w := do(http.MethodPost, "/register", register); w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/register", register); w.Code != http.StatusConflict {
+		t.Errorf("register twice: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/login", `{"email": "ada@example.com", "password": "wrong horse"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password: %d %s", w.Code, w.Body)
+	}
+	accounts.Faults.Fail("Authenticate", errors.New("credentials unavailable"))
+	if w := do(http.MethodPost, "/login", `{"email": "ada@example.com", "password": "correct horse"}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("login with a failing service: %d %s", w.Code, w.Body)
+	}
+}
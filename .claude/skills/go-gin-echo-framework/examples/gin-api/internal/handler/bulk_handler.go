@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/confirm"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// maxBulk caps the IDs of one bulk operation.
+const maxBulk = 100
+
+// BulkHandler serves operations on many users at once. Being easy to get
+// wrong and hard to undo, each is confirmed in two calls (see package
+// confirm) unless confirmations is nil.
+type BulkHandler struct {
+	userService   service.UserService
+	confirmations *confirm.Store
+}
+
+func NewBulkHandler(userService service.UserService, confirmations *confirm.Store) *BulkHandler {
+	return &BulkHandler{
+		userService:   userService,
+		confirmations: confirmations,
+	}
+}
+
+// @Summary Delete users
+// @Description Deletes the users with the given IDs, all or none. Unless confirmations are disabled, a call without confirm deletes nothing and answers with the users it would delete and a token; repeating it with that token as confirm before the token expires deletes them. A token confirms only the call it was issued for, by the same caller, once.
+// @Tags User
+// @Produce json
+// @Param ids query string true "Comma-separated IDs, at most 100"
+// @Param confirm query string false "Token of the first call"
+// @Success 200 {object} confirm.Challenge[model.User]
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /users [delete]
+func (h *BulkHandler) DeleteUsers(c *gin.Context) {
+	if !checkQuery(c, "ids", "confirm") {
+		return
+	}
+	deleteMany(c, h.userService, h.confirmations, "users")
+}
+
+// deleteMany deletes the items of svc named by the ids query parameter,
+// plural naming them, in two calls when confirmations is not nil.
+func deleteMany[T model.Entity](c *gin.Context, svc service.CrudService[T], confirmations *confirm.Store, plural string) {
+	ids, err := idList(c.Query("ids"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	if confirmations != nil {
+		// The token is bound to the operation, its IDs and the caller
+		key := "delete " + plural + " " + strings.Join(ids, ",")
+		if caller := auth.CallerFromContext(ctx); caller != nil {
+			key += " by " + caller.String()
+		}
+		token := c.Query("confirm")
+		if token == "" {
+			items, err := svc.GetByIDs(ctx, ids)
+			if err != nil {
+				c.JSON(statusOf(err), gin.H{"error": err.Error()})
+				return
+			}
+			if missing := missingIDs(ids, items); len(missing) > 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found: " + strings.Join(missing, ", ")})
+				return
+			}
+			token, expires := confirmations.Issue(key)
+			c.JSON(http.StatusOK, confirm.Challenge[T]{
+				Operation: fmt.Sprintf("delete %d %s", len(items), plural),
+				Affected:  items,
+				Token:     token,
+				ExpiresAt: expires,
+			})
+			return
+		}
+		if err := confirmations.Redeem(token, key); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := svc.DeleteMany(ctx, ids); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "some of the " + plural + " were not found"})
+			return
+		}
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// idList parses a comma-separated list of IDs into a sorted set of at most
+// maxBulk.
+func idList(s string) ([]string, error) {
+	if s == "" {
+		return nil, errors.New("ids is required")
+	}
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			return nil, errors.New("ids must not contain empty IDs")
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) > maxBulk {
+		return nil, fmt.Errorf("ids must name at most %d items", maxBulk)
+	}
+	return ids, nil
+}
+
+// missingIDs returns the ids without an item in items.
+func missingIDs[T model.Entity](ids []string, items []T) []string {
+	found := make(map[string]bool, len(items))
+	for _, item := range items {
+		found[item.GetID()] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
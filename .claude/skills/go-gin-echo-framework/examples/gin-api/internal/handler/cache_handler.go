@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/cache"
+)
+
+// CacheHandler lets admins flush the read-through cache of the
+// repositories. Mount it behind auth.RequireAdmin; with a nil store (the
+// cache disabled) it answers 501.
+type CacheHandler struct {
+	store cache.Store
+}
+
+func NewCacheHandler(store cache.Store) *CacheHandler {
+	return &CacheHandler{store: store}
+}
+
+// Register mounts the flush on /flush.
+func (h *CacheHandler) Register(g *gin.RouterGroup) {
+	g.POST("/flush", h.Flush)
+}
+
+// @Summary Flush the cache
+// @Description Drops every entry of the repositories' cache, e.g. after changing the database by hand; reads fill it again. Answers with the number of entries dropped.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/cache/flush [post]
+func (h *CacheHandler) Flush(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	f, ok := h.store.(cache.Flusher)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "the cache is not enabled"})
+		return
+	}
+	n, err := f.Flush(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"flushed": n})
+}
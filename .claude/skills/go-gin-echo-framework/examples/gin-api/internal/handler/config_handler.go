@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler shows admins the configuration being served. Mount it
+// behind auth.RequireAdmin.
+type ConfigHandler struct {
+	current func() map[string]string
+}
+
+// NewConfigHandler serves what current returns, the flattened, redacted
+// configuration, e.g. of config.Watcher.Current().Redacted.
+func NewConfigHandler(current func() map[string]string) *ConfigHandler {
+	return &ConfigHandler{current: current}
+}
+
+// Register mounts the configuration on g itself.
+func (h *ConfigHandler) Register(g *gin.RouterGroup) {
+	g.GET("", h.Show)
+}
+
+// @Summary Show the configuration
+// @Description The configuration in effect, as reloaded from the config file, flattened into dotted keys such as server.port, with every secret redacted. Settings read only on startup may differ until a restart.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/config [get]
+func (h *ConfigHandler) Show(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.current())
+}
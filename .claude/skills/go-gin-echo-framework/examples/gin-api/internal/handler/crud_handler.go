@@ -0,0 +1,433 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/your-username/gin-api/internal/compat"
+	"github.com/your-username/gin-api/internal/custom"
+	"github.com/your-username/gin-api/internal/fields"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/render"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// CrudHandler serves the standard REST routes for one resource on top of a
+// CrudService. Document a resource with a `@Resource <path> <model>`
+// annotation on its constructor for each path it is mounted on, next to
+// @Security for its auth requirement; internal/openapi expands them into the
+// eight operations below.
+type CrudHandler[T model.Entity, P model.EntityPtr[T]] struct {
+	service service.CrudService[T]
+	name    string // display name used in error messages, e.g. "User"
+}
+
+func NewCrudHandler[T model.Entity, P model.EntityPtr[T]](svc service.CrudService[T], name string) *CrudHandler[T, P] {
+	return &CrudHandler[T, P]{
+		service: svc,
+		name:    name,
+	}
+}
+
+// streamFlush is how many items Stream writes between flushes.
+const streamFlush = 100
+
+// Register mounts GET /, GET /stream, GET /schema, GET /:id, POST /,
+// PUT /:id, DELETE /:id and POST /:id/undo-delete on g.
+func (h *CrudHandler[T, P]) Register(g *gin.RouterGroup) {
+	g.GET("/", h.List)
+	g.GET("/stream", h.Stream)
+	g.GET("/schema", h.Schema)
+	g.GET("/:id", h.Get)
+	g.POST("/", h.Create)
+	g.PUT("/:id", h.Update)
+	g.DELETE("/:id", h.Delete)
+	g.POST("/:id/undo-delete", h.UndoDelete)
+}
+
+func (h *CrudHandler[T, P]) List(c *gin.Context) {
+	filter, ok := h.listQuery(c)
+	if !ok {
+		return
+	}
+	proj, ok := h.projection(c)
+	if !ok {
+		return
+	}
+	format, ok := negotiate(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	items, err := h.service.GetAll(ctx)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	items = keep(items, filter)
+	if notModified(c, lastModified(items, h.service.LastDelete())) {
+		return
+	}
+	write(c, format, proj.Apply(items))
+}
+
+// Stream lists every item as NDJSON, writing each as it is read from the
+// repository so memory use does not grow with the list. Without the whole
+// list there is no Last-Modified; a failure after the first item can only
+// end the stream early, and is logged.
+func (h *CrudHandler[T, P]) Stream(c *gin.Context) {
+	filter, ok := h.listQuery(c)
+	if !ok {
+		return
+	}
+	proj, ok := h.projection(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	enc := json.NewEncoder(c.Writer)
+	n := 0
+	err := h.service.Stream(ctx, func(item T) error {
+		if !matchesCustom(item, filter) {
+			return nil
+		}
+		if n == 0 {
+			c.Header("Content-Type", render.NDJSON.MediaType+"; charset=utf-8")
+			c.Status(http.StatusOK)
+		}
+		if err := enc.Encode(proj.Apply(item)); err != nil {
+			return err
+		}
+		if n++; n%streamFlush == 0 {
+			c.Writer.Flush()
+		}
+		return ctx.Err() // the client went away
+	})
+	switch {
+	case err != nil && n == 0:
+		h.fail(c, err)
+	case err != nil:
+		if ctx.Err() == nil {
+			log.Printf("stream %s list: stopped after %d items: %v", h.name, n, err)
+		}
+	case n == 0:
+		c.Data(http.StatusOK, render.NDJSON.MediaType+"; charset=utf-8", nil)
+	}
+}
+
+// Schema returns the custom fields of the items in the caller's scope, by
+// name; none unless the service is service.CustomFielded.
+func (h *CrudHandler[T, P]) Schema(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.customFields(c.Request.Context()))
+}
+
+func (h *CrudHandler[T, P]) Get(c *gin.Context) {
+	if !checkQuery(c, "fields") {
+		return
+	}
+	proj, ok := h.projection(c)
+	if !ok {
+		return
+	}
+	format, ok := negotiate(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	ctx := c.Request.Context()
+	item, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if notModified(c, lastModified([]T{*item}, time.Time{})) {
+		return
+	}
+	write(c, format, proj.Apply(item))
+}
+
+func (h *CrudHandler[T, P]) Create(c *gin.Context) {
+	var item T
+	if !checkQuery(c, "dry_run") || !dryRun(c) {
+		return
+	}
+	if err := bindJSON(c, &item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if item.GetID() != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errClientID.Error()})
+		return
+	}
+	model.KeepReadOnly(&item, new(T))
+
+	ctx := c.Request.Context()
+	created, err := h.service.Create(ctx, &item)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// Update decodes the body onto the stored item, so that the fields it
+// leaves out keep their values.
+func (h *CrudHandler[T, P]) Update(c *gin.Context) {
+	id := c.Param("id")
+	if !checkQuery(c, "dry_run") || !dryRun(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	stored, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	item := *stored
+	if err := bindJSON(c, &item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	model.KeepReadOnly(&item, stored) // the ID from the path among them
+
+	updated, err := h.service.Update(ctx, &item)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *CrudHandler[T, P]) Delete(c *gin.Context) {
+	if !checkQuery(c, "dry_run") || !dryRun(c) {
+		return
+	}
+	id := c.Param("id")
+	ctx := c.Request.Context()
+	if err := h.service.Delete(ctx, id); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UndoDelete restores an item deleted within the trash window, answering
+// 404 once the window has passed.
+func (h *CrudHandler[T, P]) UndoDelete(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	restored, err := h.service.Restore(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, restored)
+}
+
+func (h *CrudHandler[T, P]) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": h.name + " not found"})
+	case errors.Is(err, service.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+	}
+}
+
+// statusOf is the status of a service error that the calling handler has no
+// more specific status for: 403 across tenants, 402 beyond a quota, e.g.
+// of a tenant's plan, 500 otherwise.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrQuota):
+		return http.StatusPaymentRequired
+	}
+	return http.StatusInternalServerError
+}
+
+// customFields returns the custom fields of the items in the scope of ctx.
+func (h *CrudHandler[T, P]) customFields(ctx context.Context) custom.Schema {
+	if c, ok := h.service.(service.CustomFielded); ok {
+		if schema := c.CustomFields(ctx); schema != nil {
+			return schema
+		}
+	}
+	return custom.Schema{}
+}
+
+// listQuery checks the query of a list, which may select fields and filter
+// on custom fields (custom.<name>=<value>), answering 400 when a filter's
+// value is not one of its field's.
+func (h *CrudHandler[T, P]) listQuery(c *gin.Context) (custom.Filter, bool) {
+	schema := h.customFields(c.Request.Context())
+	if !checkQuery(c, append([]string{"fields"}, schema.Params()...)...) {
+		return nil, false
+	}
+	filter, err := custom.ParseFilter(schema, c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return filter, true
+}
+
+// keep returns the items matching filter.
+func keep[T any](items []T, filter custom.Filter) []T {
+	if len(filter) == 0 {
+		return items
+	}
+	kept := []T{}
+	for _, item := range items {
+		if matchesCustom(item, filter) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// matchesCustom reports whether the custom fields of item match filter.
+func matchesCustom[T any](item T, filter custom.Filter) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	e, ok := any(item).(model.Extensible)
+	return ok && filter.Match(e.CustomFields())
+}
+
+// projection compiles the fields query parameter (see package fields) for
+// T, answering 400 when it is malformed or names a field T does not have.
+func (h *CrudHandler[T, P]) projection(c *gin.Context) (*fields.Projection, bool) {
+	set, err := fields.Parse(c.Query("fields"))
+	var proj *fields.Projection
+	if err == nil {
+		proj, err = fields.New(reflect.TypeFor[T](), set)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return proj, true
+}
+
+// negotiate picks the response format from the Accept header (see
+// render.Default), answering 406 when none is acceptable.
+func negotiate(c *gin.Context) (render.Format, bool) {
+	c.Writer.Header().Add("Vary", "Accept")
+	format, ok := render.Default.Negotiate(c.GetHeader("Accept"))
+	if !ok {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "supported media types: " + strings.Join(render.Default.MediaTypes(), ", ")})
+	}
+	return format, ok
+}
+
+// write sends v with status 200 in format. JSON goes through gin's renderer
+// like every other response; an encoding error after the header is sent can
+// only be logged.
+func write(c *gin.Context, format render.Format, v any) {
+	if format.MediaType == render.JSON.MediaType {
+		c.JSON(http.StatusOK, v)
+		return
+	}
+	c.Header("Content-Type", format.MediaType+"; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := format.Encode(c.Writer, v); err != nil {
+		log.Printf("render %s: %v", format.MediaType, err)
+	}
+}
+
+// lastModified is the newest modification time of items and deleted, or
+// zero when T does not record one (see model.Timestamped).
+func lastModified[T any](items []T, deleted time.Time) time.Time {
+	if _, ok := any(new(T)).(model.Timestamped); !ok {
+		return time.Time{}
+	}
+	latest := deleted
+	for _, item := range items {
+		if t := any(item).(model.Timestamped).LastModified(); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// notModified sets Last-Modified and reports whether the request's
+// If-Modified-Since makes the body unnecessary, having answered 304 if so.
+// HTTP dates have a resolution of one second. As RFC 9110 prescribes, the
+// date is ignored when the request also carries If-None-Match.
+func notModified(c *gin.Context, modified time.Time) bool {
+	if modified.IsZero() {
+		return false
+	}
+	modified = modified.UTC().Truncate(time.Second)
+	c.Header("Last-Modified", modified.Format(http.TimeFormat))
+	if c.GetHeader("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil || modified.After(since) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// bindJSON decodes the request body into dst under the request's
+// compatibility mode, then applies the binding validation rules.
+func bindJSON(c *gin.Context, dst any) error {
+	if err := compat.DecodeRequest(c.Request, dst); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(dst)
+}
+
+// checkQuery rejects query parameters other than allowed in strict mode
+// (logging them in lenient mode) and reports whether the handler may go on.
+func checkQuery(c *gin.Context, allowed ...string) bool {
+	if err := compat.CheckQuery(c.Request, allowed...); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// dryRun puts the request in dry-run mode (see service.WithDryRun) when the
+// dry_run query parameter or else the X-Dry-Run header is true, marking the
+// response with X-Dry-Run, and reports whether the handler may go on; a
+// value that is not a boolean is answered with 400.
+func dryRun(c *gin.Context) bool {
+	v := c.Query("dry_run")
+	if v == "" {
+		v = c.GetHeader("X-Dry-Run")
+	}
+	if v == "" {
+		return true
+	}
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return false
+	}
+	if on {
+		c.Request = c.Request.WithContext(service.WithDryRun(c.Request.Context()))
+		c.Header("X-Dry-Run", "true")
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/service/servicetest"
+)
+
+func serve(t *testing.T, register func(g *gin.RouterGroup)) func(method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	register(router.Group(""))
+	return func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func TestCrudHandlerMapsServiceErrors(t *testing.T) {
+	users := servicetest.NewFake[model.User](nil)
+	users.Seed(model.User{ID: "u1", Name: "Ada"})
+	do := serve(t, NewCrudHandler[model.User](users, "user").Register)
+
+	if w := do(http.MethodGet, "/u1", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Ada"`) {
+		t.Errorf("GET /u1: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/", `{"name": "Grace"}`); w.Code != http.StatusCreated || users.Faults.Calls("Create") != 1 {
+		t.Errorf("POST /: %d %s", w.Code, w.Body)
+	}
+
+	users.Faults.FailNext("GetAll", 1, errors.New("connection refused"))
+	if w := do(http.MethodGet, "/", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("GET / with a failing service: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/", ""); w.Code != http.StatusOK {
+		t.Errorf("GET / after the failure: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCrudHandlerUpdateKeepsFieldsTheBodyLeavesOut(t *testing.T) {
+	users := servicetest.NewFake[model.User](nil)
+	users.Seed(model.User{ID: "u1", Name: "Ada", Email: "ada@example.com", Custom: model.Attributes{"team": "math"}})
+	do := serve(t, NewCrudHandler[model.User](users, "user").Register)
+
+	if w := do(http.MethodPut, "/u1", `{"name": "Ada Lovelace"}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT /u1: %d %s", w.Code, w.Body)
+	}
+	if u, _ := users.GetByID(context.Background(), "u1"); u.Name != "Ada Lovelace" || u.Email != "ada@example.com" || u.Custom["team"] != "math" {
+		t.Errorf("after leaving out email and custom: %+v", u)
+	}
+	if w := do(http.MethodPut, "/u1", `{"name": "Ada", "custom": {}}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT /u1: %d %s", w.Code, w.Body)
+	}
+	if u, _ := users.GetByID(context.Background(), "u1"); u.Email != "ada@example.com" || len(u.Custom) != 0 {
+		t.Errorf("after clearing custom: %+v", u)
+	}
+	if w := do(http.MethodPut, "/missing", `{"name": "Grace"}`); w.Code != http.StatusNotFound {
+		t.Errorf("PUT /missing: %d %s", w.Code, w.Body)
+	}
+}
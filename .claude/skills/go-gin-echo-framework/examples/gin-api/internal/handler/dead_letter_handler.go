@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/outbox"
+)
+
+// maxDeadLetters caps the dead letters of one list.
+const maxDeadLetters = 1000
+
+// DeadLetterHandler lets admins inspect the outbox events set aside after
+// their last failed publish, then requeue or discard them. Mount it behind
+// auth.RequireAdmin; with a nil relay (the outbox disabled) it answers 501.
+type DeadLetterHandler struct {
+	relay *outbox.Relay
+}
+
+func NewDeadLetterHandler(relay *outbox.Relay) *DeadLetterHandler {
+	return &DeadLetterHandler{relay: relay}
+}
+
+// Register mounts the dead letters on g itself and their requeue on
+// /requeue.
+func (h *DeadLetterHandler) Register(g *gin.RouterGroup) {
+	g.GET("", h.List)
+	g.DELETE("", h.Discard)
+	g.POST("/requeue", h.Requeue)
+}
+
+// DeadLetterIDs is the request of a requeue.
+type DeadLetterIDs struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=1000"`
+}
+
+// enabled answers 501 and returns false if the outbox is disabled.
+func (h *DeadLetterHandler) enabled(c *gin.Context) bool {
+	if h.relay == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "the outbox is not enabled"})
+		return false
+	}
+	return true
+}
+
+// @Summary List dead letters
+// @Description Lists the outbox events set aside after `outbox.max_attempts` failed publishes, the longest set aside first, with the error of their last attempt. Their count and age are reported by /readyz.
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Dead letters to return, at most 1000 (default 100)"
+// @Success 200 {array} outbox.DeadLetter
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/dead-letters [get]
+func (h *DeadLetterHandler) List(c *gin.Context) {
+	if !checkQuery(c, "limit") || !h.enabled(c) {
+		return
+	}
+	limit := 100
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxDeadLetters {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+	letters, err := h.relay.DeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, letters)
+}
+
+// @Summary Requeue dead letters
+// @Description Returns the given dead letters to the relay with fresh attempts; IDs of other events are ignored. A requeued event is published after the events its aggregate recorded since.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param ids body handler.DeadLetterIDs true "Dead letters to requeue"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/dead-letters/requeue [post]
+func (h *DeadLetterHandler) Requeue(c *gin.Context) {
+	var req DeadLetterIDs
+	if !checkQuery(c) || !h.enabled(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	n, err := h.relay.Requeue(c.Request.Context(), req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requeued": n})
+}
+
+// @Summary Discard dead letters
+// @Description Deletes the given dead letters, or all of them; IDs of other events are ignored.
+// @Tags Admin
+// @Produce json
+// @Param ids query string false "Comma-separated IDs of the dead letters"
+// @Param all query bool false "Discard every dead letter instead"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/dead-letters [delete]
+func (h *DeadLetterHandler) Discard(c *gin.Context) {
+	if !checkQuery(c, "ids", "all") || !h.enabled(c) {
+		return
+	}
+	var ids []string
+	switch {
+	case c.Query("all") == "true":
+	case c.Query("ids") != "":
+		ids = strings.Split(c.Query("ids"), ",")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids or all=true is required"})
+		return
+	}
+	n, err := h.relay.Discard(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"discarded": n})
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/events"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+type EventsHandler struct {
+	bus       *events.Bus
+	sockets   *events.WebSockets
+	heartbeat time.Duration
+	buffer    int
+}
+
+// NewEventsHandler streams the events published to bus, writing a heartbeat
+// on idle streams and dropping clients that fall buffer events behind. The
+// WebSocket endpoints are served by sockets.
+func NewEventsHandler(bus *events.Bus, sockets *events.WebSockets, heartbeat time.Duration, buffer int) *EventsHandler {
+	return &EventsHandler{
+		bus:       bus,
+		sockets:   sockets,
+		heartbeat: heartbeat,
+		buffer:    buffer,
+	}
+}
+
+// @Summary Stream user changes
+// @Description Server-Sent Events for every user created, updated or deleted while connected: the event type is the op, the id the event's sequence number and the data the event as JSON. Comment lines are sent as heartbeats. Clients that fall too far behind are disconnected after a `: dropped` comment and should resync through /users/sync.
+// @Tags User
+// @Produce event-stream
+// @Param op query string false "Comma-separated ops to receive: created, updated, deleted"
+// @Param id query string false "Comma-separated user IDs to receive events for"
+// @Success 200 {object} events.Event
+// @Failure 400 {object} map[string]string
+// @Security none
+// @Router /users/events [get]
+func (h *EventsHandler) UserEvents(c *gin.Context) {
+	if !checkQuery(c, "op", "id") {
+		return
+	}
+	filter, err := events.ParseFilter("user", c.Query("op"), c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Tenant = tenant.From(c.Request.Context())
+	sub := h.bus.Subscribe(filter, h.buffer)
+	defer sub.Close()
+	events.ServeSSE(c.Writer, c.Request, sub, h.heartbeat)
+}
+
+// @Summary Subscribe to user changes over WebSocket
+// @Description Upgrades to a WebSocket that sends every user created, updated or deleted while connected as an `event` message. The client narrows or widens the users it receives with `{"type": "subscribe", "ids": [...]}` and `{"type": "unsubscribe", "ids": [...]}`, each answered with the resulting `subscriptions`; a subscribe without IDs receives all users again. The server pings on idle connections and closes with 1001 when shutting down, or with 1013 when the client fell too far behind; resync through /users/sync before reconnecting.
+// @Tags User
+// @Produce json
+// @Param op query string false "Comma-separated ops to receive: created, updated, deleted"
+// @Param id query string false "Comma-separated user IDs to subscribe to initially"
+// @Success 101 {object} events.Message
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security none
+// @Router /users/ws [get]
+func (h *EventsHandler) UserSocket(c *gin.Context) {
+	if !checkQuery(c, "op", "id") {
+		return
+	}
+	filter, err := events.ParseFilter("user", c.Query("op"), c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Tenant = tenant.From(c.Request.Context())
+	h.sockets.Serve(c.Writer, c.Request, filter)
+}
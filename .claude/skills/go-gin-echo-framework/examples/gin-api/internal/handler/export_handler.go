@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/export"
+	"github.com/your-username/gin-api/internal/worker"
+)
+
+// exportJob is the name of the background job that writes exports.
+const exportJob = "exports"
+
+// ExportHandler accepts exports of a collection and reports on them, the
+// files being written by the exports job of jobs; with a nil exporter
+// (jobs.exports empty) it answers 501.
+type ExportHandler struct {
+	exports *export.Exporter
+	jobs    *worker.Worker
+	wait    time.Duration // for room in a full job queue
+}
+
+func NewExportHandler(exports *export.Exporter, jobs *worker.Worker, wait time.Duration) *ExportHandler {
+	return &ExportHandler{exports: exports, jobs: jobs, wait: wait}
+}
+
+// Register mounts GET /:id on g, the group of /exports.
+func (h *ExportHandler) Register(g *gin.RouterGroup) {
+	g.GET("/:id", h.Get)
+}
+
+// enabled answers 501 and returns false if exports are disabled.
+func (h *ExportHandler) enabled(c *gin.Context) bool {
+	if h.exports == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "exports are not enabled"})
+		return false
+	}
+	return true
+}
+
+// @Summary Export users
+// @Description Accepts an export of the users as a CSV or JSON file and answers at once: the file is written to the blob store in the background, and the export polled at the Location of the response until its status is done (with a URL of the file) or failed. A tenant has at most exports.max_pending exports waiting.
+// @Tags User
+// @Produce json
+// @Param format query string false "File format, csv or json (default json)"
+// @Success 202 {object} export.Export
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security none
+// @Router /users/export [post]
+func (h *ExportHandler) ExportUsers(c *gin.Context) {
+	if !checkQuery(c, "format") || !h.enabled(c) {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	e, err := h.exports.Request(c.Request.Context(), "users", format)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	// A full queue only delays the export to the job's next scheduled run
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.wait)
+	defer cancel()
+	h.jobs.Enqueue(ctx, exportJob)
+
+	c.Header("Location", "/exports/"+e.ID)
+	c.Header("Retry-After", "1")
+	c.JSON(http.StatusAccepted, e)
+}
+
+// @Summary Get an export
+// @Description Reports how far an export of the caller's tenant got. While it is pending or running, Retry-After gives the seconds to wait before polling again; once done, url downloads the file until expires_at (blob.url_ttl). Exports are forgotten, and their files deleted, exports.retention after they finish.
+// @Tags Export
+// @Produce json
+// @Param id path string true "Export ID"
+// @Success 200 {object} export.Export
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security none
+// @Router /exports/{id} [get]
+func (h *ExportHandler) Get(c *gin.Context) {
+	if !checkQuery(c) || !h.enabled(c) {
+		return
+	}
+	e, err := h.exports.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if e.Status == export.Pending || e.Status == export.Running {
+		c.Header("Retry-After", "1")
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+func (h *ExportHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, export.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, export.ErrBusy):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, export.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+	default:
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/fixtures"
+)
+
+// FixturesHandler lets admins restore the seed data of development. Mount
+// it behind auth.RequireAdmin; with nil fixtures (outside development, or
+// fixtures.dir unset) it answers 501.
+type FixturesHandler struct {
+	fixtures *fixtures.Fixtures
+}
+
+func NewFixturesHandler(f *fixtures.Fixtures) *FixturesHandler {
+	return &FixturesHandler{fixtures: f}
+}
+
+// Register mounts the reset on g itself.
+func (h *FixturesHandler) Register(g *gin.RouterGroup) {
+	g.POST("", h.Reset)
+}
+
+// @Summary Reset to the seed data
+// @Description Restores the seed state of development in one transaction: the items of the files in `fixtures.dir` are written as they are there, and every other item of the seeded collections is deleted, users registered since included. The files are read again, so edits apply without a restart.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} fixtures.Result
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/reset [post]
+func (h *FixturesHandler) Reset(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	if h.fixtures == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "fixtures are only loaded in development, from fixtures.dir"})
+		return
+	}
+	results, err := h.fixtures.Reset(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/flags"
+)
+
+// FlagsHandler lets admins toggle feature flags. Mount it behind
+// auth.RequireAdmin.
+type FlagsHandler struct {
+	flags *flags.Set
+}
+
+func NewFlagsHandler(f *flags.Set) *FlagsHandler {
+	return &FlagsHandler{flags: f}
+}
+
+// Register mounts the flags on g itself and each on /:name.
+func (h *FlagsHandler) Register(g *gin.RouterGroup) {
+	g.GET("", h.List)
+	g.PUT("/:name", h.Toggle)
+	g.DELETE("/:name", h.Clear)
+}
+
+// FlagToggle is the request of a toggle.
+type FlagToggle struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// @Summary List feature flags
+// @Description Lists the flags declared in feature_flags, by name, with their defaults, rules and whether they are toggled.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} flags.Flag
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/flags [get]
+func (h *FlagsHandler) List(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.flags.All(c.Request.Context()))
+}
+
+// @Summary Toggle a feature flag
+// @Description Turns a declared flag on or off in this instance, overriding feature_flags until the flag is cleared or the instance restarts.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Flag name"
+// @Param toggle body handler.FlagToggle true "The new state"
+// @Success 200 {object} flags.Flag
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/flags/{name} [put]
+func (h *FlagsHandler) Toggle(c *gin.Context) {
+	var req FlagToggle
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	flag, err := h.flags.Toggle(c.Param("name"), *req.Enabled)
+	h.respond(c, flag, err)
+}
+
+// @Summary Clear a feature flag
+// @Description Drops the toggle of a flag, returning it to its default in feature_flags.
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Flag name"
+// @Success 200 {object} flags.Flag
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /admin/flags/{name} [delete]
+func (h *FlagsHandler) Clear(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	flag, err := h.flags.Clear(c.Param("name"))
+	h.respond(c, flag, err)
+}
+
+func (h *FlagsHandler) respond(c *gin.Context, flag flags.Flag, err error) {
+	if errors.Is(err, flags.ErrUnknown) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
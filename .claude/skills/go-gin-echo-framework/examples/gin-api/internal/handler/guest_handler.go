@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// GuestHandler lets a logged-in account claim the session of a guest,
+// taking over what the guest created. Mount it behind auth.Identify and
+// auth.Require.
+type GuestHandler struct {
+	guests auth.GuestService
+}
+
+func NewGuestHandler(guests auth.GuestService) *GuestHandler {
+	return &GuestHandler{guests: guests}
+}
+
+// ClaimGuestRequest names the guest session to claim.
+type ClaimGuestRequest struct {
+	GuestToken string `json:"guest_token" binding:"required"` // the guest's current access token
+}
+
+// Register mounts POST /claim on g.
+func (h *GuestHandler) Register(g *gin.RouterGroup) {
+	g.POST("/claim", h.Claim)
+}
+
+// @Summary Claim a guest session
+// @Description Hands everything the guest of `guest_token` owns to the calling account in one transaction, e.g. after the guest registered or logged in, and ends the guest's session. A guest is claimed once, in the tenant it started in.
+// @Tags Auth
+// @Accept json
+// @Param guest body handler.ClaimGuestRequest true "Access token of the guest"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/guest/claim [post]
+func (h *GuestHandler) Claim(c *gin.Context) {
+	var req ClaimGuestRequest
+	owner, ok := sessionOwner(c, "guest sessions")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.guests.Claim(c.Request.Context(), owner, req.GuestToken); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidGuest):
+			// Not 401: the session is fine, the guest's token is not
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/hooks"
+	"github.com/your-username/gin-api/internal/worker"
+)
+
+// hookJob is the name of the background job that delivers events to the
+// registered webhooks.
+const hookJob = "hooks"
+
+// HookHandler lets callers register webhooks that the domain events of
+// their tenant are delivered to, by the hooks job of jobs, and debug their
+// deliveries; with a nil dispatcher (jobs.hooks empty) it answers 501.
+// Each account sees only the hooks it registered. Mount it behind
+// auth.Require.
+type HookHandler struct {
+	hooks *hooks.Dispatcher
+	jobs  *worker.Worker
+	wait  time.Duration // for room in a full job queue
+}
+
+func NewHookHandler(dispatcher *hooks.Dispatcher, jobs *worker.Worker, wait time.Duration) *HookHandler {
+	return &HookHandler{hooks: dispatcher, jobs: jobs, wait: wait}
+}
+
+// Register mounts GET /, POST /, DELETE /:id, GET /:id/deliveries and
+// POST /:id/deliveries/:delivery/redeliver on g, the group of /hooks.
+func (h *HookHandler) Register(g *gin.RouterGroup) {
+	g.GET("/", h.List)
+	g.POST("/", h.Create)
+	g.DELETE("/:id", h.Delete)
+	g.GET("/:id/deliveries", h.Deliveries)
+	g.POST("/:id/deliveries/:delivery/redeliver", h.Redeliver)
+}
+
+// owner returns the account whose hooks the request is about, or answers
+// 501 if hooks are disabled and returns false.
+func (h *HookHandler) owner(c *gin.Context) (string, bool) {
+	if h.hooks == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "hooks are not enabled"})
+		return "", false
+	}
+	caller := auth.CallerFromContext(c.Request.Context())
+	if caller == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", false
+	}
+	return caller.Subject, true
+}
+
+// @Summary List webhooks
+// @Description Lists the webhooks the caller registered, oldest first. Secrets are never returned after registration.
+// @Tags Hook
+// @Produce json
+// @Success 200 {array} hooks.Hook
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks [get]
+func (h *HookHandler) List(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	owner, ok := h.owner(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.hooks.List(c.Request.Context(), owner))
+}
+
+// @Summary Register a webhook
+// @Description Registers a URL that the events of the listed types (e.g. user.created, or * for all) in the caller's tenant are POSTed to once their write commits, as a JSON message {id, name, aggregate_id, data}; the URL must be of a public host unless hooks.allow_private is set. Each delivery carries X-Hook-ID, X-Hook-Delivery (the message's id, the same on every attempt) and X-Hook-Event, and is signed in X-Hook-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of the time, a dot and the body under the secret>. The returned `secret`, generated unless given, is shown only once. A delivery answered with anything but a 2xx is retried after hooks.backoff, doubling up to hooks.max_backoff, until it failed hooks.attempts times and is dead.
+// @Tags Hook
+// @Accept json
+// @Produce json
+// @Param hook body hooks.Registration true "URL, event types and optional secret"
+// @Success 201 {object} hooks.Hook
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks [post]
+func (h *HookHandler) Create(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	owner, ok := h.owner(c)
+	if !ok {
+		return
+	}
+	var req hooks.Registration
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hook, err := h.hooks.Register(c.Request.Context(), owner, req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Header("Location", "/hooks/"+hook.ID+"/deliveries")
+	c.JSON(http.StatusCreated, hook)
+}
+
+// @Summary Delete a webhook
+// @Description Deletes a webhook the caller registered with its delivery log; its pending deliveries are not made.
+// @Tags Hook
+// @Param id path string true "Hook ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks/{id} [delete]
+func (h *HookHandler) Delete(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	owner, ok := h.owner(c)
+	if !ok {
+		return
+	}
+	if err := h.hooks.Delete(c.Request.Context(), owner, c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List the deliveries of a webhook
+// @Description Lists the latest deliveries of a webhook the caller registered, newest first, to debug a failing one: each has its status (pending, delivered or dead), attempts, the HTTP status and error of its last attempt and, while pending, when it is tried next. At most hooks.log_size deliveries are kept; the oldest finished ones make room for new ones.
+// @Tags Hook
+// @Produce json
+// @Param id path string true "Hook ID"
+// @Success 200 {array} hooks.Delivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks/{id}/deliveries [get]
+func (h *HookHandler) Deliveries(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	owner, ok := h.owner(c)
+	if !ok {
+		return
+	}
+	deliveries, err := h.hooks.Deliveries(c.Request.Context(), owner, c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Redeliver an event to a webhook
+// @Description Makes a delivery of a webhook the caller registered pending again, with hooks.attempts attempts of its own, typically a dead one once the receiver is fixed. It is delivered in the background.
+// @Tags Hook
+// @Produce json
+// @Param id path string true "Hook ID"
+// @Param delivery path string true "Delivery ID"
+// @Success 202 {object} hooks.Delivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Security BearerAuth
+// @Router /hooks/{id}/deliveries/{delivery}/redeliver [post]
+func (h *HookHandler) Redeliver(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	owner, ok := h.owner(c)
+	if !ok {
+		return
+	}
+	d, err := h.hooks.Redeliver(c.Request.Context(), owner, c.Param("id"), c.Param("delivery"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	// A full queue only delays the delivery to the job's next scheduled run
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.wait)
+	defer cancel()
+	h.jobs.Enqueue(ctx, hookJob)
+	c.JSON(http.StatusAccepted, d)
+}
+
+func (h *HookHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, hooks.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, hooks.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hook not found"})
+	case errors.Is(err, hooks.ErrNoDelivery):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+	default:
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/blob"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/service"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// ImageHandler stores the images of users in a blob.Store, under
+// users/<tenant>/<id>/<name>. Uploads are multipart forms, checked against
+// the size and sniffed media types of blob.Options; mount it in a route
+// group that accepts multipart/form-data bodies that large. Images are kept
+// when their user is deleted, so that undoing the delete restores them.
+type ImageHandler struct {
+	userService service.UserService
+	store       blob.Store
+	opts        blob.Options
+	clock       clock.Clock
+}
+
+func NewImageHandler(userService service.UserService, store blob.Store, opts blob.Options, clk clock.Clock) *ImageHandler {
+	return &ImageHandler{
+		userService: userService,
+		store:       store,
+		opts:        opts,
+		clock:       clk,
+	}
+}
+
+// Register mounts POST /:id/images, GET /:id/images/:name and
+// GET /:id/images/:name/url on g, the group of /users.
+func (h *ImageHandler) Register(g *gin.RouterGroup) {
+	g.POST("/:id/images", h.Upload)
+	g.GET("/:id/images/:name", h.Download)
+	g.GET("/:id/images/:name/url", h.URL)
+}
+
+// Image is a stored image, with a signed URL that downloads it without
+// credentials until ExpiresAt.
+type Image struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// imageName is the names images may have: their file name, without paths.
+var imageName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// @Summary Upload an image of a user
+// @Description Stores the file of the multipart form as an image of the user, under its file name (letters, digits, ., _ and -), replacing any image of that name. The media type is sniffed from the content and must be one of blob.types; the file is at most blob.max_size bytes. The response carries a URL that downloads the image until expires_at (blob.url_ttl).
+// @Tags User
+// @Accept mpfd
+// @Produce json
+// @Param id path string true "User ID"
+// @Param file formData file true "The image"
+// @Success 201 {object} handler.Image
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /users/{id}/images [post]
+func (h *ImageHandler) Upload(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if _, err := h.userService.GetByID(ctx, id); err != nil {
+		h.fail(c, err)
+		return
+	}
+	file, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required: a multipart/form-data body with the image in its file field"})
+		return
+	}
+	if !imageName.MatchString(file.Filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file names are letters, digits, ., _ and -, starting with a letter or digit"})
+		return
+	}
+	if file.Size > h.opts.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("images are at most %d bytes", h.opts.MaxSize)})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !slices.Contains(h.opts.Types, contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("images are %s, not %s", strings.Join(h.opts.Types, ", "), contentType)})
+		return
+	}
+
+	key := imageKey(ctx, id, file.Filename)
+	if err := h.store.Put(ctx, key, io.MultiReader(bytes.NewReader(head[:n]), f), file.Size, contentType); err != nil {
+		h.fail(c, err)
+		return
+	}
+	img, ok := h.image(c, key, file.Filename, blob.Info{ContentType: contentType, Size: file.Size})
+	if ok {
+		c.JSON(http.StatusCreated, img)
+	}
+}
+
+// @Summary Download an image of a user
+// @Description Streams the image from the blob store.
+// @Tags User
+// @Produce image/png,image/jpeg,image/gif,image/webp
+// @Param id path string true "User ID"
+// @Param name path string true "Image name"
+// @Success 200 "The image"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /users/{id}/images/{name} [get]
+func (h *ImageHandler) Download(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	key, ok := h.key(c)
+	if ok {
+		h.serve(c, key)
+	}
+}
+
+// @Summary Get a signed URL of an image
+// @Description Signs a URL that downloads the image without credentials until expires_at (blob.url_ttl): a presigned URL of the S3 backend, or one under /blobs of the local one.
+// @Tags User
+// @Produce json
+// @Param id path string true "User ID"
+// @Param name path string true "Image name"
+// @Success 200 {object} handler.Image
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /users/{id}/images/{name}/url [get]
+func (h *ImageHandler) URL(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	key, ok := h.key(c)
+	if !ok {
+		return
+	}
+	r, info, err := h.store.Get(c.Request.Context(), key)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	r.Close()
+	if img, ok := h.image(c, key, c.Param("name"), info); ok {
+		c.JSON(http.StatusOK, img)
+	}
+}
+
+// @Summary Download a blob by signed URL
+// @Description Streams a blob of the local backend, an image or the file of an export, to the holder of a URL signed for it, e.g. by GET /users/{id}/images/{name}/url or GET /exports/{id}, until it expires. The S3 backend signs URLs of its own, so with it every blob is 404.
+// @Tags Blob
+// @Produce image/png,image/jpeg,image/gif,image/webp,text/csv,application/json
+// @Param key path string true "Blob key"
+// @Param expires query int true "Expiry of the URL, in Unix seconds"
+// @Param signature query string true "Signature of the URL"
+// @Success 200 "The blob"
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /blobs/{key} [get]
+func (h *ImageHandler) Blob(c *gin.Context) {
+	if !checkQuery(c, "expires", "signature") {
+		return
+	}
+	local, ok := h.store.(*blob.Local)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blob not found"})
+		return
+	}
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Query("expires"), c.Query("signature"), h.clock.Now()); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	h.serve(c, key)
+}
+
+// key is the blob key of the image named by the request, answering 404
+// when its user does not exist.
+func (h *ImageHandler) key(c *gin.Context) (string, bool) {
+	ctx := c.Request.Context()
+	if _, err := h.userService.GetByID(ctx, c.Param("id")); err != nil {
+		h.fail(c, err)
+		return "", false
+	}
+	if !imageName.MatchString(c.Param("name")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return "", false
+	}
+	return imageKey(ctx, c.Param("id"), c.Param("name")), true
+}
+
+// imageKey is the key of an image of user id in the tenant of ctx, if any.
+func imageKey(ctx context.Context, id, name string) string {
+	return path.Join("users", tenant.From(ctx), id, name)
+}
+
+// image describes the image under key with a freshly signed URL.
+func (h *ImageHandler) image(c *gin.Context, key, name string, info blob.Info) (Image, bool) {
+	expires := h.clock.Now().Add(h.opts.URLTTL).UTC().Truncate(time.Second)
+	url, err := h.store.SignURL(key, expires)
+	if err != nil {
+		h.fail(c, err)
+		return Image{}, false
+	}
+	return Image{Name: name, ContentType: info.ContentType, Size: info.Size, URL: url, ExpiresAt: expires}, true
+}
+
+// serve streams the blob under key.
+func (h *ImageHandler) serve(c *gin.Context, key string) {
+	r, info, err := h.store.Get(c.Request.Context(), key)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	defer r.Close()
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, r, nil)
+}
+
+func (h *ImageHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, blob.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+	default:
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+)
+
+// MFAHandler lets a logged-in account enroll the authenticator app whose
+// codes its password logins then ask for. Mount it behind auth.Identify
+// and auth.Require.
+type MFAHandler struct {
+	mfa auth.MFAService
+}
+
+func NewMFAHandler(mfa auth.MFAService) *MFAHandler {
+	return &MFAHandler{mfa: mfa}
+}
+
+// TOTPCodeRequest proves the account holds its authenticator app.
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"` // shown by the app
+}
+
+// Register mounts POST /, /confirm and /disable on g.
+func (h *MFAHandler) Register(g *gin.RouterGroup) {
+	g.POST("/", h.Enroll)
+	g.POST("/confirm", h.Confirm)
+	g.POST("/disable", h.Disable)
+}
+
+// @Summary Enroll an authenticator app
+// @Description Creates the secret of an authenticator app for the calling account: scan `uri` as a QR code, or type `secret`, then confirm with a code of the app at /auth/mfa/totp/confirm. Until then logins do not ask for codes, and enrolling again replaces the secret.
+// @Tags Auth
+// @Produce json
+// @Success 201 {object} auth.TOTPEnrollment
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/mfa/totp [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	owner, ok := sessionOwner(c, "authenticator apps")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	enrollment, err := h.mfa.EnrollTOTP(c.Request.Context(), owner)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// @Summary Confirm an authenticator app
+// @Description Turns on the enrolled authenticator app with one of its codes. From then on password logins answer 401 with an `mfa_token`, completed with a code at /auth/login/mfa.
+// @Tags Auth
+// @Accept json
+// @Param code body handler.TOTPCodeRequest true "Current code of the app"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/mfa/totp/confirm [post]
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	var req TOTPCodeRequest
+	owner, ok := sessionOwner(c, "authenticator apps")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.mfa.ConfirmTOTP(c.Request.Context(), owner, req.Code); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Disable an authenticator app
+// @Description Removes the calling account's authenticator app, proven with one of its codes; logins take the password alone again.
+// @Tags Auth
+// @Accept json
+// @Param code body handler.TOTPCodeRequest true "Current code of the app"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/mfa/totp/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req TOTPCodeRequest
+	owner, ok := sessionOwner(c, "authenticator apps")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.mfa.DisableTOTP(c.Request.Context(), owner, req.Code); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *MFAHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCode):
+		// Not 401: the session is fine, the code is not
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrMFANotEnrolled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrMFAEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
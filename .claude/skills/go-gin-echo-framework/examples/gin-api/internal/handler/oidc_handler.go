@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// OIDCHandler serves browser logins with external identity providers (see
+// auth.OIDCService).
+type OIDCHandler struct {
+	oidc auth.OIDCService
+}
+
+func NewOIDCHandler(oidc auth.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc}
+}
+
+// Query parameters providers add to the callback besides code and state.
+var callbackParams = []string{"code", "state", "error", "error_description", "error_uri", "scope", "authuser", "hd", "prompt", "iss"}
+
+// Register mounts GET /login and /callback on g.
+func (h *OIDCHandler) Register(g *gin.RouterGroup) {
+	g.GET("/login", h.Login)
+	g.GET("/callback", h.Callback)
+}
+
+// @Summary Log in with an external provider
+// @Description Redirects the browser to the provider's login page using the authorization code flow with PKCE. The provider redirects back to /auth/oidc/callback.
+// @Tags Auth
+// @Param provider query string true "Configured provider name, e.g. google or github"
+// @Success 302 "Redirect to the provider"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security none
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	if !checkQuery(c, "provider") {
+		return
+	}
+	provider := c.Query("provider")
+	if provider == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider is required"})
+		return
+	}
+	login, err := h.oidc.Begin(c.Request.Context(), provider)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	http.SetCookie(c.Writer, login.Cookie)
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+// @Summary Complete an external login
+// @Description The provider's redirect target. Verifies the login, links the external account to a local one on first use (by verified email, or a new account) and returns a token pair as /auth/login does.
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if !checkQuery(c, callbackParams...) {
+		return
+	}
+	if reason := c.Query("error"); reason != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "login failed at provider: " + reason})
+		return
+	}
+	cookie, _ := c.Cookie(auth.OIDCCookie)
+	token, err := h.oidc.Complete(c.Request.Context(), cookie, c.Query("state"), c.Query("code"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{Name: auth.OIDCCookie, Path: c.Request.URL.Path, MaxAge: -1, HttpOnly: true})
+	c.JSON(http.StatusOK, token)
+}
+
+func (h *OIDCHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrInvalidLogin):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrProviderUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
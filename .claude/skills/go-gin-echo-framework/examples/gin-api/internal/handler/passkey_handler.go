@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/webauthn"
+)
+
+// PasskeyHandler serves the WebAuthn ceremonies of passkeys: adding them
+// to a logged-in account, and logging in with them instead of a password.
+type PasskeyHandler struct {
+	passkeys auth.PasskeyService
+}
+
+func NewPasskeyHandler(passkeys auth.PasskeyService) *PasskeyHandler {
+	return &PasskeyHandler{passkeys: passkeys}
+}
+
+// FinishPasskeyRegistrationRequest is the browser's answer to the options
+// of /auth/passkeys/register/begin.
+type FinishPasskeyRegistrationRequest struct {
+	Session    string                 `json:"session" binding:"required"` // as returned with the options
+	Name       string                 `json:"name" binding:"max=100"`     // e.g. "laptop"; default "passkey"
+	Credential *webauthn.Registration `json:"credential" binding:"required"`
+}
+
+// BeginPasskeyLoginRequest names the account logging in, or nobody for the
+// authenticator to offer its passkeys of the service.
+type BeginPasskeyLoginRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+// FinishPasskeyLoginRequest is the browser's answer to the options of
+// /auth/passkeys/login/begin.
+type FinishPasskeyLoginRequest struct {
+	Session    string              `json:"session" binding:"required"` // as returned with the options
+	Credential *webauthn.Assertion `json:"credential" binding:"required"`
+}
+
+// Register mounts GET /, DELETE /:id, and POST /register/begin and
+// /register/finish on g, for the calling account's passkeys. Mount it
+// behind auth.Identify and auth.Require.
+func (h *PasskeyHandler) Register(g *gin.RouterGroup) {
+	g.GET("/", h.List)
+	g.DELETE("/:id", h.Delete)
+	g.POST("/register/begin", h.BeginRegistration)
+	g.POST("/register/finish", h.FinishRegistration)
+}
+
+// RegisterLogin mounts POST /begin and /finish on g, which log in.
+func (h *PasskeyHandler) RegisterLogin(g *gin.RouterGroup) {
+	g.POST("/begin", h.BeginLogin)
+	g.POST("/finish", h.FinishLogin)
+}
+
+// @Summary List passkeys
+// @Description Lists the passkeys of the calling account, oldest first.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} auth.PasskeyInfo
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys [get]
+func (h *PasskeyHandler) List(c *gin.Context) {
+	owner, ok := sessionOwner(c, "passkeys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	list, err := h.passkeys.List(c.Request.Context(), owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Start adding a passkey
+// @Description Returns the options to pass to `navigator.credentials.create({publicKey})` (binary fields are base64url, as `PublicKeyCredential.parseCreationOptionsFromJSON` takes them) and the session to send back with its result to /auth/passkeys/register/finish within `auth.passkeys.timeout`. The account's passkeys are excluded, so an authenticator holds one of them.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.PasskeyRegistration
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys/register/begin [post]
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	owner, ok := sessionOwner(c, "passkeys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	begun, err := h.passkeys.BeginRegistration(c.Request.Context(), owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, begun)
+}
+
+// @Summary Add a passkey
+// @Description Verifies the credential the browser created (`PublicKeyCredential.toJSON()`) for the session of /auth/passkeys/register/begin and adds it to the calling account. Authenticators must verify the user; attestation is not checked.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param registration body handler.FinishPasskeyRegistrationRequest true "Session, passkey name and created credential"
+// @Success 201 {object} auth.PasskeyInfo
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys/register/finish [post]
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	var req FinishPasskeyRegistrationRequest
+	owner, ok := sessionOwner(c, "passkeys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	info, err := h.passkeys.FinishRegistration(c.Request.Context(), owner, req.Session, req.Name, req.Credential)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPasskey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, info)
+}
+
+// @Summary Delete a passkey
+// @Description Removes one of the calling account's passkeys; it no longer logs in. Sessions it started are kept.
+// @Tags Auth
+// @Param id path string true "Passkey ID, the base64url credential ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/passkeys/{id} [delete]
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	owner, ok := sessionOwner(c, "passkeys")
+	if !ok || !checkQuery(c) {
+		return
+	}
+	if err := h.passkeys.Delete(c.Request.Context(), owner, c.Param("id")); err != nil {
+		if errors.Is(err, auth.ErrPasskeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Start a passkey login
+// @Description Returns the options to pass to `navigator.credentials.get({publicKey})` and the session to send back with its result to /auth/passkeys/login/finish within `auth.passkeys.timeout`. With an email the options allow that account's passkeys; without one the authenticator offers its discoverable passkeys, so the user need not type anything. Accounts without a passkey log in with their password, and their authenticator app if they have one.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param login body handler.BeginPasskeyLoginRequest true "Email of the account, or none"
+// @Success 200 {object} auth.PasskeyLogin
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/passkeys/login/begin [post]
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	var req BeginPasskeyLoginRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	begun, err := h.passkeys.BeginLogin(c.Request.Context(), req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, begun)
+}
+
+// @Summary Log in with a passkey
+// @Description Verifies the assertion the browser returned (`PublicKeyCredential.toJSON()`) for the session of /auth/passkeys/login/begin and exchanges it for a JWT access token. A passkey verifies the user itself, so no one-time code is asked for. Each session is good for one attempt.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param login body handler.FinishPasskeyLoginRequest true "Session and assertion"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /auth/passkeys/login/finish [post]
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var req FinishPasskeyLoginRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	token, err := h.passkeys.FinishLogin(c.Request.Context(), req.Session, req.Credential)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidPasskey):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrAccountDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPprof mounts the runtime profiles of net/http/pprof on g, which
+// must be /debug/pprof: the index on /, and /profile, /trace, /heap and so
+// on below it. Profiles expose internals and CPU profiles cost CPU while
+// they run, so mount it behind auth.RequireAdmin.
+func RegisterPprof(g *gin.RouterGroup) {
+	g.GET("/*profile", servePprof)
+}
+
+func servePprof(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("profile"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Index(c.Writer, c.Request) // the index, or a named profile such as heap
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/scim"
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// scimParams are the query parameters of SCIM reads; attributes and
+// excludedAttributes are accepted and ignored, every attribute is returned.
+var scimParams = []string{"attributes", "excludedAttributes"}
+
+// SCIMHandler serves the SCIM 2.0 Users resource to an identity provider,
+// which authenticates with the bearer token of scim.token rather than as a
+// caller, so mount it in a route group without authentication; with an
+// empty token it answers 501. Responses, errors included, are SCIM JSON.
+type SCIMHandler struct {
+	scim  *scim.Service
+	token secret.Secret
+}
+
+func NewSCIMHandler(service *scim.Service, token secret.Secret) *SCIMHandler {
+	return &SCIMHandler{scim: service, token: token}
+}
+
+// Register mounts GET /ServiceProviderConfig, GET /Users, POST /Users,
+// GET /Users/:id and PATCH /Users/:id on g, the group of /scim/v2.
+func (h *SCIMHandler) Register(g *gin.RouterGroup) {
+	g.GET("/ServiceProviderConfig", h.Config)
+	g.GET("/Users", h.List)
+	g.POST("/Users", h.Create)
+	g.GET("/Users/:id", h.Get)
+	g.PATCH("/Users/:id", h.Patch)
+}
+
+// authorized answers 501 if SCIM is disabled and 401 unless the request
+// carries the token, and reports whether the handler may go on.
+func (h *SCIMHandler) authorized(c *gin.Context) bool {
+	if h.token.Reveal() == "" {
+		h.respond(c, http.StatusNotImplemented, scim.NewError(http.StatusNotImplemented, "", "SCIM is not enabled"))
+		return false
+	}
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	// Hashed to compare in constant time whatever the length
+	got, want := sha256.Sum256([]byte(token)), sha256.Sum256([]byte(h.token.Reveal()))
+	if subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+		c.Header("WWW-Authenticate", "Bearer")
+		h.respond(c, http.StatusUnauthorized, scim.NewError(http.StatusUnauthorized, "", "Unauthorized"))
+		return false
+	}
+	return true
+}
+
+// @Summary Get the SCIM service provider configuration
+// @Description Tells identity providers what the SCIM endpoint supports: PATCH and equality filters, no bulk operations, sorting, ETags or password changes.
+// @Tags SCIM
+// @Produce application/scim+json
+// @Success 200 {object} scim.ServiceProviderConfig
+// @Failure 401 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) Config(c *gin.Context) {
+	if !checkQuery(c) || !h.authorized(c) {
+		return
+	}
+	h.respond(c, http.StatusOK, h.scim.Config())
+}
+
+// @Summary List provisioned users
+// @Description Lists the users provisioned in scim.tenant, oldest first, that match filter: conditions of id, externalId, userName, displayName, emails.value or active, the eq operator and a value, joined by and, e.g. userName eq "ada@example.com". Pages start at the 1-based startIndex and hold count users, at most scim.max_results.
+// @Tags SCIM
+// @Produce application/scim+json
+// @Param filter query string false "Filter, e.g. userName eq \"ada@example.com\""
+// @Param startIndex query int false "1-based index of the first user"
+// @Param count query int false "Users per page"
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) List(c *gin.Context) {
+	if !checkQuery(c, append([]string{"filter", "startIndex", "count"}, scimParams...)...) || !h.authorized(c) {
+		return
+	}
+	startIndex, err1 := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	count, err2 := strconv.Atoi(c.DefaultQuery("count", "-1"))
+	if err := errors.Join(err1, err2); err != nil {
+		h.respond(c, http.StatusBadRequest, scim.NewError(http.StatusBadRequest, "invalidValue", "startIndex and count must be integers"))
+		return
+	}
+	list, err := h.scim.List(c.Request.Context(), c.Query("filter"), startIndex, count)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, list)
+}
+
+// @Summary Provision a user
+// @Description Creates an account in scim.tenant for a user of the identity provider, with a login by its primary email (or the userName if it is one) and no password: it logs in with the provider's single sign-on. The account that registered with that email, if any, is adopted instead. Attributes other than userName, externalId, name, displayName, emails and active are ignored. A userName or email already provisioned is a 409.
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param user body scim.User true "The user"
+// @Success 201 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) Create(c *gin.Context) {
+	if !checkQuery(c) || !h.authorized(c) {
+		return
+	}
+	var req scim.User
+	if !h.decode(c, &req) {
+		return
+	}
+	user, err := h.scim.Create(c.Request.Context(), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Header("Location", user.Meta.Location)
+	h.respond(c, http.StatusCreated, user)
+}
+
+// @Summary Get a provisioned user
+// @Tags SCIM
+// @Produce application/scim+json
+// @Param id path string true "User ID, that of its account"
+// @Success 200 {object} scim.User
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) Get(c *gin.Context) {
+	if !checkQuery(c, scimParams...) || !h.authorized(c) {
+		return
+	}
+	user, err := h.scim.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// @Summary Change or deactivate a provisioned user
+// @Description Applies add, replace and remove operations to userName, externalId, name, displayName, emails and active, with a path or as an object of attributes without one. Replacing active with false deactivates the user: its logins and session refreshes are refused with 403 and its API keys deleted, and the access tokens it holds expire within auth.token_ttl; true reactivates it. Changing the email moves its login.
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Param id path string true "User ID"
+// @Param patch body scim.PatchRequest true "Operations"
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Failure 501 {object} scim.Error
+// @Security SCIMToken
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) Patch(c *gin.Context) {
+	if !checkQuery(c) || !h.authorized(c) {
+		return
+	}
+	var req scim.PatchRequest
+	if !h.decode(c, &req) {
+		return
+	}
+	user, err := h.scim.Patch(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// decode reads the body into dst, answering 400 if it is malformed, and
+// reports whether the handler may go on. Unlike bindJSON it accepts unknown
+// attributes whatever the compatibility mode: providers send many that are
+// not kept, and extension schemas of their own.
+func (h *SCIMHandler) decode(c *gin.Context, dst any) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(dst); err != nil {
+		h.respond(c, http.StatusBadRequest, scim.NewError(http.StatusBadRequest, "invalidSyntax", "%v", err))
+		return false
+	}
+	return true
+}
+
+func (h *SCIMHandler) respond(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", scim.ContentType)
+	c.JSON(status, body)
+}
+
+func (h *SCIMHandler) fail(c *gin.Context, err error) {
+	var e *scim.Error
+	if !errors.As(err, &e) {
+		e = scim.NewError(statusOf(err), "", "%v", err)
+	}
+	h.respond(c, e.HTTPStatus(), e)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/search"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+type SearchHandler struct {
+	userService service.UserService
+	users       search.Searcher
+}
+
+func NewSearchHandler(userService service.UserService, users search.Searcher) *SearchHandler {
+	return &SearchHandler{
+		userService: userService,
+		users:       users,
+	}
+}
+
+// @Summary Search users
+// @Description Finds the users whose name or email contains any of the words of q, best matches first, with the matched words of each field highlighted in <em> (as HTML-escaped text).
+// @Tags User
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param limit query int false "Matches per page, at most 100 (default 20)"
+// @Param offset query int false "Matches to skip"
+// @Success 200 {object} search.Page[model.User]
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /users/search [get]
+func (h *SearchHandler) SearchUsers(c *gin.Context) {
+	if !checkQuery(c, "q", "limit", "offset") {
+		return
+	}
+	q, ok := searchQuery(c)
+	if !ok {
+		return
+	}
+	res, err := h.users.Search(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+		return
+	}
+	ids := make([]string, len(res.Hits))
+	for i, hit := range res.Hits {
+		ids[i] = hit.ID
+	}
+	items, err := h.userService.GetByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, matches(q, res, items))
+}
+
+// searchQuery reads q, limit and offset, answering 400 when one is invalid.
+func searchQuery(c *gin.Context) (search.Query, bool) {
+	q := search.Query{Text: c.Query("q"), Limit: 20}
+	if q.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return q, false
+	}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > search.MaxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number between 1 and 100"})
+			return q, false
+		}
+		q.Limit = n
+	}
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a number of at least 0"})
+			return q, false
+		}
+		q.Offset = n
+	}
+	return q, true
+}
+
+// matches pairs the hits of res with their items, in the order of the hits.
+// Hits of items deleted since they were indexed are left out.
+func matches[T model.Entity](q search.Query, res search.Results, items []T) search.Page[T] {
+	byID := make(map[string]T, len(items))
+	for _, item := range items {
+		byID[item.GetID()] = item
+	}
+	page := search.Page[T]{Total: res.Total, Offset: q.Offset, Limit: q.Limit, Hits: []search.Match[T]{}}
+	for _, hit := range res.Hits {
+		if item, ok := byID[hit.ID]; ok {
+			page.Hits = append(page.Hits, search.Match[T]{Item: item, Score: hit.Score, Highlights: hit.Highlights})
+		}
+	}
+	return page
+}
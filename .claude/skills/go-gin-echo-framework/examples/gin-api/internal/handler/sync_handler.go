@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+type SyncHandler struct {
+	userService service.UserService
+	feed        *changes.Feed
+}
+
+func NewSyncHandler(userService service.UserService, feed *changes.Feed) *SyncHandler {
+	return &SyncHandler{
+		userService: userService,
+		feed:        feed,
+	}
+}
+
+// @Summary Delta-sync users
+// @Description Returns users created, updated and deleted since the client's sync token. Without a token, or with an expired one, the full dataset is returned with `reset` set.
+// @Tags User
+// @Produce json
+// @Param token query string false "Sync token from the previous response"
+// @Success 200 {object} changes.Delta[model.User]
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security none
+// @Router /users/sync [get]
+func (h *SyncHandler) SyncUsers(c *gin.Context) {
+	if !checkQuery(c, "token") {
+		return
+	}
+	delta, err := changes.BuildDelta(c.Request.Context(), h.feed, c.Query("token"), changes.Source[model.User]{
+		Get:    h.userService.GetByID,
+		List:   h.userService.GetAll,
+		IDOf:   func(u *model.User) string { return u.GetID() },
+		Absent: func(err error) bool { return errors.Is(err, service.ErrNotFound) },
+	})
+	if err != nil {
+		if errors.Is(err, changes.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sync token"})
+			return
+		}
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delta)
+}
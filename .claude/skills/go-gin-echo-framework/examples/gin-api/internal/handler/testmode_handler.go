@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/testmode"
+)
+
+// TestModeHandler lets end-to-end tests move the fake clock and inspect the
+// outbound effects captured in test mode. It is mounted in test mode only
+// and, like the other test infrastructure, left out of the API spec.
+type TestModeHandler struct {
+	harness *testmode.Harness
+}
+
+func NewTestModeHandler(harness *testmode.Harness) *TestModeHandler {
+	return &TestModeHandler{harness: harness}
+}
+
+// Register mounts the clock on /clock and the effects on /effects.
+func (h *TestModeHandler) Register(g *gin.RouterGroup) {
+	g.GET("/clock", h.Now)
+	g.POST("/clock", h.Advance)
+	g.GET("/effects", h.Effects)
+	g.DELETE("/effects", h.ClearEffects)
+}
+
+// clockResponse is the time on the fake clock.
+type clockResponse struct {
+	Now time.Time `json:"now"`
+}
+
+// advanceRequest moves the fake clock forward.
+type advanceRequest struct {
+	By string `json:"by" binding:"required"` // a Go duration, e.g. "90s" or "24h"
+}
+
+// Now reports the time on the fake clock.
+func (h *TestModeHandler) Now(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	c.JSON(http.StatusOK, clockResponse{Now: h.harness.Clock.Now().UTC()})
+}
+
+// Advance moves the fake clock forward, firing the tickers that come due,
+// and reports the new time.
+func (h *TestModeHandler) Advance(c *gin.Context) {
+	var req advanceRequest
+	if !checkQuery(c) {
+		return
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := time.ParseDuration(req.By)
+	if err != nil || d < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by must be a non-negative duration such as 90s"})
+		return
+	}
+	h.harness.Clock.Advance(d)
+	c.JSON(http.StatusOK, clockResponse{Now: h.harness.Clock.Now().UTC()})
+}
+
+// Effects lists the captured outbound effects, oldest first, selected by
+// ?kind= (event or http), ?target= and ?subject=; all of them by default.
+func (h *TestModeHandler) Effects(c *gin.Context) {
+	if !checkQuery(c, "kind", "target", "subject") {
+		return
+	}
+	c.JSON(http.StatusOK, h.harness.Effects.Find(testmode.Query{
+		Kind:    c.Query("kind"),
+		Target:  c.Query("target"),
+		Subject: c.Query("subject"),
+	}))
+}
+
+// ClearEffects forgets the captured effects.
+func (h *TestModeHandler) ClearEffects(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	h.harness.Effects.Reset()
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/rpc"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// RegisterUserRPC exposes the user service as "users.*" JSON-RPC methods.
+func RegisterUserRPC(s *rpc.Server, userService service.UserService) {
+	RegisterCrudRPC[model.User](s, "users", userService)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/webhook"
+	"github.com/your-username/gin-api/internal/worker"
+)
+
+// webhookJob is the name of the background job that handles webhook
+// deliveries.
+const webhookJob = "webhooks"
+
+// WebhookHandler receives the webhooks of third-party services, handled by
+// the webhooks job of jobs; with a nil receiver (jobs.webhooks empty) it
+// answers 501. Deliveries authenticate with their provider's signature, so
+// mount it in a route group without authentication.
+type WebhookHandler struct {
+	webhooks *webhook.Receiver
+	jobs     *worker.Worker
+	wait     time.Duration // for room in a full job queue
+}
+
+func NewWebhookHandler(webhooks *webhook.Receiver, jobs *worker.Worker, wait time.Duration) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks, jobs: jobs, wait: wait}
+}
+
+// Register mounts POST /:provider on g, the group of /webhooks.
+func (h *WebhookHandler) Register(g *gin.RouterGroup) {
+	g.POST("/:provider", h.Receive)
+}
+
+// @Summary Receive a webhook
+// @Description Accepts a delivery of a webhook provider (github, with webhooks.github.secret set) and answers at once: 202 when it is queued for the webhooks job, 200 when it was accepted before, within webhooks.replay_window, or its event is not handled. The delivery must be signed by the provider, within webhooks.tolerance of now if the provider signs a timestamp, and its payload valid for its event. At most webhooks.max_queued deliveries wait; more get 503, for the provider to redeliver them later.
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param X-Hub-Signature-256 header string false "sha256= and the hex HMAC-SHA256 of the body (github)"
+// @Param X-GitHub-Delivery header string false "Delivery GUID (github)"
+// @Param X-GitHub-Event header string false "Event name (github)"
+// @Param payload body map[string]any true "The event"
+// @Success 200 {object} webhook.Receipt
+// @Success 202 {object} webhook.Receipt
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security none
+// @Router /webhooks/{provider} [post]
+func (h *WebhookHandler) Receive(c *gin.Context) {
+	if !checkQuery(c) {
+		return
+	}
+	if h.webhooks == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "webhooks are not enabled"})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	receipt, err := h.webhooks.Receive(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if receipt.Status != webhook.Queued {
+		c.JSON(http.StatusOK, receipt)
+		return
+	}
+	// A full job queue only delays the delivery to the job's next scheduled run
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.wait)
+	defer cancel()
+	h.jobs.Enqueue(ctx, webhookJob)
+	c.JSON(http.StatusAccepted, receipt)
+}
+
+func (h *WebhookHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook provider not found"})
+	case errors.Is(err, webhook.ErrSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrBusy):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(statusOf(err), gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,17 @@
+package hardening
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware applies p to the requests of a route group, rejecting those it
+// refuses before authentication or rate limiting spend work on them.
+func Middleware(p *Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status, msg := p.Apply(c.Writer, c.Request); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,515 @@
+// Package hooks delivers domain events to the webhooks that API clients
+// register: a Hook is a URL, a secret and the event types it subscribes
+// to, in the tenant of the caller that registered it, who alone can list,
+// delete and redeliver it. Unless Options.AllowPrivate is set, hooks of
+// hosts that are not public are refused, and so should the connections of
+// the dispatcher's client be (see httpclient.Factory.Public), since a name
+// can resolve to another address by the time it is delivered to. The
+// Dispatcher wraps
+// the domain event publisher (Publisher), so every committed write queues
+// a Delivery to each hook of its tenant subscribed to its event, and a
+// background job (Run) POSTs them, signed with the hook's secret (Sign),
+// retrying failures with a backoff doubling from Options.Backoff. A
+// delivery that failed Options.Attempts times is dead: it stays in the
+// hook's delivery log, with the response or error of its last attempt,
+// until it is redelivered by hand.
+//
+// Hooks and their deliveries are held in process, like exports: a restart
+// forgets them, and each instance delivers the events of its own writes to
+// the hooks registered with it.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/httpclient"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// The headers of a delivery.
+const (
+	HeaderID        = "X-Hook-ID"        // of the hook
+	HeaderDelivery  = "X-Hook-Delivery"  // of the delivery, the same on every attempt
+	HeaderEvent     = "X-Hook-Event"     // e.g. user.created
+	HeaderSignature = "X-Hook-Signature" // see Sign
+)
+
+// AllEvents subscribes a hook to every event type.
+const AllEvents = "*"
+
+var (
+	// ErrNotFound is returned for a hook that does not exist, or is not the
+	// caller's.
+	ErrNotFound = errors.New("hook not found")
+	// ErrNoDelivery is returned by Redeliver for a delivery that is not in
+	// the hook's log.
+	ErrNoDelivery = errors.New("delivery not found")
+	// ErrInvalid is matched by the errors of Register with a URL that is
+	// not http or https, or of a host that is not public, or an unknown
+	// event type.
+	ErrInvalid = errors.New("invalid hook")
+)
+
+// Options configure the retries of deliveries and how many are logged.
+type Options struct {
+	Attempts   int           `yaml:"attempts"`    // POSTs of a delivery before it is dead
+	Backoff    time.Duration `yaml:"backoff"`     // before the first retry, doubling for each next one
+	MaxBackoff time.Duration `yaml:"max_backoff"` // caps the backoff
+	Timeout    time.Duration `yaml:"timeout"`     // of one POST
+	LogSize    int           `yaml:"log_size"`    // deliveries kept per hook; the oldest finished ones make room
+	// AllowPrivate accepts the URLs of loopback, private and link-local
+	// hosts, such as receivers on the developer's machine
+	AllowPrivate bool `yaml:"allow_private"`
+}
+
+// Validate checks the limits.
+func (o Options) Validate() error {
+	var errs []error
+	if o.Attempts < 1 {
+		errs = append(errs, errors.New("attempts must be at least 1"))
+	}
+	if o.Backoff <= 0 {
+		errs = append(errs, errors.New("backoff must be positive"))
+	}
+	if o.MaxBackoff < o.Backoff {
+		errs = append(errs, errors.New("max_backoff must be at least the backoff"))
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("timeout must be positive"))
+	}
+	if o.LogSize < 1 {
+		errs = append(errs, errors.New("log_size must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// Registration is what a caller registers a hook with.
+type Registration struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`           // event types, or * for all
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=256"` // signs the deliveries; empty generates one
+}
+
+// Hook is a registered webhook.
+type Hook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"` // only in the response that registers the hook
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Status is the stage a delivery is at.
+type Status string
+
+const (
+	Pending   Status = "pending"   // waiting for its next attempt
+	Delivered Status = "delivered" // answered with a 2xx
+	Dead      Status = "dead"      // failed Options.Attempts times
+)
+
+// Delivery is an event on its way to a hook, as its delivery log shows it.
+type Delivery struct {
+	ID          string     `json:"id"`
+	Event       string     `json:"event"`
+	AggregateID string     `json:"aggregate_id"`
+	Status      Status     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Response    int        `json:"response,omitempty"` // HTTP status of the last attempt
+	Error       string     `json:"error,omitempty"`    // of the last attempt
+	CreatedAt   time.Time  `json:"created_at"`
+	NextAt      *time.Time `json:"next_attempt_at,omitempty"` // while pending
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+
+	body []byte // a domain.Message whose ID is the delivery's
+}
+
+// Sign returns the X-Hook-Signature of a delivery of body at t: t=, the
+// Unix time, and v1=, the hex HMAC-SHA256 under secret of the Unix time, a
+// dot and body. Receivers recompute it to authenticate the delivery and
+// refuse those signed too long ago.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type hook struct {
+	Hook
+	secret     string
+	tenant     string
+	owner      string      // the account that registered it
+	deliveries []*Delivery // oldest first
+}
+
+func (h *hook) subscribes(event string) bool {
+	for _, e := range h.Events {
+		if e == event || e == AllEvents {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher keeps the hooks of every tenant and delivers their events.
+type Dispatcher struct {
+	client *http.Client
+	clock  clock.Clock
+	ids    idgen.Generator
+	opts   Options
+	events []string // the event types hooks subscribe to
+
+	mu    sync.Mutex
+	hooks map[string]*hook
+}
+
+// New returns a Dispatcher without hooks, whose hooks subscribe to events,
+// posting with client (nil is http.DefaultClient).
+func New(client *http.Client, clk clock.Clock, ids idgen.Generator, opts Options, events []string) *Dispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Dispatcher{
+		client: client,
+		clock:  clock.OrSystem(clk),
+		ids:    idgen.OrDefault(ids),
+		opts:   opts,
+		events: events,
+		hooks:  map[string]*hook{},
+	}
+}
+
+// Register adds a hook of owner in the tenant of ctx. The returned Hook
+// carries its secret, which is not shown again.
+func (d *Dispatcher) Register(ctx context.Context, owner string, r Registration) (Hook, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Hook{}, fmt.Errorf("%w: url must be an http or https URL", ErrInvalid)
+	}
+	if !d.opts.AllowPrivate && !public(u.Hostname()) {
+		return Hook{}, fmt.Errorf("%w: url must be of a public host", ErrInvalid)
+	}
+	events := []string{}
+	for _, e := range r.Events {
+		if e != AllEvents && !contains(d.events, e) {
+			return Hook{}, fmt.Errorf("%w: event types are %s or %s", ErrInvalid, strings.Join(d.events, ", "), AllEvents)
+		}
+		if !contains(events, e) {
+			events = append(events, e)
+		}
+	}
+	secret := r.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return Hook{}, err
+		}
+		secret = hex.EncodeToString(b)
+	}
+	h := &hook{
+		Hook:   Hook{ID: d.ids.NewID(), URL: r.URL, Events: events, CreatedAt: d.clock.Now().UTC()},
+		secret: secret,
+		tenant: tenant.From(ctx),
+		owner:  owner,
+	}
+	d.mu.Lock()
+	d.hooks[h.ID] = h
+	d.mu.Unlock()
+	out := h.Hook
+	out.Secret = secret
+	return out, nil
+}
+
+// List returns the hooks of owner in the tenant of ctx, oldest first.
+func (d *Dispatcher) List(ctx context.Context, owner string) []Hook {
+	t := tenant.From(ctx)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := []Hook{}
+	for _, h := range d.hooks {
+		if h.tenant == t && h.owner == owner {
+			out = append(out, h.Hook)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt) || out[i].CreatedAt.Equal(out[j].CreatedAt) && out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Delete removes a hook of owner with its pending deliveries.
+func (d *Dispatcher) Delete(ctx context.Context, owner, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.hook(ctx, owner, id); err != nil {
+		return err
+	}
+	delete(d.hooks, id)
+	return nil
+}
+
+// Deliveries returns the delivery log of a hook of owner, newest first.
+func (d *Dispatcher) Deliveries(ctx context.Context, owner, id string) ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, err := d.hook(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Delivery, 0, len(h.deliveries))
+	for i := len(h.deliveries) - 1; i >= 0; i-- {
+		out = append(out, *h.deliveries[i])
+	}
+	return out, nil
+}
+
+// Redeliver makes a delivery of a hook of owner pending again, with
+// Options.Attempts attempts of its own, for Run to POST at once.
+func (d *Dispatcher) Redeliver(ctx context.Context, owner, id, delivery string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, err := d.hook(ctx, owner, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	for _, dl := range h.deliveries {
+		if dl.ID == delivery {
+			now := d.clock.Now().UTC()
+			dl.Status, dl.Attempts, dl.NextAt = Pending, 0, &now
+			return *dl, nil
+		}
+	}
+	return Delivery{}, ErrNoDelivery
+}
+
+// hook returns the hook of owner in the tenant of ctx with the given ID;
+// d.mu is held.
+func (d *Dispatcher) hook(ctx context.Context, owner, id string) (*hook, error) {
+	h, ok := d.hooks[id]
+	if !ok || h.tenant != tenant.From(ctx) || h.owner != owner {
+		return nil, ErrNotFound
+	}
+	return h, nil
+}
+
+// public reports whether host may be public: a name other than localhost,
+// whose addresses the dispatcher's client checks as it connects, or a
+// public IP address.
+func public(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return httpclient.IsPublic(addr)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+// queue adds a pending delivery of e to each hook of tenant t subscribed
+// to it and reports whether there was any.
+func (d *Dispatcher) queue(t string, e domain.Event) bool {
+	data, err := json.Marshal(e)
+	if err != nil {
+		slog.Warn("hooks: cannot encode event", "event", e.EventName(), "error", err)
+		return false
+	}
+	now := d.clock.Now().UTC()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queued := false
+	for _, h := range d.hooks {
+		if h.tenant != t || !h.subscribes(e.EventName()) {
+			continue
+		}
+		dl := &Delivery{ID: d.ids.NewID(), Event: e.EventName(), AggregateID: e.AggregateID(), Status: Pending, CreatedAt: now, NextAt: &now}
+		dl.body, _ = json.Marshal(domain.Message{ID: dl.ID, Name: dl.Event, AggregateID: dl.AggregateID, Data: data})
+		if !h.makeRoom(d.opts.LogSize) {
+			slog.Warn("hooks: delivery dropped, the log is full of pending ones", "hook", h.ID, "event", dl.Event)
+			continue
+		}
+		h.deliveries = append(h.deliveries, dl)
+		queued = true
+	}
+	return queued
+}
+
+// makeRoom drops the oldest finished deliveries until fewer than size are
+// logged, and reports whether it managed.
+func (h *hook) makeRoom(size int) bool {
+	for len(h.deliveries) >= size {
+		i := 0
+		for i < len(h.deliveries) && h.deliveries[i].Status == Pending {
+			i++
+		}
+		if i == len(h.deliveries) {
+			return false
+		}
+		h.deliveries = append(h.deliveries[:i], h.deliveries[i+1:]...)
+	}
+	return true
+}
+
+type attempt struct {
+	delivery *Delivery
+	url      string
+	hook     string
+	secret   string
+	body     []byte
+}
+
+// Run POSTs the deliveries that are due, oldest first. It is the job that
+// deliveries are made in; a failed delivery is recorded in its hook's log
+// and tried again after its backoff, rather than failing the job.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	now := d.clock.Now()
+	var due []attempt
+	d.mu.Lock()
+	for _, h := range d.hooks {
+		for _, dl := range h.deliveries {
+			if dl.Status == Pending && !now.Before(*dl.NextAt) {
+				due = append(due, attempt{delivery: dl, url: h.URL, hook: h.ID, secret: h.secret, body: dl.body})
+			}
+		}
+	}
+	d.mu.Unlock()
+	sort.SliceStable(due, func(i, j int) bool { return due[i].delivery.CreatedAt.Before(due[j].delivery.CreatedAt) })
+
+	for _, a := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		code, err := d.post(ctx, a)
+		d.mu.Lock()
+		d.record(a.delivery, code, err)
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+// post makes one attempt of a delivery and returns the status it was
+// answered with.
+func (d *Dispatcher) post(ctx context.Context, a attempt) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(a.body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, a.hook)
+	req.Header.Set(HeaderDelivery, a.delivery.ID)
+	req.Header.Set(HeaderEvent, a.delivery.Event)
+	req.Header.Set(HeaderSignature, Sign(a.secret, d.clock.Now(), a.body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// record sets the outcome of an attempt of dl; d.mu is held.
+func (d *Dispatcher) record(dl *Delivery, code int, err error) {
+	now := d.clock.Now().UTC()
+	dl.Attempts++
+	dl.Response, dl.Error = code, ""
+	switch {
+	case err == nil:
+		dl.Status, dl.NextAt, dl.DeliveredAt = Delivered, nil, &now
+	case dl.Attempts >= d.opts.Attempts:
+		dl.Status, dl.NextAt, dl.Error = Dead, nil, err.Error()
+	default:
+		backoff := d.opts.Backoff << (dl.Attempts - 1)
+		if backoff > d.opts.MaxBackoff || backoff <= 0 {
+			backoff = d.opts.MaxBackoff
+		}
+		next := now.Add(backoff)
+		dl.NextAt, dl.Error = &next, err.Error()
+	}
+}
+
+// Publisher returns next, also queueing a delivery of every event it
+// publishes to the hooks subscribed to it once the write commits. queued,
+// if not nil, is called when there are deliveries to make, e.g. to run the
+// job sooner than its schedule. A next that publishes in the write's
+// transaction (the outbox) stays one.
+func (d *Dispatcher) Publisher(next domain.EventPublisher, queued func()) domain.EventPublisher {
+	p := &publisher{next: next, dispatcher: d, queued: queued}
+	if _, ok := next.(domain.TxPublisher); ok {
+		return txPublisher{p}
+	}
+	return p
+}
+
+type publisher struct {
+	next       domain.EventPublisher
+	dispatcher *Dispatcher
+	queued     func()
+}
+
+func (p *publisher) Publish(ctx context.Context, e domain.Event) error {
+	err := p.next.Publish(ctx, e)
+	p.dispatch(ctx, e)
+	return err
+}
+
+// dispatch queues the deliveries of e; the service publishes after the
+// commit.
+func (p *publisher) dispatch(ctx context.Context, e domain.Event) {
+	if p.dispatcher.queue(tenant.From(ctx), e) && p.queued != nil {
+		p.queued()
+	}
+}
+
+type txPublisher struct{ *publisher }
+
+// Publish records e in the write's transaction, and queues its deliveries
+// once that commits.
+func (p txPublisher) Publish(ctx context.Context, e domain.Event) error {
+	if err := p.next.Publish(ctx, e); err != nil {
+		return err // the write is rolled back
+	}
+	repository.AfterCommit(ctx, func() { p.dispatch(ctx, e) })
+	return nil
+}
+
+func (txPublisher) PublishesInTransaction() {}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// receiver is a webhook that checks signatures and keeps the messages it
+// accepts, failing with a 503 while told to.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	fail     bool
+	received []domain.Message
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ts, _, _ := strings.Cut(strings.TrimPrefix(req.Header.Get(HeaderSignature), "t="), ",")
+	unix, _ := strconv.ParseInt(ts, 10, 64)
+	if req.Header.Get(HeaderSignature) != Sign(r.secret, time.Unix(unix, 0), body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var m domain.Message
+	json.Unmarshal(body, &m)
+	if m.ID != req.Header.Get(HeaderDelivery) || m.Name != req.Header.Get(HeaderEvent) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.received = append(r.received, m)
+}
+
+func TestDispatcher(t *testing.T) {
+	rcv := &receiver{secret: "0123456789abcdef", fail: true}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	opts := Options{Attempts: 2, Backoff: time.Minute, MaxBackoff: time.Hour, Timeout: time.Second, LogSize: 2, AllowPrivate: true}
+	d := New(srv.Client(), clk, idgen.NewSequential("h"), opts, []string{"user.created", "user.deleted"})
+	acme := tenant.With(context.Background(), "acme")
+
+	if _, err := d.Register(acme, "ann", Registration{URL: "ftp://example.com", Events: []string{"user.created"}}); err == nil {
+		t.Error("Register of an ftp URL succeeded")
+	}
+	if _, err := d.Register(acme, "ann", Registration{URL: srv.URL, Events: []string{"user.renamed"}}); err == nil {
+		t.Error("Register of an unknown event type succeeded")
+	}
+	h, err := d.Register(acme, "ann", Registration{URL: srv.URL, Events: []string{"user.created"}, Secret: rcv.secret})
+	if err != nil || h.Secret != rcv.secret {
+		t.Fatalf("Register = %+v, %v", h, err)
+	}
+	generated, _ := d.Register(context.Background(), "ann", Registration{URL: srv.URL, Events: []string{AllEvents}})
+	if len(generated.Secret) != 64 {
+		t.Errorf("generated secret %q", generated.Secret)
+	}
+	if got := d.List(acme, "ann"); len(got) != 1 || got[0].ID != h.ID || got[0].Secret != "" {
+		t.Errorf("List = %+v", got)
+	}
+
+	queued := 0
+	p := d.Publisher(domain.NewInProc(), func() { queued++ })
+	p.Publish(acme, domain.UserCreated{Resource: "user", Entity: model.User{ID: "u1", Name: "Ada"}})
+	p.Publish(acme, domain.UserDeleted{Resource: "user", ID: "u1"}) // not subscribed
+	if queued != 1 {
+		t.Errorf("queued called %d times, want 1", queued)
+	}
+
+	// Fails, is retried after the backoff and then dead
+	d.Run(context.Background())
+	log, _ := d.Deliveries(acme, "ann", h.ID)
+	if len(log) != 1 || log[0].Status != Pending || log[0].Response != http.StatusServiceUnavailable || !log[0].NextAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Fatalf("after a failure: %+v", log)
+	}
+	d.Run(context.Background()) // not due yet
+	clk.Advance(time.Minute)
+	d.Run(context.Background())
+	log, _ = d.Deliveries(acme, "ann", h.ID)
+	if log[0].Status != Dead || log[0].Attempts != 2 || log[0].Error == "" {
+		t.Fatalf("after the last attempt: %+v", log[0])
+	}
+
+	rcv.fail = false
+	if _, err := d.Redeliver(acme, "ann", h.ID, log[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	d.Run(context.Background())
+	log, _ = d.Deliveries(acme, "ann", h.ID)
+	if log[0].Status != Delivered || log[0].Response != http.StatusOK || log[0].DeliveredAt == nil {
+		t.Errorf("after redelivery: %+v", log[0])
+	}
+	if len(rcv.received) != 1 || rcv.received[0].ID != log[0].ID || rcv.received[0].AggregateID != "u1" {
+		t.Errorf("received %+v", rcv.received)
+	}
+
+	// The log keeps the newest LogSize deliveries
+	for _, id := range []string{"u2", "u3"} {
+		p.Publish(acme, domain.UserCreated{Resource: "user", Entity: model.User{ID: id}})
+	}
+	d.Run(context.Background())
+	log, _ = d.Deliveries(acme, "ann", h.ID)
+	if len(log) != 2 || log[0].AggregateID != "u3" || log[1].AggregateID != "u2" {
+		t.Errorf("log of size 2: %+v", log)
+	}
+
+	if _, err := d.Deliveries(context.Background(), "ann", h.ID); err != ErrNotFound {
+		t.Errorf("Deliveries of another tenant's hook: %v", err)
+	}
+	if got := d.List(acme, "bob"); len(got) != 0 {
+		t.Errorf("List of another account = %+v", got)
+	}
+	if _, err := d.Redeliver(acme, "bob", h.ID, log[0].ID); err != ErrNotFound {
+		t.Errorf("Redeliver of another account's hook: %v", err)
+	}
+	if err := d.Delete(acme, "bob", h.ID); err != ErrNotFound {
+		t.Errorf("Delete of another account's hook: %v", err)
+	}
+	if err := d.Delete(acme, "ann", h.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Deliveries(acme, "ann", h.ID); err != ErrNotFound {
+		t.Errorf("Deliveries of a deleted hook: %v", err)
+	}
+
+	// Without AllowPrivate, only hooks of public hosts are registered
+	opts.AllowPrivate = false
+	d = New(srv.Client(), clk, idgen.NewSequential("h"), opts, []string{"user.created"})
+	for _, u := range []string{srv.URL, "http://localhost:8080/hook", "http://10.0.0.1/hook", "http://[::1]/hook", "http://169.254.169.254/latest/meta-data"} {
+		if _, err := d.Register(acme, "ann", Registration{URL: u, Events: []string{AllEvents}}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Register of %s = %v, want ErrInvalid", u, err)
+		}
+	}
+	if _, err := d.Register(acme, "ann", Registration{URL: "https://hooks.example.com/in", Events: []string{AllEvents}}); err != nil {
+		t.Errorf("Register of a public host: %v", err)
+	}
+
+	invalid := Options{Backoff: time.Hour, MaxBackoff: time.Minute}
+	err = invalid.Validate()
+	for _, want := range []string{"attempts", "max_backoff", "timeout", "log_size"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, want an error of %s", err, want)
+		}
+	}
+}
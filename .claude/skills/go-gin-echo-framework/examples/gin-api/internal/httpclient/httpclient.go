@@ -0,0 +1,181 @@
+// Package httpclient builds the clients of every call to another service,
+// so that search, blob storage, notifications, webhooks, single sign-on and
+// remote flag rules share one tuned connection pool and the same timeouts
+// rather than http.DefaultClient, which has none.
+//
+// Each request carries the trace context of its context (W3C traceparent
+// and baggage, see go.opentelemetry.io/otel/propagation), the request ID of
+// the incoming request it serves, if any, and the configured User-Agent.
+// Retries and circuit breakers are those of the resilience package: given a
+// registry, requests go through Registry.Transport, a breaker per host.
+//
+// Requests to URLs that callers choose, such as the webhooks they register,
+// go through the Public client, which refuses to connect to the loopback,
+// private and link-local addresses of the deployment's own network.
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/your-username/gin-api/internal/requestid"
+	"github.com/your-username/gin-api/internal/resilience"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Options tune the clients and their transport.
+type Options struct {
+	Timeout               time.Duration `yaml:"timeout"`                 // of a whole exchange, body included, but for streaming clients; 0 is unbounded
+	DialTimeout           time.Duration `yaml:"dial_timeout"`            // to open a connection
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`   // to negotiate TLS on it
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"` // from the end of the request to the response headers; 0 is unbounded
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`       // before an idle connection is closed
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"` // idle connections kept for reuse per host
+	MaxConnsPerHost       int           `yaml:"max_conns_per_host"`      // connections per host, further requests wait; 0 is unlimited
+	UserAgent             string        `yaml:"user_agent"`              // sent unless a request sets its own; "" is Go's
+}
+
+// Validate checks that no setting is negative.
+func (o Options) Validate() error {
+	var errs []error
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timeout", o.Timeout},
+		{"dial_timeout", o.DialTimeout},
+		{"tls_handshake_timeout", o.TLSHandshakeTimeout},
+		{"response_header_timeout", o.ResponseHeaderTimeout},
+		{"idle_conn_timeout", o.IdleConnTimeout},
+	} {
+		if d.value < 0 {
+			errs = append(errs, errors.New(d.name+" must not be negative"))
+		}
+	}
+	if o.MaxIdleConnsPerHost < 0 || o.MaxConnsPerHost < 0 {
+		errs = append(errs, errors.New("max_idle_conns_per_host and max_conns_per_host must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// ErrNotPublic is returned by the clients of Factory.Public for a
+// connection to an address that is not public.
+var ErrNotPublic = errors.New("address is not public")
+
+// Factory makes clients sharing one transport. It is safe for concurrent
+// use, as are its clients.
+type Factory struct {
+	timeout   time.Duration
+	transport http.RoundTripper
+	public    http.RoundTripper
+}
+
+// New returns a factory of clients that send through base, or a transport
+// tuned by opts if base is nil (test mode passes one recording requests
+// instead), guarded by the breakers of breakers if not nil.
+func New(opts Options, base http.RoundTripper, breakers *resilience.Registry) *Factory {
+	public := base // one that does not dial has nothing to refuse
+	if base == nil {
+		base = newTransport(opts, nil)
+		public = newTransport(opts, refuseNotPublic)
+	}
+	propagate := func(next http.RoundTripper) http.RoundTripper {
+		if breakers != nil {
+			next = breakers.Transport(next)
+		}
+		return &propagating{
+			next:       next,
+			propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+			userAgent:  opts.UserAgent,
+		}
+	}
+	return &Factory{timeout: opts.Timeout, transport: propagate(base), public: propagate(public)}
+}
+
+// newTransport returns a transport tuned by opts whose connections are
+// checked by control, if not nil, once their address is resolved.
+func newTransport(opts Options, control func(network, address string, c syscall.RawConn) error) *http.Transport {
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second, Control: control}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}
+	if control != nil {
+		// A proxy would be the address checked, rather than the host asked for
+		t.Proxy = nil
+	}
+	return t
+}
+
+// Client returns a client whose exchanges are bounded by Options.Timeout.
+func (f *Factory) Client() *http.Client {
+	return &http.Client{Transport: f.transport, Timeout: f.timeout}
+}
+
+// Public returns a client like Client that fails with ErrNotPublic rather
+// than connect to an address that is not public (see IsPublic), checked
+// once the host name is resolved so that a name cannot be rebound to one
+// in between. It does not go through the HTTP_PROXY of the environment.
+func (f *Factory) Public() *http.Client {
+	return &http.Client{Transport: f.public, Timeout: f.timeout}
+}
+
+// IsPublic reports whether addr may be reached from the internet: it is
+// not a loopback, private, link-local, multicast or unspecified address.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// refuseNotPublic is the net.Dialer Control of the Public client.
+func refuseNotPublic(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublic(ap.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, ap.Addr())
+	}
+	return nil
+}
+
+// Streaming returns a client without Options.Timeout, for transfers that
+// take as long as their body, such as blobs; the connection and response
+// header timeouts still apply.
+func (f *Factory) Streaming() *http.Client {
+	return &http.Client{Transport: f.transport}
+}
+
+// propagating sets the headers that relate a request to its caller.
+type propagating struct {
+	next       http.RoundTripper
+	propagator propagation.TextMapPropagator
+	userAgent  string
+}
+
+func (t *propagating) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// A RoundTripper must not change the request it is given
+	req = req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if id := requestid.FromContext(ctx); id != "" && req.Header.Get(requestid.Header) == "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
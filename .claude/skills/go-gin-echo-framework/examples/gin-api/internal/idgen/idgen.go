@@ -0,0 +1,194 @@
+// Package idgen generates the IDs of new entities. The service layer takes a
+// Generator instead of formatting IDs itself, so the strategy is chosen by
+// configuration and tests can use predictable sequential IDs.
+//
+// The time-based strategies sort by creation time: UUIDv7 and ULID to the
+// millisecond, KSUID to the second. IDs created within the same tick are
+// ordered randomly. NewSeeded draws their random bits from a seed instead,
+// so that on a clock.Fake they repeat from run to run.
+package idgen
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// Generator returns a new unique ID on each call. Implementations are safe
+// for concurrent use.
+type Generator interface {
+	NewID() string
+}
+
+// Strategies are the names New accepts.
+var Strategies = []string{"uuidv7", "ulid", "ksuid", "sequential"}
+
+// New returns the Generator of the named strategy, stamping IDs by clk
+// (nil is the system clock).
+func New(strategy string, clk clock.Clock) (Generator, error) {
+	return newGenerator(strategy, clk, crand.Reader)
+}
+
+// NewSeeded is New with the random bits of IDs drawn from seed rather
+// than crypto/rand. Its IDs are predictable; use it in tests only.
+func NewSeeded(strategy string, clk clock.Clock, seed int64) (Generator, error) {
+	return newGenerator(strategy, clk, &seededReader{r: rand.New(rand.NewSource(seed))})
+}
+
+func newGenerator(strategy string, clk clock.Clock, random io.Reader) (Generator, error) {
+	clk = clock.OrSystem(clk)
+	switch strategy {
+	case "uuidv7":
+		return &UUIDv7{clk, random}, nil
+	case "ulid":
+		return &ULID{clk, random}, nil
+	case "ksuid":
+		return &KSUID{clk, random}, nil
+	case "sequential":
+		return NewSequential("id-"), nil
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q", strategy)
+	}
+}
+
+// Default generates UUIDv7s on the system clock.
+var Default Generator = NewUUIDv7(nil)
+
+// OrDefault returns g, or Default when g is nil; constructors use it so
+// that callers, tests in particular, can leave the generator unset.
+func OrDefault(g Generator) Generator {
+	if g == nil {
+		return Default
+	}
+	return g
+}
+
+// UUIDv7 generates RFC 9562 version 7 UUIDs: a millisecond timestamp
+// followed by random bits, e.g. "01913f4e-7a2c-7b3d-9f1e-2a4b6c8d0e1f".
+type UUIDv7 struct {
+	clock  clock.Clock
+	random io.Reader
+}
+
+// NewUUIDv7 stamps UUIDs by clk (nil is the system clock).
+func NewUUIDv7(clk clock.Clock) *UUIDv7 { return &UUIDv7{clock.OrSystem(clk), crand.Reader} }
+
+func (g *UUIDv7) NewID() string {
+	var b [16]byte
+	read(g.random, b[6:])
+	putMillis(b[:6], g.clock)
+	b[6] = 0x70 | b[6]&0x0f // version 7
+	b[8] = 0x80 | b[8]&0x3f // RFC 9562 variant
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// crockford is the Crockford base32 alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates ULIDs: a millisecond timestamp and 80 random bits in 26
+// Crockford base32 characters, e.g. "01J4ZQ8X3M5N7P9R1S3T5V7W9Y".
+type ULID struct {
+	clock  clock.Clock
+	random io.Reader
+}
+
+// NewULID stamps ULIDs by clk (nil is the system clock).
+func NewULID(clk clock.Clock) *ULID { return &ULID{clock.OrSystem(clk), crand.Reader} }
+
+func (g *ULID) NewID() string {
+	var b [16]byte
+	putMillis(b[:6], g.clock)
+	read(g.random, b[6:])
+	// 128 bits in 26 characters of 5 bits: the first carries 3 bits only
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// ksuidEpoch is the KSUID epoch, 2014-05-13T16:53:20Z, in Unix seconds.
+const ksuidEpoch = 1400000000
+
+// base62 is the KSUID alphabet; uppercase sorts before lowercase in ASCII.
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// KSUID generates K-Sortable Unique IDs: a second timestamp and 128 random
+// bits in 27 base62 characters, e.g. "2gXQ4uZ8pV1nK3mR7sT9wY2bC5d".
+type KSUID struct {
+	clock  clock.Clock
+	random io.Reader
+}
+
+// NewKSUID stamps KSUIDs by clk (nil is the system clock).
+func NewKSUID(clk clock.Clock) *KSUID { return &KSUID{clock.OrSystem(clk), crand.Reader} }
+
+func (g *KSUID) NewID() string {
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(g.clock.Now().Unix()-ksuidEpoch))
+	read(g.random, b[4:])
+	n := new(big.Int).SetBytes(b[:])
+	base, digit := big.NewInt(62), new(big.Int)
+	out := make([]byte, 27)
+	for i := 26; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		out[i] = base62[digit.Int64()]
+	}
+	return string(out)
+}
+
+// Sequential generates prefix followed by 1, 2, 3 and so on. Its IDs are
+// unique within the process only, which makes them fit for tests and
+// nothing else.
+type Sequential struct {
+	prefix string
+	n      atomic.Int64
+}
+
+// NewSequential counts from 1 behind prefix.
+func NewSequential(prefix string) *Sequential { return &Sequential{prefix: prefix} }
+
+func (g *Sequential) NewID() string {
+	return g.prefix + strconv.FormatInt(g.n.Add(1), 10)
+}
+
+// putMillis writes the Unix time of clk in milliseconds as 48 bits to b.
+func putMillis(b []byte, clk clock.Clock) {
+	ms := uint64(clk.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// read fills b from r.
+func read(r io.Reader, b []byte) {
+	if _, err := io.ReadFull(r, b); err != nil {
+		panic(fmt.Sprintf("idgen: failed to read random bytes: %v", err))
+	}
+}
+
+// seededReader makes a *rand.Rand safe for concurrent use.
+type seededReader struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (s *seededReader) Read(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Read(b)
+}
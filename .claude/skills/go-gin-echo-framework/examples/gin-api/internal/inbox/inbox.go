@@ -0,0 +1,85 @@
+// Package inbox makes the consumers of domain events idempotent on the SQL
+// backends. Brokers deliver at least once, and the outbox publishes an
+// event again after a crash (see package outbox), so a consumer sees some
+// messages twice, as it does when a topic is replayed. Handle records the
+// Message ID of each message in the inbox table in the same transaction as
+// the handler's writes: a message whose ID is already recorded is skipped,
+// and a handler that fails records nothing, so the redelivery is processed.
+//
+// Effects outside that transaction, such as calls to other services, may
+// still happen twice and must be idempotent themselves. Messages without
+// an ID, published without the outbox, are not deduplicated.
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// Inbox records the messages each consumer has processed.
+type Inbox struct {
+	db        *sql.DB
+	uow       repository.UnitOfWork
+	clock     clock.Clock
+	retention time.Duration
+
+	claim, purge string
+}
+
+// New keeps the processed message IDs of db for retention, which must
+// outlast the longest redelivery or replay a consumer may see. Entries are
+// stamped by clk (nil is the system clock).
+func New(db *sql.DB, dialect repository.Dialect, clk clock.Clock, retention time.Duration) *Inbox {
+	p := dialect.Placeholder
+	return &Inbox{
+		db:        db,
+		uow:       repository.NewSQLUnitOfWork(db),
+		clock:     clock.OrSystem(clk),
+		retention: retention,
+		claim:     fmt.Sprintf("INSERT INTO inbox (consumer, message_id, processed_at) VALUES (%s, %s, %s) ON CONFLICT DO NOTHING", p(1), p(2), p(3)),
+		purge:     fmt.Sprintf("DELETE FROM inbox WHERE processed_at < %s", p(1)),
+	}
+}
+
+// Handle returns fn processing each message once for consumer, a name
+// that tells this consumer's inbox entries from those of other consumers
+// of the same messages. fn runs in a transaction that repositories on the
+// inbox's database join (see repository.UnitOfWork). On Postgres, a
+// concurrent redelivery waits for the first to commit or roll back.
+func (in *Inbox) Handle(consumer string, fn domain.MessageHandler) domain.MessageHandler {
+	return func(ctx context.Context, m domain.Message) error {
+		if m.ID == "" {
+			return fn(ctx, m)
+		}
+		return in.uow.Do(ctx, func(ctx context.Context) error {
+			res, err := repository.SQLConn(ctx, in.db).ExecContext(ctx, in.claim, consumer, m.ID, in.clock.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("failed to record message %s in the inbox: %w", m.ID, err)
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return fmt.Errorf("failed to record message %s in the inbox: %w", m.ID, err)
+			} else if n == 0 {
+				slog.Debug("duplicate message skipped", "consumer", consumer, "id", m.ID, "name", m.Name)
+				return nil
+			}
+			return fn(ctx, m)
+		})
+	}
+}
+
+// Purge deletes the entries processed more than the retention ago and
+// returns how many it deleted. Schedule it as a job, like the outbox's.
+func (in *Inbox) Purge(ctx context.Context) (int64, error) {
+	res, err := in.db.ExecContext(ctx, in.purge, in.clock.Now().UTC().Add(-in.retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge inbox: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,204 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Classes of BER identifiers.
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+)
+
+// Universal tags of the types LDAP messages use.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10
+	tagSet         = 0x11
+)
+
+// maxPacket bounds the messages read, so a broken or hostile server cannot
+// make a client allocate without limit.
+const maxPacket = 16 << 20
+
+// packet is a BER element: its identifier and either the bytes of a
+// primitive value or the elements of a constructed one.
+type packet struct {
+	class       byte
+	constructed bool
+	tag         byte
+	value       []byte
+	children    []*packet
+}
+
+func constructed(class, tag byte, children ...*packet) *packet {
+	return &packet{class: class, constructed: true, tag: tag, children: children}
+}
+
+func sequence(children ...*packet) *packet {
+	return constructed(classUniversal, tagSequence, children...)
+}
+
+func primitive(class, tag byte, value []byte) *packet {
+	return &packet{class: class, tag: tag, value: value}
+}
+
+func octets(s string) *packet {
+	return primitive(classUniversal, tagOctetString, []byte(s))
+}
+
+func boolean(b bool) *packet {
+	if b {
+		return primitive(classUniversal, tagBoolean, []byte{0xff})
+	}
+	return primitive(classUniversal, tagBoolean, []byte{0})
+}
+
+func integer(tag byte, v int64) *packet {
+	// Two's complement, in as few bytes as keep the sign
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if (v < 128 && v >= -128) || len(b) == 8 {
+			break
+		}
+		v >>= 8
+	}
+	return primitive(classUniversal, tag, b)
+}
+
+// bytes returns the encoding of p.
+func (p *packet) bytes() []byte {
+	value := p.value
+	if p.constructed {
+		value = nil
+		for _, c := range p.children {
+			value = append(value, c.bytes()...)
+		}
+	}
+	id := p.class | p.tag
+	if p.constructed {
+		id |= 0x20
+	}
+	out := []byte{id}
+	if n := len(value); n < 128 {
+		out = append(out, byte(n))
+	} else {
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(append(out, 0x80|byte(len(length))), length...)
+	}
+	return append(out, value...)
+}
+
+// readPacket reads one element from r.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		n := int(first & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, errors.New("ldap: unsupported BER length")
+		}
+		length = 0
+		for range n {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxPacket {
+		return nil, fmt.Errorf("ldap: message of %d bytes is too large", length)
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, unexpected(err)
+	}
+	return parsePacket(id, value)
+}
+
+// parsePacket decodes the element of identifier id and the given value.
+func parsePacket(id byte, value []byte) (*packet, error) {
+	if id&0x1f == 0x1f {
+		return nil, errors.New("ldap: unsupported BER tag")
+	}
+	p := &packet{class: id & 0xc0, constructed: id&0x20 != 0, tag: id & 0x1f}
+	if !p.constructed {
+		p.value = value
+		return p, nil
+	}
+	for len(value) > 0 {
+		if len(value) < 2 {
+			return nil, errors.New("ldap: truncated BER element")
+		}
+		id, first := value[0], value[1]
+		value = value[2:]
+		length := int(first)
+		if first&0x80 != 0 {
+			n := int(first & 0x7f)
+			if n == 0 || n > 4 || len(value) < n {
+				return nil, errors.New("ldap: unsupported BER length")
+			}
+			length = 0
+			for _, b := range value[:n] {
+				length = length<<8 | int(b)
+			}
+			value = value[n:]
+		}
+		if length > len(value) {
+			return nil, errors.New("ldap: truncated BER element")
+		}
+		child, err := parsePacket(id, value[:length])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		value = value[length:]
+	}
+	return p, nil
+}
+
+// int returns the value of an INTEGER or ENUMERATED.
+func (p *packet) int() int64 {
+	var v int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+// child returns the i-th element of p, or an empty one if p has fewer.
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+	return &packet{}
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+// Package mapping converts between protobuf messages and domain models so
+// transports never build one from the other by hand.
+//
+// Conventions: proto3 optional fields map to the model's zero value when
+// absent, and a zero value is sent as absent; enums reject unknown and
+// UNSPECIFIED values; timestamps are normalised to UTC.
+package mapping
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/model"
+	usersv1 "github.com/your-username/gin-api/internal/pb/users/v1"
+)
+
+func UserToProto(u model.User) *usersv1.User {
+	return &usersv1.User{
+		Id:    u.ID,
+		Name:  u.Name,
+		Email: optionalString(u.Email),
+	}
+}
+
+func UserFromProto(p *usersv1.User) model.User {
+	return model.User{
+		ID:    p.GetId(),
+		Name:  p.GetName(),
+		Email: p.GetEmail(),
+	}
+}
+
+func UsersToProto(users []model.User) []*usersv1.User {
+	out := make([]*usersv1.User, len(users))
+	for i, u := range users {
+		out[i] = UserToProto(u)
+	}
+	return out
+}
+
+var opToProto = map[changes.Op]usersv1.ChangeOp{
+	changes.OpCreated: usersv1.ChangeOp_CHANGE_OP_CREATED,
+	changes.OpUpdated: usersv1.ChangeOp_CHANGE_OP_UPDATED,
+	changes.OpDeleted: usersv1.ChangeOp_CHANGE_OP_DELETED,
+}
+
+var opFromProto = map[usersv1.ChangeOp]changes.Op{
+	usersv1.ChangeOp_CHANGE_OP_CREATED: changes.OpCreated,
+	usersv1.ChangeOp_CHANGE_OP_UPDATED: changes.OpUpdated,
+	usersv1.ChangeOp_CHANGE_OP_DELETED: changes.OpDeleted,
+}
+
+// ChangeToProto fails for an Op with no proto counterpart, so a new Op cannot
+// silently go out as UNSPECIFIED.
+func ChangeToProto(c changes.Change) (*usersv1.Change, error) {
+	op, ok := opToProto[c.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown change op %q", c.Op)
+	}
+	return &usersv1.Change{
+		Seq: c.Seq,
+		Op:  op,
+		Id:  c.ID,
+		At:  timestamppb.New(c.At),
+	}, nil
+}
+
+func ChangeFromProto(p *usersv1.Change) (changes.Change, error) {
+	op, ok := opFromProto[p.GetOp()]
+	if !ok {
+		return changes.Change{}, fmt.Errorf("unknown change op %v", p.GetOp())
+	}
+	if err := p.GetAt().CheckValid(); err != nil {
+		return changes.Change{}, fmt.Errorf("invalid change timestamp: %w", err)
+	}
+	return changes.Change{
+		Seq: p.GetSeq(),
+		Op:  op,
+		ID:  p.GetId(),
+		At:  p.GetAt().AsTime(),
+	}, nil
+}
+
+func ChangesToProto(cs []changes.Change) ([]*usersv1.Change, error) {
+	out := make([]*usersv1.Change, len(cs))
+	for i, c := range cs {
+		p, err := ChangeToProto(c)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,135 @@
+package mapping
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/fakedata"
+	"github.com/your-username/gin-api/internal/model"
+	usersv1 "github.com/your-username/gin-api/internal/pb/users/v1"
+)
+
+var ops = []changes.Op{changes.OpCreated, changes.OpUpdated, changes.OpDeleted}
+
+// randomChange draws an arbitrary change, including the zero and far-future
+// instants and odd nanoseconds, in a non-UTC zone.
+func randomChange(r *rand.Rand) changes.Change {
+	at := time.Unix(r.Int63n(1<<34)-1<<33, r.Int63n(1e9)).In(time.FixedZone("X", 3600*(r.Intn(25)-12)))
+	return changes.Change{
+		Seq: r.Uint64(),
+		Op:  ops[r.Intn(len(ops))],
+		ID:  randomString(r),
+		At:  at,
+	}
+}
+
+func randomString(r *rand.Rand) string {
+	v, _ := quick.Value(reflect.TypeOf(""), r)
+	return v.String()
+}
+
+func TestUserRoundTrip(t *testing.T) {
+	modelFirst := func(id, name, email string) bool {
+		u := model.User{ID: id, Name: name, Email: email}
+		return reflect.DeepEqual(UserFromProto(UserToProto(u)), u)
+	}
+	if err := quick.Check(modelFirst, nil); err != nil {
+		t.Error(err)
+	}
+
+	protoFirst := func(id, name string, email *string) bool {
+		if email != nil && *email == "" {
+			email = nil // empty and absent are the same value; see package doc
+		}
+		p := &usersv1.User{Id: id, Name: name, Email: email}
+		return proto.Equal(UserToProto(UserFromProto(p)), p)
+	}
+	if err := quick.Check(protoFirst, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Realistic values in every locale, including non-Latin scripts
+	for tag := range fakedata.Locales {
+		for _, u := range fakedata.New(1, tag).Users(20) {
+			if got := UserFromProto(UserToProto(u)); !reflect.DeepEqual(got, u) {
+				t.Errorf("%s: round trip of %+v gave %+v", tag, u, got)
+			}
+		}
+	}
+}
+
+func TestUserEmptyEmailIsAbsent(t *testing.T) {
+	if p := UserToProto(model.User{ID: "u1"}); p.Email != nil {
+		t.Fatalf("Email = %q, want absent", *p.Email)
+	}
+	if u := UserFromProto(&usersv1.User{Id: "u1"}); u.Email != "" {
+		t.Fatalf("Email = %q, want empty", u.Email)
+	}
+}
+
+func TestChangeRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		c := randomChange(r)
+		p, err := ChangeToProto(c)
+		if err != nil {
+			t.Fatalf("ChangeToProto(%+v): %v", c, err)
+		}
+		got, err := ChangeFromProto(p)
+		if err != nil {
+			t.Fatalf("ChangeFromProto(%v): %v", p, err)
+		}
+		if !got.At.Equal(c.At) || got.At.Location() != time.UTC {
+			t.Fatalf("At = %v, want %v in UTC", got.At, c.At)
+		}
+		c.At = c.At.UTC()
+		if got != c {
+			t.Fatalf("round trip = %+v, want %+v", got, c)
+		}
+
+		back, err := ChangeToProto(got)
+		if err != nil || !proto.Equal(back, p) {
+			t.Fatalf("proto round trip = %v (%v), want %v", back, err, p)
+		}
+	}
+}
+
+func TestChangeEnumValidation(t *testing.T) {
+	if _, err := ChangeToProto(changes.Change{Op: "renamed"}); err == nil {
+		t.Error("ChangeToProto accepted an unknown op")
+	}
+	for _, op := range []usersv1.ChangeOp{usersv1.ChangeOp_CHANGE_OP_UNSPECIFIED, usersv1.ChangeOp(42)} {
+		p := &usersv1.Change{Op: op, At: timestamppb.Now()}
+		if _, err := ChangeFromProto(p); err == nil {
+			t.Errorf("ChangeFromProto accepted op %v", op)
+		}
+	}
+	for _, op := range ops {
+		if _, ok := opToProto[op]; !ok {
+			t.Errorf("op %q has no proto value", op)
+		}
+	}
+	if len(opFromProto) != len(usersv1.ChangeOp_name)-1 {
+		t.Errorf("opFromProto covers %d of %d non-UNSPECIFIED proto values", len(opFromProto), len(usersv1.ChangeOp_name)-1)
+	}
+}
+
+func TestChangeTimestampValidation(t *testing.T) {
+	for name, at := range map[string]*timestamppb.Timestamp{
+		"missing":      nil,
+		"out of range": {Seconds: 1 << 62},
+		"bad nanos":    {Seconds: 1, Nanos: -1},
+	} {
+		p := &usersv1.Change{Op: usersv1.ChangeOp_CHANGE_OP_CREATED, At: at}
+		if _, err := ChangeFromProto(p); err == nil {
+			t.Errorf("%s: ChangeFromProto accepted %v", name, at)
+		}
+	}
+}
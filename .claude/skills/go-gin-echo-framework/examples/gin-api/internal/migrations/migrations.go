@@ -0,0 +1,169 @@
+// Package migrations evolves the SQL schema used by repository.NewSQLRepository.
+// Migrations are the embedded sql/NNNN_name.sql files, applied once each in
+// name order and recorded in the schema_migrations table. They must be valid
+// for both Postgres and SQLite.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is an arbitrary key for the Postgres advisory lock that keeps
+// concurrently starting instances from applying the same migration twice.
+const lockID = 7212377
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version TEXT PRIMARY KEY,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Status lists the embedded migrations by whether the database has them.
+type Status struct {
+	Applied []string
+	Pending []string
+}
+
+// Versions returns the embedded migration names, e.g. 0001_create_users, in
+// the order they apply.
+func Versions() []string {
+	entries, _ := fs.Glob(files, "sql/*.sql")
+	versions := make([]string, len(entries))
+	for i, e := range entries {
+		versions[i] = strings.TrimSuffix(path.Base(e), ".sql")
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the versions it applied.
+func Up(ctx context.Context, db *sql.DB, dialect repository.Dialect) ([]string, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if dialect == repository.DialectPostgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+			return nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+	}
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var done []string
+	for _, v := range Versions() {
+		if applied[v] {
+			continue
+		}
+		if err := apply(ctx, conn, dialect, v); err != nil {
+			return done, err
+		}
+		done = append(done, v)
+	}
+	return done, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, dialect repository.Dialect, version string) error {
+	script, err := files.ReadFile("sql/" + version + ".sql")
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migration %s: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return fmt.Errorf("migration %s: %w", version, err)
+	}
+	insert := "INSERT INTO schema_migrations (version) VALUES (" + dialect.Placeholder(1) + ")"
+	if _, err := tx.ExecContext(ctx, insert, version); err != nil {
+		return fmt.Errorf("migration %s: failed to record: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %s: %w", version, err)
+	}
+	return nil
+}
+
+// CurrentStatus compares the embedded migrations with the database without
+// changing it. A database that was never migrated has every migration pending.
+func CurrentStatus(ctx context.Context, db *sql.DB) (Status, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		// Most likely schema_migrations does not exist yet; check that the
+		// database itself answers before reporting everything as pending.
+		if pingErr := conn.PingContext(ctx); pingErr != nil {
+			return Status{}, pingErr
+		}
+		applied = map[string]bool{}
+	}
+	var s Status
+	for _, v := range Versions() {
+		if applied[v] {
+			s.Applied = append(s.Applied, v)
+		} else {
+			s.Pending = append(s.Pending, v)
+		}
+	}
+	return s, nil
+}
+
+// Check is a readiness check that fails while migrations are pending, so an
+// instance never serves traffic against an outdated schema.
+func Check(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		s, err := CurrentStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(s.Pending) > 0 {
+			return fmt.Errorf("%d pending migration(s), next %s; restart with -migrate", len(s.Pending), s.Pending[0])
+		}
+		return nil
+	}
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := map[string]bool{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
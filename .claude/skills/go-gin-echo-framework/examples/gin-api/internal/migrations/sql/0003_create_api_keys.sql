@@ -0,0 +1,7 @@
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	name TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
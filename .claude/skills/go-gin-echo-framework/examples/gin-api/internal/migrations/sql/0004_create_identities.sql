@@ -0,0 +1,7 @@
+CREATE TABLE identities (
+	id TEXT PRIMARY KEY,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE outbox (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	aggregate_id TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	published_at TIMESTAMP,
+	failed_at TIMESTAMP
+);
+CREATE INDEX outbox_pending ON outbox (created_at, id) WHERE published_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE credentials ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX users_tenant_id ON users (tenant_id, id);
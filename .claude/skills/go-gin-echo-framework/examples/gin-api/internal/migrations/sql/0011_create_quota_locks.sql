@@ -0,0 +1,4 @@
+CREATE TABLE quota_locks (
+	scope TEXT PRIMARY KEY,
+	taken_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN custom JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,13 @@
+CREATE TABLE provisioned (
+	id TEXT PRIMARY KEY,
+	user_name TEXT NOT NULL,
+	external_id TEXT NOT NULL,
+	given_name TEXT NOT NULL,
+	family_name TEXT NOT NULL,
+	display_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	active BOOLEAN NOT NULL,
+	tenant_id TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
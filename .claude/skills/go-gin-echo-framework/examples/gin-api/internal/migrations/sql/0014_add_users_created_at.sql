@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE users SET created_at = updated_at;
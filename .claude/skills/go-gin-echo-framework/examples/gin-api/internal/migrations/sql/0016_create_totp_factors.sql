@@ -0,0 +1,7 @@
+CREATE TABLE totp_factors (
+	id TEXT PRIMARY KEY,
+	secret TEXT NOT NULL,
+	confirmed BOOLEAN NOT NULL,
+	last_step BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,173 @@
+package mock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/fakedata"
+)
+
+// Generator returns a fake value for one schema. It must be valid for the
+// schema it is registered for: a string generator returns a string, etc.
+type Generator func(f *fakedata.Faker) any
+
+// Formats are the generators for string and integer schemas by their
+// "format". Add to them before serving requests.
+var Formats = map[string]Generator{
+	"date-time": func(f *fakedata.Faker) any {
+		return epoch.Add(time.Duration(f.Rand().Int64N(int64(365 * 24 * time.Hour)))).Truncate(time.Second).Format(time.RFC3339)
+	},
+	"byte": func(f *fakedata.Faker) any {
+		return base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "%x", f.Rand().Uint32()))
+	},
+	"int64": func(f *fakedata.Faker) any { return f.Rand().Int64N(1 << 40) },
+}
+
+// Fields are the generators for scalar properties by name, matched
+// case-insensitively: first as "<schema>.<property>" (the schema's name
+// without its package, e.g. "user.name"), then as the property name, then
+// as its last _ separated word, so refresh_token uses "token". They make
+// the data look like what the API returns.
+var Fields = map[string]Generator{
+	"id":           func(f *fakedata.Faker) any { return f.ID() },
+	"subject":      func(f *fakedata.Faker) any { return f.ID() },
+	"token":        func(f *fakedata.Faker) any { return "mock." + f.ID() },
+	"key":          func(f *fakedata.Faker) any { return "mock_" + f.ID() },
+	"email":        func(f *fakedata.Faker) any { return f.Email() },
+	"name":         func(f *fakedata.Faker) any { return f.Name() },
+	"product.name": func(f *fakedata.Faker) any { return f.ProductName() },
+	"sku":          func(f *fakedata.Faker) any { return f.SKU() },
+	"price":        func(f *fakedata.Faker) any { return f.Price() },
+	"url":          func(f *fakedata.Faker) any { return "https://example.com/" + f.Word() },
+	"token_type":   func(f *fakedata.Faker) any { return "Bearer" },
+}
+
+// epoch anchors generated timestamps, so they are stable across runs.
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxDepth bounds nesting, which also ends self-referencing schemas.
+const maxDepth = 6
+
+// generator produces the body of one response.
+type generator struct {
+	schemas map[string]any
+	params  map[string]string // path parameters, echoed by properties of the same name
+	fake    *fakedata.Faker
+}
+
+// value returns fake data for schema, a decoded JSON schema. name is the
+// property the value is for, if any, qualified as in Fields.
+func (g *generator) value(schema any, name string, depth int) any {
+	s, _ := schema.(map[string]any)
+	if ref, ok := s["$ref"].(string); ok {
+		ref = strings.TrimPrefix(ref, "#/components/schemas/")
+		resolved, _ := g.schemas[ref].(map[string]any)
+		_, local, _ := strings.Cut(ref, ".")
+		return g.schema(resolved, strings.ToLower(local), name, depth)
+	}
+	return g.schema(s, "", name, depth)
+}
+
+// schema draws a value for s, which is the component self when reached
+// through a $ref.
+func (g *generator) schema(s map[string]any, self, name string, depth int) any {
+	r := g.fake.Rand()
+	if all, ok := s["allOf"].([]any); ok && len(all) > 0 {
+		return g.value(all[0], name, depth) // the spec generator only emits allOf to describe a $ref
+	}
+	if values, ok := s["enum"].([]any); ok && len(values) > 0 {
+		return values[r.IntN(len(values))]
+	}
+
+	switch s["type"] {
+	case "object":
+		out := map[string]any{}
+		if depth >= maxDepth {
+			return out
+		}
+		props, _ := s["properties"].(map[string]any)
+		names := make([]string, 0, len(props))
+		for n := range props {
+			names = append(names, n)
+		}
+		sort.Strings(names) // draw in a fixed order, so the data is stable
+		for _, n := range names {
+			qualified := n
+			if self != "" {
+				qualified = self + "." + n
+			}
+			out[n] = g.value(props[n], qualified, depth+1)
+		}
+		if values, ok := s["additionalProperties"].(map[string]any); ok {
+			for i := 0; i < 1+r.IntN(2); i++ {
+				out[g.fake.Word()] = g.value(values, "", depth+1)
+			}
+		}
+		return out
+	case "array":
+		out := []any{}
+		if depth >= maxDepth {
+			return out
+		}
+		for i := 0; i < 1+r.IntN(3); i++ {
+			out = append(out, g.value(s["items"], "", depth+1))
+		}
+		return out
+	}
+
+	if v, ok := g.field(name, depth); ok && fits(v, s["type"]) {
+		return v
+	}
+	if f, ok := Formats[fmt.Sprint(s["format"])]; ok {
+		return f(g.fake)
+	}
+	switch s["type"] {
+	case "integer":
+		return r.IntN(1000)
+	case "number":
+		return float64(r.IntN(100000)) / 100
+	case "boolean":
+		return r.IntN(2) == 1
+	}
+	return g.fake.Word() + " " + g.fake.Word() // strings, and {} accepts anything
+}
+
+// fits reports whether v, drawn from Fields, is valid for a schema of typ,
+// so a string generator for "id" leaves integer IDs alone.
+func fits(v, typ any) bool {
+	switch v.(type) {
+	case string:
+		return typ == "string" || typ == nil
+	case int, int64:
+		return typ == "integer" || typ == "number" || typ == nil
+	case float64:
+		return typ == "number" || typ == nil
+	}
+	return typ == nil
+}
+
+// field draws the value of the scalar property name from Fields, or echoes
+// the path parameter of that name on the top-level object.
+func (g *generator) field(name string, depth int) (any, bool) {
+	name = strings.ToLower(name)
+	_, prop, qualified := strings.Cut(name, ".")
+	if !qualified {
+		prop = name
+	}
+	if v, ok := g.params[prop]; ok && depth == 1 {
+		return v, true // e.g. the id of GET /users/{id}
+	}
+	candidates := []string{name, prop}
+	if i := strings.LastIndex(prop, "_"); i >= 0 {
+		candidates = append(candidates, prop[i+1:])
+	}
+	for _, c := range candidates {
+		if f, ok := Fields[c]; ok {
+			return f(g.fake), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Identity links an account at an external OAuth2/OIDC provider to a local
+// account. Its ID is "<provider>:<external subject>".
+type Identity struct {
+	ID        string    `json:"id" bson:"_id"`
+	Provider  string    `json:"provider" bson:"provider"`
+	Subject   string    `json:"subject" bson:"subject"` // local account ID
+	Email     string    `json:"email" bson:"email"`     // as reported by the provider at link time
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (i Identity) GetID() string { return i.ID }
+
+func (i *Identity) SetID(id string) { i.ID = id }
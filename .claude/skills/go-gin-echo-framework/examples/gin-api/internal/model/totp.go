@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// TOTPFactor is the authenticator app an account proves its password
+// logins with, a second factor of RFC 6238 time-based one-time codes (see
+// auth.MFAService). Its ID is the account ID; like Credential it is never
+// served as is.
+type TOTPFactor struct {
+	ID        string    `json:"id" bson:"_id"`
+	Secret    string    `json:"secret" bson:"secret"`       // base32, shared with the app
+	Confirmed bool      `json:"confirmed" bson:"confirmed"` // set once a code proved the app holds the secret; logins ask for codes from then on
+	LastStep  int64     `json:"last_step" bson:"last_step"` // time step of the last code accepted, refused a second time
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (f TOTPFactor) GetID() string { return f.ID }
+
+func (f *TOTPFactor) SetID(id string) { f.ID = id }
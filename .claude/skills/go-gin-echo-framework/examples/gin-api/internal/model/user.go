@@ -1,7 +1,64 @@
 package model
 
+import "time"
+
 type User struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	Email string `json:"email"`
+	ID        string     `json:"id" bson:"_id" readonly:"true"` // assigned by the service on create
+	Name      string     `json:"name" bson:"name" binding:"required"`
+	Email     string     `json:"email" bson:"email" binding:"omitempty,email"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at" readonly:"true"`                   // set by the service on create
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at" readonly:"true"`                   // set by the service on every write
+	TenantID  string     `json:"tenant_id,omitempty" bson:"tenant_id,omitempty" readonly:"true"` // set by the repository when tenancy is enabled
+	OwnerID   string     `json:"owner_id,omitempty" bson:"owner_id,omitempty" readonly:"true"`   // the account that created it, set by the service on create
+	Custom    Attributes `json:"custom,omitempty" bson:"custom,omitempty"`                       // see GET /users/schema
+}
+
+func (u User) GetID() string { return u.ID }
+
+func (u *User) SetID(id string) { u.ID = id }
+
+func (u User) LastModified() time.Time { return u.UpdatedAt }
+
+func (u *User) Touch(t time.Time) { u.UpdatedAt = t }
+
+func (u User) Created() time.Time { return u.CreatedAt }
+
+func (u *User) SetCreated(t time.Time) { u.CreatedAt = t }
+
+func (u User) Tenant() string { return u.TenantID }
+
+func (u *User) SetTenant(id string) { u.TenantID = id }
+
+func (u User) Owner() string { return u.OwnerID }
+
+func (u *User) SetOwner(id string) { u.OwnerID = id }
+
+func (u User) CustomFields() Attributes { return u.Custom }
+
+func (u *User) SetCustomFields(a Attributes) { u.Custom = a }
+
+// UserV2 is a User as version 2 of the HTTP API represents it: name is
+// display_name. Version 2 handlers convert with User.V2 and UserV2.V1 and
+// share the User service, hooks and storage with version 1.
+type UserV2 struct {
+	ID          string     `json:"id" readonly:"true"`
+	DisplayName string     `json:"display_name" binding:"required"`
+	Email       string     `json:"email" binding:"omitempty,email"`
+	CreatedAt   time.Time  `json:"created_at" readonly:"true"`
+	UpdatedAt   time.Time  `json:"updated_at" readonly:"true"`
+	Custom      Attributes `json:"custom,omitempty"`
+}
+
+func (u User) V2() UserV2 {
+	return UserV2{ID: u.ID, DisplayName: u.Name, Email: u.Email, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt, Custom: u.Custom}
 }
+
+func (u UserV2) V1() User {
+	return User{ID: u.ID, Name: u.DisplayName, Email: u.Email, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt, Custom: u.Custom}
+}
+
+func (u UserV2) GetID() string { return u.ID }
+
+func (u *UserV2) SetID(id string) { u.ID = id }
+
+func (u UserV2) LastModified() time.Time { return u.UpdatedAt }
@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// Requests counts the responses of an http.Handler and those that are
+// server errors. It is safe for concurrent use.
+type Requests struct {
+	total  atomic.Int64
+	errors atomic.Int64
+}
+
+// Middleware counts the responses of next.
+func (r *Requests) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+		r.total.Add(1)
+		if sw.status >= 500 {
+			r.errors.Add(1)
+		}
+	})
+}
+
+// take returns the counts and restarts them.
+func (r *Requests) take() (total, errors int64) {
+	return r.total.Swap(0), r.errors.Swap(0)
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps event streams working behind the middleware.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Monitor notifies of spikes in the rate of server errors and of growth of
+// the dead letters, each time it runs.
+type Monitor struct {
+	notifier    *Notifier
+	requests    *Requests
+	deadLetters func(ctx context.Context) (int, error)
+	clock       clock.Clock
+	opts        RateOptions
+
+	since time.Time // of the counts of requests
+	dead  int       // dead letters at the last run
+	known bool      // whether dead is known
+}
+
+// NewMonitor returns a Monitor of the responses counted by requests and the
+// dead letters counted by deadLetters, which is nil without an outbox.
+func NewMonitor(n *Notifier, requests *Requests, deadLetters func(ctx context.Context) (int, error), clk clock.Clock, opts RateOptions) *Monitor {
+	m := &Monitor{notifier: n, requests: requests, deadLetters: deadLetters, clock: clock.OrSystem(clk), opts: opts}
+	m.since = m.clock.Now()
+	return m
+}
+
+// Run posts an error_rate event if the share of 5xx responses since the
+// last run reached RateOptions.Threshold, and a dead_letters event if
+// there are more dead letters than at the last run. The first run only
+// counts the dead letters. It is the job of the monitor, whose schedule is
+// therefore the window of the error rate.
+func (m *Monitor) Run(ctx context.Context) error {
+	now := m.clock.Now()
+	total, failed := m.requests.take()
+	window := now.Sub(m.since).Round(time.Second)
+	m.since = now
+	var errs []error
+	if total >= int64(m.opts.MinRequests) && float64(failed)/float64(total) >= m.opts.Threshold {
+		errs = append(errs, m.notifier.Notify(ctx, ErrorRate, map[string]any{
+			"Rate":     float64(failed) / float64(total),
+			"Errors":   failed,
+			"Requests": total,
+			"Window":   window,
+		}))
+	}
+	if m.deadLetters != nil && m.notifier.Posts(DeadLetters) {
+		n, err := m.deadLetters(ctx)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case m.known && n > m.dead:
+			errs = append(errs, m.notifier.Notify(ctx, DeadLetters, map[string]any{"Added": n - m.dead, "Total": n}))
+		}
+		if err == nil {
+			m.dead, m.known = n, true
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// chat is a Slack-compatible webhook that keeps the messages posted to it.
+type chat struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (c *chat) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var msg struct{ Text string }
+	json.NewDecoder(r.Body).Decode(&msg)
+	c.mu.Lock()
+	c.messages = append(c.messages, strings.TrimPrefix(r.URL.Path, "/")+": "+msg.Text)
+	c.mu.Unlock()
+}
+
+func (c *chat) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.messages
+	c.messages = nil
+	return out
+}
+
+func TestNotifier(t *testing.T) {
+	c := &chat{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	opts := Options{
+		Events: map[string]Route{
+			Deploy:      {URL: secret.Secret(srv.URL + "/deploys"), Template: "{{.Version}} is live"},
+			DeadLetters: {URL: secret.Secret(srv.URL + "/alerts"), Every: time.Minute},
+		},
+		ErrorRate: RateOptions{Threshold: 0.5, MinRequests: 1},
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(srv.Client(), clk, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := n.Notify(ctx, Deploy, map[string]any{"Version": "v1.2.3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(ctx, ErrorRate, map[string]any{"Rate": 1.0}); err != nil {
+		t.Errorf("Notify of an event type not posted: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		n.Notify(ctx, DeadLetters, map[string]any{"Added": 1, "Total": i + 1})
+	}
+	clk.Advance(time.Minute)
+	n.Notify(ctx, DeadLetters, map[string]any{"Added": 1, "Total": 4})
+	want := []string{
+		"deploys: v1.2.3 is live",
+		"alerts: 1 more events dead-lettered, 1 waiting; see GET /admin/dead-letters",
+		"alerts: 1 more events dead-lettered, 4 waiting; see GET /admin/dead-letters (2 more since the last message)",
+	}
+	if got := c.take(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("posted %q, want %q", got, want)
+	}
+	if err := n.Notify(ctx, Deploy, nil); err == nil || !strings.Contains(err.Error(), "Version") {
+		t.Errorf("Notify without the fields of the template: %v", err)
+	}
+
+	invalid := Options{
+		Events: map[string]Route{
+			"outage":  {URL: "https://chat.example.com/hooks/1"},
+			Deploy:    {URL: "chat.example.com/hooks/1"},
+			ErrorRate: {URL: "https://chat.example.com/hooks/1", Template: "{{.Rate"},
+		},
+		ErrorRate: RateOptions{Threshold: 5, MinRequests: 1},
+	}
+	err = invalid.Validate()
+	for _, want := range []string{"events.outage", "events.deploy.url", "events.error_rate.template", "error_rate.threshold"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, want an error of %s", err, want)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	c := &chat{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	n, _ := New(srv.Client(), clk, Options{Events: map[string]Route{
+		ErrorRate:   {URL: secret.Secret(srv.URL + "/alerts")},
+		DeadLetters: {URL: secret.Secret(srv.URL + "/alerts")},
+	}})
+	requests := &Requests{}
+	dead := 2
+	m := NewMonitor(n, requests, func(context.Context) (int, error) { return dead, nil }, clk, RateOptions{Threshold: 0.25, MinRequests: 4})
+	h := requests.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	serve := func(paths ...string) {
+		for _, p := range paths {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+		}
+	}
+	ctx := context.Background()
+
+	serve("/fail", "/fail", "/ok") // too few requests
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Minute)
+	serve("/fail", "/ok", "/ok", "/ok", "/ok")
+	dead = 5
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Minute)
+	serve("/ok", "/ok", "/ok", "/ok")
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 1 of 5 requests failed, below the threshold
+	if got := c.take(); len(got) != 1 || got[0] != "alerts: 3 more events dead-lettered, 5 waiting; see GET /admin/dead-letters" {
+		t.Errorf("posted %q", got)
+	}
+
+	clk.Advance(time.Minute)
+	serve("/fail", "/fail", "/ok", "/ok", "/ok")
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.take(); len(got) != 1 || got[0] != "alerts: Error rate at 40.0%: 2 of 5 requests failed with a 5xx in the last 1m0s" {
+		t.Errorf("posted %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+// Package outbox makes domain event publishing reliable on the SQL
+// backends. Instead of publishing to the broker after a write commits, and
+// losing the event if the broker is down or the process dies in between,
+// the service layer records each event in the outbox table in the write's
+// own transaction (Writer). A Relay then publishes pending events to the
+// broker in order and marks them published.
+//
+// Delivery is at least once: an event published just before a crash or a
+// failed commit of its mark is published again, under the same Message ID,
+// so consumers must deduplicate (see package inbox). Events of one
+// aggregate are published in the order they were written. An event the
+// broker keeps rejecting is set aside as poison after MaxAttempts: it is
+// marked failed, with its last error, and the aggregate's later events go
+// ahead. Failed events stay in the table as dead letters, for an admin to
+// inspect and then requeue or discard (see Relay.DeadLetters).
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// Options configure the outbox and its relay.
+type Options struct {
+	Enabled      bool          `yaml:"enabled"`       // record domain events in the outbox table and relay them
+	PollInterval time.Duration `yaml:"poll_interval"` // how often the relay looks for pending events
+	BatchSize    int           `yaml:"batch_size"`    // events relayed per poll at most
+	MaxAttempts  int           `yaml:"max_attempts"`  // failed publishes before an event is set aside as poison
+	Retention    time.Duration `yaml:"retention"`     // how long published events are kept before Purge deletes them
+}
+
+// Validate checks the relay settings.
+func (o Options) Validate() error {
+	var errs []error
+	if o.PollInterval <= 0 {
+		errs = append(errs, errors.New("poll_interval must be positive"))
+	}
+	if o.BatchSize <= 0 {
+		errs = append(errs, errors.New("batch_size must be positive"))
+	}
+	if o.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("max_attempts must be positive"))
+	}
+	if o.Retention < 0 {
+		errs = append(errs, errors.New("retention must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Writer is the domain.TxPublisher that records events in the outbox table.
+type Writer struct {
+	db     *sql.DB
+	insert string
+	clock  clock.Clock
+	ids    idgen.Generator
+}
+
+// NewWriter records events in the outbox table of db, in the
+// repository.NewSQLUnitOfWork(db) transaction on the context if there is
+// one. Events are stamped by clk (nil is the system clock) and identified
+// by ids (nil is idgen.Default).
+func NewWriter(db *sql.DB, dialect repository.Dialect, clk clock.Clock, ids idgen.Generator) *Writer {
+	p := dialect.Placeholder
+	return &Writer{
+		db: db,
+		insert: fmt.Sprintf("INSERT INTO outbox (id, name, aggregate_id, payload, created_at, next_attempt_at) VALUES (%s, %s, %s, %s, %s, %s)",
+			p(1), p(2), p(3), p(4), p(5), p(5)),
+		clock: clock.OrSystem(clk),
+		ids:   idgen.OrDefault(ids),
+	}
+}
+
+// Publish records e as pending. An error fails the write the event
+// belongs to.
+func (w *Writer) Publish(ctx context.Context, e domain.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", e.EventName(), err)
+	}
+	_, err = repository.SQLConn(ctx, w.db).ExecContext(ctx, w.insert,
+		w.ids.NewID(), e.EventName(), e.AggregateID(), string(payload), w.clock.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record %s in the outbox: %w", e.EventName(), err)
+	}
+	return nil
+}
+
+// PublishesInTransaction marks Writer as a domain.TxPublisher.
+func (*Writer) PublishesInTransaction() {}
+
+// stored is an event read back from the outbox. It encodes as the event it
+// was recorded from, with its outbox ID as the Message ID.
+type stored struct {
+	id, name, aggregateID string
+	payload               json.RawMessage
+}
+
+func (e stored) EventName() string            { return e.name }
+func (e stored) AggregateID() string          { return e.aggregateID }
+func (e stored) EventID() string              { return e.id }
+func (e stored) MarshalJSON() ([]byte, error) { return e.payload, nil }
@@ -0,0 +1,370 @@
+// Package pact verifies the API, as provider, against the contracts its
+// consumers publish in the Pact format (specification v2 and v3): every
+// interaction's request is replayed against the handler and the response
+// must satisfy the interaction's expectations and matching rules. Contracts
+// are read from files consumer teams commit to pacts/ (Load) or from a Pact
+// Broker (Fetch), so a change that breaks a consumer fails the tests before
+// it is merged.
+package pact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Contract is one consumer's pact with a provider.
+type Contract struct {
+	Consumer     Pacticipant   `json:"consumer"`
+	Provider     Pacticipant   `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+	Metadata     struct {
+		PactSpecification struct {
+			Version string `json:"version"`
+		} `json:"pactSpecification"`
+	} `json:"metadata"`
+}
+
+// Pacticipant names a consumer or provider.
+type Pacticipant struct {
+	Name string `json:"name"`
+}
+
+// Interaction is one request a consumer makes and the response it relies on.
+type Interaction struct {
+	Description    string          `json:"description"`
+	ProviderState  string          `json:"providerState"`  // v2
+	ProviderStates []ProviderState `json:"providerStates"` // v3
+	Request        Request         `json:"request"`
+	Response       Response        `json:"response"`
+}
+
+// ProviderState is a precondition of an interaction, such as "a user
+// exists", set up by the provider's StateFunc of that name.
+type ProviderState struct {
+	Name   string         `json:"name"`
+	Params map[string]any `json:"params"`
+}
+
+// Request is the request a consumer sends.
+type Request struct {
+	Method     string                     `json:"method"`
+	Path       string                     `json:"path"`
+	Query      json.RawMessage            `json:"query"` // "a=1&b=2" in v2, {"a": ["1"]} in v3
+	Headers    map[string]string          `json:"headers"`
+	Body       json.RawMessage            `json:"body"`
+	Generators map[string]json.RawMessage `json:"generators"` // v3; only ProviderState generators apply to a provider
+}
+
+// Response is what the consumer expects back. Fields the consumer does not
+// read are left out and may have any value; MatchingRules loosen the rest
+// from equality to, e.g., "any string".
+type Response struct {
+	Status        int               `json:"status"`
+	Headers       map[string]string `json:"headers"`
+	Body          json.RawMessage   `json:"body"`
+	MatchingRules json.RawMessage   `json:"matchingRules"`
+}
+
+// Load reads the contracts for provider from the *.json files in dir.
+func Load(dir, provider string) ([]Contract, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Contract
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		c, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if c.Provider.Name == provider {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// Parse decodes a pact file.
+func Parse(data []byte) (Contract, error) {
+	var c Contract
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid pact: %w", err)
+	}
+	if v := c.Metadata.PactSpecification.Version; v != "" && !strings.HasPrefix(v, "2.") && !strings.HasPrefix(v, "3.") {
+		return c, fmt.Errorf("pact specification %s is not supported (2.x and 3.x are)", v)
+	}
+	return c, nil
+}
+
+// Fetch downloads the latest contract of every consumer of provider from the
+// Pact Broker at brokerURL, authenticating with token when it is set.
+func Fetch(ctx context.Context, brokerURL, token, provider string) ([]Contract, error) {
+	get := func(u string, v any) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/hal+json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: %s", u, res.Status)
+		}
+		return json.NewDecoder(res.Body).Decode(v)
+	}
+
+	var index struct {
+		Links struct {
+			Pacts []struct {
+				Href string `json:"href"`
+			} `json:"pb:pacts"`
+		} `json:"_links"`
+	}
+	if err := get(strings.TrimSuffix(brokerURL, "/")+"/pacts/provider/"+url.PathEscape(provider)+"/latest", &index); err != nil {
+		return nil, err
+	}
+	var out []Contract
+	for _, link := range index.Links.Pacts {
+		var raw json.RawMessage
+		if err := get(link.Href, &raw); err != nil {
+			return nil, err
+		}
+		c, err := Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", link.Href, err)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// StateFunc sets up a provider state and returns the values it created, which
+// the interaction's ProviderState generators substitute, as the id in
+// "/users/${id}".
+type StateFunc func(params map[string]any) (map[string]any, error)
+
+// Verifier replays interactions against a provider.
+type Verifier struct {
+	States map[string]StateFunc // by provider state name
+}
+
+// Verify sets up i's provider states, sends its request to h and reports
+// every way the response breaks the consumer's expectations. h should hold
+// no state from earlier interactions.
+func (v *Verifier) Verify(h http.Handler, i Interaction) error {
+	states := i.ProviderStates
+	if i.ProviderState != "" {
+		states = append(states, ProviderState{Name: i.ProviderState})
+	}
+	values := map[string]any{}
+	for _, s := range states {
+		setup, ok := v.States[s.Name]
+		if !ok {
+			return fmt.Errorf("provider state %q is not implemented", s.Name)
+		}
+		created, err := setup(s.Params)
+		if err != nil {
+			return fmt.Errorf("provider state %q: %w", s.Name, err)
+		}
+		for k, val := range created {
+			values[k] = val
+		}
+	}
+
+	req, err := i.Request.build(values)
+	if err != nil {
+		return err
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return i.Response.check(w.Result().StatusCode, w.Header(), w.Body.Bytes())
+}
+
+func (r Request) build(values map[string]any) (*http.Request, error) {
+	gen := map[string]map[string]generator{}
+	for part, raw := range r.Generators {
+		var g generator
+		if part == "path" && json.Unmarshal(raw, &g) == nil && g.Type != "" {
+			gen[part] = map[string]generator{"": g}
+			continue
+		}
+		var byKey map[string]generator
+		if err := json.Unmarshal(raw, &byKey); err != nil {
+			return nil, fmt.Errorf("generators.%s: %w", part, err)
+		}
+		gen[part] = byKey
+	}
+
+	path := r.Path
+	if g, ok := gen["path"][""]; ok {
+		path = fmt.Sprint(g.apply(path, values))
+	}
+
+	query := url.Values{}
+	if len(r.Query) > 0 {
+		var v2 string
+		if json.Unmarshal(r.Query, &v2) == nil {
+			parsed, err := url.ParseQuery(v2)
+			if err != nil {
+				return nil, fmt.Errorf("query: %w", err)
+			}
+			query = parsed
+		} else if err := json.Unmarshal(r.Query, &query); err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+	}
+	for name, g := range gen["query"] {
+		query.Set(name, fmt.Sprint(g.apply(query.Get(name), values)))
+	}
+
+	var body []byte
+	if len(r.Body) > 0 && string(r.Body) != "null" {
+		var doc any
+		if err := json.Unmarshal(r.Body, &doc); err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		for p, g := range gen["body"] {
+			doc = g.set(doc, parsePath(p), values)
+		}
+		body, _ = json.Marshal(doc)
+	}
+
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req := httptest.NewRequest(r.Method, target, bytes.NewReader(body))
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, g := range gen["header"] {
+		req.Header.Set(name, fmt.Sprint(g.apply(req.Header.Get(name), values)))
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// generator replaces an example value with one the provider created.
+type generator struct {
+	Type       string `json:"type"`
+	Expression string `json:"expression"`
+}
+
+// apply returns the value the generator produces in place of example: the
+// expression with ${name} replaced from values. An expression that is a
+// single ${name} keeps the value's JSON type.
+func (g generator) apply(example any, values map[string]any) any {
+	if g.Type != "ProviderState" {
+		return example // random generators only matter to the consumer's mock
+	}
+	if name, ok := strings.CutPrefix(g.Expression, "${"); ok && strings.Count(g.Expression, "${") == 1 && strings.HasSuffix(name, "}") {
+		if v, ok := values[strings.TrimSuffix(name, "}")]; ok {
+			return v
+		}
+	}
+	out := g.Expression
+	for name, v := range values {
+		out = strings.ReplaceAll(out, "${"+name+"}", fmt.Sprint(v))
+	}
+	return out
+}
+
+// set replaces the value at path in doc with the generated one.
+func (g generator) set(doc any, path []string, values map[string]any) any {
+	if len(path) == 0 {
+		return g.apply(doc, values)
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = g.set(node[path[0]], path[1:], values)
+	case []any:
+		for i := range node {
+			if path[0] == "*" || path[0] == fmt.Sprint(i) {
+				node[i] = g.set(node[i], path[1:], values)
+			}
+		}
+	}
+	return doc
+}
+
+func (r Response) check(status int, header http.Header, body []byte) error {
+	rules, err := parseRules(r.MatchingRules)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	if status != r.Status {
+		problems = append(problems, fmt.Sprintf("status is %d, want %d", status, r.Status))
+	}
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want, got := r.Headers[name], header.Get(name)
+		if got == "" {
+			problems = append(problems, fmt.Sprintf("header %s is missing", name))
+		} else if m, ok := rules.exact([]string{"headers", strings.ToLower(name)}); ok {
+			problems = append(problems, m.check("header "+name, want, got)...)
+		} else if !headerMatches(name, want, got) {
+			problems = append(problems, fmt.Sprintf("header %s is %q, want %q", name, got, want))
+		}
+	}
+	if len(r.Body) > 0 && string(r.Body) != "null" {
+		var expected, actual any
+		if err := json.Unmarshal(r.Body, &expected); err != nil {
+			return fmt.Errorf("expected body: %w", err)
+		}
+		if err := json.Unmarshal(body, &actual); err != nil {
+			problems = append(problems, fmt.Sprintf("body is not JSON: %q", body))
+		} else {
+			problems = append(problems, rules.compare([]string{"body"}, expected, actual)...)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// headerMatches compares header values, ignoring the parameters of a
+// Content-Type the consumer does not specify (e.g. charset).
+func headerMatches(name, want, got string) bool {
+	if !strings.EqualFold(name, "Content-Type") {
+		return strings.ReplaceAll(want, ", ", ",") == strings.ReplaceAll(got, ", ", ",")
+	}
+	wantType, wantParams, _ := strings.Cut(want, ";")
+	gotType, gotParams, _ := strings.Cut(got, ";")
+	if !strings.EqualFold(strings.TrimSpace(wantType), strings.TrimSpace(gotType)) {
+		return false
+	}
+	for _, p := range strings.Split(wantParams, ";") {
+		if p = strings.TrimSpace(p); p != "" && !strings.Contains(strings.ReplaceAll(gotParams, " ", ""), p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package payloadlog
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/requestid"
+)
+
+// Middleware logs the bodies of every request and its response, as the
+// client sent and received them. requestid.Middleware must run first.
+func Middleware(l *Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		req := l.CaptureRequest(c.Request)
+		w := &bodyWriter{ResponseWriter: c.Writer, body: l.NewBody()}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		l.Log(c.Request.Context(), Exchange{
+			RequestID: requestid.FromContext(c.Request.Context()),
+			Method:    c.Request.Method,
+			URL:       c.Request.URL.RequestURI(),
+			Status:    w.Status(),
+			Duration:  time.Since(start),
+			Request:   req,
+			Response:  w.body.Capture(w.Header().Get("Content-Type")),
+		})
+	}
+}
+
+// bodyWriter passes the response through while keeping a copy of its body.
+type bodyWriter struct {
+	gin.ResponseWriter
+	body *Body
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
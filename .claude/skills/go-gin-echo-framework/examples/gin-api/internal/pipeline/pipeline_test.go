@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+)
+
+func stage(name string, requires ...string) Stage[string] {
+	return Stage[string]{Name: name, Requires: requires, Middleware: name}
+}
+
+func TestNewVerifiesOrderAndDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []Stage[string]
+		wantErr string
+	}{
+		{"policy order", []Stage[string]{stage(Recovery), stage(RequestID), stage(Logging, RequestID)}, ""},
+		{"stages may be skipped", []Stage[string]{stage(Recovery), stage(RateLimit)}, ""},
+		{"misordered", []Stage[string]{stage(Logging), stage(Recovery)}, `stage "recovery" must run before "logging"`},
+		{"missing dependency", []Stage[string]{stage(Recovery), stage(Logging, RequestID)}, `stage "logging" requires "request-id" to run before it`},
+		{"duplicate", []Stage[string]{stage(Recovery), stage(Recovery)}, `stage "recovery" is installed twice`},
+		{"unknown", []Stage[string]{stage("gzip")}, `unknown stage "gzip"`},
+		{"explicit handler", []Stage[string]{stage(Handler)}, "the handler stage is implicit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.stages...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtendAndDescribe(t *testing.T) {
+	c, err := New(stage(Recovery), stage(RequestID), stage(Logging, RequestID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := c.Extend("users", stage(Auth), stage(RateLimit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(mw, ",") != "auth,ratelimit" {
+		t.Fatalf("Extend returned %v", mw)
+	}
+	if _, err := c.Extend("rpc", stage(Recovery)); err == nil {
+		t.Fatal("Extend accepted a stage already in the global chain")
+	}
+	if _, err := c.Extend("users", stage(Auth)); err == nil {
+		t.Fatal("Extend accepted auth after the scope's ratelimit")
+	}
+
+	want := "global: recovery → request-id → logging → handler\n" +
+		"users: recovery → request-id → logging → auth → ratelimit → handler"
+	if got := c.Describe(); got != want {
+		t.Fatalf("Describe =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPresetShouldLog(t *testing.T) {
+	for env, p := range Presets {
+		if p.Name != env {
+			t.Errorf("Presets[%q].Name = %q", env, p.Name)
+		}
+		if !p.ShouldLog(500) {
+			t.Errorf("%s: server errors must always be logged", env)
+		}
+	}
+	if !Presets["development"].ShouldLog(200) {
+		t.Error("development must log every request")
+	}
+	if Presets["test"].ShouldLog(404) {
+		t.Error("test must not log client errors")
+	}
+}
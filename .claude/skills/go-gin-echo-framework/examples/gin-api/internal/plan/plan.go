@@ -0,0 +1,98 @@
+// Package plan puts tenants (see package tenant) on tiers of service, e.g.
+// free, pro and enterprise. A plan sets the requests its tenant may make
+// (ratelimit.TenantMiddleware, answering 429), the feature flags it gets
+// (flags.Require, 403) and the most items of each collection it may hold
+// (service.Limited, 402).
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/your-username/gin-api/internal/ratelimit"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Plan is one tier of service.
+type Plan struct {
+	RateLimit string          `yaml:"rate_limit"` // "<requests>/<period>" shared by every caller of a tenant, across route groups; empty is unlimited
+	Features  map[string]bool `yaml:"features"`   // feature flags overriding feature_flags for the tenants on the plan, e.g. {search: false}
+	MaxItems  map[string]int  `yaml:"max_items"`  // collection -> most items a tenant may hold, e.g. {users: 100}; absent is unlimited
+}
+
+// Limit returns the rate limit of the plan. Limits are checked by Validate,
+// so parsing cannot fail on validated Options.
+func (p Plan) Limit() ratelimit.Limit {
+	limit, _ := ratelimit.ParseLimit(p.RateLimit)
+	return limit
+}
+
+// Options declare the plans and the tenants on each.
+type Options struct {
+	Tiers   map[string]Plan   `yaml:"tiers"`   // plans by name; none leaves every tenant unlimited
+	Default string            `yaml:"default"` // the plan of tenants Tenants does not list
+	Tenants map[string]string `yaml:"tenants"` // tenant -> plan
+}
+
+// Enabled reports whether any plan is declared.
+func (o Options) Enabled() bool {
+	return len(o.Tiers) > 0
+}
+
+// Validate checks that every plan named is declared and that the rate
+// limits, item counts and tenant IDs are well-formed.
+func (o Options) Validate() error {
+	if !o.Enabled() {
+		if o.Default != "" || len(o.Tenants) > 0 {
+			return errors.New("default and tenants name plans, which tiers must declare")
+		}
+		return nil
+	}
+	if _, ok := o.Tiers[o.Default]; !ok {
+		return fmt.Errorf("default must name a plan of tiers (got %q)", o.Default)
+	}
+	for _, name := range o.Names() {
+		p := o.Tiers[name]
+		if _, err := ratelimit.ParseLimit(p.RateLimit); err != nil {
+			return fmt.Errorf("tiers.%s.rate_limit: %w", name, err)
+		}
+		for collection, n := range p.MaxItems {
+			if n < 1 {
+				return fmt.Errorf("tiers.%s.max_items.%s must be positive; leave it out to allow any number", name, collection)
+			}
+		}
+	}
+	for id, name := range o.Tenants {
+		if err := tenant.Check(id); err != nil {
+			return err
+		}
+		if _, ok := o.Tiers[name]; !ok {
+			return fmt.Errorf("tenants.%s: unknown plan %q", id, name)
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the declared plans, sorted.
+func (o Options) Names() []string {
+	names := make([]string, 0, len(o.Tiers))
+	for name := range o.Tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Of returns the plan of tenant id and its name: the plan Tenants assigns
+// it, or the default. It returns false without plans or a tenant.
+func (o Options) Of(id string) (string, Plan, bool) {
+	if !o.Enabled() || id == "" {
+		return "", Plan{}, false
+	}
+	name, ok := o.Tenants[id]
+	if !ok {
+		name = o.Default
+	}
+	return name, o.Tiers[name], true
+}
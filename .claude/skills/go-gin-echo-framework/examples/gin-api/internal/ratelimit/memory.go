@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	clock     clock.Clock
+}
+
+// NewMemoryStore returns a process-local Store. Buckets are not shared
+// between instances, so use the Redis store when running several replicas.
+// Buckets refill by clk (nil is the system clock).
+func NewMemoryStore(clk clock.Clock) Store {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+		clock:   clock.OrSystem(clk),
+	}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.tokens = refill(b, now)
+	b.last = now
+
+	if b.tokens < 1 {
+		return bucketResult(limit, b.tokens, false), nil
+	}
+	b.tokens--
+	return bucketResult(limit, b.tokens, true), nil
+}
+
+func refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last).Seconds()
+	return math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate())
+}
+
+// sweep drops buckets that have refilled completely; they are
+// indistinguishable from new ones. Must be called with s.mu held.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if refill(b, now) >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+func TestMemoryStoreRefillsOverTime(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	store := NewMemoryStore(clk)
+	limit := Limit{Burst: 2, Per: time.Minute} // a token every 30s
+
+	take := func() Result {
+		t.Helper()
+		res, err := store.Take(ctx, "client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	for i := 0; i < limit.Burst; i++ {
+		if res := take(); !res.Allowed {
+			t.Fatalf("take %d of the burst was refused", i+1)
+		}
+	}
+	res := take()
+	if res.Allowed || res.RetryAfter != 30*time.Second || res.Reset != time.Minute {
+		t.Fatalf("empty bucket: %+v, want refused with RetryAfter 30s and Reset 1m", res)
+	}
+
+	clk.Advance(29 * time.Second)
+	if res := take(); res.Allowed {
+		t.Fatal("allowed before a token was refilled")
+	}
+	clk.Advance(time.Second)
+	if res := take(); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after 30s: %+v, want allowed with nothing remaining", res)
+	}
+	clk.Advance(time.Hour)
+	if res := take(); !res.Allowed || res.Remaining != limit.Burst-1 {
+		t.Fatalf("after an hour: %+v, want a full bucket", res)
+	}
+}
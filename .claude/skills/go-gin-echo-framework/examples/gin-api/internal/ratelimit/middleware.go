@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Middleware enforces the limit returned by limitFn per caller (as recorded
+// by auth.Identify, which must run first) or, for anonymous requests, per
+// client IP, for the route group named scope. limitFn is consulted on every
+// request so limits can be reloaded at runtime. Buckets are keyed by scope so
+// each group gets its own budget. When the store is unavailable requests are
+// let through rather than failing closed.
+func Middleware(store Store, scope string, limitFn func() Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if take(c, store, scope+":"+clientKey(c), limitFn(), "Rate limit exceeded") {
+			c.Next()
+		}
+	}
+}
+
+// TenantMiddleware enforces the limit limitFn returns for the tenant of
+// each request (see package tenant), e.g. that of its plan, as one budget
+// shared by every caller of the tenant across route groups. It runs inside
+// Middleware, whose RateLimit headers it replaces with its own. Requests
+// without a tenant pass.
+func TenantMiddleware(store Store, limitFn func(tenantID string) Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := tenant.From(c.Request.Context())
+		if id == "" || take(c, store, "tenant:"+id, limitFn(id), "Rate limit of the tenant's plan exceeded") {
+			c.Next()
+		}
+	}
+}
+
+// take takes a token from the bucket of key, setting the RateLimit headers,
+// and reports whether the request may proceed; if not, it has answered 429
+// with message.
+func take(c *gin.Context, store Store, key string, limit Limit, message string) bool {
+	if limit.Unlimited() {
+		return true
+	}
+	res, err := store.Take(c.Request.Context(), key, limit)
+	if err != nil {
+		log.Printf("WARNING: rate limiter unavailable: %v", err)
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", seconds(res.Reset))
+
+	if !res.Allowed {
+		c.Header("Retry-After", seconds(res.RetryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
+func clientKey(c *gin.Context) string {
+	if caller := auth.CallerFromContext(c.Request.Context()); caller != nil {
+		return "caller:" + caller.Subject // one budget per account, across its tokens and keys
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds renders d as whole seconds, rounding up so clients never retry early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/lifecycle"
+)
+
+// Limit describes a token bucket: Burst tokens, refilled evenly over Per.
+// "100/m" allows bursts of 100 requests and sustains 100 requests per minute.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// Rate returns the refill rate in tokens per second.
+func (l Limit) Rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Per)
+}
+
+// ParseLimit parses "<requests>/<period>" where period is s, m, h or a Go
+// duration such as 30s. An empty string or "off" yields a zero Limit (unlimited).
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+	var per time.Duration
+	switch period {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(period)
+		if err != nil || per <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: bad period", s)
+		}
+	}
+	return Limit{Burst: n, Per: per}, nil
+}
+
+// Unlimited reports whether l imposes no limit.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Per <= 0
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token is available; zero when Allowed
+}
+
+// Store holds token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucketResult derives a Result from the token count left after a take attempt.
+func bucketResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.Rate()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+// NewStore builds the Store for backend ("memory" or "redis").
+func NewStore(ctx context.Context, lc *lifecycle.Manager, backend, redisURL string, clk clock.Clock) (Store, error) {
+	switch backend {
+	case "redis":
+		return NewRedisStore(ctx, lc, redisURL, "ratelimit:", clk)
+	case "memory", "":
+		return NewMemoryStore(clk), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", backend)
+	}
+}
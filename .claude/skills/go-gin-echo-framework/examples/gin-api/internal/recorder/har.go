@@ -0,0 +1,175 @@
+package recorder
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR is an HTTP Archive 1.2 document
+// (http://www.softwareishard.com/blog/har-12-spec/), as imported by browser
+// developer tools and API clients.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"` // -1: unknown
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary bodies
+}
+
+// HARTimings holds the phases HAR requires. The recorder only knows the
+// total, which is reported as wait.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ToHAR converts exchanges, e.g. from Exchanges, into a HAR document created
+// by the named application. Bodies and headers are exported as recorded, so
+// redacted values stay redacted; truncated bodies are flagged in the entry's
+// comment.
+func ToHAR(exchanges []Exchange, creator string) HAR {
+	entries := make([]HAREntry, 0, len(exchanges))
+	for _, e := range exchanges {
+		entries = append(entries, harEntry(e))
+	}
+	return HAR{Log: HARLog{Version: "1.2", Creator: HARCreator{Name: creator, Version: "1.0"}, Entries: entries}}
+}
+
+func harEntry(e Exchange) HAREntry {
+	query := []HARNameValue{}
+	if _, raw, ok := strings.Cut(e.URL, "?"); ok {
+		values, _ := url.ParseQuery(raw)
+		query = nameValues(values)
+	}
+	req := HARRequest{
+		Method:      e.Method,
+		URL:         e.Origin + e.URL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HARNameValue{},
+		Headers:     nameValues(e.Request.Header),
+		QueryString: query,
+		HeadersSize: -1,
+		BodySize:    len(e.Request.Body),
+	}
+	if e.Request.Body != "" {
+		req.PostData = &HARPostData{MimeType: e.Request.Header.Get("Content-Type"), Text: e.Request.Body}
+	}
+	res := HARResponse{
+		Status:      e.Status,
+		StatusText:  http.StatusText(e.Status),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HARNameValue{},
+		Headers:     nameValues(e.Response.Header),
+		Content: HARContent{
+			Size:     len(e.Response.Body),
+			MimeType: e.Response.Header.Get("Content-Type"),
+			Text:     e.Response.Body,
+		},
+		RedirectURL: e.Response.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(e.Response.Body),
+	}
+	if res.Content.MimeType == "" {
+		res.Content.MimeType = "application/octet-stream"
+	}
+	if !utf8.ValidString(res.Content.Text) {
+		res.Content.Text, res.Content.Encoding = base64.StdEncoding.EncodeToString([]byte(res.Content.Text)), "base64"
+	}
+
+	var notes []string
+	if e.Request.Truncated {
+		notes = append(notes, "request body truncated")
+	}
+	if e.Response.Truncated {
+		notes = append(notes, "response body truncated")
+	}
+	return HAREntry{
+		StartedDateTime: e.Time,
+		Time:            e.DurationMS,
+		Request:         req,
+		Response:        res,
+		Timings:         HARTimings{Wait: e.DurationMS},
+		Comment:         strings.Join(notes, "; "),
+	}
+}
+
+// nameValues flattens headers or query values into HAR pairs, sorted by
+// name so exports are stable.
+func nameValues(m map[string][]string) []HARNameValue {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []HARNameValue{}
+	for _, name := range names {
+		for _, v := range m[name] {
+			out = append(out, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/requestid"
+)
+
+// Middleware records the requests the active filter selects, matching routes
+// by their registered pattern (c.FullPath) and callers as identified by
+// auth.Identify, which must run first.
+func Middleware(rec *Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var subject string
+		if caller := auth.CallerFromContext(c.Request.Context()); caller != nil {
+			subject = caller.Subject
+		}
+		if !rec.Wants(c.Request.Method, c.FullPath(), subject) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		req := rec.CaptureRequest(c.Request)
+		w := &bodyWriter{ResponseWriter: c.Writer, body: rec.NewBody()}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		rec.Add(Exchange{
+			Time:       start,
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			RequestID:  requestid.FromContext(c.Request.Context()),
+			Method:     c.Request.Method,
+			Origin:     origin(c.Request),
+			URL:        c.Request.URL.RequestURI(),
+			Route:      c.FullPath(),
+			Subject:    subject,
+			Status:     w.Status(),
+			Request:    req,
+			Response:   w.body.Message(w.Header()),
+		})
+	}
+}
+
+// bodyWriter passes the response through while keeping a copy of its body.
+type bodyWriter struct {
+	gin.ResponseWriter
+	body *Body
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
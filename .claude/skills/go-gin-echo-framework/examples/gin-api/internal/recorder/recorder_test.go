@@ -0,0 +1,141 @@
+package recorder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/redact"
+)
+
+func TestRecorderKeepsTheMostRecentExchanges(t *testing.T) {
+	rec := New(Options{Capacity: 2, MaxBodyBytes: 16})
+	if rec.Wants("GET", "/users/", "") {
+		t.Fatal("a new recorder records")
+	}
+	if err := rec.Start(Filter{Method: "GET"}); err == nil {
+		t.Fatal("Start accepted a filter without route or subject")
+	}
+	if err := rec.Start(Filter{Route: "/users/:id", Subject: "user-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Wants("GET", "/users/:id", "user-1") || rec.Wants("GET", "/users/:id", "user-2") || rec.Wants("GET", "/users/", "user-1") {
+		t.Fatal("filter fields are not all matched")
+	}
+
+	for _, url := range []string{"/users/1", "/users/2", "/users/3"} {
+		rec.Add(Exchange{URL: url})
+	}
+	got := rec.Exchanges()
+	if len(got) != 2 || got[0].URL != "/users/2" || got[1].URL != "/users/3" || got[1].ID != 3 {
+		t.Fatalf("exchanges = %+v, want /users/2 and /users/3 with IDs 2 and 3", got)
+	}
+
+	rec.Stop()
+	if s := rec.Status(); s.Recording || s.Count != 2 {
+		t.Fatalf("status after Stop = %+v, want stopped with 2 exchanges", s)
+	}
+	rec.Clear()
+	if s := rec.Status(); s.Count != 0 {
+		t.Fatalf("status after Clear = %+v, want no exchanges", s)
+	}
+}
+
+func TestRecorderRedacts(t *testing.T) {
+	rec := New(Options{
+		Capacity:      1,
+		MaxBodyBytes:  1 << 10,
+		RedactHeaders: []string{"authorization"},
+		RedactFields:  []string{"Password", "token"},
+	})
+	rec.Add(Exchange{
+		URL: "/login?token=abc&next=%2Fhome",
+		Request: Message{
+			Header: http.Header{"Authorization": {"Bearer abc"}, "Content-Type": {"application/json"}},
+			Body:   `{"email":"a@example.com","password":"hunter22","devices":[{"token":"t","id":7}]}`,
+		},
+		Response: Message{
+			Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+			Body:   "token=abc&ok=1",
+		},
+	})
+	e := rec.Exchanges()[0]
+
+	if e.URL != "/login?next=%2Fhome&token=%5BREDACTED%5D" {
+		t.Errorf("url = %s", e.URL)
+	}
+	if got := e.Request.Header.Get("Authorization"); got != Redacted {
+		t.Errorf("Authorization = %q, want redacted", got)
+	}
+	if want := `{"devices":[{"id":7,"token":"[REDACTED]"}],"email":"a@example.com","password":"[REDACTED]"}`; e.Request.Body != want {
+		t.Errorf("request body = %s, want %s", e.Request.Body, want)
+	}
+	if want := "ok=1&token=%5BREDACTED%5D"; e.Response.Body != want {
+		t.Errorf("response body = %s, want %s", e.Response.Body, want)
+	}
+
+	rec.Add(Exchange{Request: Message{Header: http.Header{"Content-Type": {"application/json"}}, Body: `{"password":"hun`}})
+	if got := rec.Exchanges()[0].Request.Body; got != redact.Unparseable {
+		t.Errorf("truncated JSON body = %s, want it replaced", got)
+	}
+}
+
+func TestToHAR(t *testing.T) {
+	har := ToHAR([]Exchange{{
+		Method: "POST",
+		Origin: "https://api.example.com",
+		URL:    "/users/?dry_run=true",
+		Status: 201,
+		Request: Message{
+			Header: http.Header{"Content-Type": {"application/json"}},
+			Body:   `{"name":"A"}`,
+		},
+		Response: Message{
+			Header:    http.Header{"Content-Type": {"application/octet-stream"}},
+			Body:      "\xff\x00",
+			Truncated: true,
+		},
+	}}, "test")
+
+	if har.Log.Version != "1.2" || har.Log.Creator.Name != "test" || len(har.Log.Entries) != 1 {
+		t.Fatalf("log = %+v", har.Log)
+	}
+	e := har.Log.Entries[0]
+	if e.Request.URL != "https://api.example.com/users/?dry_run=true" {
+		t.Errorf("url = %s", e.Request.URL)
+	}
+	if q := e.Request.QueryString; len(q) != 1 || q[0] != (HARNameValue{Name: "dry_run", Value: "true"}) {
+		t.Errorf("queryString = %+v", q)
+	}
+	if e.Request.PostData == nil || e.Request.PostData.MimeType != "application/json" || e.Request.PostData.Text != `{"name":"A"}` {
+		t.Errorf("postData = %+v", e.Request.PostData)
+	}
+	if e.Response.StatusText != "Created" || e.Response.Content.Encoding != "base64" || e.Response.Content.Text != "/wA=" {
+		t.Errorf("response = %+v", e.Response)
+	}
+	if e.Comment != "response body truncated" {
+		t.Errorf("comment = %q", e.Comment)
+	}
+}
+
+func TestCaptureRequestLeavesTheBodyReadable(t *testing.T) {
+	rec := New(Options{Capacity: 1, MaxBodyBytes: 4})
+	req := httptest.NewRequest(http.MethodPost, "/users/", strings.NewReader("0123456789"))
+
+	m := rec.CaptureRequest(req)
+	if m.Body != "0123" || !m.Truncated {
+		t.Fatalf("captured %q (truncated %v), want 0123 truncated", m.Body, m.Truncated)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "0123456789" {
+		t.Fatalf("handler reads %q, want the whole body", body)
+	}
+
+	b := rec.NewBody()
+	b.Write([]byte("ab"))
+	b.Write([]byte("cdef"))
+	if m := b.Message(http.Header{}); m.Body != "abcd" || !m.Truncated {
+		t.Fatalf("response capture = %q (truncated %v), want abcd truncated", m.Body, m.Truncated)
+	}
+}
@@ -0,0 +1,146 @@
+// Package redact replaces sensitive values in captured traffic before it is
+// stored or logged: headers, query parameters and form fields by name, and
+// JSON fields by name at any depth or by dotted path from the root of the
+// body, such as user.password. Elements of an array are matched by the path
+// of the array, so items.token matches the token of every item.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the value of every redacted header and field.
+const Redacted = "[REDACTED]"
+
+// Unparseable replaces JSON bodies that cannot be decoded, e.g. because they
+// were truncated, since their fields cannot be redacted one by one.
+const Unparseable = "[REDACTED: unparseable JSON body]"
+
+// Validate reports field rules that match nothing, such as a.b. or "".
+func Validate(fields []string) error {
+	for _, f := range fields {
+		if f == "" || strings.HasPrefix(f, ".") || strings.HasSuffix(f, ".") || strings.Contains(f, "..") {
+			return fmt.Errorf("field %q must be a name, e.g. password, or a dotted path, e.g. user.password", f)
+		}
+	}
+	return nil
+}
+
+// Redactor applies one set of rules. It is safe for concurrent use.
+type Redactor struct {
+	headers map[string]bool // canonical header names
+	names   map[string]bool // lower-cased field names matched at any depth
+	paths   map[string]bool // lower-cased dotted paths matched from the root
+}
+
+// New redacts the headers (case-insensitive) and fields (names or dotted
+// paths, case-insensitive) given; fields should pass Validate.
+func New(headers, fields []string) *Redactor {
+	r := &Redactor{headers: map[string]bool{}, names: map[string]bool{}, paths: map[string]bool{}}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, f := range fields {
+		if f = strings.ToLower(f); strings.Contains(f, ".") {
+			r.paths[f] = true
+		} else {
+			r.names[f] = true
+		}
+	}
+	return r
+}
+
+// Header redacts h in place.
+func (r *Redactor) Header(h http.Header) {
+	for name := range h {
+		if r.headers[name] {
+			h[name] = []string{Redacted}
+		}
+	}
+}
+
+// Body returns body, of the media type in contentType, with the fields
+// redacted. Bodies other than JSON and forms are returned as they are.
+func (r *Redactor) Body(contentType, body string) string {
+	if body == "" || len(r.names)+len(r.paths) == 0 {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return r.json(body)
+	case mediaType == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(body); err == nil {
+			return r.values(values).Encode()
+		}
+	}
+	return body
+}
+
+// URL redacts the fields in the query of a path and query. Query
+// parameters are matched by name only.
+func (r *Redactor) URL(raw string) string {
+	path, query, ok := strings.Cut(raw, "?")
+	if !ok || len(r.names) == 0 {
+		return raw
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return path + "?" + Redacted
+	}
+	return path + "?" + r.values(values).Encode()
+}
+
+func (r *Redactor) json(body string) string {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber() // keep numbers exactly as sent
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return Unparseable
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r.value(v, "")); err != nil {
+		return Unparseable
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// value redacts v, found at path ("" at the root).
+func (r *Redactor) value(v any, path string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			p := strings.ToLower(k)
+			if path != "" {
+				p = path + "." + p
+			}
+			if r.names[strings.ToLower(k)] || r.paths[p] {
+				v[k] = Redacted
+			} else {
+				v[k] = r.value(item, p)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.value(item, path)
+		}
+	}
+	return v
+}
+
+func (r *Redactor) values(values url.Values) url.Values {
+	for k := range values {
+		if r.names[strings.ToLower(k)] {
+			values[k] = []string{Redacted}
+		}
+	}
+	return values
+}
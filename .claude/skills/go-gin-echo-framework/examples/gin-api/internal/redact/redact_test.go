@@ -0,0 +1,45 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	r := New([]string{"authorization"}, []string{"Password", "user.token", "items.secret"})
+
+	h := http.Header{"Authorization": {"Bearer abc"}, "Accept": {"*/*"}}
+	r.Header(h)
+	if h.Get("Authorization") != Redacted || h.Get("Accept") != "*/*" {
+		t.Errorf("header = %v", h)
+	}
+
+	body := `{"password":"a","token":"b","user":{"password":"c","token":"d","profile":{"token":"e"}},"items":[{"secret":"f"},{"secret":"g","id":1}]}`
+	want := `{"items":[{"secret":"[REDACTED]"},{"id":1,"secret":"[REDACTED]"}],"password":"[REDACTED]","token":"b","user":{"password":"[REDACTED]","profile":{"token":"e"},"token":"[REDACTED]"}}`
+	if got := r.Body("application/json; charset=utf-8", body); got != want {
+		t.Errorf("JSON body = %s, want %s", got, want)
+	}
+	if got := r.Body("application/json", `{"password":"a`); got != Unparseable {
+		t.Errorf("truncated JSON body = %s, want it replaced", got)
+	}
+	if got := r.Body("application/x-www-form-urlencoded", "password=a&ok=1"); got != "ok=1&password=%5BREDACTED%5D" {
+		t.Errorf("form body = %s", got)
+	}
+	if got := r.Body("text/plain", "password=a"); got != "password=a" {
+		t.Errorf("text body = %s, want it as is", got)
+	}
+	if got := r.URL("/login?password=a&next=%2F"); got != "/login?next=%2F&password=%5BREDACTED%5D" {
+		t.Errorf("url = %s", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]string{"password", "user.token"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"", ".token", "user.", "user..token"} {
+		if err := Validate([]string{f}); err == nil {
+			t.Errorf("Validate accepted %q", f)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package render
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// column is a struct field rendered under its JSON name, so every format
+// names fields alike.
+type column struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func columns(t reflect.Type) []column {
+	var out []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, column{name: name, index: f.Index, omitEmpty: strings.Contains(opts, "omitempty")})
+	}
+	return out
+}
+
+// elements returns the elements of v when it is a slice or array.
+func elements(v any) ([]any, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, true
+}
+
+// record dereferences v to the struct it points to, if any.
+func record(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
+
+// text formats a scalar as JSON would, without quotes, and a nil map, e.g.
+// of absent custom fields, as empty; ok is false for values that have no
+// flat form (structs, maps, slices).
+func text(v reflect.Value) (s string, ok bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", true
+		}
+		v = v.Elem()
+	}
+	if m, isText := v.Interface().(encoding.TextMarshaler); isText {
+		b, err := m.MarshalText()
+		return string(b), err == nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	case reflect.Map:
+		return "", v.IsNil()
+	}
+	return "", false
+}
+
+// encodeCSV writes one row per record under a header of the JSON field
+// names; nested values are written as JSON. v is a struct or a slice of
+// them, and an empty slice still gets its header.
+func encodeCSV(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	var rows []reflect.Value
+	elem := rv.Type()
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		elem = elem.Elem()
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, rv.Index(i))
+		}
+	} else {
+		rows = []reflect.Value{rv}
+	}
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("render: %T cannot be written as CSV rows", v)
+	}
+
+	cols := columns(elem)
+	cw := csv.NewWriter(w)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(cols))
+	for _, r := range rows {
+		r, ok := record(r)
+		if !ok {
+			continue // nil element
+		}
+		for i, c := range cols {
+			field := r.FieldByIndex(c.index)
+			s, ok := text(field)
+			if !ok {
+				b, err := json.Marshal(field.Interface())
+				if err != nil {
+					return err
+				}
+				s = string(b)
+			}
+			row[i] = s
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// encodeXML writes a struct as an element named after its type, with one
+// child element per field under its JSON name; a slice is wrapped in
+// <items>.
+func encodeXML(w io.Writer, v any) error {
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	rv := reflect.ValueOf(v)
+	var err error
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		err = writeElement(enc, "items", rv)
+	} else {
+		err = writeElement(enc, elementName(rv), rv)
+	}
+	if err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func writeElement(enc *xml.Encoder, name string, v reflect.Value) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if s, ok := text(v); ok {
+		if err := enc.EncodeToken(xml.CharData(s)); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	if r, ok := record(v); ok {
+		for _, c := range columns(r.Type()) {
+			field := r.FieldByIndex(c.index)
+			if c.omitEmpty && field.IsZero() {
+				continue
+			}
+			if err := writeElement(enc, xmlName(c.name), field); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := writeElement(enc, elementName(v.Index(i)), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			if err := writeElement(enc, xmlName(fmt.Sprint(k)), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// elementName names a record's element after its type in snake case.
+func elementName(v reflect.Value) string {
+	t := v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if r, ok := record(v); ok {
+		t = r.Type()
+	}
+	if t.Name() == "" || t.Kind() != reflect.Struct {
+		return "item"
+	}
+	var b strings.Builder
+	for i, r := range t.Name() {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// xmlName makes a JSON key a valid element name.
+func xmlName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || !unicode.IsLetter(rune(name[0])) && name[0] != '_' {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type lineItem struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Qty       int       `json:"qty"`
+	Note      string    `json:"note,omitempty"`
+	Tags      []string  `json:"tags"`
+	UpdatedAt time.Time `json:"updated_at"`
+	secret    string
+}
+
+var (
+	at    = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	items = []lineItem{
+		{ID: "1", Name: `Lamp, "Nova"`, Qty: 2, Tags: []string{"a", "b"}, UpdatedAt: at},
+		{ID: "2", Name: "Desk & Chair", Qty: 1, Note: "x", UpdatedAt: at, secret: "s"},
+	}
+)
+
+func encode(t *testing.T, f Format, v any) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := f.Encode(&b, v); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                                "application/json",
+		"*/*":                             "application/json",
+		"text/csv":                        "text/csv",
+		"text/*":                          "text/csv",
+		"application/x-ndjson, */*;q=0.1": "application/x-ndjson",
+		"application/json;q=0.5, application/xml":                         "application/xml",
+		"text/*;q=0.2, */*;q=0.1, text/csv;q=0":                           "application/json", // the specific range wins
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": "application/xml",
+	} {
+		f, ok := Default.Negotiate(accept)
+		if !ok || f.MediaType != want {
+			t.Errorf("Negotiate(%q) = %q, %v, want %q", accept, f.MediaType, ok, want)
+		}
+	}
+	if f, ok := Default.Negotiate("image/png"); ok {
+		t.Errorf("Negotiate(image/png) = %q, want none", f.MediaType)
+	}
+}
+
+func TestRegisterReplaces(t *testing.T) {
+	r := NewRegistry(JSON, CSV)
+	custom := Format{MediaType: "text/csv", Encode: encodeJSON}
+	r.Register(custom)
+	if got := r.MediaTypes(); len(got) != 2 || got[1] != "text/csv" {
+		t.Fatalf("MediaTypes() = %q", got)
+	}
+	if f, _ := r.Negotiate("text/csv"); encode(t, f, 1) != "1\n" {
+		t.Error("the replaced encoder is still used")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	want := "id,name,qty,note,tags,updated_at\n" +
+		"1,\"Lamp, \"\"Nova\"\"\",2,,\"[\"\"a\"\",\"\"b\"\"]\",2026-03-01T12:00:00Z\n" +
+		"2,Desk & Chair,1,x,null,2026-03-01T12:00:00Z\n"
+	if got := encode(t, CSV, items); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := encode(t, CSV, []*lineItem{}); got != "id,name,qty,note,tags,updated_at\n" {
+		t.Errorf("empty list: %q", got)
+	}
+	if got := encode(t, CSV, &items[1]); got != "id,name,qty,note,tags,updated_at\n2,Desk & Chair,1,x,null,2026-03-01T12:00:00Z\n" {
+		t.Errorf("single record: %q", got)
+	}
+	if err := CSV.Encode(&bytes.Buffer{}, []int{1}); err == nil {
+		t.Error("a list of numbers was written as CSV")
+	}
+}
+
+func TestXML(t *testing.T) {
+	want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<items><line_item><id>1</id><name>Lamp, &#34;Nova&#34;</name><qty>2</qty><tags><item>a</item><item>b</item></tags><updated_at>2026-03-01T12:00:00Z</updated_at></line_item>` +
+		`<line_item><id>2</id><name>Desk &amp; Chair</name><qty>1</qty><note>x</note><tags></tags><updated_at>2026-03-01T12:00:00Z</updated_at></line_item></items>` + "\n"
+	if got := encode(t, XML, items); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := encode(t, XML, &items[1]); !strings.Contains(got, "?>\n<line_item><id>2</id>") {
+		t.Errorf("single record: %s", got)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	first := `{"id":"1","name":"Lamp, \"Nova\"","qty":2,"tags":["a","b"],"updated_at":"2026-03-01T12:00:00Z"}` + "\n"
+	second := `{"id":"2","name":"Desk \u0026 Chair","qty":1,"note":"x","tags":null,"updated_at":"2026-03-01T12:00:00Z"}` + "\n"
+	if got := encode(t, NDJSON, items); got != first+second {
+		t.Errorf("got\n%s\nwant\n%s", got, first+second)
+	}
+	if got := encode(t, NDJSON, items[0]); got != first {
+		t.Errorf("single record: %s", got)
+	}
+}
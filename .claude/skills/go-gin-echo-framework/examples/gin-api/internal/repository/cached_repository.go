@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/your-username/gin-api/internal/cache"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/schema"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// cachedRepository decorates a CrudRepository with read-through caching.
+// Reads are served from the cache when possible; any write invalidates the
+// affected entry and the cached list. Inside a UnitOfWork reads bypass the
+// cache, so uncommitted data is never cached, and invalidation waits for the
+// commit. Entries are stamped with the schema version of T, so that those
+// written by an older deployment are upgraded as they are read.
+type cachedRepository[T model.Entity] struct {
+	next      CrudRepository[T]
+	store     cache.Store
+	ttl       time.Duration
+	metrics   *cache.Metrics
+	namespace string
+}
+
+// Refresher is implemented by repositories that can reload what they cache
+// ahead of a read, such as the ones returned by NewCachedRepository.
+type Refresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// NewCachedRepository caches next under keys derived from namespace, e.g.
+// "users:all" and "users:id:<id>", prefixed with the tenant on the context
+// if there is one ("tenant:acme:users:all"), so tenants never share entries.
+func NewCachedRepository[T model.Entity](next CrudRepository[T], namespace string, store cache.Store, ttl time.Duration, metrics *cache.Metrics) CrudRepository[T] {
+	return &cachedRepository[T]{
+		next:      next,
+		store:     store,
+		ttl:       ttl,
+		metrics:   metrics,
+		namespace: namespace,
+	}
+}
+
+// keyAll is the key of the cached list.
+func (r *cachedRepository[T]) keyAll(ctx context.Context) string {
+	return r.prefix(ctx) + ":all"
+}
+
+// keyID is the key of the cached record id.
+func (r *cachedRepository[T]) keyID(ctx context.Context, id string) string {
+	return r.prefix(ctx) + ":id:" + id
+}
+
+func (r *cachedRepository[T]) prefix(ctx context.Context) string {
+	if id := tenant.From(ctx); id != "" {
+		return "tenant:" + id + ":" + r.namespace
+	}
+	return r.namespace
+}
+
+func (r *cachedRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	if InTransaction(ctx) {
+		return r.next.GetAll(ctx)
+	}
+	var items []T
+	if r.load(ctx, r.keyAll(ctx), &items) {
+		return items, nil
+	}
+
+	items, err := r.next.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.save(ctx, r.keyAll(ctx), items)
+	return items, nil
+}
+
+// Refresh reloads the cached list from next, so that list reads keep
+// hitting the cache when it is refreshed more often than it expires.
+func (r *cachedRepository[T]) Refresh(ctx context.Context) error {
+	items, err := r.next.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	r.save(ctx, r.keyAll(ctx), items)
+	return nil
+}
+
+// Stream always reads from next: caching a stream would mean holding the
+// whole list, which is what streaming avoids.
+func (r *cachedRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	return r.next.Stream(ctx, fn)
+}
+
+func (r *cachedRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	if InTransaction(ctx) {
+		return r.next.GetByID(ctx, id)
+	}
+	var item T
+	if r.load(ctx, r.keyID(ctx, id), &item) {
+		return &item, nil
+	}
+
+	found, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.save(ctx, r.keyID(ctx, id), found)
+	return found, nil
+}
+
+// GetByIDs serves what it can from the cached records and reads the rest
+// from next in one call, caching them.
+func (r *cachedRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	if InTransaction(ctx) {
+		return r.next.GetByIDs(ctx, ids)
+	}
+	found := []T{}
+	var missed []string
+	for _, id := range uniqueIDs(ids) {
+		var item T
+		if r.load(ctx, r.keyID(ctx, id), &item) {
+			found = append(found, item)
+		} else {
+			missed = append(missed, id)
+		}
+	}
+	if len(missed) > 0 {
+		read, err := r.next.GetByIDs(ctx, missed)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range read {
+			r.save(ctx, r.keyID(ctx, item.GetID()), item)
+		}
+		found = append(found, read...)
+		sort.Slice(found, func(i, j int) bool { return found[i].GetID() < found[j].GetID() })
+	}
+	return found, nil
+}
+
+func (r *cachedRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	created, err := r.next.Create(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, (*created).GetID())
+	return created, nil
+}
+
+func (r *cachedRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	updated, err := r.next.Update(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, (*updated).GetID())
+	return updated, nil
+}
+
+func (r *cachedRepository[T]) Delete(ctx context.Context, id string) error {
+	if err := r.next.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+// load reports whether key was found in the cache and decoded into dst.
+// Cache failures are logged and treated as misses so the backing store stays authoritative.
+func (r *cachedRepository[T]) load(ctx context.Context, key string, dst any) bool {
+	data, err := r.store.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, cache.ErrMiss) {
+			log.Printf("WARNING: cache get %s: %v", key, err)
+		}
+		r.metrics.Miss()
+		return false
+	}
+	if err := r.decode(data, dst); err != nil {
+		log.Printf("WARNING: cache decode %s: %v", key, err)
+		r.metrics.Miss()
+		return false
+	}
+	r.metrics.Hit()
+	return true
+}
+
+func (r *cachedRepository[T]) save(ctx context.Context, key string, value any) {
+	data, err := r.encode(value)
+	if err != nil {
+		log.Printf("WARNING: cache encode %s: %v", key, err)
+		return
+	}
+	if err := r.store.Set(ctx, key, data, r.ttl); err != nil {
+		log.Printf("WARNING: cache set %s: %v", key, err)
+	}
+}
+
+func (r *cachedRepository[T]) invalidate(ctx context.Context, id string) {
+	AfterCommit(ctx, func() {
+		if err := r.store.Delete(ctx, r.keyID(ctx, id), r.keyAll(ctx)); err != nil {
+			log.Printf("WARNING: cache invalidate %s: %v", id, err)
+		}
+	})
+}
+
+// encode returns the entry of value, a T, a *T or a []T, each item with
+// its schema version.
+func (r *cachedRepository[T]) encode(value any) ([]byte, error) {
+	items, ok := value.([]T)
+	if !ok || items == nil {
+		return schema.Marshal(value)
+	}
+	entries := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		data, err := schema.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = data
+	}
+	return json.Marshal(entries)
+}
+
+// decode decodes an entry made by encode into dst, a *T or a *[]T.
+func (r *cachedRepository[T]) decode(data []byte, dst any) error {
+	items, ok := dst.(*[]T)
+	if !ok {
+		return schema.Unmarshal(data, dst)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil || entries == nil {
+		return err
+	}
+	*items = make([]T, len(entries))
+	for i, entry := range entries {
+		if err := schema.Unmarshal(entry, &(*items)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/cache"
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/fakedata"
+	"github.com/your-username/gin-api/internal/lifecycle"
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/repository/repotest"
+	"github.com/your-username/gin-api/internal/resilience"
+)
+
+// newUser draws a user, updated some time in 2026.
+func newUser(f *fakedata.Faker, id string) model.User {
+	p := f.Person()
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.Rand().Int64N(int64(365 * 24 * time.Hour))))
+	return model.User{ID: id, Name: p.Name, Email: p.Email, UpdatedAt: at.Truncate(time.Millisecond)}
+}
+
+func newMemory(t testing.TB) repository.CrudRepository[model.User] {
+	return repository.NewMemoryRepository[model.User]("user")
+}
+
+// newSQLite returns a repository on a migrated in-memory SQLite database.
+func newSQLite(t testing.TB) repository.CrudRepository[model.User] {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return repository.NewSQLRepository[model.User](db, dialect, "users", "user")
+}
+
+// newPostgres returns a factory of repositories on the migrated database
+// at POSTGRES_URL, emptied for each, skipping t when it is not set.
+func newPostgres(t testing.TB) repotest.Factory[model.User] {
+	url := os.Getenv("POSTGRES_URL")
+	if url == "" {
+		t.Skip("POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewSQLRepository[model.User](db, dialect, "users", "user")
+	}
+}
+
+// newMongo returns a factory of repositories on fresh collections of the
+// database at MONGODB_URL, skipping t when it is not set.
+func newMongo(t testing.TB) repotest.Factory[model.User] {
+	url := os.Getenv("MONGODB_URL")
+	if url == "" {
+		t.Skip("MONGODB_URL is not set")
+	}
+	db, err := repository.OpenMongo(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Client().Disconnect(context.Background()) })
+	var n atomic.Int64
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		coll := db.Collection(fmt.Sprintf("users_conformance_%d_%d", time.Now().UnixNano(), n.Add(1)))
+		t.Cleanup(func() { coll.Drop(context.Background()) })
+		return repository.NewMongoRepository[model.User](coll, "user")
+	}
+}
+
+// cached decorates the repositories of next with an LRU cache.
+func cached(next repotest.Factory[model.User]) repotest.Factory[model.User] {
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		return repository.NewCachedRepository(next(t), "users", cache.NewLRUStore(1024, nil), time.Minute, &cache.Metrics{})
+	}
+}
+
+// redisCached decorates the repositories of next with a cache in the Redis
+// at REDIS_URL, under a key prefix of each's own, skipping t when it is not
+// set.
+func redisCached(t testing.TB, next repotest.Factory[model.User]) repotest.Factory[model.User] {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL is not set")
+	}
+	lc := lifecycle.New()
+	t.Cleanup(func() { lc.Shutdown(context.Background()) })
+	var n atomic.Int64
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		prefix := fmt.Sprintf("conformance:%d:%d:", time.Now().UnixNano(), n.Add(1))
+		store, err := cache.NewRedisStore(context.Background(), lc, url, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewCachedRepository(next(t), "users", store, time.Minute, &cache.Metrics{})
+	}
+}
+
+// changeTracked decorates the repositories of next with a change feed.
+func changeTracked(next repotest.Factory[model.User]) repotest.Factory[model.User] {
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		return repository.NewChangeTrackingRepository(next(t), changes.NewFeed(1000, nil))
+	}
+}
+
+// resilient guards the repositories of next with a circuit breaker.
+func resilient(next repotest.Factory[model.User]) repotest.Factory[model.User] {
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		breakers := resilience.NewRegistry(resilience.Options{Enabled: true, Timeout: time.Second, Retries: 2, Backoff: time.Millisecond, FailureThreshold: 5, OpenFor: time.Minute}, nil)
+		return repository.NewResilientRepository(next(t), breakers, "repository:users")
+	}
+}
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newMemory, newUser)
+}
+
+// TestMemoryRepositoryIsSafeForConcurrentUse has workers write their own
+// records and read everyone's at once, as concurrent requests do. Run it
+// with the race detector:
+//
+//	go test -race -run ConcurrentUse ./internal/repository
+func TestMemoryRepositoryIsSafeForConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewUserRepository()
+	const workers, records = 8, 200
+	f := fakedata.New(1, fakedata.DefaultLocale)
+	users := make([][]model.User, workers)
+	for w := range users {
+		for i := 0; i < records; i++ {
+			users[w] = append(users[w], newUser(f, fmt.Sprintf("user-%d-%02d", w, i)))
+		}
+	}
+
+	start := make(chan struct{}) // so that the workers overlap
+	var wg sync.WaitGroup
+	for w := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i, u := range users[w] {
+				if _, err := repo.Create(ctx, &u); err != nil {
+					t.Error(err)
+					return
+				}
+				u.Name += " (renamed)"
+				if _, err := repo.Update(ctx, &u); err != nil {
+					t.Error(err)
+					return
+				}
+				other := users[(w+1)%workers][i].ID
+				repo.GetByID(ctx, other) // may not exist yet
+				repo.GetByIDs(ctx, []string{u.ID, other})
+				repo.GetAll(ctx)
+				if i%2 == 0 {
+					if err := repo.Delete(ctx, u.ID); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if all, err := repo.GetAll(ctx); err != nil || len(all) != workers*records/2 {
+		t.Errorf("GetAll = %d records, %v; want %d", len(all), err, workers*records/2)
+	}
+	if all, _ := repository.NewUserRepository().GetAll(ctx); len(all) != 0 {
+		t.Errorf("a new repository holds %d records, want none", len(all))
+	}
+}
+
+func TestFakeRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(testing.TB) repository.CrudRepository[model.User] {
+		return repotest.NewFake[model.User]("user")
+	}, newUser)
+}
+
+func TestSQLRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newSQLite, newUser)
+}
+
+func TestPostgresRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newPostgres(t), newUser)
+}
+
+func TestMongoRepositoryConformance(t *testing.T) {
+	repotest.Run(t, newMongo(t), newUser)
+}
+
+func TestCachedRepositoryConformance(t *testing.T) {
+	repotest.Run(t, cached(newMemory), newUser)
+}
+
+func TestRedisCachedRepositoryConformance(t *testing.T) {
+	repotest.Run(t, redisCached(t, newSQLite), newUser)
+}
+
+func TestChangeTrackingRepositoryConformance(t *testing.T) {
+	repotest.Run(t, changeTracked(newMemory), newUser)
+}
+
+func TestResilientRepositoryConformance(t *testing.T) {
+	repotest.Run(t, resilient(newMemory), newUser)
+}
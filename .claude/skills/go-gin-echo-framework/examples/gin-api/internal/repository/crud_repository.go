@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/your-username/gin-api/internal/model"
+)
+
+var ErrNotFound = errors.New("record not found")
+
+// ErrExists is returned by Create when a record with the same ID is stored.
+var ErrExists = errors.New("record already exists")
+
+// CrudRepository is the storage contract shared by every resource. Backends
+// and decorators implement it once for all models.
+type CrudRepository[T model.Entity] interface {
+	GetAll(ctx context.Context) ([]T, error)
+	// Stream calls fn for every record in GetAll's order without holding
+	// them all in memory, stopping at the first error fn returns, which
+	// Stream returns.
+	Stream(ctx context.Context, fn func(item T) error) error
+	GetByID(ctx context.Context, id string) (*T, error)
+	// GetByIDs returns the records with the given IDs in one round trip,
+	// ordered by ID. Missing IDs are left out and duplicates return the
+	// record once.
+	GetByIDs(ctx context.Context, ids []string) ([]T, error)
+	Create(ctx context.Context, item *T) (*T, error)
+	Update(ctx context.Context, item *T) (*T, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// uniqueIDs returns ids sorted and without duplicates.
+func uniqueIDs(ids []string) []string {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
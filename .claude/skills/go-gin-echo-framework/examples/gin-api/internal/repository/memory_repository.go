@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/your-username/gin-api/internal/model"
+)
+
+// memoryRepository keeps its records in a map of its own, so repositories
+// share nothing, and is safe for concurrent use.
+type memoryRepository[T model.Entity] struct {
+	// db *sql.DB // In a real application, this would be a database connection
+	mu    sync.RWMutex
+	store map[string]T
+	name  string // singular resource name used in error messages
+}
+
+// NewMemoryRepository returns an empty in-memory repository of its own,
+// e.g. for one tenant; name is the singular used in error messages.
+func NewMemoryRepository[T model.Entity](name string) CrudRepository[T] {
+	return &memoryRepository[T]{store: map[string]T{}, name: name}
+}
+
+func (r *memoryRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	// Simulate database call
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var all []T
+	for _, item := range r.store {
+		all = append(all, item)
+	}
+	// Ordered by ID like the SQL and MongoDB repositories
+	sort.Slice(all, func(i, j int) bool { return all[i].GetID() < all[j].GetID() })
+	return all, nil
+}
+
+func (r *memoryRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	// The records are in memory already; iterate over an ordered copy so
+	// fn may write to the repository
+	all, _ := r.GetAll(ctx)
+	for _, item := range all {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	// Simulate database call
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.store[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &item, nil
+}
+
+func (r *memoryRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found := []T{}
+	for _, id := range uniqueIDs(ids) {
+		if item, ok := r.store[id]; ok {
+			found = append(found, item)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	// Simulate database call
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := (*item).GetID()
+	if _, exists := r.store[id]; exists {
+		return nil, fmt.Errorf("%s with ID %s: %w", r.name, id, ErrExists)
+	}
+	r.store[id] = *item
+	return item, nil
+}
+
+func (r *memoryRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	// Simulate database call
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := (*item).GetID()
+	if _, exists := r.store[id]; !exists {
+		return nil, ErrNotFound
+	}
+	r.store[id] = *item
+	return item, nil
+}
+
+func (r *memoryRepository[T]) Delete(ctx context.Context, id string) error {
+	// Simulate database call
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.store[id]; !exists {
+		return ErrNotFound
+	}
+	delete(r.store, id)
+	return nil
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/your-username/gin-api/internal/model"
+
+type PasskeyRepository = CrudRepository[model.Passkey]
+
+// NewPasskeyRepository returns an empty in-memory repository of its own.
+func NewPasskeyRepository() PasskeyRepository {
+	return NewMemoryRepository[model.Passkey]("passkey")
+}
@@ -0,0 +1,127 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+func TestQuotaRepositoryCapsConcurrentCreates(t *testing.T) {
+	stores := map[string]func(t *testing.T) (repository.CrudRepository[model.User], repository.UnitOfWork, repository.Locks){
+		"memory": func(t *testing.T) (repository.CrudRepository[model.User], repository.UnitOfWork, repository.Locks) {
+			return newMemory(t), repository.NewNoopUnitOfWork(), repository.NewMemoryLocks()
+		},
+		"sqlite": func(t *testing.T) (repository.CrudRepository[model.User], repository.UnitOfWork, repository.Locks) {
+			ctx := context.Background()
+			db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			if _, err := migrations.Up(ctx, db, dialect); err != nil {
+				t.Fatal(err)
+			}
+			return repository.NewSQLRepository[model.User](db, dialect, "users", "user"), repository.NewSQLUnitOfWork(db), repository.NewSQLLocks(db, dialect)
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			next, uow, locks := open(t)
+			repo := repository.NewQuotaRepository(repository.NewColumnTenantRepository(next), uow, locks, "users", func(ctx context.Context) repository.Quota {
+				q := repository.Quota{Scope: "tenant:" + tenant.From(ctx)}
+				if tenant.From(ctx) == "acme" {
+					q.Max, q.Per = 5, "in the free plan"
+				}
+				return q
+			}, nil)
+			acme := tenant.With(context.Background(), "acme")
+			globex := tenant.With(context.Background(), "globex")
+
+			var created, refused atomic.Int32
+			var wg sync.WaitGroup
+			for i := range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := repo.Create(acme, &model.User{ID: fmt.Sprintf("acme-%02d", i), Name: "n"})
+					switch {
+					case err == nil:
+						created.Add(1)
+					case errors.Is(err, repository.ErrQuota):
+						refused.Add(1)
+					default:
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+			if created.Load() != 5 || refused.Load() != 15 {
+				t.Errorf("%d creates in acme succeeded and %d were refused, want 5 and 15", created.Load(), refused.Load())
+			}
+			for i := range 8 {
+				if _, err := repo.Create(globex, &model.User{ID: fmt.Sprintf("globex-%d", i), Name: "n"}); err != nil {
+					t.Errorf("user of globex, which has no quota: %v", err)
+				}
+			}
+
+			if n, q, err := repo.Usage(acme); err != nil || n != 5 || q.Max != 5 {
+				t.Errorf("usage of acme: %d of %d (%v)", n, q.Max, err)
+			}
+			if n, q, err := repo.Usage(globex); err != nil || n != 8 || q.Max != 0 {
+				t.Errorf("usage of globex: %d of %d (%v)", n, q.Max, err)
+			}
+
+			// A delete makes room for one more
+			all, _ := repo.GetAll(acme)
+			if err := repo.Delete(acme, all[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.Create(acme, &model.User{ID: "acme-new", Name: "n"}); err != nil {
+				t.Errorf("create after a delete: %v", err)
+			}
+			if _, err := repo.Create(acme, &model.User{ID: "acme-over", Name: "n"}); !errors.Is(err, repository.ErrQuota) {
+				t.Errorf("create beyond the quota: %v, want ErrQuota", err)
+			}
+		})
+	}
+}
+
+func TestQuotaRepositoryCountsWhatTheScopeHolds(t *testing.T) {
+	keys := repository.NewQuotaRepository(repository.NewMemoryRepository[model.APIKey]("API key"), repository.NewNoopUnitOfWork(), repository.NewMemoryLocks(), "API keys",
+		func(ctx context.Context) repository.Quota {
+			return repository.Quota{Scope: "account:" + owner(ctx), Max: 2, Per: "per account"}
+		},
+		func(ctx context.Context, k model.APIKey) bool { return k.Owner == owner(ctx) })
+	ann, bob := withOwner("ann"), withOwner("bob")
+
+	for i, ctx := range []context.Context{ann, ann, bob} {
+		if _, err := keys.Create(ctx, &model.APIKey{ID: fmt.Sprint(i), Owner: owner(ctx)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := keys.Create(ann, &model.APIKey{ID: "3", Owner: "ann"}); !errors.Is(err, repository.ErrQuota) || err.Error() != "quota exceeded: at most 2 API keys per account" {
+		t.Errorf("third key of ann: %v", err)
+	}
+	if _, err := keys.Create(bob, &model.APIKey{ID: "4", Owner: "bob"}); err != nil {
+		t.Errorf("second key of bob: %v", err)
+	}
+}
+
+type ownerKey struct{}
+
+func withOwner(id string) context.Context {
+	return context.WithValue(context.Background(), ownerKey{}, id)
+}
+
+func owner(ctx context.Context) string {
+	id, _ := ctx.Value(ownerKey{}).(string)
+	return id
+}
@@ -0,0 +1,180 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-username/gin-api/internal/fakedata"
+	"github.com/your-username/gin-api/internal/model"
+)
+
+// Workload is a mix of repository operations, as relative weights. A churn
+// deletes a record and creates it again, so the number of records stays at
+// Records whatever the mix.
+type Workload struct {
+	Name                        string
+	Gets, Lists, Updates, Churn int
+}
+
+// Workloads are the mixes BenchmarkRepositories compares backends under.
+var Workloads = []Workload{
+	{Name: "read-heavy", Gets: 90, Lists: 1, Updates: 9},
+	{Name: "mixed", Gets: 50, Lists: 1, Updates: 40, Churn: 9},
+	{Name: "write-heavy", Gets: 10, Lists: 1, Updates: 70, Churn: 19},
+}
+
+// Records is how many records a benchmarked repository holds.
+var Records = 1000
+
+// latencySamples bounds the latencies Benchmark keeps for percentiles.
+const latencySamples = 10000
+
+// Result is the outcome of one Benchmark run.
+type Result struct {
+	Workload    string
+	Ops         int
+	NsPerOp     float64
+	P50, P99    time.Duration
+	AllocsPerOp float64
+	BytesPerOp  float64
+}
+
+// Benchmark runs b.N operations of w, one after another, against a
+// repository from newRepo holding Records records drawn from gen. Besides
+// the usual ns/op and allocations it reports p50 and p99 latencies, from
+// up to 10000 evenly spaced operations. Operations run sequentially, so
+// the latencies are those of a single caller.
+func Benchmark[T model.Entity](b *testing.B, newRepo Factory[T], gen Generator[T], w Workload) Result {
+	b.Helper()
+	ctx := context.Background()
+	repo := newRepo(b)
+	f := fakedata.New(1, fakedata.DefaultLocale)
+
+	// Two versions of every record, so updates and churn generate nothing
+	// while timed
+	versions := make([][2]T, Records)
+	for i := range versions {
+		id := f.ID()
+		versions[i] = [2]T{gen(f, id), gen(f, id)}
+		item := versions[i][0]
+		if _, err := repo.Create(ctx, &item); err != nil {
+			b.Fatalf("Create: %v", err)
+		}
+	}
+	kinds := make([]int, 0, w.Gets+w.Lists+w.Updates+w.Churn)
+	for kind, weight := range []int{w.Gets, w.Lists, w.Updates, w.Churn} {
+		for i := 0; i < weight; i++ {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		b.Fatalf("workload %q has no operations", w.Name)
+	}
+	f.Rand().Shuffle(len(kinds), func(i, j int) { kinds[i], kinds[j] = kinds[j], kinds[i] })
+	stride := max(1, b.N/latencySamples)
+	samples := make([]time.Duration, 0, b.N/stride+1)
+
+	var before, after runtime.MemStats
+	b.ReportAllocs()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var start time.Time
+		if i%stride == 0 {
+			start = time.Now()
+		}
+		record := versions[(i*7919)%len(versions)] // a prime stride visits every record
+		var err error
+		switch kinds[i%len(kinds)] {
+		case 0:
+			_, err = repo.GetByID(ctx, record[0].GetID())
+		case 1:
+			_, err = repo.GetAll(ctx)
+		case 2:
+			item := record[i/len(versions)%2]
+			_, err = repo.Update(ctx, &item)
+		case 3:
+			if err = repo.Delete(ctx, record[0].GetID()); err == nil {
+				item := record[i/len(versions)%2]
+				_, err = repo.Create(ctx, &item)
+			}
+		}
+		if err != nil {
+			b.Fatalf("operation %d: %v", i, err)
+		}
+		if i%stride == 0 {
+			samples = append(samples, time.Since(start))
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	r := Result{
+		Workload:    w.Name,
+		Ops:         b.N,
+		NsPerOp:     float64(b.Elapsed().Nanoseconds()) / float64(b.N),
+		P50:         samples[len(samples)*50/100],
+		P99:         samples[len(samples)*99/100],
+		AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(b.N),
+		BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(b.N),
+	}
+	b.ReportMetric(float64(r.P50.Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(r.P99.Nanoseconds()), "p99-ns")
+	return r
+}
+
+// Table collects Benchmark results by backend and prints them side by side.
+// The zero value is ready to use.
+type Table struct {
+	mu      sync.Mutex
+	results map[[2]string]Result // by workload and backend
+	order   [][2]string
+}
+
+// Add records r for backend, replacing the result of an earlier, shorter
+// run of the same benchmark.
+func (t *Table) Add(backend string, r Result) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := [2]string{r.Workload, backend}
+	if t.results == nil {
+		t.results = map[[2]string]Result{}
+	}
+	if _, ok := t.results[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.results[key] = r
+}
+
+// Print writes one row per workload and backend, with each backend's time
+// per operation relative to the fastest for the workload.
+func (t *Table) Print(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.order) == 0 {
+		return
+	}
+	fastest := map[string]float64{}
+	for _, key := range t.order {
+		r := t.results[key]
+		if best, ok := fastest[r.Workload]; !ok || r.NsPerOp < best {
+			fastest[r.Workload] = r.NsPerOp
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "workload\tbackend\tops/s\tp50\tp99\tallocs/op\tB/op\tvs fastest\t")
+	for _, key := range t.order {
+		r := t.results[key]
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%v\t%v\t%.1f\t%.0f\t%.1fx\t\n",
+			r.Workload, key[1], 1e9/r.NsPerOp, r.P50, r.P99, r.AllocsPerOp, r.BytesPerOp, r.NsPerOp/fastest[r.Workload])
+	}
+	tw.Flush()
+}
@@ -0,0 +1,404 @@
+// Package repotest is the conformance suite for CrudRepository
+// implementations. Every backend and decorator runs it, so a new one cannot
+// silently diverge from the others on what the services rely on: CRUD
+// semantics, ErrNotFound for missing records, and a list order that is the
+// same on every call, so clients paging through GetAll see each record once.
+// Stream must yield what GetAll returns, and GetByIDs what GetByID does.
+//
+// Besides fixed cases, Run replays random sequences of operations against
+// the repository and a map modelling it. A failure reports the seed; rerun
+// with REPOTEST_SEED=<seed> to replay the same sequences.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/fakedata"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// Sequences and Steps size the random part of Run.
+var (
+	Sequences = 50
+	Steps     = 40
+)
+
+// Factory returns an empty repository; Run calls it once per case and
+// Benchmark once per workload.
+type Factory[T model.Entity] func(t testing.TB) repository.CrudRepository[T]
+
+// Generator returns a record with the given ID and otherwise random values
+// drawn from f. Values must survive the backend unchanged: timestamps in UTC
+// and truncated to milliseconds, as the services stamp them.
+type Generator[T model.Entity] func(f *fakedata.Faker, id string) T
+
+// Run checks that the repositories newRepo returns behave like every other
+// CrudRepository. Records are compared by their JSON encoding.
+func Run[T model.Entity](t *testing.T, newRepo Factory[T], gen Generator[T]) {
+	seed := seedOf(t)
+	f := fakedata.New(seed, fakedata.DefaultLocale)
+	ctx := context.Background()
+
+	t.Run("CreateThenGet", func(t *testing.T) {
+		repo := newRepo(t)
+		item := gen(f, f.ID())
+		created, err := repo.Create(ctx, &item)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		mustEqual(t, "Create result", *created, item)
+		got, err := repo.GetByID(ctx, item.GetID())
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		mustEqual(t, "GetByID", *got, item)
+	})
+
+	t.Run("CreateDuplicate", func(t *testing.T) {
+		repo := newRepo(t)
+		id := f.ID()
+		first, second := gen(f, id), gen(f, id)
+		if _, err := repo.Create(ctx, &first); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := repo.Create(ctx, &second); !errors.Is(err, repository.ErrExists) {
+			t.Fatalf("second Create with ID %s: err = %v, want %v", id, err, repository.ErrExists)
+		}
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		mustEqual(t, "record after the rejected Create", *got, first)
+	})
+
+	t.Run("GetByIDs", func(t *testing.T) {
+		repo := newRepo(t)
+		var items []T
+		for i := 0; i < 5; i++ {
+			item := gen(f, f.ID())
+			if _, err := repo.Create(ctx, &item); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			items = append(items, item)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].GetID() < items[j].GetID() })
+		missing := gen(f, f.ID()).GetID()
+		got, err := repo.GetByIDs(ctx, []string{items[3].GetID(), missing, items[0].GetID(), items[3].GetID()})
+		if err != nil {
+			t.Fatalf("GetByIDs: %v", err)
+		}
+		mustEqual(t, "GetByIDs", got, []T{items[0], items[3]})
+		if none, err := repo.GetByIDs(ctx, nil); err != nil || len(none) != 0 {
+			t.Fatalf("GetByIDs of no IDs = %d records, %v", len(none), err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		id := f.ID()
+		item, changed := gen(f, id), gen(f, id)
+		if _, err := repo.Create(ctx, &item); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		updated, err := repo.Update(ctx, &changed)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		mustEqual(t, "Update result", *updated, changed)
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		mustEqual(t, "GetByID after Update", *got, changed)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		item := gen(f, f.ID())
+		if _, err := repo.Create(ctx, &item); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repo.Delete(ctx, item.GetID()); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, item.GetID()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID after Delete: err = %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, item.GetID()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := gen(f, f.ID())
+		if _, err := repo.GetByID(ctx, missing.GetID()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID: err = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.Update(ctx, &missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update: err = %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, missing.GetID()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete: err = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByID(ctx, missing.GetID()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update created the missing record")
+		}
+	})
+
+	t.Run("ListOrder", func(t *testing.T) {
+		repo := newRepo(t)
+		if all, err := repo.GetAll(ctx); err != nil || len(all) != 0 {
+			t.Fatalf("GetAll on an empty repository = %d records, %v", len(all), err)
+		}
+		var ids []string
+		for i := 0; i < 20; i++ {
+			item := gen(f, f.ID())
+			if _, err := repo.Create(ctx, &item); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids = append(ids, item.GetID())
+		}
+		sort.Strings(ids)
+		first := listIDs(t, repo)
+		if !equalIDs(first, ids) {
+			t.Fatalf("GetAll order %q, want ascending IDs %q", first, ids)
+		}
+		if again := listIDs(t, repo); !equalIDs(again, first) {
+			t.Fatalf("GetAll order changed between calls:\n%q\n%q", first, again)
+		}
+		// Removing a record must not move the others between pages
+		if err := repo.Delete(ctx, ids[3]); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if after := listIDs(t, repo); !equalIDs(after, append(ids[:3:3], ids[4:]...)) {
+			t.Fatalf("GetAll after a Delete = %q", after)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 0; i < 20; i++ {
+			item := gen(f, f.ID())
+			if _, err := repo.Create(ctx, &item); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		all, err := repo.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		var streamed []T
+		if err := repo.Stream(ctx, func(item T) error {
+			streamed = append(streamed, item)
+			return nil
+		}); err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		mustEqual(t, "Stream", streamed, all)
+
+		stop := errors.New("stop")
+		n := 0
+		err = repo.Stream(ctx, func(T) error {
+			if n++; n == 5 {
+				return stop
+			}
+			return nil
+		})
+		if !errors.Is(err, stop) || n != 5 {
+			t.Fatalf("Stream after fn failed on record 5: called %d times, err = %v", n, err)
+		}
+	})
+
+	t.Run("RandomSequences", func(t *testing.T) {
+		for i := 0; i < Sequences; i++ {
+			if !runSequence(t, newRepo(t), f, gen) {
+				t.Fatalf("sequence %d failed; replay with REPOTEST_SEED=%d", i+1, seed)
+			}
+		}
+	})
+}
+
+// runSequence applies Steps random operations to repo and to a model of
+// it, and reports whether every result matched the model's.
+func runSequence[T model.Entity](t *testing.T, repo repository.CrudRepository[T], f *fakedata.Faker, gen Generator[T]) bool {
+	t.Helper()
+	ctx := context.Background()
+	want := map[string]T{}
+	var log []string
+	fail := func(format string, args ...any) bool {
+		t.Errorf("after %s:\n"+format, append([]any{strings.Join(log, ", ")}, args...)...)
+		return false
+	}
+	// pick returns an existing ID most of the time and a new one otherwise
+	pick := func() string {
+		if len(want) == 0 || f.Rand().IntN(4) == 0 {
+			return f.ID()
+		}
+		ids := sortedKeys(want)
+		return ids[f.Rand().IntN(len(ids))]
+	}
+
+	for step := 0; step < Steps; step++ {
+		id := pick()
+		_, exists := want[id]
+		switch op := f.Rand().IntN(7); op {
+		case 0:
+			log = append(log, "Create "+id)
+			item := gen(f, id)
+			_, err := repo.Create(ctx, &item)
+			if exists != (err != nil) {
+				return fail("Create %s: err = %v, record existed: %v", id, err, exists)
+			}
+			if !exists {
+				want[id] = item
+			}
+		case 1:
+			log = append(log, "Update "+id)
+			item := gen(f, id)
+			_, err := repo.Update(ctx, &item)
+			if !exists && !errors.Is(err, repository.ErrNotFound) || exists && err != nil {
+				return fail("Update %s: err = %v, record existed: %v", id, err, exists)
+			}
+			if exists {
+				want[id] = item
+			}
+		case 2:
+			log = append(log, "Delete "+id)
+			err := repo.Delete(ctx, id)
+			if !exists && !errors.Is(err, repository.ErrNotFound) || exists && err != nil {
+				return fail("Delete %s: err = %v, record existed: %v", id, err, exists)
+			}
+			delete(want, id)
+		case 3:
+			log = append(log, "GetByID "+id)
+			got, err := repo.GetByID(ctx, id)
+			if !exists {
+				if !errors.Is(err, repository.ErrNotFound) {
+					return fail("GetByID %s: err = %v, want ErrNotFound", id, err)
+				}
+				continue
+			}
+			if err != nil {
+				return fail("GetByID %s: %v", id, err)
+			}
+			if a, b := encode(*got), encode(want[id]); a != b {
+				return fail("GetByID %s = %s, want %s", id, a, b)
+			}
+		case 4:
+			log = append(log, "GetAll")
+			all, err := repo.GetAll(ctx)
+			if err != nil {
+				return fail("GetAll: %v", err)
+			}
+			ids := sortedKeys(want)
+			if len(all) != len(ids) {
+				return fail("GetAll returned %d records, want %d", len(all), len(ids))
+			}
+			for i, item := range all {
+				if a, b := encode(item), encode(want[ids[i]]); a != b {
+					return fail("GetAll[%d] = %s, want %s", i, a, b)
+				}
+			}
+		case 5:
+			log = append(log, "Stream")
+			ids := sortedKeys(want)
+			i := 0
+			err := repo.Stream(ctx, func(item T) error {
+				if i >= len(ids) {
+					return fmt.Errorf("record %s beyond the %d expected", item.GetID(), len(ids))
+				}
+				if a, b := encode(item), encode(want[ids[i]]); a != b {
+					return fmt.Errorf("record %d = %s, want %s", i, a, b)
+				}
+				i++
+				return nil
+			})
+			if err != nil || i != len(ids) {
+				return fail("Stream: %d of %d records, %v", i, len(ids), err)
+			}
+		case 6:
+			ids := []string{id, pick(), pick()}
+			log = append(log, "GetByIDs "+strings.Join(ids, " "))
+			got, err := repo.GetByIDs(ctx, ids)
+			if err != nil {
+				return fail("GetByIDs: %v", err)
+			}
+			var expected []T
+			for _, id := range sortedKeys(want) {
+				if slices.Contains(ids, id) {
+					expected = append(expected, want[id])
+				}
+			}
+			if a, b := encode(got), encode(expected); len(got) != len(expected) || len(got) > 0 && a != b {
+				return fail("GetByIDs %v = %s, want %s", ids, a, b)
+			}
+		}
+	}
+	return true
+}
+
+// seedOf returns REPOTEST_SEED, or a new seed if it is not set.
+func seedOf(t *testing.T) uint64 {
+	s := os.Getenv("REPOTEST_SEED")
+	if s == "" {
+		return uint64(time.Now().UnixNano())
+	}
+	seed, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		t.Fatalf("REPOTEST_SEED: %v", err)
+	}
+	return seed
+}
+
+func encode(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return string(b)
+}
+
+func mustEqual(t *testing.T, what string, got, want any) {
+	t.Helper()
+	if a, b := encode(got), encode(want); a != b {
+		t.Fatalf("%s = %s, want %s", what, a, b)
+	}
+}
+
+func listIDs[T model.Entity](t *testing.T, repo repository.CrudRepository[T]) []string {
+	t.Helper()
+	all, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	ids := make([]string, len(all))
+	for i, item := range all {
+		ids[i] = item.GetID()
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/resilience"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// resilientRepository guards the calls to a store with a resilience.Policy.
+// Reads are retried; writes are not, since a write that timed out may have
+// been applied. Inside a UnitOfWork nothing is retried either: a failed
+// statement aborts the transaction, which UnitOfWork.Do retries as a whole.
+// ErrNotFound, ErrExists, ErrQuota and unknown tenants are answers of the
+// store, not failures of it, so callers cannot open the breaker with
+// requests the store refuses.
+type resilientRepository[T model.Entity] struct {
+	next   CrudRepository[T]
+	policy *resilience.Policy
+}
+
+// NewResilientRepository guards next with the policy of name in breakers,
+// e.g. "repository:users".
+func NewResilientRepository[T model.Entity](next CrudRepository[T], breakers *resilience.Registry, name string) CrudRepository[T] {
+	failure := func(err error) bool {
+		for _, answer := range []error{ErrNotFound, ErrExists, ErrQuota, tenant.ErrUnknown} {
+			if errors.Is(err, answer) {
+				return false
+			}
+		}
+		return true
+	}
+	return &resilientRepository[T]{next: next, policy: breakers.Policy(name, failure)}
+}
+
+// read calls fn as a read, retried outside transactions.
+func (r *resilientRepository[T]) read(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InTransaction(ctx) {
+		return r.policy.Once(ctx, fn)
+	}
+	return r.policy.Do(ctx, fn)
+}
+
+func (r *resilientRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	var items []T
+	err := r.read(ctx, func(ctx context.Context) (err error) {
+		items, err = r.next.GetAll(ctx)
+		return err
+	})
+	return items, err
+}
+
+// Stream is not retried, since fn has seen the items before the failure,
+// nor timed out, since it runs as long as fn takes. Errors of fn are not
+// failures of the store.
+func (r *resilientRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	var fnErr error
+	err := r.policy.Guard(ctx, func(ctx context.Context) error {
+		err := r.next.Stream(ctx, func(item T) error {
+			fnErr = fn(item)
+			return fnErr
+		})
+		if fnErr != nil {
+			return nil
+		}
+		return err
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+func (r *resilientRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	var item *T
+	err := r.read(ctx, func(ctx context.Context) (err error) {
+		item, err = r.next.GetByID(ctx, id)
+		return err
+	})
+	return item, err
+}
+
+func (r *resilientRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	var items []T
+	err := r.read(ctx, func(ctx context.Context) (err error) {
+		items, err = r.next.GetByIDs(ctx, ids)
+		return err
+	})
+	return items, err
+}
+
+func (r *resilientRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	var created *T
+	err := r.policy.Once(ctx, func(ctx context.Context) (err error) {
+		created, err = r.next.Create(ctx, item)
+		return err
+	})
+	return created, err
+}
+
+func (r *resilientRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	var updated *T
+	err := r.policy.Once(ctx, func(ctx context.Context) (err error) {
+		updated, err = r.next.Update(ctx, item)
+		return err
+	})
+	return updated, err
+}
+
+func (r *resilientRepository[T]) Delete(ctx context.Context, id string) error {
+	return r.policy.Once(ctx, func(ctx context.Context) error {
+		return r.next.Delete(ctx, id)
+	})
+}
@@ -0,0 +1,46 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/fakedata"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/resilience"
+)
+
+func TestResilientRepositoryDoesNotCountRefusedCreatesAsFailures(t *testing.T) {
+	stores := map[string]func(t testing.TB) repository.CrudRepository[model.User]{
+		"memory": newMemory,
+		"sqlite": newSQLite,
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			breakers := resilience.NewRegistry(resilience.Options{Enabled: true, Timeout: time.Second, Retries: 2, Backoff: time.Millisecond, FailureThreshold: 2, OpenFor: time.Minute}, nil)
+			repo := repository.NewResilientRepository(open(t), breakers, "repository:users")
+			user := newUser(fakedata.New(1, fakedata.DefaultLocale), "user-1")
+			if _, err := repo.Create(ctx, &user); err != nil {
+				t.Fatal(err)
+			}
+
+			for range 5 {
+				if _, err := repo.Create(ctx, &user); !errors.Is(err, repository.ErrExists) {
+					t.Fatalf("Create of a taken ID = %v, want ErrExists", err)
+				}
+			}
+			if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("GetByID of a missing ID = %v, want ErrNotFound", err)
+			}
+			if status := breakers.Breaker("repository:users").Status(); status.State != resilience.Closed || status.Failures != 0 {
+				t.Errorf("breaker = %s with %d failures, want closed with none", status.State, status.Failures)
+			}
+			if _, err := repo.GetByID(ctx, user.ID); err != nil {
+				t.Errorf("GetByID after the refused Creates = %v", err)
+			}
+		})
+	}
+}
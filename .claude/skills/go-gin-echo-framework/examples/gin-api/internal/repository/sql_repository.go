@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/your-username/gin-api/internal/model"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect is the SQL flavour a *sql.DB speaks.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// Placeholder returns the n-th (1-based) bind parameter. Both dialects
+// number them, so statements can list columns in any order.
+func (d Dialect) Placeholder(n int) string {
+	if d == DialectPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?" + strconv.Itoa(n)
+}
+
+type sqlRepository[T model.Entity] struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	name    string   // singular resource name used in error messages
+	columns []string // "id" first
+	fields  [][]int  // struct field index per column
+	list    string   // SELECT ... ORDER BY id
+	get     string   // SELECT ... WHERE id = ?
+	insert  string
+	update  string
+	delete  string
+}
+
+// NewSQLRepository stores T in table, one column per JSON field of T (the
+// ID field must be tagged json:"id"). The table must already exist. Calls
+// inside a NewSQLUnitOfWork(db) transaction run on that transaction.
+func NewSQLRepository[T model.Entity](db *sql.DB, dialect Dialect, table, name string) CrudRepository[T] {
+	r := &sqlRepository[T]{db: db, dialect: dialect, table: table, name: name}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		col, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || col == "" || col == "-" {
+			continue
+		}
+		if col == "id" {
+			r.columns = append([]string{col}, r.columns...)
+			r.fields = append([][]int{f.Index}, r.fields...)
+		} else {
+			r.columns = append(r.columns, col)
+			r.fields = append(r.fields, f.Index)
+		}
+	}
+	if len(r.columns) == 0 || r.columns[0] != "id" {
+		panic(fmt.Sprintf("repository: %s has no field tagged json:\"id\"", t))
+	}
+
+	cols := strings.Join(r.columns, ", ")
+	params := make([]string, len(r.columns))
+	sets := make([]string, 0, len(r.columns)-1)
+	for i, c := range r.columns {
+		params[i] = dialect.Placeholder(i + 1)
+		if i > 0 {
+			sets = append(sets, c+" = "+dialect.Placeholder(i+1))
+		}
+	}
+	r.list = fmt.Sprintf("SELECT %s FROM %s ORDER BY id", cols, table)
+	r.get = fmt.Sprintf("SELECT %s FROM %s WHERE id = %s", cols, table, dialect.Placeholder(1))
+	r.insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, cols, strings.Join(params, ", "))
+	r.update = fmt.Sprintf("UPDATE %s SET %s WHERE id = %s", table, strings.Join(sets, ", "), dialect.Placeholder(1))
+	r.delete = fmt.Sprintf("DELETE FROM %s WHERE id = %s", table, dialect.Placeholder(1))
+	return r
+}
+
+// values returns pointers to item's fields in column order, for Scan.
+func (r *sqlRepository[T]) values(item *T) []any {
+	v := reflect.ValueOf(item).Elem()
+	out := make([]any, len(r.fields))
+	for i, idx := range r.fields {
+		out[i] = v.FieldByIndex(idx).Addr().Interface()
+	}
+	return out
+}
+
+// args returns item's field values in column order, for Exec.
+func (r *sqlRepository[T]) args(item *T) []any {
+	v := reflect.ValueOf(item).Elem()
+	out := make([]any, len(r.fields))
+	for i, idx := range r.fields {
+		out[i] = v.FieldByIndex(idx).Interface()
+	}
+	return out
+}
+
+func (r *sqlRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	rows, err := SQLConn(ctx, r.db).QueryContext(ctx, r.list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.table, err)
+	}
+	defer rows.Close()
+
+	var all []T
+	for rows.Next() {
+		var item T
+		if err := rows.Scan(r.values(&item)...); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", r.table, err)
+		}
+		all = append(all, item)
+	}
+	return all, rows.Err()
+}
+
+// Stream holds a connection until fn has seen the last row; with SQLite's
+// single connection, other queries wait for it.
+func (r *sqlRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	rows, err := SQLConn(ctx, r.db).QueryContext(ctx, r.list)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", r.table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err := rows.Scan(r.values(&item)...); err != nil {
+			return fmt.Errorf("failed to list %s: %w", r.table, err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *sqlRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	var item T
+	err := SQLConn(ctx, r.db).QueryRowContext(ctx, r.get, id).Scan(r.values(&item)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %w", r.name, id, err)
+	}
+	return &item, nil
+}
+
+func (r *sqlRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	ids = uniqueIDs(ids)
+	found := []T{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+	params := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		params[i] = r.dialect.Placeholder(i + 1)
+		args[i] = id
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id IN (%s) ORDER BY id", strings.Join(r.columns, ", "), r.table, strings.Join(params, ", "))
+	rows, err := SQLConn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", r.table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err := rows.Scan(r.values(&item)...); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", r.table, err)
+		}
+		found = append(found, item)
+	}
+	return found, rows.Err()
+}
+
+func (r *sqlRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	if _, err := SQLConn(ctx, r.db).ExecContext(ctx, r.insert, r.args(item)...); err != nil {
+		if uniqueViolation(err) {
+			return nil, fmt.Errorf("%s with ID %s: %w", r.name, (*item).GetID(), ErrExists)
+		}
+		return nil, fmt.Errorf("failed to create %s: %w", r.name, err)
+	}
+	return item, nil
+}
+
+// uniqueViolation reports whether err refused a row whose key is taken.
+func uniqueViolation(err error) bool {
+	var pg *pgconn.PgError
+	if errors.As(err, &pg) {
+		return pg.Code == "23505" // unique_violation
+	}
+	var lite *sqlite.Error
+	if errors.As(err, &lite) {
+		return lite.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || lite.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
+
+func (r *sqlRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	res, err := SQLConn(ctx, r.db).ExecContext(ctx, r.update, r.args(item)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s %s: %w", r.name, (*item).GetID(), err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrNotFound
+	}
+	return item, nil
+}
+
+func (r *sqlRepository[T]) Delete(ctx context.Context, id string) error {
+	res, err := SQLConn(ctx, r.db).ExecContext(ctx, r.delete, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", r.name, id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
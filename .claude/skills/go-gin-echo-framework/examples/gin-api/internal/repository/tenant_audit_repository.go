@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// tenantAuditRepository checks what next returns against the tenant each
+// call was made for.
+type tenantAuditRepository[T model.Entity] struct {
+	next   CrudRepository[T]
+	name   string
+	legacy bool // items recording no tenant pass
+}
+
+// NewTenantAuditRepository asserts tenant isolation at runtime, whatever
+// the backend and decorators below it. Each call is tagged with the tenant
+// on its context when made, and every item it returns must record that
+// tenant (see model.Tenanted): a call that would return another tenant's
+// item logs the tenants and item involved and fails with
+// tenant.ErrIsolation instead, so a scoping bug surfaces as an error rather
+// than a leak. A Create or Update failing the check has been made all the
+// same, and only rolls back in a unit of work. Calls without a tenant pass
+// unchecked. name is the singular used in messages.
+//
+// Wrap the outermost repository, above any cache. With schema isolation,
+// rows written before the repositories stamped them record no tenant, so
+// pass schema true to let those through.
+func NewTenantAuditRepository[T model.Entity](next CrudRepository[T], name string, schema bool) CrudRepository[T] {
+	if _, ok := any(new(T)).(model.TenantedPtr); !ok {
+		panic(fmt.Sprintf("repository: %s does not record its tenant", reflect.TypeFor[T]()))
+	}
+	return &tenantAuditRepository[T]{next: next, name: name, legacy: schema}
+}
+
+// check fails unless item belongs to tag, the tenant op was called for.
+func (r *tenantAuditRepository[T]) check(op, tag string, item T) error {
+	owner := any(item).(model.Tenanted).Tenant()
+	if owner == tag || owner == "" && r.legacy {
+		return nil
+	}
+	log.Printf("ERROR: tenant isolation: %s for tenant %q returned %s %s of tenant %q", op, tag, r.name, item.GetID(), owner)
+	return fmt.Errorf("%w: %s returned a %s of another tenant", tenant.ErrIsolation, op, r.name)
+}
+
+// checkAll checks every item of items.
+func (r *tenantAuditRepository[T]) checkAll(op, tag string, items []T) error {
+	for _, item := range items {
+		if err := r.check(op, tag, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *tenantAuditRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	tag := tenant.From(ctx)
+	all, err := r.next.GetAll(ctx)
+	if err != nil || tag == "" {
+		return all, err
+	}
+	if err := r.checkAll("GetAll", tag, all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (r *tenantAuditRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	tag := tenant.From(ctx)
+	if tag == "" {
+		return r.next.Stream(ctx, fn)
+	}
+	return r.next.Stream(ctx, func(item T) error {
+		if err := r.check("Stream", tag, item); err != nil {
+			return err
+		}
+		return fn(item)
+	})
+}
+
+func (r *tenantAuditRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	tag := tenant.From(ctx)
+	item, err := r.next.GetByID(ctx, id)
+	if err != nil || tag == "" {
+		return item, err
+	}
+	if err := r.check("GetByID", tag, *item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *tenantAuditRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	tag := tenant.From(ctx)
+	found, err := r.next.GetByIDs(ctx, ids)
+	if err != nil || tag == "" {
+		return found, err
+	}
+	if err := r.checkAll("GetByIDs", tag, found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *tenantAuditRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	tag := tenant.From(ctx)
+	created, err := r.next.Create(ctx, item)
+	if err != nil || tag == "" {
+		return created, err
+	}
+	if err := r.check("Create", tag, *created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *tenantAuditRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	tag := tenant.From(ctx)
+	updated, err := r.next.Update(ctx, item)
+	if err != nil || tag == "" {
+		return updated, err
+	}
+	if err := r.check("Update", tag, *updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete returns nothing to check, so it reads the item first: a delete of
+// another tenant's item fails before it is made.
+func (r *tenantAuditRepository[T]) Delete(ctx context.Context, id string) error {
+	if tag := tenant.From(ctx); tag != "" {
+		item, err := r.next.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := r.check("Delete", tag, *item); err != nil {
+			return err
+		}
+	}
+	return r.next.Delete(ctx, id)
+}
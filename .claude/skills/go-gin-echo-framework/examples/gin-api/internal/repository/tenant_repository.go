@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// columnTenantRepository scopes a repository shared by every tenant to the
+// tenant on the context, by the tenant each item records (see
+// model.Tenanted). Another tenant's items read as missing, and writes to
+// them fail with ErrNotFound. Reads are filtered after next answers them,
+// so every backend is scoped alike; the tenant_id index keeps the SQL
+// tables quick to scan.
+type columnTenantRepository[T model.Entity] struct {
+	next CrudRepository[T]
+}
+
+// NewColumnTenantRepository scopes next by the tenant_id of its items. T
+// must implement model.TenantedPtr through its pointer. Every call fails
+// with tenant.ErrMissing on a context without a tenant (see tenant.With).
+func NewColumnTenantRepository[T model.Entity](next CrudRepository[T]) CrudRepository[T] {
+	if _, ok := any(new(T)).(model.TenantedPtr); !ok {
+		panic(fmt.Sprintf("repository: %s does not record its tenant", reflect.TypeFor[T]()))
+	}
+	return &columnTenantRepository[T]{next: next}
+}
+
+// owned reports whether item belongs to id.
+func owned[T model.Entity](item T, id string) bool {
+	return any(item).(model.Tenanted).Tenant() == id
+}
+
+func (r *columnTenantRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	id, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all, err := r.next.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var mine []T
+	for _, item := range all {
+		if owned(item, id) {
+			mine = append(mine, item)
+		}
+	}
+	return mine, nil
+}
+
+func (r *columnTenantRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	id, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+	return r.next.Stream(ctx, func(item T) error {
+		if !owned(item, id) {
+			return nil
+		}
+		return fn(item)
+	})
+}
+
+func (r *columnTenantRepository[T]) GetByID(ctx context.Context, itemID string) (*T, error) {
+	id, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	item, err := r.next.GetByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned(*item, id) {
+		return nil, ErrNotFound
+	}
+	return item, nil
+}
+
+func (r *columnTenantRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	id, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found, err := r.next.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mine := []T{}
+	for _, item := range found {
+		if owned(item, id) {
+			mine = append(mine, item)
+		}
+	}
+	return mine, nil
+}
+
+func (r *columnTenantRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	id, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	any(item).(model.TenantedPtr).SetTenant(id)
+	return r.next.Create(ctx, item)
+}
+
+func (r *columnTenantRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	// Checking ownership first keeps an update from moving another
+	// tenant's item into this one
+	if _, err := r.GetByID(ctx, (*item).GetID()); err != nil {
+		return nil, err
+	}
+	any(item).(model.TenantedPtr).SetTenant(tenant.From(ctx))
+	return r.next.Update(ctx, item)
+}
+
+func (r *columnTenantRepository[T]) Delete(ctx context.Context, itemID string) error {
+	if _, err := r.GetByID(ctx, itemID); err != nil {
+		return err
+	}
+	return r.next.Delete(ctx, itemID)
+}
+
+// schemaTenantRepository routes every call to the repository of the tenant
+// on the context.
+type schemaTenantRepository[T model.Entity] struct {
+	tenants map[string]CrudRepository[T]
+}
+
+// NewSchemaTenantRepository serves each tenant from a repository of its
+// own, by tenant ID, e.g. over a table in the tenant's schema (see
+// PrepareTenantTable). Items that are model.Tenanted record their tenant
+// all the same. Calls fail with tenant.ErrMissing on a context
+// without a tenant and with tenant.ErrUnknown for a tenant not in tenants.
+func NewSchemaTenantRepository[T model.Entity](tenants map[string]CrudRepository[T]) CrudRepository[T] {
+	return &schemaTenantRepository[T]{tenants: tenants}
+}
+
+func (r *schemaTenantRepository[T]) repo(ctx context.Context) (CrudRepository[T], error) {
+	id, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repo, ok := r.tenants[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", tenant.ErrUnknown, id)
+	}
+	return repo, nil
+}
+
+func (r *schemaTenantRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetAll(ctx)
+}
+
+func (r *schemaTenantRepository[T]) Stream(ctx context.Context, fn func(item T) error) error {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return err
+	}
+	return repo.Stream(ctx, fn)
+}
+
+func (r *schemaTenantRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByID(ctx, id)
+}
+
+func (r *schemaTenantRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByIDs(ctx, ids)
+}
+
+func (r *schemaTenantRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stamp(ctx, item)
+	return repo.Create(ctx, item)
+}
+
+func (r *schemaTenantRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stamp(ctx, item)
+	return repo.Update(ctx, item)
+}
+
+// stamp records the tenant on ctx in item if it is tenanted. The tables of
+// each tenant do not need it, but NewTenantAuditRepository checks it.
+func stamp[T model.Entity](ctx context.Context, item *T) {
+	if t, ok := any(item).(model.TenantedPtr); ok {
+		t.SetTenant(tenant.From(ctx))
+	}
+}
+
+func (r *schemaTenantRepository[T]) Delete(ctx context.Context, id string) error {
+	repo, err := r.repo(ctx)
+	if err != nil {
+		return err
+	}
+	return repo.Delete(ctx, id)
+}
+
+// scope returns the tenant on ctx, or tenant.ErrMissing.
+func scope(ctx context.Context) (string, error) {
+	id := tenant.From(ctx)
+	if id == "" {
+		return "", tenant.ErrMissing
+	}
+	return id, nil
+}
+
+// PrepareTenantTable creates the copy of table that holds the rows of the
+// tenant whose schema (see tenant.Schema) is given, unless it exists, and
+// returns its name for NewSQLRepository. On PostgreSQL the copy is
+// schema.table, created LIKE the public table with its defaults,
+// constraints and indexes; SQLite has no schemas, so it is schema__table,
+// created from the table's DDL without its indexes.
+//
+// Copies are made from the migrated public table when first prepared.
+// Migrations run on the public tables only: one changing a table must be
+// applied to the existing tenant copies by hand.
+func PrepareTenantTable(ctx context.Context, db *sql.DB, dialect Dialect, schema, table string) (string, error) {
+	switch dialect {
+	case DialectPostgres:
+		name := schema + "." + table
+		if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+schema); err != nil {
+			return "", fmt.Errorf("failed to create schema %s: %w", schema, err)
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (LIKE public.%s INCLUDING ALL)", name, table)); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		return name, nil
+	case DialectSQLite:
+		name := schema + "__" + table
+		var ddl string
+		err := db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?1", table).Scan(&ddl)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("failed to create %s: no table %s to copy", name, table)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		columns, ok := strings.CutPrefix(ddl, "CREATE TABLE "+table)
+		if !ok {
+			return "", fmt.Errorf("failed to create %s: unexpected DDL of %s: %s", name, table, ddl)
+		}
+		if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+name+columns); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("unsupported SQL dialect %q", dialect)
+}
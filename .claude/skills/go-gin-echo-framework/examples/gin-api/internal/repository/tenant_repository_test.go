@@ -0,0 +1,225 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/repository/repotest"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// ids returns the IDs of items, in order.
+func ids(items []model.User) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.ID
+	}
+	return out
+}
+
+func TestColumnTenantRepository(t *testing.T) {
+	repo := repository.NewColumnTenantRepository(newMemory(t))
+	acme := tenant.With(context.Background(), "acme")
+	globex := tenant.With(context.Background(), "globex")
+
+	for ctx, id := range map[context.Context]string{acme: "1", globex: "2"} {
+		if _, err := repo.Create(ctx, &model.User{ID: id, Name: "n", TenantID: "spoofed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := repo.GetAll(acme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != "1" || all[0].TenantID != "acme" {
+		t.Errorf("acme lists %+v, want only user 1 of acme", all)
+	}
+	found, err := repo.GetByIDs(globex, []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(found); len(got) != 1 || got[0] != "2" {
+		t.Errorf("globex gets %v by ID, want [2]", got)
+	}
+	var streamed []string
+	if err := repo.Stream(globex, func(u model.User) error {
+		streamed = append(streamed, u.ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != 1 || streamed[0] != "2" {
+		t.Errorf("globex streams %v, want [2]", streamed)
+	}
+
+	// acme cannot see, change or delete globex's user
+	if _, err := repo.GetByID(acme, "2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID of another tenant's user: %v", err)
+	}
+	if _, err := repo.Update(acme, &model.User{ID: "2", Name: "taken"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update of another tenant's user: %v", err)
+	}
+	if err := repo.Delete(acme, "2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete of another tenant's user: %v", err)
+	}
+	if u, err := repo.GetByID(globex, "2"); err != nil || u.Name != "n" {
+		t.Errorf("globex's user after acme's attempts: %+v, %v", u, err)
+	}
+
+	if _, err := repo.GetAll(context.Background()); !errors.Is(err, tenant.ErrMissing) {
+		t.Errorf("GetAll without a tenant: %v", err)
+	}
+	if _, err := repo.Create(context.Background(), &model.User{ID: "3", Name: "n"}); !errors.Is(err, tenant.ErrMissing) {
+		t.Errorf("Create without a tenant: %v", err)
+	}
+}
+
+func TestColumnTenantRepositoryRequiresTenantedModel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("scoped a model that does not record its tenant")
+		}
+	}()
+	repository.NewColumnTenantRepository(repository.NewMemoryRepository[model.UserV2]("user"))
+}
+
+func TestSchemaTenantRepositoryOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	repos := map[string]repository.CrudRepository[model.User]{}
+	for _, id := range []string{"acme", "globex-corp"} {
+		// Preparing is idempotent, as on every startup
+		for range 2 {
+			table, err := repository.PrepareTenantTable(ctx, db, dialect, tenant.Schema(id), "users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			repos[id] = repository.NewSQLRepository[model.User](db, dialect, table, "user")
+		}
+	}
+	repo := repository.NewSchemaTenantRepository(repos)
+	acme := tenant.With(ctx, "acme")
+	globex := tenant.With(ctx, "globex-corp")
+
+	// The same ID in two tenants names two users
+	if _, err := repo.Create(acme, &model.User{ID: "1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(globex, &model.User{ID: "1", Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	for ctx, want := range map[context.Context]string{acme: "Ann", globex: "Bob"} {
+		all, err := repo.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].Name != want {
+			t.Errorf("%s lists %+v, want only %s", tenant.From(ctx), all, want)
+		}
+	}
+	var public int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&public); err != nil || public != 0 {
+		t.Errorf("%d users in the public table (%v), want none", public, err)
+	}
+
+	if _, err := repo.GetAll(tenant.With(ctx, "initech")); !errors.Is(err, tenant.ErrUnknown) {
+		t.Errorf("GetAll of an unknown tenant: %v", err)
+	}
+	if _, err := repo.GetAll(ctx); !errors.Is(err, tenant.ErrMissing) {
+		t.Errorf("GetAll without a tenant: %v", err)
+	}
+}
+
+// columnScoped scopes the repositories of next by the tenant of each item.
+func columnScoped(next repotest.Factory[model.User]) repotest.Factory[model.User] {
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		return repository.NewColumnTenantRepository(next(t))
+	}
+}
+
+// schemaScoped serves each of repotest.Tenants from a repository of next.
+func schemaScoped(next repotest.Factory[model.User]) repotest.Factory[model.User] {
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		tenants := map[string]repository.CrudRepository[model.User]{}
+		for _, id := range repotest.Tenants {
+			tenants[id] = next(t)
+		}
+		return repository.NewSchemaTenantRepository(tenants)
+	}
+}
+
+// audited checks the results of the repositories of next against the tenant
+// of each call.
+func audited(next repotest.Factory[model.User], schema bool) repotest.Factory[model.User] {
+	return func(t testing.TB) repository.CrudRepository[model.User] {
+		return repository.NewTenantAuditRepository(next(t), "user", schema)
+	}
+}
+
+func TestColumnTenantIsolation(t *testing.T) {
+	for name, backend := range map[string]func(t *testing.T) repotest.Factory[model.User]{
+		"memory":   func(*testing.T) repotest.Factory[model.User] { return newMemory },
+		"sqlite":   func(*testing.T) repotest.Factory[model.User] { return newSQLite },
+		"postgres": func(t *testing.T) repotest.Factory[model.User] { return newPostgres(t) },
+		"mongo":    func(t *testing.T) repotest.Factory[model.User] { return newMongo(t) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			repotest.RunTenantIsolation(t, audited(cached(columnScoped(backend(t))), false), newUser)
+		})
+	}
+}
+
+func TestSchemaTenantIsolation(t *testing.T) {
+	for name, backend := range map[string]repotest.Factory[model.User]{
+		"memory": newMemory,
+		"sqlite": newSQLite,
+	} {
+		t.Run(name, func(t *testing.T) {
+			repotest.RunTenantIsolation(t, audited(cached(schemaScoped(backend)), true), newUser)
+		})
+	}
+}
+
+func TestTenantAuditRepositoryFailsCallsThatLeak(t *testing.T) {
+	// Unscoped, the repository answers every tenant with every user
+	leaky := newMemory(t)
+	for _, u := range []model.User{{ID: "1", Name: "n", TenantID: "acme"}, {ID: "2", Name: "n", TenantID: "globex"}} {
+		if _, err := leaky.Create(context.Background(), &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := repository.NewTenantAuditRepository(leaky, "user", false)
+	acme := tenant.With(context.Background(), "acme")
+
+	if u, err := repo.GetByID(acme, "1"); err != nil || u.ID != "1" {
+		t.Errorf("GetByID of acme's own user: %+v, %v", u, err)
+	}
+	_, getAll := repo.GetAll(acme)
+	_, get := repo.GetByID(acme, "2")
+	_, getMany := repo.GetByIDs(acme, []string{"1", "2"})
+	stream := repo.Stream(acme, func(model.User) error { return nil })
+	_, update := repo.Update(acme, &model.User{ID: "2", Name: "taken", TenantID: "globex"})
+	del := repo.Delete(acme, "2")
+	for op, err := range map[string]error{"GetAll": getAll, "GetByID": get, "GetByIDs": getMany, "Stream": stream, "Update": update, "Delete": del} {
+		if !errors.Is(err, tenant.ErrIsolation) {
+			t.Errorf("%s returning globex's user to acme: %v, want tenant.ErrIsolation", op, err)
+		}
+	}
+	if _, err := leaky.GetByID(context.Background(), "2"); err != nil {
+		t.Errorf("globex's user after acme's delete: %v", err)
+	}
+	if all, err := repo.GetAll(context.Background()); err != nil || len(all) != 2 {
+		t.Errorf("GetAll without a tenant = %d users, %v; want both, unchecked", len(all), err)
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnitOfWork runs a group of repository calls as one transaction.
+type UnitOfWork interface {
+	// Do calls fn with a context carrying the transaction; repositories on
+	// the same database pick it up from that context. The transaction commits
+	// when fn returns nil and rolls back when it returns an error. A Do
+	// nested inside another joins the outer transaction.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type unitKey struct{}
+
+// unit is the per-transaction state shared by every backend.
+type unit struct {
+	afterCommit []func()
+}
+
+// InTransaction reports whether ctx belongs to a UnitOfWork.Do call.
+func InTransaction(ctx context.Context) bool {
+	return ctx.Value(unitKey{}) != nil
+}
+
+// AfterCommit runs fn once the transaction on ctx commits, or immediately
+// outside a transaction. Side effects that must not outlive a rollback, such
+// as change-feed entries and cache invalidation, go through it.
+func AfterCommit(ctx context.Context, fn func()) {
+	if u, ok := ctx.Value(unitKey{}).(*unit); ok {
+		u.afterCommit = append(u.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// begin returns ctx carrying a fresh unit, and false if ctx already has one
+// (the caller then joins the outer transaction).
+func begin(ctx context.Context) (context.Context, *unit, bool) {
+	if _, ok := ctx.Value(unitKey{}).(*unit); ok {
+		return ctx, nil, false
+	}
+	u := &unit{}
+	return context.WithValue(ctx, unitKey{}, u), u, true
+}
+
+func (u *unit) committed() {
+	for _, fn := range u.afterCommit {
+		fn()
+	}
+}
+
+type noopUnitOfWork struct{}
+
+// NewNoopUnitOfWork is the UnitOfWork for the in-memory repositories: fn runs
+// as is, so writes made before an error are kept. AfterCommit callbacks still
+// run only when fn succeeds.
+func NewNoopUnitOfWork() UnitOfWork {
+	return noopUnitOfWork{}
+}
+
+func (noopUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, u, outer := begin(ctx)
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	if outer {
+		u.committed()
+	}
+	return nil
+}
+
+type sqlTxKey struct{ db *sql.DB }
+
+// Querier is the part of *sql.DB and *sql.Tx the SQL repository uses.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLConn returns the transaction on ctx for db, or db itself. Stores
+// outside this package use it to write in a NewSQLUnitOfWork(db) transaction.
+func SQLConn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(sqlTxKey{db}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+type sqlUnitOfWork struct {
+	db *sql.DB
+}
+
+// NewSQLUnitOfWork runs units of work in transactions on db, shared by every
+// NewSQLRepository on the same *sql.DB.
+func NewSQLUnitOfWork(db *sql.DB) UnitOfWork {
+	return &sqlUnitOfWork{db: db}
+}
+
+func (w *sqlUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, u, outer := begin(ctx)
+	if !outer {
+		return fn(ctx)
+	}
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(context.WithValue(ctx, sqlTxKey{w.db}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("WARNING: rollback: %v", rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	u.committed()
+	return nil
+}
+
+type mongoUnitOfWork struct {
+	client *mongo.Client
+}
+
+// NewMongoUnitOfWork runs units of work in MongoDB transactions, which need a
+// replica set or sharded cluster. Against a standalone server it logs a
+// warning and returns NewNoopUnitOfWork instead.
+func NewMongoUnitOfWork(ctx context.Context, db *mongo.Database) (UnitOfWork, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, fmt.Errorf("failed to query MongoDB topology: %w", err)
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		log.Printf("WARNING: MongoDB is a standalone server; multi-document transactions are disabled")
+		return NewNoopUnitOfWork(), nil
+	}
+	return &mongoUnitOfWork{client: db.Client()}, nil
+}
+
+func (w *mongoUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, u, outer := begin(ctx)
+	if !outer {
+		return fn(ctx)
+	}
+	session, err := w.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(context.Background())
+
+	// WithTransaction retries fn on transient errors, so AfterCommit
+	// callbacks from failed attempts are dropped before each retry.
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		u.afterCommit = nil
+		return nil, fn(sc)
+	})
+	if err != nil {
+		return err
+	}
+	u.committed()
+	return nil
+}
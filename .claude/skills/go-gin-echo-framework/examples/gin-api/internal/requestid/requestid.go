@@ -0,0 +1,82 @@
+// Package requestid tags every request with an ID, taken from the caller's
+// X-Request-ID header or generated, and echoes it in the response so logs on
+// both sides can be correlated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/gin-api/internal/auth"
+)
+
+// Header carries the request ID in both directions.
+const Header = "X-Request-ID"
+
+// maxLen bounds IDs accepted from callers so they cannot bloat log lines.
+const maxLen = 64
+
+type contextKey struct{}
+
+// Middleware sets the request ID on the response header, the gin context
+// (key "request_id") and the request context (see FromContext).
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if id == "" || len(id) > maxLen {
+			id = generate()
+		}
+		c.Set("request_id", id)
+		c.Header(Header, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, id))
+		c.Next()
+	}
+}
+
+// FromContext returns the request ID stored by Middleware, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// LogFormatter is gin's default access log line with the request ID added,
+// for gin.LoggerWithFormatter.
+func LogFormatter(p gin.LogFormatterParams) string {
+	return logLine(p, "")
+}
+
+// VerboseLogFormatter is LogFormatter plus the user agent and the request and
+// response body sizes.
+func VerboseLogFormatter(p gin.LogFormatterParams) string {
+	return logLine(p, fmt.Sprintf(" | ua=%q | in=%d out=%d", p.Request.UserAgent(), p.Request.ContentLength, p.BodySize))
+}
+
+func logLine(p gin.LogFormatterParams, extra string) string {
+	id, _ := p.Keys["request_id"].(string)
+	if caller, ok := p.Keys[auth.CallerKey].(*auth.Caller); ok {
+		extra = " | caller=" + caller.String() + extra
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | id=%s%s\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		p.StatusCode,
+		p.Latency,
+		p.ClientIP,
+		p.Method,
+		p.Path,
+		id,
+		extra,
+		p.ErrorMessage,
+	)
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
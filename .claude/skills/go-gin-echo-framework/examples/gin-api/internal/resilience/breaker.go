@@ -0,0 +1,144 @@
+package resilience
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// ErrOpen is returned instead of calling a dependency whose breaker is open.
+var ErrOpen = errors.New("circuit breaker open")
+
+// State is where a breaker is in its cycle.
+type State string
+
+const (
+	Closed   State = "closed"    // calls go through
+	Open     State = "open"      // calls fail fast with ErrOpen
+	HalfOpen State = "half-open" // one trial call goes through
+)
+
+// Status is a point-in-time snapshot of a Breaker.
+type Status struct {
+	Name        string           `json:"name"`
+	State       State            `json:"state"`
+	Since       time.Time        `json:"since"`       // of the last state change, or the first call
+	Failures    int              `json:"failures"`    // consecutive, since the last success
+	Rejected    uint64           `json:"rejected"`    // calls failed fast with ErrOpen
+	Transitions map[State]uint64 `json:"transitions"` // state changes, by the state entered
+}
+
+// Breaker trips after consecutive failures of one dependency. It is safe
+// for concurrent use.
+type Breaker struct {
+	name      string
+	threshold int
+	openFor   time.Duration
+	clk       clock.Clock
+
+	mu          sync.Mutex
+	state       State
+	since       time.Time
+	failures    int
+	trial       bool // a half-open trial call is running
+	rejected    uint64
+	transitions map[State]uint64
+}
+
+func newBreaker(name string, threshold int, openFor time.Duration, clk clock.Clock) *Breaker {
+	return &Breaker{
+		name:        name,
+		threshold:   threshold,
+		openFor:     openFor,
+		clk:         clk,
+		state:       Closed,
+		since:       clk.Now(),
+		transitions: map[State]uint64{},
+	}
+}
+
+// Status returns the state of b, half-open once an open breaker has waited
+// OpenFor.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	transitions := make(map[State]uint64, len(b.transitions))
+	for s, n := range b.transitions {
+		transitions[s] = n
+	}
+	return Status{
+		Name:        b.name,
+		State:       b.state,
+		Since:       b.since,
+		Failures:    b.failures,
+		Rejected:    b.rejected,
+		Transitions: transitions,
+	}
+}
+
+// allow admits a call, or returns ErrOpen. Every admitted call must be
+// followed by success, failure or release.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	switch {
+	case b.state == Open, b.state == HalfOpen && b.trial:
+		b.rejected++
+		return ErrOpen
+	case b.state == HalfOpen:
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *Breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	if b.state == HalfOpen {
+		b.trial = false
+		b.change(Closed)
+	}
+}
+
+func (b *Breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	switch {
+	case b.state == HalfOpen:
+		b.trial = false
+		b.change(Open)
+	case b.state == Closed && b.failures >= b.threshold:
+		b.change(Open)
+	}
+}
+
+// release ends an admitted call whose outcome says nothing about the
+// dependency, letting another trial call through.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen {
+		b.trial = false
+	}
+}
+
+// expire moves an open breaker to half-open once it has waited openFor.
+func (b *Breaker) expire() {
+	if b.state == Open && !b.clk.Now().Before(b.since.Add(b.openFor)) {
+		b.change(HalfOpen)
+	}
+}
+
+func (b *Breaker) change(to State) {
+	log.Printf("WARNING: breaker %s: %s -> %s", b.name, b.state, to)
+	b.state = to
+	b.since = b.clk.Now()
+	b.transitions[to]++
+}
@@ -0,0 +1,205 @@
+// Package resilience guards calls to the database and to other services
+// with a circuit breaker, a timeout and retries with jitter. A Policy wraps
+// one dependency; repository.NewResilientRepository and Registry.Transport
+// apply policies to repositories and HTTP clients.
+//
+// A breaker opens after FailureThreshold consecutive failures and then
+// fails calls fast with ErrOpen for OpenFor, sparing a struggling
+// dependency. It then lets one trial call through: its success closes the
+// breaker, its failure opens it again. Registry.Snapshot reports every
+// breaker, as served at /debug/breakers.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// Options configures every policy of a Registry.
+type Options struct {
+	Enabled          bool          `yaml:"enabled"`
+	Timeout          time.Duration `yaml:"timeout"`           // per attempt; 0 leaves attempts unbounded
+	Retries          int           `yaml:"retries"`           // further attempts of a failed call, if it is safe to repeat
+	Backoff          time.Duration `yaml:"backoff"`           // wait before the first retry, doubling for each further one, with full jitter
+	FailureThreshold int           `yaml:"failure_threshold"` // consecutive failures that open a breaker
+	OpenFor          time.Duration `yaml:"open_for"`          // how long an open breaker fails fast before a trial call
+}
+
+// Validate checks the settings of enabled policies.
+func (o Options) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	var errs []error
+	if o.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must not be negative"))
+	}
+	if o.Retries < 0 {
+		errs = append(errs, errors.New("retries must not be negative"))
+	}
+	if o.Retries > 0 && o.Backoff <= 0 {
+		errs = append(errs, errors.New("backoff must be positive"))
+	}
+	if o.FailureThreshold <= 0 {
+		errs = append(errs, errors.New("failure_threshold must be positive"))
+	}
+	if o.OpenFor <= 0 {
+		errs = append(errs, errors.New("open_for must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// maxBackoff caps the wait between retries.
+const maxBackoff = 5 * time.Second
+
+// Registry holds the breakers of one process, one per dependency name. It
+// is safe for concurrent use.
+type Registry struct {
+	opts Options
+	clk  clock.Clock
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry creates the breakers of opts on demand; clk (nil is the
+// system clock) times how long they stay open.
+func NewRegistry(opts Options, clk clock.Clock) *Registry {
+	return &Registry{opts: opts, clk: clock.OrSystem(clk), breakers: map[string]*Breaker{}}
+}
+
+// Breaker returns the breaker of the dependency name, creating it closed.
+func (r *Registry) Breaker(name string) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.breakers[name]
+	if !ok {
+		b = newBreaker(name, r.opts.FailureThreshold, r.opts.OpenFor, r.clk)
+		r.breakers[name] = b
+	}
+	return b
+}
+
+// Snapshot returns the status of every breaker, by name.
+func (r *Registry) Snapshot() []Status {
+	r.mu.Lock()
+	breakers := make([]*Breaker, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		breakers = append(breakers, b)
+	}
+	r.mu.Unlock()
+
+	statuses := make([]Status, len(breakers))
+	for i, b := range breakers {
+		statuses[i] = b.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Policy returns the policy of the dependency name. failure reports the
+// errors that count against the dependency; others, such as a record that
+// does not exist, are returned without a retry and leave the breaker as it
+// is. nil counts every error but context.Canceled, which is the caller
+// giving up.
+func (r *Registry) Policy(name string, failure func(error) bool) *Policy {
+	if failure == nil {
+		failure = func(error) bool { return true }
+	}
+	return &Policy{opts: r.opts, breaker: r.Breaker(name), failure: failure}
+}
+
+// Policy guards the calls to one dependency.
+type Policy struct {
+	opts    Options
+	breaker *Breaker
+	failure func(error) bool
+}
+
+// Do calls fn with a context bounded by the timeout, retrying failures.
+// Only use it for calls that are safe to repeat.
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.run(ctx, true, p.bounded(fn))
+}
+
+// Once calls fn with a context bounded by the timeout, without retries.
+func (p *Policy) Once(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.run(ctx, false, p.bounded(fn))
+}
+
+// Guard calls fn through the breaker only, for calls that take as long as
+// their caller wants, such as a stream.
+func (p *Policy) Guard(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.run(ctx, false, fn)
+}
+
+func (p *Policy) bounded(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	if p.opts.Timeout <= 0 {
+		return fn
+	}
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+		defer cancel()
+		return fn(ctx)
+	}
+}
+
+// run calls fn through the breaker, up to 1+Retries times if retry is set.
+func (p *Policy) run(ctx context.Context, retry bool, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := p.attempt(ctx, fn)
+		if err == nil || !retry || attempt >= p.opts.Retries || !p.failed(err) || errors.Is(err, ErrOpen) || ctx.Err() != nil {
+			return err
+		}
+		if err := sleep(ctx, p.backoff(attempt+1)); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Policy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := p.breaker.allow(); err != nil {
+		return err
+	}
+	err := fn(ctx)
+	switch {
+	case errors.Is(err, context.Canceled):
+		p.breaker.release() // says nothing about the dependency
+	case err != nil && p.failure(err):
+		p.breaker.failure()
+	default:
+		p.breaker.success()
+	}
+	return err
+}
+
+func (p *Policy) failed(err error) bool {
+	return !errors.Is(err, context.Canceled) && p.failure(err)
+}
+
+// backoff returns a random wait of up to Backoff doubled for each retry
+// before this one, so that clients failing together retry apart.
+func (p *Policy) backoff(retry int) time.Duration {
+	backoff := p.opts.Backoff
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return rand.N(min(backoff, maxBackoff)) + 1
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,148 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+var errDown = errors.New("down")
+
+func newRegistry(clk clock.Clock) *Registry {
+	return NewRegistry(Options{Enabled: true, Retries: 2, Backoff: time.Millisecond, FailureThreshold: 3, OpenFor: time.Minute}, clk)
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := newRegistry(clk).Policy("db", nil)
+	fail := func(context.Context) error { return errDown }
+	ok := func(context.Context) error { return nil }
+	state := func() State { return p.breaker.Status().State }
+
+	for range 2 {
+		if err := p.Once(context.Background(), fail); !errors.Is(err, errDown) {
+			t.Fatalf("err = %v, want errDown", err)
+		}
+	}
+	if state() != Closed {
+		t.Fatalf("state after 2 failures = %s", state())
+	}
+	p.Once(context.Background(), fail)
+	if state() != Open {
+		t.Fatalf("state after 3 failures = %s", state())
+	}
+	calls := 0
+	if err := p.Do(context.Background(), func(context.Context) error { calls++; return nil }); !errors.Is(err, ErrOpen) || calls != 0 {
+		t.Fatalf("open breaker: err = %v after %d calls", err, calls)
+	}
+
+	// A failed trial opens the breaker for another minute
+	clk.Advance(time.Minute)
+	if state() != HalfOpen {
+		t.Fatalf("state after open_for = %s", state())
+	}
+	p.Once(context.Background(), fail)
+	if state() != Open {
+		t.Fatalf("state after failed trial = %s", state())
+	}
+	clk.Advance(time.Minute)
+	if err := p.Once(context.Background(), ok); err != nil {
+		t.Fatal(err)
+	}
+
+	s := p.breaker.Status()
+	if s.State != Closed || s.Failures != 0 || s.Rejected != 1 {
+		t.Errorf("status = %+v", s)
+	}
+	if want := map[State]uint64{Open: 2, HalfOpen: 2, Closed: 1}; len(s.Transitions) != 3 || s.Transitions[Open] != want[Open] || s.Transitions[HalfOpen] != want[HalfOpen] || s.Transitions[Closed] != want[Closed] {
+		t.Errorf("transitions = %v, want %v", s.Transitions, want)
+	}
+}
+
+func TestPolicyRetries(t *testing.T) {
+	errMissing := errors.New("missing")
+	p := newRegistry(nil).Policy("db", func(err error) bool { return !errors.Is(err, errMissing) })
+
+	calls := 0
+	err := p.Do(context.Background(), func(context.Context) error {
+		if calls++; calls < 3 {
+			return errDown
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Do: err = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	p.Once(context.Background(), func(context.Context) error { calls++; return errDown })
+	if calls != 1 {
+		t.Errorf("Once called fn %d times", calls)
+	}
+
+	// Errors that are not failures are neither retried nor counted
+	calls = 0
+	for range 5 {
+		p.Do(context.Background(), func(context.Context) error { calls++; return errMissing })
+	}
+	if s := p.breaker.Status(); calls != 5 || s.State != Closed {
+		t.Errorf("after 5 misses: %d calls, state %s", calls, s.State)
+	}
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	p := NewRegistry(Options{Enabled: true, Timeout: 10 * time.Millisecond, FailureThreshold: 1, OpenFor: time.Minute}, nil).Policy("db", nil)
+	err := p.Once(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || p.breaker.Status().State != Open {
+		t.Errorf("err = %v, state %s", err, p.breaker.Status().State)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	breakers := newRegistry(nil)
+	client := &http.Client{Transport: breakers.Transport(nil)}
+
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("GET: %d after %d calls, want 200 after 3", res.StatusCode, calls.Load())
+	}
+
+	// POST is not repeatable: its 503 is returned as it is
+	calls.Store(0)
+	res, err = client.Post(srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Fatalf("POST: %d after %d calls, want 503 after 1", res.StatusCode, calls.Load())
+	}
+
+	statuses := breakers.Snapshot()
+	if len(statuses) != 1 || statuses[0].Name != "http:"+strings.TrimPrefix(srv.URL, "http://") || statuses[0].Failures != 1 {
+		t.Errorf("snapshot = %+v", statuses)
+	}
+}
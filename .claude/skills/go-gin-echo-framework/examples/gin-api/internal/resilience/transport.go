@@ -0,0 +1,95 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Transport guards the requests of next (nil is http.DefaultTransport) with
+// a policy per host, named "http:<host>". Responses with a 5xx status count
+// as failures. Requests with a method that is safe to repeat (GET, HEAD,
+// OPTIONS, PUT, DELETE) are retried if their body, if any, can be sent
+// again; the last response is returned if every attempt failed. The timeout
+// runs until the response body is closed.
+func (r *Registry) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{registry: r, next: next}
+}
+
+type transport struct {
+	registry *Registry
+	next     http.RoundTripper
+}
+
+// errStatus marks a response that counts as a failure.
+var errStatus = errors.New("server error")
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.registry.Policy("http:"+req.URL.Host, nil)
+	var res *http.Response
+	sent := false
+	err := p.run(req.Context(), repeatable(req), func(ctx context.Context) error {
+		attempt := req
+		if sent {
+			if res != nil {
+				res.Body.Close()
+				res = nil
+			}
+			attempt = req.Clone(ctx)
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return err
+				}
+				attempt.Body = body
+			}
+		}
+		sent = true
+
+		cancel := context.CancelFunc(func() {})
+		if p.opts.Timeout > 0 {
+			var ctx context.Context
+			ctx, cancel = context.WithTimeout(attempt.Context(), p.opts.Timeout)
+			attempt = attempt.WithContext(ctx)
+		}
+		got, err := t.next.RoundTrip(attempt)
+		if err != nil {
+			cancel()
+			return err
+		}
+		got.Body = &cancelBody{ReadCloser: got.Body, cancel: cancel}
+		res = got
+		if got.StatusCode >= 500 {
+			return errStatus
+		}
+		return nil
+	})
+	if res != nil {
+		return res, nil
+	}
+	return nil, err
+}
+
+// repeatable reports whether req can be sent again after a failure.
+func repeatable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+// cancelBody ends the timeout of a response once it is read.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
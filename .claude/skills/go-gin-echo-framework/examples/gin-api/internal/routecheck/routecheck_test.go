@@ -0,0 +1,35 @@
+package routecheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Route
+		want string // substring of the report, "" for no conflict
+	}{
+		{"distinct paths", Route{"GET", "/users/", "list"}, Route{"GET", "/users/:id", "get"}, ""},
+		{"different methods", Route{"GET", "/users/:id", "get"}, Route{"PUT", "/users/:id", "update"}, ""},
+		{"static beside wildcard", Route{"GET", "/users/sync", "sync"}, Route{"GET", "/users/:id", "get"}, ""},
+		{"duplicate", Route{"GET", "/users/", "list"}, Route{"GET", "/users/", "other"}, "registered twice"},
+		{"trailing slash", Route{"GET", "/users", "a"}, Route{"GET", "/users/", "b"}, "trailing slash"},
+		{"wildcard names", Route{"GET", "/users/:id", "a"}, Route{"GET", "/users/:userID/orders", "b"}, "share a position"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Conflicts([]Route{tt.a, tt.b})
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected conflict: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Conflicts = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
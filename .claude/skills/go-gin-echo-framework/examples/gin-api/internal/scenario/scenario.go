@@ -0,0 +1,418 @@
+// Package scenario runs API walkthroughs described in YAML: each scenario is
+// a list of steps, each step a request, the expectations on its response and
+// the values to extract from it for later steps, as the ID of a created
+// record or a token. The same files are run by the tests against the router
+// and by cmd/scenario against a live instance, so the walkthroughs in
+// scenarios/ double as documentation that cannot go stale.
+//
+// A step looks like this:
+//
+//	steps:
+//	  - name: read the user back
+//	    request:
+//	      method: GET
+//	      path: /users/${id}                   # ${name} is replaced by a variable
+//	      headers: {Authorization: "Bearer ${access_token}"}
+//	      body: {name: Ada}                    # sent as JSON
+//	    expect:
+//	      status: 200                          # 0 accepts any 2xx
+//	      headers: {Content-Type: application/json; charset=utf-8}
+//	      body: {$.id: "${id}", $.name: Ada}   # path -> value; numbers and strings compare as JSON
+//	      matches: {$.updated_at: "^2\\d{3}-"} # path -> regular expression
+//	      length: {$: 1}                       # path -> items of an array or keys of an object
+//	      absent: [$.password]                 # paths that must not exist
+//	    extract:
+//	      id: $.id                             # variable -> path
+//
+// Paths are $ for the whole body, followed by .field and [index] segments.
+// Besides the scenario's own variables, ${run} is unique to each run, so a
+// scenario can create records that do not collide with earlier runs' on a
+// live instance.
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is one walkthrough. Vars are the variables before the first step;
+// they may refer to ${run}.
+type Scenario struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Vars        map[string]string `yaml:"vars"`
+	Steps       []Step            `yaml:"steps"`
+}
+
+// Step is one request and what its response must look like.
+type Step struct {
+	Name    string            `yaml:"name"`
+	Request Request           `yaml:"request"`
+	Expect  Expect            `yaml:"expect"`
+	Extract map[string]string `yaml:"extract"` // variable -> body path
+}
+
+// Request is sent with its strings interpolated.
+type Request struct {
+	Method  string            `yaml:"method"`
+	Path    string            `yaml:"path"`
+	Headers map[string]string `yaml:"headers"`
+	Body    any               `yaml:"body"` // encoded as JSON; Content-Type defaults to application/json
+}
+
+// Expect lists the assertions on a response. Every one is checked, so a
+// failing step reports all its differences at once.
+type Expect struct {
+	Status  int               `yaml:"status"`  // 0 accepts any 2xx
+	Headers map[string]string `yaml:"headers"` // exact values
+	Body    map[string]any    `yaml:"body"`    // path -> expected value
+	Matches map[string]string `yaml:"matches"` // path -> regular expression the value must match
+	Length  map[string]int    `yaml:"length"`  // path -> items of an array or keys of an object
+	Absent  []string          `yaml:"absent"`  // paths that must not exist
+}
+
+// Load reads the scenarios from the *.yaml files in dir, in file name order.
+func Load(dir string) ([]Scenario, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var out []Scenario
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Parse decodes and checks a scenario file.
+func Parse(data []byte) (Scenario, error) {
+	var s Scenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return s, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if s.Name == "" {
+		return s, errors.New("invalid scenario: name is required")
+	}
+	for i, step := range s.Steps {
+		if step.Request.Method == "" || !strings.HasPrefix(step.Request.Path, "/") {
+			return s, fmt.Errorf("invalid scenario: step %d: request needs a method and a path starting with /", i+1)
+		}
+		for _, pattern := range step.Expect.Matches {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return s, fmt.Errorf("invalid scenario: step %d: %w", i+1, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Doer sends a request, as *http.Client does.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Handler serves requests with h directly, without a network, e.g. to run
+// scenarios against the router in tests.
+func Handler(h http.Handler) Doer {
+	return handlerDoer{h}
+}
+
+type handlerDoer struct{ h http.Handler }
+
+func (d handlerDoer) Do(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	d.h.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+// Runner runs scenarios against one server.
+type Runner struct {
+	Base   string    // prepended to every path, e.g. http://localhost:8080; empty with Handler
+	Client Doer      // e.g. Handler(router) or an *http.Client
+	Log    io.Writer // each step is reported here when set
+}
+
+// Run performs the steps of s in order and stops at the first that fails,
+// as later steps usually depend on it. The error names the step and lists
+// every expectation it missed.
+func (r *Runner) Run(ctx context.Context, s Scenario) error {
+	vars := map[string]string{"run": strconv.FormatInt(time.Now().UnixNano(), 36)}
+	for k, v := range s.Vars {
+		vars[k] = interpolate(v, vars)
+	}
+	for i, step := range s.Steps {
+		start := time.Now()
+		err := r.step(ctx, step, vars)
+		if r.Log != nil {
+			result := "ok  "
+			if err != nil {
+				result = "FAIL"
+			}
+			fmt.Fprintf(r.Log, "%s %s: %d. %s (%s)\n", result, s.Name, i+1, step.Name, time.Since(start).Round(time.Millisecond))
+		}
+		if err != nil {
+			return fmt.Errorf("step %d %q: %w", i+1, step.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) step(ctx context.Context, step Step, vars map[string]string) error {
+	req, err := r.build(ctx, step.Request, vars)
+	if err != nil {
+		return err
+	}
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	var body any
+	if len(bytes.TrimSpace(data)) > 0 && strings.Contains(res.Header.Get("Content-Type"), "json") {
+		if err := json.Unmarshal(data, &body); err != nil {
+			return fmt.Errorf("%s %s: invalid JSON response: %w", req.Method, req.URL.Path, err)
+		}
+	}
+
+	if err := step.Expect.check(res.StatusCode, res.Header, body, vars); err != nil {
+		return fmt.Errorf("%s %s: %w\nresponse %d: %s", req.Method, req.URL.Path, err, res.StatusCode, truncate(data))
+	}
+	for name, path := range step.Extract {
+		v, ok := lookup(body, path)
+		if !ok {
+			return fmt.Errorf("extract %s: %s is missing from the response", name, path)
+		}
+		vars[name] = text(v)
+	}
+	return nil
+}
+
+func (r *Runner) build(ctx context.Context, spec Request, vars map[string]string) (*http.Request, error) {
+	var body io.Reader
+	if spec.Body != nil {
+		data, err := json.Marshal(interpolateValue(spec.Body, vars))
+		if err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, spec.Method, r.Base+interpolate(spec.Path, vars), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range spec.Headers {
+		req.Header.Set(k, interpolate(v, vars))
+	}
+	return req, nil
+}
+
+// check reports every expectation the response misses.
+func (e Expect) check(status int, header http.Header, body any, vars map[string]string) error {
+	var errs []error
+	switch {
+	case e.Status == 0 && (status < 200 || status > 299):
+		errs = append(errs, fmt.Errorf("status %d, want 2xx", status))
+	case e.Status != 0 && status != e.Status:
+		errs = append(errs, fmt.Errorf("status %d, want %d", status, e.Status))
+	}
+	for _, k := range sortedKeys(e.Headers) {
+		if got, want := header.Get(k), interpolate(e.Headers[k], vars); got != want {
+			errs = append(errs, fmt.Errorf("header %s = %q, want %q", k, got, want))
+		}
+	}
+	for _, path := range sortedKeys(e.Body) {
+		got, ok := lookup(body, path)
+		want := normalize(interpolateValue(e.Body[path], vars))
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s is missing, want %s", path, encode(want)))
+		case !reflect.DeepEqual(got, want):
+			errs = append(errs, fmt.Errorf("%s = %s, want %s", path, encode(got), encode(want)))
+		}
+	}
+	for _, path := range sortedKeys(e.Matches) {
+		pattern := interpolate(e.Matches[path], vars)
+		re, err := regexp.Compile(pattern)
+		got, ok := lookup(body, path)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s is missing, want a match of %s", path, pattern))
+		case !re.MatchString(text(got)):
+			errs = append(errs, fmt.Errorf("%s = %s does not match %s", path, encode(got), pattern))
+		}
+	}
+	for _, path := range sortedKeys(e.Length) {
+		got, ok := lookup(body, path)
+		n := -1
+		switch v := got.(type) {
+		case []any:
+			n = len(v)
+		case map[string]any:
+			n = len(v)
+		}
+		if !ok || n != e.Length[path] {
+			errs = append(errs, fmt.Errorf("%s has length %d, want %d", path, n, e.Length[path]))
+		}
+	}
+	for _, path := range e.Absent {
+		if got, ok := lookup(body, path); ok {
+			errs = append(errs, fmt.Errorf("%s = %s, want it absent", path, encode(got)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var varPattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// interpolate replaces ${name} with the variable; unknown ones are kept.
+func interpolate(s string, vars map[string]string) string {
+	return varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[m[2:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// interpolateValue interpolates every string within v.
+func interpolateValue(v any, vars map[string]string) any {
+	switch v := v.(type) {
+	case string:
+		return interpolate(v, vars)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = interpolateValue(item, vars)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = interpolateValue(item, vars)
+		}
+		return out
+	}
+	return v
+}
+
+// normalize converts a YAML value to what decoding its JSON yields, so
+// that 2 and 2.0 compare equal.
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// lookup returns the value at path, such as $.items[0].id, in a decoded
+// JSON document.
+func lookup(doc any, path string) (any, bool) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, false
+	}
+	cur := doc
+	for rest != "" {
+		var seg string
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			seg, rest = rest[1:end+1], rest[end+1:]
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = obj[seg]; !ok {
+				return nil, false
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false
+			}
+			seg, rest = rest[1:end], rest[end+1:]
+			i, err := strconv.Atoi(seg)
+			arr, ok := cur.([]any)
+			if err != nil || !ok || i < 0 || i >= len(arr) {
+				return nil, false
+			}
+			cur = arr[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// text is the variable form of a JSON value: strings as they are, anything
+// else as JSON.
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return encode(v)
+}
+
+func encode(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func truncate(data []byte) string {
+	const max = 512
+	if len(data) > max {
+		return string(data[:max]) + "..."
+	}
+	return string(data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,53 @@
+package schema_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/schema"
+)
+
+// TestStoredModelsReadEveryVersion decodes testdata/<model>/v<N>.json, one
+// record of the model as stored at each version N, and expects the same
+// item from all of them. A model that gains an upgrade needs a record of
+// its new version.
+func TestStoredModelsReadEveryVersion(t *testing.T) {
+	models := map[string]func() any{
+		"user":       func() any { return new(model.User) },
+		"credential": func() any { return new(model.Credential) },
+		"api_key":    func() any { return new(model.APIKey) },
+		"identity":   func() any { return new(model.Identity) },
+	}
+	for name, newItem := range models {
+		current := schema.Version(newItem())
+		want := newItem()
+		if err := read(name, current, want); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		for v := 1; v < current; v++ {
+			got := newItem()
+			if err := read(name, v, got); err != nil {
+				t.Errorf("%s: %v", name, err)
+			} else if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s version %d = %+v, want %+v", name, v, got, want)
+			}
+		}
+	}
+}
+
+func read(name string, version int, item any) error {
+	path := filepath.Join("testdata", name, fmt.Sprintf("v%d.json", version))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no record of version %d: %w", version, err)
+	}
+	if err := schema.Unmarshal(data, item); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package scim is a subset of a SCIM 2.0 server (RFC 7643 and 7644) that
+// lets an enterprise identity provider such as Okta or Entra ID provision
+// accounts: the Users resource, created with POST, read and listed with a
+// filter, and changed with PATCH, which is how providers deactivate a user
+// (active false). Groups, PUT, DELETE, bulk operations, sorting and ETags
+// are not supported, as the ServiceProviderConfig tells providers.
+//
+// A provisioned user is an account with a password-less credential under
+// its email, so it logs in with the single sign-on of its provider (see
+// auth.OIDCService), which links logins to accounts by verified email. A
+// user created with the email of an account that registered itself adopts
+// that account. Deactivation refuses the account's logins and refreshes
+// through auth.Options.Active (see Active) and deletes its API keys;
+// the access tokens it holds work until they expire.
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// ContentType is the media type of SCIM requests and responses; providers
+// may also send application/json.
+const ContentType = "application/scim+json"
+
+// UsersPath is where the Users resource is served, the base of the
+// location of each user.
+const UsersPath = "/scim/v2/Users"
+
+// Schema URIs of the resources and messages.
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Options configure the SCIM endpoint.
+type Options struct {
+	Token      secret.Secret `yaml:"token"`       // bearer token the identity provider authenticates with; empty disables /scim/v2
+	Tenant     string        `yaml:"tenant"`      // tenant users are provisioned into; "" without tenancy
+	MaxResults int           `yaml:"max_results"` // users per page of a list, and its default count
+}
+
+// Validate checks the page size and that a token is long enough to resist
+// guessing.
+func (o Options) Validate() error {
+	var errs []error
+	if t := o.Token.Reveal(); t != "" && len(t) < 16 {
+		errs = append(errs, errors.New("token must be at least 16 bytes long"))
+	}
+	if o.MaxResults < 1 {
+		errs = append(errs, errors.New("max_results must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// User is the SCIM representation of a provisioned account. Attributes
+// other than these are accepted and ignored.
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"` // true when omitted on creation
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Name is the name of a user.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is an address of a user. Only one is kept, the primary one or else
+// the first.
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Meta is the metadata of a resource.
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// ListResponse is a page of the users matching a filter.
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"` // matching users on every page
+	StartIndex   int      `json:"startIndex"`   // 1-based
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+// PatchRequest is the body of a PATCH.
+type PatchRequest struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// Operation is one change of a PATCH: op is add, replace or remove, in any
+// case. Without a path, value is an object of the attributes to set.
+type Operation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// ServiceProviderConfig tells identity providers which features are
+// supported.
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  Supported              `json:"bulk"`
+	Filter                FilterLimit            `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+}
+
+// Supported is whether a feature is supported.
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+// FilterLimit is the support of filters and the largest page of a list.
+type FilterLimit struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// AuthenticationScheme is a way providers authenticate.
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Error is a SCIM error response, and the error of every request a
+// Service refuses.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`             // the HTTP status, as a string
+	ScimType string   `json:"scimType,omitempty"` // e.g. uniqueness, invalidFilter
+	Detail   string   `json:"detail"`
+}
+
+// NewError returns the error of status, of scimType if not "".
+func NewError(status int, scimType, format string, args ...any) *Error {
+	return &Error{Schemas: []string{SchemaError}, Status: strconv.Itoa(status), ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string { return e.Detail }
+
+// HTTPStatus returns the status of e, 500 if it is malformed.
+func (e *Error) HTTPStatus() int {
+	status, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// ErrNotFound is the error of a user that does not exist, or not in the
+// tenant of the endpoint.
+var ErrNotFound = NewError(http.StatusNotFound, "", "User not found")
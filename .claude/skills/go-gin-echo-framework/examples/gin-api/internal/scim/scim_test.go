@@ -0,0 +1,137 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	users := repository.NewSQLRepository[model.Provisioned](db, dialect, "provisioned", "provisioned account")
+	creds := repository.NewSQLRepository[model.Credential](db, dialect, "credentials", "credential")
+	keys := repository.NewSQLRepository[model.APIKey](db, dialect, "api_keys", "API key")
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	svc := New(users, creds, keys, repository.NewSQLUnitOfWork(db), nil, clk, idgen.NewSequential("acct"), Options{MaxResults: 2})
+	active := Active(users)
+
+	ada, err := svc.Create(ctx, User{UserName: "Ada@example.com", ExternalID: "00u1", Name: &Name{GivenName: "Ada", FamilyName: "Lovelace"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ada.Emails[0].Value != "ada@example.com" || !*ada.Active || ada.Meta.Location != UsersPath+"/"+ada.ID || ada.Name.Formatted != "Ada Lovelace" {
+		t.Errorf("Create = %+v", ada)
+	}
+	if cred, err := creds.GetByID(ctx, "ada@example.com"); err != nil || cred.Subject != ada.ID || cred.PasswordHash != "" {
+		t.Errorf("credential = %+v, %v", cred, err)
+	}
+	if _, err := svc.Create(ctx, User{UserName: "ADA@example.com"}); !matches(err, http.StatusConflict, "uniqueness") {
+		t.Errorf("Create of a taken userName: %v", err)
+	}
+	if _, err := svc.Create(ctx, User{UserName: "grace"}); !matches(err, http.StatusBadRequest, "invalidValue") {
+		t.Errorf("Create without an email: %v", err)
+	}
+
+	// An account that registered itself is adopted
+	creds.Create(ctx, &model.Credential{ID: "grace@example.com", Subject: "registered", PasswordHash: "hash"})
+	grace, err := svc.Create(ctx, User{UserName: "grace", Emails: []Email{{Value: "g@example.org"}, {Value: "Grace@example.com", Primary: true}}})
+	if err != nil || grace.ID != "registered" {
+		t.Fatalf("Create of a registered email = %+v, %v", grace, err)
+	}
+	clk.Advance(time.Second)
+	if _, err := svc.Create(ctx, User{UserName: "alan@example.com", Active: new(bool)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for filter, want := range map[string]int{
+		``:                                        3,
+		`userName eq "ADA@EXAMPLE.COM"`:           1,
+		`externalId eq "00U1"`:                    0,
+		`emails.value eq "grace@example.com"`:     1,
+		`active eq true and userName eq "grace"`:  1,
+		`active eq false`:                         1,
+		`userName eq "has \"quotes\" and spaces"`: 0,
+	} {
+		list, err := svc.List(ctx, filter, 1, -1)
+		if err != nil || list.TotalResults != want {
+			t.Errorf("List(%s) = %+v, %v; want %d results", filter, list, err, want)
+		}
+	}
+	for _, filter := range []string{`userName sw "a"`, `title eq "x"`, `userName eq ada`, `userName eq "a" or active eq true`, `userName eq "a`} {
+		if _, err := svc.List(ctx, filter, 1, -1); !matches(err, http.StatusBadRequest, "invalidFilter") {
+			t.Errorf("List(%s): %v, want invalidFilter", filter, err)
+		}
+	}
+	page, _ := svc.List(ctx, "", 2, 5)
+	if page.TotalResults != 3 || page.ItemsPerPage != 2 || page.StartIndex != 2 || page.Resources[0].ID != grace.ID {
+		t.Errorf("second page = %+v", page)
+	}
+
+	// Deactivation, as Entra ID sends it, deletes the account's API keys
+	keys.Create(ctx, &model.APIKey{ID: "k1", Owner: ada.ID})
+	keys.Create(ctx, &model.APIKey{ID: "k2", Owner: grace.ID})
+	deactivated, err := svc.Patch(ctx, ada.ID, PatchRequest{Operations: []Operation{{Op: "Replace", Value: map[string]any{"active": "False", "displayName": "Countess"}}}})
+	if err != nil || *deactivated.Active || deactivated.DisplayName != "Countess" {
+		t.Fatalf("Patch = %+v, %v", deactivated, err)
+	}
+	if ok, err := active(ctx, ada.ID); ok || err != nil {
+		t.Errorf("Active of a deactivated user = %v, %v", ok, err)
+	}
+	if ok, _ := active(ctx, "someone"); !ok {
+		t.Error("Active of an account that is not provisioned = false")
+	}
+	if all, _ := keys.GetAll(ctx); len(all) != 1 || all[0].ID != "k2" {
+		t.Errorf("API keys after deactivation: %+v", all)
+	}
+
+	// A new email moves the login
+	moved, err := svc.Patch(ctx, ada.ID, PatchRequest{Operations: []Operation{
+		{Op: "replace", Path: "active", Value: true},
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: "Ada@Lovelace.org"},
+		{Op: "remove", Path: "externalId"},
+	}})
+	if err != nil || !*moved.Active || moved.Emails[0].Value != "ada@lovelace.org" || moved.ExternalID != "" {
+		t.Fatalf("Patch = %+v, %v", moved, err)
+	}
+	if _, err := creds.GetByID(ctx, "ada@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("old login: %v", err)
+	}
+	if cred, err := creds.GetByID(ctx, "ada@lovelace.org"); err != nil || cred.Subject != ada.ID {
+		t.Errorf("moved login = %+v, %v", cred, err)
+	}
+
+	if _, err := svc.Patch(ctx, ada.ID, PatchRequest{Operations: []Operation{{Op: "replace", Path: "userName", Value: "grace"}}}); !matches(err, http.StatusConflict, "uniqueness") {
+		t.Errorf("Patch to a taken userName: %v", err)
+	}
+	if _, err := svc.Patch(ctx, ada.ID, PatchRequest{Operations: []Operation{{Op: "move", Path: "active"}}}); !matches(err, http.StatusBadRequest, "invalidSyntax") {
+		t.Errorf("Patch with an unknown op: %v", err)
+	}
+	if _, err := svc.Patch(ctx, "missing", PatchRequest{Operations: []Operation{{Op: "replace", Path: "active", Value: false}}}); err != ErrNotFound {
+		t.Errorf("Patch of a missing user: %v", err)
+	}
+	if _, err := New(users, creds, keys, repository.NewSQLUnitOfWork(db), nil, clk, nil, Options{Tenant: "acme", MaxResults: 2}).Get(ctx, ada.ID); err != ErrNotFound {
+		t.Errorf("Get from another tenant: %v", err)
+	}
+}
+
+// matches reports whether err is a *Error of status and scimType.
+func matches(err error, status int, scimType string) bool {
+	var e *Error
+	return errors.As(err, &e) && e.HTTPStatus() == status && e.ScimType == scimType
+}
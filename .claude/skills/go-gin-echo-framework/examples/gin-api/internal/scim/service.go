@@ -0,0 +1,464 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Service provisions the accounts of an identity provider. Every method
+// works in the tenant of Options.Tenant, whatever the tenant of ctx.
+type Service struct {
+	users         repository.ProvisionedRepository
+	creds         repository.CredentialRepository
+	keys          repository.APIKeyRepository
+	uow           repository.UnitOfWork
+	createAccount auth.AccountCreator
+	clock         clock.Clock
+	opts          Options
+}
+
+// New stores provisioned users in users and their logins in creds, the
+// credentials of the auth service, deleting the keys of deactivated users
+// from keys. createAccount creates the account of a new user, as it does
+// for registrations; nil gives accounts an ID from ids and nothing else.
+func New(users repository.ProvisionedRepository, creds repository.CredentialRepository, keys repository.APIKeyRepository, uow repository.UnitOfWork, createAccount auth.AccountCreator, clk clock.Clock, ids idgen.Generator, opts Options) *Service {
+	if createAccount == nil {
+		ids = idgen.OrDefault(ids)
+		createAccount = func(context.Context, string, string) (string, error) {
+			return ids.NewID(), nil
+		}
+	}
+	return &Service{users: users, creds: creds, keys: keys, uow: uow, createAccount: createAccount, clock: clock.OrSystem(clk), opts: opts}
+}
+
+// Config returns what the service supports.
+func (s *Service) Config() ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas: []string{SchemaServiceProviderConfig},
+		Patch:   Supported{true},
+		Filter:  FilterLimit{Supported: true, MaxResults: s.opts.MaxResults},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "The token of scim.token in the Authorization header",
+		}},
+	}
+}
+
+// Create provisions u, adopting the account that registered with its
+// email if there is one. A userName or email already provisioned is a
+// uniqueness error.
+func (s *Service) Create(ctx context.Context, u User) (*User, error) {
+	ctx = s.scope(ctx)
+	p := model.Provisioned{Active: true, TenantID: tenant.From(ctx)}
+	if u.Active != nil {
+		p.Active = *u.Active
+	}
+	set(&p, u)
+	if err := check(p); err != nil {
+		return nil, err
+	}
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.unique(ctx, p); err != nil {
+			return err
+		}
+		cred, err := s.creds.GetByID(ctx, p.Email)
+		switch {
+		case err == nil && cred.TenantID != p.TenantID:
+			return NewError(http.StatusConflict, "uniqueness", "email %s is registered in another tenant", p.Email)
+		case err == nil:
+			p.ID = cred.Subject
+			if _, err := s.users.GetByID(ctx, p.ID); err == nil {
+				return NewError(http.StatusConflict, "uniqueness", "the account of %s is already provisioned", p.Email)
+			}
+		case errors.Is(err, repository.ErrNotFound):
+			if p.ID, err = s.createAccount(ctx, p.Email, displayName(p)); err != nil {
+				return err
+			}
+			if _, err := s.creds.Create(ctx, &model.Credential{ID: p.Email, Subject: p.ID, TenantID: p.TenantID}); err != nil {
+				return fmt.Errorf("failed to store credential: %w", err)
+			}
+		default:
+			return fmt.Errorf("failed to look up credential: %w", err)
+		}
+		p.CreatedAt = s.clock.Now().UTC().Truncate(time.Millisecond)
+		p.UpdatedAt = p.CreatedAt
+		if _, err := s.users.Create(ctx, &p); err != nil {
+			return fmt.Errorf("failed to store user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resource(p), nil
+}
+
+// Get returns the user id.
+func (s *Service) Get(ctx context.Context, id string) (*User, error) {
+	p, err := s.get(s.scope(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	return resource(*p), nil
+}
+
+// List returns the page of the users matching filter (see ParseFilter),
+// oldest first, from the 1-based startIndex; count is capped at
+// Options.MaxResults, which it defaults to when negative.
+func (s *Service) List(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	ctx = s.scope(ctx)
+	f, err := ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var matched []model.Provisioned
+	err = s.users.Stream(ctx, func(p model.Provisioned) error {
+		if p.TenantID == tenant.From(ctx) && f.Matches(p) {
+			matched = append(matched, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		return a.CreatedAt.Before(b.CreatedAt) || a.CreatedAt.Equal(b.CreatedAt) && a.ID < b.ID
+	})
+	if count < 0 || count > s.opts.MaxResults {
+		count = s.opts.MaxResults
+	}
+	startIndex = max(startIndex, 1)
+	page := matched[min(startIndex-1, len(matched)):]
+	page = page[:min(count, len(page))]
+	list := &ListResponse{Schemas: []string{SchemaListResponse}, TotalResults: len(matched), StartIndex: startIndex, ItemsPerPage: len(page), Resources: []User{}}
+	for _, p := range page {
+		list.Resources = append(list.Resources, *resource(p))
+	}
+	return list, nil
+}
+
+// Patch applies the operations of req to the user id, in one transaction.
+// Changing the email moves the user's login to it; deactivating the user
+// deletes its API keys.
+func (s *Service) Patch(ctx context.Context, id string, req PatchRequest) (*User, error) {
+	ctx = s.scope(ctx)
+	if len(req.Operations) == 0 {
+		return nil, NewError(http.StatusBadRequest, "invalidSyntax", "a PATCH needs Operations")
+	}
+	var patched model.Provisioned
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		old, err := s.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		p := *old
+		for _, op := range req.Operations {
+			if err := apply(&p, op); err != nil {
+				return err
+			}
+		}
+		if err := check(p); err != nil {
+			return err
+		}
+		if !strings.EqualFold(p.UserName, old.UserName) || p.Email != old.Email {
+			if err := s.unique(ctx, p); err != nil {
+				return err
+			}
+		}
+		if p.Email != old.Email {
+			if err := s.moveLogin(ctx, p.ID, old.Email, p.Email); err != nil {
+				return err
+			}
+		}
+		if old.Active && !p.Active {
+			if err := s.deleteKeys(ctx, p.ID); err != nil {
+				return err
+			}
+		}
+		p.UpdatedAt = s.clock.Now().UTC().Truncate(time.Millisecond)
+		if _, err := s.users.Update(ctx, &p); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		patched = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resource(patched), nil
+}
+
+// Active is the auth.Options.Active of the auth service, the users of the
+// Service in users: accounts that are not provisioned may log in,
+// provisioned ones while they are active.
+func Active(users repository.ProvisionedRepository) auth.ActiveCheck {
+	return func(ctx context.Context, subject string) (bool, error) {
+		p, err := users.GetByID(ctx, subject)
+		if errors.Is(err, repository.ErrNotFound) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return p.Active, nil
+	}
+}
+
+// scope returns ctx in the tenant of Options.Tenant.
+func (s *Service) scope(ctx context.Context) context.Context {
+	return tenant.With(ctx, s.opts.Tenant)
+}
+
+// get returns the user id of the tenant of ctx, or ErrNotFound.
+func (s *Service) get(ctx context.Context, id string) (*model.Provisioned, error) {
+	p, err := s.users.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && p.TenantID != tenant.From(ctx) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	return p, nil
+}
+
+// unique returns a uniqueness error if another user of p's tenant has its
+// userName or email.
+func (s *Service) unique(ctx context.Context, p model.Provisioned) error {
+	var taken string
+	err := s.users.Stream(ctx, func(other model.Provisioned) error {
+		switch {
+		case other.ID == p.ID || other.TenantID != p.TenantID:
+		case strings.EqualFold(other.UserName, p.UserName):
+			taken = "userName " + p.UserName
+		case other.Email == p.Email:
+			taken = "email " + p.Email
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to look up users: %w", err)
+	}
+	if taken != "" {
+		return NewError(http.StatusConflict, "uniqueness", "%s is already provisioned", taken)
+	}
+	return nil
+}
+
+// moveLogin moves the credential of account id from one email to another.
+func (s *Service) moveLogin(ctx context.Context, id, from, to string) error {
+	if _, err := s.creds.GetByID(ctx, to); err == nil {
+		return NewError(http.StatusConflict, "uniqueness", "email %s is already registered", to)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("failed to look up credential: %w", err)
+	}
+	cred, err := s.creds.GetByID(ctx, from)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && cred.Subject != id {
+		// The admin deleted the login (see auth.AccountService.Delete)
+		cred = &model.Credential{Subject: id, TenantID: tenant.From(ctx)}
+	} else if err != nil {
+		return fmt.Errorf("failed to look up credential: %w", err)
+	} else if err := s.creds.Delete(ctx, from); err != nil {
+		return fmt.Errorf("failed to move credential: %w", err)
+	}
+	cred.ID = to
+	if _, err := s.creds.Create(ctx, cred); err != nil {
+		return fmt.Errorf("failed to move credential: %w", err)
+	}
+	return nil
+}
+
+// deleteKeys deletes the API keys of account id.
+func (s *Service) deleteKeys(ctx context.Context, id string) error {
+	var owned []string
+	err := s.keys.Stream(ctx, func(k model.APIKey) error {
+		if k.Owner == id {
+			owned = append(owned, k.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list API keys: %w", err)
+	}
+	for _, key := range owned {
+		if err := s.keys.Delete(ctx, key); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to delete API keys: %w", err)
+		}
+	}
+	return nil
+}
+
+// set copies the attributes of u to p, all but active.
+func set(p *model.Provisioned, u User) {
+	p.UserName = strings.TrimSpace(u.UserName)
+	p.ExternalID = u.ExternalID
+	p.DisplayName = u.DisplayName
+	if u.Name != nil {
+		p.GivenName, p.FamilyName = u.Name.GivenName, u.Name.FamilyName
+	}
+	p.Email = primary(u.Emails)
+	if p.Email == "" && strings.Contains(p.UserName, "@") {
+		p.Email = normalizeEmail(p.UserName)
+	}
+}
+
+// primary returns the primary address of emails or else the first.
+func primary(emails []Email) string {
+	for _, e := range emails {
+		if e.Primary {
+			return normalizeEmail(e.Value)
+		}
+	}
+	if len(emails) > 0 {
+		return normalizeEmail(emails[0].Value)
+	}
+	return ""
+}
+
+// check refuses a user without the userName and email it needs.
+func check(p model.Provisioned) error {
+	switch {
+	case p.UserName == "":
+		return NewError(http.StatusBadRequest, "invalidValue", "userName is required")
+	case p.Email == "" || !strings.Contains(p.Email, "@"):
+		return NewError(http.StatusBadRequest, "invalidValue", "an email address is required, in emails or as the userName")
+	}
+	return nil
+}
+
+// apply applies op to p. Attributes that are not kept are ignored, as
+// providers send many of them.
+func apply(p *model.Provisioned, op Operation) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return NewError(http.StatusBadRequest, "invalidSyntax", "op %q is not add, replace or remove", op.Op)
+	}
+	if op.Path == "" {
+		if kind == "remove" {
+			return NewError(http.StatusBadRequest, "noTarget", "remove needs a path")
+		}
+		attrs, ok := op.Value.(map[string]any)
+		if !ok {
+			return NewError(http.StatusBadRequest, "invalidValue", "the value of an op without a path must be an object")
+		}
+		for path, value := range attrs {
+			if err := applyPath(p, kind, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return applyPath(p, kind, op.Path, op.Value)
+}
+
+func applyPath(p *model.Provisioned, kind, path string, value any) error {
+	attr := strings.ToLower(path)
+	if kind == "remove" {
+		value = nil
+	}
+	switch {
+	case attr == "active":
+		if kind == "remove" {
+			return NewError(http.StatusBadRequest, "mutability", "active cannot be removed")
+		}
+		active, err := boolean(value)
+		if err != nil {
+			return err
+		}
+		p.Active = active
+	case attr == "username":
+		p.UserName = strings.TrimSpace(str(value))
+	case attr == "externalid":
+		p.ExternalID = str(value)
+	case attr == "displayname":
+		p.DisplayName = str(value)
+	case attr == "name.givenname":
+		p.GivenName = str(value)
+	case attr == "name.familyname":
+		p.FamilyName = str(value)
+	case attr == "name":
+		name, _ := value.(map[string]any)
+		p.GivenName, p.FamilyName = str(name["givenName"]), str(name["familyName"])
+	case attr == "emails":
+		var emails []Email
+		list, _ := value.([]any)
+		for _, item := range list {
+			e, _ := item.(map[string]any)
+			isPrimary, _ := e["primary"].(bool)
+			emails = append(emails, Email{Value: str(e["value"]), Primary: isPrimary})
+		}
+		p.Email = primary(emails)
+	case strings.HasPrefix(attr, "emails[") && strings.HasSuffix(attr, "].value"):
+		// e.g. emails[type eq "work"].value: the one address kept
+		p.Email = normalizeEmail(str(value))
+	}
+	return nil
+}
+
+// boolean reads a JSON boolean, or the "True" and "False" strings some
+// providers send.
+func boolean(v any) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(strings.ToLower(v)); err == nil {
+			return b, nil
+		}
+	}
+	return false, NewError(http.StatusBadRequest, "invalidValue", "active must be true or false")
+}
+
+// str returns v if it is a string, else "".
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// resource returns the SCIM representation of p.
+func resource(p model.Provisioned) *User {
+	active := p.Active
+	u := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          p.ID,
+		ExternalID:  p.ExternalID,
+		UserName:    p.UserName,
+		DisplayName: p.DisplayName,
+		Emails:      []Email{{Value: p.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        &Meta{ResourceType: "User", Created: p.CreatedAt, LastModified: p.UpdatedAt, Location: UsersPath + "/" + p.ID},
+	}
+	if p.GivenName != "" || p.FamilyName != "" {
+		u.Name = &Name{Formatted: strings.TrimSpace(p.GivenName + " " + p.FamilyName), GivenName: p.GivenName, FamilyName: p.FamilyName}
+	}
+	return u
+}
+
+// displayName is the name of the account created for p.
+func displayName(p model.Provisioned) string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	if name := strings.TrimSpace(p.GivenName + " " + p.FamilyName); name != "" {
+		return name
+	}
+	return p.UserName
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,195 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Elasticsearch keeps the index in an Elasticsearch (or OpenSearch) cluster,
+// spoken to over its REST API. Each document is indexed with its fields as
+// text, and searched with a multi_match over all of them. With tenancy each
+// tenant has an index of its own, <index>.<tenant>.
+type Elasticsearch struct {
+	base   *url.URL
+	index  string
+	client *http.Client
+}
+
+// NewElasticsearch returns the backend keeping documents in index of the
+// cluster at rawURL, whose userinfo, if any, is sent as basic auth. A nil
+// client is http.DefaultClient.
+func NewElasticsearch(rawURL, index string, client *http.Client) (*Elasticsearch, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("search: elasticsearch url: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("search: elasticsearch url: want http or https, got %q", base.Scheme)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Elasticsearch{base: base, index: index, client: client}, nil
+}
+
+// Ping checks that the cluster answers.
+func (e *Elasticsearch) Ping(ctx context.Context) error {
+	return e.do(ctx, http.MethodGet, "/", nil, nil)
+}
+
+func (e *Elasticsearch) Index(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	// One bulk request, refreshed before it returns so that the documents
+	// can be found as soon as the write is acknowledged
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range docs {
+		enc.Encode(map[string]any{"index": map[string]string{"_index": e.indexOf(ctx), "_id": doc.ID}})
+		enc.Encode(doc.Fields)
+	}
+	return e.bulk(ctx, &body)
+}
+
+func (e *Elasticsearch) Remove(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, id := range ids {
+		enc.Encode(map[string]any{"delete": map[string]string{"_index": e.indexOf(ctx), "_id": id}})
+	}
+	return e.bulk(ctx, &body)
+}
+
+// bulk sends a _bulk request and fails if any of its actions failed, other
+// than deleting a document that is not there.
+func (e *Elasticsearch) bulk(ctx context.Context, body io.Reader) error {
+	var res struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := e.do(ctx, http.MethodPost, "/_bulk?refresh=wait_for", body, &res); err != nil {
+		return err
+	}
+	if !res.Errors {
+		return nil
+	}
+	for _, item := range res.Items {
+		for action, r := range item {
+			if r.Status >= 300 && !(action == "delete" && r.Status == http.StatusNotFound) {
+				return fmt.Errorf("search: elasticsearch: %s %s: %d %s", action, r.ID, r.Status, r.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Elasticsearch) Search(ctx context.Context, q Query) (Results, error) {
+	req := map[string]any{
+		"query": map[string]any{
+			"multi_match": map[string]any{"query": q.Text, "fields": []string{"*"}},
+		},
+		"from":             max(q.Offset, 0),
+		"size":             q.limit(),
+		"track_total_hits": true,
+		"highlight": map[string]any{
+			"fields":              map[string]any{"*": map[string]any{}},
+			"encoder":             "html",
+			"number_of_fragments": 0, // the whole field, as Memory does
+		},
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Results{}, err
+	}
+	var res struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID        string              `json:"_id"`
+				Score     float64             `json:"_score"`
+				Highlight map[string][]string `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := e.do(ctx, http.MethodPost, "/"+url.PathEscape(e.indexOf(ctx))+"/_search?ignore_unavailable=true", bytes.NewReader(body), &res); err != nil {
+		return Results{}, err
+	}
+	out := Results{Total: res.Hits.Total.Value, Hits: make([]Hit, 0, len(res.Hits.Hits))}
+	for _, h := range res.Hits.Hits {
+		hit := Hit{ID: h.ID, Score: h.Score}
+		for field, fragments := range h.Highlight {
+			if hit.Highlights == nil {
+				hit.Highlights = map[string]string{}
+			}
+			hit.Highlights[field] = strings.Join(fragments, " … ")
+		}
+		out.Hits = append(out.Hits, hit)
+	}
+	return out, nil
+}
+
+// indexOf returns the index of the tenant of ctx.
+func (e *Elasticsearch) indexOf(ctx context.Context) string {
+	if id := tenant.From(ctx); id != "" {
+		return e.index + "." + id
+	}
+	return e.index
+}
+
+// do sends a request with a JSON (or, to _bulk, NDJSON) body and decodes
+// the response into out unless it is nil. Responses other than 2xx fail.
+func (e *Elasticsearch) do(ctx context.Context, method, path string, body io.Reader, out any) error {
+	p, query, _ := strings.Cut(path, "?")
+	target := e.base.JoinPath(p)
+	target.RawQuery = query
+	target.User = nil
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		if strings.HasPrefix(p, "/_bulk") {
+			req.Header.Set("Content-Type", "application/x-ndjson")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if u := e.base.User; u != nil {
+		password, _ := u.Password()
+		req.SetBasicAuth(u.Username(), password)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("search: elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("search: elasticsearch: %s %s: %s: %s", method, p, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("search: elasticsearch: %s %s: %w", method, p, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+func TestElasticsearch(t *testing.T) {
+	var requests []string
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pw, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+user+":"+pw)
+		bodies = append(bodies, string(body))
+		switch r.URL.Path {
+		case "/_bulk":
+			io.WriteString(w, `{"errors": true, "items": [{"delete": {"_id": "gone", "status": 404}}]}`)
+		case "/users/_search":
+			io.WriteString(w, `{"hits": {"total": {"value": 3}, "hits": [
+				{"_id": "u-1", "_score": 1.5, "highlight": {"name": ["<em>Ada</em> Lovelace"]}},
+				{"_id": "u-2", "_score": 0.5}
+			]}}`)
+		default:
+			http.Error(w, `{"error": "nope"}`, http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	es, err := NewElasticsearch(strings.Replace(srv.URL, "http://", "http://elastic:pw@", 1), "users", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := es.Index(ctx, Document{ID: "u-1", Fields: map[string]string{"name": "Ada Lovelace"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.Remove(ctx, "gone"); err != nil {
+		t.Fatalf("removing a missing document: %v", err)
+	}
+	res, err := es.Search(ctx, Query{Text: "ada", Offset: 2, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Results{Total: 3, Hits: []Hit{
+		{ID: "u-1", Score: 1.5, Highlights: map[string]string{"name": "<em>Ada</em> Lovelace"}},
+		{ID: "u-2", Score: 0.5},
+	}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("got %+v, want %+v", res, want)
+	}
+	if _, err := es.Search(tenant.With(ctx, "acme"), Query{Text: "ada"}); err == nil || !strings.Contains(err.Error(), "/users.acme/_search") {
+		t.Errorf("search of a tenant went to %v, want its own index", err)
+	}
+	if err := es.Ping(ctx); err == nil || !strings.Contains(err.Error(), "400 Bad Request") {
+		t.Errorf("ping of a failing cluster: %v", err)
+	}
+
+	wantRequests := []string{
+		"POST /_bulk?refresh=wait_for elastic:pw",
+		"POST /_bulk?refresh=wait_for elastic:pw",
+		"POST /users/_search?ignore_unavailable=true elastic:pw",
+		"POST /users.acme/_search?ignore_unavailable=true elastic:pw",
+		"GET / elastic:pw",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests %q", requests)
+	}
+	if want := `{"index":{"_id":"u-1","_index":"users"}}` + "\n" + `{"name":"Ada Lovelace"}` + "\n"; bodies[0] != want {
+		t.Errorf("bulk index %s", bodies[0])
+	}
+	var search map[string]any
+	json.Unmarshal([]byte(bodies[2]), &search)
+	if search["from"] != 2.0 || search["size"] != 2.0 {
+		t.Errorf("search %s", bodies[2])
+	}
+}
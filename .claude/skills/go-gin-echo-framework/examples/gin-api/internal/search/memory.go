@@ -0,0 +1,203 @@
+package search
+
+import (
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// BM25 parameters: how quickly repeating a word stops adding to the score,
+// and how much long documents are penalised.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Memory is an inverted index held in process: each word maps to the
+// documents containing it, which are ranked by BM25. Each tenant has an
+// index of its own.
+type Memory struct {
+	mu      sync.RWMutex
+	tenants map[string]*index
+}
+
+type index struct {
+	docs     map[string]Document
+	lengths  map[string]int            // document ID -> words
+	postings map[string]map[string]int // word -> document ID -> occurrences
+	words    int                       // total over lengths
+}
+
+func NewMemory() *Memory {
+	return &Memory{tenants: map[string]*index{}}
+}
+
+// indexOf returns the index of the tenant of ctx, creating it if create is
+// set, or nil. It must be called with m.mu held, for writing to create.
+func (m *Memory) indexOf(ctx context.Context, create bool) *index {
+	id := tenant.From(ctx)
+	if m.tenants[id] == nil && create {
+		m.tenants[id] = &index{docs: map[string]Document{}, lengths: map[string]int{}, postings: map[string]map[string]int{}}
+	}
+	return m.tenants[id]
+}
+
+func (m *Memory) Index(ctx context.Context, docs ...Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.indexOf(ctx, true).add(docs)
+	return nil
+}
+
+func (x *index) add(docs []Document) {
+	for _, doc := range docs {
+		x.remove(doc.ID)
+		x.docs[doc.ID] = doc
+		for _, text := range doc.Fields {
+			for _, t := range tokenize(text) {
+				if x.postings[t.word] == nil {
+					x.postings[t.word] = map[string]int{}
+				}
+				x.postings[t.word][doc.ID]++
+				x.lengths[doc.ID]++
+				x.words++
+			}
+		}
+	}
+}
+
+func (m *Memory) Remove(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if x := m.indexOf(ctx, false); x != nil {
+		for _, id := range ids {
+			x.remove(id)
+		}
+	}
+	return nil
+}
+
+func (x *index) remove(id string) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for _, text := range doc.Fields {
+		for _, t := range tokenize(text) {
+			if postings := x.postings[t.word]; postings != nil {
+				delete(postings, id)
+				if len(postings) == 0 {
+					delete(x.postings, t.word)
+				}
+			}
+		}
+	}
+	x.words -= x.lengths[id]
+	delete(x.lengths, id)
+	delete(x.docs, id)
+}
+
+func (m *Memory) Search(ctx context.Context, q Query) (Results, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	x := m.indexOf(ctx, false)
+	if x == nil {
+		return Results{Hits: []Hit{}}, nil
+	}
+	return x.search(q), nil
+}
+
+func (x *index) search(q Query) Results {
+	words := map[string]bool{}
+	for _, t := range tokenize(q.Text) {
+		words[t.word] = true
+	}
+
+	scores := map[string]float64{}
+	n := float64(len(x.docs))
+	avgLength := float64(x.words) / max(n, 1)
+	for word := range words {
+		postings := x.postings[word]
+		idf := math.Log(1 + (n-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for id, tf := range postings {
+			norm := 1 - bm25B + bm25B*float64(x.lengths[id])/avgLength
+			scores[id] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	res := Results{Total: len(hits)}
+	start := min(max(q.Offset, 0), len(hits))
+	res.Hits = hits[start:min(start+q.limit(), len(hits))]
+	for i := range res.Hits {
+		res.Hits[i].Highlights = highlight(x.docs[res.Hits[i].ID], words)
+	}
+	return res
+}
+
+// token is a word of a text and where it is, in bytes.
+type token struct {
+	word       string // lower case
+	start, end int
+}
+
+// tokenize splits text into runs of letters and digits.
+func tokenize(text string) []token {
+	var out []token
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			out = append(out, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		out = append(out, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return out
+}
+
+// highlight returns the fields of doc containing any of words, escaped as
+// HTML with those words in <em>.
+func highlight(doc Document, words map[string]bool) map[string]string {
+	out := map[string]string{}
+	for field, text := range doc.Fields {
+		var b strings.Builder
+		last, matched := 0, false
+		for _, t := range tokenize(text) {
+			if !words[t.word] {
+				continue
+			}
+			b.WriteString(html.EscapeString(text[last:t.start]))
+			b.WriteString("<em>" + html.EscapeString(text[t.start:t.end]) + "</em>")
+			last, matched = t.end, true
+		}
+		if matched {
+			b.WriteString(html.EscapeString(text[last:]))
+			out[field] = b.String()
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,200 @@
+// Package search finds items by the words in their text fields, best
+// matches first, with the matched words highlighted.
+//
+// A Searcher keeps an index of Documents, one per item. The Memory backend
+// builds an inverted index in process and is rebuilt on startup with
+// Reindex; the Elasticsearch backend keeps the index in a cluster. Wrap a
+// repository with NewIndexingRepository to keep its index in step with
+// committed writes.
+//
+// With tenancy, Index, Remove and Search work on an index of the tenant of
+// their context (see tenant.From), so that counts and pages of hits hold
+// only the tenant's documents.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/secret"
+)
+
+// Backend names in Options.Backend.
+const (
+	BackendMemory        = "memory"
+	BackendElasticsearch = "elasticsearch"
+)
+
+// Options select the backend.
+type Options struct {
+	Backend     string        `yaml:"backend"`      // memory or elasticsearch
+	URL         secret.Secret `yaml:"url"`          // Elasticsearch URL, credentials included, e.g. http://elastic:pw@localhost:9200
+	IndexPrefix string        `yaml:"index_prefix"` // Elasticsearch index of each resource is <index_prefix><plural>, e.g. users
+}
+
+// Validate checks the backend name and that Elasticsearch has a URL.
+func (o Options) Validate() error {
+	switch o.Backend {
+	case BackendMemory:
+		return nil
+	case BackendElasticsearch:
+		if o.URL == "" {
+			return errors.New("url is required by the elasticsearch backend")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown backend %q (want memory or elasticsearch)", o.Backend)
+}
+
+// Document is the searchable text of one item, by field name.
+type Document struct {
+	ID     string
+	Fields map[string]string
+}
+
+// MaxLimit caps the hits of one query.
+const MaxLimit = 100
+
+// Query asks for the documents containing any of the words of Text, the
+// ones matching more, rarer words first.
+type Query struct {
+	Text   string
+	Offset int
+	Limit  int // at most MaxLimit; 0 is 20
+}
+
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return 20
+	}
+	return min(q.Limit, MaxLimit)
+}
+
+// Hit is one matching document.
+type Hit struct {
+	ID    string
+	Score float64
+	// Highlights are the matching fields as HTML: the text escaped, with
+	// the matched words in <em>.
+	Highlights map[string]string
+}
+
+// Results are one page of hits, best first.
+type Results struct {
+	Total int // hits across all pages
+	Hits  []Hit
+}
+
+// Searcher indexes documents and queries them.
+type Searcher interface {
+	// Index adds documents, replacing those with the same ID.
+	Index(ctx context.Context, docs ...Document) error
+	// Remove drops documents; unknown IDs are ignored.
+	Remove(ctx context.Context, ids ...string) error
+	Search(ctx context.Context, q Query) (Results, error)
+}
+
+// Page is one page of matching items as the search endpoints serve it.
+type Page[T any] struct {
+	Total  int        `json:"total"` // matches across all pages
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+	Hits   []Match[T] `json:"hits"` // best first
+}
+
+// Match is a matching item with its relevance and highlights.
+type Match[T any] struct {
+	Item       T                 `json:"item"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"` // field -> its text as HTML, matched words in <em>
+}
+
+// indexingRepository indexes every successful write once its transaction
+// commits. The write stands if the index cannot follow, so that is only
+// logged; Reindex catches up.
+type indexingRepository[T model.Entity] struct {
+	repository.CrudRepository[T]
+	searcher Searcher
+	document func(T) Document
+}
+
+// NewIndexingRepository keeps searcher in step with the writes to next,
+// indexing the document of each created or updated item.
+func NewIndexingRepository[T model.Entity](next repository.CrudRepository[T], searcher Searcher, document func(T) Document) repository.CrudRepository[T] {
+	return &indexingRepository[T]{CrudRepository: next, searcher: searcher, document: document}
+}
+
+func (r *indexingRepository[T]) Create(ctx context.Context, item *T) (*T, error) {
+	created, err := r.CrudRepository.Create(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	r.index(ctx, *created)
+	return created, nil
+}
+
+func (r *indexingRepository[T]) Update(ctx context.Context, item *T) (*T, error) {
+	updated, err := r.CrudRepository.Update(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	r.index(ctx, *updated)
+	return updated, nil
+}
+
+func (r *indexingRepository[T]) Delete(ctx context.Context, id string) error {
+	if err := r.CrudRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	repository.AfterCommit(ctx, func() {
+		if err := r.searcher.Remove(context.WithoutCancel(ctx), id); err != nil {
+			log.Printf("WARNING: search: remove %s: %v", id, err)
+		}
+	})
+	return nil
+}
+
+func (r *indexingRepository[T]) index(ctx context.Context, item T) {
+	doc := r.document(item)
+	repository.AfterCommit(ctx, func() {
+		if err := r.searcher.Index(context.WithoutCancel(ctx), doc); err != nil {
+			log.Printf("WARNING: search: index %s: %v", doc.ID, err)
+		}
+	})
+}
+
+// reindexBatch is how many documents Reindex sends at once.
+const reindexBatch = 500
+
+// Reindex indexes every item of repo, e.g. to fill an in-memory index on
+// startup, and returns how many it indexed.
+func Reindex[T model.Entity](ctx context.Context, repo repository.CrudRepository[T], searcher Searcher, document func(T) Document) (int, error) {
+	var batch []Document
+	n := 0
+	flush := func() error {
+		if err := searcher.Index(ctx, batch...); err != nil {
+			return fmt.Errorf("search: reindex: %w", err)
+		}
+		n += len(batch)
+		batch = slices.Delete(batch, 0, len(batch))
+		return nil
+	}
+	err := repo.Stream(ctx, func(item T) error {
+		if batch = append(batch, document(item)); len(batch) == reindexBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	if len(batch) > 0 {
+		return n, flush()
+	}
+	return n, nil
+}
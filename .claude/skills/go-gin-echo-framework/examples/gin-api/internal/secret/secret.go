@@ -0,0 +1,41 @@
+// Package secret keeps credentials out of logs, error messages, panics and
+// diagnostics. DSNs, signing keys and tokens are held as a Secret from the
+// moment the configuration is read; formatting or marshalling one prints
+// [REDACTED], and only Reveal, called where the credential is used, returns
+// its value.
+package secret
+
+import "encoding/json"
+
+// Redacted is what a non-empty Secret prints as.
+const Redacted = "[REDACTED]"
+
+// Secret is a string that never prints its value. An empty Secret prints as
+// "", so that diagnostics still tell a missing credential from a set one.
+type Secret string
+
+// Reveal returns the value, for the code that connects, signs or compares
+// with it. Never format the result.
+func (s Secret) Reveal() string { return string(s) }
+
+// String implements fmt.Stringer, which fmt's %v, %s and %q and the runtime's
+// panic messages use.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer for %#v.
+func (s Secret) GoString() string { return `secret.Secret("` + s.String() + `")` }
+
+// MarshalJSON writes the redacted form, for JSON logs and config dumps.
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+// UnmarshalText reads the value from the config file, environment variables
+// and flags.
+func (s *Secret) UnmarshalText(text []byte) error {
+	*s = Secret(text)
+	return nil
+}
@@ -0,0 +1,45 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecretNeverPrintsItsValue(t *testing.T) {
+	const value = "postgres://app:hunter2@db/app"
+	s := Secret(value)
+	holder := struct {
+		URL   Secret            `json:"url"`
+		Peers map[string]Secret `json:"peers"`
+	}{s, map[string]Secret{"a": s}}
+
+	js, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{
+		"%v":    fmt.Sprintf("%v", s),
+		"%s":    fmt.Sprintf("%s", s),
+		"%q":    fmt.Sprintf("%q", s),
+		"%+v":   fmt.Sprintf("%+v", holder),
+		"%#v":   fmt.Sprintf("%#v", holder),
+		"error": fmt.Errorf("connect %v: %w", s, errors.New("refused")).Error(),
+		"json":  string(js),
+	} {
+		if strings.Contains(out, "hunter2") || !strings.Contains(out, Redacted) {
+			t.Errorf("%s printed %s", name, out)
+		}
+	}
+	if s.Reveal() != value {
+		t.Fatalf("Reveal = %q", s.Reveal())
+	}
+}
+
+func TestEmptySecretPrintsEmpty(t *testing.T) {
+	if got := fmt.Sprint(Secret("")); got != "" {
+		t.Fatalf("empty secret printed %q", got)
+	}
+}
@@ -0,0 +1,525 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-username/gin-api/internal/audit"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/events"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+	"github.com/your-username/gin-api/internal/trash"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalid is wrapped by hook errors that reject the input; handlers
+// report it as a client error.
+var ErrInvalid = errors.New("invalid")
+
+// ErrForbidden is returned when the caller belongs to another tenant than
+// the one the request is scoped to.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrConflict is returned by Restore when an item with the same ID was
+// created after the delete.
+var ErrConflict = errors.New("conflict")
+
+// ErrQuota is returned when a create or restore would take the request's
+// scope, e.g. its tenant, past its quota; see repository.NewQuotaRepository.
+var ErrQuota = repository.ErrQuota
+
+// CrudService is the business-logic contract shared by every resource.
+type CrudService[T model.Entity] interface {
+	GetAll(ctx context.Context) ([]T, error)
+	// Stream calls fn for every item, in GetAll's order, as it is read; see
+	// repository.CrudRepository.Stream.
+	Stream(ctx context.Context, fn func(item T) error) error
+	GetByID(ctx context.Context, id string) (*T, error)
+	// GetByIDs returns the items with the given IDs, ordered by ID, leaving
+	// out missing ones; see repository.CrudRepository.GetByIDs.
+	GetByIDs(ctx context.Context, ids []string) ([]T, error)
+	// Create, Update and Delete only try the write on a WithDryRun context.
+	Create(ctx context.Context, item *T) (*T, error)
+	Update(ctx context.Context, item *T) (*T, error)
+	Delete(ctx context.Context, id string) error
+	// DeleteMany deletes the items with the given IDs in one transaction:
+	// if one is missing, none is deleted.
+	DeleteMany(ctx context.Context, ids []string) error
+	// Restore undoes the delete of the item with the given ID within the
+	// trash window (see package trash), as a create.
+	Restore(ctx context.Context, id string) (*T, error)
+	// Transfer hands the items account from owns (see model.Owned) to
+	// account to in one transaction, e.g. when a guest's drafts are claimed.
+	// Each is audited and published as an update; no hook runs, as the
+	// item itself is unchanged. Models that are not Owned have none.
+	Transfer(ctx context.Context, from, to string) error
+
+	// LastDelete is when an item was last deleted through this service, or
+	// when the service started. A list is as recent as its newest item or
+	// the last deletion, whichever is later; deletions made by other
+	// instances are not seen.
+	LastDelete() time.Time
+}
+
+// Hooks carry per-resource business logic. Each is optional. Before hooks
+// run before the repository call and After hooks after it, all in the same
+// UnitOfWork as the write, so an After hook can write related records (an
+// audit entry, a counter) atomically with it. Returning an error from any
+// hook rolls the whole operation back.
+//
+// Merge runs first on an Update: it fills in item, the replacement the
+// client sent, from the stored item, so that fields the client left out
+// keep their value. Without it an Update replaces the whole item.
+type Hooks[T any] struct {
+	Merge        func(stored T, item *T)
+	BeforeCreate func(ctx context.Context, item *T) error
+	BeforeUpdate func(ctx context.Context, item *T) error
+	BeforeDelete func(ctx context.Context, id string) error
+	AfterCreate  func(ctx context.Context, item *T) error
+	AfterUpdate  func(ctx context.Context, item *T) error
+	AfterDelete  func(ctx context.Context, id string) error
+}
+
+type crudService[T model.Entity, P model.EntityPtr[T]] struct {
+	repo      repository.CrudRepository[T]
+	uow       repository.UnitOfWork
+	bus       *events.Bus           // nil publishes nothing
+	publisher domain.EventPublisher // nil publishes nothing
+	audit     *audit.Recorder       // nil records nothing
+	trash     *trash.Bin            // nil makes deletes final
+	clock     clock.Clock
+	ids       idgen.Generator
+	name      string // singular resource name, e.g. "user"
+	hooks     Hooks[T]
+
+	lastDelete atomic.Int64 // unix nanos
+}
+
+// NewCrudService builds the service for one resource. Each write runs in a
+// uow transaction together with its hooks and, once that commits, is
+// published to bus and, as a typed domain event (domain.Created and so on),
+// to publisher; a domain.TxPublisher records it in the transaction instead.
+// Each write is also recorded by rec (nil audits nothing), with the item
+// before and after it. Deleted items are kept in bin (nil keeps none) for
+// Restore. Timestamps come from clk (nil is the system clock). Created items
+// that neither the client nor a hook gave an ID get one from ids (nil is
+// idgen.Default).
+func NewCrudService[T model.Entity, P model.EntityPtr[T]](repo repository.CrudRepository[T], uow repository.UnitOfWork, bus *events.Bus, publisher domain.EventPublisher, rec *audit.Recorder, bin *trash.Bin, clk clock.Clock, ids idgen.Generator, name string, hooks Hooks[T]) CrudService[T] {
+	s := &crudService[T, P]{
+		repo:      repo,
+		uow:       uow,
+		bus:       bus,
+		publisher: publisher,
+		audit:     rec,
+		trash:     bin,
+		clock:     clock.OrSystem(clk),
+		ids:       idgen.OrDefault(ids),
+		name:      name,
+		hooks:     hooks,
+	}
+	s.lastDelete.Store(s.clock.Now().UnixNano())
+	return s
+}
+
+// touch stamps items that record their modification time and, if create,
+// those that record their creation time. Millisecond precision is the
+// coarsest of the backends (MongoDB), so an item reads back as it was
+// written.
+func (s *crudService[T, P]) touch(item *T, create bool) {
+	now := s.clock.Now().UTC().Truncate(time.Millisecond)
+	if t, ok := any(item).(model.TimestampedPtr); ok {
+		t.Touch(now)
+	}
+	if c, ok := any(item).(model.CreatedPtr); ok && create {
+		c.SetCreated(now)
+	}
+}
+
+// own stamps items that record their owner with the caller, leaving those
+// created without one unowned whatever the item said.
+func own(ctx context.Context, item any) {
+	if o, ok := item.(model.OwnedPtr); ok {
+		var owner string
+		if caller := auth.CallerFromContext(ctx); caller != nil {
+			owner = caller.Subject
+		}
+		o.SetOwner(owner)
+	}
+}
+
+// authorize blocks access across tenants: on a request scoped to a tenant
+// (see package tenant), an authenticated caller must have been issued its
+// token or API key in that tenant. The repositories then keep the request
+// to the tenant's data.
+func authorize(ctx context.Context) error {
+	id := tenant.From(ctx)
+	if id == "" {
+		return nil
+	}
+	if caller := auth.CallerFromContext(ctx); caller != nil && caller.Tenant != id {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *crudService[T, P]) GetAll(ctx context.Context) ([]T, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all %ss: %w", s.name, err)
+	}
+	return items, nil
+}
+
+func (s *crudService[T, P]) Stream(ctx context.Context, fn func(item T) error) error {
+	if err := authorize(ctx); err != nil {
+		return err
+	}
+	if err := s.repo.Stream(ctx, fn); err != nil {
+		return fmt.Errorf("failed to stream %ss: %w", s.name, err)
+	}
+	return nil
+}
+
+func (s *crudService[T, P]) GetByID(ctx context.Context, id string) (*T, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	item, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound // Translate repository error to service-level error
+		}
+		return nil, fmt.Errorf("failed to get %s by ID: %w", s.name, err)
+	}
+	return item, nil
+}
+
+func (s *crudService[T, P]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %ss by ID: %w", s.name, err)
+	}
+	return items, nil
+}
+
+func (s *crudService[T, P]) Create(ctx context.Context, item *T) (*T, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	var created *T
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if s.hooks.BeforeCreate != nil {
+			if err := s.hooks.BeforeCreate(ctx, item); err != nil {
+				return err
+			}
+		}
+		own(ctx, item)
+		if IsDryRun(ctx) {
+			s.touch(item, true)
+			created = item
+			return errDryRun
+		}
+		if P(item).GetID() == "" {
+			P(item).SetID(s.ids.NewID())
+		}
+		s.touch(item, true)
+
+		var err error
+		created, err = s.repo.Create(ctx, item)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", s.name, err)
+		}
+		if s.hooks.AfterCreate != nil {
+			if err := s.hooks.AfterCreate(ctx, created); err != nil {
+				return err
+			}
+		}
+		if err := s.record(ctx, changes.OpCreated, P(created).GetID(), nil, created); err != nil {
+			return err
+		}
+		return s.publish(ctx, changes.OpCreated, P(created).GetID(), *created, domain.Created[T]{Resource: s.name, Entity: *created, At: s.clock.Now().UTC()})
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *crudService[T, P]) Update(ctx context.Context, item *T) (*T, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	var updated *T
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		// Read, merge and write in one transaction, so that a concurrent
+		// update cannot slip in between
+		stamped, keepsCreated := any(item).(model.CreatedPtr)
+		owned, keepsOwner := any(item).(model.OwnedPtr)
+		before, err := s.prior(ctx, P(item).GetID(), s.hooks.Merge != nil || keepsCreated || keepsOwner)
+		if err != nil {
+			return err
+		}
+		if s.hooks.Merge != nil {
+			s.hooks.Merge(*before, item)
+		}
+		if keepsCreated {
+			// An update never changes when the item was created
+			stamped.SetCreated(any(before).(model.Created).Created())
+		}
+		if keepsOwner {
+			// nor who owns it; see Transfer
+			owned.SetOwner(any(before).(model.Owned).Owner())
+		}
+		if s.hooks.BeforeUpdate != nil {
+			if err := s.hooks.BeforeUpdate(ctx, item); err != nil {
+				return err
+			}
+		}
+		s.touch(item, false)
+		if IsDryRun(ctx) {
+			updated = item
+			return errDryRun
+		}
+		updated, err = s.repo.Update(ctx, item)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to update %s: %w", s.name, err)
+		}
+		if s.hooks.AfterUpdate != nil {
+			if err := s.hooks.AfterUpdate(ctx, updated); err != nil {
+				return err
+			}
+		}
+		if err := s.record(ctx, changes.OpUpdated, P(updated).GetID(), before, updated); err != nil {
+			return err
+		}
+		return s.publish(ctx, changes.OpUpdated, P(updated).GetID(), *updated, domain.Updated[T]{Resource: s.name, Entity: *updated, At: s.clock.Now().UTC()})
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *crudService[T, P]) Delete(ctx context.Context, id string) error {
+	if err := authorize(ctx); err != nil {
+		return err
+	}
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if s.hooks.BeforeDelete != nil {
+			if err := s.hooks.BeforeDelete(ctx, id); err != nil {
+				return err
+			}
+		}
+		before, err := s.prior(ctx, id, s.trash != nil)
+		if err != nil {
+			return err
+		}
+		if IsDryRun(ctx) {
+			return errDryRun
+		}
+		if err := s.repo.Delete(ctx, id); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to delete %s: %w", s.name, err)
+		}
+		if s.trash != nil {
+			if err := s.trash.Put(ctx, s.name, id, *before); err != nil {
+				return err
+			}
+		}
+		if s.hooks.AfterDelete != nil {
+			if err := s.hooks.AfterDelete(ctx, id); err != nil {
+				return err
+			}
+		}
+		if err := s.record(ctx, changes.OpDeleted, id, before, nil); err != nil {
+			return err
+		}
+		return s.publish(ctx, changes.OpDeleted, id, nil, domain.Deleted[T]{Resource: s.name, ID: id, At: s.clock.Now().UTC()})
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.lastDelete.Store(s.clock.Now().UnixNano())
+	return nil
+}
+
+func (s *crudService[T, P]) DeleteMany(ctx context.Context, ids []string) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		for _, id := range ids {
+			if err := s.Delete(ctx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Restore takes the item back out of the trash and creates it again, with
+// a fresh modification time. Its AfterCreate hook runs and it is audited
+// and published as created; BeforeCreate does not run, as the item passed
+// it when first created.
+func (s *crudService[T, P]) Restore(ctx context.Context, id string) (*T, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	var restored *T
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		item := new(T)
+		if err := s.trash.Take(ctx, s.name, id, item); err != nil {
+			if errors.Is(err, trash.ErrNotFound) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to restore %s: %w", s.name, err)
+		}
+		if _, err := s.repo.GetByID(ctx, id); err == nil {
+			return fmt.Errorf("%w: a %s with ID %s was created since the delete", ErrConflict, s.name, id)
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to restore %s: %w", s.name, err)
+		}
+		// A restored item keeps the creation time it was deleted with
+		s.touch(item, false)
+
+		var err error
+		restored, err = s.repo.Create(ctx, item)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", s.name, err)
+		}
+		if s.hooks.AfterCreate != nil {
+			if err := s.hooks.AfterCreate(ctx, restored); err != nil {
+				return err
+			}
+		}
+		if err := s.record(ctx, changes.OpCreated, id, nil, restored); err != nil {
+			return err
+		}
+		return s.publish(ctx, changes.OpCreated, id, *restored, domain.Created[T]{Resource: s.name, Entity: *restored, At: s.clock.Now().UTC()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func (s *crudService[T, P]) Transfer(ctx context.Context, from, to string) error {
+	if _, ok := any(new(T)).(model.OwnedPtr); !ok || from == "" {
+		return nil
+	}
+	if err := authorize(ctx); err != nil {
+		return err
+	}
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		var owned []T
+		err := s.repo.Stream(ctx, func(item T) error {
+			if any(item).(model.Owned).Owner() == from {
+				owned = append(owned, item)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list %ss to transfer: %w", s.name, err)
+		}
+		for _, before := range owned {
+			item := before
+			any(&item).(model.OwnedPtr).SetOwner(to)
+			s.touch(&item, false)
+			updated, err := s.repo.Update(ctx, &item)
+			if err != nil {
+				return fmt.Errorf("failed to transfer %s: %w", s.name, err)
+			}
+			if err := s.record(ctx, changes.OpUpdated, P(updated).GetID(), &before, updated); err != nil {
+				return err
+			}
+			if err := s.publish(ctx, changes.OpUpdated, P(updated).GetID(), *updated, domain.Updated[T]{Resource: s.name, Entity: *updated, At: s.clock.Now().UTC()}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// prior reads the item a write is about to change, for its audit entry, to
+// check a dry run against or, if keep, to move to the trash, or returns nil
+// when none needs it.
+func (s *crudService[T, P]) prior(ctx context.Context, id string, keep bool) (*T, error) {
+	if !keep && s.audit == nil && !IsDryRun(ctx) {
+		return nil, nil
+	}
+	item, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get %s by ID: %w", s.name, err)
+	}
+	return item, nil
+}
+
+// record adds the audit entry of a write; before and after are nil where
+// the item did not exist.
+func (s *crudService[T, P]) record(ctx context.Context, op changes.Op, id string, before, after *T) error {
+	if s.audit == nil {
+		return nil
+	}
+	var b, a any
+	if before != nil {
+		b = *before
+	}
+	if after != nil {
+		a = *after
+	}
+	return s.audit.Record(ctx, s.name, op, id, b, a)
+}
+
+// publish queues the events for when the transaction on ctx commits, so
+// subscribers never see a write that was rolled back. The write stands
+// even if the domain event cannot be published, so that is only logged;
+// a domain.TxPublisher (the outbox) instead records the event right away,
+// in the transaction, and its error rolls the write back.
+func (s *crudService[T, P]) publish(ctx context.Context, op changes.Op, id string, data any, e domain.Event) error {
+	tx, inTx := s.publisher.(domain.TxPublisher)
+	if inTx {
+		if err := tx.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	repository.AfterCommit(ctx, func() {
+		if s.bus != nil {
+			s.bus.Publish(events.Event{Resource: s.name, Op: op, ID: id, Data: data, Tenant: tenant.From(ctx)})
+		}
+		if s.publisher != nil && !inTx {
+			if err := s.publisher.Publish(context.WithoutCancel(ctx), e); err != nil {
+				log.Printf("WARNING: domain event %s %s: %v", e.EventName(), e.AggregateID(), err)
+			}
+		}
+	})
+	return nil
+}
+
+func (s *crudService[T, P]) LastDelete() time.Time {
+	return time.Unix(0, s.lastDelete.Load())
+}
@@ -0,0 +1,515 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/audit"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/changes"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/events"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+	"github.com/your-username/gin-api/internal/trash"
+)
+
+type auditEntry struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+}
+
+func (a auditEntry) GetID() string { return a.ID }
+
+// newSQLStack returns user and audit repositories sharing one SQLite
+// database, and the unit of work spanning both.
+func newSQLStack(t *testing.T) (repository.CrudRepository[model.User], repository.CrudRepository[auditEntry], repository.UnitOfWork) {
+	t.Helper()
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, ddl := range []string{
+		"CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, tenant_id TEXT NOT NULL DEFAULT '', owner_id TEXT NOT NULL DEFAULT '', custom JSONB NOT NULL DEFAULT '{}')",
+		"CREATE TABLE audit (id TEXT PRIMARY KEY, action TEXT NOT NULL)",
+	} {
+		if _, err := db.ExecContext(ctx, ddl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repository.NewSQLRepository[model.User](db, dialect, "users", "user"),
+		repository.NewSQLRepository[auditEntry](db, dialect, "audit", "audit entry"),
+		repository.NewSQLUnitOfWork(db)
+}
+
+// auditCreates records "created <id>" for every user, under a fixed ID when
+// id is set so a second create collides.
+func auditCreates(audit repository.CrudRepository[auditEntry], id string) Hooks[model.User] {
+	return Hooks[model.User]{
+		AfterCreate: func(ctx context.Context, u *model.User) error {
+			entryID := id
+			if entryID == "" {
+				entryID = "audit-" + u.ID
+			}
+			_, err := audit.Create(ctx, &auditEntry{ID: entryID, Action: "created " + u.ID})
+			return err
+		},
+	}
+}
+
+func count[T model.Entity](t *testing.T, repo repository.CrudRepository[T]) int {
+	t.Helper()
+	all, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(all)
+}
+
+func TestCreateCommitsRecordAndAuditTogether(t *testing.T) {
+	users, audit, uow := newSQLStack(t)
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, nil, nil, nil, "user", auditCreates(audit, ""))
+
+	if _, err := svc.Create(context.Background(), &model.User{ID: "u1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, m := count(t, users), count(t, audit); n != 1 || m != 1 {
+		t.Fatalf("users = %d, audit entries = %d; want 1 and 1", n, m)
+	}
+}
+
+func TestCreateRollsBackWhenAuditWriteFails(t *testing.T) {
+	users, audit, uow := newSQLStack(t)
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, nil, nil, nil, "user", auditCreates(audit, "audit-fixed"))
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, &model.User{ID: "u1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	// The second audit entry reuses audit-fixed, so the user insert before it must be undone.
+	if _, err := svc.Create(ctx, &model.User{ID: "u2", Name: "Bob"}); err == nil {
+		t.Fatal("expected the duplicate audit entry to fail the create")
+	}
+	if _, err := users.GetByID(ctx, "u2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("u2 survived the rollback: %v", err)
+	}
+	if n, m := count(t, users), count(t, audit); n != 1 || m != 1 {
+		t.Fatalf("users = %d, audit entries = %d; want 1 and 1", n, m)
+	}
+}
+
+func TestHookErrorRollsBackEveryWrite(t *testing.T) {
+	users, audit, uow := newSQLStack(t)
+	ctx := context.Background()
+	if _, err := users.Create(ctx, &model.User{ID: "u1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	errBlocked := errors.New("blocked")
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, nil, nil, nil, "user", Hooks[model.User]{
+		AfterUpdate: func(ctx context.Context, u *model.User) error {
+			if _, err := audit.Create(ctx, &auditEntry{ID: "a1", Action: "updated " + u.ID}); err != nil {
+				return err
+			}
+			return errBlocked
+		},
+		AfterDelete: func(ctx context.Context, id string) error { return errBlocked },
+	})
+
+	if _, err := svc.Update(ctx, &model.User{ID: "u1", Name: "Changed"}); !errors.Is(err, errBlocked) {
+		t.Fatalf("Update error = %v, want %v", err, errBlocked)
+	}
+	if err := svc.Delete(ctx, "u1"); !errors.Is(err, errBlocked) {
+		t.Fatalf("Delete error = %v, want %v", err, errBlocked)
+	}
+	got, err := users.GetByID(ctx, "u1")
+	if err != nil || got.Name != "Ann" {
+		t.Fatalf("u1 = %+v, %v; want the original record", got, err)
+	}
+	if m := count(t, audit); m != 0 {
+		t.Fatalf("audit entries = %d, want 0", m)
+	}
+}
+
+func TestChangeFeedSkipsRolledBackWrites(t *testing.T) {
+	users, audit, uow := newSQLStack(t)
+	feed := changes.NewFeed(10, nil)
+	defer feed.Close()
+	start := feed.Token()
+	tracked := repository.NewChangeTrackingRepository(users, feed)
+	svc := NewCrudService[model.User](tracked, uow, nil, nil, nil, nil, nil, nil, "user", auditCreates(audit, "audit-fixed"))
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, &model.User{ID: "u1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, &model.User{ID: "u2", Name: "Bob"}); err == nil {
+		t.Fatal("expected the second create to fail")
+	}
+	got, _, err := feed.Since("", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "u1" {
+		t.Fatalf("feed = %+v, want only the committed u1 create", got)
+	}
+}
+
+func TestDomainEventsSkipRolledBackWrites(t *testing.T) {
+	users, audit, uow := newSQLStack(t)
+	publisher := domain.NewInProc()
+	var got []string
+	domain.Subscribe(publisher, func(_ context.Context, e domain.Event) error {
+		got = append(got, e.EventName()+" "+e.AggregateID())
+		return nil
+	})
+	var created []domain.UserCreated
+	domain.Subscribe(publisher, func(_ context.Context, e domain.UserCreated) error {
+		created = append(created, e)
+		return errors.New("subscriber failed") // logged; the write stands
+	})
+	svc := NewCrudService[model.User](users, uow, nil, publisher, nil, nil, nil, nil, "user", auditCreates(audit, "audit-fixed"))
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, &model.User{ID: "u1", Name: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, &model.User{ID: "u2", Name: "Bob"}); err == nil {
+		t.Fatal("expected the second create to fail")
+	}
+	if _, err := svc.Update(ctx, &model.User{ID: "u1", Name: "Ann B."}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[user.created u1 user.updated u1 user.deleted u1]"; fmt.Sprint(got) != want {
+		t.Fatalf("events = %v, want %s", got, want)
+	}
+	if len(created) != 1 || created[0].Entity.Name != "Ann" || created[0].At.IsZero() {
+		t.Fatalf("typed subscriber got %+v, want the u1 create", created)
+	}
+}
+
+func TestNoopUnitOfWorkRunsAfterCommitOnlyOnSuccess(t *testing.T) {
+	uow := repository.NewNoopUnitOfWork()
+	ran := 0
+	for _, fail := range []bool{false, true} {
+		uow.Do(context.Background(), func(ctx context.Context) error {
+			repository.AfterCommit(ctx, func() { ran++ })
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		})
+	}
+	if ran != 1 {
+		t.Fatalf("AfterCommit ran %d times, want 1", ran)
+	}
+}
+
+func TestWritesAreStampedByTheClock(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, nil, clk, idgen.NewSequential("user-"), "user", Hooks[model.User]{})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, &model.User{Name: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := clk.Now()
+	if !created.UpdatedAt.Equal(start) || !created.CreatedAt.Equal(start) || created.ID != "user-1" {
+		t.Fatalf("created %+v, want ID user-1 and created_at and updated_at from %v", created, start)
+	}
+	clk.Advance(time.Hour)
+	// An update without created_at keeps the stored one
+	updated, err := svc.Update(ctx, &model.User{ID: created.ID, Name: "Anne"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := clk.Now(); !updated.UpdatedAt.Equal(want) || !updated.CreatedAt.Equal(start) {
+		t.Fatalf("updated %+v, want created_at %v and updated_at %v", updated, start, want)
+	}
+	clk.Advance(time.Minute)
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := svc.LastDelete(), clk.Now(); !got.Equal(want) {
+		t.Fatalf("LastDelete() = %v, want %v", got, want)
+	}
+}
+
+func TestItemsAreOwnedByTheirCreatorUntilTransferred(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, nil, nil, idgen.NewSequential("user-"), "user", Hooks[model.User]{})
+	guest := auth.WithCaller(context.Background(), &auth.Caller{Subject: "guest-1", Method: auth.MethodJWT, Guest: true})
+
+	for _, name := range []string{"Ann", "Bob"} {
+		if _, err := svc.Create(guest, &model.User{Name: name, OwnerID: "someone-else"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	anonymous, err := svc.Create(context.Background(), &model.User{Name: "Cy", OwnerID: "someone-else"})
+	if err != nil || anonymous.OwnerID != "" {
+		t.Fatalf("anonymous create: %+v, %v; want no owner", anonymous, err)
+	}
+	// An update cannot take the item over
+	updated, err := svc.Update(context.Background(), &model.User{ID: "user-1", Name: "Anne", OwnerID: "user-9"})
+	if err != nil || updated.OwnerID != "guest-1" {
+		t.Fatalf("update: %+v, %v; want owner guest-1", updated, err)
+	}
+
+	account := auth.WithCaller(context.Background(), &auth.Caller{Subject: "account-1", Method: auth.MethodJWT})
+	if err := svc.Transfer(account, "guest-1", "account-1"); err != nil {
+		t.Fatal(err)
+	}
+	all, err := svc.GetAll(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners := map[string]string{}
+	for _, u := range all {
+		owners[u.ID] = u.OwnerID
+	}
+	if want := map[string]string{"user-1": "account-1", "user-2": "account-1", "user-3": ""}; fmt.Sprint(owners) != fmt.Sprint(want) {
+		t.Fatalf("owners = %v, want %v", owners, want)
+	}
+}
+
+func TestUpdateKeepsFieldsItLeavesOut(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc := NewUserService(users, uow, nil, nil, nil, nil, clk, idgen.NewSequential("user-"))
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, &model.User{Name: "Ann", Email: "ann@example.com", Custom: model.Attributes{"team": "ops"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Hour)
+	// A PUT body without custom fields
+	updated, err := svc.Update(ctx, &model.User{ID: created.ID, Name: " Anne ", Email: "ann@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := users.GetByID(ctx, created.ID)
+	for _, u := range []*model.User{updated, stored} {
+		if u.Name != "Anne" || u.Email != "ann@example.com" || u.Custom["team"] != "ops" || !u.UpdatedAt.Equal(clk.Now()) {
+			t.Errorf("after update: %+v", u)
+		}
+	}
+
+	if updated, _ := svc.Update(ctx, &model.User{ID: created.ID, Name: "Anne", Custom: model.Attributes{}}); len(updated.Custom) != 0 || updated.Email != "" {
+		t.Errorf("after clearing: %+v", updated)
+	}
+	if _, err := svc.Update(ctx, &model.User{ID: "missing", Name: "Bob"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a missing user: %v", err)
+	}
+}
+
+func TestWritesAreAuditedWithTheItemBefore(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	trail := audit.NewMemory(10)
+	svc := NewCrudService[model.User](users, uow, nil, nil, audit.NewRecorder(nil, nil, trail), nil, nil, idgen.NewSequential("user-"), "user", Hooks[model.User]{})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, &model.User{Name: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created.Name = "Anne"
+	if _, err := svc.Update(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleting twice: %v", err)
+	}
+
+	entries, err := trail.List(ctx, audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		var before, after model.User
+		json.Unmarshal(e.Before, &before)
+		json.Unmarshal(e.After, &after)
+		got = append(got, fmt.Sprintf("%s %s: %q -> %q", e.Op, e.SubjectID, before.Name, after.Name))
+	}
+	want := []string{`deleted user-1: "Anne" -> ""`, `updated user-1: "Ann" -> "Anne"`, `created user-1: "" -> "Ann"`}
+	if !slices.Equal(got, want) {
+		t.Fatalf("audit trail\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDryRunChecksButStoresNothing(t *testing.T) {
+	users, audits, uow := newSQLStack(t)
+	trail := audit.NewMemory(10)
+	svc := NewCrudService[model.User](users, uow, nil, nil, audit.NewRecorder(nil, nil, trail), nil, nil, idgen.NewSequential("user-"), "user", auditCreates(audits, ""))
+	ctx := context.Background()
+	existing, err := svc.Create(ctx, &model.User{Name: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dry := WithDryRun(ctx)
+
+	created, err := svc.Create(dry, &model.User{Name: "Bob"})
+	if err != nil || created.Name != "Bob" || created.ID != "" || created.UpdatedAt.IsZero() {
+		t.Fatalf("dry create: %+v, %v", created, err)
+	}
+	updated, err := svc.Update(dry, &model.User{ID: existing.ID, Name: "Anne"})
+	if err != nil || updated.Name != "Anne" {
+		t.Fatalf("dry update: %+v, %v", updated, err)
+	}
+	if err := svc.Delete(dry, existing.ID); err != nil {
+		t.Fatalf("dry delete: %v", err)
+	}
+	if _, err := svc.Update(dry, &model.User{ID: "missing", Name: "X"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("dry update of a missing user: %v", err)
+	}
+	if err := svc.Delete(dry, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("dry delete of a missing user: %v", err)
+	}
+
+	stored, err := svc.GetByID(ctx, existing.ID)
+	if err != nil || stored.Name != "Ann" {
+		t.Fatalf("after dry runs: %+v, %v", stored, err)
+	}
+	if n := count(t, users); n != 1 {
+		t.Fatalf("%d users, want only the one really created", n)
+	}
+	if n := count(t, audits); n != 1 {
+		t.Fatalf("%d hook audit rows, want only the real create's", n)
+	}
+	if entries, _ := trail.List(ctx, audit.Query{}); len(entries) != 1 {
+		t.Fatalf("%d audit entries, want only the real create's", len(entries))
+	}
+}
+
+func TestDeleteManyDeletesAllOrNone(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, nil, nil, idgen.NewSequential("user-"), "user", Hooks[model.User]{})
+	ctx := context.Background()
+	for _, name := range []string{"Ann", "Bob", "Cy"} {
+		if _, err := svc.Create(ctx, &model.User{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := svc.DeleteMany(ctx, []string{"user-1", "missing", "user-2"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleting with a missing ID: %v", err)
+	}
+	if n := count(t, users); n != 3 {
+		t.Fatalf("%d users, want all 3 after the failed delete", n)
+	}
+	if err := svc.DeleteMany(ctx, []string{"user-1", "user-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := users.GetAll(ctx); len(left) != 1 || left[0].ID != "user-3" {
+		t.Fatalf("left %+v, want only user-3", left)
+	}
+}
+
+func TestRestoreUndoesADeleteWithinTheWindow(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	clk := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	bin := trash.NewBin(trash.NewMemory(), clk, time.Minute)
+	svc := NewCrudService[model.User](users, uow, nil, nil, nil, bin, clk, idgen.NewSequential("user-"), "user", Hooks[model.User]{})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, &model.User{Name: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(30 * time.Second)
+	restored, err := svc.Restore(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != "Ann" || !restored.UpdatedAt.Equal(clk.Now()) {
+		t.Errorf("restored %+v, want Ann updated now", restored)
+	}
+	if _, err := svc.GetByID(ctx, created.ID); err != nil {
+		t.Errorf("GetByID after Restore: %v", err)
+	}
+	if _, err := svc.Restore(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restored twice: %v", err)
+	}
+
+	// An item created again under the ID is not overwritten
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, &model.User{ID: created.ID, Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Restore(ctx, created.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("restored over a new item: %v", err)
+	}
+	if u, err := svc.GetByID(ctx, created.ID); err != nil || u.Name != "Bob" {
+		t.Errorf("item after a conflicting Restore: %+v, %v", u, err)
+	}
+
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Minute)
+	if _, err := svc.Restore(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restored after the window: %v", err)
+	}
+}
+
+func TestCallersCannotCrossTenants(t *testing.T) {
+	users, _, uow := newSQLStack(t)
+	bus := events.NewBus(nil)
+	svc := NewCrudService[model.User](repository.NewColumnTenantRepository(users), uow, bus, nil, nil, nil, nil, idgen.NewSequential("user-"), "user", Hooks[model.User]{})
+	acme := tenant.With(context.Background(), "acme")
+	ann := auth.WithCaller(acme, &auth.Caller{Subject: "ann", Method: auth.MethodJWT, Tenant: "acme"})
+	sub := bus.Subscribe(events.Filter{Tenant: "acme"}, 1)
+	defer sub.Close()
+	created, err := svc.Create(ann, &model.User{Name: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-sub.C; e.Tenant != "acme" || e.ID != created.ID {
+		t.Errorf("event %+v, want the create in acme", e)
+	}
+
+	// A caller of another tenant, or of none, is refused even if the
+	// request is scoped to acme
+	for _, tenantID := range []string{"globex", ""} {
+		bob := auth.WithCaller(acme, &auth.Caller{Subject: "bob", Method: auth.MethodJWT, Tenant: tenantID})
+		if _, err := svc.GetAll(bob); !errors.Is(err, ErrForbidden) {
+			t.Errorf("GetAll by a caller of %q: %v", tenantID, err)
+		}
+		if err := svc.Delete(bob, created.ID); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Delete by a caller of %q: %v", tenantID, err)
+		}
+	}
+	// and a caller of globex in its own tenant does not see acme's users
+	globex := tenant.With(context.Background(), "globex")
+	bob := auth.WithCaller(globex, &auth.Caller{Subject: "bob", Method: auth.MethodJWT, Tenant: "globex"})
+	if _, err := svc.GetByID(bob, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID of acme's user from globex: %v", err)
+	}
+	if all, err := svc.GetAll(bob); err != nil || len(all) != 0 {
+		t.Errorf("globex lists %+v (%v), want none", all, err)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/your-username/gin-api/internal/custom"
+	"github.com/your-username/gin-api/internal/model"
+)
+
+// Mapped presents svc as a CrudService of another representation of its
+// items, converting with to and from. Versions of the HTTP API that change
+// a representation serve it through Mapped, so that every version shares
+// one service, its hooks and its storage.
+func Mapped[T, U model.Entity](svc CrudService[T], to func(T) U, from func(U) T) CrudService[U] {
+	return &mappedService[T, U]{next: svc, to: to, from: from}
+}
+
+type mappedService[T, U model.Entity] struct {
+	next CrudService[T]
+	to   func(T) U
+	from func(U) T
+}
+
+func (s *mappedService[T, U]) GetAll(ctx context.Context) ([]U, error) {
+	items, err := s.next.GetAll(ctx)
+	return s.all(items), err
+}
+
+func (s *mappedService[T, U]) Stream(ctx context.Context, fn func(item U) error) error {
+	return s.next.Stream(ctx, func(item T) error { return fn(s.to(item)) })
+}
+
+func (s *mappedService[T, U]) GetByID(ctx context.Context, id string) (*U, error) {
+	return s.one(s.next.GetByID(ctx, id))
+}
+
+func (s *mappedService[T, U]) GetByIDs(ctx context.Context, ids []string) ([]U, error) {
+	items, err := s.next.GetByIDs(ctx, ids)
+	return s.all(items), err
+}
+
+func (s *mappedService[T, U]) Create(ctx context.Context, item *U) (*U, error) {
+	t := s.from(*item)
+	return s.one(s.next.Create(ctx, &t))
+}
+
+func (s *mappedService[T, U]) Update(ctx context.Context, item *U) (*U, error) {
+	t := s.from(*item)
+	return s.one(s.next.Update(ctx, &t))
+}
+
+func (s *mappedService[T, U]) Delete(ctx context.Context, id string) error {
+	return s.next.Delete(ctx, id)
+}
+
+func (s *mappedService[T, U]) DeleteMany(ctx context.Context, ids []string) error {
+	return s.next.DeleteMany(ctx, ids)
+}
+
+func (s *mappedService[T, U]) Restore(ctx context.Context, id string) (*U, error) {
+	return s.one(s.next.Restore(ctx, id))
+}
+
+func (s *mappedService[T, U]) Transfer(ctx context.Context, from, to string) error {
+	return s.next.Transfer(ctx, from, to)
+}
+
+func (s *mappedService[T, U]) LastDelete() time.Time { return s.next.LastDelete() }
+
+// CustomFields are those of svc, none if it is not CustomFielded.
+func (s *mappedService[T, U]) CustomFields(ctx context.Context) custom.Schema {
+	if c, ok := s.next.(CustomFielded); ok {
+		return c.CustomFields(ctx)
+	}
+	return nil
+}
+
+func (s *mappedService[T, U]) one(item *T, err error) (*U, error) {
+	if err != nil {
+		return nil, err
+	}
+	u := s.to(*item)
+	return &u, nil
+}
+
+func (s *mappedService[T, U]) all(items []T) []U {
+	if items == nil {
+		return nil
+	}
+	out := make([]U, len(items))
+	for i, item := range items {
+		out[i] = s.to(item)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// Usage is how many items of a collection the scope of a request, e.g.
+// its tenant, holds, and the most it may.
+type Usage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit,omitempty"` // absent is unlimited
+}
+
+// Meter reports the Usage of one collection.
+type Meter struct {
+	Name  string // plural, e.g. users
+	usage func(ctx context.Context) (Usage, bool, error)
+}
+
+// Metered meters the collection of repo, held to its quota by
+// repository.NewQuotaRepository.
+func Metered[T model.Entity](name string, repo repository.QuotaRepository[T]) Meter {
+	return Meter{Name: name, usage: func(ctx context.Context) (Usage, bool, error) {
+		n, q, err := repo.Usage(ctx)
+		if err != nil || q.Scope == "" {
+			return Usage{}, false, err
+		}
+		return Usage{Used: n, Limit: q.Max}, true, nil
+	}}
+}
+
+// Measure returns the usage of the collections of meters by the scope of
+// ctx, by name, leaving out those that meter no scope for it.
+func Measure(ctx context.Context, meters []Meter) (map[string]Usage, error) {
+	out := map[string]Usage{}
+	for _, m := range meters {
+		u, ok, err := m.usage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out[m.Name] = u
+		}
+	}
+	return out, nil
+}
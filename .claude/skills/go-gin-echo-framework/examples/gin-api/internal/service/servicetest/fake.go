@@ -0,0 +1,243 @@
+// Package servicetest provides a fake CrudService, e.g. a service.UserService,
+// for the tests of handlers and other consumers, so they need neither a
+// repository nor the rest of the service stack. Its failures are injected
+// through Fake.Faults; see package fault.
+package servicetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/fault"
+	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/service"
+)
+
+// Fake is an in-memory CrudService. It keeps the contract handlers rely on:
+// service.ErrNotFound for missing items, lists ordered by ID, dry runs that
+// store nothing, all-or-none DeleteMany, and Restore of deleted items with
+// service.ErrConflict once the ID is taken again. It runs no hooks, audits
+// nothing and publishes no events. It is safe for concurrent use.
+type Fake[T model.Entity, P model.EntityPtr[T]] struct {
+	Faults fault.Injector
+
+	mu         sync.Mutex
+	clock      clock.Clock
+	items      map[string]T
+	deleted    map[string]T
+	lastDelete time.Time
+	ids        int
+}
+
+var _ service.UserService = (*Fake[model.User, *model.User])(nil)
+
+// NewFake returns an empty fake that stamps writes by clk (nil is the
+// system clock) and gives created items without one the IDs "1", "2" and
+// so on.
+func NewFake[T model.Entity, P model.EntityPtr[T]](clk clock.Clock) *Fake[T, P] {
+	clk = clock.OrSystem(clk)
+	return &Fake[T, P]{clock: clk, items: map[string]T{}, deleted: map[string]T{}, lastDelete: clk.Now()}
+}
+
+// Seed stores items as they are, bypassing Faults, e.g. to set up a test.
+func (f *Fake[T, P]) Seed(items ...T) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		f.items[item.GetID()] = item
+	}
+}
+
+func (f *Fake[T, P]) GetAll(ctx context.Context) ([]T, error) {
+	if err := f.Faults.Check("GetAll"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.all(), nil
+}
+
+func (f *Fake[T, P]) all() []T {
+	all := []T{}
+	for _, item := range f.items {
+		all = append(all, item)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].GetID() < all[j].GetID() })
+	return all
+}
+
+func (f *Fake[T, P]) Stream(ctx context.Context, fn func(item T) error) error {
+	if err := f.Faults.Check("Stream"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	all := f.all()
+	f.mu.Unlock()
+	for _, item := range all {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fake[T, P]) GetByID(ctx context.Context, id string) (*T, error) {
+	if err := f.Faults.Check("GetByID"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, ok := f.items[id]
+	if !ok {
+		return nil, service.ErrNotFound
+	}
+	return &item, nil
+}
+
+func (f *Fake[T, P]) GetByIDs(ctx context.Context, ids []string) ([]T, error) {
+	if err := f.Faults.Check("GetByIDs"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	found := []T{}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	for _, id := range slices.Compact(ids) {
+		if item, ok := f.items[id]; ok {
+			found = append(found, item)
+		}
+	}
+	return found, nil
+}
+
+func (f *Fake[T, P]) Create(ctx context.Context, item *T) (*T, error) {
+	if err := f.Faults.Check("Create"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.touch(item)
+	if service.IsDryRun(ctx) {
+		return item, nil
+	}
+	if P(item).GetID() == "" {
+		f.ids++
+		P(item).SetID(strconv.Itoa(f.ids))
+	}
+	id := P(item).GetID()
+	if _, exists := f.items[id]; exists {
+		return nil, fmt.Errorf("failed to create: ID %s already exists", id)
+	}
+	f.items[id] = *item
+	created := *item
+	return &created, nil
+}
+
+func (f *Fake[T, P]) Update(ctx context.Context, item *T) (*T, error) {
+	if err := f.Faults.Check("Update"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := P(item).GetID()
+	if _, ok := f.items[id]; !ok {
+		return nil, service.ErrNotFound
+	}
+	f.touch(item)
+	if !service.IsDryRun(ctx) {
+		f.items[id] = *item
+	}
+	updated := *item
+	return &updated, nil
+}
+
+func (f *Fake[T, P]) Delete(ctx context.Context, id string) error {
+	if err := f.Faults.Check("Delete"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.delete(ctx, []string{id})
+}
+
+func (f *Fake[T, P]) DeleteMany(ctx context.Context, ids []string) error {
+	if err := f.Faults.Check("DeleteMany"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.delete(ctx, ids)
+}
+
+// delete deletes every item of ids, or none if one is missing.
+func (f *Fake[T, P]) delete(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		if _, ok := f.items[id]; !ok {
+			return service.ErrNotFound
+		}
+	}
+	if service.IsDryRun(ctx) {
+		return nil
+	}
+	for _, id := range ids {
+		f.deleted[id] = f.items[id]
+		delete(f.items, id)
+	}
+	f.lastDelete = f.clock.Now()
+	return nil
+}
+
+// Restore restores any deleted item; the fake has no trash window.
+func (f *Fake[T, P]) Restore(ctx context.Context, id string) (*T, error) {
+	if err := f.Faults.Check("Restore"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, ok := f.deleted[id]
+	if !ok {
+		return nil, service.ErrNotFound
+	}
+	if _, exists := f.items[id]; exists {
+		return nil, fmt.Errorf("%w: an item with ID %s was created since the delete", service.ErrConflict, id)
+	}
+	f.touch(&item)
+	f.items[id] = item
+	delete(f.deleted, id)
+	return &item, nil
+}
+
+func (f *Fake[T, P]) Transfer(ctx context.Context, from, to string) error {
+	if err := f.Faults.Check("Transfer"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, item := range f.items {
+		if o, ok := any(&item).(model.OwnedPtr); ok && from != "" && o.Owner() == from {
+			o.SetOwner(to)
+			f.touch(&item)
+			f.items[id] = item
+		}
+	}
+	return nil
+}
+
+func (f *Fake[T, P]) LastDelete() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastDelete
+}
+
+func (f *Fake[T, P]) touch(item *T) {
+	if t, ok := any(item).(model.TimestampedPtr); ok {
+		t.Touch(f.clock.Now().UTC().Truncate(time.Millisecond))
+	}
+}
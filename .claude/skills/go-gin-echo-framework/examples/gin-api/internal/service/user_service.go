@@ -2,16 +2,45 @@ package service
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/your-username/gin-api/internal/audit"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/events"
+	"github.com/your-username/gin-api/internal/idgen"
 	"github.com/your-username/gin-api/internal/model"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/trash"
 )
 
-var ErrNotFound = errors.New("not found")
+type UserService = CrudService[model.User]
+
+func NewUserService(userRepo repository.UserRepository, uow repository.UnitOfWork, bus *events.Bus, publisher domain.EventPublisher, rec *audit.Recorder, bin *trash.Bin, clk clock.Clock, ids idgen.Generator) UserService {
+	return NewCrudService[model.User](userRepo, uow, bus, publisher, rec, bin, clk, ids, "user", Hooks[model.User]{
+		Merge:        mergeUser,
+		BeforeCreate: normalizeUser,
+		BeforeUpdate: normalizeUser,
+	})
+}
+
+// mergeUser keeps the custom fields of a user that an update leaves out;
+// an empty custom object clears them. An empty email clears it, so the email
+// always comes from the update: the handlers decode a PUT onto the stored
+// user, so that one leaving the email out keeps it.
+func mergeUser(stored model.User, u *model.User) {
+	if u.Custom == nil {
+		u.Custom = stored.Custom
+	}
+}
 
-type UserService interface {
-	GetAllUsers(ctx context.Context) ([]model.User, error)
-	GetUserByID(ctx context.Context, id string) (*model.User, error)
-	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
-	UpdateUser(ctx context.Context, user *model.User) (*model.User, error)
-	DeleteUser(ctx context.Context, id string) error
+// normalizeUser keeps emails comparable regardless of how clients typed them.
+func normalizeUser(_ context.Context, u *model.User) error {
+	u.Name = strings.TrimSpace(u.Name)
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+	if u.Name == "" {
+		return fmt.Errorf("%w: name must not be blank", ErrInvalid)
+	}
+	return nil
 }
@@ -0,0 +1,31 @@
+package tenant
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware scopes every request to the tenant r resolves, answering 400
+// when it names none or a malformed one, 403 when the caller belongs to
+// another tenant and 404 when the tenant is not served. It runs after
+// authentication so r can see the caller.
+func Middleware(r *Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := r.Resolve(c.Request)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, ErrForbidden):
+				status = http.StatusForbidden
+			case errors.Is(err, ErrUnknown):
+				status = http.StatusNotFound
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request = c.Request.WithContext(With(c.Request.Context(), id))
+		c.Next()
+	}
+}
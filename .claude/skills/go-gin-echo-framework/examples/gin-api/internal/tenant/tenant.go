@@ -0,0 +1,205 @@
+// Package tenant serves several customers (tenants) from one deployment,
+// each seeing only its own data. Middleware resolves the tenant of every
+// request, from the caller's token, a header or the subdomain, and stores it
+// in the request context, where the tenant-scoping repositories (see
+// repository.NewColumnTenantRepository and NewSchemaTenantRepository) and
+// the service layer read it.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Sources of the tenant in Options.Sources.
+const (
+	SourceClaim     = "claim"     // the tenant the caller's token or API key was issued in
+	SourceHeader    = "header"    // the Options.Header request header
+	SourceSubdomain = "subdomain" // the label of the host name in front of Options.Domain
+)
+
+// Isolation modes in Options.Isolation.
+const (
+	IsolationColumn = "column" // shared tables; every row records its tenant
+	IsolationSchema = "schema" // a schema (SQL), collection prefix (MongoDB) or map (in-memory) per tenant
+)
+
+// Options turn tenancy on and say how tenants are told apart.
+type Options struct {
+	Enabled   bool     `yaml:"enabled"`   // false serves every request from the same, untenanted data
+	Sources   []string `yaml:"sources"`   // where the tenant is read from, first found wins: claim, header, subdomain
+	Header    string   `yaml:"header"`    // request header naming the tenant, for the header source
+	Domain    string   `yaml:"domain"`    // base domain whose subdomains name tenants, e.g. example.com, for the subdomain source
+	Isolation string   `yaml:"isolation"` // column or schema
+	Tenants   []string `yaml:"tenants"`   // the tenants served; empty serves any, with column isolation only
+	Audit     bool     `yaml:"audit"`     // check every result against the tenant of its request (see repository.NewTenantAuditRepository)
+}
+
+// Validate checks the sources, the isolation mode and the tenant IDs.
+func (o Options) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if !slices.Contains(o.Sources, SourceHeader) && !slices.Contains(o.Sources, SourceSubdomain) {
+		// Logins and registrations have no caller to take a claim from
+		return errors.New("sources must include header or subdomain")
+	}
+	for _, s := range o.Sources {
+		switch s {
+		case SourceClaim:
+		case SourceHeader:
+			if o.Header == "" {
+				return errors.New("header is required by the header source")
+			}
+		case SourceSubdomain:
+			if o.Domain == "" {
+				return errors.New("domain is required by the subdomain source")
+			}
+		default:
+			return fmt.Errorf("unknown source %q (want claim, header or subdomain)", s)
+		}
+	}
+	switch o.Isolation {
+	case IsolationColumn:
+	case IsolationSchema:
+		// Each tenant's tables are created on startup, so the set is fixed
+		if len(o.Tenants) == 0 {
+			return errors.New("tenants must list the tenants served with schema isolation")
+		}
+	default:
+		return fmt.Errorf("unknown isolation %q (want column or schema)", o.Isolation)
+	}
+	for _, id := range o.Tenants {
+		if err := Check(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	// ErrMissing is returned when a request names no tenant, and by the
+	// tenant-scoping repositories for a context without one.
+	ErrMissing = errors.New("tenant is required")
+	// ErrUnknown is returned for a tenant that is not served.
+	ErrUnknown = errors.New("unknown tenant")
+	// ErrForbidden is returned for a request naming another tenant than its
+	// caller was authenticated in.
+	ErrForbidden = errors.New("caller belongs to another tenant")
+	// ErrIsolation is returned by the tenant audit for a call that would
+	// have returned another tenant's data.
+	ErrIsolation = errors.New("tenant isolation violated")
+)
+
+// validID keeps tenant IDs usable as DNS labels and, through Schema, as SQL
+// identifiers.
+var validID = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+// Check reports whether id is a well-formed tenant ID: 1 to 32 lower-case
+// letters, digits and inner hyphens.
+func Check(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid tenant %q: want 1 to 32 lower-case letters, digits and inner hyphens", id)
+	}
+	return nil
+}
+
+// Schema is the SQL schema (and MongoDB collection prefix) holding the data
+// of tenant id with schema isolation, e.g. "tenant_acme_corp".
+func Schema(id string) string {
+	return "tenant_" + strings.ReplaceAll(id, "-", "_")
+}
+
+type contextKey struct{}
+
+// With returns a copy of ctx scoped to tenant id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the tenant stored by With, or "" if ctx has none.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Resolver finds the tenant of a request by the sources of its Options.
+type Resolver struct {
+	opts    Options
+	caller  func(ctx context.Context) (string, bool)
+	tenants map[string]bool // nil serves any
+}
+
+// NewResolver resolves tenants by opts. caller returns the tenant the
+// request's caller was authenticated in and true, or false for an anonymous
+// request; nil treats every request as anonymous.
+func NewResolver(opts Options, caller func(ctx context.Context) (string, bool)) *Resolver {
+	r := &Resolver{opts: opts, caller: caller}
+	if len(opts.Tenants) > 0 {
+		r.tenants = map[string]bool{}
+		for _, id := range opts.Tenants {
+			r.tenants[id] = true
+		}
+	}
+	return r
+}
+
+// Resolve returns the tenant of req from the first source naming one. It
+// fails with ErrMissing if none does, ErrUnknown if the tenant is not
+// served, ErrForbidden if the caller was authenticated in another tenant,
+// or an error describing a malformed tenant. A caller is thus only ever
+// served in its own tenant, whatever the sources.
+func (r *Resolver) Resolve(req *http.Request) (string, error) {
+	var claimed string
+	authenticated := false
+	if r.caller != nil {
+		claimed, authenticated = r.caller(req.Context())
+	}
+	var id string
+	for _, source := range r.opts.Sources {
+		switch source {
+		case SourceClaim:
+			id = claimed
+		case SourceHeader:
+			id = strings.TrimSpace(req.Header.Get(r.opts.Header))
+		case SourceSubdomain:
+			id = r.subdomain(req.Host)
+		}
+		if id != "" {
+			break
+		}
+	}
+	if id == "" {
+		return "", ErrMissing
+	}
+	if err := Check(id); err != nil {
+		return "", err
+	}
+	if r.tenants != nil && !r.tenants[id] {
+		return "", fmt.Errorf("%w %q", ErrUnknown, id)
+	}
+	if authenticated && id != claimed {
+		return "", ErrForbidden
+	}
+	return id, nil
+}
+
+// subdomain returns the single label in front of the configured domain in
+// host, e.g. "acme" for "acme.example.com:8080", or "".
+func (r *Resolver) subdomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	label, ok := strings.CutSuffix(host, "."+strings.ToLower(r.opts.Domain))
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
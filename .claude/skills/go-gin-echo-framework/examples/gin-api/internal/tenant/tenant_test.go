@@ -0,0 +1,114 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	opts := Options{
+		Enabled:   true,
+		Sources:   []string{SourceClaim, SourceHeader, SourceSubdomain},
+		Header:    "X-Tenant-ID",
+		Domain:    "example.com",
+		Isolation: IsolationColumn,
+	}
+	type claimKey struct{}
+	r := NewResolver(opts, func(ctx context.Context) (string, bool) {
+		id, ok := ctx.Value(claimKey{}).(string)
+		return id, ok
+	})
+	for _, tc := range []struct {
+		name, host, header string
+		claim              *string // nil is anonymous
+		want               string
+		err                error
+	}{
+		{name: "claim first", host: "acme.example.com", header: "acme", claim: ptr("acme"), want: "acme"},
+		{name: "header", host: "globex.example.com", header: "initech", want: "initech"},
+		{name: "claim over header", host: "example.com", header: "initech", claim: ptr("acme"), want: "acme"},
+		{name: "caller without a tenant", host: "example.com", header: "initech", claim: ptr(""), err: ErrForbidden},
+		{name: "subdomain", host: "globex.example.com:8080", want: "globex"},
+		{name: "nested subdomain", host: "a.globex.example.com", err: ErrMissing},
+		{name: "other domain", host: "globex.example.org", err: ErrMissing},
+		{name: "none", host: "example.com", err: ErrMissing},
+	} {
+		req := httptest.NewRequest("GET", "http://"+tc.host+"/users/", nil)
+		if tc.header != "" {
+			req.Header.Set("X-Tenant-ID", tc.header)
+		}
+		if tc.claim != nil {
+			req = req.WithContext(context.WithValue(req.Context(), claimKey{}, *tc.claim))
+		}
+		got, err := r.Resolve(req)
+		if got != tc.want || !errors.Is(err, tc.err) {
+			t.Errorf("%s: Resolve = %q, %v; want %q, %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestResolvePinsCallersToTheirTenant(t *testing.T) {
+	r := NewResolver(Options{Sources: []string{SourceHeader}, Header: "X-Tenant-ID"}, func(context.Context) (string, bool) {
+		return "acme", true
+	})
+	for header, want := range map[string]error{"acme": nil, "initech": ErrForbidden} {
+		req := httptest.NewRequest("GET", "/users/", nil)
+		req.Header.Set("X-Tenant-ID", header)
+		if _, err := r.Resolve(req); !errors.Is(err, want) {
+			t.Errorf("caller of acme naming %s: %v, want %v", header, err, want)
+		}
+	}
+}
+
+func TestResolveChecksTenants(t *testing.T) {
+	r := NewResolver(Options{Sources: []string{SourceHeader}, Header: "X-Tenant-ID", Tenants: []string{"acme"}}, nil)
+	resolve := func(header string) error {
+		req := httptest.NewRequest("GET", "/users/", nil)
+		req.Header.Set("X-Tenant-ID", header)
+		_, err := r.Resolve(req)
+		return err
+	}
+	if err := resolve("acme"); err != nil {
+		t.Errorf("served tenant: %v", err)
+	}
+	if err := resolve("globex"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("tenant not served: %v", err)
+	}
+	for _, malformed := range []string{"Acme", "-acme", "acme_co", "a.b"} {
+		if err := resolve(malformed); err == nil || errors.Is(err, ErrUnknown) {
+			t.Errorf("malformed tenant %q: %v", malformed, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Options{Enabled: true, Sources: []string{SourceHeader}, Header: "X-Tenant-ID", Isolation: IsolationColumn}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, edit := range map[string]func(*Options){
+		"no sources":           func(o *Options) { o.Sources = nil },
+		"claim only":           func(o *Options) { o.Sources = []string{SourceClaim} },
+		"unknown source":       func(o *Options) { o.Sources = []string{"cookie"} },
+		"subdomain, no domain": func(o *Options) { o.Sources = []string{SourceSubdomain} },
+		"unknown isolation":    func(o *Options) { o.Isolation = "database" },
+		"schema, no tenants":   func(o *Options) { o.Isolation = IsolationSchema },
+		"malformed tenant":     func(o *Options) { o.Tenants = []string{"Acme"} },
+	} {
+		o := valid
+		edit(&o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("%s: Validate accepted %+v", name, o)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	if got := Schema("acme-corp"); got != "tenant_acme_corp" {
+		t.Errorf("Schema = %q", got)
+	}
+}
@@ -0,0 +1,338 @@
+// Package transform applies gateway-style rewrite rules to requests and
+// responses, for when the API fronts clients or backends that expect a
+// different path layout, headers or JSON field names.
+package transform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Rule describes the transformations for every request under Path. Request
+// changes are applied before routing, so RewritePath decides which handler runs.
+type Rule struct {
+	Path                  string            `yaml:"path"`                    // path prefix the rule applies to, e.g. /legacy/users
+	Methods               []string          `yaml:"methods"`                 // empty matches every method
+	RewritePath           string            `yaml:"rewrite_path"`            // replaces the matched prefix; empty keeps the path
+	SetRequestHeaders     map[string]string `yaml:"set_request_headers"`     // added or overwritten before the handler runs
+	RemoveRequestHeaders  []string          `yaml:"remove_request_headers"`  // stripped before the handler runs
+	SetResponseHeaders    map[string]string `yaml:"set_response_headers"`    // added or overwritten on the response
+	RemoveResponseHeaders []string          `yaml:"remove_response_headers"` // stripped from the response
+	ResponseFields        map[string]string `yaml:"response_fields"`         // JSON field renames at any depth, old -> new; "" drops the field
+}
+
+// Validate reports every problem with the rule at once.
+func (r Rule) Validate() error {
+	var errs []error
+	if !strings.HasPrefix(r.Path, "/") {
+		errs = append(errs, fmt.Errorf("path must start with / (got %q)", r.Path))
+	}
+	if r.RewritePath != "" && !strings.HasPrefix(r.RewritePath, "/") {
+		errs = append(errs, fmt.Errorf("rewrite_path must start with / (got %q)", r.RewritePath))
+	}
+	for _, m := range r.Methods {
+		if m == "" || strings.ToUpper(m) != m {
+			errs = append(errs, fmt.Errorf("method %q must be an upper-case HTTP method", m))
+		}
+	}
+	headers := append(append([]string{}, r.RemoveRequestHeaders...), r.RemoveResponseHeaders...)
+	for h := range r.SetRequestHeaders {
+		headers = append(headers, h)
+	}
+	for h := range r.SetResponseHeaders {
+		headers = append(headers, h)
+	}
+	for _, h := range headers {
+		if h == "" || strings.ContainsAny(h, " :\r\n") {
+			errs = append(errs, fmt.Errorf("invalid header name %q", h))
+		}
+	}
+	for from := range r.ResponseFields {
+		if from == "" {
+			errs = append(errs, errors.New("response_fields: field name must not be empty"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// matches reports whether the rule applies, matching Path on whole segments
+// so /users does not match /usersettings.
+func (r Rule) matches(method, path string) bool {
+	if len(r.Methods) > 0 && !contains(r.Methods, method) {
+		return false
+	}
+	prefix := strings.TrimSuffix(r.Path, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// Middleware applies the first rule matching each request. rules is called per
+// request so a reloaded configuration takes effect immediately.
+func Middleware(rules func() []Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rule, ok := find(rules(), r.Method, r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The request is modified in place: routers that captured the
+			// *http.Request before their pre-routing middleware (Echo) still see it.
+			if rule.RewritePath != "" {
+				rest := strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(rule.Path, "/"))
+				r.URL.Path = strings.TrimSuffix(rule.RewritePath, "/") + rest
+				if r.URL.Path == "" {
+					r.URL.Path = "/"
+				}
+				r.URL.RawPath = ""
+			}
+			for _, h := range rule.RemoveRequestHeaders {
+				r.Header.Del(h)
+			}
+			for h, v := range rule.SetRequestHeaders {
+				r.Header.Set(h, v)
+			}
+
+			if len(rule.ResponseFields) == 0 {
+				next.ServeHTTP(&headerWriter{ResponseWriter: w, rule: rule}, r)
+				return
+			}
+			buf := &bufferedWriter{w: w, rule: rule, header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(buf, r)
+			buf.flush()
+		})
+	}
+}
+
+func find(rules []Rule, method, path string) (Rule, bool) {
+	for _, r := range rules {
+		if r.matches(method, path) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func applyResponseHeaders(h http.Header, rule Rule) {
+	for _, name := range rule.RemoveResponseHeaders {
+		h.Del(name)
+	}
+	for name, v := range rule.SetResponseHeaders {
+		h.Set(name, v)
+	}
+}
+
+// headerWriter applies the response header rules just before the status line
+// is written, after the handler has set its own headers.
+type headerWriter struct {
+	http.ResponseWriter
+	rule        Rule
+	wroteHeader bool
+}
+
+func (w *headerWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		applyResponseHeaders(w.Header(), w.rule)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps long-poll and streaming handlers working behind a header-only rule.
+func (w *headerWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through a header-only rule.
+func (w *headerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to lift the
+// write deadline for an event stream.
+func (w *headerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bufferedWriter holds a JSON response so its body can be rewritten, and
+// writes any other through to w, with the header rules applied, as soon as
+// its status is written.
+type bufferedWriter struct {
+	w           http.ResponseWriter
+	rule        Rule
+	header      http.Header
+	status      int
+	wroteHeader bool
+	through     bool // the response goes to w as is
+	body        bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	if w.through {
+		return w.w.Header()
+	}
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if !isJSON(w.header.Get("Content-Type")) {
+		w.writeThrough()
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.through {
+		return w.w.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// Flush sends what is held and the rest of the response with its fields
+// as they are: a handler that flushes streams, e.g. NDJSON or server-sent
+// events.
+func (w *bufferedWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	w.writeThrough()
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through.
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.through = true
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to lift the
+// write deadline for an event stream.
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+// writeThrough writes the status, headers and held body to w, once; the rest
+// of the response follows them unbuffered.
+func (w *bufferedWriter) writeThrough() {
+	if w.through {
+		return
+	}
+	w.through = true
+	h := w.w.Header()
+	for k, v := range w.header {
+		h[k] = v
+	}
+	applyResponseHeaders(h, w.rule)
+	w.w.WriteHeader(w.status)
+	w.w.Write(w.body.Bytes())
+	w.body.Reset()
+}
+
+func (w *bufferedWriter) flush() {
+	if w.through {
+		return
+	}
+	body := w.body.Bytes()
+	if len(body) > 0 {
+		if rewritten, err := renameFields(body, w.rule.ResponseFields); err == nil {
+			body = rewritten
+		}
+		// A body that does not parse is passed through untouched.
+	}
+	h := w.w.Header()
+	for k, v := range w.header {
+		h[k] = v
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	applyResponseHeaders(h, w.rule)
+	w.w.WriteHeader(w.status)
+	w.w.Write(body)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func renameFields(body []byte, fields map[string]string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // keep large integers exact
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(rename(v, fields))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(body, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+func rename(v any, fields map[string]string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if to, ok := fields[k]; ok {
+				if to == "" {
+					continue
+				}
+				k = to
+			}
+			out[k] = rename(val, fields)
+		}
+		return out
+	case []any:
+		for i := range v {
+			v[i] = rename(v[i], fields)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/repository"
+)
+
+// Memory keeps entries in process, for the in-memory database and MongoDB.
+// It cannot take part in a transaction, so entries are added and removed
+// once the write on the context commits.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[[3]string]Entry // by resource, tenant and ID
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: map[[3]string]Entry{}}
+}
+
+func (m *Memory) Put(ctx context.Context, e Entry) error {
+	repository.AfterCommit(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.entries[[3]string{e.Resource, e.Tenant, e.ID}] = e
+	})
+	return nil
+}
+
+func (m *Memory) Take(ctx context.Context, resource, tenantID, id string, now time.Time) (Entry, error) {
+	key := [3]string{resource, tenantID, id}
+	m.mu.Lock()
+	e, ok := m.entries[key]
+	m.mu.Unlock()
+	if !ok || !now.Before(e.ExpiresAt) {
+		return Entry{}, ErrNotFound
+	}
+	repository.AfterCommit(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.entries[key].DeletedAt.Equal(e.DeletedAt) {
+			delete(m.entries, key)
+		}
+	})
+	return e, nil
+}
+
+func (m *Memory) Purge(_ context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for key, e := range m.entries {
+		if !now.Before(e.ExpiresAt) {
+			delete(m.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// SQL keeps entries in the trash table, in the repository.NewSQLUnitOfWork
+// transaction on the context if there is one, so a delete and its entry
+// commit or roll back together.
+type SQL struct {
+	db                          *sql.DB
+	remove, insert, take, purge string
+}
+
+func NewSQL(db *sql.DB, dialect repository.Dialect) *SQL {
+	p := dialect.Placeholder
+	return &SQL{
+		db:     db,
+		remove: fmt.Sprintf("DELETE FROM trash WHERE resource = %s AND tenant_id = %s AND id = %s", p(1), p(2), p(3)),
+		insert: fmt.Sprintf("INSERT INTO trash (resource, tenant_id, id, item, deleted_at, expires_at) VALUES (%s, %s, %s, %s, %s, %s)",
+			p(1), p(2), p(3), p(4), p(5), p(6)),
+		take: fmt.Sprintf("DELETE FROM trash WHERE resource = %s AND tenant_id = %s AND id = %s AND expires_at > %s RETURNING item, deleted_at, expires_at",
+			p(1), p(2), p(3), p(4)),
+		purge: fmt.Sprintf("DELETE FROM trash WHERE expires_at <= %s", p(1)),
+	}
+}
+
+func (s *SQL) Put(ctx context.Context, e Entry) error {
+	conn := repository.SQLConn(ctx, s.db)
+	if _, err := conn.ExecContext(ctx, s.remove, e.Resource, e.Tenant, e.ID); err != nil {
+		return fmt.Errorf("failed to move %s %s to the trash: %w", e.Resource, e.ID, err)
+	}
+	if _, err := conn.ExecContext(ctx, s.insert, e.Resource, e.Tenant, e.ID, string(e.Item), e.DeletedAt, e.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to move %s %s to the trash: %w", e.Resource, e.ID, err)
+	}
+	return nil
+}
+
+func (s *SQL) Take(ctx context.Context, resource, tenantID, id string, now time.Time) (Entry, error) {
+	e := Entry{Resource: resource, Tenant: tenantID, ID: id}
+	var item string
+	err := repository.SQLConn(ctx, s.db).QueryRowContext(ctx, s.take, resource, tenantID, id, now).Scan(&item, &e.DeletedAt, &e.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to take %s %s from the trash: %w", resource, id, err)
+	}
+	e.Item = []byte(item)
+	e.DeletedAt, e.ExpiresAt = e.DeletedAt.UTC(), e.ExpiresAt.UTC()
+	return e, nil
+}
+
+func (s *SQL) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.purge, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge the trash: %w", err)
+	}
+	return res.RowsAffected()
+}
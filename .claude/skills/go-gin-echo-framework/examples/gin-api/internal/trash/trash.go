@@ -0,0 +1,115 @@
+// Package trash keeps deleted items restorable for a while: a delete moves
+// the item to the trash in the same transaction, an undo within the window
+// takes it back out, and a scheduled purge deletes what has expired for
+// good.
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/schema"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+// Options set the undo window.
+type Options struct {
+	Window time.Duration `yaml:"window"` // how long a deleted item can be restored; 0 makes deletes final
+}
+
+// Validate checks that the window is not negative.
+func (o Options) Validate() error {
+	if o.Window < 0 {
+		return errors.New("window must not be negative")
+	}
+	return nil
+}
+
+// ErrNotFound is returned by Take for an item that was not deleted, or
+// whose window has passed.
+var ErrNotFound = errors.New("not in the trash")
+
+// Entry is a deleted item.
+type Entry struct {
+	Resource  string          // singular resource name, e.g. "user"
+	Tenant    string          // tenant the item was deleted in; "" without tenancy
+	ID        string          // ID of the item
+	Item      json.RawMessage // the item as it was deleted, with its schema version (see package schema)
+	DeletedAt time.Time
+	ExpiresAt time.Time // when Purge deletes it for good
+}
+
+// Store holds entries.
+type Store interface {
+	// Put adds e, replacing an entry of the same item.
+	Put(ctx context.Context, e Entry) error
+	// Take removes and returns the entry of an item, failing with
+	// ErrNotFound if it has none or it expired by now.
+	Take(ctx context.Context, resource, tenantID, id string, now time.Time) (Entry, error)
+	// Purge deletes the entries expired by now and returns how many.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Bin moves deleted items to a Store and back. A nil *Bin keeps nothing:
+// deletes are final.
+type Bin struct {
+	store  Store
+	clock  clock.Clock
+	window time.Duration
+}
+
+// NewBin keeps items in store for window after they are deleted, timed by
+// clk (nil is the system clock).
+func NewBin(store Store, clk clock.Clock, window time.Duration) *Bin {
+	return &Bin{store: store, clock: clock.OrSystem(clk), window: window}
+}
+
+// Put keeps item, the deleted item of resource identified by id, in the
+// tenant on ctx.
+func (b *Bin) Put(ctx context.Context, resource, id string, item any) error {
+	if b == nil {
+		return nil
+	}
+	data, err := schema.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("trash %s %s: %w", resource, id, err)
+	}
+	now := b.clock.Now().UTC()
+	return b.store.Put(ctx, Entry{
+		Resource:  resource,
+		Tenant:    tenant.From(ctx),
+		ID:        id,
+		Item:      data,
+		DeletedAt: now,
+		ExpiresAt: now.Add(b.window),
+	})
+}
+
+// Take removes the item of resource identified by id from the trash of the
+// tenant on ctx and decodes it into item, upgraded if it was deleted at an
+// older schema version, failing with ErrNotFound unless it was deleted
+// within the window.
+func (b *Bin) Take(ctx context.Context, resource, id string, item any) error {
+	if b == nil {
+		return ErrNotFound
+	}
+	e, err := b.store.Take(ctx, resource, tenant.From(ctx), id, b.clock.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := schema.Unmarshal(e.Item, item); err != nil {
+		return fmt.Errorf("trash %s %s: %w", resource, id, err)
+	}
+	return nil
+}
+
+// Purge deletes the items whose window has passed and returns how many. It
+// runs as a scheduled job; until it does, expired items can no longer be
+// restored but still take up space.
+func (b *Bin) Purge(ctx context.Context) (int64, error) {
+	return b.store.Purge(ctx, b.clock.Now().UTC())
+}
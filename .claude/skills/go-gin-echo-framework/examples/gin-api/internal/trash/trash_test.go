@@ -0,0 +1,118 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/migrations"
+	"github.com/your-username/gin-api/internal/repository"
+	"github.com/your-username/gin-api/internal/tenant"
+)
+
+type item struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newSQL(t *testing.T) (*SQL, repository.UnitOfWork) {
+	ctx := context.Background()
+	db, dialect, err := repository.OpenSQL(ctx, "sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(ctx, db, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQL(db, dialect), repository.NewSQLUnitOfWork(db)
+}
+
+func TestBin(t *testing.T) {
+	sqlStore, _ := newSQL(t)
+	for name, store := range map[string]Store{"memory": NewMemory(), "sql": sqlStore} {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+			bin := NewBin(store, clk, time.Minute)
+			ctx := context.Background()
+
+			if err := bin.Put(ctx, "item", "a", item{ID: "a", Name: "first"}); err != nil {
+				t.Fatal(err)
+			}
+			// Deleting an item again replaces what the trash holds of it
+			if err := bin.Put(ctx, "item", "a", item{ID: "a", Name: "second"}); err != nil {
+				t.Fatal(err)
+			}
+			var got item
+			if err := bin.Take(tenant.With(ctx, "acme"), "item", "a", &got); !errors.Is(err, ErrNotFound) {
+				t.Errorf("taken in another tenant: %v", err)
+			}
+			if err := bin.Take(ctx, "item", "a", &got); err != nil || got.Name != "second" {
+				t.Fatalf("Take = %+v, %v", got, err)
+			}
+			if err := bin.Take(ctx, "item", "a", &got); !errors.Is(err, ErrNotFound) {
+				t.Errorf("taken twice: %v", err)
+			}
+
+			if err := bin.Put(ctx, "item", "b", item{ID: "b"}); err != nil {
+				t.Fatal(err)
+			}
+			clk.Advance(time.Minute)
+			if err := bin.Take(ctx, "item", "b", &got); !errors.Is(err, ErrNotFound) {
+				t.Errorf("taken after the window: %v", err)
+			}
+			if n, err := bin.Purge(ctx); err != nil || n != 1 {
+				t.Errorf("Purge = %d, %v; want 1", n, err)
+			}
+		})
+	}
+}
+
+func TestPutAndTakeRollBackWithTheTransaction(t *testing.T) {
+	store, uow := newSQL(t)
+	bin := NewBin(store, nil, time.Minute)
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+
+	err := uow.Do(ctx, func(ctx context.Context) error {
+		if err := bin.Put(ctx, "item", "a", item{ID: "a"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	var got item
+	if err := bin.Take(ctx, "item", "a", &got); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("entry of a rolled back delete: %v", err)
+	}
+
+	if err := bin.Put(ctx, "item", "a", item{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if err := bin.Take(ctx, "item", "a", &got); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	if err := bin.Take(ctx, "item", "a", &got); err != nil {
+		t.Errorf("entry of a rolled back undo: %v", err)
+	}
+}
+
+func TestNilBinKeepsNothing(t *testing.T) {
+	var bin *Bin
+	if err := bin.Put(context.Background(), "item", "a", item{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bin.Take(context.Background(), "item", "a", &item{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Take from a nil bin: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// Authenticator is a software authenticator of discoverable ES256
+// credentials, which verifies every user and counts signatures. It stands
+// in for a browser and its passkey provider in tests, answering the
+// options of a RelyingParty as navigator.credentials would from Origin.
+// It is safe for concurrent use.
+type Authenticator struct {
+	Origin string
+
+	mu    sync.Mutex
+	creds []*softCredential
+}
+
+type softCredential struct {
+	id    []byte
+	rpID  string
+	user  []byte
+	key   *ecdsa.PrivateKey
+	count uint32
+}
+
+// NewAuthenticator returns an authenticator without credentials whose
+// ceremonies run at origin.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Create registers a new credential as navigator.credentials.create does
+// with opts.
+func (a *Authenticator) Create(opts CreationOptions) (*Registration, error) {
+	if !slices.ContainsFunc(opts.PubKeyCredParams, func(p CredentialParameters) bool { return p.Alg == AlgES256 }) {
+		return nil, errors.New("webauthn: ES256 is not offered")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ex := range opts.ExcludeCredentials {
+		if c := a.find(opts.RP.ID, ex.ID); c != nil {
+			return nil, errors.New("webauthn: a credential of the user is already registered")
+		}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	c := &softCredential{id: NewChallenge(), rpID: opts.RP.ID, user: opts.User.ID, key: key}
+	a.creds = append(a.creds, c)
+
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	cose := encodeCBOR(map[any]any{
+		int64(coseKty): int64(ktyEC2), int64(coseAlg): int64(AlgES256),
+		int64(-1): int64(curveP256), int64(-2): x, int64(-3): y,
+	})
+	data := c.authData(flagUserPresent | flagUserVerified | flagAttested)
+	data = append(data, make([]byte, 16)...) // AAGUID, zero for software
+	data = binary.BigEndian.AppendUint16(data, uint16(len(c.id)))
+	data = append(append(data, c.id...), cose...)
+
+	return &Registration{
+		ID:    base64.RawURLEncoding.EncodeToString(c.id),
+		RawID: c.id,
+		Type:  "public-key",
+		Response: AttestationResponse{
+			ClientDataJSON:    a.clientData("webauthn.create", opts.Challenge),
+			AttestationObject: encodeCBOR(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": data}),
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Get signs in with a credential of opts.RPID as navigator.credentials.get
+// does with opts: the first of opts.AllowCredentials it holds or, if the
+// list is empty, the first it holds for the relying party.
+func (a *Authenticator) Get(opts RequestOptions) (*Assertion, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var c *softCredential
+	if len(opts.AllowCredentials) == 0 {
+		c = a.find(opts.RPID, nil)
+	}
+	for _, allowed := range opts.AllowCredentials {
+		if c = a.find(opts.RPID, allowed.ID); c != nil {
+			break
+		}
+	}
+	if c == nil {
+		return nil, errors.New("webauthn: no credential for the relying party")
+	}
+	c.count++
+	data := c.authData(flagUserPresent | flagUserVerified)
+	clientData := a.clientData("webauthn.get", opts.Challenge)
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(slices.Clip(data), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Assertion{
+		ID:    base64.RawURLEncoding.EncodeToString(c.id),
+		RawID: c.id,
+		Type:  "public-key",
+		Response: AssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: data,
+			Signature:         sig,
+			UserHandle:        c.user,
+		},
+	}, nil
+}
+
+// find returns the credential id of rpID, or the first of rpID if id is
+// nil.
+func (a *Authenticator) find(rpID string, id []byte) *softCredential {
+	for _, c := range a.creds {
+		if c.rpID == rpID && (id == nil || bytes.Equal(c.id, id)) {
+			return c
+		}
+	}
+	return nil
+}
+
+// authData returns the authenticator data of c without attested
+// credential data.
+func (c *softCredential) authData(flags byte) []byte {
+	hash := sha256.Sum256([]byte(c.rpID))
+	return binary.BigEndian.AppendUint32(append(hash[:], flags), c.count)
+}
+
+func (a *Authenticator) clientData(typ string, challenge []byte) []byte {
+	b, _ := json.Marshal(clientData{Type: typ, Challenge: base64.RawURLEncoding.EncodeToString(challenge), Origin: a.Origin})
+	return b
+}
@@ -0,0 +1,378 @@
+// Package webauthn is the relying party side of Web Authentication
+// (W3C WebAuthn Level 2): the options a browser passes to
+// navigator.credentials.create and .get, and the verification of what they
+// return, which is what registering passkeys and logging in with them
+// takes. Credentials sign with ES256, EdDSA or RS256; attestation
+// statements are not verified, as the options ask for none, which is what
+// passkey providers send. Authenticator is a software authenticator that
+// stands in for a browser in tests.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is wrapped by every verification failure, with the reason.
+var ErrInvalid = errors.New("webauthn: invalid credential response")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
+
+// Flags of authenticator data (WebAuthn section 6.1).
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+	flagExtensions   = 0x80
+)
+
+// maxCredentialID is the longest credential ID accepted (WebAuthn section 5.8.3).
+const maxCredentialID = 1023
+
+// Bytes is binary data that JSON carries as unpadded base64url, as the JSON
+// forms of WebAuthn options and responses do (PublicKeyCredential.toJSON).
+// Padded and standard base64 are accepted too.
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	s = strings.TrimRight(s, "=")
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		if decoded, err = base64.RawStdEncoding.DecodeString(s); err != nil {
+			return errors.New("invalid base64url")
+		}
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingParty is the service credentials are registered with. Users
+// must be verified by the authenticator, with a PIN or biometrics, in
+// both ceremonies, so a passkey stands for a password and a second factor.
+type RelyingParty struct {
+	ID      string   // the domain credentials are scoped to, e.g. example.com
+	Name    string   // shown by the authenticator
+	Origins []string // where ceremonies may run, e.g. https://example.com
+}
+
+// RelyingPartyEntity names the relying party in CreationOptions.
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity is the account a credential is created for. ID is the user
+// handle, returned with assertions of discoverable credentials.
+type UserEntity struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameters offers a signature algorithm.
+type CredentialParameters struct {
+	Type string `json:"type"` // always "public-key"
+	Alg  int    `json:"alg"`  // COSE algorithm, e.g. -7 for ES256
+}
+
+// CredentialDescriptor names a credential, to exclude from a registration
+// or allow in a login.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"` // always "public-key"
+	ID         Bytes    `json:"id"`
+	Transports []string `json:"transports,omitempty"` // as the authenticator reported them, e.g. usb or internal
+}
+
+// AuthenticatorSelection asks for the kind of credential to create.
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"` // "preferred": discoverable, so logins need no email
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"` // "required"
+}
+
+// CreationOptions is the publicKey argument of navigator.credentials.create,
+// in the JSON form of PublicKeyCredential.parseCreationOptionsFromJSON.
+type CreationOptions struct {
+	Challenge              Bytes                  `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"` // milliseconds
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"` // always "none"
+}
+
+// RequestOptions is the publicKey argument of navigator.credentials.get, in
+// the JSON form of PublicKeyCredential.parseRequestOptionsFromJSON. Without
+// AllowCredentials the authenticator offers its discoverable credentials.
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout"` // milliseconds
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"` // "required"
+}
+
+// Registration is the result of navigator.credentials.create, as
+// PublicKeyCredential.toJSON encodes it.
+type Registration struct {
+	ID                      string              `json:"id"` // base64url of RawID
+	RawID                   Bytes               `json:"rawId"`
+	Type                    string              `json:"type"`
+	Response                AttestationResponse `json:"response"`
+	AuthenticatorAttachment string              `json:"authenticatorAttachment,omitempty"`
+	ClientExtensionResults  map[string]any      `json:"clientExtensionResults,omitempty"`
+}
+
+// AttestationResponse is the response of a Registration. The authenticator
+// data and public key are those in AttestationObject, which is what is
+// verified; browsers add the copies for clients that cannot decode it.
+type AttestationResponse struct {
+	ClientDataJSON     Bytes    `json:"clientDataJSON"`
+	AttestationObject  Bytes    `json:"attestationObject"`
+	Transports         []string `json:"transports,omitempty"`
+	AuthenticatorData  Bytes    `json:"authenticatorData,omitempty"`
+	PublicKey          Bytes    `json:"publicKey,omitempty"`
+	PublicKeyAlgorithm int      `json:"publicKeyAlgorithm,omitempty"`
+}
+
+// Assertion is the result of navigator.credentials.get, as
+// PublicKeyCredential.toJSON encodes it.
+type Assertion struct {
+	ID                      string            `json:"id"` // base64url of RawID
+	RawID                   Bytes             `json:"rawId"`
+	Type                    string            `json:"type"`
+	Response                AssertionResponse `json:"response"`
+	AuthenticatorAttachment string            `json:"authenticatorAttachment,omitempty"`
+	ClientExtensionResults  map[string]any    `json:"clientExtensionResults,omitempty"`
+}
+
+// AssertionResponse is the response of an Assertion.
+type AssertionResponse struct {
+	ClientDataJSON    Bytes `json:"clientDataJSON"`
+	AuthenticatorData Bytes `json:"authenticatorData"`
+	Signature         Bytes `json:"signature"`
+	UserHandle        Bytes `json:"userHandle,omitempty"` // UserEntity.ID of the registration; set for discoverable credentials
+}
+
+// Credential is a verified registration, to store for its logins.
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key, passed back to VerifyAssertion
+	SignCount  uint32
+	Transports []string
+}
+
+// NewChallenge returns a random challenge for a ceremony.
+func NewChallenge() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("webauthn: failed to generate challenge: %v", err))
+	}
+	return b
+}
+
+// CreationOptions returns the options of registering a credential for user,
+// which must not be one of exclude, the user's existing credentials.
+func (rp RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor, timeout time.Duration) CreationOptions {
+	return CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:      user,
+		PubKeyCredParams: []CredentialParameters{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:                timeout.Milliseconds(),
+		ExcludeCredentials:     nonNil(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "preferred", UserVerification: "required"},
+		Attestation:            "none",
+	}
+}
+
+// RequestOptions returns the options of logging in with one of allow, or
+// with any discoverable credential if allow is empty.
+func (rp RelyingParty) RequestOptions(challenge []byte, allow []CredentialDescriptor, timeout time.Duration) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: nonNil(allow),
+		UserVerification: "required",
+	}
+}
+
+func nonNil(d []CredentialDescriptor) []CredentialDescriptor {
+	if d == nil {
+		return []CredentialDescriptor{}
+	}
+	return d
+}
+
+// VerifyRegistration checks r against the challenge of its CreationOptions
+// (WebAuthn section 7.1) and returns the credential it registers.
+func (rp RelyingParty) VerifyRegistration(r *Registration, challenge []byte) (*Credential, error) {
+	if r.Type != "public-key" {
+		return nil, invalid("type %q is not public-key", r.Type)
+	}
+	if err := rp.checkClientData(r.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	v, rest, err := decodeCBOR(r.Response.AttestationObject)
+	if err != nil {
+		return nil, invalid("attestation object: %v", err)
+	}
+	obj, ok := v.(map[any]any)
+	raw, _ := obj["authData"].([]byte)
+	if !ok || len(rest) != 0 || raw == nil {
+		return nil, invalid("attestation object has no authenticator data")
+	}
+	data, err := rp.checkAuthData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if data.flags&flagAttested == 0 {
+		return nil, invalid("authenticator data holds no credential")
+	}
+	if !bytes.Equal(data.credentialID, r.RawID) || r.ID != base64.RawURLEncoding.EncodeToString(r.RawID) {
+		return nil, invalid("credential ID does not match the authenticator data")
+	}
+	if _, err := parsePublicKey(data.publicKey); err != nil {
+		return nil, invalid("%v", err)
+	}
+	return &Credential{ID: data.credentialID, PublicKey: data.publicKey, SignCount: data.signCount, Transports: r.Response.Transports}, nil
+}
+
+// VerifyAssertion checks a against the challenge of its RequestOptions and
+// the public key and signature count stored for the credential (WebAuthn
+// section 7.2), and returns the count to store. A count that does not
+// increase means the credential was cloned; authenticators that keep no
+// count always send 0.
+func (rp RelyingParty) VerifyAssertion(a *Assertion, challenge, publicKey []byte, signCount uint32) (uint32, error) {
+	if a.Type != "public-key" {
+		return 0, invalid("type %q is not public-key", a.Type)
+	}
+	if a.ID != base64.RawURLEncoding.EncodeToString(a.RawID) {
+		return 0, invalid("id does not match rawId")
+	}
+	if err := rp.checkClientData(a.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	data, err := rp.checkAuthData(a.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return 0, fmt.Errorf("webauthn: stored %v", err)
+	}
+	hash := sha256.Sum256(a.Response.ClientDataJSON)
+	if !key.verify(append(slices.Clip(a.Response.AuthenticatorData), hash[:]...), a.Response.Signature) {
+		return 0, invalid("signature does not verify")
+	}
+	if (data.signCount != 0 || signCount != 0) && data.signCount <= signCount {
+		return 0, invalid("signature count did not increase; the credential may be cloned")
+	}
+	return data.signCount, nil
+}
+
+// clientData is the part of the client data the ceremonies check.
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp RelyingParty) checkClientData(raw []byte, typ string, challenge []byte) error {
+	var c clientData
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return invalid("client data: %v", err)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(c.Challenge)
+	switch {
+	case c.Type != typ:
+		return invalid("client data type %q is not %s", c.Type, typ)
+	case err != nil || subtle.ConstantTimeCompare(got, challenge) != 1:
+		return invalid("challenge does not match")
+	case !slices.Contains(rp.Origins, c.Origin):
+		return invalid("origin %q is not allowed", c.Origin)
+	case c.CrossOrigin:
+		return invalid("cross-origin ceremonies are not allowed")
+	}
+	return nil
+}
+
+// authData is parsed authenticator data (WebAuthn section 6.1).
+type authData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte // with flagAttested
+	publicKey    []byte // COSE_Key, with flagAttested
+}
+
+// checkAuthData parses raw and checks it is scoped to the relying party
+// and that the user was present and verified.
+func (rp RelyingParty) checkAuthData(raw []byte) (*authData, error) {
+	if len(raw) < 37 {
+		return nil, invalid("authenticator data is too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(raw[:32], rpIDHash[:]) != 1 {
+		return nil, invalid("authenticator data is for another relying party")
+	}
+	data := &authData{flags: raw[32], signCount: binary.BigEndian.Uint32(raw[33:37])}
+	if data.flags&flagUserPresent == 0 || data.flags&flagUserVerified == 0 {
+		return nil, invalid("the user was not present and verified")
+	}
+	rest := raw[37:]
+	if data.flags&flagAttested != 0 {
+		if len(rest) < 18 { // AAGUID and ID length
+			return nil, invalid("attested credential data is too short")
+		}
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n > maxCredentialID || len(rest) < n {
+			return nil, invalid("invalid credential ID length %d", n)
+		}
+		data.credentialID, rest = rest[:n], rest[n:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, invalid("credential public key: %v", err)
+		}
+		data.publicKey, rest = rest[:len(rest)-len(after)], after
+	}
+	if data.flags&flagExtensions != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, invalid("extensions: %v", err)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, invalid("trailing bytes after authenticator data")
+	}
+	return data, nil
+}
@@ -0,0 +1,165 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var rp = RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+
+// register creates a credential with a and verifies it.
+func register(t *testing.T, a *Authenticator) *Credential {
+	t.Helper()
+	challenge := NewChallenge()
+	r, err := a.Create(rp.CreationOptions(challenge, UserEntity{ID: []byte("user-1"), Name: "ada@example.com", DisplayName: "Ada"}, nil, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := rp.VerifyRegistration(r, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cred
+}
+
+func TestRegisterAndLogIn(t *testing.T) {
+	a := NewAuthenticator("https://example.com")
+	cred := register(t, a)
+
+	count := cred.SignCount
+	for range 2 {
+		challenge := NewChallenge()
+		assertion, err := a.Get(rp.RequestOptions(challenge, []CredentialDescriptor{{Type: "public-key", ID: cred.ID}}, time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(assertion.Response.UserHandle) != "user-1" {
+			t.Errorf("user handle = %q", assertion.Response.UserHandle)
+		}
+		next, err := rp.VerifyAssertion(assertion, challenge, cred.PublicKey, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next <= count {
+			t.Errorf("sign count went from %d to %d", count, next)
+		}
+		count = next
+	}
+}
+
+func TestRegistrationIsRefused(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rp     RelyingParty
+		origin string
+		tamper func(r *Registration, challenge *[]byte)
+	}{
+		"other origin": {rp, "https://evil.example", nil},
+		"other relying party": {
+			RelyingParty{ID: "evil.example", Origins: []string{"https://example.com"}}, "https://example.com", nil,
+		},
+		"other challenge": {rp, "https://example.com", func(_ *Registration, challenge *[]byte) { *challenge = NewChallenge() }},
+		"other ID":        {rp, "https://example.com", func(r *Registration, _ *[]byte) { r.RawID = []byte("other") }},
+		"assertion": {rp, "https://example.com", func(r *Registration, _ *[]byte) {
+			r.Response.ClientDataJSON = []byte(`{"type":"webauthn.get"}`)
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			challenge := NewChallenge()
+			r, err := NewAuthenticator(tc.origin).Create(tc.rp.CreationOptions(challenge, UserEntity{ID: []byte("user-1")}, nil, time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.tamper != nil {
+				tc.tamper(r, &challenge)
+			}
+			if _, err := rp.VerifyRegistration(r, challenge); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("VerifyRegistration = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestAssertionIsRefused(t *testing.T) {
+	a := NewAuthenticator("https://example.com")
+	cred := register(t, a)
+	other := register(t, NewAuthenticator("https://example.com"))
+
+	for name, tc := range map[string]struct {
+		key    []byte
+		count  uint32
+		tamper func(a *Assertion)
+	}{
+		"other key":      {key: other.PublicKey},
+		"replayed count": {key: cred.PublicKey, count: 100},
+		"tampered data": {key: cred.PublicKey, tamper: func(a *Assertion) {
+			a.Response.AuthenticatorData[36]++
+		}},
+		"not verified": {key: cred.PublicKey, tamper: func(a *Assertion) {
+			a.Response.AuthenticatorData[32] &^= flagUserVerified
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			challenge := NewChallenge()
+			assertion, err := a.Get(rp.RequestOptions(challenge, nil, time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.tamper != nil {
+				tc.tamper(assertion)
+			}
+			if _, err := rp.VerifyAssertion(assertion, challenge, tc.key, tc.count); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("VerifyAssertion = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestExcludedCredentials(t *testing.T) {
+	a := NewAuthenticator("https://example.com")
+	cred := register(t, a)
+	opts := rp.CreationOptions(NewChallenge(), UserEntity{ID: []byte("user-1")}, []CredentialDescriptor{{Type: "public-key", ID: cred.ID}}, time.Minute)
+	if _, err := a.Create(opts); err == nil {
+		t.Fatal("registered a second credential with the same authenticator")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r, err := NewAuthenticator("https://example.com").Create(rp.CreationOptions(NewChallenge(), UserEntity{ID: []byte("user-1")}, nil, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Registration
+	if err := json.Unmarshal(b, &back); err != nil || !reflect.DeepEqual(&back, r) {
+		t.Fatalf("round trip = %+v, %v; want %+v", back, err, r)
+	}
+	// Browsers may pad
+	var padded Bytes
+	if err := json.Unmarshal([]byte(`"YQ=="`), &padded); err != nil || string(padded) != "a" {
+		t.Errorf("padded base64url = %q, %v", padded, err)
+	}
+}
+
+func TestCBOR(t *testing.T) {
+	v := map[any]any{int64(1): int64(-7), "k": []any{[]byte{1, 2}, "text", true, nil, int64(1 << 40)}}
+	got, rest, err := decodeCBOR(encodeCBOR(v))
+	if err != nil || len(rest) != 0 || !reflect.DeepEqual(got, v) {
+		t.Fatalf("round trip = %v, %x, %v", got, rest, err)
+	}
+	for _, bad := range [][]byte{
+		{0x5f},                         // indefinite byte string
+		{0x43, 1},                      // truncated byte string
+		{0xa2, 1, 2, 1, 3},             // duplicate key
+		{0xfb, 0, 0, 0, 0, 0, 0, 0, 0}, // float
+	} {
+		if _, _, err := decodeCBOR(bad); err == nil {
+			t.Errorf("decoded %x", bad)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package webhook receives the webhooks of third-party services. Each
+// service is a Provider, which authenticates its deliveries (usually by an
+// HMAC of the body) and names their ID and event; the Receiver checks the
+// payload of every subscribed event against a Go type, refuses replays of
+// deliveries it has already accepted, and queues them for a background job
+// (Run) that hands each to the handler of its event, retrying failures.
+// Answering at once, before the handler runs, keeps providers from timing
+// out and redelivering.
+//
+// Deliveries are queued and remembered in process, so a restart loses
+// those not yet handled, and instances behind a load balancer each refuse
+// only the replays they received themselves. Providers that sign a
+// timestamp have replays outside Options.Tolerance refused everywhere.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+var (
+	// ErrUnknownProvider is returned by Receive for a provider that is not
+	// registered.
+	ErrUnknownProvider = errors.New("unknown webhook provider")
+	// ErrSignature is matched by the errors of deliveries that are not
+	// signed by their provider, or signed too long ago.
+	ErrSignature = errors.New("invalid webhook signature")
+	// ErrInvalid is matched by the errors of deliveries whose headers or
+	// payload are malformed.
+	ErrInvalid = errors.New("invalid webhook delivery")
+	// ErrBusy is returned by Receive when Options.MaxQueued deliveries are
+	// already waiting; the provider should redeliver later.
+	ErrBusy = errors.New("too many webhook deliveries waiting")
+)
+
+// Options configure replay protection and the queue of deliveries.
+type Options struct {
+	Tolerance    time.Duration `yaml:"tolerance"`     // how far from now the timestamp signed by a provider may be
+	ReplayWindow time.Duration `yaml:"replay_window"` // how long the IDs of accepted deliveries are remembered to refuse replays
+	MaxQueued    int           `yaml:"max_queued"`    // deliveries waiting for the job; more get 503
+	Attempts     int           `yaml:"attempts"`      // runs of the job that try a delivery before it is dropped
+	GitHub       GitHub        `yaml:"github"`        // webhook of a GitHub repository or organization at POST /webhooks/github
+}
+
+// Validate checks the limits.
+func (o Options) Validate() error {
+	var errs []error
+	if o.Tolerance <= 0 {
+		errs = append(errs, errors.New("tolerance must be positive"))
+	}
+	if o.ReplayWindow < o.Tolerance {
+		errs = append(errs, errors.New("replay_window must be at least the tolerance"))
+	}
+	if o.MaxQueued < 1 {
+		errs = append(errs, errors.New("max_queued must be at least 1"))
+	}
+	if o.Attempts < 1 {
+		errs = append(errs, errors.New("attempts must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// Delivery is one authenticated request of a provider.
+type Delivery struct {
+	Provider  string
+	ID        string    // unique to the delivery, for replay protection
+	Event     string    // what happened, which selects the handler
+	Timestamp time.Time // when the provider signed it, if it signs one
+	Body      []byte
+}
+
+// Provider authenticates the deliveries of one service.
+type Provider interface {
+	// Verify checks that body was signed by the provider and returns the
+	// delivery the headers describe, failing with errors matching
+	// ErrSignature or ErrInvalid. Provider and Body are set by the caller.
+	Verify(header http.Header, body []byte) (Delivery, error)
+}
+
+// Handler handles the deliveries of one event. Build it with On.
+type Handler struct {
+	payload func() any
+	handle  func(ctx context.Context, d Delivery, payload any) error
+}
+
+// On returns the Handler of an event whose payload decodes into T and
+// passes validation: fn gets the decoded payload of each delivery.
+func On[T any](fn func(ctx context.Context, d Delivery, payload *T) error) Handler {
+	return Handler{
+		payload: func() any { return new(T) },
+		handle: func(ctx context.Context, d Delivery, payload any) error {
+			return fn(ctx, d, payload.(*T))
+		},
+	}
+}
+
+// Status is what became of a delivery Receive accepted.
+type Status string
+
+const (
+	Queued    Status = "queued"    // for the job to handle
+	Duplicate Status = "duplicate" // already accepted, e.g. a replay or a redelivery
+	Ignored   Status = "ignored"   // of an event without handler
+)
+
+// Receipt acknowledges a delivery to its provider.
+type Receipt struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Event    string `json:"event"`
+	Status   Status `json:"status"`
+}
+
+type provider struct {
+	Provider
+	events map[string]Handler
+}
+
+type queued struct {
+	delivery Delivery
+	payload  any
+	attempts int
+}
+
+// Receiver accepts the deliveries of the registered providers and queues
+// them for Run.
+type Receiver struct {
+	validate  func(payload any) error
+	clock     clock.Clock
+	opts      Options
+	providers map[string]provider
+
+	mu    sync.Mutex
+	seen  map[string]time.Time // provider/ID -> accepted at
+	queue []*queued
+}
+
+// New returns a Receiver without providers. Payloads are checked with
+// validate, the framework's request validation, after decoding.
+func New(validate func(payload any) error, clk clock.Clock, opts Options) *Receiver {
+	return &Receiver{
+		validate:  validate,
+		clock:     clock.OrSystem(clk),
+		opts:      opts,
+		providers: map[string]provider{},
+		seen:      map[string]time.Time{},
+	}
+}
+
+// Register adds provider p under name, with the handlers of the events it
+// subscribes to; deliveries of other events are acknowledged and ignored.
+func (r *Receiver) Register(name string, p Provider, events map[string]Handler) {
+	r.providers[name] = provider{Provider: p, events: events}
+}
+
+// Receive authenticates a delivery of the provider named name and queues
+// it, unless it was accepted before or its event has no handler.
+func (r *Receiver) Receive(name string, header http.Header, body []byte) (Receipt, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return Receipt{}, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+	}
+	d, err := p.Verify(header, body)
+	if err != nil {
+		return Receipt{}, err
+	}
+	d.Provider, d.Body = name, body
+	now := r.clock.Now()
+	if !d.Timestamp.IsZero() && (d.Timestamp.Before(now.Add(-r.opts.Tolerance)) || d.Timestamp.After(now.Add(r.opts.Tolerance))) {
+		return Receipt{}, fmt.Errorf("%w: signed at %s, more than %s from now", ErrSignature, d.Timestamp.UTC().Format(time.RFC3339), r.opts.Tolerance)
+	}
+	receipt := Receipt{Provider: name, ID: d.ID, Event: d.Event}
+	h, ok := p.events[d.Event]
+	if !ok {
+		receipt.Status = Ignored
+		return receipt, nil
+	}
+	payload := h.payload()
+	if err := json.Unmarshal(body, payload); err != nil {
+		return Receipt{}, fmt.Errorf("%w: %s payload: %v", ErrInvalid, d.Event, err)
+	}
+	if err := r.validate(payload); err != nil {
+		return Receipt{}, fmt.Errorf("%w: %s payload: %v", ErrInvalid, d.Event, err)
+	}
+
+	key := name + "/" + d.ID
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.seen[key]; dup {
+		receipt.Status = Duplicate
+		return receipt, nil
+	}
+	if len(r.queue) >= r.opts.MaxQueued {
+		return Receipt{}, fmt.Errorf("%w: %d", ErrBusy, len(r.queue))
+	}
+	r.seen[key] = now
+	r.queue = append(r.queue, &queued{delivery: d, payload: payload})
+	receipt.Status = Queued
+	return receipt, nil
+}
+
+// Run hands the queued deliveries to their handlers, in the order they
+// were received, and forgets the IDs accepted longer than
+// Options.ReplayWindow ago. It is the job that deliveries are handled in:
+// a delivery whose handler fails is queued again until it has been tried
+// Options.Attempts times, then dropped and forgotten, so that the provider
+// may redeliver it. The errors of the handlers are returned, for the job
+// to be retried.
+func (r *Receiver) Run(ctx context.Context) error {
+	r.mu.Lock()
+	due := r.queue
+	r.queue = nil
+	cutoff := r.clock.Now().Add(-r.opts.ReplayWindow)
+	for key, at := range r.seen {
+		if at.Before(cutoff) {
+			delete(r.seen, key)
+		}
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	var again []*queued
+	for _, q := range due {
+		d := q.delivery
+		err := r.providers[d.Provider].events[d.Event].handle(ctx, d, q.payload)
+		if err == nil {
+			continue
+		}
+		q.attempts++
+		if q.attempts < r.opts.Attempts {
+			again = append(again, q)
+			errs = append(errs, fmt.Errorf("%s delivery %s (%s): %w", d.Provider, d.ID, d.Event, err))
+			continue
+		}
+		r.mu.Lock()
+		delete(r.seen, d.Provider+"/"+d.ID)
+		r.mu.Unlock()
+		errs = append(errs, fmt.Errorf("%s delivery %s (%s) dropped after %d attempts: %w", d.Provider, d.ID, d.Event, q.attempts, err))
+	}
+	r.mu.Lock()
+	r.queue = append(again, r.queue...)
+	r.mu.Unlock()
+	return errors.Join(errs...)
+}
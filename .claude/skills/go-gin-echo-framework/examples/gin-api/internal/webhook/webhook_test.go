@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+func validate(payload any) error {
+	if p, ok := payload.(*GitHubPush); ok && p.Ref == "" {
+		return errors.New("ref is required")
+	}
+	return nil
+}
+
+func signed(secret, id, event, body string) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	h := http.Header{}
+	h.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	h.Set("X-GitHub-Delivery", id)
+	h.Set("X-GitHub-Event", event)
+	return h
+}
+
+// stamped signs its deliveries with the timestamp of X-Timestamp.
+type stamped struct{}
+
+func (stamped) Verify(header http.Header, _ []byte) (Delivery, error) {
+	at, err := time.Parse(time.RFC3339, header.Get("X-Timestamp"))
+	return Delivery{ID: header.Get("X-Timestamp"), Event: "tick", Timestamp: at}, err
+}
+
+func TestReceiver(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	var pushed []string
+	failures := 0
+	r := New(validate, clk, Options{Tolerance: 5 * time.Minute, ReplayWindow: time.Hour, MaxQueued: 2, Attempts: 2})
+	r.Register("github", GitHub{Secret: "s3cret"}, map[string]Handler{
+		"push": On(func(_ context.Context, d Delivery, p *GitHubPush) error {
+			if p.Ref == "refs/heads/broken" {
+				failures++
+				return errors.New("handler failed")
+			}
+			pushed = append(pushed, d.ID+" "+p.Ref)
+			return nil
+		}),
+	})
+	r.Register("stamped", stamped{}, map[string]Handler{"tick": On(func(context.Context, Delivery, *struct{}) error { return nil })})
+
+	push := `{"ref":"refs/heads/main","after":"abc","repository":{"full_name":"acme/api"}}`
+	got, err := r.Receive("github", signed("s3cret", "d1", "push", push), []byte(push))
+	if err != nil || got.Status != Queued {
+		t.Fatalf("Receive = %+v, %v", got, err)
+	}
+	if got, _ := r.Receive("github", signed("s3cret", "d1", "push", push), []byte(push)); got.Status != Duplicate {
+		t.Errorf("replay: %+v", got)
+	}
+	if got, _ := r.Receive("github", signed("s3cret", "d2", "issues", `{}`), []byte(`{}`)); got.Status != Ignored {
+		t.Errorf("unsubscribed event: %+v", got)
+	}
+	for name, err := range map[string]error{
+		"wrong secret": func() error {
+			_, err := r.Receive("github", signed("guess", "d3", "push", push), []byte(push))
+			return err
+		}(),
+		"tampered body": func() error {
+			_, err := r.Receive("github", signed("s3cret", "d3", "push", push), []byte(strings.Replace(push, "main", "prod", 1)))
+			return err
+		}(),
+		"stale timestamp": func() error {
+			h := http.Header{"X-Timestamp": {clk.Now().Add(-time.Hour).Format(time.RFC3339)}}
+			_, err := r.Receive("stamped", h, nil)
+			return err
+		}(),
+	} {
+		if !errors.Is(err, ErrSignature) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	invalid := `{"after":"abc"}`
+	if _, err := r.Receive("github", signed("s3cret", "d3", "push", invalid), []byte(invalid)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid payload: %v", err)
+	}
+	if _, err := r.Receive("gitlab", nil, nil); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("unknown provider: %v", err)
+	}
+
+	broken := strings.Replace(push, "main", "broken", 1)
+	if got, err := r.Receive("github", signed("s3cret", "d4", "push", broken), []byte(broken)); err != nil || got.Status != Queued {
+		t.Fatalf("Receive = %+v, %v", got, err)
+	}
+	if _, err := r.Receive("github", signed("s3cret", "d5", "push", push), []byte(push)); !errors.Is(err, ErrBusy) {
+		t.Errorf("Receive beyond max_queued: %v", err)
+	}
+
+	if err := r.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "handler failed") {
+		t.Errorf("Run: %v", err)
+	}
+	if len(pushed) != 1 || pushed[0] != "d1 refs/heads/main" {
+		t.Errorf("pushed %q", pushed)
+	}
+	if err := r.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "dropped after 2 attempts") || failures != 2 {
+		t.Errorf("Run = %v after %d failures", err, failures)
+	}
+	// Dropped deliveries are forgotten, so that they can be redelivered
+	if got, _ := r.Receive("github", signed("s3cret", "d4", "push", broken), []byte(broken)); got.Status != Queued {
+		t.Errorf("redelivery of a dropped delivery: %+v", got)
+	}
+
+	clk.Advance(2 * time.Hour)
+	if err := r.Run(context.Background()); err == nil {
+		t.Error("Run of the redelivery succeeded")
+	}
+	if got, _ := r.Receive("github", signed("s3cret", "d1", "push", push), []byte(push)); got.Status != Queued {
+		t.Errorf("delivery replayed after the replay window: %+v", got)
+	}
+}
@@ -0,0 +1,375 @@
+// Package worker runs registered background jobs on cron-style schedules.
+// A job that fails is retried after a backoff doubling from Options.Backoff,
+// up to Options.Retries times, and then waits for its next scheduled run. A
+// job never overlaps itself: a run that comes due while the previous one is
+// still going is skipped. Enqueue requests an extra run, which waits in a
+// queue of Options.Queue runs; when the queue is full Enqueue waits for room
+// as long as its context allows, rather than dropping the run. Shutdown
+// stops scheduling and drains the runs in flight, cancelling their contexts
+// if the drain times out.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/health"
+)
+
+// resolution is how often the worker checks for due jobs.
+const resolution = time.Second
+
+// maxBackoff caps the wait before a failed job is retried.
+const maxBackoff = time.Hour
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// ParseSchedule parses a standard 5-field cron expression ("0 3 * * *") or
+// a descriptor: @hourly, @daily, @weekly, @monthly, @yearly or
+// "@every <duration>".
+func ParseSchedule(spec string) (Schedule, error) {
+	s, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return s, nil
+}
+
+// ErrUnknownJob is returned by Enqueue for a job that is not registered.
+var ErrUnknownJob = errors.New("unknown job")
+
+// ErrQueueFull is matched by the *QueueFullError of Enqueue.
+var ErrQueueFull = errors.New("job queue is full")
+
+// QueueFullError is returned by Enqueue when the queue stayed full until
+// its context ended.
+type QueueFullError struct {
+	Queue      int           // runs waiting in the queue
+	RetryAfter time.Duration // until the scheduler next starts queued runs
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("%v: %d runs waiting", ErrQueueFull, e.Queue)
+}
+
+func (e *QueueFullError) Is(target error) bool { return target == ErrQueueFull }
+
+// Options configure retries of failed jobs and the queue of Enqueue.
+type Options struct {
+	Retries int           `yaml:"retries"` // retries of a failed run before waiting for the next scheduled one
+	Backoff time.Duration `yaml:"backoff"` // wait before the first retry, doubling for each further one
+	Queue   int           `yaml:"queue"`   // runs requested with Enqueue that may wait to start
+}
+
+// Validate checks the retry and queue settings.
+func (o Options) Validate() error {
+	var errs []error
+	if o.Retries < 0 {
+		errs = append(errs, errors.New("retries must not be negative"))
+	}
+	if o.Retries > 0 && o.Backoff <= 0 {
+		errs = append(errs, errors.New("backoff must be positive"))
+	}
+	if o.Queue < 0 {
+		errs = append(errs, errors.New("queue must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// job is a registered job and its scheduling state.
+type job struct {
+	name     string
+	schedule Schedule
+	timeout  time.Duration
+	run      func(ctx context.Context) error
+
+	next     time.Time
+	failures int
+	running  bool
+}
+
+// request is a run of j requested with Enqueue at at.
+type request struct {
+	job *job
+	at  time.Time
+}
+
+// Worker schedules and runs jobs.
+type Worker struct {
+	opts  Options
+	clock clock.Clock
+
+	mu        sync.Mutex
+	jobs      []*job
+	heartbeat time.Time     // when the scheduler last checked for due jobs
+	queue     []request     // runs requested with Enqueue, in order
+	room      chan struct{} // closed, and replaced, when runs leave a full queue
+
+	// ctx is the parent of every run; cancel aborts them when a drain
+	// times out.
+	ctx    context.Context
+	cancel context.CancelFunc
+	runs   sync.WaitGroup
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New returns a Worker that schedules by clk (nil is the system clock).
+func New(clk clock.Clock, opts Options) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		opts:   opts,
+		clock:  clock.OrSystem(clk),
+		ctx:    ctx,
+		cancel: cancel,
+		room:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Register adds a job that runs on schedule (see ParseSchedule), each run
+// cancelled after timeout unless it is 0.
+func (w *Worker) Register(name, schedule string, timeout time.Duration, run func(ctx context.Context) error) error {
+	s, err := ParseSchedule(schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, j := range w.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %s is already registered", name)
+		}
+	}
+	w.jobs = append(w.jobs, &job{name: name, schedule: s, timeout: timeout, run: run, next: s.Next(w.clock.Now())})
+	return nil
+}
+
+// Enqueue requests a run of the job registered as name, started by the
+// scheduler's next check for due jobs once the job is not running. If the
+// queue is full, Enqueue waits for room until ctx ends and then returns a
+// *QueueFullError; give ctx a deadline to bound the wait.
+func (w *Worker) Enqueue(ctx context.Context, name string) error {
+	for {
+		w.mu.Lock()
+		var j *job
+		for _, candidate := range w.jobs {
+			if candidate.name == name {
+				j = candidate
+			}
+		}
+		if j == nil {
+			w.mu.Unlock()
+			return fmt.Errorf("%w %q", ErrUnknownJob, name)
+		}
+		if len(w.queue) < w.opts.Queue {
+			w.queue = append(w.queue, request{job: j, at: w.clock.Now()})
+			w.mu.Unlock()
+			return nil
+		}
+		room, queued := w.room, len(w.queue)
+		w.mu.Unlock()
+		select {
+		case <-room:
+		case <-ctx.Done():
+			return &QueueFullError{Queue: queued, RetryAfter: resolution}
+		}
+	}
+}
+
+// Start runs due jobs until Shutdown.
+func (w *Worker) Start() {
+	w.mu.Lock()
+	w.heartbeat = w.clock.Now()
+	w.mu.Unlock()
+	go func() {
+		defer close(w.done)
+		tick := w.clock.NewTicker(resolution)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C():
+				w.dispatch()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown stops scheduling and waits for the runs in flight. If ctx ends
+// first, their contexts are cancelled and ctx's error is returned.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	close(w.stop)
+	<-w.done
+	drained := make(chan struct{})
+	go func() {
+		w.runs.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// dispatch starts every due job that is not already running, then the
+// queued runs of jobs that are not running, in order.
+func (w *Worker) dispatch() {
+	now := w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.heartbeat = now
+	for _, j := range w.jobs {
+		if j.running || now.Before(j.next) {
+			continue
+		}
+		w.start(j)
+	}
+	full := len(w.queue) >= w.opts.Queue
+	waiting := w.queue[:0]
+	for _, r := range w.queue {
+		if r.job.running {
+			waiting = append(waiting, r)
+			continue
+		}
+		w.start(r.job)
+	}
+	clear(w.queue[len(waiting):])
+	w.queue = waiting
+	if full && len(w.queue) < w.opts.Queue {
+		close(w.room)
+		w.room = make(chan struct{})
+	}
+}
+
+// start runs j. Must be called with w.mu held.
+func (w *Worker) start(j *job) {
+	j.running = true
+	w.runs.Add(1)
+	go w.execute(j)
+}
+
+// execute runs j once and schedules its retry or next run.
+func (w *Worker) execute(j *job) {
+	defer w.runs.Done()
+	ctx := w.ctx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+	err := runSafely(ctx, j.run)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	j.running = false
+	now := w.clock.Now()
+	switch {
+	case err == nil:
+		j.failures = 0
+		j.next = j.schedule.Next(now)
+	case j.failures < w.opts.Retries:
+		j.failures++
+		backoff := w.backoff(j.failures)
+		log.Printf("WARNING: job %s failed (attempt %d), retrying in %s: %v", j.name, j.failures, backoff, err)
+		j.next = now.Add(backoff)
+	default:
+		j.next = j.schedule.Next(now)
+		log.Printf("WARNING: job %s failed after %d attempts, next run at %s: %v", j.name, j.failures+1, j.next.Format(time.RFC3339), err)
+		j.failures = 0
+	}
+}
+
+// Stats is a point-in-time snapshot of a Worker.
+type Stats struct {
+	Jobs      int       `json:"jobs"`
+	Running   int       `json:"running"`
+	Queued    int       `json:"queued"`    // due or enqueued for longer than a tick and not started, behind a stalled scheduler or their own run
+	Retrying  int       `json:"retrying"`  // failed, waiting for a retry
+	Heartbeat time.Time `json:"heartbeat"` // when the scheduler last checked for due jobs; zero before Start
+}
+
+// Stats returns the state of the jobs.
+func (w *Worker) Stats() Stats {
+	now := w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := Stats{Jobs: len(w.jobs), Heartbeat: w.heartbeat}
+	for _, j := range w.jobs {
+		// A running job's next is the run in flight; its next slot may
+		// have passed since
+		due := j.next
+		if j.running {
+			s.Running++
+			due = j.schedule.Next(j.next)
+		}
+		if now.Sub(due) > resolution {
+			s.Queued++
+		}
+		if j.failures > 0 {
+			s.Retrying++
+		}
+	}
+	for _, r := range w.queue {
+		if now.Sub(r.at) > resolution {
+			s.Queued++
+		}
+	}
+	return s
+}
+
+// HealthCheck returns a readiness check that reports Stats and fails when
+// more than maxQueued jobs are queued or the scheduler has not checked for
+// due jobs within maxHeartbeatAge. 0 disables either limit.
+func (w *Worker) HealthCheck(maxQueued int, maxHeartbeatAge time.Duration) health.DetailFunc {
+	return func(ctx context.Context) (any, error) {
+		s := w.Stats()
+		switch {
+		case s.Heartbeat.IsZero():
+			return s, errors.New("scheduler not started")
+		case maxHeartbeatAge > 0 && w.clock.Now().Sub(s.Heartbeat) > maxHeartbeatAge:
+			return s, fmt.Errorf("scheduler stalled: last heartbeat %s ago", w.clock.Now().Sub(s.Heartbeat).Round(time.Second))
+		case maxQueued > 0 && s.Queued > maxQueued:
+			return s, fmt.Errorf("%d jobs queued, more than %d", s.Queued, maxQueued)
+		}
+		return s, nil
+	}
+}
+
+// backoff returns the wait before the given retry, doubling from
+// Options.Backoff.
+func (w *Worker) backoff(retry int) time.Duration {
+	backoff := w.opts.Backoff
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// runSafely runs fn, turning a panic into an error so that a faulty job
+// cannot take the process down.
+func runSafely(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-username/gin-api/internal/clock"
+)
+
+// step advances clk by d and runs the jobs that came due to completion.
+func step(w *Worker, clk *clock.Fake, d time.Duration) {
+	clk.Advance(d)
+	w.dispatch()
+	w.runs.Wait()
+}
+
+func TestJobsRunOnTheirSchedule(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC))
+	w := New(clk, Options{})
+	var runs []time.Time
+	if err := w.Register("nightly", "0 3 * * *", 0, func(context.Context) error {
+		runs = append(runs, clk.Now())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	step(w, clk, 29*time.Minute)
+	if len(runs) != 0 {
+		t.Fatalf("ran at %v, before 03:00", runs)
+	}
+	step(w, clk, time.Minute)
+	step(w, clk, time.Hour)
+	if len(runs) != 1 || runs[0].Hour() != 3 {
+		t.Fatalf("runs %v, want one at 03:00", runs)
+	}
+	step(w, clk, 23*time.Hour)
+	if len(runs) != 2 {
+		t.Fatalf("%d runs after a day, want 2", len(runs))
+	}
+}
+
+func TestFailedJobsAreRetriedWithBackoff(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := New(clk, Options{Retries: 2, Backoff: 10 * time.Second})
+	attempts := 0
+	if err := w.Register("flaky", "@every 1h", 0, func(context.Context) error {
+		attempts++
+		return errors.New("unavailable")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	step(w, clk, time.Hour)
+	step(w, clk, 9*time.Second)
+	if attempts != 1 {
+		t.Fatalf("%d attempts before the backoff passed, want 1", attempts)
+	}
+	step(w, clk, time.Second)
+	step(w, clk, 20*time.Second) // the second retry waits twice as long
+	if attempts != 3 {
+		t.Fatalf("%d attempts after both retries, want 3", attempts)
+	}
+	// Out of retries: the job waits for its next scheduled run
+	step(w, clk, 40*time.Second)
+	if attempts != 3 {
+		t.Fatalf("%d attempts, want no retry after the last", attempts)
+	}
+	step(w, clk, time.Hour)
+	if attempts != 4 {
+		t.Fatalf("%d attempts, want the next scheduled run", attempts)
+	}
+}
+
+func TestPanickingJobFailsTheRun(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := New(clk, Options{Retries: 1, Backoff: time.Second})
+	attempts := 0
+	if err := w.Register("faulty", "@every 1m", 0, func(context.Context) error {
+		attempts++
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	step(w, clk, time.Minute)
+	step(w, clk, time.Second)
+	if attempts != 2 {
+		t.Fatalf("%d attempts, want the panic retried once", attempts)
+	}
+}
+
+func TestShutdownDrainsRunningJobs(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := New(clk, Options{})
+	started, release := make(chan struct{}), make(chan struct{})
+	var finished, cancelled bool
+	if err := w.Register("slow", "@every 1m", 0, func(context.Context) error {
+		close(started)
+		<-release
+		finished = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stuck := make(chan struct{})
+	if err := w.Register("stuck", "@every 1m", 0, func(ctx context.Context) error {
+		<-ctx.Done()
+		cancelled = true
+		close(stuck)
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	w.Start()
+	for clk.Tickers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(time.Minute)
+	<-started
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want the drain to time out on the stuck job", err)
+	}
+	<-stuck
+	w.runs.Wait()
+	if !finished || !cancelled {
+		t.Fatalf("finished %v, cancelled %v: want the slow job drained and the stuck one cancelled", finished, cancelled)
+	}
+}
+
+func TestRegisterRejectsBadSchedules(t *testing.T) {
+	w := New(nil, Options{})
+	if err := w.Register("bad", "every day", 0, func(context.Context) error { return nil }); err == nil {
+		t.Fatal("expected an invalid schedule to be rejected")
+	}
+	if err := w.Register("ok", "@daily", 0, func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Register("ok", "@hourly", 0, func(context.Context) error { return nil }); err == nil {
+		t.Fatal("expected a duplicate job name to be rejected")
+	}
+}
+
+func TestHealthCheckReportsStalledJobs(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := New(clk, Options{})
+	release := make(chan struct{})
+	if err := w.Register("slow", "@every 1m", 0, func(context.Context) error {
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	check := w.HealthCheck(0, 10*time.Second)
+	if _, err := check(context.Background()); err == nil {
+		t.Fatal("check passed before Start")
+	}
+	w.heartbeat = clk.Now() // as Start does, without its ticker
+
+	clk.Advance(time.Minute)
+	w.dispatch()
+	if details, err := check(context.Background()); err != nil || details.(Stats).Running != 1 {
+		t.Fatalf("running job: %+v, %v", details, err)
+	}
+	// Its next run comes due while it is still going
+	clk.Advance(time.Minute + 2*time.Second)
+	w.dispatch()
+	if s := w.Stats(); s.Queued != 1 || s.Running != 1 {
+		t.Fatalf("stats = %+v, want the job queued behind its own run", s)
+	}
+	if _, err := w.HealthCheck(0, 0)(context.Background()); err != nil {
+		t.Fatalf("check without limits failed: %v", err)
+	}
+	if _, err := w.HealthCheck(1, 0)(context.Background()); err != nil {
+		t.Fatalf("1 queued job failed a limit of 1: %v", err)
+	}
+	close(release)
+	w.runs.Wait()
+
+	clk.Advance(11 * time.Second)
+	if _, err := check(context.Background()); err == nil {
+		t.Fatal("check passed 11s after the last heartbeat")
+	}
+}
+
+func TestEnqueueWaitsForRoom(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := New(clk, Options{Queue: 1})
+	runs := 0
+	if err := w.Register("reindex", "@daily", 0, func(context.Context) error {
+		runs++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := w.Enqueue(ctx, "compact"); !errors.Is(err, ErrUnknownJob) {
+		t.Fatalf("unknown job: err = %v", err)
+	}
+	if err := w.Enqueue(ctx, "reindex"); err != nil {
+		t.Fatal(err)
+	}
+	expired, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	var full *QueueFullError
+	if err := w.Enqueue(expired, "reindex"); !errors.Is(err, ErrQueueFull) || !errors.As(err, &full) || full.RetryAfter != resolution {
+		t.Fatalf("full queue: err = %v", err)
+	}
+
+	// A waiting Enqueue gets the room of the run the scheduler starts
+	enqueued := make(chan error)
+	go func() { enqueued <- w.Enqueue(ctx, "reindex") }()
+	step(w, clk, time.Second)
+	if err := <-enqueued; err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Fatalf("%d runs after the first dispatch, want 1", runs)
+	}
+	clk.Advance(2 * time.Second)
+	if s := w.Stats(); s.Queued != 1 {
+		t.Fatalf("stats = %+v, want the enqueued run queued", s)
+	}
+	step(w, clk, 0)
+	if runs != 2 || w.Stats().Queued != 0 {
+		t.Fatalf("%d runs, stats %+v; want 2 and none queued", runs, w.Stats())
+	}
+}
@@ -0,0 +1,90 @@
+// Load profile of the user routes for k6 (https://k6.io), against a running
+// server:
+//
+//	go run . &
+//	k6 run -e BASE_URL=http://localhost:8080 loadtest/crud.js
+//
+// Each virtual user works through the mix of BenchmarkCRUD: mostly reads,
+// some updates, and churn that creates a user and deletes it. The readers
+// ramp up to READERS virtual users (default 50) while the writers hold at
+// WRITERS (default 5). The staging and production presets rate limit every
+// client to rate_limit.limits.default, so raise it for the run or all but
+// the first requests are refused with 429. To profile the server under
+// load, enable profiling.pprof and fetch /debug/pprof/profile?seconds=30
+// as an admin meanwhile.
+import http from 'k6/http';
+import { check, sleep } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:8080';
+const params = { headers: { 'Content-Type': 'application/json' } };
+
+export const options = {
+  scenarios: {
+    readers: {
+      executor: 'ramping-vus',
+      exec: 'read',
+      startVUs: 0,
+      stages: [
+        { duration: '30s', target: Number(__ENV.READERS || 50) },
+        { duration: '1m', target: Number(__ENV.READERS || 50) },
+        { duration: '10s', target: 0 },
+      ],
+    },
+    writers: {
+      executor: 'constant-vus',
+      exec: 'write',
+      vus: Number(__ENV.WRITERS || 5),
+      duration: '1m40s',
+    },
+  },
+  thresholds: {
+    http_req_failed: ['rate<0.01'],
+    'http_req_duration{scenario:readers}': ['p(95)<100', 'p(99)<250'],
+    'http_req_duration{scenario:writers}': ['p(95)<250', 'p(99)<500'],
+  },
+};
+
+// setup seeds the users the readers get.
+export function setup() {
+  const ids = [];
+  for (let i = 0; i < 100; i++) {
+    ids.push(create(`seed${i}`));
+  }
+  return { ids };
+}
+
+export function read({ ids }) {
+  if (Math.random() < 0.01) {
+    check(http.get(`${BASE_URL}/users/`, { tags: { name: 'list' } }), { 'list 200': (r) => r.status === 200 });
+  } else {
+    const id = ids[Math.floor(Math.random() * ids.length)];
+    check(http.get(`${BASE_URL}/users/${id}`, { tags: { name: 'get' } }), { 'get 200': (r) => r.status === 200 });
+  }
+  sleep(0.1);
+}
+
+export function write({ ids }) {
+  if (Math.random() < 0.8) {
+    const id = ids[Math.floor(Math.random() * ids.length)];
+    const body = JSON.stringify({ name: `User ${__VU}`, email: `user${__VU}@example.com` });
+    check(http.put(`${BASE_URL}/users/${id}`, body, { ...params, tags: { name: 'update' } }), { 'update 200': (r) => r.status === 200 });
+  } else {
+    const id = create(`churn${__VU}-${__ITER}`);
+    check(http.del(`${BASE_URL}/users/${id}`, null, { tags: { name: 'delete' } }), { 'delete 204': (r) => r.status === 204 });
+  }
+  sleep(0.1);
+}
+
+// teardown deletes the seeded users.
+export function teardown({ ids }) {
+  for (const id of ids) {
+    http.del(`${BASE_URL}/users/${id}`);
+  }
+}
+
+function create(name) {
+  const body = JSON.stringify({ name: `User ${name}`, email: `${name}@example.com` });
+  const res = http.post(`${BASE_URL}/users/`, body, { ...params, tags: { name: 'create' } });
+  check(res, { 'create 201': (r) => r.status === 201 });
+  return res.json('id');
+}
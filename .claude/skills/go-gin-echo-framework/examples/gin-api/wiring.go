@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/your-username/gin-api/config"
+	"github.com/your-username/gin-api/internal/audit"
+	"github.com/your-username/gin-api/internal/auth"
+	"github.com/your-username/gin-api/internal/blob"
+	"github.com/your-username/gin-api/internal/cache"
+	"github.com/your-username/gin-api/internal/clock"
+	"github.com/your-username/gin-api/internal/container"
+	"github.com/your-username/gin-api/internal/domain"
+	"github.com/your-username/gin-api/internal/events"
+	"github.com/your-username/gin-api/internal/health"
+	"github.com/your-username/gin-api/internal/httpclient"
+	"github.com/your-username/gin-api/internal/idgen"
+	"github.com/your-username/gin-api/internal/lifecycle"
+	"github.com/your-username/gin-api/internal/notify"
+	"github.com/your-username/gin-api/internal/outbox"
+	"github.com/your-username/gin-api/internal/ratelimit"
+	"github.com/your-username/gin-api/internal/resilience"
+	"github.com/your-username/gin-api/internal/testmode"
+	"github.com/your-username/gin-api/internal/trash"
+	"github.com/your-username/gin-api/internal/worker"
+)
+
+// newContainer is the composition root of the components the resources
+// share: storage, caches, brokers, jobs and the clients of other services.
+// Each is declared by a provider asking for what it depends on, and built
+// when newServer first asks for it, appending its hooks to lc then. The
+// resources themselves, with their services and routes, are wired in
+// newServer.
+func newContainer(cfg *config.Config, watcher *config.Watcher, lc *lifecycle.Manager) *container.Container {
+	c := container.New(lc)
+	container.Supply(c, cfg)
+	container.Supply(c, watcher)
+
+	// Test mode stands in for the outside world: a fake clock, seeded IDs,
+	// and outbound requests recorded instead of sent
+	container.Provide(c, func(*container.Container) (*testmode.Harness, error) {
+		if !cfg.TestMode.Enabled {
+			return nil, nil
+		}
+		return testmode.New(cfg.TestMode, cfg.IDStrategy())
+	})
+
+	// Everything that stamps, expires or schedules by time reads this clock,
+	// so its tests can run on a clock.Fake
+	container.Provide(c, func(c *container.Container) (clock.Clock, error) {
+		if harness := get[*testmode.Harness](c); harness != nil {
+			return harness.Clock, nil
+		}
+		return clock.System, nil
+	})
+
+	// IDs of created entities, tokens and outbox events, by the ids strategy
+	container.Provide(c, func(c *container.Container) (idgen.Generator, error) {
+		if harness := get[*testmode.Harness](c); harness != nil {
+			return harness.IDs, nil
+		}
+		return idgen.New(cfg.IDStrategy(), get[clock.Clock](c))
+	})
+
+	// Calls to the database and other services go through circuit breakers,
+	// listed at /debug/breakers. They time outages of real dependencies, so
+	// they read the system clock even in test mode.
+	container.Provide(c, func(*container.Container) (*resilience.Registry, error) {
+		return resilience.NewRegistry(cfg.Resilience, nil), nil
+	})
+
+	// Calls to other services share the transport of the factory, with its
+	// timeouts, connection pool and trace propagation
+	container.Provide(c, func(c *container.Container) (*httpclient.Factory, error) {
+		var transport http.RoundTripper // nil is one tuned by cfg.HTTPClient
+		if harness := get[*testmode.Harness](c); harness != nil {
+			transport = harness.Effects.Client().Transport
+		}
+		var guard *resilience.Registry
+		if cfg.Resilience.Enabled {
+			guard = get[*resilience.Registry](c)
+		}
+		return httpclient.New(cfg.HTTPClient, transport, guard), nil
+	})
+
+	// Liveness and readiness checks; dependencies register theirs as they
+	// are built
+	container.Provide(c, func(c *container.Container) (*health.Registry, error) {
+		checks := health.NewRegistry()
+		if len(cfg.Health.Downstreams) > 0 {
+			outbound := get[*httpclient.Factory](c).Client()
+			for name, url := range cfg.Health.Downstreams {
+				checks.Register("downstream:"+name, health.Readiness, cfg.Health.Timeout, health.HTTPCheck(outbound, url))
+			}
+		}
+		return checks, nil
+	})
+
+	// Persistence backend chosen by the database.url scheme; services and
+	// handlers only see the repository interfaces
+	container.Provide(c, func(c *container.Container) (*database, error) {
+		return openDatabase(context.Background(), cfg.Database, lc, get[*health.Registry](c), cfg.Health.Timeout)
+	})
+
+	// Store of the read-through cache of repositories, nil with the cache
+	// disabled (cache.ttl=0)
+	container.Provide(c, func(c *container.Container) (cache.Store, error) {
+		if cfg.Cache.TTL <= 0 {
+			return nil, nil
+		}
+		store, err := cache.NewStore(context.Background(), lc, cfg.Cache.Backend, cfg.Redis.URL.Reveal(), cfg.Cache.Size, get[clock.Clock](c))
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := store.(health.Pinger); ok {
+			get[*health.Registry](c).Register("cache", health.Readiness, cfg.Health.Timeout, p.Ping)
+		}
+		return store, nil
+	})
+
+	// Committed writes are published to the bus for the event streams, and
+	// as typed domain events to the publisher selected by domain_events
+	container.Provide(c, func(c *container.Container) (*events.Bus, error) {
+		return events.NewBus(get[clock.Clock](c)), nil
+	})
+	container.Provide(c, func(*container.Container) (domain.EventPublisher, error) {
+		return domain.NewPublisher(context.Background(), lc, cfg.Domain)
+	})
+
+	// With the outbox, events are recorded with each write instead and
+	// relayed to the publisher; nil unless the outbox is enabled
+	container.Provide(c, func(c *container.Container) (*outbox.Relay, error) {
+		if !cfg.Outbox.Enabled {
+			return nil, nil
+		}
+		db := get[*database](c)
+		relay := outbox.NewRelay(db.sql, db.dialect, get[domain.EventPublisher](c), get[clock.Clock](c), cfg.Outbox)
+		lc.Append(lifecycle.Hook{Name: "outbox relay", Timeout: cfg.Server.ShutdownTimeout, Start: started(relay.Start), Stop: relay.Shutdown})
+		get[*health.Registry](c).RegisterDetailed("outbox", health.Readiness, cfg.Health.Timeout, relay.HealthCheck(cfg.Health.Background.MaxOutboxLag, cfg.Health.Background.MaxOutboxPending))
+		return relay, nil
+	})
+
+	// Background jobs run on the schedules of the jobs section
+	container.Provide(c, func(c *container.Container) (*worker.Worker, error) {
+		jobs := worker.New(get[clock.Clock](c), cfg.Jobs.Options)
+		lc.Append(lifecycle.Hook{Name: "jobs", Timeout: cfg.Server.ShutdownTimeout, Start: started(jobs.Start), Stop: jobs.Shutdown})
+		get[*health.Registry](c).RegisterDetailed("jobs", health.Readiness, cfg.Health.Timeout, jobs.HealthCheck(cfg.Health.Background.MaxQueuedJobs, cfg.Health.Background.MaxHeartbeatAge))
+		return jobs, nil
+	})
+
+	// Operational events are posted to chat webhooks, this deploy once the
+	// app starts; nil unless events are posted
+	container.Provide(c, func(c *container.Container) (*notify.Notifier, error) {
+		if len(cfg.Notify.Events) == 0 {
+			return nil, nil
+		}
+		notifier, err := notify.New(get[*httpclient.Factory](c).Client(), get[clock.Clock](c), cfg.Notify)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(lifecycle.Hook{Name: "deploy notification", Start: func(context.Context) error {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				host, _ := os.Hostname()
+				err := notifier.Notify(ctx, notify.Deploy, map[string]any{"Version": notify.Version(cfg.Notify.Version), "Environment": cfg.Environment, "Host": host})
+				if err != nil {
+					slog.Warn("deploy notification failed", "error", err)
+				}
+			}()
+			return nil
+		}})
+		return notifier, nil
+	})
+	// Responses counted for the error rate the notifications watch; nil
+	// unless events are posted
+	container.Provide(c, func(*container.Container) (*notify.Requests, error) {
+		if len(cfg.Notify.Events) == 0 {
+			return nil, nil
+		}
+		return &notify.Requests{}, nil
+	})
+
+	// Deleted items can be restored for trash.window; nil when it is 0
+	container.Provide(c, func(c *container.Container) (*trash.Bin, error) {
+		return newBin(cfg.Trash, get[*database](c), get[clock.Clock](c)), nil
+	})
+
+	// Every write is audited, with its caller and the item before and after,
+	// to the sinks of the audit section
+	container.Provide(c, func(c *container.Container) (*audit.Recorder, error) {
+		return newAuditor(cfg.Audit, get[*database](c), lc, get[clock.Clock](c))
+	})
+
+	// Logouts and reused refresh tokens are recorded in the revocation store
+	container.Provide(c, func(c *container.Container) (auth.Revocations, error) {
+		revocations, err := auth.NewRevocationStore(context.Background(), lc, cfg.Auth.RevocationBackend, cfg.Redis.URL.Reveal(), get[clock.Clock](c))
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := revocations.(health.Pinger); ok {
+			get[*health.Registry](c).Register("revocation store", health.Readiness, cfg.Health.Timeout, p.Ping)
+		}
+		return revocations, nil
+	})
+
+	// Counters of the per-client rate limits, and with tenancy those of the
+	// tenants' plans; nil when neither applies
+	container.Provide(c, func(c *container.Container) (ratelimit.Store, error) {
+		if !cfg.MiddlewarePreset().RateLimit && !cfg.Tenancy.Enabled {
+			return nil, nil
+		}
+		store, err := ratelimit.NewStore(context.Background(), lc, cfg.RateLimit.Backend, cfg.Redis.URL.Reveal(), get[clock.Clock](c))
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := store.(health.Pinger); ok {
+			get[*health.Registry](c).Register("rate limit store", health.Readiness, cfg.Health.Timeout, p.Ping)
+		}
+		return store, nil
+	})
+
+	// Uploaded files, on the local disk or in an S3 bucket
+	container.Provide(c, func(c *container.Container) (blob.Store, error) {
+		return newBlobStore(cfg.Blob, get[*httpclient.Factory](c).Streaming(), get[clock.Clock](c), get[*health.Registry](c), cfg.Health.Timeout)
+	})
+
+	return c
+}
+
+// get returns the T of c, stopping the process if it cannot be built: the
+// app does not start with a component missing.
+func get[T any](c *container.Container) T {
+	v, err := container.Get[T](c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return v
+}
+
+// started adapts the Start method of a component that cannot fail.
+func started(start func()) lifecycle.StartFunc {
+	return func(context.Context) error {
+		start()
+		return nil
+	}
+}